@@ -0,0 +1,313 @@
+// Package client calls the dispatcher's control and ingestion API from Go,
+// following the OpenAPI document the dispatcher serves at /openapi.json.
+// Every operation of the document has a method here except the WebSocket
+// event stream, which needs a WebSocket client.
+//
+//	c := client.New("http://localhost:8080")
+//	c.Token = os.Getenv("DISPATCHER_TOKEN")
+//	result, err := c.SubmitOrder(ctx, client.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300})
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Client calls one dispatcher. Set its fields before the first call.
+type Client struct {
+	BaseURL    string       // such as http://localhost:8080
+	HTTPClient *http.Client // http.DefaultClient if nil
+
+	Token  string // sent as a bearer token, for auth.tokens
+	APIKey string // sent as X-API-Key, naming a tenant
+	Tenant string // sent as X-Tenant, naming a tenant without a key
+}
+
+// New creates a client for the dispatcher at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Error is a response the API answered with an error status
+type Error struct {
+	StatusCode int
+	Message    string
+	Fields     []FieldError // the fields at fault in an invalid order
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("dispatcher answered %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("dispatcher answered %d: %s", e.StatusCode, e.Message)
+}
+
+// OrderFilter selects the shelved orders returned by Orders. Zero fields
+// match every order.
+type OrderFilter struct {
+	Temp       string
+	Shelf      string
+	NamePrefix string
+	MinValue   *float64
+	MaxValue   *float64
+	MinAge     float64 // seconds
+	MaxAge     float64 // seconds
+}
+
+func (f OrderFilter) query() url.Values {
+	q := url.Values{}
+	set := func(key, value string) {
+		if value != "" {
+			q.Set(key, value)
+		}
+	}
+	number := func(v float64) string { return strconv.FormatFloat(v, 'g', -1, 64) }
+	set("temp", f.Temp)
+	set("shelf", f.Shelf)
+	set("name", f.NamePrefix)
+	if f.MinValue != nil {
+		q.Set("minValue", number(*f.MinValue))
+	}
+	if f.MaxValue != nil {
+		q.Set("maxValue", number(*f.MaxValue))
+	}
+	if f.MinAge > 0 {
+		q.Set("minAge", number(f.MinAge))
+	}
+	if f.MaxAge > 0 {
+		q.Set("maxAge", number(f.MaxAge))
+	}
+	return q
+}
+
+// Stats returns the counters of the run (getStats)
+func (c *Client) Stats(ctx context.Context) (Stats, error) {
+	var stats Stats
+	return stats, c.call(ctx, http.MethodGet, "/api/stats", nil, &stats)
+}
+
+// ResetStats starts a fresh measurement and returns the stats after it
+// (resetStats)
+func (c *Client) ResetStats(ctx context.Context) (Stats, error) {
+	var stats Stats
+	return stats, c.call(ctx, http.MethodPost, "/api/stats/reset", nil, &stats)
+}
+
+// StatsHistory returns the time series and outcome breakdowns of the run
+// (getStatsHistory)
+func (c *Client) StatsHistory(ctx context.Context) (*StatsHistory, error) {
+	var history StatsHistory
+	if err := c.call(ctx, http.MethodGet, "/stats/history", nil, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// Shelves returns every shelf and the orders on it (getShelves)
+func (c *Client) Shelves(ctx context.Context) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := c.call(ctx, http.MethodGet, "/api/shelves", nil, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Run returns the labels of the run (getRun)
+func (c *Client) Run(ctx context.Context) (*Run, error) {
+	var run Run
+	if err := c.call(ctx, http.MethodGet, "/api/run", nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Orders returns the shelved orders matching filter (queryOrders)
+func (c *Client) Orders(ctx context.Context, filter OrderFilter) ([]Order, error) {
+	var orders []Order
+	return orders, c.call(ctx, http.MethodGet, "/orders?"+filter.query().Encode(), nil, &orders)
+}
+
+// CompletedOrders returns up to limit completed orders, newest first, with
+// the given outcome unless it is empty (completedOrders). A limit of 0
+// returns everything archived.
+func (c *Client) CompletedOrders(ctx context.Context, limit int, outcome string) ([]CompletedOrder, error) {
+	q := url.Values{"limit": {strconv.Itoa(limit)}}
+	if outcome != "" {
+		q.Set("outcome", outcome)
+	}
+	var orders []CompletedOrder
+	return orders, c.call(ctx, http.MethodGet, "/orders/completed?"+q.Encode(), nil, &orders)
+}
+
+// Aging returns every shelved order, least valuable first (agingOrders)
+func (c *Client) Aging(ctx context.Context) ([]AgingOrder, error) {
+	var orders []AgingOrder
+	return orders, c.call(ctx, http.MethodGet, "/orders/aging", nil, &orders)
+}
+
+// SubmitOrder places one order (submitOrders). A wasted, deferred or
+// rejected order is a result, with its Status, rather than an error; an
+// invalid one is an *Error listing the fields at fault.
+func (c *Client) SubmitOrder(ctx context.Context, d OrderData) (*PlacementResult, error) {
+	var result PlacementResult
+	status, err := c.do(ctx, http.MethodPost, "/orders", d, &result, http.StatusCreated,
+		http.StatusAccepted, http.StatusConflict, http.StatusTooManyRequests)
+	if err != nil {
+		return nil, err
+	}
+	result.Status = status
+	return &result, nil
+}
+
+// SubmitOrders places a batch of orders, returning a result per order
+// (submitOrders)
+func (c *Client) SubmitOrders(ctx context.Context, orders []OrderData) ([]PlacementResult, error) {
+	var results []PlacementResult
+	return results, c.call(ctx, http.MethodPost, "/orders", orders, &results)
+}
+
+// MoveOrder moves a shelved order to another shelf and returns it
+// (moveOrder)
+func (c *Client) MoveOrder(ctx context.Context, id, shelf string) (*Order, error) {
+	var o Order
+	body := map[string]string{"shelf": shelf}
+	if err := c.call(ctx, http.MethodPost, "/orders/"+url.PathEscape(id)+"/move", body, &o); err != nil {
+		return nil, err
+	}
+	return &o, nil
+}
+
+// OrderHistory returns the timeline of an order traced by the dispatcher
+// (getOrderHistory)
+func (c *Client) OrderHistory(ctx context.Context, id string) (*OrderTimeline, error) {
+	var timeline OrderTimeline
+	if err := c.call(ctx, http.MethodGet, "/orders/"+url.PathEscape(id)+"/history", nil, &timeline); err != nil {
+		return nil, err
+	}
+	return &timeline, nil
+}
+
+// Health checks the dispatcher is alive (health)
+func (c *Client) Health(ctx context.Context) error {
+	return c.call(ctx, http.MethodGet, "/healthz", nil, nil)
+}
+
+// Ready reports whether the dispatcher takes orders (ready)
+func (c *Client) Ready(ctx context.Context) (bool, error) {
+	status, err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, http.StatusOK, http.StatusServiceUnavailable)
+	return status == http.StatusOK, err
+}
+
+// Version returns the build of the dispatcher (version)
+func (c *Client) Version(ctx context.Context) (*Version, error) {
+	var v Version
+	if err := c.call(ctx, http.MethodGet, "/version", nil, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// Metrics returns the Prometheus metrics in the text format (metrics)
+func (c *Client) Metrics(ctx context.Context) (string, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/metrics", nil, "")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", readError(resp)
+	}
+	text, err := io.ReadAll(resp.Body)
+	return string(text), err
+}
+
+// OpenAPI returns the API document (openAPI)
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	var doc json.RawMessage
+	return doc, c.call(ctx, http.MethodGet, "/openapi.json", nil, &doc)
+}
+
+// call sends a request expecting 200 and decodes the response into out
+func (c *Client) call(ctx context.Context, method, path string, in, out any) error {
+	_, err := c.do(ctx, method, path, in, out, http.StatusOK)
+	return err
+}
+
+// do sends in as JSON, decoding the response into out if its status is one
+// of ok, and returns the status
+func (c *Client) do(ctx context.Context, method, path string, in, out any, ok ...int) (int, error) {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		payload, err := json.Marshal(in)
+		if err != nil {
+			return 0, err
+		}
+		body, contentType = bytes.NewReader(payload), "application/json"
+	}
+
+	resp, err := c.send(ctx, method, path, body, contentType)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	for _, status := range ok {
+		if resp.StatusCode != status {
+			continue
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return resp.StatusCode, fmt.Errorf("decoding %s %s: %w", method, path, err)
+			}
+		}
+		return resp.StatusCode, nil
+	}
+	return resp.StatusCode, readError(resp)
+}
+
+// send sends a request with the client's credentials
+func (c *Client) send(ctx context.Context, method, path string, body io.Reader, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+	if c.APIKey != "" {
+		req.Header.Set("X-API-Key", c.APIKey)
+	}
+	if c.Tenant != "" {
+		req.Header.Set("X-Tenant", c.Tenant)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return httpClient.Do(req)
+}
+
+// readError turns an error response into an *Error
+func readError(resp *http.Response) error {
+	var body struct {
+		Error  string       `json:"error"`
+		Fields []FieldError `json:"fields"`
+	}
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err := json.Unmarshal(raw, &body); err != nil {
+		body.Error = strings.TrimSpace(string(raw))
+	}
+	return &Error{StatusCode: resp.StatusCode, Message: body.Error, Fields: body.Fields}
+}
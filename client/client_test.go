@@ -0,0 +1,324 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/client"
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/simulator"
+)
+
+func newClient(t *testing.T) (*client.Client, *api.Server) {
+	cfg := config.DefaultConfig()
+	cfg.HotShelfCapacity = 1
+	cfg.OverflowCapacity = 1
+	cfg.Service.Enabled = true
+	cfg.Run.Name = "client-test"
+	cfg.Trace.Orders = "Salad"
+
+	sim, err := simulator.NewSimulator(cfg, "")
+	require.NoError(t, err)
+
+	server := api.NewServer(sim.ShelfManager, sim.Events, sim.Archive)
+	server.SetRun(sim)
+	server.SetService(sim)
+	server.SetHistory(sim)
+	server.SetOrderHistory(sim)
+	server.SetReady(true)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+	return client.New(srv.URL + "/"), server
+}
+
+func TestClient_Orders(t *testing.T) {
+	c, _ := newClient(t)
+	ctx := context.Background()
+
+	results, err := c.SubmitOrders(ctx, []client.OrderData{{Name: "Salad", Temp: "cold", ShelfLife: 300, DecayRate: 0.5}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "cold", results[0].Order.Shelf)
+
+	orders, err := c.Orders(ctx, client.OrderFilter{Temp: "cold"})
+	require.NoError(t, err)
+	require.Len(t, orders, 1)
+	assert.Equal(t, "Salad", orders[0].Name)
+
+	moved, err := c.MoveOrder(ctx, orders[0].ID, "overflow")
+	require.NoError(t, err)
+	assert.Equal(t, "overflow", moved.Shelf)
+	_, err = c.MoveOrder(ctx, "missing", "cold")
+	var apiErr *client.Error
+	require.True(t, errors.As(err, &apiErr), "got %v", err)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+
+	timeline, err := c.OrderHistory(ctx, orders[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Salad", timeline.Name)
+	require.Len(t, timeline.Entries, 2)
+	assert.Equal(t, "shelved", timeline.Entries[0].To)
+	assert.Equal(t, "cold", timeline.Entries[0].Shelf)
+
+	result, err := c.SubmitOrder(ctx, client.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusCreated, result.Status)
+	require.NotNil(t, result.Order)
+	assert.Equal(t, "hot", result.Order.Shelf)
+
+	// The hot shelf and the overflow are full
+	result, err = c.SubmitOrder(ctx, client.OrderData{Name: "Stew", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, result.Status)
+	assert.NotEmpty(t, result.Reason)
+
+	_, err = c.SubmitOrder(ctx, client.OrderData{Name: "Soup", Temp: "hot"})
+	require.True(t, errors.As(err, &apiErr), "got %v", err)
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Len(t, apiErr.Fields, 1)
+	assert.Equal(t, "shelfLife", apiErr.Fields[0].Field)
+
+	aging, err := c.Aging(ctx)
+	require.NoError(t, err)
+	assert.Len(t, aging, 2)
+
+	completed, err := c.CompletedOrders(ctx, 0, "wasted")
+	require.NoError(t, err)
+	require.Len(t, completed, 1)
+	assert.Equal(t, "Stew", completed[0].Name)
+	_, err = c.OrderHistory(ctx, completed[0].ID)
+	require.True(t, errors.As(err, &apiErr), "got %v", err)
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode, "only salads are traced")
+
+	snapshot, err := c.Shelves(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, snapshot.Shelves)
+}
+
+func TestClient_Stream(t *testing.T) {
+	c, _ := newClient(t)
+
+	stream, err := c.StreamOrders(context.Background())
+	require.NoError(t, err)
+	defer stream.Close()
+
+	require.NoError(t, stream.Send(client.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}))
+	ack, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 1, ack.Seq)
+	assert.Equal(t, http.StatusCreated, ack.Status)
+
+	require.NoError(t, stream.Send(client.OrderData{Name: "Soup", Temp: "hot"}))
+	ack, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, 2, ack.Seq)
+	assert.Equal(t, http.StatusBadRequest, ack.Status)
+	assert.NotEmpty(t, ack.Fields)
+}
+
+func TestClient_Service(t *testing.T) {
+	c, server := newClient(t)
+	ctx := context.Background()
+
+	assert.NoError(t, c.Health(ctx))
+	ready, err := c.Ready(ctx)
+	require.NoError(t, err)
+	assert.True(t, ready)
+	server.SetReady(false)
+	ready, err = c.Ready(ctx)
+	require.NoError(t, err)
+	assert.False(t, ready)
+
+	run, err := c.Run(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "client-test", run.Name)
+	assert.Regexp(t, `^client-test-[0-9a-f]{8}$`, run.ID)
+
+	version, err := c.Version(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, version.GoVersion)
+
+	metrics, err := c.Metrics(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, metrics, "dispatcher_shelf_orders")
+
+	stats, err := c.ResetStats(ctx)
+	require.NoError(t, err)
+	assert.NotEmpty(t, stats)
+	stats, err = c.Stats(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, stats, "totalOrders")
+
+	history, err := c.StatsHistory(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hot", "cold", "frozen", "overflow"}, history.Shelves)
+	reset, err := c.Run(ctx)
+	require.NoError(t, err)
+	assert.NotEqual(t, run.ID, reset.ID, "a reset starts a new run")
+	assert.Equal(t, reset.ID, history.RunID)
+	assert.Len(t, history.ByTemperature, 3)
+
+	doc, err := c.OpenAPI(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, string(doc), `"openapi"`)
+}
+
+func TestClient_Credentials(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error":"a bearer token is required"}`)
+	}))
+	defer srv.Close()
+
+	c := client.New(srv.URL)
+	c.Token, c.APIKey, c.Tenant = "secret", "key", "checkout"
+	_, err := c.Stats(context.Background())
+	assert.EqualError(t, err, "dispatcher answered 401: a bearer token is required")
+	assert.Equal(t, "Bearer secret", got.Get("Authorization"))
+	assert.Equal(t, "key", got.Get("X-API-Key"))
+	assert.Equal(t, "checkout", got.Get("X-Tenant"))
+}
+
+// operations reads the API document's operations by ID
+func operations(t *testing.T) map[string]operation {
+	spec, err := os.ReadFile("../internal/api/openapi.json")
+	require.NoError(t, err)
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(spec, &doc))
+
+	ops := make(map[string]operation)
+	for path, byMethod := range doc.Paths {
+		for method, op := range byMethod {
+			if op.OperationID == "streamEvents" {
+				continue // WebSocket
+			}
+			ops[op.OperationID] = operation{method: strings.ToUpper(method), path: path}
+		}
+	}
+	return ops
+}
+
+type operation struct {
+	method, path string
+}
+
+// matches reports whether a request is for the operation, whose path may
+// have {parameters}
+func (op operation) matches(method, path string) bool {
+	var pattern strings.Builder
+	for i, segment := range strings.Split(op.path, "/") {
+		if i > 0 {
+			pattern.WriteString("/")
+		}
+		if strings.HasPrefix(segment, "{") {
+			pattern.WriteString("[^/]+")
+		} else {
+			pattern.WriteString(regexp.QuoteMeta(segment))
+		}
+	}
+	return method == op.method && regexp.MustCompile("^"+pattern.String()+"$").MatchString(path)
+}
+
+// documentedMethods maps each exported Client method to the operation ID
+// its doc comment names in parentheses
+func documentedMethods(t *testing.T) map[string]string {
+	fset := token.NewFileSet()
+	methods := make(map[string]string)
+	for _, file := range []string{"client.go", "stream.go"} {
+		f, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+		require.NoError(t, err)
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || !fn.Name.IsExported() || fn.Doc == nil {
+				continue
+			}
+			star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+			if !ok || star.X.(*ast.Ident).Name != "Client" {
+				continue
+			}
+			if m := regexp.MustCompile(`\((\w+)\)`).FindStringSubmatch(fn.Doc.Text()); m != nil {
+				methods[fn.Name.Name] = m[1]
+			}
+		}
+	}
+	return methods
+}
+
+// TestClient_CoversDocument checks every operation of the API document has
+// a method naming its operation ID in its doc comment, and every operation
+// a method names is in the document
+func TestClient_CoversDocument(t *testing.T) {
+	ops := operations(t)
+	named := make(map[string]bool)
+	for method, id := range documentedMethods(t) {
+		_, ok := ops[id]
+		assert.True(t, ok, "%s names %s, which the document does not have", method, id)
+		named[id] = true
+	}
+	for id, op := range ops {
+		assert.True(t, named[id], "no method for %s (%s %s)", id, op.method, op.path)
+	}
+}
+
+// TestClient_MatchesDocument calls every method and checks it sends the
+// HTTP method and path of the operation it names
+func TestClient_MatchesDocument(t *testing.T) {
+	requests := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.Method + " " + r.URL.Path
+		// Answered at once, as the order stream needs before it sends
+		http.NewResponseController(w).EnableFullDuplex()
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "{}")
+		w.(http.Flusher).Flush()
+	}))
+	defer srv.Close()
+
+	ops := operations(t)
+	c := reflect.ValueOf(client.New(srv.URL))
+	for name, id := range documentedMethods(t) {
+		method := c.MethodByName(name)
+		require.True(t, method.IsValid(), name)
+		// Cancelled once the call returns, which also ends an order stream
+		ctx, cancel := context.WithCancel(context.Background())
+		args := make([]reflect.Value, method.Type().NumIn())
+		for i := range args {
+			switch in := method.Type().In(i); {
+			case in.Implements(reflect.TypeFor[context.Context]()):
+				args[i] = reflect.ValueOf(ctx)
+			case in.Kind() == reflect.String:
+				args[i] = reflect.ValueOf("x").Convert(in)
+			default:
+				args[i] = reflect.Zero(in)
+			}
+		}
+
+		method.Call(args)
+		cancel()
+		verb, path, _ := strings.Cut(<-requests, " ")
+		assert.True(t, ops[id].matches(verb, path), "%s sent %s %s for %s (%s %s)",
+			name, verb, path, id, ops[id].method, ops[id].path)
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+)
+
+// OrderStream places orders over one long-lived request, acknowledging
+// each on the same connection
+type OrderStream struct {
+	orders *io.PipeWriter
+	enc    *json.Encoder
+	resp   *http.Response
+	acks   *json.Decoder
+}
+
+// StreamOrders opens an order stream (streamOrders). Send orders with Send
+// and read an ack for each, in order, with Recv. The dispatcher must be
+// reachable over HTTP/2 or an HTTP/1.1 connection it can read and write at
+// once, as it is by default.
+func (c *Client) StreamOrders(ctx context.Context) (*OrderStream, error) {
+	body, orders := io.Pipe()
+	resp, err := c.send(ctx, http.MethodPost, "/orders/stream", body, "application/x-ndjson")
+	if err != nil {
+		orders.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		orders.Close()
+		return nil, readError(resp)
+	}
+	return &OrderStream{
+		orders: orders,
+		enc:    json.NewEncoder(orders),
+		resp:   resp,
+		acks:   json.NewDecoder(bufio.NewReader(resp.Body)),
+	}, nil
+}
+
+// Send sends an order
+func (s *OrderStream) Send(d OrderData) error {
+	return s.enc.Encode(d)
+}
+
+// Recv waits for the ack of the next order sent. It returns io.EOF once
+// the dispatcher ends the stream.
+func (s *OrderStream) Recv() (StreamAck, error) {
+	var ack StreamAck
+	err := s.acks.Decode(&ack)
+	return ack, err
+}
+
+// Close ends the stream
+func (s *OrderStream) Close() error {
+	s.orders.Close()
+	return s.resp.Body.Close()
+}
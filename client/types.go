@@ -0,0 +1,176 @@
+package client
+
+import "time"
+
+// The types below are the schemas of the API document, served at
+// /openapi.json, as they appear on the wire.
+
+// Stats are the counters of a run, keyed by shelf and total. Their shape
+// depends on the dispatcher's shelf backend.
+type Stats map[string]any
+
+// Run identifies and labels a run
+type Run struct {
+	ID          string            `json:"id"` // unique within the dispatcher, changes when stats are reset
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// OrderData describes an order to place
+type OrderData struct {
+	Name      string  `json:"name"`
+	Temp      string  `json:"temp"`
+	ShelfLife float64 `json:"shelfLife"` // seconds
+	DecayRate float64 `json:"decayRate"`
+	Size      float64 `json:"size,omitempty"` // shelf space taken, one unit if 0
+
+	// Optional safe temperature band in °C and the decay multiplier applied
+	// while the order is held outside it
+	MinTemp            *float64 `json:"minTemp,omitempty"`
+	MaxTemp            *float64 `json:"maxTemp,omitempty"`
+	SpoilageMultiplier float64  `json:"spoilageMultiplier,omitempty"`
+
+	// Version 2 fields, which need Version set to 2
+	Priority int     `json:"priority,omitempty"`
+	Zone     string  `json:"zone,omitempty"`
+	Price    float64 `json:"price,omitempty"`
+	Version  int     `json:"version,omitempty"`
+}
+
+// Order is a shelved order
+type Order struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Temp  string  `json:"temp"`
+	Shelf string  `json:"shelf"`
+	Value float64 `json:"value"`
+	Age   float64 `json:"age"` // seconds since first shelved
+
+	// PromisedAt is when the order should reach its customer, nil if no
+	// promise was made
+	PromisedAt *time.Time `json:"promisedAt,omitempty"`
+}
+
+// AgingOrder is a shelved order with the time it has left
+type AgingOrder struct {
+	Order
+	SecondsToExpiry *float64 `json:"secondsToExpiry"` // nil for orders that never expire
+}
+
+// StateChange is one step of a completed order's lifecycle
+type StateChange struct {
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// CompletedOrder is an order that left the shelves for good
+type CompletedOrder struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Temp        string        `json:"temp"`
+	Shelf       string        `json:"shelf,omitempty"`
+	Outcome     string        `json:"outcome"` // delivered, expired, wasted or cancelled
+	CompletedAt time.Time     `json:"completedAt"`
+	FinalValue  float64       `json:"finalValue"`
+	Timeline    []StateChange `json:"timeline"`
+}
+
+// Shelf is the state of one shelf
+type Shelf struct {
+	Type       string  `json:"type"`
+	Capacity   int     `json:"capacity"`
+	Volume     float64 `json:"volume,omitempty"`
+	UsedVolume float64 `json:"usedVolume,omitempty"`
+	InOutage   bool    `json:"inOutage"`
+	Orders     []Order `json:"orders"`
+}
+
+// Snapshot is the state of every shelf at a point in time
+type Snapshot struct {
+	Time    time.Time `json:"time"`
+	Shelves []Shelf   `json:"shelves"`
+}
+
+// FieldError is what is wrong with one field of an invalid order
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// PlacementResult is the outcome of one submitted order
+type PlacementResult struct {
+	Order  *Order       `json:"order,omitempty"`
+	Reason string       `json:"reason,omitempty"` // why the order was wasted, deferred or rejected
+	Error  string       `json:"error,omitempty"`
+	Fields []FieldError `json:"fields,omitempty"`
+
+	// Status is the code the order was answered with: 201 shelved, 202
+	// deferred, 409 wasted, 429 rejected. Batch results leave it 0.
+	Status int `json:"-"`
+}
+
+// StreamAck acknowledges one order of an order stream
+type StreamAck struct {
+	Seq    int `json:"seq"`    // counts the stream's orders from 1
+	Status int `json:"status"` // as for PlacementResult
+	PlacementResult
+}
+
+// OrderTimeline is what happened to a traced order
+type OrderTimeline struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Temp    string       `json:"temp"`
+	Source  string       `json:"source,omitempty"`
+	Entries []TraceEntry `json:"entries"` // oldest first
+}
+
+// TraceEntry is one step of a traced order's lifecycle
+type TraceEntry struct {
+	At     time.Time `json:"at"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Shelf  string    `json:"shelf,omitempty"`  // put on or taken from, if any
+	Value  float64   `json:"value"`            // at the time of the step, 0 once lost
+	Reason string    `json:"reason,omitempty"` // why the order was lost, if known
+}
+
+// StatsHistory is the time series and outcome breakdowns of a run
+type StatsHistory struct {
+	RunID           string           `json:"runId"`
+	IntervalSeconds float64          `json:"intervalSeconds"`
+	Shelves         []string         `json:"shelves"` // the keys of each sample's shelves, in layout order
+	Samples         []StatsSample    `json:"samples"` // oldest first
+	ByItem          []OutcomeSummary `json:"byItem"`
+	ByTemperature   []OutcomeSummary `json:"byTemperature"`
+}
+
+// StatsSample is the shelves and running order totals at one moment
+type StatsSample struct {
+	Timestamp      time.Time      `json:"timestamp"`
+	ElapsedSeconds float64        `json:"elapsedSeconds"`
+	Shelves        map[string]int `json:"shelves"` // orders on each shelf
+	Received       int            `json:"received"`
+	Delivered      int            `json:"delivered"`
+	Wasted         int            `json:"wasted"`
+	Expired        int            `json:"expired"`
+}
+
+// OutcomeSummary is how the orders of one item or temperature ended
+type OutcomeSummary struct {
+	Name              string  `json:"name"`
+	Delivered         int     `json:"delivered"`
+	Wasted            int     `json:"wasted"`
+	Expired           int     `json:"expired"`
+	AvgDeliveredValue float64 `json:"avgDeliveredValue"`
+}
+
+// Version identifies the dispatcher's build
+type Version struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	GoVersion string `json:"goVersion"`
+}
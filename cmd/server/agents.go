@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"dish-dispatcher/internal/agent"
+	"dish-dispatcher/internal/courier"
+)
+
+// runAgents implements the agents subcommand, which connects simulated
+// couriers to a dispatcher accepting external agents
+func runAgents(args []string) error {
+	fs := flag.NewFlagSet("agents", flag.ContinueOnError)
+	addr := fs.String("addr", "localhost:9090", "Address where the dispatcher accepts courier agents (couriers.agentAddr)")
+	count := fs.Int("count", 5, "Number of couriers to connect")
+	reach := fs.Float64("reach", 6, "Furthest a courier strays from the kitchen, in seconds of travel")
+	prefix := fs.String("prefix", "agent", "Prefix of the courier IDs, which must be unique per dispatcher")
+	useTLS := fs.Bool("tls", false, "Connect over TLS, implied by -ca and -cert")
+	tlsConfig := clientTLSFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *count <= 0 {
+		return errors.New("-count must be positive")
+	}
+	tc, err := tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := agent.NewClient(*addr)
+	if *useTLS || tc != nil {
+		client = agent.NewTLSClient(*addr, tc)
+	}
+	var delivered, missed atomic.Int64
+	var wg sync.WaitGroup
+	errs := make(chan error, *count)
+	for i := 1; i <= *count; i++ {
+		id := fmt.Sprintf("%s-%d", *prefix, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := runSimulatedAgent(ctx, client, id, *reach, &delivered, &missed); err != nil {
+				errs <- fmt.Errorf("%s: %w", id, err)
+				stop()
+			}
+		}()
+	}
+	fmt.Printf("Connected %d couriers to %s\n", *count, *addr)
+
+	wg.Wait()
+	close(errs)
+	fmt.Printf("Couriers delivered %d orders, missed %d\n", delivered.Load(), missed.Load())
+	return <-errs
+}
+
+// runSimulatedAgent travels to the kitchen for each dispatch, then on to a
+// random customer, reporting each step, until ctx is cancelled
+func runSimulatedAgent(ctx context.Context, client *agent.Client, id string, reach float64, delivered, missed *atomic.Int64) error {
+	stream, err := client.Connect(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	rng := rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	x, y := courier.RandomPosition(reach, rng)
+	for {
+		dispatch, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		report := agent.Report{CourierID: id, OrderID: dispatch.OrderID}
+		if !sleepCtx(ctx, travelTime(x, y)) {
+			return nil
+		}
+		reply, err := client.ReportPickup(ctx, report)
+		if err != nil {
+			return err
+		}
+		x, y = 0, 0
+		if !reply.OK {
+			missed.Add(1)
+			continue
+		}
+
+		x, y = courier.RandomPosition(reach, rng)
+		if !sleepCtx(ctx, travelTime(x, y)) {
+			return nil
+		}
+		if _, err := client.ReportDelivery(ctx, report); err != nil {
+			return err
+		}
+		delivered.Add(1)
+	}
+}
+
+// travelTime returns how long a courier at x, y takes to reach the kitchen
+func travelTime(x, y float64) time.Duration {
+	return time.Duration(math.Hypot(x, y) * float64(time.Second))
+}
+
+// sleepCtx sleeps for d and returns false if ctx was cancelled first
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
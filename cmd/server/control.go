@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime/pprof"
+	"sort"
+	"strings"
+
+	"dish-dispatcher/internal/simulator"
+)
+
+// controlCommands inspect or adjust a run in progress. They are typed on
+// the console, where help lists them, or sent as the signals in
+// signalCommands.
+var controlCommands = map[string]struct {
+	help string
+	run  func(sim *simulator.Simulator)
+}{
+	"stats": {"print the current stats and aging report", func(sim *simulator.Simulator) {
+		sim.PrintCurrentStats()
+		sim.PrintAgingReport()
+	}},
+	"aging":   {"print the aging report of shelved orders", (*simulator.Simulator).PrintAgingReport},
+	"stacks":  {"dump every goroutine's stack to stderr", func(*simulator.Simulator) { dumpStacks() }},
+	"verbose": {"toggle the per-order log lines", toggleVerbose},
+}
+
+// signalCommand runs a control command when the process gets a signal
+type signalCommand struct {
+	signal  os.Signal
+	name    string // as given to kill
+	command string
+}
+
+// watchControlRequests runs control commands as they are typed on the
+// console or signalled, until ctx is done
+func watchControlRequests(ctx context.Context, sim *simulator.Simulator) {
+	signals := make(chan os.Signal, 1)
+	bySignal := make(map[os.Signal]string, len(signalCommands))
+	for _, sc := range signalCommands {
+		signal.Notify(signals, sc.signal)
+		bySignal[sc.signal] = sc.command
+	}
+
+	commands := make(chan string)
+	go readConsole(ctx, commands)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case sig := <-signals:
+				runCommand(sim, bySignal[sig])
+			case command := <-commands:
+				runCommand(sim, command)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func runCommand(sim *simulator.Simulator, name string) {
+	if name == "help" {
+		printCommands()
+		return
+	}
+	command, ok := controlCommands[name]
+	if !ok {
+		fmt.Printf("Unknown command %q. Commands:\n", name)
+		printCommands()
+		return
+	}
+	command.run(sim)
+}
+
+// printCommands lists the control commands and the signals that send them
+func printCommands() {
+	names := make([]string, 0, len(controlCommands))
+	for name := range controlCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		signalled := ""
+		for _, sc := range signalCommands {
+			if sc.command == name {
+				signalled = " (" + sc.name + ")"
+			}
+		}
+		fmt.Printf("  %s%s: %s\n", name, signalled, controlCommands[name].help)
+	}
+}
+
+func toggleVerbose(sim *simulator.Simulator) {
+	if sim.ToggleVerbose() {
+		fmt.Println("🔊 Per-order logging on")
+	} else {
+		fmt.Println("🔇 Per-order logging off")
+	}
+}
+
+// dumpStacks writes every goroutine's stack in the format of an unrecovered
+// panic
+func dumpStacks() {
+	fmt.Fprintln(os.Stderr, "=== goroutine stacks ===")
+	pprof.Lookup("goroutine").WriteTo(os.Stderr, 2)
+	fmt.Fprintln(os.Stderr, "=== end of goroutine stacks ===")
+}
+
+// readConsole sends each non-empty line typed on stdin until it is closed
+func readConsole(ctx context.Context, commands chan<- string) {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		command := strings.TrimSpace(scanner.Text())
+		if command == "" {
+			continue
+		}
+		select {
+		case commands <- command:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/simulator"
+)
+
+// Exit codes, so CI pipelines can tell why a run failed
+const (
+	exitSuccess     = 0
+	exitError       = 1 // any failure without a more specific code
+	exitConfigError = 2 // invalid configuration or flags
+	exitWasteRate   = 3 // the -fail-on-waste-rate threshold was exceeded
+	exitAborted     = 4 // interrupted or stopped before finishing
+	exitAssertion   = 5 // the run failed one of the configured assertions
+	exitBaseline    = 6 // a metric regressed from the -baseline past its -tolerance
+)
+
+// failsAssertions reports whether the simulation's final stats fail any of
+// the assertions, printing each failure as a diff of expected and actual
+func failsAssertions(sim *simulator.Simulator, assertions []history.Assertion) bool {
+	summary := history.NewSummary(sim.ShelfManager)
+	failed := history.FailedAssertions(assertions, summary)
+	if len(failed) == 0 {
+		return false
+	}
+	fmt.Printf("%d of %d assertions failed:\n", len(failed), len(assertions))
+	for _, a := range failed {
+		fmt.Println(a.Diff(summary))
+	}
+	return true
+}
+
+// exceedsWasteRate reports whether more than threshold percent of the
+// simulation's orders were wasted or expired. A zero threshold disables the
+// check.
+func exceedsWasteRate(sim *simulator.Simulator, threshold float64) bool {
+	if threshold == 0 {
+		return false
+	}
+	rate := history.NewSummary(sim.ShelfManager).WasteRate()
+	if rate <= threshold {
+		return false
+	}
+	fmt.Printf("Waste rate %.1f%% exceeds the -fail-on-waste-rate threshold of %g%%\n", rate, threshold)
+	return true
+}
+
+// regressesBaseline prints how the simulation's final stats moved from the
+// baseline and reports whether any regressed past its tolerance. It does
+// nothing without a baseline.
+func regressesBaseline(sim *simulator.Simulator, baseline *history.Summary, tolerances []history.Tolerance) bool {
+	if baseline == nil {
+		return false
+	}
+	deltas := history.DiffBaseline(*baseline, runSummary(sim), tolerances)
+	fmt.Println("Compared with the baseline:")
+	for _, d := range deltas {
+		fmt.Printf("  %s\n", d)
+	}
+	regressed := history.Regressed(deltas)
+	if len(regressed) == 0 {
+		return false
+	}
+	fmt.Printf("%d of %d metrics regressed past their tolerance\n", len(regressed), len(deltas))
+	return true
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/simulator"
+)
+
+const historyUsage = `usage: dish-dispatcher history [-config file] [-file history.db] <command>
+
+commands:
+  list             list recorded runs
+  show <id>        show one run's config and final stats
+  compare <a> <b>  compare the headline stats of two runs`
+
+// runHistory implements the history subcommand
+func runHistory(args []string) error {
+	fs := flag.NewFlagSet("history", flag.ContinueOnError)
+	configFile := fs.String("config", "config.json", "Path to configuration file naming the history file")
+	file := fs.String("file", "", "History file, overriding the config")
+	fs.Usage = func() { fmt.Fprintln(fs.Output(), historyUsage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *file
+	if path == "" {
+		cfg, err := config.LoadConfig(*configFile)
+		if err != nil {
+			return err
+		}
+		path = cfg.HistoryFile
+	}
+	if path == "" {
+		return errors.New("no history file configured")
+	}
+	store, err := history.Open(path)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	switch fs.Arg(0) {
+	case "list":
+		return listRuns(store)
+	case "show":
+		if fs.NArg() != 2 {
+			return errors.New(historyUsage)
+		}
+		rec, err := getRun(store, fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		return showRun(rec)
+	case "compare":
+		if fs.NArg() != 3 {
+			return errors.New(historyUsage)
+		}
+		a, err := getRun(store, fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		b, err := getRun(store, fs.Arg(2))
+		if err != nil {
+			return err
+		}
+		return compareRuns(a, b)
+	default:
+		return errors.New(historyUsage)
+	}
+}
+
+func getRun(store *history.Store, arg string) (history.Record, error) {
+	id, err := strconv.Atoi(arg)
+	if err != nil {
+		return history.Record{}, fmt.Errorf("invalid run id %q", arg)
+	}
+	return store.Get(id)
+}
+
+func listRuns(store *history.Store) error {
+	records, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		fmt.Println("No runs recorded")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED\tDURATION\tRUN\tRECEIVED\tDELIVERED\tWASTE")
+	for _, rec := range records {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%.1f%%\t%.1f%%\n",
+			rec.ID, rec.StartedAt.Format(time.DateTime), rec.FinishedAt.Sub(rec.StartedAt).Round(time.Second),
+			rec.Run.Label(), rec.Stats.Received, rec.Stats.DeliveryRate(), rec.Stats.WasteRate())
+	}
+	return w.Flush()
+}
+
+func showRun(rec history.Record) error {
+	fmt.Printf("Run #%d %s\n", rec.ID, rec.Run.Label())
+	if rec.Run.Description != "" {
+		fmt.Printf("Description: %s\n", rec.Run.Description)
+	}
+	fmt.Printf("Started: %s, finished: %s\n", rec.StartedAt.Format(time.DateTime), rec.FinishedAt.Format(time.DateTime))
+	fmt.Printf("Seed: %d\n", rec.Seed)
+	if rec.Version != "" {
+		fmt.Printf("Version: %s\n", rec.Version)
+	}
+	if rec.Err != "" {
+		fmt.Printf("Failed: %s\n", rec.Err)
+	}
+
+	s := rec.Stats
+	fmt.Printf("Orders: received %d, delivered %d (%.1f%%), wasted %d, expired %d\n",
+		s.Received, s.Delivered, s.DeliveryRate(), s.Wasted, s.Expired)
+	fmt.Printf("Avg delivered value: %.2f\n", s.AverageDeliveredValue())
+	for temp, is := range s.ByTemperature {
+		fmt.Printf("  %s: delivered %d, wasted %d, expired %d\n", temp, is.Delivered, is.Wasted, is.Expired)
+	}
+	for reason, n := range s.Rejections {
+		fmt.Printf("  rejected %s: %d\n", reason, n)
+	}
+	if s.Promised > 0 {
+		fmt.Printf("Promised delivery kept: %.1f%% of %d (avg %+.1fs)\n", s.OnTimeRate(), s.Promised, s.PromiseDelta)
+	}
+
+	fmt.Printf("Shelves: %d/%d/%d/%d, orders/sec %.1f, duration %ds, decay %s x%.1f\n",
+		rec.Config.HotShelfCapacity, rec.Config.ColdShelfCapacity, rec.Config.FrozenShelfCapacity,
+		rec.Config.OverflowCapacity, rec.Config.OrdersPerSecond, rec.Config.SimulationDuration,
+		rec.Config.DecayFormula, rec.Config.DecayModifier)
+	return nil
+}
+
+func compareRuns(a, b history.Record) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "METRIC\t#%d %s\t#%d %s\tDELTA\t\n", a.ID, a.Run.Name, b.ID, b.Run.Name)
+	for _, c := range history.Compare(a, b) {
+		fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%+.2f\t\n", c.Metric, c.A, c.B, c.Delta())
+	}
+	return w.Flush()
+}
+
+// recordRun stores a finished run in the configured history file
+func recordRun(cfg *config.Config, sim *simulator.Simulator, seed int64, started time.Time) {
+	if cfg.HistoryFile == "" {
+		return
+	}
+
+	rec := history.Record{
+		Run:        sim.RunContext(),
+		Version:    buildinfo.Version(),
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+		Seed:       seed,
+		Config:     *cfg,
+		Stats:      runSummary(sim),
+	}
+	if err := sim.Err(); err != nil {
+		rec.Err = err.Error()
+	}
+
+	store, err := history.Open(cfg.HistoryFile)
+	if err != nil {
+		fmt.Printf("Failed to record run history: %v\n", err)
+		return
+	}
+	defer store.Close()
+
+	rec, err = store.Add(rec)
+	if err != nil {
+		fmt.Printf("Failed to record run history: %v\n", err)
+		return
+	}
+	fmt.Printf("Recorded as run #%d in %s\n", rec.ID, cfg.HistoryFile)
+}
+
+// runSummary reads the final stats of a run, with the delivery promises it
+// kept
+func runSummary(sim *simulator.Simulator) history.Summary {
+	summary := history.NewSummary(sim.ShelfManager)
+	promises := sim.Promises()
+	summary.Promised, summary.OnTime, summary.PromiseDelta = promises.HandedOff, promises.OnTime, promises.AvgDelta
+	return summary
+}
+
+// writeManifest writes what a finished run depended on to the configured
+// manifest file, so its result can be reproduced
+func writeManifest(cfg *config.Config, sim *simulator.Simulator, ordersFile string, seed int64, started time.Time) {
+	if cfg.ManifestFile == "" {
+		return
+	}
+
+	inputs, err := history.HashInputs(simulator.InputFiles(cfg, ordersFile))
+	if err != nil {
+		fmt.Printf("Failed to write run manifest: %v\n", err)
+		return
+	}
+	m := history.Manifest{
+		Run:        sim.RunContext(),
+		Version:    buildinfo.Version(),
+		StartedAt:  started,
+		FinishedAt: time.Now(),
+		Seed:       seed,
+		Inputs:     inputs,
+		Config:     *cfg,
+		Stats:      runSummary(sim),
+	}
+	if err := sim.Err(); err != nil {
+		m.Err = err.Error()
+	}
+
+	if err := history.WriteManifest(cfg.ManifestFile, m); err != nil {
+		fmt.Printf("Failed to write run manifest: %v\n", err)
+		return
+	}
+	fmt.Printf("Wrote the run manifest to %s\n", cfg.ManifestFile)
+}
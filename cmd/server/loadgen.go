@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
+
+	"dish-dispatcher/internal/loadgen"
+	"dish-dispatcher/internal/simulator"
+)
+
+// runLoadgen implements the loadgen subcommand
+func runLoadgen(args []string) error {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8080", "Base URL of a dispatcher running in service mode")
+	rps := fs.Float64("rps", 10, "Orders sent per second")
+	duration := fs.Duration("duration", 30*time.Second, "How long to send for, 0 to run until interrupted")
+	concurrency := fs.Int("concurrency", 64, "Most requests in flight at once")
+	timeout := fs.Duration("timeout", 5*time.Second, "Per-request timeout")
+	ordersFile := fs.String("orders", "orders.json", "Orders file used as templates, empty for generic orders")
+	mix := fs.String("mix", "", "Temperature weights such as hot=2,cold=1,frozen=1")
+	token := fs.String("token", os.Getenv("DISPATCHER_TOKEN"), "Bearer token for a dispatcher requiring one")
+	tlsConfig := clientTLSFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	tc, err := tlsConfig()
+	if err != nil {
+		return err
+	}
+
+	opts := loadgen.Options{
+		Target:      *target,
+		RPS:         *rps,
+		Duration:    *duration,
+		Concurrency: *concurrency,
+		Timeout:     *timeout,
+		Token:       *token,
+		TLS:         tc,
+	}
+
+	if opts.Mix, err = loadgen.ParseMix(*mix); err != nil {
+		return err
+	}
+	if *ordersFile != "" {
+		if opts.Templates, err = simulator.LoadOrdersFromFile(*ordersFile); err != nil {
+			return fmt.Errorf("failed to load orders: %w", err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Sending %.1f orders/sec to %s", opts.RPS, opts.Target)
+	if opts.Duration > 0 {
+		fmt.Printf(" for %s", opts.Duration)
+	}
+	fmt.Println()
+
+	report, err := loadgen.Run(ctx, opts)
+	if err != nil {
+		return err
+	}
+	printLoadReport(report)
+	return nil
+}
+
+// printLoadReport prints the client-side results of a load run
+func printLoadReport(r loadgen.Report) {
+	fmt.Println("\n📈 LOAD TEST RESULTS 📈")
+	fmt.Println("===============================")
+	fmt.Printf("Elapsed: %s, sent %d (%.1f completed/sec)\n", r.Elapsed.Round(time.Millisecond), r.Sent, r.Throughput())
+	fmt.Printf("Placed: %d, wasted: %d, errors: %d, skipped: %d\n", r.Placed, r.Wasted, r.Errors, r.Skipped)
+
+	statuses := make([]int, 0, len(r.ByStatus))
+	for status := range r.ByStatus {
+		statuses = append(statuses, status)
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		fmt.Printf("  HTTP %d: %d\n", status, r.ByStatus[status])
+	}
+
+	fmt.Printf("Latency: mean %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		r.Mean(), r.Percentile(50), r.Percentile(90), r.Percentile(99), r.Percentile(100))
+	fmt.Println("===============================")
+}
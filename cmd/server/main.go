@@ -1,41 +1,294 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
-	"math/rand"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/cluster"
 	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/redisshelf"
 	"dish-dispatcher/internal/simulator"
+	"dish-dispatcher/internal/tenant"
+	"dish-dispatcher/internal/tlsconfig"
 )
 
+// subcommands run instead of the simulation when named as the first argument
+var subcommands = map[string]func(args []string) error{
+	"agents":   runAgents,
+	"history":  runHistory,
+	"loadgen":  runLoadgen,
+	"optimize": runOptimize,
+	"plan":     runPlan,
+	"plugins":  runPlugins,
+	"verify":   runVerify,
+	"version":  runVersion,
+}
+
 func main() {
+	os.Exit(run())
+}
+
+// run runs a subcommand or the simulation and returns the exit code
+func run() int {
+	if len(os.Args) > 1 {
+		if subcommand, ok := subcommands[os.Args[1]]; ok {
+			if err := subcommand(os.Args[2:]); err != nil {
+				fmt.Println(err)
+				return exitError
+			}
+			return exitSuccess
+		}
+	}
+
 	// Parse command line flags
 	configFile := flag.String("config", "config.json", "Path to configuration file")
 	ordersFile := flag.String("orders", "orders.json", "Path to orders JSON file")
+	addr := flag.String("addr", os.Getenv("ADDR"), "Address for the control API and dashboard, empty to disable")
+	runName := flag.String("run-name", "", "Name for this run, overriding the config")
+	description := flag.String("description", "", "Description of this run, overriding the config")
+	service := flag.Bool("service", false, "Run as a long-lived service taking orders over the API, with no orders file or duration")
+	engineName := flag.String("engine", "", "Simulation engine, \"realtime\" or \"discrete\", overriding the config")
+	diagnostics := flag.Bool("diagnostics", false, "Serve pprof profiles and expvar on the control API")
+	quiet := flag.Bool("quiet", false, "Start without the per-order log lines; toggle them with the verbose command")
+	profile := flag.String("profile", "", "Profile overlaid on the config, built in (small-kitchen, busy-restaurant, stress-test) or from -profiles")
+	profilesDir := flag.String("profiles", "profiles", "Directory of user-defined profiles, one name.json file each")
+	strict := flag.Bool("strict", false, "Reject unknown fields in the config and orders files, naming the field and line")
+	shuffle := flag.Bool("shuffle", false, "Place the orders file in a random order, seeded by -orders-seed")
+	seedFlag := flag.Int64("seed", 0, "Seed for pickup delays, courier positions and failures, as recorded in the run history; 0 picks one from the clock")
+	ordersSeed := flag.Uint64("orders-seed", 0, "Seed for shuffling or sampling the orders file, overriding the config; 0 keeps the configured one")
+	limit := flag.Int("limit", 0, "Place only the first N orders of the orders file, after any repeat and shuffle")
+	repeat := flag.Int("repeat", 0, "Place the orders file K times over, overriding the config")
+	watch := flag.Bool("watch", false, "Keep checking the orders file and place orders appended to it until the run ends")
+	idleTimeout := flag.Int("idle-timeout", 0, "End the run after this many seconds with no orders arriving and every shelf empty, counted from the start, overriding the config's stop idleFor")
+	failOnWasteRate := flag.Float64("fail-on-waste-rate", 0, "Exit with code 3 if more than this percentage of orders is wasted or expired, 0 to disable")
+	traceOrders := flag.String("trace-order", "", "Trace orders whose ID or name matches this pattern, as in path.Match: log every step of them even when -quiet, and serve their timelines at /orders/{id}/history")
+	manifestFile := flag.String("manifest", "", "Write the run manifest to this file, overriding the config")
+	planTarget := flag.Float64("plan", 0, "After the run, recommend shelf capacities wasting at most this percentage of orders, 0 to disable")
+	baselineFile := flag.String("baseline", "", "After the run, print how its stats moved from this manifest or history record")
+	tolerances := &toleranceFlags{}
+	flag.Var(tolerances, "tolerance", "Exit with code 6 if a metric regresses from the -baseline by more than metric=value, or metric=value% of the baseline, may be repeated")
+	tags := tagFlags{}
+	flag.Var(tags, "tag", "Run tag as key=value, may be repeated")
 	flag.Parse()
 
-	// Set random seed
-	rand.Seed(time.Now().UnixNano())
+	// Seed for the simulation's pickup delays, courier positions and
+	// failures, recorded in the run history and manifest so -seed can
+	// repeat the run
+	seed := *seedFlag
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
 
 	// Load configuration
-	cfg, err := config.LoadConfig(*configFile)
+	load := config.LoadConfig
+	if *strict {
+		load = config.LoadConfigStrict
+	}
+	cfg, err := load(*configFile)
 	if err != nil {
 		fmt.Printf("Error loading configuration: %v\n", err)
-		os.Exit(1)
+		return exitConfigError
+	}
+
+	if *profile != "" {
+		if err := cfg.ApplyProfile(*profile, *profilesDir); err != nil {
+			fmt.Printf("Error loading configuration: %v\n", err)
+			return exitConfigError
+		}
+	}
+
+	applyRunFlags(&cfg.Run, *runName, *description, tags)
+	if *service {
+		cfg.Service.Enabled = true
+	}
+	if *diagnostics {
+		cfg.Diagnostics = true
+	}
+	if *engineName != "" {
+		cfg.Engine = *engineName
+	}
+	applyOrdersFlags(&cfg.Orders, *shuffle, *ordersSeed, *limit, *repeat)
+	if *watch {
+		cfg.Orders.Watch = true
+	}
+	if *idleTimeout != 0 {
+		cfg.Stop.IdleFor, cfg.Stop.IdleFrom = *idleTimeout, config.StopIdleFromStart
+	}
+	if *manifestFile != "" {
+		cfg.ManifestFile = *manifestFile
+	}
+	if *traceOrders != "" {
+		cfg.Trace.Orders = *traceOrders
+	}
+	if cfg.Service.Enabled && *addr == "" {
+		fmt.Println("Service mode requires -addr")
+		return exitConfigError
+	}
+	if cfg.Memory.PoolOrders && *addr != "" {
+		// API reads may still hold an order when it is reused
+		fmt.Println("Order pooling is disabled while serving the control API")
+		cfg.Memory.PoolOrders = false
+	}
+
+	if *failOnWasteRate < 0 || *failOnWasteRate > 100 {
+		fmt.Println("-fail-on-waste-rate must be a percentage between 0 and 100")
+		return exitConfigError
+	}
+
+	assertions, err := history.ParseAssertions(cfg.Assertions)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return exitConfigError
+	}
+
+	var baseline *history.Summary
+	if *baselineFile != "" {
+		summary, err := history.LoadBaseline(*baselineFile)
+		if err != nil {
+			fmt.Printf("Error loading baseline: %v\n", err)
+			return exitConfigError
+		}
+		baseline = &summary
+	} else if len(tolerances.list) > 0 {
+		fmt.Println("-tolerance requires -baseline")
+		return exitConfigError
+	}
+
+	authenticator, err := api.NewAuthenticator(cfg.Auth)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return exitConfigError
+	}
+
+	serverTLS, err := tlsconfig.Server(cfg.TLS)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return exitConfigError
+	}
+	clientTLS, err := tlsconfig.Client(cfg.TLS)
+	if err != nil {
+		fmt.Printf("Error loading configuration: %v\n", err)
+		return exitConfigError
+	}
+
+	if cfg.Cluster.Mode == config.ClusterModeCoordinator {
+		return runCoordinator(*addr, serverTLS)
 	}
 
 	// Create simulator
-	sim, err := simulator.NewSimulator(cfg, *ordersFile)
+	engine, sim, err := newEngine(cfg, *ordersFile)
 	if err != nil {
 		fmt.Printf("Error creating simulator: %v\n", err)
-		os.Exit(1)
+		return exitConfigError
+	}
+	sim.Seed(uint64(seed))
+	sim.SetVerbose(!*quiet)
+
+	// Give each tenant a simulation of its own
+	var tenants *tenant.Group
+	if len(cfg.Service.Tenants) > 0 {
+		tenants, err = tenant.New(cfg, func(tcfg *config.Config) (*simulator.Simulator, error) {
+			tsim, err := newSimulator(tcfg, "")
+			if err != nil {
+				return nil, err
+			}
+			tsim.Seed(uint64(seed))
+			return tsim, nil
+		})
+		if err != nil {
+			fmt.Printf("Error creating tenants: %v\n", err)
+			return exitConfigError
+		}
+		tenants.SetVerbose(!*quiet)
+	}
+
+	// Serve the control API and dashboard until main returns
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var server *api.Server
+	if *addr != "" {
+		server = api.NewServer(sim.ShelfManager, sim.Events, sim.Archive)
+		server.SetRun(sim)
+		server.SetTimings(sim.Timings)
+		server.SetMetrics(sim)
+		server.SetHistory(sim)
+		server.SetOrderHistory(sim)
+		if cfg.Diagnostics {
+			server.EnableDiagnostics()
+		}
+		if cfg.Service.Enabled {
+			server.SetService(sim)
+		}
+		// Ready once the simulation is running, until it stops
+		sim.OnStart = func() { server.SetReady(true) }
+		sim.OnStop = func() { server.SetReady(false) }
+		handler := server.Handler()
+		if tenants != nil {
+			handler = tenants.Handler(handler, cfg.Service.RequireTenant)
+		}
+		if authenticator != nil {
+			handler = authenticator.Wrap(handler)
+		}
+		go func() {
+			if err := api.ListenAndServe(ctx, *addr, handler, serverTLS); err != nil {
+				fmt.Printf("Control API stopped: %v\n", err)
+			}
+		}()
+		fmt.Printf("Control API and dashboard listening on %s%s\n", *addr, tlsNote(serverTLS))
+		if tenants != nil {
+			fmt.Printf("Serving %d tenants\n", len(tenants.Tenants()))
+		}
+	}
+
+	// Accept external courier agents until main returns
+	if sim.Agents != nil {
+		go func() {
+			if err := sim.Agents.ListenAndServe(ctx, cfg.Couriers.AgentAddr, serverTLS); err != nil {
+				fmt.Printf("Courier agent service stopped: %v\n", err)
+			}
+		}()
+	}
+
+	// Report to the coordinator until main returns
+	reported := make(chan struct{})
+	if cfg.Cluster.Mode == config.ClusterModeNode {
+		nodeID := cfg.Cluster.NodeID
+		if nodeID == "" {
+			hostname, _ := os.Hostname()
+			nodeID = fmt.Sprintf("%s-%d", hostname, os.Getpid())
+		}
+		interval := time.Duration(cfg.Cluster.ReportInterval) * time.Second
+		reporter := cluster.NewReporter(nodeID, cfg.Cluster.Coordinator, interval, sim.ShelfManager)
+		reporter.RunName = cfg.Run.Name
+		reporter.Tags = cfg.Run.Tags
+		reporter.RunID = func() string { return sim.RunContext().ID }
+		if clientTLS != nil {
+			reporter.SetTLS(clientTLS)
+		}
+		go func() {
+			reporter.Run(ctx)
+			close(reported)
+		}()
+		defer func() {
+			cancel()
+			<-reported
+		}()
+		fmt.Printf("Reporting to coordinator %s as node %s\n", cfg.Cluster.Coordinator, nodeID)
 	}
 
+	// Take control commands from the console and signals until main returns
+	watchControlRequests(ctx, sim)
+
 	// Handle graceful shutdown
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -43,8 +296,12 @@ func main() {
 	// Run simulator in a separate goroutine if we need to handle interrupts
 	done := make(chan struct{})
 
+	started := time.Now()
+	if tenants != nil {
+		tenants.Start()
+	}
 	go func() {
-		sim.Run()
+		engine.Run()
 		close(done)
 	}()
 
@@ -54,10 +311,211 @@ func main() {
 	select {
 	case <-done:
 		// Simulation finished naturally, just exit
+		stopTenants(tenants, seed, started)
+		recordRun(cfg, sim, seed, started)
+		writeManifest(cfg, sim, *ordersFile, seed, started)
+		if *planTarget > 0 {
+			if err := printPlan(cfg, *ordersFile, *planTarget); err != nil {
+				fmt.Printf("Cannot plan capacities: %v\n", err)
+			}
+		}
+		if err := engine.Err(); err != nil {
+			fmt.Printf("Simulation failed: %v\n", err)
+			return exitAborted
+		}
+		if failsAssertions(sim, assertions) {
+			return exitAssertion
+		}
+		if regressesBaseline(sim, baseline, tolerances.list) {
+			return exitBaseline
+		}
+		if exceedsWasteRate(sim, *failOnWasteRate) {
+			return exitWasteRate
+		}
 		fmt.Println("Simulation completed successfully")
+		return exitSuccess
 	case <-stop:
 		fmt.Println("\nReceived interrupt signal, shutting down...")
-		sim.Stop()
+		drain(cfg, server)
+		engine.Stop()
+		stopTenants(tenants, seed, started)
+		recordRun(cfg, sim, seed, started)
+		writeManifest(cfg, sim, *ordersFile, seed, started)
 		fmt.Println("Shutdown complete")
+		return exitAborted
+	}
+}
+
+// stopTenants ends the tenants' simulations and records each run, tagged
+// with its tenant
+func stopTenants(tenants *tenant.Group, seed int64, started time.Time) {
+	if tenants == nil {
+		return
+	}
+	tenants.Stop()
+	for _, t := range tenants.Tenants() {
+		recordRun(t.Config, t.Sim, seed, started)
+	}
+}
+
+// drain fails readiness and, in service mode, waits the configured drain
+// delay so load balancers stop sending orders before the simulation stops
+func drain(cfg *config.Config, server *api.Server) {
+	if server == nil {
+		return
+	}
+	server.SetReady(false)
+	if !cfg.Service.Enabled || cfg.Service.DrainDelay <= 0 {
+		return
+	}
+	fmt.Printf("Draining for %ds...\n", cfg.Service.DrainDelay)
+	time.Sleep(time.Duration(cfg.Service.DrainDelay) * time.Second)
+}
+
+// tagFlags collects repeated -tag key=value flags
+type tagFlags map[string]string
+
+func (t tagFlags) String() string {
+	return config.RunConfig{Tags: t}.Label()
+}
+
+func (t tagFlags) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("tag %q must be key=value", value)
+	}
+	t[key] = val
+	return nil
+}
+
+// toleranceFlags collects repeated -tolerance metric=value flags
+type toleranceFlags struct {
+	list []history.Tolerance
+}
+
+func (t *toleranceFlags) String() string {
+	parts := make([]string, len(t.list))
+	for i, tol := range t.list {
+		parts[i] = fmt.Sprintf("%s=%g", tol.Metric, tol.Value)
+		if tol.Relative {
+			parts[i] += "%"
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func (t *toleranceFlags) Set(value string) error {
+	tol, err := history.ParseTolerance(value)
+	if err != nil {
+		return err
+	}
+	t.list = append(t.list, tol)
+	return nil
+}
+
+// applyRunFlags overrides the configured run metadata with any set flags.
+// Flag tags are added to the configured ones.
+func applyRunFlags(run *config.RunConfig, name, description string, tags tagFlags) {
+	if name != "" {
+		run.Name = name
+	}
+	if description != "" {
+		run.Description = description
+	}
+	if len(tags) > 0 && run.Tags == nil {
+		run.Tags = make(map[string]string, len(tags))
+	}
+	for k, v := range tags {
+		run.Tags[k] = v
+	}
+}
+
+// applyOrdersFlags overrides how the orders file is read with any set
+// flags
+func applyOrdersFlags(orders *config.OrdersConfig, shuffle bool, seed uint64, limit, repeat int) {
+	if shuffle {
+		orders.Shuffle = true
+	}
+	if seed != 0 {
+		orders.Seed = seed
+	}
+	if limit != 0 {
+		orders.Limit = limit
+	}
+	if repeat != 0 {
+		orders.Repeat = repeat
+	}
+}
+
+// runCoordinator serves aggregated cluster stats on addr until interrupted
+// and returns the exit code
+func runCoordinator(addr string, tlsConfig *tls.Config) int {
+	if addr == "" {
+		fmt.Println("Coordinator mode requires -addr")
+		return exitConfigError
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Cluster coordinator listening on %s%s\n", addr, tlsNote(tlsConfig))
+	if err := cluster.NewCoordinator().ListenAndServe(ctx, addr, tlsConfig); err != nil {
+		fmt.Printf("Coordinator stopped: %v\n", err)
+		return exitError
+	}
+	fmt.Println("Coordinator shut down")
+	return exitSuccess
+}
+
+// newEngine creates the configured simulation engine. The simulator it
+// returns holds the shelves, events and archive served over the API.
+func newEngine(cfg *config.Config, ordersFile string) (simulator.SimulationEngine, *simulator.Simulator, error) {
+	switch cfg.Engine {
+	case "", config.EngineRealtime:
+		sim, err := newSimulator(cfg, ordersFile)
+		return sim, sim, err
+	case config.EngineDiscrete:
+		if cfg.ShelfBackend != "" && cfg.ShelfBackend != config.ShelfBackendMemory {
+			return nil, nil, errors.New("the discrete engine only supports the memory shelf backend")
+		}
+		engine, err := simulator.NewDiscreteEngine(cfg, ordersFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		return engine, engine.Simulator, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown engine %q", cfg.Engine)
+	}
+}
+
+// newSimulator creates a simulator on the configured shelf backend
+func newSimulator(cfg *config.Config, ordersFile string) (*simulator.Simulator, error) {
+	switch cfg.ShelfBackend {
+	case "", config.ShelfBackendMemory:
+		return simulator.NewSimulator(cfg, ordersFile)
+	case config.ShelfBackendRedis:
+		if len(cfg.Shelves) > 0 {
+			return nil, errors.New("the redis shelf backend only supports the default shelves")
+		}
+		manager, err := redisshelf.New(redisshelf.Options{
+			Addr:             cfg.Redis.Addr,
+			Prefix:           cfg.Redis.Prefix,
+			HotCapacity:      cfg.HotShelfCapacity,
+			ColdCapacity:     cfg.ColdShelfCapacity,
+			FrozenCapacity:   cfg.FrozenShelfCapacity,
+			OverflowCapacity: cfg.OverflowCapacity,
+		})
+		if err != nil {
+			return nil, err
+		}
+		fmt.Printf("Using Redis shelf backend at %s\n", cfg.Redis.Addr)
+		sim, err := simulator.NewSimulatorWithManager(cfg, ordersFile, manager)
+		if err != nil {
+			return nil, err
+		}
+		manager.OnTransition = sim.ObserveTransition
+		return sim, nil
+	default:
+		return nil, fmt.Errorf("unknown shelf backend %q", cfg.ShelfBackend)
 	}
 }
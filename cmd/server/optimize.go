@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/optimize"
+)
+
+// runOptimize implements the optimize subcommand, searching shelf
+// capacities and courier counts with seeded discrete runs
+func runOptimize(args []string) error {
+	fs := flag.NewFlagSet("optimize", flag.ContinueOnError)
+	configFile := fs.String("config", "config.json", "Path to the configuration file searched around")
+	ordersFile := fs.String("orders", "orders.json", "Path to orders JSON file")
+	profile := fs.String("profile", "", "Profile overlaid on the config")
+	profilesDir := fs.String("profiles", "profiles", "Directory of user-defined profiles")
+	method := fs.String("method", "climb", "Search method, \"climb\" from the config or \"grid\" over every combination")
+	runs := fs.Int("runs", 3, "Seeded runs averaged for each configuration")
+	seed := fs.Uint64("seed", 1, "Seed of the first run; the others follow it")
+	parallel := fs.Int("parallel", runtime.NumCPU(), "Simulations run at once")
+	duration := fs.Int("duration", 0, "Simulated seconds per run, overriding the config")
+	minCapacity := fs.Int("min-capacity", 0, "Smallest capacity tried for each shelf")
+	maxCapacity := fs.Int("max-capacity", 0, "Largest capacity tried for each shelf, 0 for twice the largest configured")
+	minCouriers := fs.Int("min-couriers", -1, "Fewest couriers tried, -1 for the configured count")
+	maxCouriers := fs.Int("max-couriers", -1, "Most couriers tried, -1 for the configured count")
+	step := fs.Int("step", 1, "Difference between the capacities and courier counts tried")
+	shelfCost := fs.Float64("shelf-cost", 1, "Cost of each unit of shelf capacity")
+	courierCost := fs.Float64("courier-cost", 10, "Cost of each courier")
+	budget := fs.Float64("budget", 0, "Most a configuration may cost, 0 for no limit")
+	out := fs.String("out", "", "Write the best configuration found to this file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if *profile != "" {
+		if err := cfg.ApplyProfile(*profile, *profilesDir); err != nil {
+			return err
+		}
+	}
+	if *duration > 0 {
+		cfg.SimulationDuration = *duration
+	}
+	if *runs <= 0 {
+		return fmt.Errorf("-runs must be positive, got %d", *runs)
+	}
+	if *parallel <= 0 {
+		return fmt.Errorf("-parallel must be positive, got %d", *parallel)
+	}
+
+	start := optimize.Start(cfg)
+	space := optimize.Space{
+		MinCapacity: *minCapacity,
+		MaxCapacity: *maxCapacity,
+		MinCouriers: *minCouriers,
+		MaxCouriers: *maxCouriers,
+		Step:        *step,
+	}
+	if space.MaxCapacity == 0 {
+		space.MaxCapacity = max(2*slices.Max(start.Capacities), space.MinCapacity)
+	}
+	if space.MinCouriers < 0 {
+		space.MinCouriers = start.Couriers
+	}
+	if space.MaxCouriers < 0 {
+		space.MaxCouriers = max(start.Couriers, space.MinCouriers)
+	}
+	costs := optimize.Costs{Shelf: *shelfCost, Courier: *courierCost, Budget: *budget}
+
+	// The seeds of a candidate take the workers first, and the candidates
+	// evaluated at once share what is left, so about -parallel run at once
+	runner := optimize.Runner{Base: cfg, OrdersFile: *ordersFile, Workers: min(*parallel, *runs)}
+	for i := range *runs {
+		runner.Seeds = append(runner.Seeds, *seed+uint64(i))
+	}
+	workers := max(*parallel/runner.Workers, 1)
+
+	shelves := optimize.Shelves(cfg)
+	fmt.Printf("Searching %s by %s, %d runs each, %d at once...\n", describeSpace(shelves, space), *method, *runs, runner.Workers*workers)
+	var best optimize.Result
+	var tried int
+	switch *method {
+	case "climb":
+		// Start inside the space, so the configured values may lie outside it
+		for i, capacity := range start.Capacities {
+			start.Capacities[i] = min(max(capacity, space.MinCapacity), space.MaxCapacity)
+		}
+		start.Couriers = min(max(start.Couriers, space.MinCouriers), space.MaxCouriers)
+		// Simulations print their progress and stats; only the search's matter
+		quietly(func() { best, tried, err = optimize.Climb(space, start, costs, runner.Evaluate, workers) })
+	case "grid":
+		quietly(func() { best, tried, err = optimize.Grid(space, len(shelves), costs, runner.Evaluate, workers) })
+	default:
+		return fmt.Errorf("unknown method %q", *method)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Tried %d configurations\n", tried)
+	fmt.Printf("Best: %s, waste %.1f%%, cost %.1f\n", describeCandidate(shelves, best.Candidate), best.Waste, best.Cost)
+	if *out == "" {
+		return nil
+	}
+	raw, err := json.MarshalIndent(optimize.Apply(cfg, best.Candidate), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out, append(raw, '\n'), 0o644); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote the best configuration to %s\n", *out)
+	return nil
+}
+
+// quietly runs f with the standard output discarded
+func quietly(f func()) {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		f()
+		return
+	}
+	defer null.Close()
+
+	stdout := os.Stdout
+	os.Stdout = null
+	defer func() { os.Stdout = stdout }()
+	f()
+}
+
+func describeSpace(shelves []string, space optimize.Space) string {
+	s := fmt.Sprintf("%s capacities %d-%d", strings.Join(shelves, "/"), space.MinCapacity, space.MaxCapacity)
+	if space.MinCouriers != space.MaxCouriers {
+		s += fmt.Sprintf(" and %d-%d couriers", space.MinCouriers, space.MaxCouriers)
+	}
+	return s
+}
+
+func describeCandidate(shelves []string, c optimize.Candidate) string {
+	parts := make([]string, 0, len(shelves)+1)
+	for i, name := range shelves {
+		parts = append(parts, fmt.Sprintf("%s=%d", name, c.Capacities[i]))
+	}
+	parts = append(parts, fmt.Sprintf("couriers=%d", c.Couriers))
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/simulator"
+)
+
+// runPlan implements the plan subcommand, recommending shelf capacities
+// without running the simulation
+func runPlan(args []string) error {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	configFile := fs.String("config", "config.json", "Path to configuration file with the order rate and couriers")
+	ordersFile := fs.String("orders", "orders.json", "Path to orders JSON file")
+	profile := fs.String("profile", "", "Profile overlaid on the config")
+	profilesDir := fs.String("profiles", "profiles", "Directory of user-defined profiles")
+	target := fs.Float64("target", 5, "Percentage of orders that may be wasted for want of shelf room")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if *profile != "" {
+		if err := cfg.ApplyProfile(*profile, *profilesDir); err != nil {
+			return err
+		}
+	}
+	return printPlan(cfg, *ordersFile, *target)
+}
+
+// printPlan prints the capacity plan for the orders file under cfg
+func printPlan(cfg *config.Config, ordersFile string, target float64) error {
+	orders, err := simulator.LoadOrdersFromFile(ordersFile)
+	if err != nil {
+		return fmt.Errorf("failed to load orders: %w", err)
+	}
+	plan, err := simulator.PlanCapacity(cfg, orders, target)
+	if err != nil {
+		return err
+	}
+	plan.Print(os.Stdout)
+	return nil
+}
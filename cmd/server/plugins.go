@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"dish-dispatcher/plugin"
+)
+
+// Plugin modules are linked in by blank imports in this block, such as
+// _ "example.com/kitchen/fastest-courier", and a rebuild
+import ()
+
+// runPlugins implements the plugins subcommand, listing the registered
+// plugin names selectable in config
+func runPlugins(args []string) error {
+	placement, dispatch, admission, sinks := plugin.Registered()
+	fmt.Printf("Placement strategies: %s\n", listOrNone(placement))
+	fmt.Printf("Dispatch strategies: %s\n", listOrNone(dispatch))
+	fmt.Printf("Admission policies: %s\n", listOrNone(admission))
+	fmt.Printf("Event sinks: %s\n", listOrNone(sinks))
+	return nil
+}
+
+func listOrNone(names []string) string {
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package main
+
+// signalCommands run control commands; there are no suitable signals on
+// this platform, so use the console instead
+var signalCommands []signalCommand
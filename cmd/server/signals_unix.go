@@ -0,0 +1,13 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+// signalCommands run control commands, so headless runs can be inspected
+// with kill
+var signalCommands = []signalCommand{
+	{syscall.SIGUSR1, "SIGUSR1", "stats"},
+	{syscall.SIGUSR2, "SIGUSR2", "stacks"},
+	{syscall.SIGHUP, "SIGHUP", "verbose"},
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/tlsconfig"
+)
+
+// tlsNote describes the transport of a listener for its startup line
+func tlsNote(tlsConfig *tls.Config) string {
+	switch {
+	case tlsConfig == nil:
+		return ""
+	case tlsConfig.ClientAuth == tls.RequireAndVerifyClientCert:
+		return " (TLS, client certificates required)"
+	default:
+		return " (TLS)"
+	}
+}
+
+// clientTLSFlags adds the flags of a subcommand connecting over TLS. The
+// returned function builds the client config once the flags are parsed,
+// or returns nil if none was set.
+func clientTLSFlags(fs *flag.FlagSet) func() (*tls.Config, error) {
+	ca := fs.String("ca", "", "PEM file of the CAs trusted for the dispatcher's certificate, instead of the system roots")
+	cert := fs.String("cert", "", "PEM client certificate, for a dispatcher requiring one")
+	key := fs.String("key", "", "PEM private key of -cert")
+	return func() (*tls.Config, error) {
+		return tlsconfig.Client(config.TLSConfig{CAFile: *ca, CertFile: *cert, KeyFile: *key})
+	}
+}
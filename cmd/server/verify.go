@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/simulator"
+)
+
+// defaultVerifyTolerances are how far the discrete engine may stray from
+// the real-time run when no -tolerance is given
+var defaultVerifyTolerances = []history.Tolerance{
+	{Metric: "deliveryRate", Value: 2},
+	{Metric: "wasteRate", Value: 2},
+	{Metric: "avgValue", Value: 5, Relative: true},
+}
+
+// runVerify implements the verify subcommand, checking that the discrete
+// engine's fast-forwarded results can be trusted: it runs the config with
+// the same seed on the discrete engine and in real time, and fails if their
+// final stats are further apart than the tolerances
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	configFile := fs.String("config", "config.json", "Path to the configuration file verified")
+	ordersFile := fs.String("orders", "orders.json", "Path to orders JSON file")
+	profile := fs.String("profile", "", "Profile overlaid on the config")
+	profilesDir := fs.String("profiles", "profiles", "Directory of user-defined profiles")
+	seed := fs.Uint64("seed", 1, "Seed of both runs")
+	duration := fs.Int("duration", 0, "Seconds to run, overriding the config; the real-time run takes as long")
+	tolerances := &toleranceFlags{}
+	fs.Var(tolerances, "tolerance", "Fail if a metric of the two runs differs by more than metric=value, or metric=value% of the real-time value, may be repeated; defaults to deliveryRate=2, wasteRate=2 and avgValue=5%")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if *profile != "" {
+		if err := cfg.ApplyProfile(*profile, *profilesDir); err != nil {
+			return err
+		}
+	}
+	if *duration > 0 {
+		cfg.SimulationDuration = *duration
+	}
+	if cfg.ShelfBackend != "" && cfg.ShelfBackend != config.ShelfBackendMemory {
+		return errors.New("verify compares with the discrete engine, which only supports the memory shelf backend")
+	}
+	cfg.Orders.Seed = *seed
+	// Both runs are only measured, and would otherwise write the same files
+	cfg.HistoryFile = ""
+	cfg.ManifestFile = ""
+	cfg.EventLog.File = ""
+	cfg.TimeSeries.File = ""
+	cfg.Results.File = ""
+	if len(tolerances.list) == 0 {
+		tolerances.list = defaultVerifyTolerances
+	}
+
+	discreteCfg, realtimeCfg := *cfg, *cfg
+	discreteCfg.Engine, realtimeCfg.Engine = config.EngineDiscrete, config.EngineRealtime
+
+	engine, err := simulator.NewDiscreteEngine(&discreteCfg, *ordersFile)
+	if err != nil {
+		return err
+	}
+	if ignored := engine.Ignored(); len(ignored) > 0 {
+		fmt.Printf("⚠️ Not modelled by the discrete engine, so the runs may differ: %s\n", strings.Join(ignored, ", "))
+	}
+	engine.Seed(*seed)
+	engine.SetVerbose(false)
+	began := time.Now()
+	quietly(engine.Run)
+	if err := engine.Err(); err != nil {
+		return fmt.Errorf("discrete run failed: %w", err)
+	}
+	fmt.Printf("Discrete run finished in %s\n", time.Since(began).Round(time.Millisecond))
+
+	sim, err := simulator.NewSimulator(&realtimeCfg, *ordersFile)
+	if err != nil {
+		return err
+	}
+	sim.Seed(*seed)
+	sim.SetVerbose(false)
+	if cfg.SimulationDuration > 0 {
+		fmt.Printf("Running in real time for up to %d seconds...\n", cfg.SimulationDuration)
+	} else {
+		fmt.Println("Running in real time until the orders run out...")
+	}
+	began = time.Now()
+	quietly(sim.Run)
+	if err := sim.Err(); err != nil {
+		return fmt.Errorf("real-time run failed: %w", err)
+	}
+	fmt.Printf("Real-time run finished in %s\n", time.Since(began).Round(time.Millisecond))
+
+	deltas := history.DiffRuns(runSummary(sim), runSummary(engine.Simulator), tolerances.list)
+	fmt.Println("Real time -> discrete:")
+	for _, d := range deltas {
+		fmt.Printf("  %s\n", d)
+	}
+	if apart := history.Regressed(deltas); len(apart) > 0 {
+		return fmt.Errorf("%d of %d metrics differ past their tolerance", len(apart), len(deltas))
+	}
+	fmt.Println("The discrete engine matches the real-time run")
+	return nil
+}
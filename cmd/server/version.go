@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"dish-dispatcher/internal/buildinfo"
+)
+
+// runVersion implements the version subcommand, identifying the binary
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "Print the build information as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	info := buildinfo.Get()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	fmt.Printf("dish-dispatcher %s\n", info.Version)
+	if info.Commit != "" {
+		fmt.Printf("Commit: %s\n", info.Commit)
+	}
+	if info.Date != "" {
+		fmt.Printf("Built: %s\n", info.Date)
+	}
+	fmt.Printf("Go: %s\n", info.GoVersion)
+	return nil
+}
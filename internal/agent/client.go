@@ -0,0 +1,104 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net/http"
+
+	"dish-dispatcher/internal/grpcwire"
+)
+
+// Client calls the Couriers service of a dispatcher, for agents written in
+// Go and for tests
+type Client struct {
+	base string
+	http *http.Client
+}
+
+// NewClient creates a client for the dispatcher accepting agents on addr,
+// such as localhost:9090
+func NewClient(addr string) *Client {
+	return &Client{
+		base: "http://" + addr,
+		http: &http.Client{Transport: &http.Transport{Protocols: grpcwire.Protocols()}},
+	}
+}
+
+// NewTLSClient creates a client for a dispatcher serving agents over TLS,
+// connecting with tlsConfig, or the defaults if it is nil
+func NewTLSClient(addr string, tlsConfig *tls.Config) *Client {
+	return &Client{
+		base: "https://" + addr,
+		http: &http.Client{Transport: &http.Transport{Protocols: grpcwire.Protocols(), TLSClientConfig: tlsConfig}},
+	}
+}
+
+// Stream receives the dispatches sent to a connected agent
+type Stream struct {
+	resp *http.Response
+}
+
+// Connect registers the agent under courierID. The agent stays connected
+// until ctx is cancelled or the stream is closed.
+func (c *Client) Connect(ctx context.Context, courierID string) (*Stream, error) {
+	resp, err := c.call(ctx, "Connect", ConnectRequest{CourierID: courierID}.marshal())
+	if err != nil {
+		return nil, err
+	}
+	// A refused connection ends at once, with its status in the headers
+	if resp.Header.Get("Grpc-Status") != "" {
+		defer resp.Body.Close()
+		io.Copy(io.Discard, resp.Body)
+		return nil, grpcwire.ReadStatus(resp)
+	}
+	return &Stream{resp: resp}, nil
+}
+
+// Recv waits for the next dispatch. It returns the call's status error,
+// or io.EOF if the dispatcher ended the stream cleanly.
+func (s *Stream) Recv() (Dispatch, error) {
+	msg, err := grpcwire.ReadMessage(s.resp.Body)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			if err := grpcwire.ReadStatus(s.resp); err != nil {
+				return Dispatch{}, err
+			}
+		}
+		return Dispatch{}, err
+	}
+	var d Dispatch
+	return d, d.unmarshal(msg)
+}
+
+// Close disconnects the agent
+func (s *Stream) Close() error {
+	return s.resp.Body.Close()
+}
+
+// ReportPickup collects a dispatched order. The reply is not OK if the
+// order was gone, which frees the courier for another dispatch.
+func (c *Client) ReportPickup(ctx context.Context, r Report) (ReportReply, error) {
+	return c.report(ctx, "ReportPickup", r)
+}
+
+// ReportDelivery hands a collected order to its customer, freeing the
+// courier for another dispatch
+func (c *Client) ReportDelivery(ctx context.Context, r Report) (ReportReply, error) {
+	return c.report(ctx, "ReportDelivery", r)
+}
+
+func (c *Client) report(ctx context.Context, method string, r Report) (ReportReply, error) {
+	msg, err := grpcwire.Invoke(ctx, c.http, c.base+servicePath+method, r.marshal())
+	if err != nil {
+		return ReportReply{}, err
+	}
+	var reply ReportReply
+	return reply, reply.unmarshal(msg)
+}
+
+// call starts a call with a single request message
+func (c *Client) call(ctx context.Context, method string, msg []byte) (*http.Response, error) {
+	return grpcwire.Call(ctx, c.http, c.base+servicePath+method, msg)
+}
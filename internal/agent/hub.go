@@ -0,0 +1,343 @@
+// Package agent lets couriers outside the process, real or simulated,
+// connect to the dispatcher over gRPC. Each agent holds a stream open to
+// receive dispatches and reports back when it collects and delivers each
+// order, so the dispatcher can be tested against actual courier software.
+//
+// The service is described by couriers.proto. It is served with grpcwire,
+// over unencrypted HTTP/2 or TLS.
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/grpcwire"
+	"dish-dispatcher/internal/order"
+)
+
+// Dispatcher is the dispatcher core as seen by courier agents. The hub
+// calls it without holding its own lock.
+type Dispatcher interface {
+	// PickUp takes the order off its shelf and returns its value, or false
+	// if the order is no longer shelved
+	PickUp(o *order.Order) (float64, bool)
+
+	// HandOff records the collected order reaching the customer and
+	// returns its value at handoff
+	HandOff(o *order.Order, pickupValue float64) float64
+}
+
+// Stats counts what connected agents have done
+type Stats struct {
+	Connected  int `json:"connected"`
+	Dispatched int `json:"dispatched"`
+	PickedUp   int `json:"pickedUp"`
+	Missed     int `json:"missed"` // orders gone before the courier collected them
+	Delivered  int `json:"delivered"`
+	Abandoned  int `json:"abandoned"` // collected orders whose agent disconnected
+}
+
+// courierAgent is one connected agent
+type courierAgent struct {
+	id         string
+	dispatches chan Dispatch
+	deliveries int
+
+	// order is the dispatched order, nil while the agent is idle
+	order       *order.Order
+	pickedUp    bool
+	pickupValue float64
+}
+
+// Hub dispatches orders to connected agents. It is safe for concurrent use.
+type Hub struct {
+	dispatcher Dispatcher
+
+	mutex    sync.Mutex
+	agents   map[string]*courierAgent
+	assigned map[string]*courierAgent // by order ID
+	stats    Stats
+}
+
+// NewHub creates a hub with no agents connected
+func NewHub(dispatcher Dispatcher) *Hub {
+	return &Hub{
+		dispatcher: dispatcher,
+		agents:     make(map[string]*courierAgent),
+		assigned:   make(map[string]*courierAgent),
+	}
+}
+
+// Idle returns how many connected agents are free
+func (h *Hub) Idle() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return len(h.idle())
+}
+
+// idle returns the free agents, fewest deliveries first
+func (h *Hub) idle() []*courierAgent {
+	var idle []*courierAgent
+	for _, a := range h.agents {
+		if a.order == nil {
+			idle = append(idle, a)
+		}
+	}
+	sort.Slice(idle, func(i, j int) bool {
+		if idle[i].deliveries != idle[j].deliveries {
+			return idle[i].deliveries < idle[j].deliveries
+		}
+		return idle[i].id < idle[j].id
+	})
+	return idle
+}
+
+// Assign dispatches the order to the free agent with the fewest deliveries
+// and returns false if every agent is busy or one is already on its way
+func (h *Hub) Assign(o *order.Order, now time.Time) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.assigned[o.ID]; ok {
+		return false
+	}
+	idle := h.idle()
+	if len(idle) == 0 {
+		return false
+	}
+
+	a := idle[0]
+	a.order, a.pickedUp = o, false
+	h.assigned[o.ID] = a
+	h.stats.Dispatched++
+	// An idle agent's stream has taken its last dispatch, so this never
+	// blocks
+	a.dispatches <- Dispatch{OrderID: o.ID, Name: o.Name, Temp: string(o.Temp), Value: o.CalculateValue(now)}
+	return true
+}
+
+// Stats returns a copy of the hub's counters
+func (h *Hub) Stats() Stats {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	stats := h.stats
+	stats.Connected = len(h.agents)
+	return stats
+}
+
+// connect registers an agent
+func (h *Hub) connect(id string) (*courierAgent, error) {
+	if id == "" {
+		return nil, grpcwire.Errorf(grpcwire.CodeInvalidArgument, "courier ID is required")
+	}
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if _, ok := h.agents[id]; ok {
+		return nil, grpcwire.Errorf(grpcwire.CodeAlreadyExists, "courier %q is already connected", id)
+	}
+	a := &courierAgent{id: id, dispatches: make(chan Dispatch, 1)}
+	h.agents[id] = a
+	return a, nil
+}
+
+// disconnect removes an agent. An order it had not collected waits for the
+// next courier; one it had collected is lost.
+func (h *Hub) disconnect(a *courierAgent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	delete(h.agents, a.id)
+	if a.order != nil {
+		delete(h.assigned, a.order.ID)
+		if a.pickedUp {
+			h.stats.Abandoned++
+		}
+	}
+}
+
+// reported returns the agent's order if it matches the report
+func (h *Hub) reported(r Report, wantPickedUp bool) (*courierAgent, *order.Order, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	a, ok := h.agents[r.CourierID]
+	if !ok {
+		return nil, nil, grpcwire.Errorf(grpcwire.CodeNotFound, "courier %q is not connected", r.CourierID)
+	}
+	if a.order == nil || a.order.ID != r.OrderID {
+		return nil, nil, grpcwire.Errorf(grpcwire.CodeFailedPrecondition, "order %q is not dispatched to courier %q", r.OrderID, r.CourierID)
+	}
+	if a.pickedUp != wantPickedUp {
+		if a.pickedUp {
+			return nil, nil, grpcwire.Errorf(grpcwire.CodeFailedPrecondition, "order %q is already collected", r.OrderID)
+		}
+		return nil, nil, grpcwire.Errorf(grpcwire.CodeFailedPrecondition, "order %q is not collected yet", r.OrderID)
+	}
+	return a, a.order, nil
+}
+
+// pickUp handles ReportPickup
+func (h *Hub) pickUp(r Report) (ReportReply, error) {
+	a, o, err := h.reported(r, false)
+	if err != nil {
+		return ReportReply{}, err
+	}
+	value, ok := h.dispatcher.PickUp(o)
+
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if !ok {
+		h.stats.Missed++
+		h.release(a, o)
+		return ReportReply{}, nil
+	}
+	h.stats.PickedUp++
+	if a.order == o {
+		a.pickedUp, a.pickupValue = true, value
+	} else {
+		// The agent disconnected while the order was collected
+		h.stats.Abandoned++
+	}
+	return ReportReply{OK: true, Value: value}, nil
+}
+
+// deliver handles ReportDelivery
+func (h *Hub) deliver(r Report) (ReportReply, error) {
+	a, o, err := h.reported(r, true)
+	if err != nil {
+		return ReportReply{}, err
+	}
+
+	h.mutex.Lock()
+	pickupValue := a.pickupValue
+	a.deliveries++
+	h.stats.Delivered++
+	h.release(a, o)
+	h.mutex.Unlock()
+
+	return ReportReply{OK: true, Value: h.dispatcher.HandOff(o, pickupValue)}, nil
+}
+
+// release frees the agent of its order. Callers hold the hub lock.
+func (h *Hub) release(a *courierAgent, o *order.Order) {
+	if a.order == o {
+		a.order, a.pickedUp, a.pickupValue = nil, false, 0
+	}
+	if h.assigned[o.ID] == a {
+		delete(h.assigned, o.ID)
+	}
+}
+
+// servicePath prefixes the path of every method of the Couriers service
+const servicePath = "/dishdispatcher.courier.v1.Couriers/"
+
+// ServeHTTP serves the Couriers service
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !grpcwire.CheckCall(w, r) {
+		return
+	}
+
+	var err error
+	switch r.URL.Path {
+	case servicePath + "Connect":
+		err = h.serveConnect(w, r)
+	case servicePath + "ReportPickup":
+		err = serveReport(w, r, h.pickUp)
+	case servicePath + "ReportDelivery":
+		err = serveReport(w, r, h.deliver)
+	default:
+		err = grpcwire.Errorf(grpcwire.CodeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+	grpcwire.WriteStatus(w, err)
+}
+
+// serveConnect registers the agent and streams its dispatches until it
+// disconnects
+func (h *Hub) serveConnect(w http.ResponseWriter, r *http.Request) error {
+	var req ConnectRequest
+	if err := grpcwire.ReadRequest(r, req.unmarshal); err != nil {
+		return err
+	}
+	a, err := h.connect(req.CourierID)
+	if err != nil {
+		return err
+	}
+	defer h.disconnect(a)
+
+	// Send the headers now, so the agent knows it is connected
+	flusher := w.(http.Flusher)
+	grpcwire.StartResponse(w)
+	flusher.Flush()
+
+	for {
+		select {
+		case d := <-a.dispatches:
+			if err := grpcwire.WriteMessage(w, d.marshal()); err != nil {
+				return err
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return grpcwire.Errorf(grpcwire.CodeCanceled, "agent disconnected")
+		}
+	}
+}
+
+// serveReport handles a unary report call
+func serveReport(w http.ResponseWriter, r *http.Request, handle func(Report) (ReportReply, error)) error {
+	var req Report
+	if err := grpcwire.ReadRequest(r, req.unmarshal); err != nil {
+		return err
+	}
+	reply, err := handle(req)
+	if err != nil {
+		return err
+	}
+	return grpcwire.WriteReply(w, reply.marshal())
+}
+
+// ListenAndServe serves agents on addr until ctx is cancelled. Open streams
+// are closed on shutdown, since they never end on their own. A non-nil
+// tlsConfig serves gRPC over TLS.
+func (h *Hub) ListenAndServe(ctx context.Context, addr string, tlsConfig *tls.Config) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return h.Serve(ctx, listener, tlsConfig)
+}
+
+// Serve serves agents on listener until ctx is cancelled
+func (h *Hub) Serve(ctx context.Context, listener net.Listener, tlsConfig *tls.Config) error {
+	httpServer := &http.Server{Handler: h, Protocols: grpcwire.Protocols(), TLSConfig: tlsConfig}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			errCh <- httpServer.ServeTLS(listener, "", "")
+			return
+		}
+		errCh <- httpServer.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		httpServer.Close()
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
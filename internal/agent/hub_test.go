@@ -0,0 +1,205 @@
+package agent_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/agent"
+	"dish-dispatcher/internal/grpcwire"
+	"dish-dispatcher/internal/order"
+)
+
+// fakeDispatcher holds shelved orders at a fixed value
+type fakeDispatcher struct {
+	mutex     sync.Mutex
+	shelved   map[string]bool
+	handedOff []string
+}
+
+func (d *fakeDispatcher) PickUp(o *order.Order) (float64, bool) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if !d.shelved[o.ID] {
+		return 0, false
+	}
+	delete(d.shelved, o.ID)
+	return 0.9, true
+}
+
+func (d *fakeDispatcher) HandOff(o *order.Order, pickupValue float64) float64 {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.handedOff = append(d.handedOff, o.ID)
+	return pickupValue - 0.1
+}
+
+func newHub(t *testing.T, orders ...*order.Order) (*agent.Hub, *fakeDispatcher, *agent.Client) {
+	t.Helper()
+
+	d := &fakeDispatcher{shelved: make(map[string]bool)}
+	for _, o := range orders {
+		d.shelved[o.ID] = true
+	}
+	hub := agent.NewHub(d)
+
+	ts := httptest.NewUnstartedServer(hub)
+	ts.Config.Protocols = grpcwire.Protocols()
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	return hub, d, agent.NewClient(strings.TrimPrefix(ts.URL, "http://"))
+}
+
+// connect connects an agent and waits until the hub has registered it
+func connect(t *testing.T, hub *agent.Hub, client *agent.Client, id string) *agent.Stream {
+	t.Helper()
+
+	before := hub.Stats().Connected
+	stream, err := client.Connect(context.Background(), id)
+	require.NoError(t, err)
+	t.Cleanup(func() { stream.Close() })
+	require.Eventually(t, func() bool { return hub.Stats().Connected > before }, time.Second, time.Millisecond)
+	return stream
+}
+
+func TestHub_DispatchPickupDelivery(t *testing.T) {
+	burger := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	hub, d, client := newHub(t, burger)
+	ctx := context.Background()
+
+	assert.False(t, hub.Assign(burger, time.Now()), "no agents connected")
+
+	stream := connect(t, hub, client, "courier-1")
+	assert.Equal(t, 1, hub.Idle())
+	require.True(t, hub.Assign(burger, time.Now()))
+	assert.False(t, hub.Assign(burger, time.Now()), "already dispatched")
+	assert.Equal(t, 0, hub.Idle())
+
+	dispatch, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, burger.ID, dispatch.OrderID)
+	assert.Equal(t, "Burger", dispatch.Name)
+	assert.Equal(t, "hot", dispatch.Temp)
+	assert.InDelta(t, 1, dispatch.Value, 0.01)
+
+	report := agent.Report{CourierID: "courier-1", OrderID: burger.ID}
+	_, err = client.ReportDelivery(ctx, report)
+	assertCode(t, grpcwire.CodeFailedPrecondition, err)
+
+	reply, err := client.ReportPickup(ctx, report)
+	require.NoError(t, err)
+	assert.True(t, reply.OK)
+	assert.InDelta(t, 0.9, reply.Value, 1e-9)
+
+	reply, err = client.ReportDelivery(ctx, report)
+	require.NoError(t, err)
+	assert.True(t, reply.OK)
+	assert.InDelta(t, 0.8, reply.Value, 1e-9)
+
+	assert.Equal(t, []string{burger.ID}, d.handedOff)
+	assert.Equal(t, 1, hub.Idle())
+	assert.Equal(t, agent.Stats{Connected: 1, Dispatched: 1, PickedUp: 1, Delivered: 1}, hub.Stats())
+}
+
+func TestHub_MissedPickupFreesAgent(t *testing.T) {
+	gone := order.NewOrder("Salad", order.Cold, 300, 0.5)
+	hub, _, client := newHub(t) // never shelved
+
+	stream := connect(t, hub, client, "courier-1")
+	require.True(t, hub.Assign(gone, time.Now()))
+	_, err := stream.Recv()
+	require.NoError(t, err)
+
+	reply, err := client.ReportPickup(context.Background(), agent.Report{CourierID: "courier-1", OrderID: gone.ID})
+	require.NoError(t, err)
+	assert.False(t, reply.OK)
+	assert.Equal(t, 1, hub.Idle())
+	assert.Equal(t, 1, hub.Stats().Missed)
+}
+
+func TestHub_DisconnectReturnsOrder(t *testing.T) {
+	burger := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	hub, _, client := newHub(t, burger)
+
+	stream := connect(t, hub, client, "courier-1")
+	require.True(t, hub.Assign(burger, time.Now()))
+	require.NoError(t, stream.Close())
+	require.Eventually(t, func() bool { return hub.Stats().Connected == 0 }, time.Second, time.Millisecond)
+
+	// The order was never collected, so the next agent can take it
+	connect(t, hub, client, "courier-2")
+	assert.True(t, hub.Assign(burger, time.Now()))
+}
+
+func TestHub_Errors(t *testing.T) {
+	hub, _, client := newHub(t)
+	ctx := context.Background()
+
+	connect(t, hub, client, "courier-1")
+	_, err := client.Connect(ctx, "courier-1")
+	assertCode(t, grpcwire.CodeAlreadyExists, err)
+
+	_, err = client.Connect(ctx, "")
+	assertCode(t, grpcwire.CodeInvalidArgument, err)
+
+	_, err = client.ReportPickup(ctx, agent.Report{CourierID: "nobody", OrderID: "x"})
+	assertCode(t, grpcwire.CodeNotFound, err)
+
+	_, err = client.ReportPickup(ctx, agent.Report{CourierID: "courier-1", OrderID: "x"})
+	assertCode(t, grpcwire.CodeFailedPrecondition, err)
+}
+
+func TestHub_TLS(t *testing.T) {
+	burger := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	hub := agent.NewHub(&fakeDispatcher{shelved: map[string]bool{burger.ID: true}})
+
+	// Borrow a certificate for 127.0.0.1 from httptest
+	certs := httptest.NewTLSServer(nil)
+	serverTLS := &tls.Config{Certificates: certs.TLS.Certificates}
+	roots := x509.NewCertPool()
+	roots.AddCert(certs.Certificate())
+	certs.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- hub.Serve(ctx, listener, serverTLS) }()
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-served)
+	})
+
+	client := agent.NewTLSClient(listener.Addr().String(), &tls.Config{RootCAs: roots})
+	stream := connect(t, hub, client, "courier-1")
+	require.True(t, hub.Assign(burger, time.Now()))
+	dispatch, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, burger.ID, dispatch.OrderID)
+
+	// Agents without TLS are refused
+	_, err = agent.NewClient(listener.Addr().String()).Connect(context.Background(), "courier-2")
+	assert.Error(t, err)
+}
+
+func assertCode(t *testing.T, want int, err error) {
+	t.Helper()
+
+	var status *grpcwire.StatusError
+	if assert.True(t, errors.As(err, &status), "error %v is not a status", err) {
+		assert.Equal(t, want, status.Code, status.Message)
+	}
+}
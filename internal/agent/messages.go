@@ -0,0 +1,102 @@
+package agent
+
+import "dish-dispatcher/internal/grpcwire"
+
+// The messages of couriers.proto. Each encodes itself in the protobuf wire
+// format with the grpcwire helpers.
+
+// ConnectRequest registers an agent under a courier ID of its choosing
+type ConnectRequest struct {
+	CourierID string // field 1
+}
+
+// Dispatch sends a connected agent to collect an order
+type Dispatch struct {
+	OrderID string  // field 1
+	Name    string  // field 2
+	Temp    string  // field 3
+	Value   float64 // field 4, the order's value when dispatched
+}
+
+// Report is an agent telling the dispatcher it collected or delivered an
+// order
+type Report struct {
+	CourierID string // field 1
+	OrderID   string // field 2
+}
+
+// ReportReply answers a Report. OK is false if the order was gone by the
+// time the courier reached the shelf.
+type ReportReply struct {
+	OK    bool    // field 1
+	Value float64 // field 2, the order's value at pickup or delivery
+}
+
+func (m ConnectRequest) marshal() []byte {
+	return grpcwire.AppendString(nil, 1, m.CourierID)
+}
+
+func (m *ConnectRequest) unmarshal(b []byte) error {
+	return grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		if field == 1 {
+			m.CourierID = f.String()
+		}
+		return nil
+	})
+}
+
+func (m Dispatch) marshal() []byte {
+	var b []byte
+	b = grpcwire.AppendString(b, 1, m.OrderID)
+	b = grpcwire.AppendString(b, 2, m.Name)
+	b = grpcwire.AppendString(b, 3, m.Temp)
+	return grpcwire.AppendDouble(b, 4, m.Value)
+}
+
+func (m *Dispatch) unmarshal(b []byte) error {
+	return grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		switch field {
+		case 1:
+			m.OrderID = f.String()
+		case 2:
+			m.Name = f.String()
+		case 3:
+			m.Temp = f.String()
+		case 4:
+			m.Value = f.Double()
+		}
+		return nil
+	})
+}
+
+func (m Report) marshal() []byte {
+	return grpcwire.AppendString(grpcwire.AppendString(nil, 1, m.CourierID), 2, m.OrderID)
+}
+
+func (m *Report) unmarshal(b []byte) error {
+	return grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		switch field {
+		case 1:
+			m.CourierID = f.String()
+		case 2:
+			m.OrderID = f.String()
+		}
+		return nil
+	})
+}
+
+func (m ReportReply) marshal() []byte {
+	return grpcwire.AppendDouble(grpcwire.AppendBool(nil, 1, m.OK), 2, m.Value)
+}
+
+func (m *ReportReply) unmarshal(b []byte) error {
+	return grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		switch field {
+		case 1:
+			m.OK = f.Bool()
+		case 2:
+			m.Value = f.Double()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,215 @@
+package amqp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Frame types
+const (
+	frameMethod    = 1
+	frameHeader    = 2
+	frameBody      = 3
+	frameHeartbeat = 8
+)
+
+// frameEnd terminates every frame
+const frameEnd = 0xce
+
+// protocolHeader opens a connection, announcing AMQP 0-9-1
+var protocolHeader = []byte{'A', 'M', 'Q', 'P', 0, 0, 9, 1}
+
+// Class and method IDs, as class<<16 | method
+const (
+	connectionStart   = 10<<16 | 10
+	connectionStartOk = 10<<16 | 11
+	connectionTune    = 10<<16 | 30
+	connectionTuneOk  = 10<<16 | 31
+	connectionOpen    = 10<<16 | 40
+	connectionOpenOk  = 10<<16 | 41
+	connectionClose   = 10<<16 | 50
+	connectionCloseOk = 10<<16 | 51
+	channelOpen       = 20<<16 | 10
+	channelOpenOk     = 20<<16 | 11
+	channelClose      = 20<<16 | 40
+	channelCloseOk    = 20<<16 | 41
+	basicQos          = 60<<16 | 10
+	basicQosOk        = 60<<16 | 11
+	basicConsume      = 60<<16 | 20
+	basicConsumeOk    = 60<<16 | 21
+	basicDeliver      = 60<<16 | 60
+	basicAck          = 60<<16 | 80
+	basicNack         = 60<<16 | 120
+)
+
+var errMalformed = errors.New("amqp: malformed frame")
+
+// frame is one frame read from the broker
+type frame struct {
+	kind    byte
+	channel uint16
+	payload []byte
+}
+
+// method returns the class and method ID of a method frame, and its
+// arguments
+func (f frame) method() (int, *decoder) {
+	if f.kind != frameMethod || len(f.payload) < 4 {
+		return 0, &decoder{err: errMalformed}
+	}
+	id := int(binary.BigEndian.Uint16(f.payload))<<16 | int(binary.BigEndian.Uint16(f.payload[2:]))
+	return id, &decoder{b: f.payload[4:]}
+}
+
+func readFrame(r *bufio.Reader) (frame, error) {
+	var header [7]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return frame{}, err
+	}
+	size := binary.BigEndian.Uint32(header[3:])
+	payload := make([]byte, size+1) // with the frame end
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return frame{}, err
+	}
+	if payload[size] != frameEnd {
+		return frame{}, errMalformed
+	}
+	return frame{kind: header[0], channel: binary.BigEndian.Uint16(header[1:]), payload: payload[:size]}, nil
+}
+
+// methodFrame encodes a method frame from its arguments
+func methodFrame(channel uint16, id int, args []byte) []byte {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(id>>16))
+	payload = binary.BigEndian.AppendUint16(payload, uint16(id))
+	return encodeFrame(frameMethod, channel, append(payload, args...))
+}
+
+func encodeFrame(kind byte, channel uint16, payload []byte) []byte {
+	b := []byte{kind}
+	b = binary.BigEndian.AppendUint16(b, channel)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(payload)))
+	b = append(b, payload...)
+	return append(b, frameEnd)
+}
+
+// encoder builds method arguments
+type encoder struct {
+	b []byte
+}
+
+func (e *encoder) octet(v byte) *encoder {
+	e.b = append(e.b, v)
+	return e
+}
+
+func (e *encoder) short(v uint16) *encoder {
+	e.b = binary.BigEndian.AppendUint16(e.b, v)
+	return e
+}
+
+func (e *encoder) long(v uint32) *encoder {
+	e.b = binary.BigEndian.AppendUint32(e.b, v)
+	return e
+}
+
+func (e *encoder) longlong(v uint64) *encoder {
+	e.b = binary.BigEndian.AppendUint64(e.b, v)
+	return e
+}
+
+func (e *encoder) shortstr(s string) *encoder {
+	e.b = append(e.b, byte(len(s)))
+	e.b = append(e.b, s...)
+	return e
+}
+
+func (e *encoder) longstr(s string) *encoder {
+	e.long(uint32(len(s)))
+	e.b = append(e.b, s...)
+	return e
+}
+
+// table encodes a field table of string values
+func (e *encoder) table(fields map[string]string) *encoder {
+	var t encoder
+	for k, v := range fields {
+		t.shortstr(k).octet('S').longstr(v)
+	}
+	e.long(uint32(len(t.b)))
+	e.b = append(e.b, t.b...)
+	return e
+}
+
+// decoder reads method arguments, remembering the first error so callers
+// check once at the end
+type decoder struct {
+	b   []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if len(d.b) < n {
+		d.err = errMalformed
+		return nil
+	}
+	v := d.b[:n]
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *decoder) octet() byte {
+	if v := d.take(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (d *decoder) short() uint16 {
+	if v := d.take(2); v != nil {
+		return binary.BigEndian.Uint16(v)
+	}
+	return 0
+}
+
+func (d *decoder) long() uint32 {
+	if v := d.take(4); v != nil {
+		return binary.BigEndian.Uint32(v)
+	}
+	return 0
+}
+
+func (d *decoder) longlong() uint64 {
+	if v := d.take(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (d *decoder) shortstr() string {
+	return string(d.take(int(d.octet())))
+}
+
+func (d *decoder) longstr() string {
+	return string(d.take(int(d.long())))
+}
+
+// skipTable skips a field table, whose contents the client never needs
+func (d *decoder) skipTable() {
+	d.take(int(d.long()))
+}
+
+// closeReason describes a Connection.Close or Channel.Close from the broker
+func closeReason(d *decoder) error {
+	code := d.short()
+	text := d.shortstr()
+	if d.err != nil {
+		return d.err
+	}
+	return fmt.Errorf("amqp: closed by broker: %d %s", code, text)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"dish-dispatcher/internal/order"
+)
+
+// defaultCompletedLimit is how many completed orders are returned when the
+// request does not ask for a limit
+const defaultCompletedLimit = 50
+
+// handleCompleted serves GET /orders/completed, newest first. Supported query
+// parameters are limit (0 for everything archived) and outcome (delivered,
+// expired, wasted or cancelled).
+func (s *Server) handleCompleted(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	limit := defaultCompletedLimit
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q: must be a non-negative integer", raw))
+			return
+		}
+		limit = n
+	}
+
+	outcome := order.State(q.Get("outcome"))
+	switch outcome {
+	case "", order.StateDelivered, order.StateExpired, order.StateWasted, order.StateCancelled:
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid outcome %q: must be delivered, expired, wasted or cancelled", outcome))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.archive.Recent(limit, outcome))
+}
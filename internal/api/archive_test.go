@@ -0,0 +1,65 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/archive"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestServer_CompletedOrders(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	completed := archive.New(10)
+	srv := httptest.NewServer(api.NewServer(sm, events.NewBus(), completed).Handler())
+	t.Cleanup(srv.Close)
+
+	for _, name := range []string{"Burger", "Pasta"} {
+		o := order.NewOrder(name, order.Hot, 300, 0.5)
+		o.OnTransition = completed.Observe
+		require.NoError(t, sm.PlaceOrder(o))
+		require.True(t, sm.DeliverOrder(o.ID))
+	}
+
+	resp, err := http.Get(srv.URL + "/orders/completed?limit=1&outcome=delivered")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var entries []archive.Entry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&entries))
+	require.Len(t, entries, 1)
+	assert.Equal(t, "Pasta", entries[0].Name)
+	assert.Equal(t, order.StateDelivered, entries[0].Outcome)
+	assert.Len(t, entries[0].Timeline, 3)
+}
+
+func TestServer_CompletedOrdersWithoutArchive(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/orders/completed")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var entries []archive.Entry
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&entries))
+	assert.Empty(t, entries)
+}
+
+func TestServer_CompletedOrdersBadParameter(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	for _, query := range []string{"limit=-1", "limit=lots", "outcome=shelved", "outcome=eaten"} {
+		resp, err := http.Get(srv.URL + "/orders/completed?" + query)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"dish-dispatcher/internal/config"
+)
+
+// openPaths are served without a token, so probes and integrators reading
+// the API document need no credentials
+var openPaths = map[string]bool{"/healthz": true, "/readyz": true, "/version": true, "/openapi.json": true}
+
+// Authenticator checks the bearer token of every control API request
+// against the configured tokens and their roles
+type Authenticator struct {
+	tokens      []config.TokenConfig
+	publicReads bool
+}
+
+// NewAuthenticator checks the configured tokens. It returns nil if there
+// are none, leaving the API open.
+func NewAuthenticator(cfg config.AuthConfig) (*Authenticator, error) {
+	if len(cfg.Tokens) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]bool)
+	for i, tc := range cfg.Tokens {
+		var err error
+		switch {
+		case tc.Token == "":
+			err = errors.New("needs a token")
+		case seen[tc.Token]:
+			err = errors.New("token is already taken")
+		case tc.Role != config.RoleReadOnly && tc.Role != config.RoleAdmin:
+			err = fmt.Errorf("unknown role %q, expected %s or %s", tc.Role, config.RoleReadOnly, config.RoleAdmin)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("auth.tokens[%d] (%s): %w", i, tc.Name, err)
+		}
+		seen[tc.Token] = true
+	}
+	return &Authenticator{tokens: cfg.Tokens, publicReads: cfg.PublicReads}, nil
+}
+
+// Wrap serves requests to next once their token grants the role they need:
+// any role to read, admin to change anything or reach the diagnostics. The
+// Authorization header is consumed, so handlers behind it never mistake the
+// token for their own credentials.
+func (a *Authenticator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if openPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		read := (r.Method == http.MethodGet || r.Method == http.MethodHead) && !strings.HasPrefix(r.URL.Path, "/debug/")
+		role, ok := a.role(r)
+		switch {
+		case !ok && r.Header.Get("Authorization") != "":
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, errors.New("invalid token"))
+			return
+		case !ok && !(read && a.publicReads):
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("a bearer token is required"))
+			return
+		case ok && !read && role != config.RoleAdmin:
+			writeError(w, http.StatusForbidden, errors.New("this needs an admin token"))
+			return
+		}
+
+		r.Header.Del("Authorization")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// role returns the role granted by the request's bearer token, comparing
+// in constant time so the tokens cannot be guessed from response times
+func (a *Authenticator) role(r *http.Request) (string, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	role, found := "", false
+	for _, tc := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(tc.Token)) == 1 {
+			role, found = tc.Role, true
+		}
+	}
+	return role, found
+}
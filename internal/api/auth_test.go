@@ -0,0 +1,107 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/config"
+)
+
+var testTokens = []config.TokenConfig{
+	{Name: "dashboard", Token: "read-token", Role: config.RoleReadOnly},
+	{Name: "ops", Token: "admin-token", Role: config.RoleAdmin},
+}
+
+// newAuthServer serves an API that records the Authorization header it sees
+// behind an authenticator
+func newAuthServer(t *testing.T, cfg config.AuthConfig) (*httptest.Server, *string) {
+	auth, err := api.NewAuthenticator(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, auth)
+
+	var seen string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := httptest.NewServer(auth.Wrap(next))
+	t.Cleanup(srv.Close)
+	return srv, &seen
+}
+
+func request(t *testing.T, method, url, token string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	auth, err := api.NewAuthenticator(config.AuthConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, auth, "no tokens leave the API open")
+
+	for name, tokens := range map[string][]config.TokenConfig{
+		"empty token":   {{Name: "ops", Role: config.RoleAdmin}},
+		"unknown role":  {{Name: "ops", Token: "t", Role: "owner"}},
+		"missing role":  {{Name: "ops", Token: "t"}},
+		"shared tokens": {{Name: "a", Token: "t", Role: config.RoleAdmin}, {Name: "b", Token: "t", Role: config.RoleReadOnly}},
+	} {
+		_, err := api.NewAuthenticator(config.AuthConfig{Tokens: tokens})
+		assert.Error(t, err, name)
+	}
+}
+
+func TestAuthenticator(t *testing.T) {
+	srv, seen := newAuthServer(t, config.AuthConfig{Tokens: testTokens})
+
+	tests := []struct {
+		method, path, token string
+		want                int
+	}{
+		{http.MethodGet, "/healthz", "", http.StatusOK},
+		{http.MethodGet, "/version", "", http.StatusOK},
+		{http.MethodGet, "/api/stats", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/stats", "wrong", http.StatusUnauthorized},
+		{http.MethodGet, "/api/stats", "read-token", http.StatusOK},
+		{http.MethodGet, "/api/stats", "admin-token", http.StatusOK},
+		{http.MethodPost, "/orders", "", http.StatusUnauthorized},
+		{http.MethodPost, "/orders", "read-token", http.StatusForbidden},
+		{http.MethodPost, "/orders", "admin-token", http.StatusOK},
+		{http.MethodPost, "/api/stats/reset", "read-token", http.StatusForbidden},
+		{http.MethodGet, "/debug/pprof/", "read-token", http.StatusForbidden},
+		{http.MethodGet, "/debug/pprof/", "admin-token", http.StatusOK},
+	}
+	for _, tt := range tests {
+		resp := request(t, tt.method, srv.URL+tt.path, tt.token)
+		assert.Equal(t, tt.want, resp.StatusCode, "%s %s with %q", tt.method, tt.path, tt.token)
+		if tt.want == http.StatusUnauthorized {
+			assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Bearer")
+		}
+	}
+
+	// The token is not passed on
+	request(t, http.MethodPost, srv.URL+"/orders", "admin-token")
+	assert.Empty(t, *seen)
+}
+
+func TestAuthenticator_PublicReads(t *testing.T) {
+	srv, _ := newAuthServer(t, config.AuthConfig{Tokens: testTokens, PublicReads: true})
+
+	assert.Equal(t, http.StatusOK, request(t, http.MethodGet, srv.URL+"/api/stats", "").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, request(t, http.MethodGet, srv.URL+"/api/stats", "wrong").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, request(t, http.MethodPost, srv.URL+"/orders", "").StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, request(t, http.MethodGet, srv.URL+"/debug/vars", "").StatusCode)
+	assert.Equal(t, http.StatusForbidden, request(t, http.MethodPost, srv.URL+"/orders", "read-token").StatusCode)
+}
@@ -0,0 +1,64 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openAPIDocument is the part of the OpenAPI document the tests check
+type openAPIDocument struct {
+	OpenAPI string                                `json:"openapi"`
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+func TestServer_OpenAPI(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/openapi.json")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var doc openAPIDocument
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&doc))
+	assert.True(t, strings.HasPrefix(doc.OpenAPI, "3."), "OpenAPI version %s", doc.OpenAPI)
+}
+
+// TestOpenAPI_Routes checks the document describes exactly the routes the
+// server registers, leaving out the dashboard and diagnostics
+func TestOpenAPI_Routes(t *testing.T) {
+	source, err := os.ReadFile("server.go")
+	require.NoError(t, err)
+	var routes []string
+	for _, m := range regexp.MustCompile(`s\.mux\.Handle(?:Func)?\("(\w+) ([^"]+)"`).FindAllStringSubmatch(string(source), -1) {
+		if m[2] == "/" || strings.HasPrefix(m[2], "/debug/") {
+			continue
+		}
+		routes = append(routes, m[1]+" "+m[2])
+	}
+	require.NotEmpty(t, routes)
+
+	spec, err := os.ReadFile("openapi.json")
+	require.NoError(t, err)
+	var doc openAPIDocument
+	require.NoError(t, json.Unmarshal(spec, &doc))
+	var documented []string
+	for path, ops := range doc.Paths {
+		for method := range ops {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(documented)
+	assert.Equal(t, routes, documented)
+}
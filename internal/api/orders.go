@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// handleOrders serves GET /orders. Supported query parameters are temp,
+// shelf, name (prefix), minValue, maxValue, minAge and maxAge (seconds).
+func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseOrderFilter(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	now := time.Now()
+	views := make([]OrderView, 0)
+	for _, o := range s.manager.Query(filter, now) {
+		views = append(views, newOrderView(o, now))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// AgingView is one order in the aging report
+type AgingView struct {
+	OrderView
+	// SecondsToExpiry is null for orders that never expire
+	SecondsToExpiry *float64 `json:"secondsToExpiry"`
+}
+
+// handleAging serves GET /orders/aging, every shelved order least valuable
+// first with the seconds it has left
+func (s *Server) handleAging(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	views := make([]AgingView, 0)
+	for _, entry := range shelf.AgingReport(s.manager, now) {
+		view := AgingView{OrderView: newOrderView(entry.Order, now)}
+		if entry.Expires {
+			remaining := entry.ExpiresIn.Seconds()
+			view.SecondsToExpiry = &remaining
+		}
+		views = append(views, view)
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+// maxMoveBody caps the size of a POST /orders/{id}/move request
+const maxMoveBody = 1 << 10
+
+// moveRequest is the body of POST /orders/{id}/move
+type moveRequest struct {
+	Shelf string `json:"shelf"`
+}
+
+// handleMoveOrder serves POST /orders/{id}/move, moving a shelved order to
+// the shelf named in the body. It returns the moved order, 404 if the order
+// is not shelved, 409 if the shelf is full and 400 if the order cannot go
+// on that shelf.
+func (s *Server) handleMoveOrder(w http.ResponseWriter, r *http.Request) {
+	mover, ok := s.manager.(shelf.OrderMover)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, errors.New("the shelf manager cannot move orders"))
+		return
+	}
+
+	var req moveRequest
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMoveBody))
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil || req.Shelf == "" {
+		writeError(w, http.StatusBadRequest, errors.New(`body must be {"shelf": "<shelf type>"}`))
+		return
+	}
+
+	id := r.PathValue("id")
+	target := shelf.ShelfType(req.Shelf)
+	err = mover.MoveOrder(id, target)
+	switch {
+	case errors.Is(err, shelf.ErrOrderNotShelved):
+		writeError(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, shelf.ErrShelfFull):
+		writeError(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	now := time.Now()
+	for _, o := range s.manager.Query(shelf.OrderFilter{Shelf: target}, now) {
+		if o.ID == id {
+			s.events.Publish(events.Event{Type: events.OrderMoved, Time: now, OrderID: o.ID,
+				Name: o.Name, Temp: string(o.Temp), Shelf: o.CurrentShelfType, Value: o.CalculateValue(now)})
+			writeJSON(w, http.StatusOK, newOrderView(o, now))
+			return
+		}
+	}
+	// Delivered or expired the moment it was moved
+	writeError(w, http.StatusNotFound, shelf.ErrOrderNotShelved)
+}
+
+func parseOrderFilter(q url.Values) (shelf.OrderFilter, error) {
+	filter := shelf.OrderFilter{
+		Temp:       order.Temperature(q.Get("temp")),
+		Shelf:      shelf.ShelfType(q.Get("shelf")),
+		NamePrefix: q.Get("name"),
+	}
+
+	var err error
+	if filter.MinValue, err = parseOptionalFloat(q, "minValue"); err != nil {
+		return filter, err
+	}
+	if filter.MaxValue, err = parseOptionalFloat(q, "maxValue"); err != nil {
+		return filter, err
+	}
+	if filter.MinAge, err = parseSeconds(q, "minAge"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAge, err = parseSeconds(q, "maxAge"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseOptionalFloat(q url.Values, key string) (*float64, error) {
+	raw := q.Get(key)
+	if raw == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s %q: must be a number", key, raw)
+	}
+	return &v, nil
+}
+
+func parseSeconds(q url.Values, key string) (time.Duration, error) {
+	v, err := parseOptionalFloat(q, key)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return time.Duration(*v * float64(time.Second)), nil
+}
@@ -0,0 +1,111 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestServer_QueryOrders(t *testing.T) {
+	srv, sm, _ := newTestServer(t)
+	sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5))
+	sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5))
+
+	resp, err := http.Get(srv.URL + "/orders?temp=hot&minValue=0.5")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var views []api.OrderView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&views))
+	require.Len(t, views, 1)
+	assert.Equal(t, "Burger", views[0].Name)
+	assert.Equal(t, "hot", views[0].Shelf)
+}
+
+func TestServer_QueryOrdersBadParameter(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/orders?minValue=lots")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_MoveOrder(t *testing.T) {
+	srv, sm, bus := newTestServer(t)
+	updates, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(o))
+
+	resp, err := http.Post(srv.URL+"/orders/"+o.ID+"/move", "application/json", strings.NewReader(`{"shelf":"overflow"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var view api.OrderView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&view))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "overflow", view.Shelf)
+	assert.Equal(t, 1, sm.GetShelf(shelf.OverflowShelf).Size())
+
+	event := <-updates
+	assert.Equal(t, events.OrderMoved, event.Type)
+	assert.Equal(t, o.ID, event.OrderID)
+}
+
+func TestServer_MoveOrderErrors(t *testing.T) {
+	srv, sm, _ := newTestServer(t)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(o))
+
+	for body, want := range map[string]int{
+		`{"shelf":"cold"}`:  http.StatusBadRequest,
+		`{"shelf":"hot"}`:   http.StatusBadRequest,
+		`{"shelf":"sauna"}`: http.StatusBadRequest,
+		`{}`:                http.StatusBadRequest,
+		`not json`:          http.StatusBadRequest,
+	} {
+		resp, err := http.Post(srv.URL+"/orders/"+o.ID+"/move", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, want, resp.StatusCode, body)
+	}
+
+	resp, err := http.Post(srv.URL+"/orders/missing/move", "application/json", strings.NewReader(`{"shelf":"overflow"}`))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_Aging(t *testing.T) {
+	srv, sm, _ := newTestServer(t)
+	sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5))
+	sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 1, 0))
+	o := order.NewOrder("Fries", order.Hot, 10, 1)
+	o.PlacedOnShelfAt = time.Now().Add(-5 * time.Second)
+	sm.PlaceOrder(o)
+
+	resp, err := http.Get(srv.URL + "/orders/aging")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var views []api.AgingView
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&views))
+	require.Len(t, views, 3)
+	assert.Equal(t, "Fries", views[0].Name)
+	require.NotNil(t, views[0].SecondsToExpiry)
+	assert.InDelta(t, 5, *views[0].SecondsToExpiry, 0.5)
+	assert.Equal(t, "Burger", views[2].Name)
+	assert.Nil(t, views[2].SecondsToExpiry)
+}
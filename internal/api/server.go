@@ -0,0 +1,344 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"embed"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/pprof"
+	"sync/atomic"
+	"time"
+
+	"dish-dispatcher/internal/archive"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+	"dish-dispatcher/internal/timing"
+)
+
+//go:embed static
+var staticFiles embed.FS
+
+// openAPISpec documents the API, see handleOpenAPI
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// snapshotInterval is how often the event stream pushes a full shelf snapshot
+const snapshotInterval = time.Second
+
+// Server is the HTTP control API. It serves JSON endpoints, the live
+// dashboard and a WebSocket stream of simulation events.
+type Server struct {
+	manager shelf.ShelfManager
+	events  *events.Bus
+	archive *archive.Archive
+	run     RunSource
+	mux     *http.ServeMux
+
+	// service ingests orders in service mode, or is nil
+	service Service
+	ready   atomic.Bool
+
+	// timings and metrics are served at /metrics, or nil
+	timings *timing.Set
+	metrics MetricsWriter
+
+	// history is served at /stats/history, or nil
+	history HistorySource
+	// orderHistory is served at /orders/{id}/history, or nil
+	orderHistory OrderHistorySource
+}
+
+// NewServer creates a control API over the given shelf manager, event bus
+// and completed-order archive, which may be nil
+func NewServer(manager shelf.ShelfManager, bus *events.Bus, completed *archive.Archive) *Server {
+	s := &Server{
+		manager: manager,
+		events:  bus,
+		archive: completed,
+		mux:     http.NewServeMux(),
+	}
+
+	s.mux.Handle("GET /", http.FileServerFS(staticFS()))
+	s.mux.HandleFunc("GET /api/stats", s.handleStats)
+	s.mux.HandleFunc("GET /stats/history", s.handleStatsHistory)
+	s.mux.HandleFunc("GET /api/shelves", s.handleShelves)
+	s.mux.HandleFunc("GET /api/events", s.handleEvents)
+	s.mux.HandleFunc("GET /api/run", s.handleRun)
+	s.mux.HandleFunc("GET /orders", s.handleOrders)
+	s.mux.HandleFunc("GET /orders/completed", s.handleCompleted)
+	s.mux.HandleFunc("GET /orders/aging", s.handleAging)
+	s.mux.HandleFunc("POST /orders", s.handleSubmitOrders)
+	s.mux.HandleFunc("POST /orders/stream", s.handleOrderStream)
+	s.mux.HandleFunc("POST /orders/{id}/move", s.handleMoveOrder)
+	s.mux.HandleFunc("GET /orders/{id}/history", s.handleOrderHistory)
+	s.mux.HandleFunc("POST /api/stats/reset", s.handleResetStats)
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.HandleFunc("GET /readyz", s.handleReady)
+	s.mux.HandleFunc("GET /version", s.handleVersion)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+	s.mux.HandleFunc("GET /openapi.json", s.handleOpenAPI)
+
+	return s
+}
+
+// RunSource supplies the run being served, which may change as stats are
+// reset
+type RunSource interface {
+	RunContext() config.RunContext
+}
+
+// SetRun sets the run served at /api/run, whose ID labels the metrics.
+// Call it before serving.
+func (s *Server) SetRun(run RunSource) {
+	s.run = run
+}
+
+// runContext returns the run being served, or none if it is not set
+func (s *Server) runContext() config.RunContext {
+	if s.run == nil {
+		return config.RunContext{}
+	}
+	return s.run.RunContext()
+}
+
+// MetricsWriter writes metrics in the Prometheus text exposition format
+type MetricsWriter interface {
+	WritePrometheus(w io.Writer) error
+}
+
+// SetMetrics sets further metrics served at /metrics, such as the labelled
+// order counts of a simulator. Call it before serving.
+func (s *Server) SetMetrics(metrics MetricsWriter) {
+	s.metrics = metrics
+}
+
+// HistorySource supplies the time series and outcome breakdowns of a run
+type HistorySource interface {
+	StatsHistory() simulator.StatsHistory
+}
+
+// SetHistory sets the run whose history is served at /stats/history. Call
+// it before serving.
+func (s *Server) SetHistory(history HistorySource) {
+	s.history = history
+}
+
+// OrderHistorySource supplies the timelines of traced orders
+type OrderHistorySource interface {
+	OrderHistory(id string) (simulator.OrderTimeline, bool)
+}
+
+// SetOrderHistory sets the run whose traced orders are served at
+// /orders/{id}/history. Call it before serving.
+func (s *Server) SetOrderHistory(history OrderHistorySource) {
+	s.orderHistory = history
+}
+
+// SetTimings sets the operation timings served at /metrics. Call it before
+// serving.
+func (s *Server) SetTimings(timings *timing.Set) {
+	s.timings = timings
+}
+
+// EnableDiagnostics serves the runtime profiles of net/http/pprof under
+// /debug/pprof/ and the expvar variables, including memstats, at
+// /debug/vars. Call it before serving.
+func (s *Server) EnableDiagnostics() {
+	s.mux.HandleFunc("GET /debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("GET /debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("GET /debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("GET /debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("POST /debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("GET /debug/pprof/trace", pprof.Trace)
+	s.mux.Handle("GET /debug/vars", expvar.Handler())
+}
+
+// Handler returns the server's HTTP handler
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// ListenAndServe serves on addr until ctx is cancelled, then shuts down
+// gracefully
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	return ListenAndServe(ctx, addr, s.mux, nil)
+}
+
+// ListenAndServe serves handler on addr until ctx is cancelled, then shuts
+// down gracefully. A non-nil tlsConfig serves HTTPS.
+func ListenAndServe(ctx context.Context, addr string, handler http.Handler, tlsConfig *tls.Config) error {
+	httpServer := &http.Server{Addr: addr, Handler: handler, TLSConfig: tlsConfig}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			errCh <- httpServer.ListenAndServeTLS("", "")
+			return
+		}
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.manager.GetStats())
+}
+
+// handleStatsHistory serves GET /stats/history, the samples kept of the run
+// and the outcomes of its orders, for notebooks polling a running
+// simulation
+func (s *Server) handleStatsHistory(w http.ResponseWriter, r *http.Request) {
+	if s.history == nil {
+		writeError(w, http.StatusNotFound, errors.New("no stats history is kept"))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.history.StatsHistory())
+}
+
+// handleOrderHistory serves GET /orders/{id}/history, the timeline of a
+// traced order
+func (s *Server) handleOrderHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if s.orderHistory != nil {
+		if timeline, ok := s.orderHistory.OrderHistory(id); ok {
+			writeJSON(w, http.StatusOK, timeline)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, fmt.Errorf("order %s is not traced", id))
+}
+
+// handleMetrics serves the shelf occupancy, any further metrics and the
+// operation latency histograms in the Prometheus text format, labelled by
+// the run's ID
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	runID := s.runContext().ID
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeShelfMetrics(w, s.manager.ShelfStates(), runID)
+	if s.metrics != nil {
+		s.metrics.WritePrometheus(w)
+	}
+	s.timings.WritePrometheus(w, "dispatcher_operation_duration_seconds",
+		"Latency of shelf operations inside the dispatcher.", "op", "run_id", runID)
+}
+
+// writeShelfMetrics writes the orders on each shelf and its capacity as
+// Prometheus gauges labelled by shelf and run
+func writeShelfMetrics(w io.Writer, states []shelf.ShelfState, runID string) {
+	fmt.Fprint(w, "# HELP dispatcher_shelf_orders Orders on the shelf.\n# TYPE dispatcher_shelf_orders gauge\n")
+	for _, st := range states {
+		fmt.Fprintf(w, "dispatcher_shelf_orders{shelf=%q,run_id=%q} %d\n", st.Type, runID, len(st.Orders))
+	}
+	fmt.Fprint(w, "# HELP dispatcher_shelf_capacity Orders the shelf holds.\n# TYPE dispatcher_shelf_capacity gauge\n")
+	for _, st := range states {
+		fmt.Fprintf(w, "dispatcher_shelf_capacity{shelf=%q,run_id=%q} %d\n", st.Type, runID, st.Capacity)
+	}
+}
+
+// handleOpenAPI serves GET /openapi.json, the OpenAPI 3 document of every
+// endpoint but the dashboard and diagnostics. Keep it in step with the
+// routes; TestOpenAPI_Routes checks they match.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPISpec)
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.runContext())
+}
+
+func (s *Server) handleShelves(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, TakeSnapshot(s.manager, time.Now()))
+}
+
+// streamMessage is the envelope for every WebSocket message
+type streamMessage struct {
+	Kind     string        `json:"kind"` // "event" or "snapshot"
+	Event    *events.Event `json:"event,omitempty"`
+	Snapshot *Snapshot     `json:"snapshot,omitempty"`
+}
+
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer ws.Close()
+
+	eventCh, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	ticker := time.NewTicker(snapshotInterval)
+	defer ticker.Stop()
+
+	send := func(msg streamMessage) bool {
+		payload, err := json.Marshal(msg)
+		return err == nil && ws.WriteText(payload) == nil
+	}
+
+	snapshot := TakeSnapshot(s.manager, time.Now())
+	if !send(streamMessage{Kind: "snapshot", Snapshot: &snapshot}) {
+		return
+	}
+
+	for {
+		select {
+		case e, ok := <-eventCh:
+			if !ok || !send(streamMessage{Kind: "event", Event: &e}) {
+				return
+			}
+		case <-ticker.C:
+			snapshot := TakeSnapshot(s.manager, time.Now())
+			if !send(streamMessage{Kind: "snapshot", Snapshot: &snapshot}) {
+				return
+			}
+		case <-ws.Done():
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// staticFS returns the embedded dashboard files rooted at static/
+func staticFS() fs.FS {
+	sub, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
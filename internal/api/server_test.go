@@ -0,0 +1,312 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+	"dish-dispatcher/internal/timing"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, *shelf.InMemoryShelfManager, *events.Bus) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	bus := events.NewBus()
+	srv := httptest.NewServer(api.NewServer(sm, bus, nil).Handler())
+	t.Cleanup(srv.Close)
+	return srv, sm, bus
+}
+
+func TestServer_Dashboard(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "/api/events")
+}
+
+func TestServer_Shelves(t *testing.T) {
+	srv, sm, _ := newTestServer(t)
+	sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5))
+
+	resp, err := http.Get(srv.URL + "/api/shelves")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var snapshot api.Snapshot
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&snapshot))
+	require.Len(t, snapshot.Shelves, 4)
+	assert.Equal(t, "hot", snapshot.Shelves[0].Type)
+	require.Len(t, snapshot.Shelves[0].Orders, 1)
+	assert.Equal(t, "Burger", snapshot.Shelves[0].Orders[0].Name)
+}
+
+// runFunc serves a run context for SetRun
+type runFunc func() config.RunContext
+
+func (f runFunc) RunContext() config.RunContext { return f() }
+
+func TestServer_Run(t *testing.T) {
+	server := api.NewServer(shelf.NewShelfManager(1, 1, 1, 1), events.NewBus(), nil)
+	server.SetRun(runFunc(func() config.RunContext {
+		return config.RunContext{ID: "baseline-1", RunConfig: config.RunConfig{Name: "baseline", Tags: map[string]string{"shelves": "small"}}}
+	}))
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/run")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var run config.RunContext
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&run))
+	assert.Equal(t, "baseline-1", run.ID)
+	assert.Equal(t, "baseline", run.Name)
+	assert.Equal(t, "small", run.Tags["shelves"])
+}
+
+func TestServer_EventStream(t *testing.T) {
+	srv, _, bus := newTestServer(t)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("GET /api/events HTTP/1.1\r\n" +
+		"Host: test\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"))
+	require.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	// The first message is always a snapshot
+	assert.Contains(t, readTextFrame(t, reader), `"kind":"snapshot"`)
+
+	bus.Publish(events.Event{Type: events.OrderPlaced, Name: "Burger"})
+	for {
+		msg := readTextFrame(t, reader)
+		if strings.Contains(msg, `"kind":"event"`) {
+			assert.Contains(t, msg, `"name":"Burger"`)
+			return
+		}
+	}
+}
+
+// readTextFrame reads one unmasked server frame and returns its payload
+func readTextFrame(t *testing.T, r *bufio.Reader) string {
+	head := make([]byte, 2)
+	_, err := io.ReadFull(r, head)
+	require.NoError(t, err)
+
+	length := int(head[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		_, err = io.ReadFull(r, ext)
+		require.NoError(t, err)
+		length = int(ext[0])<<8 | int(ext[1])
+	case 127:
+		t.Fatal("unexpectedly large frame")
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(r, payload)
+	require.NoError(t, err)
+	return string(payload)
+}
+
+func TestServer_Metrics(t *testing.T) {
+	timings := timing.NewSet()
+	timings.Histogram("place_order").Observe(3 * time.Microsecond)
+	manager := shelf.NewShelfManager(2, 1, 1, 1)
+	require.NoError(t, manager.PlaceOrder(order.NewOrder("Soup", order.Hot, 300, 0.5)))
+	server := api.NewServer(manager, events.NewBus(), nil)
+	server.SetTimings(timings)
+	server.SetRun(runFunc(func() config.RunContext { return config.RunContext{ID: "lunch-1"} }))
+	server.SetMetrics(metricsFunc(func(w io.Writer) error {
+		_, err := io.WriteString(w, "dispatcher_orders_total{outcome=\"delivered\"} 4\n")
+		return err
+	}))
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), `dispatcher_operation_duration_seconds_count{op="place_order",run_id="lunch-1"} 1`)
+	assert.Contains(t, string(body), `dispatcher_orders_total{outcome="delivered"} 4`)
+	assert.Contains(t, string(body), `dispatcher_shelf_orders{shelf="hot",run_id="lunch-1"} 1`)
+	assert.Contains(t, string(body), `dispatcher_shelf_capacity{shelf="hot",run_id="lunch-1"} 2`)
+}
+
+// metricsFunc adapts a function to api.MetricsWriter
+type metricsFunc func(io.Writer) error
+
+func (f metricsFunc) WritePrometheus(w io.Writer) error {
+	return f(w)
+}
+
+// historyFunc adapts a function to api.HistorySource
+type historyFunc func() simulator.StatsHistory
+
+func (f historyFunc) StatsHistory() simulator.StatsHistory {
+	return f()
+}
+
+func TestServer_StatsHistory(t *testing.T) {
+	server := api.NewServer(shelf.NewShelfManager(1, 1, 1, 1), events.NewBus(), nil)
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	server.SetHistory(historyFunc(func() simulator.StatsHistory {
+		return simulator.StatsHistory{
+			IntervalSeconds: 1,
+			Shelves:         []string{"hot"},
+			Samples: []simulator.StatsSample{
+				{Timestamp: at, ElapsedSeconds: 0, Shelves: map[string]int{"hot": 0}},
+				{Timestamp: at.Add(time.Second), ElapsedSeconds: 1, Shelves: map[string]int{"hot": 1}, Received: 1},
+			},
+			ByItem: []simulator.OutcomeSummary{{Name: "Soup", Delivered: 1, AvgDeliveredValue: 0.9}},
+		}
+	}))
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/stats/history")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Decoded loosely, as a notebook would, to pin the field names
+	var history map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&history))
+	assert.Equal(t, 1.0, history["intervalSeconds"])
+	samples := history["samples"].([]any)
+	require.Len(t, samples, 2)
+	assert.Equal(t, map[string]any{
+		"timestamp":      "2024-01-01T12:00:01Z",
+		"elapsedSeconds": 1.0,
+		"shelves":        map[string]any{"hot": 1.0},
+		"received":       1.0,
+		"delivered":      0.0,
+		"wasted":         0.0,
+		"expired":        0.0,
+	}, samples[1])
+	assert.Equal(t, []any{map[string]any{
+		"name": "Soup", "delivered": 1.0, "wasted": 0.0, "expired": 0.0, "avgDeliveredValue": 0.9,
+	}}, history["byItem"])
+}
+
+func TestServer_StatsHistoryNotKept(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/stats/history")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// orderHistoryFunc adapts a function to api.OrderHistorySource
+type orderHistoryFunc func(string) (simulator.OrderTimeline, bool)
+
+func (f orderHistoryFunc) OrderHistory(id string) (simulator.OrderTimeline, bool) {
+	return f(id)
+}
+
+func TestServer_OrderHistory(t *testing.T) {
+	server := api.NewServer(shelf.NewShelfManager(1, 1, 1, 1), events.NewBus(), nil)
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	server.SetOrderHistory(orderHistoryFunc(func(id string) (simulator.OrderTimeline, bool) {
+		if id != "abc" {
+			return simulator.OrderTimeline{}, false
+		}
+		return simulator.OrderTimeline{ID: id, Name: "Soup", Temp: order.Hot, Entries: []simulator.TraceEntry{
+			{At: at, From: order.StateCreated, To: order.StateShelved, Shelf: "hot", Value: 1},
+		}}, true
+	}))
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/orders/abc/history")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var timeline map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&timeline))
+	assert.Equal(t, "Soup", timeline["name"])
+	assert.Equal(t, []any{map[string]any{
+		"at": "2024-01-01T12:00:00Z", "from": "created", "to": "shelved", "shelf": "hot", "value": 1.0,
+	}}, timeline["entries"])
+
+	missing, err := http.Get(srv.URL + "/orders/xyz/history")
+	require.NoError(t, err)
+	defer missing.Body.Close()
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
+
+func TestServer_OrderHistoryNotTraced(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, err := http.Get(srv.URL + "/orders/abc/history")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_Diagnostics(t *testing.T) {
+	server := api.NewServer(shelf.NewShelfManager(1, 1, 1, 1), events.NewBus(), nil)
+	srv := httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	// Off unless enabled
+	resp, err := http.Get(srv.URL + "/debug/vars")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	server = api.NewServer(shelf.NewShelfManager(1, 1, 1, 1), events.NewBus(), nil)
+	server.EnableDiagnostics()
+	srv = httptest.NewServer(server.Handler())
+	defer srv.Close()
+
+	resp, err = http.Get(srv.URL + "/debug/vars")
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "memstats")
+
+	resp, err = http.Get(srv.URL + "/debug/pprof/goroutine?debug=1")
+	require.NoError(t, err)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "goroutine profile")
+}
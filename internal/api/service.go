@@ -0,0 +1,260 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"time"
+
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+)
+
+// maxOrderBody caps the size of a POST /orders request
+const maxOrderBody = 1 << 20
+
+// Service is the running simulation behind the service mode endpoints
+type Service interface {
+	ValidateOrder(d simulator.OrderData) error
+	Submit(d simulator.OrderData) (*order.Order, error)
+	SubmitBatch(ds []simulator.OrderData, mode shelf.BatchMode) ([]*order.Order, []error)
+	ResetStats() error
+}
+
+// SetService enables order ingestion and stats reset through svc. Call it
+// before serving.
+func (s *Server) SetService(svc Service) {
+	s.service = svc
+}
+
+// SetReady marks the server ready or not ready for traffic, as reported by
+// /readyz. Orders are only accepted while ready.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+// PlacementResult is the outcome of one submitted order
+type PlacementResult struct {
+	Order  *OrderView `json:"order,omitempty"`
+	Reason string     `json:"reason,omitempty"` // why the order was wasted, deferred or rejected
+	Error  string     `json:"error,omitempty"`
+
+	// Fields are the fields at fault in an invalid order
+	Fields []simulator.FieldError `json:"fields,omitempty"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleVersion serves GET /version, identifying the running binary
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, buildinfo.Get())
+}
+
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	if !s.ready.Load() {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "not ready"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// handleSubmitOrders serves POST /orders. The body is one order, in the
+// orders file format, or an array of them. A single order gets 201 if it
+// was shelved, 409 if it was wasted, 202 if the admission policy deferred
+// it and 429 if the policy rejected it; a batch gets 200 with a result per
+// order. A batch is placed together, best-effort unless the mode query
+// parameter asks for all-or-nothing.
+func (s *Server) handleSubmitOrders(w http.ResponseWriter, r *http.Request) {
+	if s.service == nil {
+		writeError(w, http.StatusNotFound, errors.New("order ingestion is only available in service mode"))
+		return
+	}
+	if !s.ready.Load() {
+		writeError(w, http.StatusServiceUnavailable, errors.New("not ready"))
+		return
+	}
+	mode := shelf.BatchBestEffort
+	if m := r.URL.Query().Get("mode"); m != "" {
+		mode = shelf.BatchMode(m)
+		if mode != shelf.BatchBestEffort && mode != shelf.BatchAllOrNothing {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("mode must be %s or %s, got %q", shelf.BatchBestEffort, shelf.BatchAllOrNothing, m))
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOrderBody))
+	if err != nil {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	batch, orders, err := decodeOrders(body)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, invalidResult(err))
+		return
+	}
+
+	if batch {
+		writeJSON(w, http.StatusOK, s.submitBatch(orders, mode, time.Now()))
+		return
+	}
+	result, status := s.submit(orders[0], time.Now())
+	writeJSON(w, status, result)
+}
+
+// submit validates and places one order and returns its result and the
+// status code for a single order request. Invalid orders are turned away
+// before reaching the service, so they count against no quota.
+func (s *Server) submit(d simulator.OrderData, now time.Time) (PlacementResult, int) {
+	if err := s.service.ValidateOrder(d); err != nil {
+		return invalidResult(err), http.StatusBadRequest
+	}
+	o, err := s.service.Submit(d)
+	return placementResult(o, err, now)
+}
+
+// submitBatch validates and places a batch of orders together, returning
+// a result for each. As with single orders, invalid orders never reach the
+// service; in all-or-nothing mode they turn the whole batch away.
+func (s *Server) submitBatch(ds []simulator.OrderData, mode shelf.BatchMode, now time.Time) []PlacementResult {
+	results := make([]PlacementResult, len(ds))
+	var valid []simulator.OrderData
+	var indexes []int
+	for i, d := range ds {
+		if err := s.service.ValidateOrder(d); err != nil {
+			results[i] = invalidResult(err)
+			continue
+		}
+		valid = append(valid, d)
+		indexes = append(indexes, i)
+	}
+
+	if mode == shelf.BatchAllOrNothing && len(valid) < len(ds) {
+		for _, i := range indexes {
+			results[i], _ = placementResult(nil, simulator.ErrBatchRejected, now)
+		}
+		return results
+	}
+	if len(valid) == 0 {
+		return results
+	}
+	orders, errs := s.service.SubmitBatch(valid, mode)
+	for j, i := range indexes {
+		results[i], _ = placementResult(orders[j], errs[j], now)
+	}
+	return results
+}
+
+// placementResult describes how the service answered a submitted order,
+// with the status code for a single order request
+func placementResult(o *order.Order, err error, now time.Time) (PlacementResult, int) {
+	var invalid *simulator.InvalidOrderError
+	switch {
+	case errors.As(err, &invalid):
+		return invalidResult(err), http.StatusBadRequest
+	case errors.Is(err, simulator.ErrStopped):
+		return PlacementResult{Error: err.Error()}, http.StatusServiceUnavailable
+	case errors.Is(err, simulator.ErrDeferred):
+		return PlacementResult{Reason: "deferred"}, http.StatusAccepted
+	case errors.Is(err, simulator.ErrRejected):
+		return PlacementResult{Reason: "rejected", Error: err.Error()}, http.StatusTooManyRequests
+	case errors.Is(err, simulator.ErrBatchRejected):
+		return PlacementResult{Reason: string(shelf.RejectBatch), Error: err.Error()}, http.StatusConflict
+	case o == nil && err != nil:
+		// Not placed, as when the shelves turned an all-or-nothing batch
+		// away or the batch could not be placed at all
+		return PlacementResult{Reason: string(shelf.RejectionReason(err)), Error: err.Error()}, http.StatusConflict
+	}
+
+	view := newOrderView(o, now)
+	if err != nil {
+		return PlacementResult{Order: &view, Reason: string(shelf.RejectionReason(err)), Error: err.Error()}, http.StatusConflict
+	}
+	return PlacementResult{Order: &view}, http.StatusCreated
+}
+
+// invalidResult reports an order that could not be placed as described,
+// with the fields at fault if it names them
+func invalidResult(err error) PlacementResult {
+	result := PlacementResult{Error: err.Error()}
+	var invalid *simulator.InvalidOrderError
+	if errors.As(err, &invalid) {
+		result.Fields = invalid.Fields
+	}
+	return result
+}
+
+// decodeOrders parses a single order or an array of them, reporting which
+func decodeOrders(body []byte) (bool, []simulator.OrderData, error) {
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var orders []simulator.OrderData
+		if err := json.Unmarshal(body, &orders); err != nil {
+			return true, nil, decodeError(err)
+		}
+		if len(orders) == 0 {
+			return true, nil, errors.New("no orders in batch")
+		}
+		return true, orders, nil
+	}
+
+	var d simulator.OrderData
+	if err := json.Unmarshal(body, &d); err != nil {
+		return false, nil, decodeError(err)
+	}
+	return false, []simulator.OrderData{d}, nil
+}
+
+// decodeError describes an order that could not be decoded, naming the
+// field if it held a value of the wrong type
+func decodeError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field := simulator.FieldError{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be %s, got %s", jsonType(typeErr.Type), typeErr.Value),
+		}
+		return &simulator.InvalidOrderError{Reason: field.String(), Fields: []simulator.FieldError{field}}
+	}
+	return fmt.Errorf("invalid order: %w", err)
+}
+
+// jsonType names the JSON type decoded into t
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+func (s *Server) handleResetStats(w http.ResponseWriter, r *http.Request) {
+	if s.service == nil {
+		writeError(w, http.StatusNotFound, errors.New("stats reset is only available in service mode"))
+		return
+	}
+	if err := s.service.ResetStats(); err != nil {
+		writeError(w, http.StatusNotImplemented, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s.manager.GetStats())
+}
@@ -0,0 +1,258 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/config"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+	"dish-dispatcher/plugin"
+)
+
+func newServiceServer(t *testing.T) (*httptest.Server, *api.Server, *simulator.Simulator) {
+	cfg := config.DefaultConfig()
+	cfg.HotShelfCapacity = 1
+	cfg.OverflowCapacity = 0
+	cfg.Service.Enabled = true
+
+	sim, err := simulator.NewSimulator(cfg, "")
+	require.NoError(t, err)
+
+	server := api.NewServer(sim.ShelfManager, sim.Events, sim.Archive)
+	server.SetService(sim)
+	server.SetReady(true)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+	return srv, server, sim
+}
+
+func postJSON(t *testing.T, url, body string) (*http.Response, []byte) {
+	t.Helper()
+
+	resp, err := http.Post(url, "application/json", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&raw))
+	return resp, raw
+}
+
+func TestServer_Health(t *testing.T) {
+	srv, server, _ := newServiceServer(t)
+
+	resp, err := http.Get(srv.URL + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = http.Get(srv.URL + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	server.SetReady(false)
+	resp, err = http.Get(srv.URL + "/readyz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, _ = postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestServer_Version(t *testing.T) {
+	srv, _, _ := newServiceServer(t)
+
+	resp, err := http.Get(srv.URL + "/version")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var info buildinfo.Info
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+	assert.Equal(t, buildinfo.Get(), info)
+}
+
+func TestServer_SubmitOrder(t *testing.T) {
+	srv, _, _ := newServiceServer(t)
+
+	resp, body := postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	var result api.PlacementResult
+	require.NoError(t, json.Unmarshal(body, &result))
+	require.NotNil(t, result.Order)
+	assert.Equal(t, "hot", result.Order.Shelf)
+
+	// The hot shelf is full and there is no overflow
+	resp, body = postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, string(shelf.RejectOverflowFull), result.Reason)
+
+	resp, _ = postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot"}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, _ = postJSON(t, srv.URL+"/orders", `not json`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServer_SubmitInvalidFields(t *testing.T) {
+	srv, _, sim := newServiceServer(t)
+
+	resp, body := postJSON(t, srv.URL+"/orders", `{"name":" ","temp":"hot","shelfLife":0,"decayRate":50}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	var result api.PlacementResult
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, []simulator.FieldError{
+		{Field: "name", Message: "is required"},
+		{Field: "shelfLife", Message: "must be positive, got 0"},
+		{Field: "decayRate", Message: "must be between 0 and 10, got 50"},
+	}, result.Fields)
+	assert.Contains(t, result.Error, "shelfLife must be positive")
+
+	resp, body = postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":"long","decayRate":0.5}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	result = api.PlacementResult{}
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, []simulator.FieldError{{Field: "shelfLife", Message: "must be a number, got string"}}, result.Fields)
+
+	// Under the strict policy a temperature no shelf takes is invalid too
+	sim.Config.UnknownTemps.Policy = config.UnknownTempStrict
+	resp, body = postJSON(t, srv.URL+"/orders", `{"name":"Bread","temp":"ambient","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	result = api.PlacementResult{}
+	require.NoError(t, json.Unmarshal(body, &result))
+	require.Len(t, result.Fields, 1)
+	assert.Equal(t, "temp", result.Fields[0].Field)
+
+	// No invalid order took the one hot slot
+	resp, _ = postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestServer_SubmitBatch(t *testing.T) {
+	srv, _, _ := newServiceServer(t)
+
+	resp, body := postJSON(t, srv.URL+"/orders", `[
+		{"name":"Salad","temp":"cold","shelfLife":300,"decayRate":0.5},
+		{"name":"Soup","temp":"hot"}
+	]`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var results []api.PlacementResult
+	require.NoError(t, json.Unmarshal(body, &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, "cold", results[0].Order.Shelf)
+	assert.Contains(t, results[1].Error, "shelfLife")
+}
+
+func TestServer_SubmitBatchAllOrNothing(t *testing.T) {
+	srv, _, sim := newServiceServer(t)
+
+	// One hot shelf slot and no overflow room: the second soup does not fit
+	resp, body := postJSON(t, srv.URL+"/orders?mode=all-or-nothing", `[
+		{"name":"Salad","temp":"cold","shelfLife":300,"decayRate":0.5},
+		{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5},
+		{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}
+	]`)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var results []api.PlacementResult
+	require.NoError(t, json.Unmarshal(body, &results))
+	require.Len(t, results, 3)
+	assert.Equal(t, string(shelf.RejectBatch), results[0].Reason)
+	assert.Equal(t, string(shelf.RejectBatch), results[1].Reason)
+	assert.Equal(t, string(shelf.RejectOverflowFull), results[2].Reason)
+	assert.Nil(t, results[2].Order)
+	assert.Empty(t, sim.ShelfManager.GetAllOrders())
+
+	resp, body = postJSON(t, srv.URL+"/orders?mode=all-or-nothing", `[
+		{"name":"Salad","temp":"cold","shelfLife":300,"decayRate":0.5},
+		{"name":"Soup","temp":"hot"}
+	]`)
+	require.NoError(t, json.Unmarshal(body, &results))
+	assert.Equal(t, string(shelf.RejectBatch), results[0].Reason)
+	assert.Contains(t, results[1].Error, "shelfLife")
+	assert.Empty(t, sim.ShelfManager.GetAllOrders())
+
+	resp, _ = postJSON(t, srv.URL+"/orders?mode=sometimes", `[]`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+// byName defers pizzas and rejects sushi
+type byName struct{}
+
+func (byName) Admit(o plugin.Order, _ []plugin.Shelf) plugin.Admission {
+	switch o.Name {
+	case "Pizza":
+		return plugin.Defer
+	case "Sushi":
+		return plugin.Reject
+	}
+	return plugin.Accept
+}
+
+func TestServer_SubmitAdmission(t *testing.T) {
+	plugin.RegisterAdmissionPolicy("api-by-name", func() plugin.AdmissionPolicy { return byName{} })
+	cfg := config.DefaultConfig()
+	cfg.Service.Enabled = true
+	cfg.Admission.Policy = "api-by-name"
+	sim, err := simulator.NewSimulator(cfg, "")
+	require.NoError(t, err)
+	server := api.NewServer(sim.ShelfManager, sim.Events, sim.Archive)
+	server.SetService(sim)
+	server.SetReady(true)
+	srv := httptest.NewServer(server.Handler())
+	t.Cleanup(srv.Close)
+
+	var result api.PlacementResult
+	resp, body := postJSON(t, srv.URL+"/orders", `{"name":"Pizza","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, "deferred", result.Reason)
+
+	resp, body = postJSON(t, srv.URL+"/orders", `{"name":"Sushi","temp":"cold","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	require.NoError(t, json.Unmarshal(body, &result))
+	assert.Equal(t, "rejected", result.Reason)
+
+	resp, _ = postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+}
+
+func TestServer_ResetStats(t *testing.T) {
+	srv, _, sim := newServiceServer(t)
+	_, err := sim.Submit(simulator.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+	require.NoError(t, err)
+	_, err = sim.Submit(simulator.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+	require.Error(t, err)
+
+	resp, body := postJSON(t, srv.URL+"/api/stats/reset", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var stats struct {
+		TotalOrders map[string]int `json:"totalOrders"`
+	}
+	require.NoError(t, json.Unmarshal(body, &stats))
+	assert.Equal(t, 1, stats.TotalOrders["received"])
+	assert.Equal(t, 0, stats.TotalOrders["wasted"])
+}
+
+func TestServer_ServiceDisabled(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, _ := postJSON(t, srv.URL+"/orders", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = postJSON(t, srv.URL+"/api/stats/reset", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
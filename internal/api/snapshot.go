@@ -0,0 +1,81 @@
+package api
+
+import (
+	"sort"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// OrderView is the dashboard's view of one shelved order
+type OrderView struct {
+	ID    string  `json:"id"`
+	Name  string  `json:"name"`
+	Temp  string  `json:"temp"`
+	Shelf string  `json:"shelf"`
+	Value float64 `json:"value"`
+	Age   float64 `json:"age"` // seconds since first shelved
+
+	// PromisedAt is when the order should reach its customer, estimated on
+	// placement, or nil if no promise was made
+	PromisedAt *time.Time `json:"promisedAt,omitempty"`
+}
+
+func newOrderView(o *order.Order, now time.Time) OrderView {
+	view := OrderView{
+		ID:    o.ID,
+		Name:  o.Name,
+		Temp:  string(o.Temp),
+		Shelf: o.CurrentShelfType,
+		Value: o.CalculateValue(now),
+		Age:   now.Sub(o.PlacedOnShelfAt).Seconds(),
+	}
+	if !o.PromisedAt.IsZero() {
+		promised := o.PromisedAt
+		view.PromisedAt = &promised
+	}
+	return view
+}
+
+// ShelfView is the state of one shelf at a point in time
+type ShelfView struct {
+	Type       string      `json:"type"`
+	Capacity   int         `json:"capacity"`
+	Volume     float64     `json:"volume,omitempty"` // set on volume-limited shelves
+	UsedVolume float64     `json:"usedVolume,omitempty"`
+	InOutage   bool        `json:"inOutage"`
+	Orders     []OrderView `json:"orders"`
+}
+
+// Snapshot is the state of every shelf at a point in time
+type Snapshot struct {
+	Time    time.Time   `json:"time"`
+	Shelves []ShelfView `json:"shelves"`
+}
+
+// TakeSnapshot captures every shelf's contents with values computed at now
+func TakeSnapshot(manager shelf.ShelfManager, now time.Time) Snapshot {
+	states := manager.ShelfStates()
+
+	snapshot := Snapshot{Time: now, Shelves: make([]ShelfView, 0, len(states))}
+	for _, state := range states {
+		view := ShelfView{
+			Type:       string(state.Type),
+			Capacity:   state.Capacity,
+			Volume:     state.Volume,
+			UsedVolume: state.UsedVolume,
+			InOutage:   state.InOutage,
+			Orders:     make([]OrderView, 0, len(state.Orders)),
+		}
+		for _, o := range state.Orders {
+			view.Orders = append(view.Orders, newOrderView(o, now))
+		}
+		// Most urgent orders first
+		sort.Slice(view.Orders, func(i, j int) bool {
+			return view.Orders[i].Value < view.Orders[j].Value
+		})
+		snapshot.Shelves = append(snapshot.Shelves, view)
+	}
+	return snapshot
+}
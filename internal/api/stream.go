@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"dish-dispatcher/internal/simulator"
+)
+
+// StreamAck acknowledges one order of an order stream. Seq counts the
+// stream's orders from 1, so acks can be matched to orders without IDs;
+// Status is the code POST /orders would have answered the order with.
+type StreamAck struct {
+	Seq    int `json:"seq"`
+	Status int `json:"status"`
+	PlacementResult
+}
+
+// handleOrderStream serves POST /orders/stream, which places orders as the
+// client streams them and acknowledges each on the same connection. The
+// request body is a sequence of JSON orders, one after another as in
+// NDJSON; the response is an NDJSON StreamAck per order, sent as soon as it
+// is placed. The stream ends when the client closes its side, or after an
+// ack with seq 0 if the body is not valid JSON.
+func (s *Server) handleOrderStream(w http.ResponseWriter, r *http.Request) {
+	if s.service == nil {
+		writeError(w, http.StatusNotFound, errors.New("order ingestion is only available in service mode"))
+		return
+	}
+	if !s.ready.Load() {
+		writeError(w, http.StatusServiceUnavailable, errors.New("not ready"))
+		return
+	}
+
+	// HTTP/1.1 otherwise stops reading the request once the response
+	// starts; HTTP/2 streams are always full duplex
+	rc := http.NewResponseController(w)
+	if err := rc.EnableFullDuplex(); err != nil && r.ProtoMajor < 2 {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	dec := json.NewDecoder(r.Body)
+	enc := json.NewEncoder(w)
+	for seq := 1; ; seq++ {
+		var d simulator.OrderData
+		result, status := PlacementResult{}, 0
+		err := dec.Decode(&d)
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case err == nil:
+			result, status = s.submit(d, time.Now())
+		case errors.As(err, &typeErr):
+			// The decoder skips the rest of an order with a mistyped field
+			result, status = invalidResult(decodeError(err)), http.StatusBadRequest
+		case errors.Is(err, io.EOF) || r.Context().Err() != nil:
+			return
+		default:
+			// The decoder cannot find the next order after a syntax error
+			enc.Encode(StreamAck{Status: http.StatusBadRequest, PlacementResult: PlacementResult{Error: "invalid order: " + err.Error()}})
+			return
+		}
+
+		if err := enc.Encode(StreamAck{Seq: seq, Status: status, PlacementResult: result}); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
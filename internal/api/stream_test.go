@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/api"
+)
+
+func TestServer_OrderStream(t *testing.T) {
+	srv, _, _ := newServiceServer(t)
+
+	body, orders := io.Pipe()
+	defer orders.Close()
+	resp, err := http.Post(srv.URL+"/orders/stream", "application/x-ndjson", body)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	acks := bufio.NewScanner(resp.Body)
+	send := func(order string) api.StreamAck {
+		t.Helper()
+		_, err := io.WriteString(orders, order+"\n")
+		require.NoError(t, err)
+		require.True(t, acks.Scan(), "expected an ack: %v", acks.Err())
+		var ack api.StreamAck
+		require.NoError(t, json.Unmarshal(acks.Bytes(), &ack))
+		return ack
+	}
+
+	// Each order is acknowledged before the next is sent
+	ack := send(`{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, 1, ack.Seq)
+	assert.Equal(t, http.StatusCreated, ack.Status)
+	require.NotNil(t, ack.Order)
+	assert.Equal(t, "hot", ack.Order.Shelf)
+
+	ack = send(`{"name":"Stew","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, 2, ack.Seq)
+	assert.Equal(t, http.StatusConflict, ack.Status)
+	assert.NotEmpty(t, ack.Reason)
+
+	ack = send(`{"name":"","temp":"hot"}`)
+	assert.Equal(t, 3, ack.Seq)
+	assert.Equal(t, http.StatusBadRequest, ack.Status)
+
+	// A mistyped field skips just its order
+	ack = send(`{"name":"Soup","temp":"hot","shelfLife":"long"}`)
+	assert.Equal(t, 4, ack.Seq)
+	assert.Equal(t, http.StatusBadRequest, ack.Status)
+	require.Len(t, ack.Fields, 1)
+	assert.Equal(t, "shelfLife", ack.Fields[0].Field)
+
+	// A syntax error ends the stream
+	ack = send(`{"name": }`)
+	assert.Equal(t, http.StatusBadRequest, ack.Status)
+	orders.Close()
+	assert.False(t, acks.Scan())
+}
+
+func TestServer_OrderStream_NotService(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+
+	resp, _ := postJSON(t, srv.URL+"/orders/stream", `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,115 @@
+// Package archive keeps a bounded history of recently completed orders, so
+// dashboards can show recent outcomes without retaining every order.
+package archive
+
+import (
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// Entry is the final record of one completed order
+type Entry struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name"`
+	Temp        order.Temperature   `json:"temp"`
+	Shelf       string              `json:"shelf,omitempty"` // last shelf held, empty if never shelved
+	Outcome     order.State         `json:"outcome"`
+	CompletedAt time.Time           `json:"completedAt"`
+	FinalValue  float64             `json:"finalValue"` // value at delivery, zero for lost orders
+	Timeline    []order.StateChange `json:"timeline"`
+}
+
+// Archive is a fixed-size ring buffer of completed orders. Once full, each
+// new entry evicts the oldest. A nil Archive records nothing.
+type Archive struct {
+	mutex   sync.RWMutex
+	entries []Entry
+	next    int // slot the next entry is written to
+	count   int
+}
+
+// New creates an archive holding up to size entries, or nil if size is not
+// positive
+func New(size int) *Archive {
+	if size <= 0 {
+		return nil
+	}
+	return &Archive{entries: make([]Entry, size)}
+}
+
+// Observe is an order.TransitionHook that archives orders as they reach a
+// terminal state
+func (a *Archive) Observe(o *order.Order, from, to order.State, at time.Time) {
+	if a == nil || !to.Terminal() {
+		return
+	}
+
+	entry := Entry{
+		ID:          o.ID,
+		Name:        o.Name,
+		Temp:        o.Temp,
+		Shelf:       o.CurrentShelfType,
+		Outcome:     to,
+		CompletedAt: at,
+		Timeline:    o.History(),
+	}
+	if to == order.StateDelivered {
+		entry.FinalValue = o.CalculateValue(at)
+	}
+	a.Add(entry)
+}
+
+// Add records an entry, evicting the oldest if the archive is full
+func (a *Archive) Add(e Entry) {
+	if a == nil {
+		return
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.entries[a.next] = e
+	a.next = (a.next + 1) % len(a.entries)
+	if a.count < len(a.entries) {
+		a.count++
+	}
+}
+
+// Len returns how many entries are held
+func (a *Archive) Len() int {
+	if a == nil {
+		return 0
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	return a.count
+}
+
+// Recent returns up to limit entries, newest first, keeping only those with
+// the given outcome unless it is empty. A limit of zero or less returns
+// every match.
+func (a *Archive) Recent(limit int, outcome order.State) []Entry {
+	entries := make([]Entry, 0)
+	if a == nil {
+		return entries
+	}
+
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for i := 1; i <= a.count; i++ {
+		e := a.entries[(a.next-i+len(a.entries))%len(a.entries)]
+		if outcome != "" && e.Outcome != outcome {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) == limit {
+			break
+		}
+	}
+	return entries
+}
@@ -0,0 +1,93 @@
+package archive_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/archive"
+	"dish-dispatcher/internal/order"
+)
+
+func TestNew_Disabled(t *testing.T) {
+	a := archive.New(0)
+	assert.Nil(t, a)
+
+	// A nil archive is safe to use
+	a.Add(archive.Entry{ID: "1"})
+	assert.Zero(t, a.Len())
+	assert.Empty(t, a.Recent(10, ""))
+}
+
+func TestArchive_EvictsOldest(t *testing.T) {
+	a := archive.New(3)
+	for i := 1; i <= 5; i++ {
+		a.Add(archive.Entry{ID: strconv.Itoa(i), Outcome: order.StateDelivered})
+	}
+
+	assert.Equal(t, 3, a.Len())
+	recent := a.Recent(0, "")
+	require.Len(t, recent, 3)
+	assert.Equal(t, "5", recent[0].ID)
+	assert.Equal(t, "3", recent[2].ID)
+}
+
+func TestArchive_RecentFilters(t *testing.T) {
+	a := archive.New(10)
+	a.Add(archive.Entry{ID: "1", Outcome: order.StateDelivered})
+	a.Add(archive.Entry{ID: "2", Outcome: order.StateExpired})
+	a.Add(archive.Entry{ID: "3", Outcome: order.StateDelivered})
+	a.Add(archive.Entry{ID: "4", Outcome: order.StateDelivered})
+
+	delivered := a.Recent(2, order.StateDelivered)
+	require.Len(t, delivered, 2)
+	assert.Equal(t, "4", delivered[0].ID)
+	assert.Equal(t, "3", delivered[1].ID)
+
+	expired := a.Recent(0, order.StateExpired)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "2", expired[0].ID)
+}
+
+func TestArchive_Observe(t *testing.T) {
+	a := archive.New(10)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	o.OnTransition = a.Observe
+
+	start := time.Now()
+	o.PlacedOnShelfAt = start
+	o.CurrentShelfType = "hot"
+	require.NoError(t, o.Transition(order.StateShelved, start))
+	assert.Zero(t, a.Len())
+
+	deliveredAt := start.Add(100 * time.Second)
+	require.NoError(t, o.Transition(order.StateInTransit, deliveredAt))
+	require.NoError(t, o.Transition(order.StateDelivered, deliveredAt))
+
+	recent := a.Recent(1, "")
+	require.Len(t, recent, 1)
+	e := recent[0]
+	assert.Equal(t, o.ID, e.ID)
+	assert.Equal(t, "Burger", e.Name)
+	assert.Equal(t, "hot", e.Shelf)
+	assert.Equal(t, order.StateDelivered, e.Outcome)
+	assert.Equal(t, deliveredAt, e.CompletedAt)
+	assert.InDelta(t, (300-0.5*100)/300.0, e.FinalValue, 0.001)
+	assert.Len(t, e.Timeline, 3)
+}
+
+func TestArchive_ObserveLostOrder(t *testing.T) {
+	a := archive.New(10)
+	o := order.NewOrder("Salad", order.Cold, 300, 0.5)
+	o.OnTransition = a.Observe
+
+	require.NoError(t, o.Transition(order.StateWasted, time.Now()))
+
+	recent := a.Recent(0, order.StateWasted)
+	require.Len(t, recent, 1)
+	assert.Zero(t, recent[0].FinalValue)
+	assert.Empty(t, recent[0].Shelf)
+}
@@ -0,0 +1,94 @@
+// Package buildinfo identifies the running binary, so results can be traced
+// back to the code that produced them.
+//
+// Release builds stamp the version, commit and date with ldflags:
+//
+//	go build -ldflags "-X dish-dispatcher/internal/buildinfo.version=v1.2.0 \
+//		-X dish-dispatcher/internal/buildinfo.commit=$(git rev-parse HEAD) \
+//		-X dish-dispatcher/internal/buildinfo.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Anything left unstamped falls back to what Go records in the binary.
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set with -ldflags "-X dish-dispatcher/internal/buildinfo.name=value"
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`          // module version, "(devel)" for local builds
+	Commit    string `json:"commit,omitempty"` // VCS revision, with "+dirty" for uncommitted changes
+	Date      string `json:"date,omitempty"`   // when the binary was built, or else committed
+	GoVersion string `json:"goVersion"`
+}
+
+// Get returns the build information of the running binary
+func Get() Info {
+	info := Info{Version: version, Commit: commit, Date: date, GoVersion: runtime.Version()}
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		if info.Version == "" {
+			info.Version = "unknown"
+		}
+		return info
+	}
+	if info.Version == "" {
+		info.Version = build.Main.Version
+	}
+	if info.Version == "" {
+		info.Version = "(devel)"
+	}
+
+	var revision, committed string
+	var modified bool
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.time":
+			committed = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if info.Commit == "" && revision != "" {
+		info.Commit = revision
+		if modified {
+			info.Commit += "+dirty"
+		}
+	}
+	if info.Date == "" {
+		info.Date = committed
+	}
+	return info
+}
+
+// String returns the version and short commit, such as
+// "v1.2.0 (3f2a9c1d7e4b)" or "(devel) (3f2a9c1d7e4b+dirty)"
+func (i Info) String() string {
+	if i.Commit == "" {
+		return i.Version
+	}
+	revision, dirty := strings.CutSuffix(i.Commit, "+dirty")
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if dirty {
+		revision += "+dirty"
+	}
+	return i.Version + " (" + revision + ")"
+}
+
+// Version returns the version and short commit of the running binary
+func Version() string {
+	return Get().String()
+}
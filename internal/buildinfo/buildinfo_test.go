@@ -0,0 +1,26 @@
+package buildinfo_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/buildinfo"
+)
+
+func TestGet(t *testing.T) {
+	// Test binaries carry build information without a module version
+	info := buildinfo.Get()
+	assert.Equal(t, "(devel)", info.Version)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Contains(t, buildinfo.Version(), "(devel)")
+}
+
+func TestInfo_String(t *testing.T) {
+	assert.Equal(t, "v1.2.0", buildinfo.Info{Version: "v1.2.0"}.String())
+	assert.Equal(t, "v1.2.0 (3f2a9c1d7e4b)",
+		buildinfo.Info{Version: "v1.2.0", Commit: "3f2a9c1d7e4b5a6978123456"}.String())
+	assert.Equal(t, "(devel) (3f2a9c1d7e4b+dirty)",
+		buildinfo.Info{Version: "(devel)", Commit: "3f2a9c1d7e4b5a6978123456+dirty"}.String())
+}
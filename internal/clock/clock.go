@@ -0,0 +1,52 @@
+// Package clock abstracts the current time so shelf managers can be driven
+// by a fake clock in deterministic tests.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock reports the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// Real is the wall clock
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+// Fake is a manually advanced clock. It is safe for concurrent use.
+type Fake struct {
+	mutex sync.Mutex
+	now   time.Time
+}
+
+// NewFake returns a fake clock stopped at start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.now
+}
+
+// Advance moves the clock forward by d
+func (f *Fake) Advance(d time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = f.now.Add(d)
+}
+
+// Set moves the clock to t, which may be in the past
+func (f *Fake) Set(t time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.now = t
+}
@@ -0,0 +1,22 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/clock"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFake(start)
+	assert.Equal(t, start, c.Now())
+
+	c.Advance(90 * time.Second)
+	assert.Equal(t, start.Add(90*time.Second), c.Now())
+
+	c.Set(start)
+	assert.Equal(t, start, c.Now())
+}
@@ -0,0 +1,171 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/grpcwire"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// DefaultStaleAfter is how long a node may go without reporting before the
+// coordinator flags it as stale
+const DefaultStaleAfter = 30 * time.Second
+
+// Coordinator collects node reports and serves the aggregated stats
+type Coordinator struct {
+	// StaleAfter flags nodes whose last report is older than this. Stale
+	// nodes still count towards the totals, since their orders happened.
+	StaleAfter time.Duration
+
+	mutex   sync.RWMutex
+	reports map[string]NodeReport
+	mux     *http.ServeMux
+}
+
+// NewCoordinator creates a coordinator with no nodes
+func NewCoordinator() *Coordinator {
+	c := &Coordinator{
+		StaleAfter: DefaultStaleAfter,
+		reports:    make(map[string]NodeReport),
+		mux:        http.NewServeMux(),
+	}
+
+	c.mux.HandleFunc(servicePath, c.serveRPC)
+	c.mux.HandleFunc("GET /cluster/stats", c.handleStats)
+
+	return c
+}
+
+// Record stores a node's report, ignoring it if a newer one is already held
+func (c *Coordinator) Record(report NodeReport) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if current, ok := c.reports[report.NodeID]; ok && current.Timestamp.After(report.Timestamp) {
+		return
+	}
+	c.reports[report.NodeID] = report
+}
+
+// Stats aggregates the latest report of every node, evaluated at now
+func (c *Coordinator) Stats(now time.Time) ClusterStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	stats := ClusterStats{
+		Nodes:         make([]NodeStatus, 0, len(c.reports)),
+		ByName:        make(map[string]shelf.ItemStats),
+		ByTemperature: make(map[order.Temperature]shelf.ItemStats),
+	}
+	for _, report := range c.reports {
+		stats.add(report)
+		stats.Nodes = append(stats.Nodes, NodeStatus{
+			NodeID:   report.NodeID,
+			LastSeen: report.Timestamp,
+			Stale:    now.Sub(report.Timestamp) > c.StaleAfter,
+			Received: report.Received,
+			Run:      report.Run,
+			RunID:    report.RunID,
+			Tags:     report.Tags,
+		})
+	}
+	sort.Slice(stats.Nodes, func(i, j int) bool {
+		return stats.Nodes[i].NodeID < stats.Nodes[j].NodeID
+	})
+	return stats
+}
+
+// Handler returns the coordinator's handler, serving both the gRPC
+// Coordinator service and the stats over plain HTTP
+func (c *Coordinator) Handler() http.Handler {
+	return c.mux
+}
+
+// Protocols returns the protocols the coordinator speaks: HTTP/2 for the
+// gRPC service, unencrypted or over TLS, and HTTP/1 as well for the stats
+func Protocols() *http.Protocols {
+	p := grpcwire.Protocols()
+	p.SetHTTP1(true)
+	return p
+}
+
+// ListenAndServe serves on addr until ctx is cancelled, then shuts down
+// gracefully. A non-nil tlsConfig serves over TLS.
+func (c *Coordinator) ListenAndServe(ctx context.Context, addr string, tlsConfig *tls.Config) error {
+	httpServer := &http.Server{Addr: addr, Handler: c.mux, Protocols: Protocols(), TLSConfig: tlsConfig}
+
+	errCh := make(chan error, 1)
+	go func() {
+		if tlsConfig != nil {
+			errCh <- httpServer.ListenAndServeTLS("", "")
+			return
+		}
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+// servicePath prefixes the path of every method of the Coordinator service
+const servicePath = "/dishdispatcher.cluster.v1.Coordinator/"
+
+// serveRPC serves the Coordinator service
+func (c *Coordinator) serveRPC(w http.ResponseWriter, r *http.Request) {
+	if !grpcwire.CheckCall(w, r) {
+		return
+	}
+
+	var err error
+	switch r.URL.Path {
+	case servicePath + "Report":
+		err = c.serveReport(w, r)
+	default:
+		err = grpcwire.Errorf(grpcwire.CodeUnimplemented, "unknown method %s", r.URL.Path)
+	}
+	grpcwire.WriteStatus(w, err)
+}
+
+// serveReport handles Report
+func (c *Coordinator) serveReport(w http.ResponseWriter, r *http.Request) error {
+	var report NodeReport
+	if err := grpcwire.ReadRequest(r, report.unmarshal); err != nil {
+		return err
+	}
+	if report.NodeID == "" {
+		return grpcwire.Errorf(grpcwire.CodeInvalidArgument, "report has no node ID")
+	}
+
+	c.Record(report)
+	return grpcwire.WriteReply(w, nil)
+}
+
+func (c *Coordinator) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, c.Stats(time.Now()))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,91 @@
+package cluster_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/cluster"
+	"dish-dispatcher/internal/grpcwire"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestCoordinator_Stats(t *testing.T) {
+	c := cluster.NewCoordinator()
+	now := time.Now()
+
+	c.Record(cluster.NodeReport{
+		NodeID: "b", Timestamp: now, Received: 10, Delivered: 6, Wasted: 4,
+		ByTemperature: map[order.Temperature]shelf.ItemStats{order.Hot: {Delivered: 6, TotalDeliveredValue: 3}},
+	})
+	c.Record(cluster.NodeReport{
+		NodeID: "a", Timestamp: now.Add(-time.Minute), Received: 5, Delivered: 2, Expired: 3,
+		ByTemperature: map[order.Temperature]shelf.ItemStats{order.Hot: {Delivered: 2, TotalDeliveredValue: 1}},
+	})
+
+	stats := c.Stats(now)
+	assert.Equal(t, 15, stats.Received)
+	assert.Equal(t, 8, stats.Delivered)
+	assert.Equal(t, 3, stats.Expired)
+	assert.Equal(t, 4, stats.Wasted)
+	assert.Equal(t, 0.5, stats.ByTemperature[order.Hot].AverageDeliveredValue())
+
+	require.Len(t, stats.Nodes, 2)
+	assert.Equal(t, "a", stats.Nodes[0].NodeID)
+	assert.True(t, stats.Nodes[0].Stale)
+	assert.False(t, stats.Nodes[1].Stale)
+}
+
+func TestCoordinator_RecordKeepsNewest(t *testing.T) {
+	c := cluster.NewCoordinator()
+	now := time.Now()
+
+	c.Record(cluster.NodeReport{NodeID: "a", Timestamp: now, Received: 10})
+	c.Record(cluster.NodeReport{NodeID: "a", Timestamp: now.Add(-time.Second), Received: 5})
+
+	assert.Equal(t, 10, c.Stats(now).Received)
+}
+
+func TestCoordinator_Serve(t *testing.T) {
+	c := cluster.NewCoordinator()
+	server := newCoordinatorServer(t, c)
+
+	manager := shelf.NewShelfManager(1, 1, 1, 1)
+	manager.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5))
+	require.NoError(t, cluster.NewReporter("a", server, time.Minute, manager).Report(context.Background()))
+
+	// Reports must name their node
+	err := cluster.NewReporter("", server, time.Minute, manager).Report(context.Background())
+	var status *grpcwire.StatusError
+	require.ErrorAs(t, err, &status)
+	assert.Equal(t, grpcwire.CodeInvalidArgument, status.Code)
+
+	// The stats are served over plain HTTP
+	resp, err := http.Get("http://" + server + "/cluster/stats")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var stats cluster.ClusterStats
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&stats))
+	assert.Equal(t, 1, stats.Received)
+	assert.Len(t, stats.Nodes, 1)
+}
+
+// newCoordinatorServer serves c for the test and returns its address
+func newCoordinatorServer(t *testing.T, c *cluster.Coordinator) string {
+	t.Helper()
+
+	ts := httptest.NewUnstartedServer(c.Handler())
+	ts.Config.Protocols = cluster.Protocols()
+	ts.Start()
+	t.Cleanup(ts.Close)
+	return strings.TrimPrefix(ts.URL, "http://")
+}
@@ -0,0 +1,136 @@
+package cluster
+
+import (
+	"time"
+
+	"dish-dispatcher/internal/grpcwire"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// NodeReport and the item stats it carries encode themselves in the
+// protobuf wire format of cluster.proto with the grpcwire helpers
+
+func (r NodeReport) marshal() []byte {
+	var b []byte
+	b = grpcwire.AppendString(b, 1, r.NodeID)
+	if !r.Timestamp.IsZero() {
+		b = grpcwire.AppendInt(b, 2, r.Timestamp.UnixNano())
+	}
+	b = grpcwire.AppendString(b, 3, r.Run)
+	b = grpcwire.AppendString(b, 4, r.RunID)
+	for k, v := range r.Tags {
+		b = grpcwire.AppendBytes(b, 5, grpcwire.AppendString(grpcwire.AppendString(nil, 1, k), 2, v))
+	}
+	b = grpcwire.AppendInt(b, 6, int64(r.Received))
+	b = grpcwire.AppendInt(b, 7, int64(r.Delivered))
+	b = grpcwire.AppendInt(b, 8, int64(r.Expired))
+	b = grpcwire.AppendInt(b, 9, int64(r.Wasted))
+	for name, stats := range r.ByName {
+		b = grpcwire.AppendBytes(b, 10, marshalItemStatsEntry(name, stats))
+	}
+	for temp, stats := range r.ByTemperature {
+		b = grpcwire.AppendBytes(b, 11, marshalItemStatsEntry(string(temp), stats))
+	}
+	return b
+}
+
+func (r *NodeReport) unmarshal(b []byte) error {
+	return grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		switch field {
+		case 1:
+			r.NodeID = f.String()
+		case 2:
+			r.Timestamp = time.Unix(0, f.Int())
+		case 3:
+			r.Run = f.String()
+		case 4:
+			r.RunID = f.String()
+		case 5:
+			var k, v string
+			err := grpcwire.DecodeFields(f.Bytes, func(field int, f grpcwire.Field) error {
+				switch field {
+				case 1:
+					k = f.String()
+				case 2:
+					v = f.String()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if r.Tags == nil {
+				r.Tags = make(map[string]string)
+			}
+			r.Tags[k] = v
+		case 6:
+			r.Received = int(f.Int())
+		case 7:
+			r.Delivered = int(f.Int())
+		case 8:
+			r.Expired = int(f.Int())
+		case 9:
+			r.Wasted = int(f.Int())
+		case 10:
+			name, stats, err := unmarshalItemStatsEntry(f.Bytes)
+			if err != nil {
+				return err
+			}
+			if r.ByName == nil {
+				r.ByName = make(map[string]shelf.ItemStats)
+			}
+			r.ByName[name] = stats
+		case 11:
+			temp, stats, err := unmarshalItemStatsEntry(f.Bytes)
+			if err != nil {
+				return err
+			}
+			if r.ByTemperature == nil {
+				r.ByTemperature = make(map[order.Temperature]shelf.ItemStats)
+			}
+			r.ByTemperature[order.Temperature(temp)] = stats
+		}
+		return nil
+	})
+}
+
+// marshalItemStatsEntry encodes one entry of a map<string, ItemStats>
+func marshalItemStatsEntry(key string, s shelf.ItemStats) []byte {
+	var v []byte
+	v = grpcwire.AppendInt(v, 1, int64(s.Delivered))
+	v = grpcwire.AppendInt(v, 2, int64(s.Wasted))
+	v = grpcwire.AppendInt(v, 3, int64(s.Expired))
+	v = grpcwire.AppendDouble(v, 4, s.TotalDeliveredValue)
+	return grpcwire.AppendBytes(grpcwire.AppendString(nil, 1, key), 2, v)
+}
+
+// unmarshalItemStatsEntry decodes one entry of a map<string, ItemStats>
+func unmarshalItemStatsEntry(b []byte) (string, shelf.ItemStats, error) {
+	var (
+		key   string
+		stats shelf.ItemStats
+	)
+	err := grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		switch field {
+		case 1:
+			key = f.String()
+		case 2:
+			return grpcwire.DecodeFields(f.Bytes, func(field int, f grpcwire.Field) error {
+				switch field {
+				case 1:
+					stats.Delivered = int(f.Int())
+				case 2:
+					stats.Wasted = int(f.Int())
+				case 3:
+					stats.Expired = int(f.Int())
+				case 4:
+					stats.TotalDeliveredValue = f.Double()
+				}
+				return nil
+			})
+		}
+		return nil
+	})
+	return key, stats, err
+}
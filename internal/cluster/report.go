@@ -0,0 +1,95 @@
+// Package cluster runs the simulator across several processes. Each node
+// periodically reports its outcome counters to a coordinator over gRPC,
+// which aggregates them into cluster-wide stats.
+package cluster
+
+import (
+	"time"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// NodeReport is a node's cumulative counters at one point in time. Reports
+// replace rather than add to earlier ones, so a lost report is harmless.
+type NodeReport struct {
+	NodeID    string    `json:"nodeId"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// Run, RunID and Tags label the experiment the node is running
+	Run   string            `json:"run,omitempty"`
+	RunID string            `json:"runId,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+
+	Received  int `json:"received"`
+	Delivered int `json:"delivered"`
+	Expired   int `json:"expired"`
+	Wasted    int `json:"wasted"`
+
+	ByName        map[string]shelf.ItemStats            `json:"byName"`
+	ByTemperature map[order.Temperature]shelf.ItemStats `json:"byTemperature"`
+}
+
+// NewNodeReport reads the current counters from a node's shelf manager
+func NewNodeReport(nodeID string, manager shelf.ShelfManager, now time.Time) NodeReport {
+	report := NodeReport{
+		NodeID:        nodeID,
+		Timestamp:     now,
+		ByName:        manager.StatsByName(),
+		ByTemperature: manager.StatsByTemperature(),
+	}
+
+	if totals, ok := manager.GetStats()["totalOrders"].(map[string]interface{}); ok {
+		report.Received, _ = totals["received"].(int)
+		report.Delivered, _ = totals["delivered"].(int)
+		report.Expired, _ = totals["expired"].(int)
+		report.Wasted, _ = totals["wasted"].(int)
+	}
+	return report
+}
+
+// ClusterStats aggregates the latest report from every node
+type ClusterStats struct {
+	Nodes []NodeStatus `json:"nodes"`
+
+	Received  int `json:"received"`
+	Delivered int `json:"delivered"`
+	Expired   int `json:"expired"`
+	Wasted    int `json:"wasted"`
+
+	ByName        map[string]shelf.ItemStats            `json:"byName"`
+	ByTemperature map[order.Temperature]shelf.ItemStats `json:"byTemperature"`
+}
+
+// NodeStatus summarizes one node's latest report
+type NodeStatus struct {
+	NodeID   string    `json:"nodeId"`
+	LastSeen time.Time `json:"lastSeen"`
+	Stale    bool      `json:"stale"` // no report within the coordinator's StaleAfter
+	Received int       `json:"received"`
+
+	Run   string            `json:"run,omitempty"`
+	RunID string            `json:"runId,omitempty"`
+	Tags  map[string]string `json:"tags,omitempty"`
+}
+
+// add folds a node report into the cluster totals
+func (cs *ClusterStats) add(report NodeReport) {
+	cs.Received += report.Received
+	cs.Delivered += report.Delivered
+	cs.Expired += report.Expired
+	cs.Wasted += report.Wasted
+	mergeItemStats(cs.ByName, report.ByName)
+	mergeItemStats(cs.ByTemperature, report.ByTemperature)
+}
+
+func mergeItemStats[K comparable](dst, src map[K]shelf.ItemStats) {
+	for k, s := range src {
+		merged := dst[k]
+		merged.Delivered += s.Delivered
+		merged.Wasted += s.Wasted
+		merged.Expired += s.Expired
+		merged.TotalDeliveredValue += s.TotalDeliveredValue
+		dst[k] = merged
+	}
+}
@@ -0,0 +1,93 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dish-dispatcher/internal/grpcwire"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// DefaultReportInterval is how often nodes report when none is configured
+const DefaultReportInterval = 5 * time.Second
+
+// Reporter pushes a node's counters to the coordinator's gRPC service on
+// an interval
+type Reporter struct {
+	NodeID      string
+	Coordinator string // address, e.g. coordinator:9090
+	Interval    time.Duration
+
+	// RunName and Tags label every report. Set them before Run.
+	RunName string
+	Tags    map[string]string
+	// RunID, if set, returns the ID of the run each report is labelled
+	// with, which changes as the node's stats are reset
+	RunID func() string
+
+	manager shelf.ShelfManager
+	base    string // URL the service is called at
+	client  *http.Client
+}
+
+// NewReporter creates a reporter for the node's shelf manager
+func NewReporter(nodeID, coordinator string, interval time.Duration, manager shelf.ShelfManager) *Reporter {
+	if interval <= 0 {
+		interval = DefaultReportInterval
+	}
+	return &Reporter{
+		NodeID:      nodeID,
+		Coordinator: coordinator,
+		Interval:    interval,
+		manager:     manager,
+		base:        "http://" + coordinator,
+		client: &http.Client{
+			Timeout:   5 * time.Second,
+			Transport: &http.Transport{Protocols: grpcwire.Protocols()},
+		},
+	}
+}
+
+// SetTLS connects to a coordinator served over TLS with tlsConfig
+func (r *Reporter) SetTLS(tlsConfig *tls.Config) {
+	r.base = "https://" + r.Coordinator
+	r.client.Transport = &http.Transport{Protocols: grpcwire.Protocols(), TLSClientConfig: tlsConfig}
+}
+
+// Run reports every Interval until ctx is cancelled, then sends one final
+// report so the coordinator sees the node's closing counters. Failed
+// reports are logged and retried on the next tick.
+func (r *Reporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Report(ctx); err != nil {
+				fmt.Printf("Cluster report failed: %v\n", err)
+			}
+		case <-ctx.Done():
+			if err := r.Report(context.Background()); err != nil {
+				fmt.Printf("Final cluster report failed: %v\n", err)
+			}
+			return
+		}
+	}
+}
+
+// Report sends the node's current counters to the coordinator
+func (r *Reporter) Report(ctx context.Context) error {
+	report := NewNodeReport(r.NodeID, r.manager, time.Now())
+	report.Run = r.RunName
+	report.Tags = r.Tags
+	if r.RunID != nil {
+		report.RunID = r.RunID()
+	}
+
+	_, err := grpcwire.Invoke(ctx, r.client, r.base+servicePath+"Report", report.marshal())
+	return err
+}
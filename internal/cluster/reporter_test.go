@@ -0,0 +1,63 @@
+package cluster_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/cluster"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestReporter_Report(t *testing.T) {
+	c := cluster.NewCoordinator()
+	server := newCoordinatorServer(t, c)
+
+	manager := shelf.NewShelfManager(1, 1, 1, 1)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	manager.PlaceOrder(o)
+	manager.DeliverOrder(o.ID)
+
+	reporter := cluster.NewReporter("node-1", server, time.Minute, manager)
+	reporter.RunName = "baseline"
+	reporter.Tags = map[string]string{"shelves": "small"}
+	reporter.RunID = func() string { return "baseline-1a2b3c4d" }
+	require.NoError(t, reporter.Report(context.Background()))
+
+	stats := c.Stats(time.Now())
+	require.Len(t, stats.Nodes, 1)
+	assert.Equal(t, "baseline", stats.Nodes[0].Run)
+	assert.Equal(t, "baseline-1a2b3c4d", stats.Nodes[0].RunID)
+	assert.Equal(t, "small", stats.Nodes[0].Tags["shelves"])
+	assert.Equal(t, 1, stats.Received)
+	assert.Equal(t, 1, stats.Delivered)
+	assert.Equal(t, 1, stats.ByName["Burger"].Delivered)
+	assert.Equal(t, 1, stats.ByTemperature[order.Hot].Delivered)
+	assert.Greater(t, stats.ByTemperature[order.Hot].TotalDeliveredValue, 0.0)
+}
+
+func TestReporter_RunSendsFinalReport(t *testing.T) {
+	c := cluster.NewCoordinator()
+	server := newCoordinatorServer(t, c)
+
+	reporter := cluster.NewReporter("node-1", server, time.Hour, shelf.NewShelfManager(1, 1, 1, 1))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reporter.Run(ctx)
+		close(done)
+	}()
+
+	cancel()
+	<-done
+	assert.Len(t, c.Stats(time.Now()).Nodes, 1)
+}
+
+func TestReporter_CoordinatorDown(t *testing.T) {
+	reporter := cluster.NewReporter("node-1", "127.0.0.1:1", time.Minute, shelf.NewShelfManager(1, 1, 1, 1))
+	assert.Error(t, reporter.Report(context.Background()))
+}
@@ -2,7 +2,9 @@ package config_test
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +20,30 @@ func TestDefaultConfig(t *testing.T) {
 	assert.Equal(t, 30, cfg.OverflowCapacity)
 	assert.Equal(t, 2.0, cfg.OrdersPerSecond)
 	assert.Equal(t, 300, cfg.SimulationDuration)
+	assert.Equal(t, config.ExpiryModeScheduled, cfg.ExpiryMode)
+	assert.Equal(t, "classic", cfg.DecayFormula)
+	assert.Equal(t, config.ShelfBackendMemory, cfg.ShelfBackend)
+	assert.Equal(t, config.EventFormatNative, cfg.EventFormat)
+	assert.Equal(t, "/dish-dispatcher", cfg.EventSource)
+	assert.Equal(t, 60, cfg.StatsWindow)
+	assert.Equal(t, "manifest.json", cfg.ManifestFile)
+	assert.Equal(t, 5.0, cfg.Throttle.Smoothing)
+	assert.Equal(t, 30.0, cfg.Admission.MaxDefer)
+	assert.Equal(t, 1.0, cfg.Escalation.Interval)
+	assert.Equal(t, config.EscalateBoth, cfg.Escalation.Action)
+	assert.Equal(t, 1.0, cfg.Couriers.ReserveGrace)
+	assert.Equal(t, config.OrdersModeSequence, cfg.Orders.Mode)
+	assert.Equal(t, 1.0, cfg.Orders.WatchInterval)
+	assert.Equal(t, config.ClusterModeStandalone, cfg.Cluster.Mode)
+	assert.Equal(t, config.InvariantCheckOff, cfg.Invariants.Mode)
+	assert.Equal(t, 0.5, cfg.Cleanup.Interval)
+	assert.Equal(t, 1.0, cfg.TimeSeries.Interval)
+	assert.Equal(t, 86400, cfg.TimeSeries.MaxSamples)
+	assert.Equal(t, 65536, cfg.Results.RowGroupSize)
+	assert.Equal(t, 1000, cfg.Trace.MaxOrders)
+	assert.Equal(t, config.PostMortemConfig{Top: 3, Window: 10}, cfg.PostMortem)
+	assert.Equal(t, "dish-dispatcher", cfg.MQTT.TopicPrefix)
+	assert.Equal(t, 16, cfg.AMQP.Prefetch)
 }
 
 func TestLoadConfig_FileNotFound(t *testing.T) {
@@ -67,3 +93,81 @@ func TestLoadConfig_InvalidJSON(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, cfg)
 }
+
+func TestLoadConfig_Version(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+
+	for _, version := range []int{1, 2} {
+		assert.NoError(t, os.WriteFile(path, []byte(fmt.Sprintf(`{"version": %d, "ordersPerSecond": 4}`, version)), 0o644))
+		cfg, err := config.LoadConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, version, cfg.Version)
+		assert.Equal(t, 4.0, cfg.OrdersPerSecond)
+	}
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"version": 3, "ordersPerSecond": 4}`), 0o644))
+	_, err := config.LoadConfig(path)
+	assert.ErrorContains(t, err, "unsupported version 3, expected 1 to 2")
+}
+
+func TestLoadConfig_Strict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	typo := "{\n  \"couriers\": {\"count\": 2, \"strategyy\": \"fifo\"},\n  \"ordersPerSec\": 4\n}"
+	assert.NoError(t, os.WriteFile(path, []byte(typo), 0o644))
+
+	// Lenient by default, as before
+	cfg, err := config.LoadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.0, cfg.OrdersPerSecond)
+	assert.False(t, cfg.Strict)
+
+	_, err = config.LoadConfigStrict(path)
+	assert.EqualError(t, err, path+`: line 2, column 28: unknown field "couriers.strategyy"`)
+
+	// The file can opt in itself
+	assert.NoError(t, os.WriteFile(path, []byte(`{"strict": true, "ordersPerSec": 4}`), 0o644))
+	_, err = config.LoadConfig(path)
+	assert.EqualError(t, err, path+`: line 1, column 18: unknown field "ordersPerSec"`)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`{"ordersPerSecond": 4}`), 0o644))
+	cfg, err = config.LoadConfigStrict(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 4.0, cfg.OrdersPerSecond)
+	assert.True(t, cfg.Strict)
+
+	cfg, err = config.LoadConfigStrict(filepath.Join(t.TempDir(), "missing.json"))
+	assert.NoError(t, err)
+	assert.True(t, cfg.Strict)
+}
+
+func TestRunConfig_Label(t *testing.T) {
+	assert.Empty(t, config.RunConfig{}.Label())
+	assert.Equal(t, "baseline", config.RunConfig{Name: "baseline"}.Label())
+	assert.Equal(t, "[shelves=small]", config.RunConfig{Tags: map[string]string{"shelves": "small"}}.Label())
+	assert.Equal(t, "baseline [region=eu shelves=small]", config.RunConfig{
+		Name: "baseline",
+		Tags: map[string]string{"shelves": "small", "region": "eu"},
+	}.Label())
+}
+
+func TestNewRunContext(t *testing.T) {
+	run := config.RunConfig{Name: "baseline", Tags: map[string]string{"shelves": "small"}}
+	first, second := config.NewRunContext(run), config.NewRunContext(run)
+
+	assert.Regexp(t, `^baseline-[0-9a-f]{8}$`, first.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, run, first.RunConfig)
+	assert.Regexp(t, `^[0-9a-f]{8}$`, config.NewRunContext(config.RunConfig{}).ID)
+}
+
+func TestSourceConfig_Label(t *testing.T) {
+	assert.Equal(t, "generator", config.SourceConfig{Type: config.SourceGenerator}.Label())
+	assert.Equal(t, "adhoc", config.SourceConfig{Name: "adhoc", Type: config.SourceHTTP}.Label())
+}
+
+func TestOrdersConfig_Rearranged(t *testing.T) {
+	assert.False(t, config.OrdersConfig{Mode: config.OrdersModeSequence, Seed: 3, Repeat: 1}.Rearranged())
+	assert.True(t, config.OrdersConfig{Repeat: 2}.Rearranged())
+	assert.True(t, config.OrdersConfig{Shuffle: true}.Rearranged())
+	assert.True(t, config.OrdersConfig{Limit: 10}.Rearranged())
+}
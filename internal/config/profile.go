@@ -0,0 +1,106 @@
+package config
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"dish-dispatcher/internal/strictjson"
+)
+
+// presets are the profiles built into the binary
+//
+//go:embed profiles/*.json
+var presets embed.FS
+
+// ProfileTag is the run tag recording the profile a run was configured with
+const ProfileTag = "profile"
+
+// Profiles returns the names of the built-in profiles and those in dir, a
+// directory of name.json files, sorted. A missing dir has no profiles.
+func Profiles(dir string) ([]string, error) {
+	seen := make(map[string]bool)
+	builtIn, _ := fs.Glob(presets, "profiles/*.json")
+	for _, path := range builtIn {
+		seen[strings.TrimSuffix(filepath.Base(path), ".json")] = true
+	}
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if name, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() {
+				seen[name] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ApplyProfile overlays the named profile on the config: only the fields
+// the profile sets change, and maps are merged. A profile in dir takes
+// precedence over a built-in one of the same name. The run is tagged with
+// the profile unless the tag is already set.
+func (c *Config) ApplyProfile(name, dir string) error {
+	data, err := readProfile(name, dir)
+	if err != nil {
+		return err
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if json.Unmarshal(data, &header) == nil {
+		if err := CheckSchemaVersion(header.Version); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
+		}
+	}
+	if c.Strict {
+		err = strictjson.Unmarshal(data, c)
+	} else {
+		err = json.Unmarshal(data, c)
+	}
+	if err != nil {
+		return fmt.Errorf("profile %s: %w", name, err)
+	}
+
+	if _, ok := c.Run.Tags[ProfileTag]; !ok {
+		if c.Run.Tags == nil {
+			c.Run.Tags = make(map[string]string)
+		}
+		c.Run.Tags[ProfileTag] = name
+	}
+	return nil
+}
+
+// readProfile returns the JSON of the named profile
+func readProfile(name, dir string) ([]byte, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+	if dir != "" {
+		data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	data, err := presets.ReadFile("profiles/" + name + ".json")
+	if err != nil {
+		names, _ := Profiles(dir)
+		return nil, fmt.Errorf("unknown profile %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return data, nil
+}
@@ -0,0 +1,62 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/config"
+)
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "night-shift.json"), []byte(`{}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`-`), 0o644))
+
+	names, err := config.Profiles(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"busy-restaurant", "night-shift", "small-kitchen", "stress-test"}, names)
+
+	names, err = config.Profiles(filepath.Join(dir, "missing"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"busy-restaurant", "small-kitchen", "stress-test"}, names)
+}
+
+func TestApplyProfile_BuiltIn(t *testing.T) {
+	for _, name := range []string{"small-kitchen", "busy-restaurant", "stress-test"} {
+		cfg := config.DefaultConfig()
+		cfg.Strict = true // the presets must name only real fields
+		assert.NoError(t, cfg.ApplyProfile(name, ""), name)
+		assert.Equal(t, name, cfg.Run.Tags[config.ProfileTag])
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.SimulationDuration = 60
+	assert.NoError(t, cfg.ApplyProfile("small-kitchen", ""))
+	assert.Equal(t, 6, cfg.HotShelfCapacity)
+	assert.Equal(t, 2, cfg.Couriers.Count)
+	assert.Equal(t, 60, cfg.SimulationDuration, "fields the profile does not set are kept")
+	assert.Equal(t, "nearest-idle", cfg.Couriers.Strategy, "nested fields the profile does not set are kept")
+}
+
+func TestApplyProfile_UserDefined(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "small-kitchen.json"), []byte(`{"hotShelfCapacity": 3, "run": {"tags": {"site": "test"}}}`), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "typo.json"), []byte(`{"ordersPerSec": 3}`), 0o644))
+
+	cfg := config.DefaultConfig()
+	cfg.Run.Tags = map[string]string{"team": "a", config.ProfileTag: "custom"}
+	assert.NoError(t, cfg.ApplyProfile("small-kitchen", dir))
+	assert.Equal(t, 3, cfg.HotShelfCapacity, "the user's profile overrides the built-in one")
+	assert.Equal(t, 20, cfg.ColdShelfCapacity)
+	assert.Equal(t, map[string]string{"team": "a", "site": "test", config.ProfileTag: "custom"}, cfg.Run.Tags)
+
+	assert.NoError(t, cfg.ApplyProfile("typo", dir))
+	cfg.Strict = true
+	assert.EqualError(t, cfg.ApplyProfile("typo", dir), `profile typo: line 1, column 2: unknown field "ordersPerSec"`)
+
+	assert.ErrorContains(t, cfg.ApplyProfile("huge-kitchen", dir), `unknown profile "huge-kitchen", expected one of busy-restaurant, small-kitchen, stress-test, typo`)
+	assert.ErrorContains(t, cfg.ApplyProfile("../etc/passwd", dir), "invalid profile name")
+}
@@ -0,0 +1,253 @@
+// Package courier models a fleet of couriers collecting orders from the
+// kitchen, with a pluggable policy for which free courier takes each order.
+package courier
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// Courier is one member of the fleet. Positions are measured in seconds of
+// travel from the kitchen, which sits at the origin.
+type Courier struct {
+	ID         int
+	X, Y       float64
+	Deliveries int // orders picked up so far
+
+	busy bool
+}
+
+// Distance returns the courier's travel time to the kitchen
+func (c *Courier) Distance() time.Duration {
+	return time.Duration(math.Hypot(c.X, c.Y) * float64(time.Second))
+}
+
+// RandomCouriers creates n couriers at random positions within reach
+// seconds of the kitchen
+func RandomCouriers(n int, reach float64, rng *rand.Rand) []*Courier {
+	couriers := make([]*Courier, n)
+	for i := range couriers {
+		x, y := RandomPosition(reach, rng)
+		couriers[i] = &Courier{ID: i + 1, X: x, Y: y}
+	}
+	return couriers
+}
+
+// RandomPosition returns a point uniformly distributed within reach seconds
+// of the kitchen
+func RandomPosition(reach float64, rng *rand.Rand) (float64, float64) {
+	r := reach * math.Sqrt(rng.Float64())
+	theta := 2 * math.Pi * rng.Float64()
+	return r * math.Cos(theta), r * math.Sin(theta)
+}
+
+// StrategyStats summarizes the pickups made under one assignment strategy
+type StrategyStats struct {
+	Assignments  int           `json:"assignments"`
+	Pickups      int           `json:"pickups"`
+	Missed       int           `json:"missed"` // orders gone before the courier arrived
+	TotalLatency time.Duration `json:"totalLatency"`
+	MaxLatency   time.Duration `json:"maxLatency"`
+}
+
+// AvgLatency returns the mean time from shelving to pickup
+func (s StrategyStats) AvgLatency() time.Duration {
+	if s.Pickups == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Pickups)
+}
+
+// assignment is a courier on its way to collect an order
+type assignment struct {
+	courier  *Courier
+	strategy string
+}
+
+// Fleet assigns free couriers to orders and tracks each strategy's pickup
+// latency. It is safe for concurrent use.
+type Fleet struct {
+	mutex    sync.Mutex
+	couriers []*Courier
+	strategy AssignmentStrategy
+	assigned map[string]assignment // by order ID
+	stats    map[string]*StrategyStats
+}
+
+// NewFleet creates a fleet assigning couriers with the given strategy
+func NewFleet(couriers []*Courier, strategy AssignmentStrategy) *Fleet {
+	return &Fleet{
+		couriers: couriers,
+		strategy: strategy,
+		assigned: make(map[string]assignment),
+		stats:    make(map[string]*StrategyStats),
+	}
+}
+
+// SetStrategy switches the strategy used for future assignments
+func (f *Fleet) SetStrategy(strategy AssignmentStrategy) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.strategy = strategy
+}
+
+// Strategy returns the name of the current strategy
+func (f *Fleet) Strategy() string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.strategy.Name()
+}
+
+// Idle returns how many couriers are free
+func (f *Fleet) Idle() int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return len(f.idle())
+}
+
+func (f *Fleet) idle() []*Courier {
+	var idle []*Courier
+	for _, c := range f.couriers {
+		if !c.busy {
+			idle = append(idle, c)
+		}
+	}
+	return idle
+}
+
+// NearestIdle returns the travel time to the kitchen of the nearest free
+// courier, or false if every courier is busy
+func (f *Fleet) NearestIdle() (time.Duration, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	nearest, found := time.Duration(0), false
+	for _, c := range f.idle() {
+		if d := c.Distance(); !found || d < nearest {
+			nearest, found = d, true
+		}
+	}
+	return nearest, found
+}
+
+// Assign sends a free courier to collect an order and returns it with its
+// travel time to the kitchen. It returns nil if every courier is busy or
+// the order already has a courier on the way.
+func (f *Fleet) Assign(o *order.Order) (*Courier, time.Duration) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if _, ok := f.assigned[o.ID]; ok {
+		return nil, 0
+	}
+	idle := f.idle()
+	if len(idle) == 0 {
+		return nil, 0
+	}
+
+	c := f.strategy.Choose(o, idle)
+	c.busy = true
+	name := f.strategy.Name()
+	f.assigned[o.ID] = assignment{courier: c, strategy: name}
+	f.statsFor(name).Assignments++
+	return c, c.Distance()
+}
+
+// AssignedTo returns the ID of the courier assigned to an order, if any
+func (f *Fleet) AssignedTo(o *order.Order) (int, bool) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	a, ok := f.assigned[o.ID]
+	if !ok {
+		return 0, false
+	}
+	return a.courier.ID, true
+}
+
+// PickedUp records that the courier assigned to an order collected it at
+// the given time. The courier stays busy until Release.
+func (f *Fleet) PickedUp(o *order.Order, at time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	a, ok := f.assigned[o.ID]
+	if !ok {
+		return
+	}
+	a.courier.Deliveries++
+	a.courier.X, a.courier.Y = 0, 0
+
+	stats := f.statsFor(a.strategy)
+	stats.Pickups++
+	latency := at.Sub(o.PlacedOnShelfAt)
+	stats.TotalLatency += latency
+	stats.MaxLatency = max(stats.MaxLatency, latency)
+}
+
+// Missed records that an order was gone by the time its courier arrived
+func (f *Fleet) Missed(o *order.Order) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	a, ok := f.assigned[o.ID]
+	if !ok {
+		return
+	}
+	a.courier.X, a.courier.Y = 0, 0
+	f.statsFor(a.strategy).Missed++
+}
+
+// Release frees the courier assigned to an order at its new position
+func (f *Fleet) Release(o *order.Order, x, y float64) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	a, ok := f.assigned[o.ID]
+	if !ok {
+		return
+	}
+	delete(f.assigned, o.ID)
+	a.courier.X, a.courier.Y = x, y
+	a.courier.busy = false
+}
+
+// Scatter moves every courier to a random position within reach seconds of
+// the kitchen
+func (f *Fleet) Scatter(reach float64, rng *rand.Rand) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, c := range f.couriers {
+		c.X, c.Y = RandomPosition(reach, rng)
+	}
+}
+
+// Stats returns a copy of the pickup stats for every strategy used, keyed
+// by strategy name
+func (f *Fleet) Stats() map[string]StrategyStats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	stats := make(map[string]StrategyStats, len(f.stats))
+	for name, s := range f.stats {
+		stats[name] = *s
+	}
+	return stats
+}
+
+func (f *Fleet) statsFor(name string) *StrategyStats {
+	s, ok := f.stats[name]
+	if !ok {
+		s = &StrategyStats{}
+		f.stats[name] = s
+	}
+	return s
+}
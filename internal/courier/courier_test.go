@@ -0,0 +1,209 @@
+package courier_test
+
+import (
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/order"
+	"dish-dispatcher/plugin"
+)
+
+func testCouriers() []*courier.Courier {
+	return []*courier.Courier{
+		{ID: 1, X: 4},
+		{ID: 2, X: 1, Deliveries: 3},
+		{ID: 3, Y: 2},
+	}
+}
+
+func shelvedOrder() *order.Order {
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	o.PlacedOnShelfAt = time.Now()
+	return o
+}
+
+func TestStrategies(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []int // courier IDs chosen for successive orders
+	}{
+		{courier.StrategyNearestIdle, []int{2, 3, 1}},
+		{courier.StrategyRoundRobin, []int{1, 2, 3}},
+		{courier.StrategyLeastLoaded, []int{3, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			strategy, err := courier.NewStrategy(tt.strategy)
+			require.NoError(t, err)
+			fleet := courier.NewFleet(testCouriers(), strategy)
+
+			for _, want := range tt.want {
+				c, _ := fleet.Assign(shelvedOrder())
+				require.NotNil(t, c)
+				assert.Equal(t, want, c.ID)
+			}
+			assert.Equal(t, 0, fleet.Idle())
+		})
+	}
+
+	_, err := courier.NewStrategy("fastest")
+	assert.Error(t, err)
+}
+
+func TestScriptStrategy(t *testing.T) {
+	nearest, err := courier.NewScriptStrategy("distance")
+	require.NoError(t, err)
+	least, err := courier.NewScriptStrategy("deliveries * 100 + distance")
+	require.NoError(t, err)
+	assert.Equal(t, courier.StrategyScript, nearest.Name())
+
+	for strategy, want := range map[courier.AssignmentStrategy][]int{
+		nearest: {2, 3, 1},
+		least:   {3, 1, 2},
+	} {
+		fleet := courier.NewFleet(testCouriers(), strategy)
+		for _, id := range want {
+			c, _ := fleet.Assign(shelvedOrder())
+			require.NotNil(t, c)
+			assert.Equal(t, id, c.ID)
+		}
+	}
+
+	_, err = courier.NewScriptStrategy("speed * 2")
+	assert.Error(t, err)
+}
+
+// farthestCourier is a plugin dispatch strategy
+type farthestCourier struct{}
+
+func (farthestCourier) Choose(_ plugin.Order, idle []plugin.Courier) int {
+	best := 0
+	for i, c := range idle {
+		if c.Distance > idle[best].Distance {
+			best = i
+		}
+	}
+	return best
+}
+
+func TestPluginStrategy(t *testing.T) {
+	plugin.RegisterDispatchStrategy("farthest", func() plugin.DispatchStrategy { return farthestCourier{} })
+
+	strategy, err := courier.NewStrategy("farthest")
+	require.NoError(t, err)
+	assert.Equal(t, "farthest", strategy.Name())
+
+	fleet := courier.NewFleet(testCouriers(), strategy)
+	for _, want := range []int{1, 3, 2} {
+		c, _ := fleet.Assign(shelvedOrder())
+		require.NotNil(t, c)
+		assert.Equal(t, want, c.ID)
+	}
+}
+
+func TestRoundRobin_Wraps(t *testing.T) {
+	fleet := courier.NewFleet(testCouriers(), &courier.RoundRobin{})
+
+	var ids []int
+	for i := 0; i < 5; i++ {
+		o := shelvedOrder()
+		c, _ := fleet.Assign(o)
+		require.NotNil(t, c)
+		ids = append(ids, c.ID)
+		fleet.Release(o, 0, 0)
+	}
+	assert.Equal(t, []int{1, 2, 3, 1, 2}, ids)
+}
+
+func TestFleet_Lifecycle(t *testing.T) {
+	fleet := courier.NewFleet([]*courier.Courier{{ID: 1, X: 3, Y: 4}}, courier.NearestIdle{})
+	assert.Equal(t, courier.StrategyNearestIdle, fleet.Strategy())
+
+	o := shelvedOrder()
+	c, travel := fleet.Assign(o)
+	require.NotNil(t, c)
+	assert.Equal(t, 5*time.Second, travel)
+
+	// One courier per order, and none left for another
+	c2, _ := fleet.Assign(o)
+	assert.Nil(t, c2)
+	c3, _ := fleet.Assign(shelvedOrder())
+	assert.Nil(t, c3)
+	id, ok := fleet.AssignedTo(o)
+	assert.True(t, ok)
+	assert.Equal(t, 1, id)
+
+	fleet.PickedUp(o, o.PlacedOnShelfAt.Add(5*time.Second))
+	fleet.Release(o, 1, 0)
+	_, ok = fleet.AssignedTo(o)
+	assert.False(t, ok)
+	assert.Equal(t, 1, c.Deliveries)
+	assert.Equal(t, time.Second, c.Distance())
+	assert.Equal(t, 1, fleet.Idle())
+
+	// Switching strategy keeps the stats apart
+	fleet.SetStrategy(courier.LeastLoaded{})
+	missed := shelvedOrder()
+	_, _ = fleet.Assign(missed)
+	fleet.Missed(missed)
+	fleet.Release(missed, 0, 0)
+
+	stats := fleet.Stats()
+	require.Len(t, stats, 2)
+	nearest := stats[courier.StrategyNearestIdle]
+	assert.Equal(t, 1, nearest.Assignments)
+	assert.Equal(t, 1, nearest.Pickups)
+	assert.Equal(t, 5*time.Second, nearest.AvgLatency())
+	assert.Equal(t, 5*time.Second, nearest.MaxLatency)
+	least := stats[courier.StrategyLeastLoaded]
+	assert.Equal(t, 1, least.Missed)
+	assert.Equal(t, time.Duration(0), least.AvgLatency())
+}
+
+func TestFleet_NearestIdle(t *testing.T) {
+	fleet := courier.NewFleet([]*courier.Courier{{ID: 1, X: 3, Y: 4}, {ID: 2, X: 0, Y: 2}}, courier.NearestIdle{})
+
+	travel, ok := fleet.NearestIdle()
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, travel)
+
+	fleet.Assign(shelvedOrder())
+	travel, ok = fleet.NearestIdle()
+	assert.True(t, ok)
+	assert.Equal(t, 5*time.Second, travel)
+
+	fleet.Assign(shelvedOrder())
+	_, ok = fleet.NearestIdle()
+	assert.False(t, ok)
+}
+
+func TestRandomCouriers(t *testing.T) {
+	couriers := courier.RandomCouriers(50, 6, rand.New(rand.NewPCG(1, 2)))
+	require.Len(t, couriers, 50)
+	for i, c := range couriers {
+		assert.Equal(t, i+1, c.ID)
+		assert.LessOrEqual(t, c.Distance(), 6*time.Second)
+	}
+}
+
+func TestFleet_Scatter(t *testing.T) {
+	couriers := testCouriers()
+	fleet := courier.NewFleet(couriers, courier.NearestIdle{})
+
+	fleet.Scatter(0.5, rand.New(rand.NewPCG(1, 2)))
+	for _, c := range couriers {
+		assert.LessOrEqual(t, c.Distance(), 500*time.Millisecond)
+	}
+
+	// The same seed places the couriers the same way
+	x, y := couriers[0].X, couriers[0].Y
+	fleet.Scatter(0.5, rand.New(rand.NewPCG(1, 2)))
+	assert.Equal(t, x, couriers[0].X)
+	assert.Equal(t, y, couriers[0].Y)
+}
@@ -0,0 +1,188 @@
+package courier
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	"dish-dispatcher/plugin"
+)
+
+// AssignmentStrategy chooses which free courier picks up an order. The fleet
+// calls Choose under its lock, so strategies need no locking of their own.
+type AssignmentStrategy interface {
+	// Name identifies the strategy in stats and configuration
+	Name() string
+
+	// Choose returns one of the idle couriers, which is never empty
+	Choose(o *order.Order, idle []*Courier) *Courier
+}
+
+// Strategy names accepted by NewStrategy
+const (
+	StrategyNearestIdle = "nearest-idle"
+	StrategyRoundRobin  = "round-robin"
+	StrategyLeastLoaded = "least-loaded"
+)
+
+// NewStrategy returns the named built-in or plugin strategy
+func NewStrategy(name string) (AssignmentStrategy, error) {
+	switch name {
+	case "", StrategyNearestIdle:
+		return NearestIdle{}, nil
+	case StrategyRoundRobin:
+		return &RoundRobin{}, nil
+	case StrategyLeastLoaded:
+		return LeastLoaded{}, nil
+	default:
+		impl, err := plugin.NewDispatchStrategy(name)
+		if err != nil {
+			return nil, fmt.Errorf("unknown courier assignment strategy %q", name)
+		}
+		return &PluginStrategy{name: name, impl: impl}, nil
+	}
+}
+
+// PluginStrategy adapts a strategy registered with the plugin package
+type PluginStrategy struct {
+	name string
+	impl plugin.DispatchStrategy
+}
+
+func (p *PluginStrategy) Name() string { return p.name }
+
+// Choose sends the courier the plugin picks, or the first idle courier if
+// it returns an index out of range
+func (p *PluginStrategy) Choose(o *order.Order, idle []*Courier) *Courier {
+	views := make([]plugin.Courier, len(idle))
+	for i, c := range idle {
+		views[i] = plugin.Courier{ID: c.ID, X: c.X, Y: c.Y, Distance: c.Distance(), Deliveries: c.Deliveries}
+	}
+	view := plugin.Order{
+		ID:        o.ID,
+		Name:      o.Name,
+		Temp:      string(o.Temp),
+		ShelfLife: o.ShelfLife,
+		DecayRate: o.DecayRate,
+		Volume:    o.Volume(),
+		Value:     o.CalculateValue(time.Now()),
+		Priority:  o.Priority,
+		Zone:      o.Zone,
+	}
+
+	i := p.impl.Choose(view, views)
+	if i < 0 || i >= len(idle) {
+		return idle[0]
+	}
+	return idle[i]
+}
+
+// NearestIdle sends the free courier closest to the kitchen, minimizing
+// each pickup's travel
+type NearestIdle struct{}
+
+func (NearestIdle) Name() string { return StrategyNearestIdle }
+
+func (NearestIdle) Choose(_ *order.Order, idle []*Courier) *Courier {
+	best := idle[0]
+	for _, c := range idle[1:] {
+		if c.Distance() < best.Distance() {
+			best = c
+		}
+	}
+	return best
+}
+
+// RoundRobin cycles through couriers by ID, skipping busy ones, so work is
+// spread evenly regardless of position
+type RoundRobin struct {
+	last int // ID of the courier chosen last
+}
+
+func (*RoundRobin) Name() string { return StrategyRoundRobin }
+
+func (r *RoundRobin) Choose(_ *order.Order, idle []*Courier) *Courier {
+	sorted := append([]*Courier(nil), idle...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	chosen := sorted[0]
+	for _, c := range sorted {
+		if c.ID > r.last {
+			chosen = c
+			break
+		}
+	}
+	r.last = chosen.ID
+	return chosen
+}
+
+// LeastLoaded sends the free courier with the fewest deliveries so far,
+// breaking ties by distance
+type LeastLoaded struct{}
+
+func (LeastLoaded) Name() string { return StrategyLeastLoaded }
+
+func (LeastLoaded) Choose(_ *order.Order, idle []*Courier) *Courier {
+	best := idle[0]
+	for _, c := range idle[1:] {
+		if c.Deliveries < best.Deliveries ||
+			(c.Deliveries == best.Deliveries && c.Distance() < best.Distance()) {
+			best = c
+		}
+	}
+	return best
+}
+
+// StrategyScript names strategies built from a script by NewScriptStrategy
+const StrategyScript = "script"
+
+// ScriptVars are the variables a courier script may use, describing the
+// idle courier being scored and the order it would collect:
+//
+//	distance    the courier's travel time to the kitchen in seconds
+//	deliveries  orders the courier has picked up so far
+//	id, x, y    the courier's ID and position
+//	shelfLife   the order's shelf life in seconds
+//	decayRate   the order's decay rate
+var ScriptVars = []string{"distance", "deliveries", "id", "x", "y", "shelfLife", "decayRate"}
+
+// ScriptStrategy scores every idle courier with a script and sends the
+// lowest scoring, breaking ties by ID
+type ScriptStrategy struct {
+	script *order.Expression
+}
+
+// NewScriptStrategy parses a courier script. "distance" behaves like
+// nearest-idle and "deliveries * 100 + distance" like least-loaded.
+func NewScriptStrategy(script string) (*ScriptStrategy, error) {
+	expr, err := order.ParseExpression(script, ScriptVars...)
+	if err != nil {
+		return nil, fmt.Errorf("courier script: %w", err)
+	}
+	return &ScriptStrategy{script: expr}, nil
+}
+
+func (*ScriptStrategy) Name() string { return StrategyScript }
+
+func (s *ScriptStrategy) Choose(o *order.Order, idle []*Courier) *Courier {
+	var (
+		best      *Courier
+		bestScore float64
+	)
+	for _, c := range idle {
+		score := s.script.Eval(map[string]float64{
+			"distance":   c.Distance().Seconds(),
+			"deliveries": float64(c.Deliveries),
+			"id":         float64(c.ID),
+			"x":          c.X,
+			"y":          c.Y,
+			"shelfLife":  o.ShelfLife,
+			"decayRate":  o.DecayRate,
+		})
+		if best == nil || score < bestScore || (score == bestScore && c.ID < best.ID) {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
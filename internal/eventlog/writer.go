@@ -0,0 +1,221 @@
+// Package eventlog writes a log file that rotates by size and age, so
+// event logs of long, busy runs can be kept as a series of files, optionally
+// gzipped, instead of one that grows without bound.
+package eventlog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/clock"
+)
+
+// flushInterval bounds how long a write waits in the buffer before it
+// reaches the file
+const flushInterval = time.Second
+
+// Options configures a Writer
+type Options struct {
+	Path string
+
+	// MaxSize is the most bytes written to a file before rotating, 0 for no
+	// limit. A single larger write still goes to one file.
+	MaxSize int64
+	// MaxAge is the longest a file is written before rotating, 0 for no
+	// limit
+	MaxAge time.Duration
+
+	// Compress gzips each file once rotated
+	Compress bool
+
+	Clock clock.Clock // nil for the wall clock
+}
+
+// Writer appends to the log file, rotating it to a timestamped name such
+// as events-20240101T120000.000.ndjson once it is full or old enough. Each
+// Write goes entirely to one file, so writing whole lines keeps every file
+// a valid log. It is safe for concurrent use.
+type Writer struct {
+	opts Options
+
+	mutex     sync.Mutex
+	file      *os.File
+	buf       *bufio.Writer
+	size      int64
+	opened    time.Time
+	lastFlush time.Time
+	err       error // first failure compressing a rotated file
+
+	compressing sync.WaitGroup
+}
+
+// Open opens the log file, appending if it exists
+func Open(opts Options) (*Writer, error) {
+	if opts.Path == "" {
+		return nil, errors.New("eventlog: no file")
+	}
+	if opts.Clock == nil {
+		opts.Clock = clock.Real{}
+	}
+	w := &Writer{opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	file, err := os.OpenFile(w.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("eventlog: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("eventlog: %w", err)
+	}
+
+	now := w.opts.Clock.Now()
+	w.file, w.buf = file, bufio.NewWriterSize(file, 64<<10)
+	w.size, w.opened, w.lastFlush = info.Size(), now, now
+	return nil
+}
+
+// Write appends p, first rotating the file if p would take it past MaxSize
+// or it has reached MaxAge
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	now := w.opts.Clock.Now()
+	if w.size > 0 && w.due(now, int64(len(p))) {
+		if err := w.rotate(now); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.buf.Write(p)
+	w.size += int64(n)
+	if err == nil && now.Sub(w.lastFlush) >= flushInterval {
+		err = w.buf.Flush()
+		w.lastFlush = now
+	}
+	return n, err
+}
+
+// due reports whether the file must be rotated before writing n bytes
+func (w *Writer) due(now time.Time, n int64) bool {
+	if w.opts.MaxSize > 0 && w.size+n > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.MaxAge > 0 && now.Sub(w.opened) >= w.opts.MaxAge
+}
+
+// rotate closes the current file, renames it and starts a new one
+func (w *Writer) rotate(now time.Time) error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	rotated := w.rotatedName(now)
+	if err := os.Rename(w.opts.Path, rotated); err != nil {
+		return fmt.Errorf("eventlog: %w", err)
+	}
+	if w.opts.Compress {
+		w.compressing.Add(1)
+		go func() {
+			defer w.compressing.Done()
+			if err := compress(rotated); err != nil {
+				w.mutex.Lock()
+				if w.err == nil {
+					w.err = err
+				}
+				w.mutex.Unlock()
+			}
+		}()
+	}
+	return w.open()
+}
+
+// rotatedName inserts the rotation time before the extension, adding a
+// counter in the unlikely case that name is taken
+func (w *Writer) rotatedName(now time.Time) string {
+	ext := filepath.Ext(w.opts.Path)
+	base := strings.TrimSuffix(w.opts.Path, ext) + "-" + now.UTC().Format("20060102T150405.000")
+	name := base + ext
+	for i := 1; exists(name) || exists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	return name
+}
+
+func exists(name string) bool {
+	_, err := os.Stat(name)
+	return err == nil
+}
+
+func (w *Writer) closeFile() error {
+	err := w.buf.Flush()
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	w.file, w.buf = nil, nil
+	if err != nil {
+		return fmt.Errorf("eventlog: %w", err)
+	}
+	return nil
+}
+
+// Close flushes and closes the file, waiting for rotated files to finish
+// compressing. It returns the first failure to compress one.
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	var err error
+	if w.file != nil {
+		err = w.closeFile()
+	}
+	w.mutex.Unlock()
+
+	w.compressing.Wait()
+	if err != nil {
+		return err
+	}
+	return w.err
+}
+
+// compress gzips the file to name.gz and removes it
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("eventlog: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("eventlog: %w", err)
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return fmt.Errorf("eventlog: compressing %s: %w", name, err)
+	}
+	return os.Remove(name)
+}
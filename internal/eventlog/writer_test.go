@@ -0,0 +1,135 @@
+package eventlog_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/eventlog"
+)
+
+var start = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// files returns the names in dir and their contents, decompressed
+func files(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	contents := make(map[string]string)
+	for _, entry := range entries {
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		require.NoError(t, err)
+		var r io.Reader = f
+		if filepath.Ext(entry.Name()) == ".gz" {
+			r, err = gzip.NewReader(f)
+			require.NoError(t, err)
+		}
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		f.Close()
+		contents[entry.Name()] = string(data)
+	}
+	return contents
+}
+
+func names(contents map[string]string) []string {
+	var names []string
+	for name := range contents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWriter_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	c := clock.NewFake(start)
+	w, err := eventlog.Open(eventlog.Options{Path: filepath.Join(dir, "events.ndjson"), MaxSize: 10, Clock: c})
+	require.NoError(t, err)
+
+	for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n", "a line past the limit\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+		c.Advance(time.Millisecond)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, map[string]string{
+		"events-20240101T120000.002.ndjson": "aaaa\nbbbb\n",
+		"events-20240101T120000.003.ndjson": "cccc\n",
+		"events.ndjson":                     "a line past the limit\n",
+	}, files(t, dir))
+}
+
+func TestWriter_RotatesByAgeAndCompresses(t *testing.T) {
+	dir := t.TempDir()
+	c := clock.NewFake(start)
+	w, err := eventlog.Open(eventlog.Options{Path: filepath.Join(dir, "events.ndjson"), MaxAge: time.Hour, Compress: true, Clock: c})
+	require.NoError(t, err)
+
+	write := func(line string) {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	write("first\n")
+	c.Advance(59 * time.Minute)
+	write("second\n")
+	c.Advance(time.Minute)
+	write("third\n")
+	require.NoError(t, w.Close())
+
+	contents := files(t, dir)
+	assert.Equal(t, []string{"events-20240101T130000.000.ndjson.gz", "events.ndjson"}, names(contents))
+	assert.Equal(t, "first\nsecond\n", contents["events-20240101T130000.000.ndjson.gz"])
+	assert.Equal(t, "third\n", contents["events.ndjson"])
+}
+
+func TestWriter_AppendsToExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("earlier run\n"), 0o644))
+
+	c := clock.NewFake(start)
+	w, err := eventlog.Open(eventlog.Options{Path: path, MaxSize: 20, Clock: c})
+	require.NoError(t, err)
+	_, err = w.Write([]byte("this run\n"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	// The earlier run's line counts towards the limit
+	assert.Equal(t, map[string]string{
+		"events-20240101T120000.000.ndjson": "earlier run\n",
+		"events.ndjson":                     "this run\n",
+	}, files(t, dir))
+
+	_, err = w.Write([]byte("late\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestWriter_NameCollision(t *testing.T) {
+	dir := t.TempDir()
+	c := clock.NewFake(start)
+	w, err := eventlog.Open(eventlog.Options{Path: filepath.Join(dir, "events.ndjson"), MaxSize: 1, Clock: c})
+	require.NoError(t, err)
+
+	for _, line := range []string{"1\n", "2\n", "3\n"} {
+		_, err := w.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, map[string]string{
+		"events-20240101T120000.000.ndjson":   "1\n",
+		"events-20240101T120000.000-1.ndjson": "2\n",
+		"events.ndjson":                       "3\n",
+	}, files(t, dir))
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloudEventTypePrefix namespaces event types in the CloudEvents type
+// attribute, as in dish-dispatcher.order_placed
+const CloudEventTypePrefix = "dish-dispatcher."
+
+// CloudEvent is an event wrapped in the CloudEvents 1.0 JSON envelope,
+// which brokers such as Knative Eventing and EventBridge route by its
+// attributes without looking inside the data
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"` // the order or shelf the event concerns
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            Event     `json:"data"`
+}
+
+// CloudEvent wraps the event, attributing it to source, a URI reference
+// identifying this dispatcher. Each call gets a fresh ID.
+func (e Event) CloudEvent(source string) CloudEvent {
+	subject := e.OrderID
+	if subject == "" {
+		subject = e.Shelf
+	}
+	return CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              uuid.NewString(),
+		Source:          source,
+		Type:            CloudEventTypePrefix + string(e.Type),
+		Subject:         subject,
+		Time:            e.Time,
+		DataContentType: "application/json",
+		Data:            e,
+	}
+}
@@ -0,0 +1,44 @@
+package events_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/events"
+)
+
+func TestEvent_CloudEvent(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	e := events.Event{Run: "lunch", Type: events.OrderPlaced, Time: at, OrderID: "o1", Shelf: "hot"}
+
+	data, err := json.Marshal(e.CloudEvent("/kitchens/1"))
+	require.NoError(t, err)
+
+	var envelope map[string]any
+	require.NoError(t, json.Unmarshal(data, &envelope))
+	assert.NotEmpty(t, envelope["id"])
+	delete(envelope, "id")
+	assert.Equal(t, map[string]any{
+		"specversion":     "1.0",
+		"source":          "/kitchens/1",
+		"type":            "dish-dispatcher.order_placed",
+		"subject":         "o1",
+		"time":            "2024-01-01T12:00:00Z",
+		"datacontenttype": "application/json",
+		"data": map[string]any{
+			"run":     "lunch",
+			"type":    "order_placed",
+			"time":    "2024-01-01T12:00:00Z",
+			"orderId": "o1",
+			"shelf":   "hot",
+		},
+	}, envelope)
+
+	assert.NotEqual(t, e.CloudEvent("s").ID, e.CloudEvent("s").ID)
+	assert.Equal(t, "cold", events.Event{Type: events.ShelfOutage, Shelf: "cold"}.CloudEvent("s").Subject)
+	assert.Empty(t, events.Event{Type: events.StatsReset}.CloudEvent("s").Subject)
+}
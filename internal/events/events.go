@@ -0,0 +1,119 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Type identifies what happened in an event
+type Type string
+
+// Event types published by the simulator
+const (
+	OrderPlaced     Type = "order_placed"
+	OrderWasted     Type = "order_wasted"
+	OrderDelivered  Type = "order_delivered"
+	OrderHandedOff  Type = "order_handed_off"
+	OrderMoved      Type = "order_moved"
+	OrdersExpired   Type = "orders_expired"
+	ShelfOutage     Type = "shelf_outage"
+	ShelfRestored   Type = "shelf_restored"
+	CourierOutage   Type = "courier_outage"
+	CourierRestored Type = "courier_restored"
+	AlertFiring     Type = "alert_firing"
+	AlertResolved   Type = "alert_resolved"
+	StatsReset      Type = "stats_reset"
+	RunStarted      Type = "run_started"
+	RunPaused       Type = "run_paused"
+	RunResumed      Type = "run_resumed"
+)
+
+// Event is a single notable occurrence during a simulation run
+type Event struct {
+	Run     string    `json:"run,omitempty"`   // name of the run that published the event
+	RunID   string    `json:"runId,omitempty"` // ID of the run, unique within the process
+	Type    Type      `json:"type"`
+	Time    time.Time `json:"time"`
+	OrderID string    `json:"orderId,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Temp    string    `json:"temp,omitempty"`
+	Shelf   string    `json:"shelf,omitempty"`
+	Value   float64   `json:"value,omitempty"`
+	Count   int       `json:"count,omitempty"`
+	Reason  string    `json:"reason,omitempty"`  // why an order was wasted
+	Version string    `json:"version,omitempty"` // of the binary, on run_started
+}
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const subscriberBuffer = 256
+
+// Bus fans events out to any number of subscribers. Publishing never blocks:
+// subscribers that fall behind miss events rather than stall the simulation.
+type Bus struct {
+	mutex       sync.RWMutex
+	subscribers map[chan Event]struct{}
+	run, runID  string
+}
+
+// NewBus creates an event bus with no subscribers
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[chan Event]struct{})}
+}
+
+// SetRun names and identifies the run stamped on every event published
+// from now on
+func (b *Bus) SetRun(name, id string) {
+	if b == nil {
+		return
+	}
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.run, b.runID = name, id
+}
+
+// Publish delivers an event to every subscriber, stamping it with the
+// current time and the run if none are set
+func (b *Bus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if e.Run == "" && e.RunID == "" {
+		e.Run, e.RunID = b.run, b.runID
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of future events and a function that
+// unsubscribes and closes it
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mutex.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mutex.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mutex.Lock()
+			delete(b.subscribers, ch)
+			b.mutex.Unlock()
+			close(ch)
+		})
+	}
+}
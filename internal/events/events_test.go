@@ -0,0 +1,60 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/events"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe()
+
+	bus.Publish(events.Event{Type: events.OrderPlaced, OrderID: "1"})
+
+	e := <-ch
+	assert.Equal(t, events.OrderPlaced, e.Type)
+	assert.Equal(t, "1", e.OrderID)
+	assert.False(t, e.Time.IsZero())
+
+	unsubscribe()
+	unsubscribe() // safe to call twice
+	_, open := <-ch
+	assert.False(t, open)
+}
+
+func TestBus_SlowSubscriberDoesNotBlock(t *testing.T) {
+	bus := events.NewBus()
+	_, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	for i := 0; i < 10000; i++ {
+		bus.Publish(events.Event{Type: events.OrderPlaced})
+	}
+}
+
+func TestBus_NilIsNoop(t *testing.T) {
+	var bus *events.Bus
+	bus.Publish(events.Event{Type: events.OrderPlaced})
+}
+
+func TestBus_StampsRun(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	bus.Publish(events.Event{Type: events.OrderPlaced})
+	bus.SetRun("baseline", "baseline-1a2b3c4d")
+	bus.Publish(events.Event{Type: events.OrderPlaced})
+	bus.Publish(events.Event{Type: events.OrderPlaced, Run: "replay"})
+
+	assert.Empty(t, (<-ch).Run)
+	stamped := <-ch
+	assert.Equal(t, "baseline", stamped.Run)
+	assert.Equal(t, "baseline-1a2b3c4d", stamped.RunID)
+	replayed := <-ch
+	assert.Equal(t, "replay", replayed.Run)
+	assert.Empty(t, replayed.RunID)
+}
@@ -0,0 +1,222 @@
+// Package grpcwire serves and calls gRPC services with the standard library
+// alone, over HTTP/2 with or without TLS. Messages are encoded in the
+// protobuf wire format by hand with the helpers in proto.go, which is small
+// enough for the few flat messages the dispatcher exchanges, rather than
+// pulling in gRPC and a code generator.
+//
+// The courier agent service and the cluster coordinator both speak it.
+package grpcwire
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// maxMessageSize bounds a received message, as gRPC's default does
+const maxMessageSize = 4 << 20
+
+// gRPC status codes
+const (
+	CodeOK                 = 0
+	CodeCanceled           = 1
+	CodeInvalidArgument    = 3
+	CodeNotFound           = 5
+	CodeAlreadyExists      = 6
+	CodeFailedPrecondition = 9
+	CodeUnimplemented      = 12
+	CodeInternal           = 13
+	CodeUnavailable        = 14
+)
+
+// StatusError is a failed call, carrying its gRPC status code
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("rpc error: code %d: %s", e.Code, e.Message)
+}
+
+// Errorf returns a StatusError with the given code
+func Errorf(code int, format string, args ...any) error {
+	return &StatusError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Protocols returns the protocols gRPC is spoken over: HTTP/2 only,
+// unencrypted or over TLS
+func Protocols() *http.Protocols {
+	p := new(http.Protocols)
+	p.SetUnencryptedHTTP2(true)
+	p.SetHTTP2(true)
+	return p
+}
+
+// WriteMessage writes one length-prefixed, uncompressed message
+func WriteMessage(w io.Writer, msg []byte) error {
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	_, err := w.Write(append(frame, msg...))
+	return err
+}
+
+// ReadMessage reads one length-prefixed message. It returns io.EOF if the
+// stream ended cleanly before the message started.
+func ReadMessage(r io.Reader) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, Errorf(CodeInternal, "truncated message prefix")
+		}
+		return nil, err
+	}
+	if prefix[0] != 0 {
+		return nil, Errorf(CodeUnimplemented, "compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxMessageSize {
+		return nil, Errorf(CodeInvalidArgument, "message of %d bytes exceeds %d", length, maxMessageSize)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, Errorf(CodeInternal, "truncated message: %v", err)
+	}
+	return msg, nil
+}
+
+// CheckCall rejects a request that cannot be a gRPC call and sets the
+// response content type of one that can
+func CheckCall(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost || r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires POST over HTTP/2", http.StatusUnsupportedMediaType)
+		return false
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	return true
+}
+
+// ReadRequest reads the single request message of a call and decodes it
+// with unmarshal
+func ReadRequest(r *http.Request, unmarshal func([]byte) error) error {
+	msg, err := ReadMessage(r.Body)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return Errorf(CodeInvalidArgument, "missing request message")
+		}
+		return err
+	}
+	if err := unmarshal(msg); err != nil {
+		return Errorf(CodeInvalidArgument, "%v", err)
+	}
+	return nil
+}
+
+// StartResponse sends the headers of a call that will answer with
+// messages, declaring the status trailers that end it
+func StartResponse(w http.ResponseWriter) {
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	w.WriteHeader(http.StatusOK)
+}
+
+// WriteReply answers a unary call with its single reply message
+func WriteReply(w http.ResponseWriter, reply []byte) error {
+	StartResponse(w)
+	return WriteMessage(w, reply)
+}
+
+// WriteStatus ends a call. After StartResponse the status goes in the
+// trailers; a call that failed before answering sends it in the headers
+// alone, as gRPC's trailers-only response.
+func WriteStatus(w http.ResponseWriter, err error) {
+	code, message := CodeOK, ""
+	if err != nil {
+		var status *StatusError
+		if !errors.As(err, &status) {
+			status = &StatusError{Code: CodeInternal, Message: err.Error()}
+		}
+		code, message = status.Code, status.Message
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", message)
+	}
+}
+
+// ReadStatus returns the error reported by a finished call's trailers, or
+// by its headers for a call that failed before sending anything
+func ReadStatus(resp *http.Response) error {
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status, message = resp.Header.Get("Grpc-Status"), resp.Header.Get("Grpc-Message")
+	}
+	if status == "" {
+		return Errorf(CodeInternal, "call ended without a status (HTTP %d)", resp.StatusCode)
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return Errorf(CodeInternal, "invalid grpc-status %q", status)
+	}
+	if code == CodeOK {
+		return nil
+	}
+	return &StatusError{Code: code, Message: message}
+}
+
+// Call starts a call to url, the server's base URL followed by the
+// method's path, with a single request message. The caller reads the
+// response messages and then the status.
+func Call(ctx context.Context, client *http.Client, url string, msg []byte) (*http.Response, error) {
+	var body bytes.Buffer
+	if err := WriteMessage(&body, msg); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// Invoke makes a unary call and returns its single reply message
+func Invoke(ctx context.Context, client *http.Client, url string, msg []byte) ([]byte, error) {
+	resp, err := Call(ctx, client, url, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	reply, err := ReadMessage(resp.Body)
+	if errors.Is(err, io.EOF) {
+		if err := ReadStatus(resp); err != nil {
+			return nil, err
+		}
+		return nil, Errorf(CodeInternal, "call ended without a reply")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Read to the end of the body for the trailers
+	io.Copy(io.Discard, resp.Body)
+	if err := ReadStatus(resp); err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
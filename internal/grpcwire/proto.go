@@ -0,0 +1,124 @@
+package grpcwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ErrMalformed is returned for bytes that are not a protobuf message
+var ErrMalformed = errors.New("malformed protobuf message")
+
+func appendTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// AppendString appends a string field, omitting it if empty as proto3 does
+func AppendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return AppendBytes(b, field, []byte(s))
+}
+
+// AppendBytes appends a bytes field, which also carries an embedded
+// message. It is written even if empty, so an empty message in a repeated
+// field or map entry is kept.
+func AppendBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(appendTag(b, field, wireBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// AppendUint appends a uint64 field, omitting it if zero as proto3 does
+func AppendUint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, field, wireVarint), v)
+}
+
+// AppendInt appends an int64 field, omitting it if zero as proto3 does
+func AppendInt(b []byte, field int, v int64) []byte {
+	return AppendUint(b, field, uint64(v))
+}
+
+// AppendBool appends a bool field, omitting it if false as proto3 does
+func AppendBool(b []byte, field int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return AppendUint(b, field, 1)
+}
+
+// AppendDouble appends a double field, omitting it if zero as proto3 does
+func AppendDouble(b []byte, field int, v float64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.LittleEndian.AppendUint64(appendTag(b, field, wireFixed64), math.Float64bits(v))
+}
+
+// Field is one decoded field. Only the member for its wire type is set.
+type Field struct {
+	Varint uint64
+	Fixed  uint64
+	Bytes  []byte
+}
+
+func (f Field) String() string  { return string(f.Bytes) }
+func (f Field) Int() int64      { return int64(f.Varint) }
+func (f Field) Bool() bool      { return f.Varint != 0 }
+func (f Field) Double() float64 { return math.Float64frombits(f.Fixed) }
+
+// DecodeFields calls set for every field of a message, skipping none, so
+// set can ignore fields it does not know as protobuf requires. An error
+// from set, such as a malformed embedded message, stops the decoding.
+func DecodeFields(b []byte, set func(field int, f Field) error) error {
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return ErrMalformed
+		}
+		b = b[n:]
+
+		var f Field
+		switch tag & 7 {
+		case wireVarint:
+			f.Varint, n = binary.Uvarint(b)
+			if n <= 0 {
+				return ErrMalformed
+			}
+			b = b[n:]
+		case wireFixed64:
+			if len(b) < 8 {
+				return ErrMalformed
+			}
+			f.Fixed, b = binary.LittleEndian.Uint64(b), b[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(b)
+			if n <= 0 || uint64(len(b)-n) < length {
+				return ErrMalformed
+			}
+			f.Bytes, b = b[n:n+int(length)], b[n+int(length):]
+		case wireFixed32:
+			if len(b) < 4 {
+				return ErrMalformed
+			}
+			f.Fixed, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return ErrMalformed
+		}
+		if err := set(int(tag>>3), f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package grpcwire_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/grpcwire"
+)
+
+func TestDecodeFields(t *testing.T) {
+	var b []byte
+	b = grpcwire.AppendString(b, 1, "burger")
+	b = grpcwire.AppendInt(b, 2, 42)
+	b = grpcwire.AppendDouble(b, 3, 0.5)
+	b = grpcwire.AppendBool(b, 4, true)
+	b = grpcwire.AppendBytes(b, 5, grpcwire.AppendString(nil, 1, "inner"))
+	// Zero values are omitted
+	b = grpcwire.AppendString(b, 6, "")
+	b = grpcwire.AppendInt(b, 7, 0)
+
+	got := make(map[int]grpcwire.Field)
+	require.NoError(t, grpcwire.DecodeFields(b, func(field int, f grpcwire.Field) error {
+		got[field] = f
+		return nil
+	}))
+
+	assert.Len(t, got, 5)
+	assert.Equal(t, "burger", got[1].String())
+	assert.Equal(t, int64(42), got[2].Int())
+	assert.Equal(t, 0.5, got[3].Double())
+	assert.True(t, got[4].Bool())
+	assert.Equal(t, grpcwire.AppendString(nil, 1, "inner"), got[5].Bytes)
+}
+
+func TestDecodeFields_Malformed(t *testing.T) {
+	truncated := grpcwire.AppendString(nil, 1, "burger")
+	err := grpcwire.DecodeFields(truncated[:len(truncated)-1], func(int, grpcwire.Field) error { return nil })
+	assert.ErrorIs(t, err, grpcwire.ErrMalformed)
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, grpcwire.WriteMessage(&buf, []byte("hello")))
+	require.NoError(t, grpcwire.WriteMessage(&buf, nil))
+
+	msg, err := grpcwire.ReadMessage(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(msg))
+	msg, err = grpcwire.ReadMessage(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, msg)
+	_, err = grpcwire.ReadMessage(&buf)
+	assert.ErrorIs(t, err, io.EOF)
+}
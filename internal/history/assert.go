@@ -0,0 +1,151 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Assertion is a condition on a run's final stats, such as "wasteRate < 5%"
+// or "delivered >= 95", so a run can serve as a regression test
+type Assertion struct {
+	Metric string
+	Op     string
+	Value  float64
+
+	// Percent is set when the value ends in "%". Order counts are then
+	// compared as a percentage of orders received.
+	Percent bool
+}
+
+// assertionMetrics reads each metric an assertion can name. Counts are
+// converted to percentages by the assertion when it has a "%" value.
+var assertionMetrics = map[string]struct {
+	value   func(Summary) float64
+	count   bool // an order count, which may be compared as a percentage
+	percent bool // already a percentage
+}{
+	"received":     {value: func(s Summary) float64 { return float64(s.Received) }, count: true},
+	"delivered":    {value: func(s Summary) float64 { return float64(s.Delivered) }, count: true},
+	"wasted":       {value: func(s Summary) float64 { return float64(s.Wasted) }, count: true},
+	"expired":      {value: func(s Summary) float64 { return float64(s.Expired) }, count: true},
+	"deliveryRate": {value: Summary.DeliveryRate, percent: true},
+	"wasteRate":    {value: Summary.WasteRate, percent: true},
+	"avgValue":     {value: Summary.AverageDeliveredValue},
+}
+
+// assertionOps are the comparison operators, longest first so "<=" is not
+// read as "<"
+var assertionOps = []string{"<=", ">=", "==", "!=", "≤", "≥", "<", ">"}
+
+// ParseAssertion parses a condition of the form "metric op value", where op
+// is one of < <= > >= == != ≤ ≥ and value may end in "%"
+func ParseAssertion(s string) (Assertion, error) {
+	for _, op := range assertionOps {
+		i := strings.Index(s, op)
+		if i < 0 {
+			continue
+		}
+
+		a := Assertion{Metric: strings.TrimSpace(s[:i]), Op: op}
+		switch op {
+		case "≤":
+			a.Op = "<="
+		case "≥":
+			a.Op = ">="
+		}
+
+		metric, ok := assertionMetrics[a.Metric]
+		if !ok {
+			return Assertion{}, fmt.Errorf("assertion %q: unknown metric %q", s, a.Metric)
+		}
+
+		value := strings.TrimSpace(s[i+len(op):])
+		if trimmed, ok := strings.CutSuffix(value, "%"); ok {
+			if !metric.count && !metric.percent {
+				return Assertion{}, fmt.Errorf("assertion %q: %s is not a count or percentage", s, a.Metric)
+			}
+			value, a.Percent = strings.TrimSpace(trimmed), true
+		}
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Assertion{}, fmt.Errorf("assertion %q: invalid value %q", s, value)
+		}
+		a.Value = v
+		return a, nil
+	}
+	return Assertion{}, fmt.Errorf("assertion %q: want metric, operator and value", s)
+}
+
+// ParseAssertions parses every condition, failing on the first invalid one
+func ParseAssertions(conditions []string) ([]Assertion, error) {
+	assertions := make([]Assertion, len(conditions))
+	for i, c := range conditions {
+		a, err := ParseAssertion(c)
+		if err != nil {
+			return nil, err
+		}
+		assertions[i] = a
+	}
+	return assertions, nil
+}
+
+// Actual returns the asserted metric of a run
+func (a Assertion) Actual(s Summary) float64 {
+	metric := assertionMetrics[a.Metric]
+	if a.Percent && metric.count {
+		return s.percent(int(metric.value(s)))
+	}
+	return metric.value(s)
+}
+
+// Check reports whether a run satisfies the assertion
+func (a Assertion) Check(s Summary) bool {
+	actual := a.Actual(s)
+	switch a.Op {
+	case "<":
+		return actual < a.Value
+	case "<=":
+		return actual <= a.Value
+	case ">":
+		return actual > a.Value
+	case ">=":
+		return actual >= a.Value
+	case "==":
+		return actual == a.Value
+	case "!=":
+		return actual != a.Value
+	default:
+		return false
+	}
+}
+
+func (a Assertion) String() string {
+	return fmt.Sprintf("%s %s %s", a.Metric, a.Op, a.format(a.Value))
+}
+
+// Diff describes a failed assertion as the expected line, prefixed "-",
+// and the actual value, prefixed "+"
+func (a Assertion) Diff(s Summary) string {
+	actual := math.Round(a.Actual(s)*100) / 100
+	return fmt.Sprintf("- %s\n+ %s = %s", a, a.Metric, a.format(actual))
+}
+
+func (a Assertion) format(v float64) string {
+	if a.Percent || assertionMetrics[a.Metric].percent {
+		return strconv.FormatFloat(v, 'f', -1, 64) + "%"
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// FailedAssertions returns the assertions a run does not satisfy
+func FailedAssertions(assertions []Assertion, s Summary) []Assertion {
+	var failed []Assertion
+	for _, a := range assertions {
+		if !a.Check(s) {
+			failed = append(failed, a)
+		}
+	}
+	return failed
+}
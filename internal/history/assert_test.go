@@ -0,0 +1,56 @@
+package history_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestParseAssertion(t *testing.T) {
+	a, err := history.ParseAssertion("wasteRate < 5%")
+	require.NoError(t, err)
+	assert.Equal(t, history.Assertion{Metric: "wasteRate", Op: "<", Value: 5, Percent: true}, a)
+
+	a, err = history.ParseAssertion("delivered≥95")
+	require.NoError(t, err)
+	assert.Equal(t, history.Assertion{Metric: "delivered", Op: ">=", Value: 95}, a)
+	assert.Equal(t, "delivered >= 95", a.String())
+
+	for _, bad := range []string{"wasteRate", "speed > 3", "wasted < lots", "avgValue > 60%"} {
+		_, err := history.ParseAssertion(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestAssertion_Check(t *testing.T) {
+	s := history.Summary{
+		Received:  10,
+		Delivered: 8,
+		Wasted:    1,
+		Expired:   1,
+		ByTemperature: map[order.Temperature]shelf.ItemStats{
+			order.Hot: {Delivered: 8, TotalDeliveredValue: 6},
+		},
+	}
+
+	pass, err := history.ParseAssertions([]string{
+		"wasteRate <= 20",
+		"delivered >= 80%",
+		"delivered == 8",
+		"avgValue > 0.7",
+	})
+	require.NoError(t, err)
+	assert.Empty(t, history.FailedAssertions(pass, s))
+
+	fail, err := history.ParseAssertions([]string{"wasteRate < 5%", "expired != 1"})
+	require.NoError(t, err)
+	failed := history.FailedAssertions(fail, s)
+	require.Len(t, failed, 2)
+	assert.Equal(t, "- wasteRate < 5%\n+ wasteRate = 20%", failed[0].Diff(s))
+	assert.Equal(t, "- expired != 1\n+ expired = 1", failed[1].Diff(s))
+}
@@ -0,0 +1,172 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// baselineMetrics are the metrics compared with a baseline, in the order
+// they are printed, and which way each improves: 1 if higher is better, -1
+// if lower is, 0 if neither
+var baselineMetrics = []struct {
+	name   string
+	better int
+}{
+	{"received", 0},
+	{"delivered", 1},
+	{"wasted", -1},
+	{"expired", -1},
+	{"deliveryRate", 1},
+	{"wasteRate", -1},
+	{"avgValue", 1},
+}
+
+// LoadBaseline reads the final stats of an earlier run from a manifest or a
+// history record saved as JSON
+func LoadBaseline(path string) (Summary, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return Summary{}, err
+	}
+	var report struct {
+		Stats *Summary `json:"stats"`
+	}
+	if err := json.Unmarshal(raw, &report); err != nil {
+		return Summary{}, fmt.Errorf("baseline %s: %w", path, err)
+	}
+	if report.Stats == nil {
+		return Summary{}, fmt.Errorf("baseline %s: no stats", path)
+	}
+	return *report.Stats, nil
+}
+
+// Tolerance is how far a metric may regress from the baseline, such as
+// "wasteRate=1" for one percentage point or "avgValue=5%" for 5% of the
+// baseline value
+type Tolerance struct {
+	Metric   string
+	Value    float64
+	Relative bool // Value is a percentage of the baseline
+}
+
+// ParseTolerance parses a tolerance of the form "metric=value", where value
+// may end in "%" to be relative to the baseline
+func ParseTolerance(s string) (Tolerance, error) {
+	metric, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return Tolerance{}, fmt.Errorf("tolerance %q: want metric=value", s)
+	}
+	t := Tolerance{Metric: strings.TrimSpace(metric)}
+	if betterDirection(t.Metric) == 0 {
+		return Tolerance{}, fmt.Errorf("tolerance %q: %q has no direction to regress in", s, t.Metric)
+	}
+	value = strings.TrimSpace(value)
+	if trimmed, ok := strings.CutSuffix(value, "%"); ok {
+		value, t.Relative = strings.TrimSpace(trimmed), true
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil || v < 0 {
+		return Tolerance{}, fmt.Errorf("tolerance %q: invalid value %q", s, value)
+	}
+	t.Value = v
+	return t, nil
+}
+
+// betterDirection returns which way a baseline metric improves, or 0 for
+// metrics that are not compared or have no better direction
+func betterDirection(metric string) int {
+	for _, m := range baselineMetrics {
+		if m.name == metric {
+			return m.better
+		}
+	}
+	return 0
+}
+
+// allowed returns how much the metric may regress from baseline
+func (t Tolerance) allowed(baseline float64) float64 {
+	if t.Relative {
+		return math.Abs(baseline) * t.Value / 100
+	}
+	return t.Value
+}
+
+// BaselineDelta is one metric of a run next to the baseline's
+type BaselineDelta struct {
+	Metric           string
+	Baseline, Actual float64
+
+	// Regression is how far the metric moved the wrong way, 0 if it did
+	// not, and Exceeded is set when that is past its tolerance. Between runs
+	// that should agree, either way is wrong.
+	Regression float64
+	Exceeded   bool
+
+	agreement bool // between runs that should agree
+}
+
+// Delta returns how much the metric changed from the baseline
+func (d BaselineDelta) Delta() float64 {
+	return d.Actual - d.Baseline
+}
+
+func (d BaselineDelta) String() string {
+	line := fmt.Sprintf("%-12s %10.2f -> %10.2f (%+.2f)", d.Metric, d.Baseline, d.Actual, d.Delta())
+	switch {
+	case d.Exceeded && d.agreement:
+		line += "  DIFFERS"
+	case d.Exceeded:
+		line += "  REGRESSED"
+	}
+	return line
+}
+
+// DiffBaseline lines up a run's metrics with the baseline's, flagging the
+// regressions past their tolerance. Metrics without a tolerance are never
+// flagged.
+func DiffBaseline(baseline, actual Summary, tolerances []Tolerance) []BaselineDelta {
+	return diff(baseline, actual, tolerances, false)
+}
+
+// DiffRuns lines up the metrics of two runs that should agree, such as the
+// same seed on two engines, flagging those apart by more than their
+// tolerance either way. Metrics without a tolerance are never flagged.
+func DiffRuns(reference, actual Summary, tolerances []Tolerance) []BaselineDelta {
+	return diff(reference, actual, tolerances, true)
+}
+
+// diff compares actual with baseline, counting any change as a regression
+// if either is set
+func diff(baseline, actual Summary, tolerances []Tolerance, either bool) []BaselineDelta {
+	deltas := make([]BaselineDelta, 0, len(baselineMetrics))
+	for _, m := range baselineMetrics {
+		value := assertionMetrics[m.name].value
+		d := BaselineDelta{Metric: m.name, Baseline: value(baseline), Actual: value(actual), agreement: either}
+		d.Regression = math.Max(0, -float64(m.better)*d.Delta())
+		if either {
+			d.Regression = math.Abs(d.Delta())
+		}
+		for _, t := range tolerances {
+			if t.Metric == m.name && d.Regression > t.allowed(d.Baseline) {
+				d.Exceeded = true
+			}
+		}
+		deltas = append(deltas, d)
+	}
+	return deltas
+}
+
+// Regressed returns the deltas past their tolerance
+func Regressed(deltas []BaselineDelta) []BaselineDelta {
+	var regressed []BaselineDelta
+	for _, d := range deltas {
+		if d.Exceeded {
+			regressed = append(regressed, d)
+		}
+	}
+	return regressed
+}
@@ -0,0 +1,114 @@
+package history_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestParseTolerance(t *testing.T) {
+	tol, err := history.ParseTolerance("wasteRate=1.5")
+	require.NoError(t, err)
+	assert.Equal(t, history.Tolerance{Metric: "wasteRate", Value: 1.5}, tol)
+
+	tol, err = history.ParseTolerance("avgValue = 5%")
+	require.NoError(t, err)
+	assert.Equal(t, history.Tolerance{Metric: "avgValue", Value: 5, Relative: true}, tol)
+
+	for _, bad := range []string{"wasteRate", "speed=3", "received=10", "wasted=-1", "delivered=lots"} {
+		_, err := history.ParseTolerance(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestLoadBaseline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+	require.NoError(t, history.WriteManifest(path, history.Manifest{Stats: history.Summary{Received: 10, Delivered: 9}}))
+
+	baseline, err := history.LoadBaseline(path)
+	require.NoError(t, err)
+	assert.Equal(t, 9, baseline.Delivered)
+
+	empty := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(empty, []byte(`{"run": {}}`), 0o644))
+	_, err = history.LoadBaseline(empty)
+	assert.Error(t, err)
+	_, err = history.LoadBaseline(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestDiffBaseline(t *testing.T) {
+	summary := func(delivered, wasted int, value float64) history.Summary {
+		return history.Summary{
+			Received:  100,
+			Delivered: delivered,
+			Wasted:    wasted,
+			ByTemperature: map[order.Temperature]shelf.ItemStats{
+				order.Hot: {Delivered: delivered, TotalDeliveredValue: value * float64(delivered)},
+			},
+		}
+	}
+	baseline := summary(90, 10, 0.8)
+	actual := summary(88, 12, 0.9)
+
+	tolerances := []history.Tolerance{
+		{Metric: "wasteRate", Value: 1},
+		{Metric: "deliveryRate", Value: 5, Relative: true},
+		{Metric: "avgValue", Value: 0},
+	}
+	deltas := history.DiffBaseline(baseline, actual, tolerances)
+	byMetric := make(map[string]history.BaselineDelta)
+	for _, d := range deltas {
+		byMetric[d.Metric] = d
+	}
+
+	// Waste rose 2 points against a tolerance of 1
+	assert.InDelta(t, 2, byMetric["wasteRate"].Regression, 1e-9)
+	assert.True(t, byMetric["wasteRate"].Exceeded)
+	// Deliveries fell 2 points, within 5% of 90
+	assert.InDelta(t, -2, byMetric["deliveryRate"].Delta(), 1e-9)
+	assert.False(t, byMetric["deliveryRate"].Exceeded)
+	// The average value improved, which is no regression however tight
+	assert.Zero(t, byMetric["avgValue"].Regression)
+	assert.False(t, byMetric["avgValue"].Exceeded)
+	// Without a tolerance a metric is reported but never fails
+	assert.False(t, byMetric["wasted"].Exceeded)
+
+	regressed := history.Regressed(deltas)
+	require.Len(t, regressed, 1)
+	assert.Equal(t, "wasteRate", regressed[0].Metric)
+	assert.Contains(t, regressed[0].String(), "REGRESSED")
+}
+
+func TestDiffRuns(t *testing.T) {
+	summary := func(delivered, wasted int) history.Summary {
+		return history.Summary{Received: 100, Delivered: delivered, Wasted: wasted}
+	}
+	tolerances := []history.Tolerance{
+		{Metric: "wasteRate", Value: 1},
+		{Metric: "deliveryRate", Value: 1},
+	}
+
+	// Between runs that should agree, an improvement is as far off as a
+	// regression
+	deltas := history.DiffRuns(summary(90, 10), summary(92, 8), tolerances)
+	byMetric := make(map[string]history.BaselineDelta)
+	for _, d := range deltas {
+		byMetric[d.Metric] = d
+	}
+	assert.InDelta(t, 2, byMetric["wasteRate"].Regression, 1e-9)
+	assert.InDelta(t, 2, byMetric["deliveryRate"].Regression, 1e-9)
+	apart := history.Regressed(deltas)
+	require.Len(t, apart, 2)
+	assert.Contains(t, apart[0].String(), "DIFFERS")
+
+	assert.Empty(t, history.Regressed(history.DiffRuns(summary(90, 10), summary(90, 10), tolerances)))
+}
@@ -0,0 +1,267 @@
+// Package history keeps a local record of completed runs, with their
+// configuration, seed and final stats, so experiments can be listed and
+// compared later.
+//
+// Records are stored in a SQLite database file, one row per run. The run's
+// configuration and stats are kept as JSON, so new fields need no schema
+// change, while the columns listed and searched on have their own.
+package history
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	// Registers the pure Go "sqlite" driver, so builds need no cgo
+	_ "modernc.org/sqlite"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// Summary is a run's final stats
+type Summary struct {
+	Received  int `json:"received"`
+	Delivered int `json:"delivered"`
+	Wasted    int `json:"wasted"`
+	Expired   int `json:"expired"`
+
+	ByTemperature map[order.Temperature]shelf.ItemStats `json:"byTemperature,omitempty"`
+	Rejections    map[shelf.RejectReason]int            `json:"rejections,omitempty"`
+
+	// Promised and OnTime count the orders handed off with a promised
+	// delivery time and those that kept it; PromiseDelta is their mean
+	// seconds late, negative when early
+	Promised     int     `json:"promised,omitempty"`
+	OnTime       int     `json:"onTime,omitempty"`
+	PromiseDelta float64 `json:"promiseDelta,omitempty"`
+}
+
+// NewSummary reads the final stats from a shelf manager
+func NewSummary(manager shelf.ShelfManager) Summary {
+	stats := manager.GetStats()
+	summary := Summary{ByTemperature: manager.StatsByTemperature()}
+	if totals, ok := stats["totalOrders"].(map[string]interface{}); ok {
+		summary.Received, _ = totals["received"].(int)
+		summary.Delivered, _ = totals["delivered"].(int)
+		summary.Wasted, _ = totals["wasted"].(int)
+		summary.Expired, _ = totals["expired"].(int)
+	}
+	summary.Rejections, _ = stats["rejections"].(map[shelf.RejectReason]int)
+	return summary
+}
+
+// DeliveryRate returns the percentage of received orders delivered
+func (s Summary) DeliveryRate() float64 {
+	return s.percent(s.Delivered)
+}
+
+// WasteRate returns the percentage of received orders wasted or expired
+func (s Summary) WasteRate() float64 {
+	return s.percent(s.Wasted + s.Expired)
+}
+
+// AverageDeliveredValue returns the mean value of delivered orders
+func (s Summary) AverageDeliveredValue() float64 {
+	var total shelf.ItemStats
+	for _, is := range s.ByTemperature {
+		total.Delivered += is.Delivered
+		total.TotalDeliveredValue += is.TotalDeliveredValue
+	}
+	return total.AverageDeliveredValue()
+}
+
+// OnTimeRate returns the percentage of promised orders handed off on time
+func (s Summary) OnTimeRate() float64 {
+	if s.Promised == 0 {
+		return 0
+	}
+	return float64(s.OnTime) / float64(s.Promised) * 100
+}
+
+func (s Summary) percent(n int) float64 {
+	if s.Received == 0 {
+		return 0
+	}
+	return float64(n) / float64(s.Received) * 100
+}
+
+// Record is one completed run
+type Record struct {
+	ID         int               `json:"id"`
+	Run        config.RunContext `json:"run"`
+	Version    string            `json:"version,omitempty"` // of the binary that ran it
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Seed       int64             `json:"seed"` // passed to Simulator.Seed
+	Config     config.Config     `json:"config"`
+	Stats      Summary           `json:"stats"`
+	Err        string            `json:"error,omitempty"` // why the run failed, if it did
+}
+
+// ErrNotFound is returned by Get for an unknown record ID
+var ErrNotFound = errors.New("run not found")
+
+// schema creates the runs table in a new database
+const schema = `CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id      TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	run         TEXT NOT NULL,
+	version     TEXT NOT NULL,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT NOT NULL,
+	seed        INTEGER NOT NULL,
+	config      TEXT NOT NULL,
+	stats       TEXT NOT NULL,
+	error       TEXT NOT NULL
+)`
+
+// columns are the runs columns read into a Record, in scan order
+const columns = `id, run, version, started_at, finished_at, seed, config, stats, error`
+
+// Store is a history database. Several processes may record runs in the
+// same file; SQLite serializes their writes.
+type Store struct {
+	path string
+	db   *sql.DB
+}
+
+// Open opens the history database at path, creating it if needed
+func Open(path string) (*Store, error) {
+	// Wait for another process's write rather than failing at once
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &Store{path: path, db: db}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Add stores a record, assigning it the next ID, and returns it
+func (s *Store) Add(rec Record) (Record, error) {
+	run, err := json.Marshal(rec.Run)
+	if err != nil {
+		return Record{}, err
+	}
+	cfg, err := json.Marshal(rec.Config)
+	if err != nil {
+		return Record{}, err
+	}
+	stats, err := json.Marshal(rec.Stats)
+	if err != nil {
+		return Record{}, err
+	}
+
+	result, err := s.db.Exec(`INSERT INTO runs
+		(run_id, name, run, version, started_at, finished_at, seed, config, stats, error)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rec.Run.ID, rec.Run.Name, string(run), rec.Version,
+		rec.StartedAt.Format(time.RFC3339Nano), rec.FinishedAt.Format(time.RFC3339Nano),
+		rec.Seed, string(cfg), string(stats), rec.Err)
+	if err != nil {
+		return Record{}, fmt.Errorf("%s: %w", s.path, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return Record{}, err
+	}
+	rec.ID = int(id)
+	return rec, nil
+}
+
+// List returns every record, oldest first
+func (s *Store) List() ([]Record, error) {
+	rows, err := s.db.Query(`SELECT ` + columns + ` FROM runs ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	defer rows.Close()
+
+	var records []Record
+	for rows.Next() {
+		rec, err := s.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	return records, rows.Err()
+}
+
+// Get returns the record with the given ID
+func (s *Store) Get(id int) (Record, error) {
+	rec, err := s.scan(s.db.QueryRow(`SELECT `+columns+` FROM runs WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Record{}, fmt.Errorf("%w: %d", ErrNotFound, id)
+	}
+	return rec, err
+}
+
+// scan reads a record from a row of columns
+func (s *Store) scan(row interface{ Scan(...any) error }) (Record, error) {
+	var (
+		rec                   Record
+		run, cfg, stats       string
+		startedAt, finishedAt string
+	)
+	err := row.Scan(&rec.ID, &run, &rec.Version, &startedAt, &finishedAt, &rec.Seed, &cfg, &stats, &rec.Err)
+	if err != nil {
+		return Record{}, err
+	}
+
+	if err := json.Unmarshal([]byte(run), &rec.Run); err != nil {
+		return Record{}, fmt.Errorf("%s: run %d: %w", s.path, rec.ID, err)
+	}
+	if err := json.Unmarshal([]byte(cfg), &rec.Config); err != nil {
+		return Record{}, fmt.Errorf("%s: run %d config: %w", s.path, rec.ID, err)
+	}
+	if err := json.Unmarshal([]byte(stats), &rec.Stats); err != nil {
+		return Record{}, fmt.Errorf("%s: run %d stats: %w", s.path, rec.ID, err)
+	}
+	if rec.StartedAt, err = time.Parse(time.RFC3339Nano, startedAt); err != nil {
+		return Record{}, fmt.Errorf("%s: run %d: %w", s.path, rec.ID, err)
+	}
+	if rec.FinishedAt, err = time.Parse(time.RFC3339Nano, finishedAt); err != nil {
+		return Record{}, fmt.Errorf("%s: run %d: %w", s.path, rec.ID, err)
+	}
+	return rec, nil
+}
+
+// Comparison is one metric of two runs side by side
+type Comparison struct {
+	Metric string
+	A, B   float64
+}
+
+// Delta returns how much the metric changed from A to B
+func (c Comparison) Delta() float64 {
+	return c.B - c.A
+}
+
+// Compare lines up the headline metrics of two runs
+func Compare(a, b Record) []Comparison {
+	metric := func(name string, f func(Summary) float64) Comparison {
+		return Comparison{Metric: name, A: f(a.Stats), B: f(b.Stats)}
+	}
+	return []Comparison{
+		metric("received", func(s Summary) float64 { return float64(s.Received) }),
+		metric("delivered", func(s Summary) float64 { return float64(s.Delivered) }),
+		metric("wasted", func(s Summary) float64 { return float64(s.Wasted) }),
+		metric("expired", func(s Summary) float64 { return float64(s.Expired) }),
+		metric("delivery rate %", Summary.DeliveryRate),
+		metric("waste rate %", Summary.WasteRate),
+		metric("avg delivered value", Summary.AverageDeliveredValue),
+	}
+}
@@ -0,0 +1,104 @@
+package history_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/history"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestStore_AddListGet(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	records, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, records)
+
+	first, err := store.Add(history.Record{Run: config.RunContext{ID: "baseline-1", RunConfig: config.RunConfig{Name: "baseline"}}, Seed: 42, Config: *config.DefaultConfig()})
+	require.NoError(t, err)
+	assert.Equal(t, 1, first.ID)
+	second, err := store.Add(history.Record{Run: config.RunContext{RunConfig: config.RunConfig{Name: "bigger shelves"}}, Err: "stopped"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, second.ID)
+
+	records, err = store.List()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "baseline", records[0].Run.Name)
+	assert.Equal(t, "baseline-1", records[0].Run.ID)
+	assert.Equal(t, int64(42), records[0].Seed)
+	assert.Equal(t, config.DefaultConfig().HotShelfCapacity, records[0].Config.HotShelfCapacity)
+
+	got, err := store.Get(2)
+	require.NoError(t, err)
+	assert.Equal(t, "stopped", got.Err)
+
+	_, err = store.Get(3)
+	assert.ErrorIs(t, err, history.ErrNotFound)
+}
+
+func TestStore_Reopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	store, err := history.Open(path)
+	require.NoError(t, err)
+	_, err = store.Add(history.Record{StartedAt: started, FinishedAt: started.Add(time.Minute), Stats: history.Summary{Received: 7}})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	store, err = history.Open(path)
+	require.NoError(t, err)
+	defer store.Close()
+	got, err := store.Get(1)
+	require.NoError(t, err)
+	assert.True(t, started.Equal(got.StartedAt))
+	assert.Equal(t, time.Minute, got.FinishedAt.Sub(got.StartedAt))
+	assert.Equal(t, 7, got.Stats.Received)
+}
+
+func TestStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	require.NoError(t, os.WriteFile(path, []byte("{not a database\n"), 0o644))
+
+	_, err := history.Open(path)
+	assert.ErrorContains(t, err, path)
+}
+
+func TestNewSummary(t *testing.T) {
+	m := shelf.NewShelfManager(1, 1, 1, 1)
+	delivered := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, m.PlaceOrder(delivered))
+	require.True(t, m.DeliverOrder(delivered.ID))
+	require.NoError(t, m.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5)))
+
+	s := history.NewSummary(m)
+	assert.Equal(t, 2, s.Received)
+	assert.Equal(t, 1, s.Delivered)
+	assert.InDelta(t, 50, s.DeliveryRate(), 0.001)
+	assert.Equal(t, 1, s.ByTemperature[order.Hot].Delivered)
+	assert.Greater(t, s.AverageDeliveredValue(), 0.0)
+}
+
+func TestCompare(t *testing.T) {
+	a := history.Record{Stats: history.Summary{Received: 10, Delivered: 5, Wasted: 2}}
+	b := history.Record{Stats: history.Summary{Received: 10, Delivered: 8}}
+
+	byMetric := make(map[string]history.Comparison)
+	for _, c := range history.Compare(a, b) {
+		byMetric[c.Metric] = c
+	}
+	assert.Equal(t, 3.0, byMetric["delivered"].Delta())
+	assert.InDelta(t, 30, byMetric["delivery rate %"].Delta(), 0.001)
+	assert.InDelta(t, -20, byMetric["waste rate %"].Delta(), 0.001)
+}
@@ -0,0 +1,66 @@
+package history
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+// Manifest pins down everything a run depended on, so its result can be
+// reproduced later: the resolved config, the seed, the input files by
+// content and the binary.
+type Manifest struct {
+	Run        config.RunContext `json:"run"`
+	Version    string            `json:"version"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt time.Time         `json:"finishedAt"`
+	Seed       int64             `json:"seed"`             // passed to Simulator.Seed
+	Inputs     map[string]string `json:"inputs,omitempty"` // SHA-256 of each file read, by path
+	Config     config.Config     `json:"config"`
+	Stats      Summary           `json:"stats"`
+	Err        string            `json:"error,omitempty"` // why the run failed, if it did
+}
+
+// HashInputs returns the SHA-256 of each file, by path
+func HashInputs(paths []string) (map[string]string, error) {
+	hashes := make(map[string]string, len(paths))
+	for _, path := range paths {
+		if _, ok := hashes[path]; ok {
+			continue
+		}
+		sum, err := hashFile(path)
+		if err != nil {
+			return nil, err
+		}
+		hashes[path] = sum
+	}
+	return hashes, nil
+}
+
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteManifest writes m to path as indented JSON
+func WriteManifest(path string, m Manifest) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(raw, '\n'), 0o644)
+}
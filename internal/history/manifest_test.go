@@ -0,0 +1,56 @@
+package history_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/history"
+)
+
+func TestHashInputs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	require.NoError(t, os.WriteFile(path, []byte("[]"), 0o644))
+
+	hashes, err := history.HashInputs([]string{path, path})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{path: "4f53cda18c2baa0c0354bb5f9a3ecbe5ed12ab4d8e11ba873c2f11161202b945"}, hashes)
+
+	_, err = history.HashInputs([]string{filepath.Join(t.TempDir(), "missing.json")})
+	assert.Error(t, err)
+}
+
+func TestWriteManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m := history.Manifest{
+		Run:        config.NewRunContext(config.RunConfig{Name: "baseline"}),
+		Version:    "v1.2.0",
+		StartedAt:  started,
+		FinishedAt: started.Add(time.Minute),
+		Seed:       42,
+		Inputs:     map[string]string{"orders.json": "abc"},
+		Config:     *config.DefaultConfig(),
+		Err:        "stopped",
+	}
+	require.NoError(t, history.WriteManifest(path, m))
+
+	raw, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var got history.Manifest
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, "baseline", got.Run.Name)
+	assert.Equal(t, m.Run.ID, got.Run.ID)
+	assert.Equal(t, "v1.2.0", got.Version)
+	assert.True(t, got.FinishedAt.Equal(started.Add(time.Minute)))
+	assert.Equal(t, int64(42), got.Seed)
+	assert.Equal(t, m.Inputs, got.Inputs)
+	assert.Equal(t, config.DefaultConfig().HotShelfCapacity, got.Config.HotShelfCapacity)
+	assert.Equal(t, "stopped", got.Err)
+}
@@ -0,0 +1,266 @@
+// Package loadgen fires synthetic orders at a dispatcher running in service
+// mode and measures how it holds up from the client side.
+package loadgen
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/simulator"
+)
+
+// Options configures a load run
+type Options struct {
+	Target      string        // base URL of the dispatcher, such as http://localhost:8080
+	RPS         float64       // orders sent per second
+	Duration    time.Duration // how long to send for
+	Concurrency int           // most requests in flight at once
+	Timeout     time.Duration // per request
+	Token       string        // sent as a bearer token, empty for none
+	TLS         *tls.Config   // for an https target, nil for the defaults
+
+	// Templates are the orders sent, picked at random. Mix, if set, first
+	// picks a temperature by weight, then a template of that temperature.
+	Templates []simulator.OrderData
+	Mix       map[string]float64
+}
+
+// Report is the client-side view of a load run
+type Report struct {
+	Elapsed  time.Duration
+	Sent     int
+	Placed   int // shelved, 201
+	Wasted   int // rejected by the dispatcher, 409
+	Errors   int // any other status or a failed request
+	Skipped  int // not sent because Concurrency requests were in flight
+	ByStatus map[int]int
+
+	// Latencies of completed requests, sorted
+	Latencies []time.Duration
+}
+
+// Percentile returns the latency at or below which p percent of completed
+// requests finished
+func (r Report) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.Latencies))*p/100+0.5) - 1
+	return r.Latencies[min(max(i, 0), len(r.Latencies)-1)]
+}
+
+// Mean returns the mean latency of completed requests
+func (r Report) Mean() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, l := range r.Latencies {
+		total += l
+	}
+	return total / time.Duration(len(r.Latencies))
+}
+
+// Throughput returns completed requests per second
+func (r Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(len(r.Latencies)) / r.Elapsed.Seconds()
+}
+
+// ParseMix parses temperature weights such as "hot=2,cold=1,frozen=1"
+func ParseMix(s string) (map[string]float64, error) {
+	if s == "" {
+		return nil, nil
+	}
+	mix := make(map[string]float64)
+	for _, part := range strings.Split(s, ",") {
+		temp, raw, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || temp == "" {
+			return nil, fmt.Errorf("mix entry %q must be temp=weight", part)
+		}
+		weight, err := strconv.ParseFloat(raw, 64)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("mix weight for %s must be a non-negative number, got %q", temp, raw)
+		}
+		mix[temp] = weight
+	}
+	return mix, nil
+}
+
+// picker chooses the order to send next
+type picker struct {
+	byTemp  map[string][]simulator.OrderData
+	all     []simulator.OrderData
+	temps   []string
+	weights []float64
+	total   float64
+}
+
+func newPicker(templates []simulator.OrderData, mix map[string]float64) (*picker, error) {
+	p := &picker{byTemp: make(map[string][]simulator.OrderData), all: templates}
+	for _, t := range templates {
+		p.byTemp[t.Temp] = append(p.byTemp[t.Temp], t)
+	}
+
+	for temp := range mix {
+		p.temps = append(p.temps, temp)
+	}
+	sort.Strings(p.temps)
+	for _, temp := range p.temps {
+		p.weights = append(p.weights, mix[temp])
+		p.total += mix[temp]
+	}
+
+	if len(mix) > 0 && p.total <= 0 {
+		return nil, errors.New("mix weights must not all be zero")
+	}
+	if len(mix) == 0 && len(templates) == 0 {
+		return nil, errors.New("no order templates or mix")
+	}
+	return p, nil
+}
+
+// pick returns a random order. A temperature in the mix with no templates
+// gets a generic order.
+func (p *picker) pick() simulator.OrderData {
+	if p.total <= 0 {
+		return p.all[rand.IntN(len(p.all))]
+	}
+
+	r := rand.Float64() * p.total
+	temp := p.temps[len(p.temps)-1]
+	for i, w := range p.weights {
+		if r < w {
+			temp = p.temps[i]
+			break
+		}
+		r -= w
+	}
+
+	if templates := p.byTemp[temp]; len(templates) > 0 {
+		return templates[rand.IntN(len(templates))]
+	}
+	return simulator.OrderData{Name: "Synthetic " + temp, Temp: temp, ShelfLife: 300, DecayRate: 0.5}
+}
+
+// Run sends orders at opts.RPS until opts.Duration passes or ctx is
+// cancelled, then waits for in-flight requests and returns the report
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if opts.RPS <= 0 {
+		return Report{}, fmt.Errorf("rps must be positive, got %g", opts.RPS)
+	}
+	if opts.Concurrency <= 0 {
+		return Report{}, fmt.Errorf("concurrency must be positive, got %d", opts.Concurrency)
+	}
+	p, err := newPicker(opts.Templates, opts.Mix)
+	if err != nil {
+		return Report{}, err
+	}
+
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	client := &http.Client{Timeout: opts.Timeout}
+	if opts.TLS != nil {
+		client.Transport = &http.Transport{TLSClientConfig: opts.TLS}
+	}
+	url := strings.TrimSuffix(opts.Target, "/") + "/orders"
+
+	var (
+		mutex  sync.Mutex
+		report = Report{ByStatus: make(map[int]int)}
+		wg     sync.WaitGroup
+		slots  = make(chan struct{}, opts.Concurrency)
+	)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RPS))
+	defer ticker.Stop()
+
+	started := time.Now()
+	for running := true; running; {
+		select {
+		case <-ctx.Done():
+			running = false
+		case <-ticker.C:
+			select {
+			case slots <- struct{}{}:
+			default:
+				mutex.Lock()
+				report.Skipped++
+				mutex.Unlock()
+				continue
+			}
+
+			wg.Add(1)
+			go func(d simulator.OrderData) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				status, latency, err := send(client, url, opts.Token, d)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				report.Sent++
+				if err != nil {
+					report.Errors++
+					return
+				}
+				report.ByStatus[status]++
+				report.Latencies = append(report.Latencies, latency)
+				switch status {
+				case http.StatusCreated:
+					report.Placed++
+				case http.StatusConflict:
+					report.Wasted++
+				default:
+					report.Errors++
+				}
+			}(p.pick())
+		}
+	}
+
+	wg.Wait()
+	report.Elapsed = time.Since(started)
+	sort.Slice(report.Latencies, func(i, j int) bool { return report.Latencies[i] < report.Latencies[j] })
+	return report, nil
+}
+
+// send posts one order and returns the response status and latency
+func send(client *http.Client, url, token string, d simulator.OrderData) (int, time.Duration, error) {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return 0, 0, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, time.Since(start), nil
+}
@@ -0,0 +1,111 @@
+package loadgen_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/loadgen"
+	"dish-dispatcher/internal/simulator"
+)
+
+func TestParseMix(t *testing.T) {
+	mix, err := loadgen.ParseMix("hot=2, cold=1,frozen=0")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"hot": 2, "cold": 1, "frozen": 0}, mix)
+
+	mix, err = loadgen.ParseMix("")
+	assert.NoError(t, err)
+	assert.Nil(t, mix)
+
+	_, err = loadgen.ParseMix("hot")
+	assert.Error(t, err)
+	_, err = loadgen.ParseMix("hot=-1")
+	assert.Error(t, err)
+}
+
+func TestReport_Percentile(t *testing.T) {
+	var r loadgen.Report
+	assert.Zero(t, r.Percentile(50))
+
+	for i := 1; i <= 100; i++ {
+		r.Latencies = append(r.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	assert.Equal(t, 50*time.Millisecond, r.Percentile(50))
+	assert.Equal(t, 99*time.Millisecond, r.Percentile(99))
+	assert.Equal(t, 100*time.Millisecond, r.Percentile(100))
+	assert.Equal(t, 50500*time.Microsecond, r.Mean())
+}
+
+func TestRun(t *testing.T) {
+	var (
+		mutex sync.Mutex
+		temps = make(map[string]int)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var d simulator.OrderData
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&d))
+		mutex.Lock()
+		temps[d.Temp]++
+		mutex.Unlock()
+
+		switch d.Temp {
+		case "hot":
+			w.WriteHeader(http.StatusCreated)
+		case "cold":
+			w.WriteHeader(http.StatusConflict)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	report, err := loadgen.Run(context.Background(), loadgen.Options{
+		Target:      srv.URL,
+		RPS:         200,
+		Duration:    300 * time.Millisecond,
+		Concurrency: 8,
+		Timeout:     time.Second,
+		Templates:   []simulator.OrderData{{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}},
+		Mix:         map[string]float64{"hot": 1, "cold": 1, "frozen": 1},
+	})
+	require.NoError(t, err)
+
+	assert.Greater(t, report.Sent, 10)
+	assert.Equal(t, report.Sent, report.Placed+report.Wasted+report.Errors)
+	assert.Equal(t, temps["hot"], report.Placed)
+	assert.Equal(t, temps["cold"], report.Wasted)
+	assert.Equal(t, temps["frozen"], report.ByStatus[http.StatusInternalServerError])
+	assert.Len(t, report.Latencies, report.Sent)
+	assert.Positive(t, report.Throughput())
+}
+
+func TestRun_Unreachable(t *testing.T) {
+	report, err := loadgen.Run(context.Background(), loadgen.Options{
+		Target:      "http://127.0.0.1:1",
+		RPS:         50,
+		Duration:    100 * time.Millisecond,
+		Concurrency: 4,
+		Timeout:     100 * time.Millisecond,
+		Mix:         map[string]float64{"hot": 1},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, report.Sent, report.Errors)
+	assert.Empty(t, report.Latencies)
+}
+
+func TestRun_Invalid(t *testing.T) {
+	_, err := loadgen.Run(context.Background(), loadgen.Options{RPS: 0, Concurrency: 1, Mix: map[string]float64{"hot": 1}})
+	assert.Error(t, err)
+	_, err = loadgen.Run(context.Background(), loadgen.Options{RPS: 1, Concurrency: 1})
+	assert.Error(t, err)
+	_, err = loadgen.Run(context.Background(), loadgen.Options{RPS: 1, Concurrency: 1, Mix: map[string]float64{"hot": 0}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,275 @@
+// Package mqtt is a minimal MQTT 3.1.1 client. It only publishes, at QoS 0,
+// which is all the dispatcher needs to feed kitchen displays, so it avoids
+// depending on a full client library.
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Defaults used when Options leaves a field empty
+const (
+	DefaultKeepAlive   = 30 * time.Second
+	DefaultDialTimeout = 5 * time.Second
+)
+
+// Options configures a connection to a broker
+type Options struct {
+	Addr     string // broker host:port
+	ClientID string
+	Username string // empty to connect anonymously
+	Password string
+
+	// KeepAlive is the longest the client stays silent before pinging the
+	// broker, which disconnects clients silent for half as long again
+	KeepAlive   time.Duration
+	DialTimeout time.Duration
+}
+
+// Control packet types, in the high nibble of the first header byte
+const (
+	packetConnect    = 1
+	packetConnack    = 2
+	packetPublish    = 3
+	packetPingreq    = 12
+	packetDisconnect = 14
+)
+
+// ErrClosed is returned when publishing on a closed or lost connection
+var ErrClosed = errors.New("mqtt: connection closed")
+
+// connackErrors explains the return codes of a refused CONNECT
+var connackErrors = map[byte]string{
+	1: "unacceptable protocol version",
+	2: "client identifier rejected",
+	3: "server unavailable",
+	4: "bad user name or password",
+	5: "not authorized",
+}
+
+// Client is a connection to a broker. It is safe for concurrent use.
+type Client struct {
+	conn net.Conn
+
+	mutex  sync.Mutex // serializes writes
+	closed bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Dial connects to the broker and waits for it to accept the connection
+func Dial(opts Options) (*Client, error) {
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = DefaultKeepAlive
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultDialTimeout
+	}
+
+	conn, err := net.DialTimeout("tcp", opts.Addr, opts.DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(opts.DialTimeout))
+
+	r := bufio.NewReader(conn)
+	if _, err := conn.Write(connectPacket(opts)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("mqtt: connect: %w", err)
+	}
+	if err := readConnack(r); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{conn: conn, done: make(chan struct{})}
+	c.wg.Add(2)
+	go c.readLoop(r)
+	go c.keepAlive(opts.KeepAlive)
+	return c, nil
+}
+
+// Publish sends a message at QoS 0: the broker does not acknowledge it and
+// it is lost if the connection drops
+func (c *Client) Publish(topic string, payload []byte, retain bool) error {
+	header := byte(packetPublish << 4)
+	if retain {
+		header |= 1
+	}
+	body := appendString(nil, topic)
+	return c.write(packet(header, append(body, payload...)))
+}
+
+// Close disconnects from the broker
+func (c *Client) Close() error {
+	err := c.write([]byte{packetDisconnect << 4, 0})
+	c.shutdown()
+	c.wg.Wait()
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (c *Client) write(p []byte) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	if _, err := c.conn.Write(p); err != nil {
+		return fmt.Errorf("mqtt: %w", err)
+	}
+	return nil
+}
+
+// shutdown closes the connection once
+func (c *Client) shutdown() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		close(c.done)
+		c.conn.Close()
+	}
+}
+
+// readLoop discards what the broker sends, which after CONNACK is only
+// ping responses, until the connection drops
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer c.wg.Done()
+	defer c.shutdown()
+
+	for {
+		if _, _, err := readPacket(r); err != nil {
+			return
+		}
+	}
+}
+
+// keepAlive pings the broker so it keeps the connection open between
+// publishes
+func (c *Client) keepAlive(interval time.Duration) {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if c.write([]byte{packetPingreq << 4, 0}) != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func connectPacket(opts Options) []byte {
+	flags := byte(0x02) // clean session
+	if opts.Username != "" {
+		flags |= 0x80
+		if opts.Password != "" {
+			flags |= 0x40
+		}
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, 4, flags) // protocol level 4 is MQTT 3.1.1
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive/time.Second))
+	body = appendString(body, opts.ClientID)
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+		if opts.Password != "" {
+			body = appendString(body, opts.Password)
+		}
+	}
+	return packet(packetConnect<<4, body)
+}
+
+func readConnack(r *bufio.Reader) error {
+	header, body, err := readPacket(r)
+	if err != nil {
+		return fmt.Errorf("mqtt: waiting for connack: %w", err)
+	}
+	if header>>4 != packetConnack || len(body) != 2 {
+		return fmt.Errorf("mqtt: expected connack, got packet type %d", header>>4)
+	}
+	if code := body[1]; code != 0 {
+		reason, ok := connackErrors[code]
+		if !ok {
+			reason = fmt.Sprintf("return code %d", code)
+		}
+		return fmt.Errorf("mqtt: connection refused: %s", reason)
+	}
+	return nil
+}
+
+// packet frames a body behind its fixed header
+func packet(header byte, body []byte) []byte {
+	p := appendRemainingLength([]byte{header}, len(body))
+	return append(p, body...)
+}
+
+// appendRemainingLength encodes n seven bits at a time, least significant
+// first, with the high bit marking that more bytes follow
+func appendRemainingLength(b []byte, n int) []byte {
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			return b
+		}
+	}
+}
+
+// appendString appends a string behind its two-byte length
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// readPacket reads one packet, returning its first header byte and body
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("mqtt: malformed remaining length")
+		}
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
@@ -0,0 +1,168 @@
+package mqtt_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/mqtt"
+)
+
+// message is a PUBLISH received by the fake broker
+type message struct {
+	Topic   string
+	Payload string
+	Retain  bool
+}
+
+// fakeBroker accepts one client, answers its CONNECT with returnCode and
+// records what it publishes
+type fakeBroker struct {
+	listener   net.Listener
+	returnCode byte
+
+	mutex        sync.Mutex
+	clientID     string
+	username     string
+	keepAlive    uint16
+	messages     []message
+	pings        int
+	disconnected bool
+}
+
+func newFakeBroker(t *testing.T, returnCode byte) *fakeBroker {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	b := &fakeBroker{listener: listener, returnCode: returnCode}
+	t.Cleanup(func() { listener.Close() })
+	go b.serve()
+	return b
+}
+
+func (b *fakeBroker) addr() string { return b.listener.Addr().String() }
+
+func (b *fakeBroker) serve() {
+	conn, err := b.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	for {
+		header, body, err := readPacket(r)
+		if err != nil {
+			return
+		}
+
+		b.mutex.Lock()
+		switch header >> 4 {
+		case 1: // CONNECT
+			_, rest := readString(body)
+			flags := rest[1]
+			b.keepAlive = binary.BigEndian.Uint16(rest[2:])
+			b.clientID, rest = readString(rest[4:])
+			if flags&0x80 != 0 {
+				b.username, _ = readString(rest)
+			}
+			conn.Write([]byte{0x20, 2, 0, b.returnCode})
+		case 3: // PUBLISH
+			topic, payload := readString(body)
+			b.messages = append(b.messages, message{Topic: topic, Payload: string(payload), Retain: header&1 != 0})
+		case 12: // PINGREQ
+			b.pings++
+			conn.Write([]byte{0xd0, 0})
+		case 14: // DISCONNECT
+			b.disconnected = true
+		}
+		b.mutex.Unlock()
+	}
+}
+
+func (b *fakeBroker) snapshot() fakeBroker {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return fakeBroker{
+		clientID:     b.clientID,
+		username:     b.username,
+		keepAlive:    b.keepAlive,
+		messages:     append([]message(nil), b.messages...),
+		pings:        b.pings,
+		disconnected: b.disconnected,
+	}
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7f) * multiplier
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return header, body, err
+}
+
+func readString(b []byte) (string, []byte) {
+	n := binary.BigEndian.Uint16(b)
+	return string(b[2 : 2+n]), b[2+n:]
+}
+
+func TestClient_Publish(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	client, err := mqtt.Dial(mqtt.Options{Addr: broker.addr(), ClientID: "kitchen-1", Username: "display", Password: "secret"})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Publish("dish-dispatcher/order_placed", []byte(`{"name":"Burger"}`), false))
+	// A payload long enough to need a two-byte remaining length
+	long := make([]byte, 300)
+	require.NoError(t, client.Publish("dish-dispatcher/shelves/hot/order_placed", long, true))
+	require.NoError(t, client.Close())
+	assert.ErrorIs(t, client.Publish("late", nil, false), mqtt.ErrClosed)
+
+	require.Eventually(t, func() bool { return broker.snapshot().disconnected }, time.Second, time.Millisecond)
+	got := broker.snapshot()
+	assert.Equal(t, "kitchen-1", got.clientID)
+	assert.Equal(t, "display", got.username)
+	assert.Equal(t, uint16(30), got.keepAlive)
+	assert.Equal(t, []message{
+		{Topic: "dish-dispatcher/order_placed", Payload: `{"name":"Burger"}`},
+		{Topic: "dish-dispatcher/shelves/hot/order_placed", Payload: string(long), Retain: true},
+	}, got.messages)
+}
+
+func TestClient_KeepAlive(t *testing.T) {
+	broker := newFakeBroker(t, 0)
+	client, err := mqtt.Dial(mqtt.Options{Addr: broker.addr(), ClientID: "kitchen-1", KeepAlive: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer client.Close()
+
+	require.Eventually(t, func() bool { return broker.snapshot().pings >= 2 }, time.Second, time.Millisecond)
+}
+
+func TestDial_Refused(t *testing.T) {
+	broker := newFakeBroker(t, 5)
+	_, err := mqtt.Dial(mqtt.Options{Addr: broker.addr(), ClientID: "kitchen-1"})
+	assert.ErrorContains(t, err, "not authorized")
+}
@@ -0,0 +1,276 @@
+// Package nats is a minimal client for the NATS text protocol. It can
+// subscribe, publish and answer requests, which is all the dispatcher needs
+// to take orders from NATS, so it avoids depending on a client library.
+package nats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDialTimeout is used when Options leaves DialTimeout empty
+const DefaultDialTimeout = 5 * time.Second
+
+// Options configures a connection to a NATS server
+type Options struct {
+	Addr string // server host:port
+	Name string // shown in the server's connection list
+
+	// Credentials, if the server requires them: a user and password, or a
+	// token
+	User     string
+	Password string
+	Token    string
+
+	DialTimeout time.Duration
+}
+
+// Msg is a message received on a subscription
+type Msg struct {
+	Subject string
+	Reply   string // where to send a response, empty unless it is a request
+	Data    []byte
+}
+
+// ErrClosed is returned when using a closed or lost connection
+var ErrClosed = errors.New("nats: connection closed")
+
+// Client is a connection to a NATS server. It is safe for concurrent use.
+// Subscription handlers run one at a time on the connection's read loop,
+// so they should not block for long.
+type Client struct {
+	conn net.Conn
+
+	mutex    sync.Mutex // serializes writes and guards the fields below
+	closed   bool
+	handlers map[int]func(Msg) // by subscription ID
+	nextSID  int
+
+	done chan struct{}
+}
+
+// connectOptions is the CONNECT message
+type connectOptions struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name,omitempty"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+	Lang     string `json:"lang"`
+	Version  string `json:"version"`
+	Protocol int    `json:"protocol"`
+}
+
+// Dial connects to the server and waits until it has accepted the
+// connection
+func Dial(opts Options) (*Client, error) {
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultDialTimeout
+	}
+
+	conn, err := net.DialTimeout("tcp", opts.Addr, opts.DialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("nats: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(opts.DialTimeout))
+
+	r := bufio.NewReader(conn)
+	if err := handshake(conn, r, opts); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+
+	c := &Client{conn: conn, handlers: make(map[int]func(Msg)), done: make(chan struct{})}
+	go c.readLoop(r)
+	return c, nil
+}
+
+// handshake reads the server's INFO, sends CONNECT and waits for the PONG
+// answering a PING, which the server sends only once it accepts CONNECT
+func handshake(conn net.Conn, r *bufio.Reader, opts Options) error {
+	line, err := readLine(r)
+	if err != nil {
+		return fmt.Errorf("nats: waiting for info: %w", err)
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("nats: expected info, got %q", line)
+	}
+
+	connect, err := json.Marshal(connectOptions{
+		Name:     opts.Name,
+		User:     opts.User,
+		Pass:     opts.Password,
+		Token:    opts.Token,
+		Lang:     "go",
+		Version:  "dish-dispatcher",
+		Protocol: 1,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\nPING\r\n", connect); err != nil {
+		return fmt.Errorf("nats: connect: %w", err)
+	}
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return fmt.Errorf("nats: connect: %w", err)
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: connection refused: %s", serverError(line))
+		}
+	}
+}
+
+// Subscribe calls handler with every message on subject. Subscribers that
+// share a non-empty queue group split the messages between them.
+func (c *Client) Subscribe(subject, queue string, handler func(Msg)) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q", subject)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	c.nextSID++
+	sid := c.nextSID
+	c.handlers[sid] = handler
+
+	command := "SUB " + subject
+	if queue != "" {
+		command += " " + queue
+	}
+	return c.writeLocked(command + " " + strconv.Itoa(sid) + "\r\n")
+}
+
+// Publish sends data to subject
+func (c *Client) Publish(subject string, data []byte) error {
+	if subject == "" || strings.ContainsAny(subject, " \t\r\n") {
+		return fmt.Errorf("nats: invalid subject %q", subject)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.closed {
+		return ErrClosed
+	}
+	return c.writeLocked(fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(data), data))
+}
+
+// Close disconnects from the server
+func (c *Client) Close() error {
+	c.shutdown()
+	<-c.done
+	return nil
+}
+
+// writeLocked writes a command. Callers hold the lock.
+func (c *Client) writeLocked(command string) error {
+	if _, err := io.WriteString(c.conn, command); err != nil {
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+// shutdown closes the connection once, which ends the read loop
+func (c *Client) shutdown() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		c.conn.Close()
+	}
+}
+
+// readLoop handles what the server sends until the connection drops
+func (c *Client) readLoop(r *bufio.Reader) {
+	defer close(c.done)
+	defer c.shutdown()
+
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			if err := c.deliver(r, line); err != nil {
+				return
+			}
+		case line == "PING":
+			c.mutex.Lock()
+			err := c.writeLocked("PONG\r\n")
+			c.mutex.Unlock()
+			if err != nil {
+				return
+			}
+		}
+		// PONG, +OK, INFO updates and -ERR after the handshake need no
+		// answer; the server closes the connection on fatal errors
+	}
+}
+
+// deliver reads the payload of a MSG and hands it to its subscription's
+// handler. The line is "MSG <subject> <sid> [reply-to] <#bytes>".
+func (c *Client) deliver(r *bufio.Reader, line string) error {
+	fields := strings.Fields(line)
+	if len(fields) != 4 && len(fields) != 5 {
+		return fmt.Errorf("nats: malformed %q", line)
+	}
+	size, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || size < 0 {
+		return fmt.Errorf("nats: malformed %q", line)
+	}
+	payload := make([]byte, size+2) // with its trailing CRLF
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return err
+	}
+
+	msg := Msg{Subject: fields[1], Data: payload[:size]}
+	if len(fields) == 5 {
+		msg.Reply = fields[3]
+	}
+	sid, _ := strconv.Atoi(fields[2])
+
+	c.mutex.Lock()
+	handler := c.handlers[sid]
+	c.mutex.Unlock()
+
+	if handler != nil {
+		handler(msg)
+	}
+	return nil
+}
+
+// readLine reads one CRLF-terminated protocol line without its terminator
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// serverError extracts the message of an -ERR line
+func serverError(line string) string {
+	return strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+}
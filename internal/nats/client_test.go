@@ -0,0 +1,161 @@
+package nats_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/nats"
+)
+
+// fakeServer accepts one client, records its commands and lets the test
+// send it messages. It refuses clients whose CONNECT lacks token, if set.
+type fakeServer struct {
+	listener net.Listener
+	token    string
+
+	mutex    sync.Mutex
+	conn     net.Conn
+	commands []string
+	payloads []string
+}
+
+func newFakeServer(t *testing.T, token string) *fakeServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: listener, token: token}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.listener.Addr().String() }
+
+func (s *fakeServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	s.mutex.Lock()
+	s.conn = conn
+	s.mutex.Unlock()
+
+	fmt.Fprint(conn, "INFO {\"server_id\":\"fake\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		s.mutex.Lock()
+		s.commands = append(s.commands, line)
+		switch {
+		case strings.HasPrefix(line, "CONNECT "):
+			if s.token != "" && !strings.Contains(line, `"auth_token":"`+s.token+`"`) {
+				fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+				s.mutex.Unlock()
+				return
+			}
+		case line == "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case strings.HasPrefix(line, "PUB "):
+			var subject string
+			var size int
+			fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+			payload := make([]byte, size+2)
+			io.ReadFull(r, payload)
+			s.payloads = append(s.payloads, string(payload[:size]))
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// send writes a raw protocol line to the client
+func (s *fakeServer) send(raw string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fmt.Fprint(s.conn, raw)
+}
+
+func (s *fakeServer) recorded() ([]string, []string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]string(nil), s.commands...), append([]string(nil), s.payloads...)
+}
+
+func (s *fakeServer) waitFor(t *testing.T, prefix string) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		commands, _ := s.recorded()
+		for _, c := range commands {
+			if strings.HasPrefix(c, prefix) {
+				return true
+			}
+		}
+		return false
+	}, time.Second, time.Millisecond)
+}
+
+func TestClient_SubscribeAndReply(t *testing.T) {
+	server := newFakeServer(t, "")
+	client, err := nats.Dial(nats.Options{Addr: server.addr(), Name: "dispatcher"})
+	require.NoError(t, err)
+	defer client.Close()
+
+	received := make(chan nats.Msg, 2)
+	require.NoError(t, client.Subscribe("orders.new", "dispatchers", func(m nats.Msg) {
+		received <- m
+		if m.Reply != "" {
+			client.Publish(m.Reply, []byte("ok"))
+		}
+	}))
+	server.waitFor(t, "SUB orders.new dispatchers 1")
+
+	server.send("PING\r\nMSG orders.new 1 5\r\nhello\r\nMSG orders.new 1 _INBOX.1 2\r\nhi\r\n")
+	assert.Equal(t, nats.Msg{Subject: "orders.new", Data: []byte("hello")}, <-received)
+	assert.Equal(t, nats.Msg{Subject: "orders.new", Reply: "_INBOX.1", Data: []byte("hi")}, <-received)
+
+	server.waitFor(t, "PUB _INBOX.1 2")
+	server.waitFor(t, "PONG")
+	commands, payloads := server.recorded()
+	assert.Contains(t, commands[0], `"name":"dispatcher"`)
+	assert.Equal(t, []string{"ok"}, payloads)
+}
+
+func TestClient_Publish(t *testing.T) {
+	server := newFakeServer(t, "")
+	client, err := nats.Dial(nats.Options{Addr: server.addr()})
+	require.NoError(t, err)
+
+	require.NoError(t, client.Publish("orders.done", []byte(`{"id":"1"}`)))
+	assert.Error(t, client.Publish("bad subject", nil))
+	server.waitFor(t, "PUB orders.done")
+	require.NoError(t, client.Close())
+	assert.ErrorIs(t, client.Publish("orders.done", nil), nats.ErrClosed)
+
+	_, payloads := server.recorded()
+	assert.Equal(t, []string{`{"id":"1"}`}, payloads)
+}
+
+func TestDial_Refused(t *testing.T) {
+	server := newFakeServer(t, "secret")
+	_, err := nats.Dial(nats.Options{Addr: server.addr(), Token: "wrong"})
+	assert.ErrorContains(t, err, "Authorization Violation")
+}
@@ -0,0 +1,301 @@
+// Package optimize searches shelf capacities and courier counts for the
+// configuration wasting the fewest orders within a cost budget.
+package optimize
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxGridSize bounds the candidates a grid search may try
+const maxGridSize = 10000
+
+// maxClimbSteps bounds the moves of a hill climb
+const maxClimbSteps = 1000
+
+// Candidate is one configuration: a capacity for each searched shelf, in
+// layout order, and the courier count
+type Candidate struct {
+	Capacities []int
+	Couriers   int
+}
+
+func (c Candidate) key() string {
+	var b strings.Builder
+	for _, capacity := range c.Capacities {
+		b.WriteString(strconv.Itoa(capacity))
+		b.WriteByte(',')
+	}
+	b.WriteString(strconv.Itoa(c.Couriers))
+	return b.String()
+}
+
+// with returns a copy of c with dimension i moved by delta. Dimensions are
+// the shelves in order, then the couriers.
+func (c Candidate) with(i, delta int) Candidate {
+	next := Candidate{Capacities: append([]int(nil), c.Capacities...), Couriers: c.Couriers}
+	if i < len(next.Capacities) {
+		next.Capacities[i] += delta
+	} else {
+		next.Couriers += delta
+	}
+	return next
+}
+
+// Costs prices a configuration
+type Costs struct {
+	Shelf   float64 // per unit of shelf capacity
+	Courier float64 // per courier
+	Budget  float64 // most a configuration may cost, 0 for no limit
+}
+
+// Of returns the cost of a candidate
+func (c Costs) Of(cand Candidate) float64 {
+	total := c.Courier * float64(cand.Couriers)
+	for _, capacity := range cand.Capacities {
+		total += c.Shelf * float64(capacity)
+	}
+	return total
+}
+
+func (c Costs) allows(cand Candidate) bool {
+	return c.Budget <= 0 || c.Of(cand) <= c.Budget
+}
+
+// Space bounds the candidates searched
+type Space struct {
+	MinCapacity, MaxCapacity int // of each shelf
+	MinCouriers, MaxCouriers int // equal to keep the count fixed
+	Step                     int // between the values tried
+}
+
+func (s Space) validate() error {
+	switch {
+	case s.Step <= 0:
+		return fmt.Errorf("step must be positive, got %d", s.Step)
+	case s.MinCapacity < 0 || s.MaxCapacity < s.MinCapacity:
+		return fmt.Errorf("invalid capacity range %d to %d", s.MinCapacity, s.MaxCapacity)
+	case s.MinCouriers < 0 || s.MaxCouriers < s.MinCouriers:
+		return fmt.Errorf("invalid courier range %d to %d", s.MinCouriers, s.MaxCouriers)
+	}
+	return nil
+}
+
+func (s Space) contains(c Candidate) bool {
+	for _, capacity := range c.Capacities {
+		if capacity < s.MinCapacity || capacity > s.MaxCapacity {
+			return false
+		}
+	}
+	return c.Couriers >= s.MinCouriers && c.Couriers <= s.MaxCouriers
+}
+
+// values returns the values tried between lo and hi
+func (s Space) values(lo, hi int) []int {
+	var values []int
+	for v := lo; v <= hi; v += s.Step {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Result is a candidate with its waste rate
+type Result struct {
+	Candidate
+	Waste float64 // percent of orders wasted or expired
+	Cost  float64
+}
+
+// Better reports whether r wastes less than other, or as little for less
+func (r Result) Better(other Result) bool {
+	if r.Waste != other.Waste {
+		return r.Waste < other.Waste
+	}
+	return r.Cost < other.Cost
+}
+
+// Evaluator returns the waste rate of a candidate. Searches with more than
+// one worker call it from several goroutines at once.
+type Evaluator func(Candidate) (float64, error)
+
+// search evaluates each candidate once, keeping the best within budget
+type search struct {
+	costs    Costs
+	evaluate Evaluator
+	workers  int // candidates evaluated at once
+	tried    map[string]Result
+	best     *Result
+}
+
+func newSearch(costs Costs, evaluate Evaluator, workers int) *search {
+	return &search{costs: costs, evaluate: evaluate, workers: max(workers, 1), tried: make(map[string]Result)}
+}
+
+// try evaluates a candidate, returning false if it is over budget
+func (s *search) try(c Candidate) (Result, bool, error) {
+	results, ok, err := s.tryAll([]Candidate{c})
+	if err != nil {
+		return Result{}, false, err
+	}
+	return results[0], ok[0], nil
+}
+
+// tryAll evaluates candidates on the search's workers, reporting for each
+// whether it is within budget. Results are kept in the order given, so
+// the best found does not depend on which evaluation finishes first.
+func (s *search) tryAll(cs []Candidate) ([]Result, []bool, error) {
+	var pending []Candidate
+	queued := make(map[string]bool)
+	for _, c := range cs {
+		if _, ok := s.tried[c.key()]; ok || queued[c.key()] || !s.costs.allows(c) {
+			continue
+		}
+		queued[c.key()] = true
+		pending = append(pending, c)
+	}
+
+	wastes := make([]float64, len(pending))
+	errs := make([]error, len(pending))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(s.workers, len(pending)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				wastes[i], errs[i] = s.evaluate(pending[i])
+			}
+		}()
+	}
+	for i := range pending {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for i, c := range pending {
+		if errs[i] != nil {
+			return nil, nil, errs[i]
+		}
+		r := Result{Candidate: c, Waste: wastes[i], Cost: s.costs.Of(c)}
+		s.tried[c.key()] = r
+		if s.best == nil || r.Better(*s.best) {
+			s.best = &r
+		}
+	}
+
+	results := make([]Result, len(cs))
+	ok := make([]bool, len(cs))
+	for i, c := range cs {
+		results[i], ok[i] = s.tried[c.key()]
+	}
+	return results, ok, nil
+}
+
+func (s *search) result() (Result, int, error) {
+	if s.best == nil {
+		return Result{}, len(s.tried), errors.New("no configuration in the search space fits the budget")
+	}
+	return *s.best, len(s.tried), nil
+}
+
+// Grid tries every candidate in the space for the given number of shelves,
+// evaluating as many at once as there are workers, and returns the best
+// with the number of candidates tried
+func Grid(space Space, shelves int, costs Costs, evaluate Evaluator, workers int) (Result, int, error) {
+	if err := space.validate(); err != nil {
+		return Result{}, 0, err
+	}
+	capacities := space.values(space.MinCapacity, space.MaxCapacity)
+	couriers := space.values(space.MinCouriers, space.MaxCouriers)
+	size := len(couriers)
+	for i := 0; i < shelves; i++ {
+		size *= len(capacities)
+		if size > maxGridSize {
+			return Result{}, 0, fmt.Errorf("the grid has over %d configurations; narrow the ranges or raise the step", maxGridSize)
+		}
+	}
+
+	var candidates []Candidate
+	index := make([]int, shelves)
+	for {
+		for _, n := range couriers {
+			c := Candidate{Capacities: make([]int, shelves), Couriers: n}
+			for i, j := range index {
+				c.Capacities[i] = capacities[j]
+			}
+			candidates = append(candidates, c)
+		}
+
+		// Advance the shelf capacities like an odometer
+		i := 0
+		for ; i < shelves; i++ {
+			index[i]++
+			if index[i] < len(capacities) {
+				break
+			}
+			index[i] = 0
+		}
+		if i == shelves {
+			break
+		}
+	}
+
+	s := newSearch(costs, evaluate, workers)
+	if _, _, err := s.tryAll(candidates); err != nil {
+		return Result{}, len(s.tried), err
+	}
+	return s.result()
+}
+
+// Climb starts from a candidate and repeatedly moves one step along the
+// dimension that cuts waste the most, until no step helps. The steps from
+// each candidate are evaluated as many at once as there are workers. It
+// returns the best candidate found with the number tried.
+func Climb(space Space, start Candidate, costs Costs, evaluate Evaluator, workers int) (Result, int, error) {
+	if err := space.validate(); err != nil {
+		return Result{}, 0, err
+	}
+	if !space.contains(start) {
+		return Result{}, 0, errors.New("the starting configuration is outside the search space")
+	}
+
+	s := newSearch(costs, evaluate, workers)
+	current, ok, err := s.try(start)
+	if err != nil {
+		return Result{}, 0, err
+	}
+	if !ok {
+		return Result{}, 0, errors.New("the starting configuration is over budget")
+	}
+
+	dimensions := len(start.Capacities) + 1
+	for step := 0; step < maxClimbSteps; step++ {
+		var steps []Candidate
+		for i := 0; i < dimensions; i++ {
+			for _, delta := range []int{-space.Step, space.Step} {
+				if c := current.with(i, delta); space.contains(c) {
+					steps = append(steps, c)
+				}
+			}
+		}
+		results, ok, err := s.tryAll(steps)
+		if err != nil {
+			return Result{}, len(s.tried), err
+		}
+		next := current
+		for i, r := range results {
+			if ok[i] && r.Better(next) {
+				next = r
+			}
+		}
+		if next.key() == current.key() {
+			break
+		}
+		current = next
+	}
+	return s.result()
+}
@@ -0,0 +1,112 @@
+package optimize_test
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/optimize"
+)
+
+// shortfall wastes 10% for each unit a shelf falls short of 3 and each
+// courier short of 2
+func shortfall(c optimize.Candidate) (float64, error) {
+	waste := 10 * float64(max(0, 2-c.Couriers))
+	for _, capacity := range c.Capacities {
+		waste += 10 * float64(max(0, 3-capacity))
+	}
+	return waste, nil
+}
+
+func TestCosts(t *testing.T) {
+	costs := optimize.Costs{Shelf: 1, Courier: 5}
+	assert.Equal(t, 17.0, costs.Of(optimize.Candidate{Capacities: []int{3, 4}, Couriers: 2}))
+}
+
+func TestGrid(t *testing.T) {
+	space := optimize.Space{MaxCapacity: 5, MinCouriers: 0, MaxCouriers: 3, Step: 1}
+	costs := optimize.Costs{Shelf: 1, Courier: 5}
+
+	best, tried, err := optimize.Grid(space, 2, costs, shortfall, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 6*6*4, tried)
+	assert.Equal(t, []int{3, 3}, best.Capacities)
+	assert.Equal(t, 2, best.Couriers)
+	assert.Zero(t, best.Waste)
+	assert.Equal(t, 16.0, best.Cost)
+
+	// Within a budget of 11 a courier goes short to fill the shelves
+	costs.Budget = 11
+	best, _, err = optimize.Grid(space, 2, costs, shortfall, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 3}, best.Capacities)
+	assert.Equal(t, 1, best.Couriers)
+	assert.Equal(t, 10.0, best.Waste)
+
+	one := optimize.Space{MaxCapacity: 1, MinCouriers: 1, MaxCouriers: 1, Step: 1}
+	_, _, err = optimize.Grid(one, 2, optimize.Costs{Courier: 5, Budget: 1}, shortfall, 1)
+	assert.ErrorContains(t, err, "fits the budget")
+
+	_, _, err = optimize.Grid(optimize.Space{MaxCapacity: 100, Step: 1}, 4, costs, shortfall, 1)
+	assert.ErrorContains(t, err, "narrow the ranges")
+}
+
+func TestSearch_Parallel(t *testing.T) {
+	space := optimize.Space{MaxCapacity: 5, MinCouriers: 0, MaxCouriers: 3, Step: 1}
+	costs := optimize.Costs{Shelf: 1, Courier: 5}
+	var calls atomic.Int32
+	counted := func(c optimize.Candidate) (float64, error) {
+		calls.Add(1)
+		return shortfall(c)
+	}
+
+	// Ties go to the same candidate however many are evaluated at once
+	serial, _, err := optimize.Grid(space, 2, costs, shortfall, 1)
+	require.NoError(t, err)
+	parallel, tried, err := optimize.Grid(space, 2, costs, counted, 8)
+	require.NoError(t, err)
+	assert.Equal(t, serial, parallel)
+	assert.Equal(t, int32(tried), calls.Load(), "each candidate is evaluated once")
+
+	start := optimize.Candidate{Capacities: []int{0, 5}, Couriers: 3}
+	serial, _, err = optimize.Climb(space, start, costs, shortfall, 1)
+	require.NoError(t, err)
+	parallel, _, err = optimize.Climb(space, start, costs, shortfall, 8)
+	require.NoError(t, err)
+	assert.Equal(t, serial, parallel)
+
+	failing := func(c optimize.Candidate) (float64, error) {
+		if c.Couriers == 2 {
+			return 0, errors.New("boom")
+		}
+		return 0, nil
+	}
+	_, _, err = optimize.Grid(space, 2, costs, failing, 8)
+	assert.ErrorContains(t, err, "boom")
+}
+
+func TestClimb(t *testing.T) {
+	space := optimize.Space{MaxCapacity: 10, MinCouriers: 1, MaxCouriers: 5, Step: 1}
+	costs := optimize.Costs{Shelf: 1, Courier: 5}
+
+	start := optimize.Candidate{Capacities: []int{0, 8}, Couriers: 5}
+	best, tried, err := optimize.Climb(space, start, costs, shortfall, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int{3, 3}, best.Capacities)
+	assert.Equal(t, 2, best.Couriers)
+	assert.Less(t, tried, 11*11*5)
+
+	_, _, err = optimize.Climb(space, optimize.Candidate{Capacities: []int{11, 0}, Couriers: 1}, costs, shortfall, 1)
+	assert.ErrorContains(t, err, "outside the search space")
+
+	costs.Budget = 10
+	_, _, err = optimize.Climb(space, start, costs, shortfall, 1)
+	assert.ErrorContains(t, err, "over budget")
+
+	failing := func(optimize.Candidate) (float64, error) { return 0, errors.New("boom") }
+	_, _, err = optimize.Climb(space, start, optimize.Costs{}, failing, 1)
+	assert.ErrorContains(t, err, "boom")
+}
@@ -0,0 +1,146 @@
+package optimize
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/history"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+)
+
+// Runner evaluates candidates by running the discrete engine on a base
+// config once per seed. Every candidate sees the same seeds, so their
+// differences come from the configuration rather than chance. Each run has
+// its own engine, seeded generators and simulated clock, and the engine
+// dispatches shelved orders in expiry order rather than map order, so a
+// seed always gives the same result. A Runner is safe for concurrent use
+// and its results do not depend on how many run at once.
+type Runner struct {
+	Base       *config.Config
+	OrdersFile string
+	Seeds      []uint64
+	Workers    int // seeds of a candidate run at once, one if not positive
+}
+
+// Shelves returns the names of the base config's shelves, in layout order
+func Shelves(cfg *config.Config) []string {
+	layout := simulator.ShelfLayout(cfg)
+	names := make([]string, len(layout))
+	for i, spec := range layout {
+		names[i] = string(spec.Type)
+	}
+	return names
+}
+
+// Start returns the candidate the config describes
+func Start(cfg *config.Config) Candidate {
+	layout := simulator.ShelfLayout(cfg)
+	c := Candidate{Capacities: make([]int, len(layout)), Couriers: cfg.Couriers.Count}
+	for i, spec := range layout {
+		c.Capacities[i] = spec.Capacity
+	}
+	return c
+}
+
+// Apply returns a copy of cfg with the candidate's shelf capacities and
+// courier count
+func Apply(cfg *config.Config, c Candidate) *config.Config {
+	applied := *cfg
+	applied.Couriers.Count = c.Couriers
+	if len(cfg.Shelves) > 0 {
+		applied.Shelves = slices.Clone(cfg.Shelves)
+		for i := range applied.Shelves {
+			applied.Shelves[i].Capacity = c.Capacities[i]
+		}
+		return &applied
+	}
+
+	// The classic shelves, in the order of shelf.DefaultLayout
+	capacities := map[shelf.ShelfType]*int{
+		shelf.HotShelf:      &applied.HotShelfCapacity,
+		shelf.ColdShelf:     &applied.ColdShelfCapacity,
+		shelf.FrozenShelf:   &applied.FrozenShelfCapacity,
+		shelf.OverflowShelf: &applied.OverflowCapacity,
+	}
+	for i, spec := range simulator.ShelfLayout(cfg) {
+		*capacities[spec.Type] = c.Capacities[i]
+	}
+	return &applied
+}
+
+// RunContext identifies the run of a candidate with seed, so the metrics
+// and events of each iteration of a sweep stay apart. The ID is the same
+// every time the iteration runs.
+func RunContext(run config.RunConfig, c Candidate, seed uint64) config.RunContext {
+	id := fmt.Sprintf("%s/seed-%d", c.key(), seed)
+	if run.Name != "" {
+		id = run.Name + "/" + id
+	}
+	return config.RunContext{ID: id, RunConfig: run}
+}
+
+// Evaluate returns the candidate's waste rate averaged over the seeds
+func (r Runner) Evaluate(c Candidate) (float64, error) {
+	if len(r.Seeds) == 0 {
+		return 0, errors.New("no seeds to run")
+	}
+
+	wastes := make([]float64, len(r.Seeds))
+	errs := make([]error, len(r.Seeds))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(max(r.Workers, 1), len(r.Seeds)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				wastes[i], errs[i] = r.run(c, r.Seeds[i])
+			}
+		}()
+	}
+	for i := range r.Seeds {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	// Summed in seed order, so the average is the same to the last bit
+	var total float64
+	for i, waste := range wastes {
+		if errs[i] != nil {
+			return 0, errs[i]
+		}
+		total += waste
+	}
+	return total / float64(len(r.Seeds)), nil
+}
+
+// run returns the candidate's waste rate in one run with seed
+func (r Runner) run(c Candidate, seed uint64) (float64, error) {
+	cfg := Apply(r.Base, c)
+	cfg.Engine = config.EngineDiscrete
+	cfg.Orders.Seed = seed
+	// Runs are only measured, and concurrent ones would share the files
+	cfg.HistoryFile = ""
+	cfg.ManifestFile = ""
+	cfg.EventLog.File = ""
+	cfg.TimeSeries.File = ""
+	cfg.Results.File = ""
+
+	e, err := simulator.NewDiscreteEngine(cfg, r.OrdersFile)
+	if err != nil {
+		return 0, err
+	}
+	e.Seed(seed)
+	e.SetRunContext(RunContext(cfg.Run, c, seed))
+	e.SetVerbose(false)
+	e.Run()
+	if err := e.Err(); err != nil {
+		return 0, fmt.Errorf("run with seed %d failed: %w", seed, err)
+	}
+	return history.NewSummary(e.ShelfManager).WasteRate(), nil
+}
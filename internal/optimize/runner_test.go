@@ -0,0 +1,99 @@
+package optimize_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/optimize"
+	"dish-dispatcher/internal/simulator"
+)
+
+func TestRunContext(t *testing.T) {
+	c := optimize.Candidate{Capacities: []int{2, 2, 2, 4}, Couriers: 3}
+	run := optimize.RunContext(config.RunConfig{Name: "sweep"}, c, 7)
+	assert.Equal(t, "sweep/2,2,2,4,3/seed-7", run.ID)
+	assert.Equal(t, "sweep", run.Name)
+
+	assert.Equal(t, "2,2,2,4,3/seed-8", optimize.RunContext(config.RunConfig{}, c, 8).ID)
+}
+
+func TestStartAndApply(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Couriers.Count = 4
+
+	assert.Equal(t, []string{"hot", "cold", "frozen", "overflow"}, optimize.Shelves(cfg))
+	start := optimize.Start(cfg)
+	assert.Equal(t, optimize.Candidate{Capacities: []int{20, 20, 20, 30}, Couriers: 4}, start)
+
+	applied := optimize.Apply(cfg, optimize.Candidate{Capacities: []int{1, 2, 3, 4}, Couriers: 5})
+	assert.Equal(t, []int{1, 2, 3, 4}, []int{applied.HotShelfCapacity, applied.ColdShelfCapacity, applied.FrozenShelfCapacity, applied.OverflowCapacity})
+	assert.Equal(t, 5, applied.Couriers.Count)
+	assert.Equal(t, 20, cfg.HotShelfCapacity, "the base config is left alone")
+
+	cfg.Shelves = []config.ShelfConfig{
+		{Name: "warm", Capacity: 5, Temps: []string{"hot"}},
+		{Name: "spare", Capacity: 2},
+	}
+	assert.Equal(t, []string{"warm", "spare"}, optimize.Shelves(cfg))
+	applied = optimize.Apply(cfg, optimize.Candidate{Capacities: []int{7, 8}})
+	assert.Equal(t, 7, applied.Shelves[0].Capacity)
+	assert.Equal(t, 8, applied.Shelves[1].Capacity)
+	assert.Equal(t, 5, cfg.Shelves[0].Capacity, "the base config is left alone")
+}
+
+func TestRunner_Evaluate(t *testing.T) {
+	orders := make([]simulator.OrderData, 10)
+	for i := range orders {
+		orders[i] = simulator.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	}
+	raw, err := json.Marshal(orders)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "orders.json")
+	require.NoError(t, os.WriteFile(path, raw, 0o644))
+
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 10
+	cfg.SimulationDuration = 0
+	runner := optimize.Runner{Base: cfg, OrdersFile: path, Seeds: []uint64{1, 2}}
+
+	waste, err := runner.Evaluate(optimize.Candidate{Capacities: []int{0, 0, 0, 0}})
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, waste)
+
+	waste, err = runner.Evaluate(optimize.Start(cfg))
+	require.NoError(t, err)
+	assert.Zero(t, waste)
+
+	_, err = optimize.Runner{Base: cfg, OrdersFile: path}.Evaluate(optimize.Start(cfg))
+	assert.Error(t, err)
+}
+
+func TestRunner_Workers(t *testing.T) {
+	orders := make([]simulator.OrderData, 40)
+	for i := range orders {
+		orders[i] = simulator.OrderData{Name: "Soup", Temp: "hot", ShelfLife: 20, DecayRate: 1}
+	}
+	raw, err := json.Marshal(orders)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "orders.json")
+	require.NoError(t, os.WriteFile(path, raw, 0o644))
+
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 4
+	cfg.SimulationDuration = 0
+	c := optimize.Candidate{Capacities: []int{2, 2, 2, 3}}
+	seeds := []uint64{1, 2, 3, 4, 5, 6}
+
+	serial, err := optimize.Runner{Base: cfg, OrdersFile: path, Seeds: seeds}.Evaluate(c)
+	require.NoError(t, err)
+	parallel, err := optimize.Runner{Base: cfg, OrdersFile: path, Seeds: seeds, Workers: 4}.Evaluate(c)
+	require.NoError(t, err)
+	assert.Positive(t, serial)
+	assert.Equal(t, serial, parallel, "runs are isolated, so running them at once changes nothing")
+}
@@ -0,0 +1,174 @@
+package order
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Names of the built-in decay formulas
+const (
+	ClassicFormulaName      = "classic"
+	CSSChallengeFormulaName = "css-challenge"
+)
+
+// DecayFormula computes how an order's value falls over time
+type DecayFormula interface {
+	// Name is the identifier used to select the formula in config
+	Name() string
+
+	// Value returns the order's remaining value in [0, 1] at now
+	Value(o *Order, now time.Time) float64
+
+	// ExpiresAt returns when Value reaches zero, or the zero time if it never does
+	ExpiresAt(o *Order) time.Time
+}
+
+var decayFormulas = map[string]DecayFormula{
+	ClassicFormulaName:      ClassicFormula{},
+	CSSChallengeFormulaName: CSSChallengeFormula{},
+}
+
+// LookupDecayFormula returns the built-in formula with the given name.
+// An empty name selects the classic formula.
+func LookupDecayFormula(name string) (DecayFormula, error) {
+	if name == "" {
+		return ClassicFormula{}, nil
+	}
+
+	formula, ok := decayFormulas[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown decay formula %q (available: %v)", name, DecayFormulaNames())
+	}
+	return formula, nil
+}
+
+// DecayFormulaNames lists the names of the built-in formulas
+func DecayFormulaNames() []string {
+	names := make([]string, 0, len(decayFormulas))
+	for name := range decayFormulas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expiryHorizon bounds the numeric search for an expiry. Orders that still
+// hold value this long after being shelved are treated as never expiring.
+const expiryHorizon = 24 * time.Hour
+
+// searchExpiry finds the first moment f's value reaches zero, assuming the
+// value never increases with age. It is used where no closed form exists.
+func searchExpiry(f DecayFormula, o *Order) time.Time {
+	start := o.PlacedOnShelfAt
+	if f.Value(o, start) <= 0 {
+		return start
+	}
+
+	// Grow the step until the value hits zero, then bisect down to a millisecond
+	low, high := time.Duration(0), time.Second
+	for f.Value(o, start.Add(high)) > 0 {
+		if high >= expiryHorizon {
+			return time.Time{}
+		}
+		low, high = high, high*2
+	}
+	for high-low > time.Millisecond {
+		mid := low + (high-low)/2
+		if f.Value(o, start.Add(mid)) > 0 {
+			low = mid
+		} else {
+			high = mid
+		}
+	}
+	return start.Add(high)
+}
+
+// afterSeconds returns the moment the given number of shelf seconds elapse
+func afterSeconds(o *Order, seconds float64) time.Time {
+	return o.PlacedOnShelfAt.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+// solvePhases returns when a quantity that grows at primarySlope per second
+// on the temperature shelf and overflowSlope per second on overflow reaches
+// target, or the zero time if it never does
+func solvePhases(o *Order, target, primarySlope, overflowSlope float64) time.Time {
+	// An order with no shelf life is worthless the moment it is shelved,
+	// however slowly it decays
+	if target <= 0 {
+		return o.PlacedOnShelfAt
+	}
+
+	if !o.PlacedOnOverflow.IsZero() {
+		primaryAge := o.PlacedOnOverflow.Sub(o.PlacedOnShelfAt).Seconds()
+		reached := primarySlope * primaryAge
+		if reached < target {
+			if overflowSlope <= 0 {
+				return time.Time{}
+			}
+			return afterSeconds(o, primaryAge+(target-reached)/overflowSlope)
+		}
+	}
+
+	if primarySlope <= 0 {
+		return time.Time{}
+	}
+	return afterSeconds(o, target/primarySlope)
+}
+
+// shelfModifier is the CSS challenge shelf modifier for the order's current shelf
+func shelfModifier(o *Order) float64 {
+	if o.PlacedOnOverflow.IsZero() {
+		return 1.0
+	}
+	return overflowDecayModifier
+}
+
+// ClassicFormula decays at the order's rate on every shelf:
+// (shelfLife - decayRate * age) / shelfLife
+//
+// Here and in CSSChallengeFormula, time spent outside the order's SafeBand
+// counts SpoilageMultiplier times towards age in the decay term.
+type ClassicFormula struct{}
+
+func (ClassicFormula) Name() string { return ClassicFormulaName }
+
+func (ClassicFormula) Value(o *Order, now time.Time) float64 {
+	return normalize(o, o.ShelfLife-o.DecayRate*o.spoilageAge(now))
+}
+
+func (ClassicFormula) ExpiresAt(o *Order) time.Time {
+	return solvePhases(o, o.ShelfLife,
+		o.DecayRate*o.primaryDecayFactor(),
+		o.DecayRate*o.overflowDecayFactor())
+}
+
+// CSSChallengeFormula also subtracts the order's age and doubles the decay
+// rate while the order sits on overflow:
+// (shelfLife - age - decayRate * age * shelfModifier) / shelfLife
+type CSSChallengeFormula struct{}
+
+// overflowDecayModifier is the shelfModifier applied on the overflow shelf
+const overflowDecayModifier = 2.0
+
+func (CSSChallengeFormula) Name() string { return CSSChallengeFormulaName }
+
+func (CSSChallengeFormula) Value(o *Order, now time.Time) float64 {
+	age := now.Sub(o.PlacedOnShelfAt).Seconds()
+	return normalize(o, o.ShelfLife-age-o.DecayRate*o.spoilageAge(now)*shelfModifier(o))
+}
+
+func (CSSChallengeFormula) ExpiresAt(o *Order) time.Time {
+	modifier := shelfModifier(o)
+	return solvePhases(o, o.ShelfLife,
+		1+o.DecayRate*modifier*o.primaryDecayFactor(),
+		1+o.DecayRate*modifier*o.overflowDecayFactor())
+}
+
+// normalize converts remaining shelf life into a value in [0, 1]
+func normalize(o *Order, remainingShelfLife float64) float64 {
+	if remainingShelfLife <= 0 {
+		return 0.0
+	}
+	return remainingShelfLife / o.ShelfLife
+}
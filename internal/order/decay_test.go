@@ -0,0 +1,74 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+)
+
+func TestLookupDecayFormula(t *testing.T) {
+	f, err := order.LookupDecayFormula("")
+	assert.NoError(t, err)
+	assert.Equal(t, order.ClassicFormulaName, f.Name())
+
+	f, err = order.LookupDecayFormula(order.CSSChallengeFormulaName)
+	assert.NoError(t, err)
+	assert.Equal(t, order.CSSChallengeFormulaName, f.Name())
+
+	_, err = order.LookupDecayFormula("bogus")
+	assert.Error(t, err)
+}
+
+func TestCSSChallengeFormula_Value(t *testing.T) {
+	o := order.NewOrder("Pizza", order.Hot, 300, 0.5)
+	o.Formula = order.CSSChallengeFormula{}
+	o.PlacedOnShelfAt = o.CreatedAt
+
+	value := o.CalculateValue(o.CreatedAt.Add(100 * time.Second))
+	assert.InDelta(t, (300-100-0.5*100)/300.0, value, 0.001)
+
+	o.PlacedOnOverflow = o.CreatedAt.Add(50 * time.Second)
+	value = o.CalculateValue(o.CreatedAt.Add(100 * time.Second))
+	assert.InDelta(t, (300-100-0.5*100*2)/300.0, value, 0.001)
+}
+
+func TestDecayFormula_ExpiresAtMatchesValue(t *testing.T) {
+	for _, name := range order.DecayFormulaNames() {
+		formula, err := order.LookupDecayFormula(name)
+		assert.NoError(t, err)
+
+		for _, overflow := range []bool{false, true} {
+			o := order.NewOrder("Soup", order.Hot, 120, 0.8)
+			o.Formula = formula
+			o.SafeBand = &order.SafeBand{MaxTemp: float64Ptr(10), SpoilageMultiplier: 1.5}
+			o.PlacedOnShelfAt = o.CreatedAt
+			if overflow {
+				o.PlacedOnOverflow = o.CreatedAt.Add(10 * time.Second)
+			}
+
+			expiresAt := o.ExpiresAt()
+			assert.Greater(t, o.CalculateValue(expiresAt.Add(-time.Millisecond)), 0.0, name)
+			assert.InDelta(t, 0.0, o.CalculateValue(expiresAt), 1e-6, name)
+		}
+	}
+}
+
+func TestDecayFormula_ExpiresAtWithNoShelfLife(t *testing.T) {
+	for _, name := range order.DecayFormulaNames() {
+		formula, err := order.LookupDecayFormula(name)
+		assert.NoError(t, err)
+
+		o := order.NewOrder("Air", order.Cold, 0, 0)
+		o.Formula = formula
+		o.PlacedOnShelfAt = o.CreatedAt
+
+		assert.Equal(t, o.PlacedOnShelfAt, o.ExpiresAt(), name)
+		assert.True(t, o.IsExpired(o.ExpiresAt()), name)
+
+		o.OpenDecayWindow(o.CreatedAt, 2)
+		assert.Equal(t, o.PlacedOnShelfAt, o.ExpiresAt(), name)
+	}
+}
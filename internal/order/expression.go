@@ -0,0 +1,388 @@
+package order
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ExpressionFormulaName is reported by Name for formulas built from an expression
+const ExpressionFormulaName = "expression"
+
+// ExpressionFormula evaluates a user-supplied arithmetic expression per order.
+// The result is clamped to [0, 1]. Available variables:
+//
+//	shelfLife   the order's shelf life in seconds
+//	decayRate   the order's decay rate
+//	age         seconds since the order was first shelved
+//	primaryAge  seconds spent on a temperature shelf
+//	overflowAge seconds spent on the overflow shelf
+//	modifier    1 on a temperature shelf, 2 on overflow
+//	spoilageAge age with time outside the order's SafeBand scaled by
+//	            its SpoilageMultiplier
+//
+// Expressions support + - * / ^, parentheses, and the functions
+// min, max, abs, sqrt, exp, log and pow.
+type ExpressionFormula struct {
+	source string
+	root   exprNode
+}
+
+// NewExpressionFormula parses expr and checks that it only uses known
+// variables and functions
+func NewExpressionFormula(expr string) (*ExpressionFormula, error) {
+	root, err := parseExpression(expr, exprVarNames)
+	if err != nil {
+		return nil, fmt.Errorf("invalid decay expression %q: %w", expr, err)
+	}
+	return &ExpressionFormula{source: expr, root: root}, nil
+}
+
+// parseExpression parses a whole expression using only the given variables
+func parseExpression(expr string, vars map[string]bool) (exprNode, error) {
+	p := &exprParser{input: expr, vars: vars}
+	p.next()
+
+	root, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.tok.text, p.tok.pos)
+	}
+	return root, nil
+}
+
+func (f *ExpressionFormula) Name() string { return ExpressionFormulaName }
+
+// String returns the source expression
+func (f *ExpressionFormula) String() string { return f.source }
+
+func (f *ExpressionFormula) Value(o *Order, now time.Time) float64 {
+	primaryAge, overflowAge := o.phaseAges(now)
+	vars := exprVars{
+		"shelfLife":   o.ShelfLife,
+		"decayRate":   o.DecayRate,
+		"age":         now.Sub(o.PlacedOnShelfAt).Seconds(),
+		"primaryAge":  primaryAge,
+		"overflowAge": overflowAge,
+		"modifier":    shelfModifier(o),
+		"spoilageAge": o.spoilageAge(now),
+	}
+
+	value := f.root.eval(vars)
+	if math.IsNaN(value) || value <= 0 {
+		return 0.0
+	}
+	return math.Min(value, 1.0)
+}
+
+// ExpiresAt searches numerically for the first moment the value reaches zero
+func (f *ExpressionFormula) ExpiresAt(o *Order) time.Time {
+	return searchExpiry(f, o)
+}
+
+// Expression is an arithmetic expression over a fixed set of variables, in
+// the language of decay expressions. Strategy scripts use it to score
+// candidates.
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// ParseExpression parses expr, allowing only the named variables
+func ParseExpression(expr string, vars ...string) (*Expression, error) {
+	allowed := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		allowed[v] = true
+	}
+	root, err := parseExpression(expr, allowed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, err)
+	}
+	return &Expression{source: expr, root: root}, nil
+}
+
+// String returns the source expression
+func (e *Expression) String() string { return e.source }
+
+// Eval evaluates the expression. Variables missing from vars are zero.
+func (e *Expression) Eval(vars map[string]float64) float64 {
+	return e.root.eval(vars)
+}
+
+type exprVars map[string]float64
+
+type exprNode interface {
+	eval(vars exprVars) float64
+}
+
+type numberNode float64
+
+func (n numberNode) eval(exprVars) float64 { return float64(n) }
+
+type varNode string
+
+func (n varNode) eval(vars exprVars) float64 { return vars[string(n)] }
+
+type unaryNode struct {
+	operand exprNode
+}
+
+func (n unaryNode) eval(vars exprVars) float64 { return -n.operand.eval(vars) }
+
+type binaryNode struct {
+	op          byte
+	left, right exprNode
+}
+
+func (n binaryNode) eval(vars exprVars) float64 {
+	l, r := n.left.eval(vars), n.right.eval(vars)
+	switch n.op {
+	case '+':
+		return l + r
+	case '-':
+		return l - r
+	case '*':
+		return l * r
+	case '/':
+		return l / r
+	default:
+		return math.Pow(l, r)
+	}
+}
+
+type callNode struct {
+	fn   exprFunc
+	args []exprNode
+}
+
+func (n callNode) eval(vars exprVars) float64 {
+	args := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		args[i] = arg.eval(vars)
+	}
+	return n.fn.call(args)
+}
+
+type exprFunc struct {
+	arity int
+	call  func(args []float64) float64
+}
+
+var exprFuncs = map[string]exprFunc{
+	"min":  {2, func(a []float64) float64 { return math.Min(a[0], a[1]) }},
+	"max":  {2, func(a []float64) float64 { return math.Max(a[0], a[1]) }},
+	"pow":  {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"abs":  {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"sqrt": {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"exp":  {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"log":  {1, func(a []float64) float64 { return math.Log(a[0]) }},
+}
+
+var exprVarNames = map[string]bool{
+	"shelfLife":   true,
+	"decayRate":   true,
+	"age":         true,
+	"primaryAge":  true,
+	"overflowAge": true,
+	"modifier":    true,
+	"spoilageAge": true,
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// exprParser is a recursive-descent parser over the grammar:
+//
+//	expr   = term { ("+" | "-") term }
+//	term   = unary { ("*" | "/") unary }
+//	unary  = "-" unary | power
+//	power  = atom [ "^" unary ]
+//	atom   = number | ident | ident "(" expr { "," expr } ")" | "(" expr ")"
+type exprParser struct {
+	input string
+	pos   int
+	tok   token
+	vars  map[string]bool // variables the expression may use
+}
+
+func (p *exprParser) next() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	start := p.pos
+	if p.pos >= len(p.input) {
+		p.tok = token{kind: tokEOF, pos: start}
+		return
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.input) && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = token{kind: tokNumber, text: p.input[start:p.pos], pos: start}
+	case unicode.IsLetter(rune(c)):
+		for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		p.tok = token{kind: tokIdent, text: p.input[start:p.pos], pos: start}
+	default:
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c), pos: start}
+	}
+}
+
+func (p *exprParser) isOp(ops string) bool {
+	return p.tok.kind == tokOp && strings.Contains(ops, p.tok.text)
+}
+
+func (p *exprParser) parseExpr() (exprNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+-") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseTerm() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*/") {
+		op := p.tok.text[0]
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.isOp("-") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{operand: operand}, nil
+	}
+	return p.parsePower()
+}
+
+func (p *exprParser) parsePower() (exprNode, error) {
+	base, err := p.parseAtom()
+	if err != nil {
+		return nil, err
+	}
+	if p.isOp("^") {
+		p.next()
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: '^', left: base, right: exponent}, nil
+	}
+	return base, nil
+}
+
+func (p *exprParser) parseAtom() (exprNode, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad number %q at offset %d", tok.text, tok.pos)
+		}
+		p.next()
+		return numberNode(value), nil
+
+	case tok.kind == tokIdent:
+		p.next()
+		if p.isOp("(") {
+			return p.parseCall(tok)
+		}
+		if !p.vars[tok.text] {
+			return nil, fmt.Errorf("unknown variable %q at offset %d", tok.text, tok.pos)
+		}
+		return varNode(tok.text), nil
+
+	case p.isOp("("):
+		p.next()
+		inner, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isOp(")") {
+			return nil, fmt.Errorf("missing ) at offset %d", p.tok.pos)
+		}
+		p.next()
+		return inner, nil
+
+	case tok.kind == tokEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", tok.text, tok.pos)
+	}
+}
+
+func (p *exprParser) parseCall(name token) (exprNode, error) {
+	fn, ok := exprFuncs[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q at offset %d", name.text, name.pos)
+	}
+
+	p.next() // consume "("
+	var args []exprNode
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	if !p.isOp(")") {
+		return nil, fmt.Errorf("missing ) at offset %d", p.tok.pos)
+	}
+	p.next()
+
+	if len(args) != fn.arity {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", name.text, fn.arity, len(args))
+	}
+	return callNode{fn: fn, args: args}, nil
+}
@@ -0,0 +1,73 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+)
+
+func TestExpressionFormula_MatchesClassic(t *testing.T) {
+	f, err := order.NewExpressionFormula("(shelfLife - decayRate * age) / shelfLife")
+	assert.NoError(t, err)
+
+	o := order.NewOrder("Pizza", order.Hot, 300, 0.5)
+	o.PlacedOnShelfAt = o.CreatedAt
+	now := o.CreatedAt.Add(100 * time.Second)
+
+	classic := o.CalculateValue(now)
+	o.Formula = f
+	assert.InDelta(t, classic, o.CalculateValue(now), 1e-9)
+	assert.WithinDuration(t, order.ClassicFormula{}.ExpiresAt(o), o.ExpiresAt(), 2*time.Millisecond)
+}
+
+func TestExpressionFormula_OverflowModifier(t *testing.T) {
+	f, err := order.NewExpressionFormula("(shelfLife - decayRate * age * modifier) / shelfLife")
+	assert.NoError(t, err)
+
+	o := order.NewOrder("Fries", order.Hot, 300, 0.5)
+	o.Formula = f
+	o.PlacedOnShelfAt = o.CreatedAt
+	o.PlacedOnOverflow = o.CreatedAt
+
+	value := o.CalculateValue(o.CreatedAt.Add(100 * time.Second))
+	assert.InDelta(t, (300-0.5*100*2)/300.0, value, 1e-9)
+}
+
+func TestExpressionFormula_Functions(t *testing.T) {
+	f, err := order.NewExpressionFormula("exp(-decayRate * age / 100) ^ 2 + min(0, -1) + 1")
+	assert.NoError(t, err)
+
+	o := order.NewOrder("Soup", order.Hot, 300, 1)
+	o.Formula = f
+	o.PlacedOnShelfAt = o.CreatedAt
+	assert.InDelta(t, 1.0, o.CalculateValue(o.CreatedAt), 1e-9)
+}
+
+func TestExpressionFormula_ClampsAndNeverExpires(t *testing.T) {
+	f, err := order.NewExpressionFormula("2")
+	assert.NoError(t, err)
+
+	o := order.NewOrder("Rock", order.Cold, 300, 1)
+	o.Formula = f
+	o.PlacedOnShelfAt = o.CreatedAt
+	assert.Equal(t, 1.0, o.CalculateValue(o.CreatedAt))
+	assert.True(t, o.ExpiresAt().IsZero())
+}
+
+func TestNewExpressionFormula_Errors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"shelfLife +",
+		"(age",
+		"unknownVar * 2",
+		"nope(age)",
+		"min(age)",
+		"age age",
+	} {
+		_, err := order.NewExpressionFormula(expr)
+		assert.Error(t, err, expr)
+	}
+}
@@ -3,6 +3,8 @@ package order
 import (
 	"fmt"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Temperature type for order temperature
@@ -24,17 +26,61 @@ type Order struct {
 	DecayRate float64
 	CreatedAt time.Time
 
+	// Size is the shelf space the order takes on volume-limited shelves;
+	// zero counts as one unit
+	Size float64
+
+	// Formula computes the order's value; nil means ClassicFormula
+	Formula DecayFormula
+
+	// Priority, Zone and Price describe the order for dispatch and reports;
+	// they do not change how it decays
+	Priority int
+	Zone     string
+	Price    float64
+
+	// Source names the order source that supplied the order, when a run
+	// reads several
+	Source string
+
+	// SafeBand optionally penalizes time on shelves outside a safe range
+	SafeBand *SafeBand
+
+	// DecayWindows records periods of accelerated decay, such as a shelf
+	// losing cooling while the order sat on it
+	DecayWindows []DecayWindow
+
+	// OnTransition, if set, is called after every lifecycle transition
+	OnTransition TransitionHook
+
+	// DiscardHistory skips recording the timeline of transitions, so
+	// History is empty, for runs that keep only aggregate stats
+	DiscardHistory bool
+
+	// PromisedAt is when the order was promised to reach its customer,
+	// estimated on placement; zero if no promise was made
+	PromisedAt time.Time
+
 	// Runtime tracking
 	PlacedOnShelfAt  time.Time
 	PlacedOnOverflow time.Time
 	CurrentShelfType string
-	WastedAt         time.Time
-	DeliveredAt      time.Time
+
+	// overflowSeconds is the time spent on overflow before the current
+	// stay, for orders moved off an overflow shelf
+	overflowSeconds float64
+
+	// state, stateAt and history are changed only by Transition
+	state   State
+	stateAt time.Time
+	history []StateChange
 }
 
+// NewOrder creates an order with a random UUID. The ID is deliberately not
+// derived from the name or clock, so identical orders never collide.
 func NewOrder(name string, temp Temperature, shelfLife float64, decayRate float64) *Order {
 	return &Order{
-		ID:        fmt.Sprintf("%s-%d", name, time.Now().UnixNano()),
+		ID:        uuid.NewString(),
 		Name:      name,
 		Temp:      temp,
 		ShelfLife: shelfLife,
@@ -43,65 +89,55 @@ func NewOrder(name string, temp Temperature, shelfLife float64, decayRate float6
 	}
 }
 
-// Value decay formula: (shelf_life - decay_rate * elapsedTime) / shelf_life
+// Volume returns the shelf space the order takes
+func (o *Order) Volume() float64 {
+	if o.Size <= 0 {
+		return 1
+	}
+	return o.Size
+}
+
+// CalculateValue returns the order's value in [0, 1] at now using its decay formula
 func (o *Order) CalculateValue(now time.Time) float64 {
 	// If the order hasn't been placed on a shelf yet, its value is 1.0
 	if o.PlacedOnShelfAt.IsZero() {
 		return 1.0
 	}
 
-	var elapsedTime float64
-	var decayAmount float64
-
-	if o.PlacedOnOverflow.IsZero() {
-		// Order is on a primary shelf
-		elapsedTime = now.Sub(o.PlacedOnShelfAt).Seconds()
-		decayAmount = o.DecayRate * elapsedTime
-	} else {
-		// Order is on the overflow shelf
-		elapsedTimePrimary := o.PlacedOnOverflow.Sub(o.PlacedOnShelfAt).Seconds()
-		decayAmountPrimary := o.DecayRate * elapsedTimePrimary
-
-		elapsedTimeOverflow := now.Sub(o.PlacedOnOverflow).Seconds()
-		decayAmountOverflow := o.DecayRate * elapsedTimeOverflow // Assuming the same decay rate on overflow
-
-		decayAmount = decayAmountPrimary + decayAmountOverflow
-	}
-
-	remainingShelfLife := o.ShelfLife - decayAmount
-
-	if remainingShelfLife <= 0 {
-		return 0.0
-	}
-
-	return remainingShelfLife / o.ShelfLife
+	return o.decayFormula().Value(o, now)
 }
 
-func (o *Order) CalculateValueV2(now time.Time) float64 {
-	// If the order hasn't been placed on a shelf yet, its value is 1.0
+// ExpiresAt returns the moment CalculateValue reaches zero, or the zero time
+// if the order is not yet shelved or does not decay
+func (o *Order) ExpiresAt() time.Time {
 	if o.PlacedOnShelfAt.IsZero() {
-		return 1.0
+		return time.Time{}
 	}
 
-	orderAge := now.Sub(o.PlacedOnShelfAt).Seconds()
-	shelfDecayModifier := 1.0 // Default for primary shelves
-
-	if !o.PlacedOnOverflow.IsZero() {
-		// Order is on overflow
-		overflowAge := now.Sub(o.PlacedOnOverflow).Seconds()
-		primaryAge := o.PlacedOnOverflow.Sub(o.PlacedOnShelfAt).Seconds()
-		orderAge = primaryAge + overflowAge
-		shelfDecayModifier = 2.0 // Overflow decay modifier
+	// Decay windows and earlier overflow stays break the closed forms, so
+	// fall back to a numeric search
+	if len(o.DecayWindows) > 0 || o.overflowSeconds > 0 {
+		return searchExpiry(o.decayFormula(), o)
 	}
+	return o.decayFormula().ExpiresAt(o)
+}
 
-	decayAmount := o.DecayRate * orderAge * shelfDecayModifier
-	remainingShelfLife := o.ShelfLife - orderAge - decayAmount
-
-	if remainingShelfLife <= 0 {
-		return 0.0
+// LeaveOverflow ends the order's stay on an overflow shelf at now, for an
+// order moved to another shelf. Its time on overflow so far still counts
+// towards its decay.
+func (o *Order) LeaveOverflow(now time.Time) {
+	if o.PlacedOnOverflow.IsZero() {
+		return
 	}
+	o.overflowSeconds += now.Sub(o.PlacedOnOverflow).Seconds()
+	o.PlacedOnOverflow = time.Time{}
+}
 
-	return remainingShelfLife / o.ShelfLife
+func (o *Order) decayFormula() DecayFormula {
+	if o.Formula == nil {
+		return ClassicFormula{}
+	}
+	return o.Formula
 }
 
 func (o *Order) IsExpired(now time.Time) bool {
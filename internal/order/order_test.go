@@ -20,6 +20,14 @@ func TestNewOrder(t *testing.T) {
 	assert.False(t, o.CreatedAt.IsZero())
 }
 
+func TestOrder_Volume(t *testing.T) {
+	o := order.NewOrder("Soda", order.Cold, 300, 0.5)
+	assert.Equal(t, 1.0, o.Volume())
+
+	o.Size = 0.25
+	assert.Equal(t, 0.25, o.Volume())
+}
+
 func TestCalculateValue(t *testing.T) {
 	o := order.NewOrder("Pizza", order.Hot, 300, 0.5)
 	testTime := o.CreatedAt.Add(100 * time.Second)
@@ -42,6 +50,24 @@ func TestCalculateValue_OverflowShelf(t *testing.T) {
 	assert.InDelta(t, expectedValue, value, 0.01)
 }
 
+func TestOrder_LeaveOverflow(t *testing.T) {
+	o := order.NewOrder("Ice Cream", order.Frozen, 300, 1.0)
+	o.SafeBand = &order.SafeBand{MaxTemp: float64Ptr(-10), SpoilageMultiplier: 3}
+	o.PlacedOnShelfAt = o.CreatedAt
+	o.PlacedOnOverflow = o.CreatedAt.Add(10 * time.Second)
+
+	o.LeaveOverflow(o.CreatedAt.Add(20 * time.Second))
+	assert.True(t, o.PlacedOnOverflow.IsZero())
+
+	// 20s on the freezer and 10s on overflow at three times the decay
+	assert.InDelta(t, (300-20-3*10)/300.0, o.CalculateValue(o.CreatedAt.Add(30*time.Second)), 0.001)
+	assert.WithinDuration(t, o.CreatedAt.Add(280*time.Second), o.ExpiresAt(), time.Millisecond)
+
+	// Leaving again without returning to overflow changes nothing
+	o.LeaveOverflow(o.CreatedAt.Add(40 * time.Second))
+	assert.InDelta(t, (300-20-3*10)/300.0, o.CalculateValue(o.CreatedAt.Add(30*time.Second)), 0.001)
+}
+
 func TestIsExpired(t *testing.T) {
 	o := order.NewOrder("Ice Cream", order.Frozen, 100, 1.0)
 	o.PlacedOnShelfAt = o.CreatedAt
@@ -55,3 +81,42 @@ func TestString(t *testing.T) {
 	assert.Contains(t, o.String(), "Salad")
 	assert.Contains(t, o.String(), "cold")
 }
+
+func TestNewOrder_UniqueIDs(t *testing.T) {
+	// Name+timestamp IDs used to collide for identical orders created in the
+	// same clock tick
+	seen := make(map[string]bool)
+	for i := 0; i < 10000; i++ {
+		o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+		assert.False(t, seen[o.ID], "duplicate order ID %s", o.ID)
+		seen[o.ID] = true
+		assert.NotContains(t, o.ID, o.Name)
+	}
+}
+
+func TestExpiresAt(t *testing.T) {
+	o := order.NewOrder("Soup", order.Hot, 100, 0.5)
+	assert.True(t, o.ExpiresAt().IsZero(), "unshelved orders have no expiry")
+
+	o.PlacedOnShelfAt = o.CreatedAt
+	expiresAt := o.ExpiresAt()
+	assert.Equal(t, o.CreatedAt.Add(200*time.Second), expiresAt)
+	assert.False(t, o.IsExpired(expiresAt.Add(-time.Second)))
+	assert.True(t, o.IsExpired(expiresAt))
+
+	o.DecayRate = 0
+	assert.True(t, o.ExpiresAt().IsZero(), "non-decaying orders never expire")
+}
+
+func TestDecayWindows(t *testing.T) {
+	o := order.NewOrder("Salad", order.Cold, 100, 1)
+	o.PlacedOnShelfAt = o.CreatedAt
+
+	// A 10s window at triple decay adds 20s of age
+	o.OpenDecayWindow(o.CreatedAt.Add(10*time.Second), 3)
+	o.CloseDecayWindows(o.CreatedAt.Add(20 * time.Second))
+
+	value := o.CalculateValue(o.CreatedAt.Add(30 * time.Second))
+	assert.InDelta(t, (100-30-20)/100.0, value, 1e-9)
+	assert.WithinDuration(t, o.CreatedAt.Add(80*time.Second), o.ExpiresAt(), time.Millisecond)
+}
@@ -0,0 +1,62 @@
+package order
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Pool recycles Order structs in runs that create millions of orders, to
+// cut allocation and GC pressure. Only put an order back once nothing can
+// reference it any more. A nil Pool allocates every order and drops the
+// ones put back.
+type Pool struct {
+	pool   sync.Pool
+	reused atomic.Int64
+}
+
+// NewPool creates an empty pool
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// Get returns an order as NewOrder would, reusing a released one if any
+func (p *Pool) Get(name string, temp Temperature, shelfLife float64, decayRate float64) *Order {
+	if p == nil {
+		return NewOrder(name, temp, shelfLife, decayRate)
+	}
+
+	o, ok := p.pool.Get().(*Order)
+	if !ok {
+		return NewOrder(name, temp, shelfLife, decayRate)
+	}
+	p.reused.Add(1)
+	*o = Order{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Temp:      temp,
+		ShelfLife: shelfLife,
+		DecayRate: decayRate,
+		CreatedAt: time.Now(),
+		history:   o.history[:0], // History hands out copies, so the array is free
+	}
+	return o
+}
+
+// Put releases a finished order for reuse
+func (p *Pool) Put(o *Order) {
+	if p == nil || o == nil {
+		return
+	}
+	p.pool.Put(o)
+}
+
+// Reused returns how many orders Get took from the pool
+func (p *Pool) Reused() int64 {
+	if p == nil {
+		return 0
+	}
+	return p.reused.Load()
+}
@@ -0,0 +1,44 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/order"
+)
+
+func TestPool_Reuse(t *testing.T) {
+	pool := order.NewPool()
+
+	first := pool.Get("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, first.Transition(order.StateShelved, time.Now()))
+	first.OpenDecayWindow(time.Now(), 2)
+	first.CurrentShelfType = "hot"
+	firstID := first.ID
+	pool.Put(first)
+
+	// sync.Pool may drop items at any time, so only check a reused order
+	// if one came back
+	second := pool.Get("Salad", order.Cold, 200, 0.2)
+	assert.NotEqual(t, firstID, second.ID)
+	assert.Equal(t, "Salad", second.Name)
+	assert.Equal(t, order.Cold, second.Temp)
+	assert.Equal(t, order.StateCreated, second.State())
+	assert.Empty(t, second.History())
+	assert.Empty(t, second.DecayWindows)
+	assert.Empty(t, second.CurrentShelfType)
+	assert.LessOrEqual(t, pool.Reused(), int64(1))
+}
+
+func TestPool_Nil(t *testing.T) {
+	var pool *order.Pool
+
+	o := pool.Get("Burger", order.Hot, 300, 0.5)
+	assert.Equal(t, "Burger", o.Name)
+	assert.NotEmpty(t, o.ID)
+	pool.Put(o)
+	assert.Zero(t, pool.Reused())
+}
@@ -0,0 +1,128 @@
+package order
+
+import (
+	"fmt"
+	"time"
+)
+
+// State is a stage of an order's lifecycle
+type State string
+
+// Lifecycle states. Orders start Created and end in one of the terminal
+// states: Delivered, Expired, Wasted or Cancelled.
+const (
+	StateCreated   State = "created"
+	StateShelved   State = "shelved"
+	StateInTransit State = "in_transit"
+	StateDelivered State = "delivered"
+	StateExpired   State = "expired"
+	StateWasted    State = "wasted"
+	StateCancelled State = "cancelled"
+)
+
+// transitions lists the states each state may move to. Shelved to Shelved
+// is a move between shelves, and Shelved to Wasted an order refused at
+// pickup. An order in transit expires if it spoils before handoff, and is
+// cancelled if it is still being carried when the run ends.
+var transitions = map[State][]State{
+	StateCreated:   {StateShelved, StateWasted, StateCancelled},
+	StateShelved:   {StateShelved, StateInTransit, StateExpired, StateWasted, StateCancelled},
+	StateInTransit: {StateDelivered, StateExpired, StateCancelled},
+}
+
+// Terminal reports whether no further transitions are possible from s
+func (s State) Terminal() bool {
+	return len(transitions[s]) == 0
+}
+
+// CanTransition reports whether an order may move from s to next
+func (s State) CanTransition(next State) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionError reports a transition the lifecycle does not allow
+type TransitionError struct {
+	OrderID  string
+	From, To State
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("order %s cannot move from %s to %s", e.OrderID, e.From, e.To)
+}
+
+// TransitionHook is called after every successful transition
+type TransitionHook func(o *Order, from, to State, at time.Time)
+
+// StateChange is one step in an order's timeline
+type StateChange struct {
+	From State     `json:"from"`
+	To   State     `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// State returns the order's current lifecycle state
+func (o *Order) State() State {
+	if o.state == "" {
+		return StateCreated
+	}
+	return o.state
+}
+
+// StateChangedAt returns when the order entered its current state, or the
+// zero time if it is still Created
+func (o *Order) StateChangedAt() time.Time {
+	return o.stateAt
+}
+
+// Transition moves the order to the next state at the given time, calling
+// OnTransition if it is set
+func (o *Order) Transition(next State, at time.Time) error {
+	from := o.State()
+	if !from.CanTransition(next) {
+		return &TransitionError{OrderID: o.ID, From: from, To: next}
+	}
+
+	o.state = next
+	o.stateAt = at
+	if !o.DiscardHistory {
+		o.history = append(o.history, StateChange{From: from, To: next, At: at})
+	}
+	if o.OnTransition != nil {
+		o.OnTransition(o, from, next, at)
+	}
+	return nil
+}
+
+// History returns a copy of the order's transitions, oldest first
+func (o *Order) History() []StateChange {
+	return append([]StateChange(nil), o.history...)
+}
+
+// DeliveredAt returns when the order was delivered, or the zero time if it
+// has not been
+func (o *Order) DeliveredAt() time.Time {
+	return o.timeIn(StateDelivered)
+}
+
+// ExpiredAt returns when the order expired, or the zero time if it has not
+func (o *Order) ExpiredAt() time.Time {
+	return o.timeIn(StateExpired)
+}
+
+// WastedAt returns when the order was wasted for lack of shelf space or
+// refused at pickup, or the zero time if it was not
+func (o *Order) WastedAt() time.Time {
+	return o.timeIn(StateWasted)
+}
+
+func (o *Order) timeIn(state State) time.Time {
+	if o.state != state {
+		return time.Time{}
+	}
+	return o.stateAt
+}
@@ -0,0 +1,121 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/order"
+)
+
+func TestOrder_InitialState(t *testing.T) {
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+
+	assert.Equal(t, order.StateCreated, o.State())
+	assert.True(t, o.StateChangedAt().IsZero())
+	assert.False(t, o.State().Terminal())
+}
+
+func TestOrder_DeliveryLifecycle(t *testing.T) {
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	start := time.Now()
+
+	require.NoError(t, o.Transition(order.StateShelved, start))
+	require.NoError(t, o.Transition(order.StateShelved, start.Add(time.Second)))
+	require.NoError(t, o.Transition(order.StateInTransit, start.Add(2*time.Second)))
+	require.NoError(t, o.Transition(order.StateDelivered, start.Add(3*time.Second)))
+
+	assert.Equal(t, order.StateDelivered, o.State())
+	assert.True(t, o.State().Terminal())
+	assert.Equal(t, start.Add(3*time.Second), o.DeliveredAt())
+	assert.True(t, o.ExpiredAt().IsZero())
+	assert.True(t, o.WastedAt().IsZero())
+
+	history := o.History()
+	require.Len(t, history, 4)
+	assert.Equal(t, order.StateChange{From: order.StateCreated, To: order.StateShelved, At: start}, history[0])
+	assert.Equal(t, order.StateDelivered, history[3].To)
+}
+
+func TestOrder_InvalidTransitions(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		path []order.State
+		next order.State
+	}{
+		{"deliver unshelved", nil, order.StateDelivered},
+		{"skip pickup", []order.State{order.StateShelved}, order.StateDelivered},
+		{"waste in transit", []order.State{order.StateShelved, order.StateInTransit}, order.StateWasted},
+		{"expire after waste", []order.State{order.StateWasted}, order.StateExpired},
+		{"deliver after expiry", []order.State{order.StateShelved, order.StateExpired}, order.StateInTransit},
+		{"reshelve in transit", []order.State{order.StateShelved, order.StateInTransit}, order.StateShelved},
+		{"revive cancelled", []order.State{order.StateCancelled}, order.StateShelved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+			for _, state := range tt.path {
+				require.NoError(t, o.Transition(state, now))
+			}
+			before := o.State()
+
+			err := o.Transition(tt.next, now.Add(time.Second))
+
+			var transitionErr *order.TransitionError
+			require.ErrorAs(t, err, &transitionErr)
+			assert.Equal(t, o.ID, transitionErr.OrderID)
+			assert.Equal(t, before, transitionErr.From)
+			assert.Equal(t, tt.next, transitionErr.To)
+			assert.Equal(t, before, o.State())
+		})
+	}
+}
+
+func TestOrder_TerminalTimestampsAreExclusive(t *testing.T) {
+	now := time.Now()
+	o := order.NewOrder("Salad", order.Cold, 300, 0.5)
+	require.NoError(t, o.Transition(order.StateShelved, now))
+	require.NoError(t, o.Transition(order.StateExpired, now.Add(time.Minute)))
+
+	assert.Error(t, o.Transition(order.StateWasted, now.Add(2*time.Minute)))
+	assert.Equal(t, now.Add(time.Minute), o.ExpiredAt())
+	assert.True(t, o.DeliveredAt().IsZero())
+	assert.True(t, o.WastedAt().IsZero())
+}
+
+func TestOrder_OnTransition(t *testing.T) {
+	type call struct{ from, to order.State }
+	var calls []call
+
+	o := order.NewOrder("Soup", order.Hot, 300, 0.5)
+	o.OnTransition = func(got *order.Order, from, to order.State, at time.Time) {
+		assert.Same(t, o, got)
+		calls = append(calls, call{from, to})
+	}
+
+	now := time.Now()
+	require.NoError(t, o.Transition(order.StateShelved, now))
+	assert.Error(t, o.Transition(order.StateDelivered, now))
+	require.NoError(t, o.Transition(order.StateCancelled, now))
+
+	assert.Equal(t, []call{
+		{order.StateCreated, order.StateShelved},
+		{order.StateShelved, order.StateCancelled},
+	}, calls)
+}
+
+func TestOrder_DiscardHistory(t *testing.T) {
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	o.DiscardHistory = true
+	now := time.Now()
+
+	require.NoError(t, o.Transition(order.StateShelved, now))
+	require.NoError(t, o.Transition(order.StateInTransit, now))
+	require.NoError(t, o.Transition(order.StateDelivered, now))
+	assert.Empty(t, o.History())
+	assert.Equal(t, now, o.DeliveredAt())
+}
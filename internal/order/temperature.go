@@ -0,0 +1,93 @@
+package order
+
+import "time"
+
+// Holding temperatures in °C of each shelf kind
+const (
+	HotHoldingTemp      = 60.0
+	ColdHoldingTemp     = 4.0
+	FrozenHoldingTemp   = -18.0
+	OverflowHoldingTemp = 20.0 // overflow sits at room temperature
+)
+
+// HoldingTemp returns the holding temperature of the temperature shelf
+// for t, and false for unknown temperatures
+func HoldingTemp(t Temperature) (float64, bool) {
+	switch t {
+	case Hot:
+		return HotHoldingTemp, true
+	case Cold:
+		return ColdHoldingTemp, true
+	case Frozen:
+		return FrozenHoldingTemp, true
+	default:
+		return 0, false
+	}
+}
+
+// SafeBand is an optional safe holding range for an order. While the order
+// sits on a shelf outside the band its decay rate is multiplied by
+// SpoilageMultiplier.
+type SafeBand struct {
+	MinTemp            *float64
+	MaxTemp            *float64
+	SpoilageMultiplier float64
+}
+
+// contains reports whether temp lies within the band; open ends are unbounded
+func (b *SafeBand) contains(temp float64) bool {
+	if b.MinTemp != nil && temp < *b.MinTemp {
+		return false
+	}
+	if b.MaxTemp != nil && temp > *b.MaxTemp {
+		return false
+	}
+	return true
+}
+
+// decayFactor returns the decay multiplier for a shelf at temp
+func (b *SafeBand) decayFactor(temp float64) float64 {
+	if b == nil || b.SpoilageMultiplier <= 0 || b.contains(temp) {
+		return 1.0
+	}
+	return b.SpoilageMultiplier
+}
+
+// primaryDecayFactor is the decay multiplier while on the temperature shelf
+func (o *Order) primaryDecayFactor() float64 {
+	temp, ok := HoldingTemp(o.Temp)
+	if !ok {
+		return 1.0
+	}
+	return o.SafeBand.decayFactor(temp)
+}
+
+// overflowDecayFactor is the decay multiplier while on the overflow shelf
+func (o *Order) overflowDecayFactor() float64 {
+	return o.SafeBand.decayFactor(OverflowHoldingTemp)
+}
+
+// phaseAges splits shelf time into seconds on the temperature shelf and on overflow
+func (o *Order) phaseAges(now time.Time) (primaryAge, overflowAge float64) {
+	if o.overflowSeconds == 0 {
+		if o.PlacedOnOverflow.IsZero() {
+			return now.Sub(o.PlacedOnShelfAt).Seconds(), 0
+		}
+		return o.PlacedOnOverflow.Sub(o.PlacedOnShelfAt).Seconds(), now.Sub(o.PlacedOnOverflow).Seconds()
+	}
+
+	// The order has been moved off overflow, so the phases interleave
+	overflowAge = o.overflowSeconds
+	if !o.PlacedOnOverflow.IsZero() {
+		overflowAge += now.Sub(o.PlacedOnOverflow).Seconds()
+	}
+	return now.Sub(o.PlacedOnShelfAt).Seconds() - overflowAge, overflowAge
+}
+
+// spoilageAge is shelf age with each phase scaled by its misplacement factor,
+// plus the extra age accrued during decay windows. Without a safe band or
+// decay windows it equals the plain shelf age.
+func (o *Order) spoilageAge(now time.Time) float64 {
+	primaryAge, overflowAge := o.phaseAges(now)
+	return primaryAge*o.primaryDecayFactor() + overflowAge*o.overflowDecayFactor() + o.windowAge(now)
+}
@@ -0,0 +1,55 @@
+package order_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+)
+
+func float64Ptr(v float64) *float64 { return &v }
+
+func TestSafeBand_OverflowPenalty(t *testing.T) {
+	// Ice cream is safe up to -10°C, so room-temperature overflow triples decay
+	o := order.NewOrder("Ice Cream", order.Frozen, 300, 1)
+	o.SafeBand = &order.SafeBand{MaxTemp: float64Ptr(-10), SpoilageMultiplier: 3}
+	o.PlacedOnShelfAt = o.CreatedAt
+	o.PlacedOnOverflow = o.CreatedAt.Add(50 * time.Second)
+
+	value := o.CalculateValue(o.CreatedAt.Add(100 * time.Second))
+	assert.InDelta(t, (300-50-50*3)/300.0, value, 1e-9)
+
+	// 50s on frozen plus (300-50)/3 seconds on overflow
+	overflowSeconds := 250.0 / 3
+	expected := o.CreatedAt.Add(50*time.Second + time.Duration(overflowSeconds*float64(time.Second)))
+	assert.WithinDuration(t, expected, o.ExpiresAt(), time.Millisecond)
+}
+
+func TestSafeBand_NoPenaltyInsideBand(t *testing.T) {
+	o := order.NewOrder("Soup", order.Hot, 300, 1)
+	o.SafeBand = &order.SafeBand{MinTemp: float64Ptr(55), SpoilageMultiplier: 4}
+	o.PlacedOnShelfAt = o.CreatedAt
+
+	value := o.CalculateValue(o.CreatedAt.Add(100 * time.Second))
+	assert.InDelta(t, (300-100)/300.0, value, 1e-9)
+}
+
+func TestSafeBand_ZeroMultiplierIsNeutral(t *testing.T) {
+	o := order.NewOrder("Salad", order.Cold, 300, 1)
+	o.SafeBand = &order.SafeBand{MinTemp: float64Ptr(100)}
+	o.PlacedOnShelfAt = o.CreatedAt
+
+	value := o.CalculateValue(o.CreatedAt.Add(100 * time.Second))
+	assert.InDelta(t, (300-100)/300.0, value, 1e-9)
+}
+
+func TestHoldingTemp(t *testing.T) {
+	temp, ok := order.HoldingTemp(order.Frozen)
+	assert.True(t, ok)
+	assert.Equal(t, order.FrozenHoldingTemp, temp)
+
+	_, ok = order.HoldingTemp(order.Temperature("lukewarm"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,44 @@
+package order
+
+import "time"
+
+// DecayWindow is a period during which the order decays Factor times faster.
+// A zero End means the window is still open.
+type DecayWindow struct {
+	Start  time.Time
+	End    time.Time
+	Factor float64
+}
+
+// OpenDecayWindow starts a period of accelerated decay at start
+func (o *Order) OpenDecayWindow(start time.Time, factor float64) {
+	o.DecayWindows = append(o.DecayWindows, DecayWindow{Start: start, Factor: factor})
+}
+
+// CloseDecayWindows ends every open decay window at end
+func (o *Order) CloseDecayWindows(end time.Time) {
+	for i := range o.DecayWindows {
+		if o.DecayWindows[i].End.IsZero() {
+			o.DecayWindows[i].End = end
+		}
+	}
+}
+
+// windowAge returns the extra seconds of age accrued in decay windows up to
+// now. Open windows are assumed to last until now.
+func (o *Order) windowAge(now time.Time) float64 {
+	extra := 0.0
+	for _, w := range o.DecayWindows {
+		start, end := w.Start, w.End
+		if start.Before(o.PlacedOnShelfAt) {
+			start = o.PlacedOnShelfAt
+		}
+		if end.IsZero() || end.After(now) {
+			end = now
+		}
+		if end.After(start) {
+			extra += (w.Factor - 1) * end.Sub(start).Seconds()
+		}
+	}
+	return extra
+}
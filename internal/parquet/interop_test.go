@@ -0,0 +1,130 @@
+package parquet_test
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/parquet"
+)
+
+// readWithPyArrow prints a Parquet file as pyarrow, the reference
+// implementation, reads it: its metadata, and each column's Arrow type and
+// values, timestamps as integer microseconds
+const readWithPyArrow = `
+import json, sys
+import pyarrow as pa
+import pyarrow.parquet as pq
+
+f = pq.ParquetFile(sys.argv[1])
+table = f.read()
+columns = {}
+for field, column in zip(table.schema, table.columns):
+    if pa.types.is_timestamp(field.type):
+        column = column.cast(pa.int64())
+    columns[field.name] = {"type": str(field.type), "nullable": field.nullable, "values": column.to_pylist()}
+json.dump({
+    "created_by": f.metadata.created_by,
+    "num_rows": f.metadata.num_rows,
+    "row_groups": f.metadata.num_row_groups,
+    "columns": columns,
+}, sys.stdout)
+`
+
+// pyArrowFile is what readWithPyArrow prints
+type pyArrowFile struct {
+	CreatedBy string `json:"created_by"`
+	NumRows   int    `json:"num_rows"`
+	RowGroups int    `json:"row_groups"`
+	Columns   map[string]struct {
+		Type     string `json:"type"`
+		Nullable bool   `json:"nullable"`
+		Values   []any  `json:"values"`
+	} `json:"columns"`
+}
+
+// pyArrow returns the Python interpreter of PARQUET_TEST_PYTHON, which
+// must have pyarrow installed, skipping the test if it is not set
+func pyArrow(t *testing.T) string {
+	t.Helper()
+
+	python := os.Getenv("PARQUET_TEST_PYTHON")
+	if python == "" {
+		t.Skip("PARQUET_TEST_PYTHON is not set; make interop sets it to a Python with pyarrow")
+	}
+	return python
+}
+
+func TestWriter_Interop(t *testing.T) {
+	python := pyArrow(t)
+
+	file := filepath.Join(t.TempDir(), "runs.parquet")
+	out, err := os.Create(file)
+	require.NoError(t, err)
+	w, err := parquet.NewWriter(out, testColumns)
+	require.NoError(t, err)
+	w.RowGroupRows = 2
+	w.CreatedBy = "dish-dispatcher test"
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 500_000, time.UTC)
+	require.NoError(t, w.Write([]any{"a", 1, 0.75, "north", at}))
+	require.NoError(t, w.Write([]any{"b", int64(2), -1.5, nil, nil}))
+	require.NoError(t, w.Write([]any{"ünïcode", 3, 0.5, "", at.Add(time.Second)}))
+	require.NoError(t, w.Close())
+	require.NoError(t, out.Close())
+
+	cmd := exec.Command(python, "-c", readWithPyArrow, file)
+	cmd.Stderr = os.Stderr
+	printed, err := cmd.Output()
+	require.NoError(t, err, "pyarrow could not read the file")
+	var got pyArrowFile
+	require.NoError(t, json.Unmarshal(printed, &got))
+
+	assert.Equal(t, "dish-dispatcher test", got.CreatedBy)
+	assert.Equal(t, 3, got.NumRows)
+	assert.Equal(t, 2, got.RowGroups)
+
+	want := map[string]struct {
+		typ      string
+		nullable bool
+		values   []any
+	}{
+		"id":           {"string", false, []any{"a", "b", "ünïcode"}},
+		"priority":     {"int64", false, []any{1.0, 2.0, 3.0}},
+		"value":        {"double", false, []any{0.75, -1.5, 0.5}},
+		"zone":         {"string", true, []any{"north", nil, ""}},
+		"delivered_at": {"timestamp[us, tz=UTC]", true, []any{float64(at.UnixMicro()), nil, float64(at.Add(time.Second).UnixMicro())}},
+	}
+	require.Len(t, got.Columns, len(want))
+	for name, w := range want {
+		c := got.Columns[name]
+		assert.Equal(t, w.typ, c.Type, name)
+		assert.Equal(t, w.nullable, c.Nullable, name)
+		assert.Equal(t, w.values, c.Values, name)
+	}
+}
+
+func TestWriter_InteropEmpty(t *testing.T) {
+	python := pyArrow(t)
+
+	file := filepath.Join(t.TempDir(), "empty.parquet")
+	out, err := os.Create(file)
+	require.NoError(t, err)
+	w, err := parquet.NewWriter(out, testColumns)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	require.NoError(t, out.Close())
+
+	printed, err := exec.Command(python, "-c", readWithPyArrow, file).Output()
+	require.NoError(t, err, "pyarrow could not read the file")
+	var got pyArrowFile
+	require.NoError(t, json.Unmarshal(printed, &got))
+	assert.Equal(t, 0, got.NumRows)
+	assert.Len(t, got.Columns, len(testColumns))
+}
@@ -0,0 +1,111 @@
+package parquet
+
+import "encoding/binary"
+
+// Parquet's metadata is serialized with Thrift's compact protocol. These
+// are the compact type codes of the fields written.
+const (
+	compactTrue   = 1
+	compactFalse  = 2
+	compactI32    = 5
+	compactI64    = 6
+	compactBinary = 8
+	compactList   = 9
+	compactStruct = 12
+)
+
+// compactWriter serializes Thrift structs in the compact protocol. Fields
+// must be written in increasing id order within each struct.
+type compactWriter struct {
+	buf    []byte
+	lastID int16
+	outer  []int16 // lastID of the enclosing structs
+}
+
+func (c *compactWriter) field(id int16, typ byte) {
+	if delta := id - c.lastID; delta > 0 && delta <= 15 {
+		c.buf = append(c.buf, byte(delta)<<4|typ)
+	} else {
+		c.buf = append(c.buf, typ)
+		c.varint(zigzag(int64(id)))
+	}
+	c.lastID = id
+}
+
+func (c *compactWriter) varint(v uint64) {
+	c.buf = binary.AppendUvarint(c.buf, v)
+}
+
+func zigzag(v int64) uint64 {
+	return uint64(v<<1) ^ uint64(v>>63)
+}
+
+func (c *compactWriter) i32(id int16, v int32) {
+	c.field(id, compactI32)
+	c.varint(zigzag(int64(v)))
+}
+
+func (c *compactWriter) i64(id int16, v int64) {
+	c.field(id, compactI64)
+	c.varint(zigzag(v))
+}
+
+func (c *compactWriter) bool(id int16, v bool) {
+	if v {
+		c.field(id, compactTrue)
+	} else {
+		c.field(id, compactFalse)
+	}
+}
+
+func (c *compactWriter) string(id int16, s string) {
+	c.field(id, compactBinary)
+	c.varint(uint64(len(s)))
+	c.buf = append(c.buf, s...)
+}
+
+// list starts a list field of n elements of type elem, which follow
+func (c *compactWriter) list(id int16, elem byte, n int) {
+	c.field(id, compactList)
+	if n < 15 {
+		c.buf = append(c.buf, byte(n)<<4|elem)
+	} else {
+		c.buf = append(c.buf, 0xf0|elem)
+		c.varint(uint64(n))
+	}
+}
+
+// begin starts a struct field, or a struct element of a list if id is 0.
+// Its fields follow, then end.
+func (c *compactWriter) begin(id int16) {
+	if id != 0 {
+		c.field(id, compactStruct)
+	}
+	c.outer = append(c.outer, c.lastID)
+	c.lastID = 0
+}
+
+// end closes the struct begun last, or the outermost struct if none was
+func (c *compactWriter) end() {
+	c.buf = append(c.buf, 0)
+	if len(c.outer) == 0 {
+		return
+	}
+	c.lastID = c.outer[len(c.outer)-1]
+	c.outer = c.outer[:len(c.outer)-1]
+}
+
+// listI32 writes the elements of a list of i32
+func (c *compactWriter) listI32(vs ...int32) {
+	for _, v := range vs {
+		c.varint(zigzag(int64(v)))
+	}
+}
+
+// listString writes the elements of a list of strings
+func (c *compactWriter) listString(ss ...string) {
+	for _, s := range ss {
+		c.varint(uint64(len(s)))
+		c.buf = append(c.buf, s...)
+	}
+}
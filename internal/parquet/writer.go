@@ -0,0 +1,368 @@
+// Package parquet is a minimal Apache Parquet writer. It writes flat tables
+// of required or optional columns, plain encoded and uncompressed, which is
+// all the dispatcher needs to export run results for columnar analysis, so
+// it avoids depending on a full Parquet library.
+package parquet
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// DefaultRowGroupRows is how many rows are buffered before they are written
+// as a row group, when Writer.RowGroupRows is not set
+const DefaultRowGroupRows = 64 * 1024
+
+// magic starts and ends every Parquet file
+const magic = "PAR1"
+
+// Type is the type of a column's values
+type Type int
+
+// Column types
+const (
+	Int64     Type = iota // int or int64
+	Double                // float64
+	String                // string, UTF-8
+	Timestamp             // time.Time, stored in microseconds since the epoch, UTC
+)
+
+// Column describes one column of the table
+type Column struct {
+	Name     string
+	Type     Type
+	Optional bool // values may be nil
+}
+
+// Physical types, repetitions, encodings and converted types of the Parquet
+// format, as numbered in its Thrift definitions
+const (
+	physicalInt64     = 2
+	physicalDouble    = 5
+	physicalByteArray = 6
+
+	repetitionRequired = 0
+	repetitionOptional = 1
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	convertedUTF8            = 0
+	convertedTimestampMicros = 10
+
+	pageData = 0
+)
+
+func (t Type) physical() int32 {
+	switch t {
+	case Double:
+		return physicalDouble
+	case String:
+		return physicalByteArray
+	}
+	return physicalInt64
+}
+
+// columnChunk buffers the values of one column for the current row group
+type columnChunk struct {
+	values  []byte // plain encoded, nulls left out
+	defined []bool // whether each row has a value, for optional columns
+}
+
+// rowGroup is where a written row group's column chunks are in the file
+type rowGroup struct {
+	rows    int64
+	size    int64
+	offsets []int64 // of each column chunk's page
+	sizes   []int64 // of each column chunk, page header included
+}
+
+// Writer writes rows to a Parquet file. Rows are buffered and written a row
+// group at a time; Close writes the last of them and the file's footer. A
+// Writer is not safe for concurrent use.
+type Writer struct {
+	// RowGroupRows is how many rows are buffered before they are written,
+	// DefaultRowGroupRows if not positive
+	RowGroupRows int
+	// CreatedBy names the application writing the file in its metadata
+	CreatedBy string
+
+	w       io.Writer
+	offset  int64
+	columns []Column
+	chunks  []columnChunk
+	rows    int // buffered
+	groups  []rowGroup
+	err     error // the first write to fail, returned from then on
+}
+
+// NewWriter starts a Parquet file of the given columns on w
+func NewWriter(w io.Writer, columns []Column) (*Writer, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("parquet: no columns")
+	}
+	seen := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		if c.Name == "" || seen[c.Name] {
+			return nil, fmt.Errorf("parquet: column name %q is empty or repeated", c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	pw := &Writer{w: w, columns: columns, chunks: make([]columnChunk, len(columns))}
+	pw.write([]byte(magic))
+	if pw.err != nil {
+		return nil, pw.err
+	}
+	return pw, nil
+}
+
+// Write buffers a row, one value per column in order, writing the row group
+// once it is full. Optional columns take nil for null.
+func (pw *Writer) Write(row []any) error {
+	if pw.err != nil {
+		return pw.err
+	}
+	if len(row) != len(pw.columns) {
+		return fmt.Errorf("parquet: row has %d values, want %d", len(row), len(pw.columns))
+	}
+	// Checked before anything is buffered, so a bad row leaves no trace
+	for i, v := range row {
+		if err := pw.columns[i].check(v); err != nil {
+			return err
+		}
+	}
+
+	for i, v := range row {
+		c := &pw.chunks[i]
+		if pw.columns[i].Optional {
+			c.defined = append(c.defined, v != nil)
+		}
+		if v != nil {
+			c.values = appendPlain(c.values, v)
+		}
+	}
+	pw.rows++
+
+	limit := pw.RowGroupRows
+	if limit <= 0 {
+		limit = DefaultRowGroupRows
+	}
+	if pw.rows >= limit {
+		return pw.Flush()
+	}
+	return nil
+}
+
+// check reports whether v is a value of the column
+func (c Column) check(v any) error {
+	var ok bool
+	switch v.(type) {
+	case nil:
+		ok = c.Optional
+	case int, int64:
+		ok = c.Type == Int64
+	case float64:
+		ok = c.Type == Double
+	case string:
+		ok = c.Type == String
+	case time.Time:
+		ok = c.Type == Timestamp
+	}
+	if !ok {
+		return fmt.Errorf("parquet: column %s cannot hold %T", c.Name, v)
+	}
+	return nil
+}
+
+// appendPlain appends a value in the plain encoding
+func appendPlain(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case int:
+		return binary.LittleEndian.AppendUint64(b, uint64(v))
+	case int64:
+		return binary.LittleEndian.AppendUint64(b, uint64(v))
+	case float64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	case string:
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+		return append(b, v...)
+	case time.Time:
+		return binary.LittleEndian.AppendUint64(b, uint64(v.UnixMicro()))
+	}
+	return b
+}
+
+// Flush writes the buffered rows as a row group
+func (pw *Writer) Flush() error {
+	if pw.err != nil || pw.rows == 0 {
+		return pw.err
+	}
+
+	g := rowGroup{rows: int64(pw.rows)}
+	for i := range pw.chunks {
+		c := &pw.chunks[i]
+		page := c.values
+		if pw.columns[i].Optional {
+			page = append(definitionLevels(c.defined), c.values...)
+		}
+
+		var h compactWriter
+		h.i32(1, pageData)
+		h.i32(2, int32(len(page)))
+		h.i32(3, int32(len(page)))
+		h.begin(5)
+		h.i32(1, int32(pw.rows))
+		h.i32(2, encodingPlain)
+		h.i32(3, encodingRLE)
+		h.i32(4, encodingRLE)
+		h.end()
+		h.end()
+
+		g.offsets = append(g.offsets, pw.offset)
+		pw.write(h.buf)
+		pw.write(page)
+		size := int64(len(h.buf) + len(page))
+		g.sizes = append(g.sizes, size)
+		g.size += size
+
+		c.values = c.values[:0]
+		c.defined = c.defined[:0]
+	}
+	pw.rows = 0
+	pw.groups = append(pw.groups, g)
+	return pw.err
+}
+
+// definitionLevels encodes whether each row has a value as definition
+// levels: a 4-byte length, then runs of the RLE/bit-packed hybrid encoding
+// at a bit width of 1
+func definitionLevels(defined []bool) []byte {
+	runs := make([]byte, 0, 8)
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		runs = binary.AppendUvarint(runs, uint64(j-i)<<1)
+		if defined[i] {
+			runs = append(runs, 1)
+		} else {
+			runs = append(runs, 0)
+		}
+		i = j
+	}
+	b := binary.LittleEndian.AppendUint32(make([]byte, 0, 4+len(runs)), uint32(len(runs)))
+	return append(b, runs...)
+}
+
+// Close writes the buffered rows and the file's footer. It does not close
+// the underlying writer.
+func (pw *Writer) Close() error {
+	if err := pw.Flush(); err != nil {
+		return err
+	}
+
+	footer := pw.footer()
+	pw.write(footer)
+	pw.write(binary.LittleEndian.AppendUint32(nil, uint32(len(footer))))
+	pw.write([]byte(magic))
+	return pw.err
+}
+
+// footer returns the file's metadata: its schema and where each row group's
+// column chunks are
+func (pw *Writer) footer() []byte {
+	var m compactWriter
+	m.i32(1, 1) // version
+
+	m.list(2, compactStruct, len(pw.columns)+1)
+	m.begin(0)
+	m.string(4, "schema")
+	m.i32(5, int32(len(pw.columns)))
+	m.end()
+	for _, c := range pw.columns {
+		m.begin(0)
+		m.i32(1, c.Type.physical())
+		if c.Optional {
+			m.i32(3, repetitionOptional)
+		} else {
+			m.i32(3, repetitionRequired)
+		}
+		m.string(4, c.Name)
+		switch c.Type {
+		case String:
+			m.i32(6, convertedUTF8)
+			m.begin(10) // logical type: string
+			m.begin(1)
+			m.end()
+			m.end()
+		case Timestamp:
+			m.i32(6, convertedTimestampMicros)
+			m.begin(10) // logical type: timestamp, adjusted to UTC, in microseconds
+			m.begin(8)
+			m.bool(1, true)
+			m.begin(2)
+			m.begin(2)
+			m.end()
+			m.end()
+			m.end()
+			m.end()
+		}
+		m.end()
+	}
+
+	var rows int64
+	for _, g := range pw.groups {
+		rows += g.rows
+	}
+	m.i64(3, rows)
+
+	m.list(4, compactStruct, len(pw.groups))
+	for _, g := range pw.groups {
+		m.begin(0)
+		m.list(1, compactStruct, len(pw.columns))
+		for i, c := range pw.columns {
+			m.begin(0)
+			m.i64(2, g.offsets[i])
+			m.begin(3)
+			m.i32(1, c.Type.physical())
+			m.list(2, compactI32, 2)
+			m.listI32(encodingPlain, encodingRLE)
+			m.list(3, compactBinary, 1)
+			m.listString(c.Name)
+			m.i32(4, 0) // uncompressed
+			m.i64(5, g.rows)
+			m.i64(6, g.sizes[i])
+			m.i64(7, g.sizes[i])
+			m.i64(9, g.offsets[i])
+			m.end()
+			m.end()
+		}
+		m.i64(2, g.size)
+		m.i64(3, g.rows)
+		m.end()
+	}
+
+	if pw.CreatedBy != "" {
+		m.string(6, pw.CreatedBy)
+	}
+	m.end()
+	return m.buf
+}
+
+// write writes b to the file, keeping the first error
+func (pw *Writer) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.offset += int64(n)
+	if err != nil {
+		pw.err = err
+	}
+}
@@ -0,0 +1,241 @@
+package parquet_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/parquet"
+)
+
+// compactReader decodes Thrift compact structs into maps of field id to
+// value: int64, bool, []byte, []any or map[int16]any
+type compactReader struct {
+	t   *testing.T
+	buf []byte
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.buf)
+	require.Positive(r.t, n)
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *compactReader) zigzag() int64 {
+	v := r.uvarint()
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case 1, 2:
+		return typ == 1
+	case 5, 6:
+		return r.zigzag()
+	case 8:
+		n := r.uvarint()
+		b := r.buf[:n]
+		r.buf = r.buf[n:]
+		return b
+	case 9:
+		header := r.buf[0]
+		r.buf = r.buf[1:]
+		n, elem := int(header>>4), header&0x0f
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			if elem == 1 {
+				list[i] = r.buf[0] == 1
+				r.buf = r.buf[1:]
+				continue
+			}
+			list[i] = r.value(elem)
+		}
+		return list
+	case 12:
+		return r.readStruct()
+	}
+	r.t.Fatalf("unexpected compact type %d", typ)
+	return nil
+}
+
+func (r *compactReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var id int16
+	for {
+		header := r.buf[0]
+		r.buf = r.buf[1:]
+		if header == 0 {
+			return fields
+		}
+		if delta := int16(header >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.zigzag())
+		}
+		fields[id] = r.value(header & 0x0f)
+	}
+}
+
+// readFile decodes a Parquet file's footer and the values of each column,
+// nil where null
+func readFile(t *testing.T, file []byte) (map[int16]any, map[string][]any) {
+	t.Helper()
+
+	require.Equal(t, "PAR1", string(file[:4]))
+	require.Equal(t, "PAR1", string(file[len(file)-4:]))
+	size := binary.LittleEndian.Uint32(file[len(file)-8:])
+	footer := (&compactReader{t: t, buf: file[len(file)-8-int(size) : len(file)-8]}).readStruct()
+
+	schema := footer[2].([]any)[1:]
+	columns := make(map[string][]any)
+	for _, group := range footer[4].([]any) {
+		for i, chunk := range group.(map[int16]any)[1].([]any) {
+			element := schema[i].(map[int16]any)
+			name := string(element[4].([]byte))
+			meta := chunk.(map[int16]any)[3].(map[int16]any)
+
+			r := &compactReader{t: t, buf: file[meta[9].(int64):]}
+			header := r.readStruct()
+			rows := int(header[5].(map[int16]any)[1].(int64))
+			page := r.buf[:header[3].(int64)]
+
+			defined := make([]bool, rows)
+			if element[3].(int64) == 1 {
+				levels := &compactReader{t: t, buf: page[4 : 4+binary.LittleEndian.Uint32(page)]}
+				page = page[4+len(levels.buf):]
+				for at := 0; len(levels.buf) > 0; {
+					run := int(levels.uvarint() >> 1)
+					for range run {
+						defined[at] = levels.buf[0] == 1
+						at++
+					}
+					levels.buf = levels.buf[1:]
+				}
+			} else {
+				for j := range defined {
+					defined[j] = true
+				}
+			}
+
+			for _, ok := range defined {
+				if !ok {
+					columns[name] = append(columns[name], nil)
+					continue
+				}
+				var v any
+				switch element[1].(int64) {
+				case 2:
+					v = int64(binary.LittleEndian.Uint64(page))
+					page = page[8:]
+				case 5:
+					v = math.Float64frombits(binary.LittleEndian.Uint64(page))
+					page = page[8:]
+				case 6:
+					n := binary.LittleEndian.Uint32(page)
+					v = string(page[4 : 4+n])
+					page = page[4+n:]
+				}
+				columns[name] = append(columns[name], v)
+			}
+			assert.Empty(t, page, "column %s has trailing bytes", name)
+		}
+	}
+	return footer, columns
+}
+
+var testColumns = []parquet.Column{
+	{Name: "id", Type: parquet.String},
+	{Name: "priority", Type: parquet.Int64},
+	{Name: "value", Type: parquet.Double},
+	{Name: "zone", Type: parquet.String, Optional: true},
+	{Name: "delivered_at", Type: parquet.Timestamp, Optional: true},
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, testColumns)
+	require.NoError(t, err)
+	w.RowGroupRows = 2
+	w.CreatedBy = "dish-dispatcher test"
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 500_000, time.UTC)
+	require.NoError(t, w.Write([]any{"a", 1, 0.75, "north", at}))
+	require.NoError(t, w.Write([]any{"b", int64(2), 0.0, nil, nil}))
+	require.NoError(t, w.Write([]any{"c", 3, 0.5, nil, at.Add(time.Second)}))
+	require.NoError(t, w.Close())
+
+	footer, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(3), footer[3])
+	assert.Len(t, footer[4], 2, "row groups")
+	assert.Equal(t, "dish-dispatcher test", string(footer[6].([]byte)))
+
+	assert.Equal(t, []any{"a", "b", "c"}, columns["id"])
+	assert.Equal(t, []any{int64(1), int64(2), int64(3)}, columns["priority"])
+	assert.Equal(t, []any{0.75, 0.0, 0.5}, columns["value"])
+	assert.Equal(t, []any{"north", nil, nil}, columns["zone"])
+	assert.Equal(t, []any{at.UnixMicro(), nil, at.Add(time.Second).UnixMicro()}, columns["delivered_at"])
+
+	// Timestamps are marked as UTC microseconds for readers
+	schema := footer[2].([]any)
+	timestamp := schema[5].(map[int16]any)
+	assert.Equal(t, int64(10), timestamp[6])
+	assert.Equal(t, true, timestamp[10].(map[int16]any)[8].(map[int16]any)[1])
+}
+
+func TestWriter_ManyRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, testColumns[:2])
+	require.NoError(t, err)
+
+	// More values than fit a short list header
+	for i := range 100 {
+		require.NoError(t, w.Write([]any{"x", i}))
+	}
+	require.NoError(t, w.Close())
+
+	footer, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(100), footer[3])
+	assert.Len(t, columns["priority"], 100)
+	assert.Equal(t, int64(99), columns["priority"][99])
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, testColumns)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	footer, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(0), footer[3])
+	assert.Empty(t, columns)
+}
+
+func TestWriter_RejectsBadRows(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := parquet.NewWriter(&buf, testColumns)
+	require.NoError(t, err)
+
+	assert.Error(t, w.Write([]any{"a", 1, 0.5}), "too few values")
+	assert.Error(t, w.Write([]any{nil, 1, 0.5, nil, nil}), "null in a required column")
+	assert.Error(t, w.Write([]any{"a", "1", 0.5, nil, nil}), "wrong type")
+	require.NoError(t, w.Close())
+
+	footer, _ := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(0), footer[3])
+}
+
+func TestNewWriter_InvalidColumns(t *testing.T) {
+	_, err := parquet.NewWriter(&bytes.Buffer{}, nil)
+	assert.Error(t, err)
+	_, err = parquet.NewWriter(&bytes.Buffer{}, []parquet.Column{{Name: "a"}, {Name: "a"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,257 @@
+package redisshelf_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis is an in-process RESP server implementing the handful of
+// commands the manager uses, so tests run without a Redis install
+type fakeRedis struct {
+	listener net.Listener
+
+	mutex   sync.Mutex
+	strs    map[string]string
+	sets    map[string]map[string]bool
+	hashes  map[string]map[string]string
+	expires map[string]time.Time
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	f := &fakeRedis{
+		listener: listener,
+		strs:     make(map[string]string),
+		sets:     make(map[string]map[string]bool),
+		hashes:   make(map[string]map[string]string),
+		expires:  make(map[string]time.Time),
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *fakeRedis) Addr() string {
+	return f.listener.Addr().String()
+}
+
+// expire makes a key lapse immediately, as if its TTL had run out
+func (f *fakeRedis) expire(key string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.strs, key)
+	delete(f.expires, key)
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		f.mutex.Lock()
+		reply := f.exec(args)
+		f.mutex.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func (f *fakeRedis) live(key string) bool {
+	if at, ok := f.expires[key]; ok && !time.Now().Before(at) {
+		delete(f.strs, key)
+		delete(f.expires, key)
+	}
+	_, ok := f.strs[key]
+	return ok
+}
+
+func (f *fakeRedis) exec(args []string) string {
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "GET":
+		if !f.live(args[1]) {
+			return "$-1\r\n"
+		}
+		return bulk(f.strs[args[1]])
+	case "SET":
+		f.strs[args[1]] = args[2]
+		delete(f.expires, args[1])
+		if len(args) == 5 && strings.ToUpper(args[3]) == "PX" {
+			ms, _ := strconv.Atoi(args[4])
+			f.expires[args[1]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "+OK\r\n"
+	case "DEL":
+		deleted := 0
+		for _, key := range args[1:] {
+			if f.live(key) || f.sets[key] != nil || f.hashes[key] != nil {
+				deleted++
+			}
+			delete(f.strs, key)
+			delete(f.sets, key)
+			delete(f.hashes, key)
+			delete(f.expires, key)
+		}
+		return integer(deleted)
+	case "EXISTS":
+		if f.live(args[1]) {
+			return integer(1)
+		}
+		return integer(0)
+	case "INCR", "DECR":
+		n, _ := strconv.Atoi(f.strs[args[1]])
+		if strings.ToUpper(args[0]) == "INCR" {
+			n++
+		} else {
+			n--
+		}
+		f.strs[args[1]] = strconv.Itoa(n)
+		return integer(n)
+	case "SADD":
+		set := f.sets[args[1]]
+		if set == nil {
+			set = make(map[string]bool)
+			f.sets[args[1]] = set
+		}
+		added := 0
+		for _, member := range args[2:] {
+			if !set[member] {
+				set[member] = true
+				added++
+			}
+		}
+		return integer(added)
+	case "SREM":
+		removed := 0
+		for _, member := range args[2:] {
+			if f.sets[args[1]][member] {
+				delete(f.sets[args[1]], member)
+				removed++
+			}
+		}
+		return integer(removed)
+	case "SMEMBERS":
+		members := make([]string, 0, len(f.sets[args[1]]))
+		for member := range f.sets[args[1]] {
+			members = append(members, member)
+		}
+		return array(members)
+	case "SCARD":
+		return integer(len(f.sets[args[1]]))
+	case "HGET":
+		value, ok := f.hashes[args[1]][args[2]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(value)
+	case "HSET":
+		f.hashField(args[1])[args[2]] = args[3]
+		return integer(1)
+	case "HDEL":
+		delete(f.hashes[args[1]], args[2])
+		return integer(1)
+	case "HINCRBY":
+		h := f.hashField(args[1])
+		n, _ := strconv.Atoi(h[args[2]])
+		delta, _ := strconv.Atoi(args[3])
+		h[args[2]] = strconv.Itoa(n + delta)
+		return integer(n + delta)
+	case "HINCRBYFLOAT":
+		h := f.hashField(args[1])
+		n, _ := strconv.ParseFloat(h[args[2]], 64)
+		delta, _ := strconv.ParseFloat(args[3], 64)
+		h[args[2]] = strconv.FormatFloat(n+delta, 'f', -1, 64)
+		return bulk(h[args[2]])
+	case "HGETALL":
+		fields := make([]string, 0, 2*len(f.hashes[args[1]]))
+		for k, v := range f.hashes[args[1]] {
+			fields = append(fields, k, v)
+		}
+		return array(fields)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func (f *fakeRedis) hashField(key string) map[string]string {
+	h := f.hashes[key]
+	if h == nil {
+		h = make(map[string]string)
+		f.hashes[key] = h
+	}
+	return h
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func integer(n int) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+func array(items []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(items))
+	for _, item := range items {
+		b.WriteString(bulk(item))
+	}
+	return b.String()
+}
@@ -0,0 +1,631 @@
+// Package redisshelf provides a ShelfManager that keeps shelf state in Redis,
+// so several dispatcher processes can share the same shelves and a restarted
+// process picks up the orders left behind by a crashed one.
+package redisshelf
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// Defaults used when Options leaves a field empty
+const (
+	DefaultPrefix       = "dish-dispatcher"
+	DefaultPollInterval = 500 * time.Millisecond
+	DefaultDialTimeout  = 5 * time.Second
+)
+
+// Options configures a Redis-backed manager
+type Options struct {
+	Addr   string
+	Prefix string // namespaces every key, so runs can share one Redis
+
+	HotCapacity      int
+	ColdCapacity     int
+	FrozenCapacity   int
+	OverflowCapacity int
+
+	// PollInterval is how often the scheduled expiry loop checks Redis for
+	// lapsed TTLs, since key expiry is not pushed to clients
+	PollInterval time.Duration
+	DialTimeout  time.Duration
+}
+
+// Manager is a ShelfManager whose orders, counters and outages live in Redis.
+//
+// Key layout, all under Options.Prefix:
+//   - order:{id}         JSON order record
+//   - ttl:{id}           marker whose TTL lapses when the order expires
+//   - shelf:{type}       set of the IDs held on a shelf
+//   - count:{type}       number of slots reserved on a shelf
+//   - shelfstats:{type}  hash of the shelf's ShelfStats counters
+//   - outage             hash of shelf type -> decay factor while cooling is lost
+//   - totals             hash of the run-wide order counters
+//   - rejections         hash of rejected placements by reason
+//   - byname:{name}, bytemp:{temp} hashes of ItemStats, indexed by the
+//     names and temps sets
+//
+// Claims are settled by SREM on the shelf set: whichever process removes an
+// ID first delivers or expires the order, so no order is counted twice.
+//
+// Manager is not a shelf.BatchPlacer: each order reserves its slot on its
+// own, and other processes may take the slots in between, so a batch could
+// not be shelved all or nothing. Best-effort batches are placed one order
+// at a time.
+type Manager struct {
+	// OnTransition, if set, is attached to every order loaded from Redis,
+	// since hooks on the placing process's copy do not survive storage.
+	// Set it before using the manager.
+	OnTransition order.TransitionHook
+
+	client     *client
+	prefix     string
+	capacities map[shelf.ShelfType]int
+	formulas   *formulaCache
+	fallback   shelf.ShelfType // takes unknown temperatures, empty to waste them
+
+	pollInterval time.Duration
+	updates      chan struct{}
+
+	errMutex sync.Mutex
+	err      error
+}
+
+var (
+	_ shelf.ShelfManager   = (*Manager)(nil)
+	_ shelf.FallbackRouter = (*Manager)(nil)
+	_ shelf.StatsResetter  = (*Manager)(nil)
+)
+
+// shelfTypes lists the shelves in hot, cold, frozen, overflow order. Redis
+// shelves always use this default layout.
+var shelfTypes = []shelf.ShelfType{shelf.HotShelf, shelf.ColdShelf, shelf.FrozenShelf, shelf.OverflowShelf}
+
+// shelfTemps maps each temperature shelf to the temperature it accepts
+var shelfTemps = map[shelf.ShelfType][]order.Temperature{
+	shelf.HotShelf:    {order.Hot},
+	shelf.ColdShelf:   {order.Cold},
+	shelf.FrozenShelf: {order.Frozen},
+}
+
+// New connects to Redis and returns a manager sharing whatever shelf state
+// is already stored under the prefix
+func New(opts Options) (*Manager, error) {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = DefaultDialTimeout
+	}
+
+	c, err := dial(opts.Addr, opts.DialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := c.str("PING"); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("ping redis: %w", err)
+	}
+
+	return &Manager{
+		client: c,
+		prefix: opts.Prefix,
+		capacities: map[shelf.ShelfType]int{
+			shelf.HotShelf:      opts.HotCapacity,
+			shelf.ColdShelf:     opts.ColdCapacity,
+			shelf.FrozenShelf:   opts.FrozenCapacity,
+			shelf.OverflowShelf: opts.OverflowCapacity,
+		},
+		formulas:     newFormulaCache(),
+		pollInterval: opts.PollInterval,
+		updates:      make(chan struct{}),
+	}, nil
+}
+
+// Close closes the Redis connection. Shelf state is left in Redis.
+func (m *Manager) Close() error {
+	return m.client.Close()
+}
+
+// Err returns the most recent Redis error. The ShelfManager methods have no
+// error results, so failures are reported here and the call is treated as
+// having done nothing.
+func (m *Manager) Err() error {
+	m.errMutex.Lock()
+	defer m.errMutex.Unlock()
+
+	return m.err
+}
+
+func (m *Manager) setErr(err error) {
+	m.errMutex.Lock()
+	defer m.errMutex.Unlock()
+
+	m.err = err
+}
+
+func (m *Manager) key(parts ...string) string {
+	k := m.prefix
+	for _, p := range parts {
+		k += ":" + p
+	}
+	return k
+}
+
+func shelfForTemperature(temp order.Temperature) (shelf.ShelfType, bool) {
+	switch temp {
+	case order.Hot:
+		return shelf.HotShelf, true
+	case order.Cold:
+		return shelf.ColdShelf, true
+	case order.Frozen:
+		return shelf.FrozenShelf, true
+	default:
+		return "", false
+	}
+}
+
+// SetFallbackShelf routes orders of unknown temperatures to the given
+// shelf, then overflow, instead of wasting them. An empty type picks the
+// overflow shelf. Call it before placing orders.
+func (m *Manager) SetFallbackShelf(shelfType shelf.ShelfType) error {
+	if shelfType == "" {
+		shelfType = shelf.OverflowShelf
+	}
+	if _, ok := m.capacities[shelfType]; !ok {
+		return fmt.Errorf("unknown fallback shelf %q", shelfType)
+	}
+	m.fallback = shelfType
+	return nil
+}
+
+// Accepts reports whether a shelf is routed orders of temp
+func (m *Manager) Accepts(temp order.Temperature) bool {
+	_, ok := shelfForTemperature(temp)
+	return ok
+}
+
+// PlaceOrder shelves a new order on its temperature's shelf or overflow. It
+// returns a *shelf.PlacementError if the order was not shelved.
+func (m *Manager) PlaceOrder(o *order.Order) error {
+	if o.State() != order.StateCreated {
+		m.recordRejection(shelf.RejectNotNew)
+		return &shelf.PlacementError{OrderID: o.ID, Reason: shelf.RejectNotNew}
+	}
+	m.hincr(m.key("totals"), "received", 1)
+
+	primary, ok := shelfForTemperature(o.Temp)
+	if !ok {
+		if m.fallback == "" {
+			return m.wasteOrder(o, shelf.RejectInvalidTemperature)
+		}
+		primary = m.fallback
+	}
+	candidates := []shelf.ShelfType{primary, shelf.OverflowShelf}
+	if primary == shelf.OverflowShelf {
+		candidates = candidates[:1]
+	}
+	for _, shelfType := range candidates {
+		placed, err := m.addOrder(shelfType, o)
+		if err != nil {
+			m.setErr(err)
+			m.recordRejection(shelf.RejectBackendError)
+			return &shelf.PlacementError{OrderID: o.ID, Reason: shelf.RejectBackendError, Err: err}
+		}
+		if placed {
+			return nil
+		}
+	}
+
+	m.hincr(m.key("shelfstats", string(shelf.OverflowShelf)), "wasted", 1)
+	return m.wasteOrder(o, shelf.RejectOverflowFull)
+}
+
+// wasteOrder records a new order that could not be shelved and returns the
+// rejection
+func (m *Manager) wasteOrder(o *order.Order, reason shelf.RejectReason) error {
+	now := time.Now()
+	o.Transition(order.StateWasted, now)
+	m.recordOutcome(o, outcomeWasted, now)
+	m.recordRejection(reason)
+	return &shelf.PlacementError{OrderID: o.ID, Reason: reason}
+}
+
+// addOrder reserves a slot on the shelf and stores the order there,
+// returning false if the shelf is full
+func (m *Manager) addOrder(shelfType shelf.ShelfType, o *order.Order) (bool, error) {
+	size, err := m.client.int("INCR", m.key("count", string(shelfType)))
+	if err != nil {
+		return false, err
+	}
+	if size > int64(m.capacities[shelfType]) {
+		_, err := m.client.int("DECR", m.key("count", string(shelfType)))
+		return false, err
+	}
+	m.updatePeak(shelfType, size)
+
+	now := time.Now()
+	if err := o.Transition(order.StateShelved, now); err != nil {
+		_, derr := m.client.int("DECR", m.key("count", string(shelfType)))
+		return false, errors.Join(err, derr)
+	}
+	if o.PlacedOnShelfAt.IsZero() {
+		o.PlacedOnShelfAt = now
+	}
+	o.CurrentShelfType = string(shelfType)
+	if shelfType == shelf.OverflowShelf && o.PlacedOnOverflow.IsZero() {
+		o.PlacedOnOverflow = now
+	}
+	if factor := m.outageFactor(shelfType); factor > 0 {
+		o.OpenDecayWindow(now, factor)
+	}
+
+	if err := m.saveOrder(o); err != nil {
+		return false, err
+	}
+	if _, err := m.client.int("SADD", m.key("shelf", string(shelfType)), o.ID); err != nil {
+		return false, err
+	}
+	m.hincr(m.key("shelfstats", string(shelfType)), "added", 1)
+	return true, nil
+}
+
+// updatePeak raises the shelf's recorded peak to size. Concurrent processes
+// may race here, so the peak is best effort.
+func (m *Manager) updatePeak(shelfType shelf.ShelfType, size int64) {
+	key := m.key("shelfstats", string(shelfType))
+	peak, err := m.client.str("HGET", key, "peak")
+	if err != nil && !errors.Is(err, ErrNil) {
+		m.setErr(err)
+		return
+	}
+	if current, _ := strconv.ParseInt(peak, 10, 64); size > current {
+		m.call("HSET", key, "peak", size)
+	}
+}
+
+// saveOrder writes the order record and refreshes its expiry marker
+func (m *Manager) saveOrder(o *order.Order) error {
+	data, err := encodeOrder(o)
+	if err != nil {
+		return err
+	}
+	if _, err := m.client.do("SET", m.key("order", o.ID), data); err != nil {
+		return err
+	}
+
+	ttlKey := m.key("ttl", o.ID)
+	expiresAt := o.ExpiresAt()
+	if expiresAt.IsZero() {
+		_, err = m.client.do("SET", ttlKey, "1")
+		return err
+	}
+	ttl := time.Until(expiresAt).Milliseconds()
+	if ttl <= 0 {
+		_, err = m.client.int("DEL", ttlKey)
+		return err
+	}
+	_, err = m.client.do("SET", ttlKey, "1", "PX", ttl)
+	return err
+}
+
+// loadOrder returns the stored order, or nil if there is none
+func (m *Manager) loadOrder(orderID string) (*order.Order, error) {
+	data, err := m.client.str("GET", m.key("order", orderID))
+	if errors.Is(err, ErrNil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	o, err := m.formulas.decode([]byte(data))
+	if err != nil {
+		return nil, err
+	}
+	o.OnTransition = m.OnTransition
+	return o, nil
+}
+
+// claim removes the order from its shelf set and releases its slot. It
+// returns false if another caller claimed the order first.
+func (m *Manager) claim(shelfType shelf.ShelfType, orderID string) (bool, error) {
+	removed, err := m.client.int("SREM", m.key("shelf", string(shelfType)), orderID)
+	if err != nil || removed == 0 {
+		return false, err
+	}
+	if _, err := m.client.int("DECR", m.key("count", string(shelfType))); err != nil {
+		return true, err
+	}
+	_, err = m.client.int("DEL", m.key("order", orderID), m.key("ttl", orderID))
+	return true, err
+}
+
+func (m *Manager) DeliverOrder(orderID string) bool {
+	o, err := m.loadOrder(orderID)
+	if err != nil {
+		m.setErr(err)
+		return false
+	}
+	if o == nil {
+		return false
+	}
+	// With no courier to carry it, pickup and drop-off are one instant
+	now := time.Now()
+	if !m.deliver(o, now) {
+		return false
+	}
+	o.Transition(order.StateDelivered, now)
+	return true
+}
+
+// TryDeliver collects an order that has not expired for delivery and
+// describes it, leaving it in transit until handoff. An expired order is
+// removed as expired instead. The claim on the order's
+// shelf set settles races with the expiry sweep and other couriers.
+func (m *Manager) TryDeliver(orderID string) (shelf.DeliveryResult, bool) {
+	o, err := m.loadOrder(orderID)
+	if err != nil {
+		m.setErr(err)
+		return shelf.DeliveryResult{}, false
+	}
+	if o == nil {
+		return shelf.DeliveryResult{}, false
+	}
+
+	now := time.Now()
+	shelfType := shelf.ShelfType(o.CurrentShelfType)
+	if o.IsExpired(now) {
+		m.expireIfDue(shelfType, orderID, now)
+		return shelf.DeliveryResult{}, false
+	}
+	value := o.CalculateValue(now)
+	if !m.deliver(o, now) {
+		return shelf.DeliveryResult{}, false
+	}
+	return shelf.NewDeliveryResult(o, value, shelfType, now), true
+}
+
+// deliver claims a loaded order and records it collected for delivery at
+// now, in transit, returning false if another caller claimed it first
+func (m *Manager) deliver(o *order.Order, now time.Time) bool {
+	shelfType := shelf.ShelfType(o.CurrentShelfType)
+	claimed, err := m.claim(shelfType, o.ID)
+	if err != nil {
+		m.setErr(err)
+	}
+	if !claimed {
+		return false
+	}
+
+	// The claim settled the race, so the order is ours to deliver
+	o.Transition(order.StateInTransit, now)
+	statsKey := m.key("shelfstats", string(shelfType))
+	m.hincr(statsKey, "delivered", 1)
+	m.hincr(statsKey, "removed", 1)
+	m.recordOutcome(o, outcomeDelivered, now)
+	return true
+}
+
+// shelfOrders loads every order on a shelf. IDs whose record has vanished
+// are skipped; the expiry sweep cleans them up.
+func (m *Manager) shelfOrders(shelfType shelf.ShelfType) ([]*order.Order, error) {
+	ids, err := m.client.strings("SMEMBERS", m.key("shelf", string(shelfType)))
+	if err != nil {
+		return nil, err
+	}
+
+	orders := make([]*order.Order, 0, len(ids))
+	for _, id := range ids {
+		o, err := m.loadOrder(id)
+		if err != nil {
+			return nil, err
+		}
+		if o != nil {
+			orders = append(orders, o)
+		}
+	}
+	return orders, nil
+}
+
+func (m *Manager) GetAllOrders() []*order.Order {
+	allOrders := make([]*order.Order, 0)
+	for _, shelfType := range shelfTypes {
+		orders, err := m.shelfOrders(shelfType)
+		if err != nil {
+			m.setErr(err)
+			continue
+		}
+		allOrders = append(allOrders, orders...)
+	}
+	return allOrders
+}
+
+// Query returns every shelved order matching the filter, evaluated at now
+func (m *Manager) Query(filter shelf.OrderFilter, now time.Time) []*order.Order {
+	matched := make([]*order.Order, 0)
+	for _, state := range m.ShelfStates() {
+		if filter.Shelf != "" && state.Type != filter.Shelf {
+			continue
+		}
+		for _, o := range state.Orders {
+			if filter.Matches(o, state.Type, now) {
+				matched = append(matched, o)
+			}
+		}
+	}
+	return matched
+}
+
+// ShelfStates describes the four shelves in hot, cold, frozen, overflow order
+func (m *Manager) ShelfStates() []shelf.ShelfState {
+	states := make([]shelf.ShelfState, 0, len(shelfTypes))
+	for _, shelfType := range shelfTypes {
+		orders, err := m.shelfOrders(shelfType)
+		if err != nil {
+			m.setErr(err)
+		}
+		states = append(states, shelf.ShelfState{
+			Type:     shelfType,
+			Capacity: m.capacities[shelfType],
+			Temps:    shelfTemps[shelfType],
+			InOutage: m.outageFactor(shelfType) > 0,
+			Orders:   orders,
+		})
+	}
+	return states
+}
+
+// RemoveExpiredOrders removes every order whose TTL has lapsed or whose
+// value has reached zero
+func (m *Manager) RemoveExpiredOrders() int {
+	return m.RemoveDueOrders(time.Now())
+}
+
+// RemoveDueOrders removes every order expired at now. Expiry is driven by
+// Redis TTLs, so there is no local schedule to consult; this is the same
+// scan RemoveExpiredOrders performs.
+func (m *Manager) RemoveDueOrders(now time.Time) int {
+	expired := 0
+	for _, shelfType := range shelfTypes {
+		ids, err := m.client.strings("SMEMBERS", m.key("shelf", string(shelfType)))
+		if err != nil {
+			m.setErr(err)
+			continue
+		}
+		for _, id := range ids {
+			if m.expireIfDue(shelfType, id, now) {
+				expired++
+			}
+		}
+	}
+	return expired
+}
+
+func (m *Manager) expireIfDue(shelfType shelf.ShelfType, orderID string, now time.Time) bool {
+	live, err := m.client.int("EXISTS", m.key("ttl", orderID))
+	if err != nil {
+		m.setErr(err)
+		return false
+	}
+	o, err := m.loadOrder(orderID)
+	if err != nil {
+		m.setErr(err)
+		return false
+	}
+	if live == 1 && o != nil && !o.IsExpired(now) {
+		return false
+	}
+
+	claimed, err := m.claim(shelfType, orderID)
+	if err != nil {
+		m.setErr(err)
+	}
+	if !claimed {
+		return false
+	}
+
+	m.hincr(m.key("shelfstats", string(shelfType)), "expired", 1)
+	if o != nil {
+		o.Transition(order.StateExpired, now)
+		m.recordOutcome(o, outcomeExpired, now)
+	} else {
+		// The record is gone too, so only the run total can be updated
+		m.hincr(m.key("totals"), "expired", 1)
+	}
+	return true
+}
+
+// NextExpiry returns the next time Redis should be polled for lapsed TTLs
+func (m *Manager) NextExpiry() (time.Time, bool) {
+	return time.Now().Add(m.pollInterval), true
+}
+
+// ExpiryUpdates never fires; NextExpiry already polls at a fixed interval
+func (m *Manager) ExpiryUpdates() <-chan struct{} {
+	return m.updates
+}
+
+// StartOutage makes a shelf lose cooling, multiplying the decay rate of its
+// contents by factor until EndOutage is called
+func (m *Manager) StartOutage(shelfType shelf.ShelfType, factor float64) error {
+	if _, ok := m.capacities[shelfType]; !ok {
+		return fmt.Errorf("unknown shelf %q", shelfType)
+	}
+	if factor <= 1 {
+		return fmt.Errorf("outage decay factor must be greater than 1, got %g", factor)
+	}
+
+	if _, err := m.client.do("HSET", m.key("outage"), string(shelfType), factor); err != nil {
+		return err
+	}
+	now := time.Now()
+	return m.updateShelfOrders(shelfType, func(o *order.Order) {
+		o.CloseDecayWindows(now)
+		o.OpenDecayWindow(now, factor)
+	})
+}
+
+// EndOutage restores cooling to a shelf
+func (m *Manager) EndOutage(shelfType shelf.ShelfType) error {
+	if _, ok := m.capacities[shelfType]; !ok {
+		return fmt.Errorf("unknown shelf %q", shelfType)
+	}
+
+	if _, err := m.client.do("HDEL", m.key("outage"), string(shelfType)); err != nil {
+		return err
+	}
+	now := time.Now()
+	return m.updateShelfOrders(shelfType, func(o *order.Order) {
+		o.CloseDecayWindows(now)
+	})
+}
+
+// updateShelfOrders applies update to every order on a shelf and stores the
+// result, which also moves each order's TTL to its new expiry
+func (m *Manager) updateShelfOrders(shelfType shelf.ShelfType, update func(*order.Order)) error {
+	orders, err := m.shelfOrders(shelfType)
+	if err != nil {
+		return err
+	}
+	for _, o := range orders {
+		update(o)
+		if err := m.saveOrder(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// outageFactor returns the shelf's outage decay factor, or zero if it has cooling
+func (m *Manager) outageFactor(shelfType shelf.ShelfType) float64 {
+	value, err := m.client.str("HGET", m.key("outage"), string(shelfType))
+	if errors.Is(err, ErrNil) {
+		return 0
+	}
+	if err != nil {
+		m.setErr(err)
+		return 0
+	}
+	factor, _ := strconv.ParseFloat(value, 64)
+	return factor
+}
+
+// call runs a command whose reply is not needed, recording any error
+func (m *Manager) call(args ...any) {
+	if _, err := m.client.do(args...); err != nil {
+		m.setErr(err)
+	}
+}
+
+func (m *Manager) hincr(key, field string, delta int) {
+	m.call("HINCRBY", key, field, delta)
+}
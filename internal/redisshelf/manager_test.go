@@ -0,0 +1,235 @@
+package redisshelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/archive"
+	"dish-dispatcher/internal/order"
+	"dish-dispatcher/internal/redisshelf"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func newManager(t *testing.T, addr string) *redisshelf.Manager {
+	t.Helper()
+
+	m, err := redisshelf.New(redisshelf.Options{
+		Addr:             addr,
+		Prefix:           "test",
+		HotCapacity:      1,
+		ColdCapacity:     1,
+		FrozenCapacity:   1,
+		OverflowCapacity: 1,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { m.Close() })
+	return m
+}
+
+func TestNew_ConnectionRefused(t *testing.T) {
+	_, err := redisshelf.New(redisshelf.Options{Addr: "127.0.0.1:1", DialTimeout: 100 * time.Millisecond})
+	assert.Error(t, err)
+}
+
+func TestManager_PlaceAndDeliver(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+
+	assert.NoError(t, m.PlaceOrder(o))
+	orders := m.GetAllOrders()
+	require.Len(t, orders, 1)
+	assert.Equal(t, o.ID, orders[0].ID)
+	assert.Equal(t, "hot", orders[0].CurrentShelfType)
+
+	assert.True(t, m.DeliverOrder(o.ID))
+	assert.False(t, m.DeliverOrder(o.ID))
+	assert.Empty(t, m.GetAllOrders())
+	assert.NoError(t, m.Err())
+
+	stats := m.GetStats()
+	hot := stats["hotShelf"].(map[string]interface{})
+	assert.Equal(t, 0, hot["current"])
+	assert.Equal(t, 1, hot["stats"].(shelf.ShelfStats).OrdersDelivered)
+	assert.Equal(t, 1, hot["stats"].(shelf.ShelfStats).PeakUsage)
+	assert.Equal(t, 1, stats["totalOrders"].(map[string]interface{})["delivered"])
+	assert.Equal(t, 1, m.StatsByName()["Burger"].Delivered)
+}
+
+func TestManager_OverflowAndWaste(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+
+	assert.NoError(t, m.PlaceOrder(order.NewOrder("A", order.Cold, 300, 0.5)))
+	overflowed := order.NewOrder("B", order.Cold, 300, 0.5)
+	assert.NoError(t, m.PlaceOrder(overflowed))
+	err := m.PlaceOrder(order.NewOrder("C", order.Cold, 300, 0.5))
+	assert.Equal(t, shelf.RejectOverflowFull, shelf.RejectionReason(err))
+
+	states := m.ShelfStates()
+	require.Len(t, states, 4)
+	assert.Len(t, states[1].Orders, 1)
+	require.Len(t, states[3].Orders, 1)
+	assert.Equal(t, overflowed.ID, states[3].Orders[0].ID)
+	assert.Equal(t, 1, m.StatsByTemperature()[order.Cold].Wasted)
+	overflow := m.GetStats()["overflowShelf"].(map[string]interface{})
+	assert.Equal(t, 1, overflow["stats"].(shelf.ShelfStats).OrdersWasted)
+	assert.Equal(t, map[shelf.RejectReason]int{shelf.RejectOverflowFull: 1}, m.Rejections())
+}
+
+func TestManager_SharedAcrossProcesses(t *testing.T) {
+	fake := newFakeRedis(t)
+	first := newManager(t, fake.Addr())
+	second := newManager(t, fake.Addr())
+
+	o := order.NewOrder("Pasta", order.Hot, 300, 0.3)
+	assert.NoError(t, first.PlaceOrder(o))
+
+	// The second process shares the hot shelf, so it is already full
+	assert.NoError(t, second.PlaceOrder(order.NewOrder("Soup", order.Hot, 300, 0.3)))
+	assert.Len(t, second.Query(shelf.OrderFilter{Shelf: shelf.OverflowShelf}, time.Now()), 1)
+
+	// Only one process can deliver a given order
+	assert.True(t, second.DeliverOrder(o.ID))
+	assert.False(t, first.DeliverOrder(o.ID))
+}
+
+func TestManager_CrashRecovery(t *testing.T) {
+	fake := newFakeRedis(t)
+	crashed := newManager(t, fake.Addr())
+
+	formula, err := order.NewExpressionFormula("shelfLife - decayRate * age")
+	require.NoError(t, err)
+	o := order.NewOrder("Ice Cream", order.Frozen, 300, 0.5)
+	o.Formula = formula
+	o.Source = "adhoc"
+	o.Priority, o.Zone, o.Price = 2, "north", 4.5
+	require.NoError(t, crashed.PlaceOrder(o))
+	crashed.Close()
+
+	restarted := newManager(t, fake.Addr())
+	orders := restarted.GetAllOrders()
+	require.Len(t, orders, 1)
+	assert.Equal(t, o.PlacedOnShelfAt.UnixNano(), orders[0].PlacedOnShelfAt.UnixNano())
+	assert.Equal(t, formula.String(), orders[0].Formula.(*order.ExpressionFormula).String())
+	assert.Equal(t, "adhoc", orders[0].Source)
+	assert.Equal(t, 2, orders[0].Priority)
+	assert.Equal(t, "north", orders[0].Zone)
+	assert.Equal(t, 4.5, orders[0].Price)
+	assert.True(t, restarted.DeliverOrder(o.ID))
+}
+
+func TestManager_TryDeliver(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+	fresh := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	stale := order.NewOrder("Salad", order.Cold, 0.02, 1)
+	require.NoError(t, m.PlaceOrder(fresh))
+	require.NoError(t, m.PlaceOrder(stale))
+	time.Sleep(20 * time.Millisecond)
+
+	result, ok := m.TryDeliver(fresh.ID)
+	assert.True(t, ok)
+	assert.InDelta(t, 1, result.Value, 0.01)
+	assert.Equal(t, shelf.HotShelf, result.Shelf)
+	assert.GreaterOrEqual(t, result.OnShelf, 20*time.Millisecond)
+	assert.Less(t, result.OnShelf, time.Second)
+	_, ok = m.TryDeliver(fresh.ID)
+	assert.False(t, ok)
+
+	// Expired before its TTL lapsed in Redis
+	_, ok = m.TryDeliver(stale.ID)
+	assert.False(t, ok)
+	assert.Empty(t, m.GetAllOrders())
+	assert.Equal(t, 1, m.StatsByName()["Burger"].Delivered)
+	assert.Equal(t, 1, m.StatsByName()["Salad"].Expired)
+	assert.NoError(t, m.Err())
+}
+
+func TestManager_TTLExpiry(t *testing.T) {
+	fake := newFakeRedis(t)
+	m := newManager(t, fake.Addr())
+
+	o := order.NewOrder("Salad", order.Cold, 300, 0.5)
+	require.NoError(t, m.PlaceOrder(o))
+	assert.Equal(t, 0, m.RemoveDueOrders(time.Now()))
+
+	fake.expire("test:ttl:" + o.ID)
+	assert.Equal(t, 1, m.RemoveDueOrders(time.Now()))
+	assert.Empty(t, m.GetAllOrders())
+	assert.Equal(t, 1, m.StatsByName()["Salad"].Expired)
+	cold := m.GetStats()["coldShelf"].(map[string]interface{})
+	assert.Equal(t, 1, cold["stats"].(shelf.ShelfStats).OrdersExpired)
+	assert.Zero(t, cold["stats"].(shelf.ShelfStats).OrdersWasted)
+}
+
+func TestManager_Outage(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+
+	o := order.NewOrder("Steak", order.Hot, 300, 0.5)
+	require.NoError(t, m.PlaceOrder(o))
+	before := m.GetAllOrders()[0].ExpiresAt()
+
+	require.NoError(t, m.StartOutage(shelf.HotShelf, 3))
+	assert.True(t, m.ShelfStates()[0].InOutage)
+	assert.True(t, m.GetAllOrders()[0].ExpiresAt().Before(before))
+
+	require.NoError(t, m.EndOutage(shelf.HotShelf))
+	assert.False(t, m.ShelfStates()[0].InOutage)
+	assert.Error(t, m.StartOutage(shelf.HotShelf, 1))
+	assert.Error(t, m.StartOutage("pantry", 2))
+}
+
+func TestManager_OnTransitionAndTimeline(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+	completed := archive.New(10)
+	m.OnTransition = completed.Observe
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, m.PlaceOrder(o))
+	require.True(t, m.DeliverOrder(o.ID))
+
+	// The delivered copy was loaded from Redis, with its stored timeline
+	recent := completed.Recent(0, order.StateDelivered)
+	require.Len(t, recent, 1)
+	assert.Equal(t, o.ID, recent[0].ID)
+	timeline := recent[0].Timeline
+	require.Len(t, timeline, 3)
+	assert.Equal(t, order.StateShelved, timeline[0].To)
+	assert.Equal(t, order.StateInTransit, timeline[1].To)
+}
+
+func TestManager_FallbackShelf(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+
+	err := m.PlaceOrder(order.NewOrder("Bread", "ambient", 300, 0.5))
+	assert.Equal(t, shelf.RejectInvalidTemperature, shelf.RejectionReason(err))
+
+	assert.Error(t, m.SetFallbackShelf("pantry"))
+	require.NoError(t, m.SetFallbackShelf(""))
+	bread := order.NewOrder("Bread", "ambient", 300, 0.5)
+	require.NoError(t, m.PlaceOrder(bread))
+	assert.Equal(t, string(shelf.OverflowShelf), bread.CurrentShelfType)
+	assert.False(t, m.Accepts("ambient"))
+	assert.True(t, m.Accepts(order.Hot))
+}
+
+func TestManager_ResetStats(t *testing.T) {
+	m := newManager(t, newFakeRedis(t).Addr())
+	delivered := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, m.PlaceOrder(delivered))
+	require.True(t, m.DeliverOrder(delivered.ID))
+	require.NoError(t, m.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5)))
+
+	m.ResetStats()
+	require.NoError(t, m.Err())
+	totals := m.GetStats()["totalOrders"].(map[string]interface{})
+	assert.Equal(t, 1, totals["received"])
+	assert.Equal(t, 0, totals["delivered"])
+	assert.Empty(t, m.StatsByName())
+	cold := m.GetStats()["coldShelf"].(map[string]interface{})
+	assert.Equal(t, shelf.ShelfStats{OrdersAdded: 1, PeakUsage: 1}, cold["stats"])
+
+	_, err := shelf.CheckInvariants(m)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,141 @@
+package redisshelf
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// orderRecord is the JSON form of a shelved order. Formulas are stored by
+// name, or by source for expression formulas, and rebuilt on load.
+type orderRecord struct {
+	ID               string              `json:"id"`
+	Name             string              `json:"name"`
+	Temp             order.Temperature   `json:"temp"`
+	ShelfLife        float64             `json:"shelfLife"`
+	DecayRate        float64             `json:"decayRate"`
+	CreatedAt        time.Time           `json:"createdAt"`
+	Size             float64             `json:"size,omitempty"`
+	Source           string              `json:"source,omitempty"`
+	Priority         int                 `json:"priority,omitempty"`
+	Zone             string              `json:"zone,omitempty"`
+	Price            float64             `json:"price,omitempty"`
+	Formula          string              `json:"formula,omitempty"`
+	Expression       string              `json:"expression,omitempty"`
+	SafeBand         *order.SafeBand     `json:"safeBand,omitempty"`
+	DecayWindows     []order.DecayWindow `json:"decayWindows,omitempty"`
+	PlacedOnShelfAt  time.Time           `json:"placedOnShelfAt"`
+	PlacedOnOverflow time.Time           `json:"placedOnOverflow"`
+	CurrentShelfType string              `json:"currentShelfType"`
+	History          []order.StateChange `json:"history,omitempty"`
+}
+
+func encodeOrder(o *order.Order) ([]byte, error) {
+	record := orderRecord{
+		ID:               o.ID,
+		Name:             o.Name,
+		Temp:             o.Temp,
+		ShelfLife:        o.ShelfLife,
+		DecayRate:        o.DecayRate,
+		CreatedAt:        o.CreatedAt,
+		Size:             o.Size,
+		Source:           o.Source,
+		Priority:         o.Priority,
+		Zone:             o.Zone,
+		Price:            o.Price,
+		SafeBand:         o.SafeBand,
+		DecayWindows:     o.DecayWindows,
+		PlacedOnShelfAt:  o.PlacedOnShelfAt,
+		PlacedOnOverflow: o.PlacedOnOverflow,
+		CurrentShelfType: o.CurrentShelfType,
+		History:          o.History(),
+	}
+
+	switch f := o.Formula.(type) {
+	case nil:
+	case *order.ExpressionFormula:
+		record.Expression = f.String()
+	default:
+		record.Formula = f.Name()
+	}
+
+	return json.Marshal(record)
+}
+
+// formulaCache rebuilds decay formulas from stored records, parsing each
+// distinct expression only once
+type formulaCache struct {
+	mutex       sync.Mutex
+	expressions map[string]order.DecayFormula
+}
+
+func newFormulaCache() *formulaCache {
+	return &formulaCache{expressions: make(map[string]order.DecayFormula)}
+}
+
+func (fc *formulaCache) decode(data []byte) (*order.Order, error) {
+	var record orderRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+
+	formula, err := fc.lookup(record)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &order.Order{
+		ID:               record.ID,
+		Name:             record.Name,
+		Temp:             record.Temp,
+		ShelfLife:        record.ShelfLife,
+		DecayRate:        record.DecayRate,
+		CreatedAt:        record.CreatedAt,
+		Size:             record.Size,
+		Source:           record.Source,
+		Priority:         record.Priority,
+		Zone:             record.Zone,
+		Price:            record.Price,
+		Formula:          formula,
+		SafeBand:         record.SafeBand,
+		DecayWindows:     record.DecayWindows,
+		PlacedOnShelfAt:  record.PlacedOnShelfAt,
+		PlacedOnOverflow: record.PlacedOnOverflow,
+		CurrentShelfType: record.CurrentShelfType,
+	}
+	// Replay the stored timeline. Records written before timelines were
+	// stored are for shelved orders, so start those from placement.
+	for _, change := range record.History {
+		if err := o.Transition(change.To, change.At); err != nil {
+			return nil, err
+		}
+	}
+	if len(record.History) == 0 {
+		o.Transition(order.StateShelved, record.PlacedOnShelfAt)
+	}
+	return o, nil
+}
+
+func (fc *formulaCache) lookup(record orderRecord) (order.DecayFormula, error) {
+	if record.Expression == "" {
+		if record.Formula == "" {
+			return nil, nil
+		}
+		return order.LookupDecayFormula(record.Formula)
+	}
+
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if f, ok := fc.expressions[record.Expression]; ok {
+		return f, nil
+	}
+	f, err := order.NewExpressionFormula(record.Expression)
+	if err != nil {
+		return nil, err
+	}
+	fc.expressions[record.Expression] = f
+	return f, nil
+}
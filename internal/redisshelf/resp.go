@@ -0,0 +1,194 @@
+package redisshelf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned when Redis replies with a nil bulk string or array
+var ErrNil = errors.New("redis: nil reply")
+
+// client is a minimal RESP2 client over a single connection. Commands are
+// serialized with a mutex, which is plenty for the dispatcher's call rates.
+type client struct {
+	mutex  sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+}
+
+func dial(addr string, timeout time.Duration) (*client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("connect to redis at %s: %w", addr, err)
+	}
+	return &client{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+		writer: bufio.NewWriter(conn),
+	}, nil
+}
+
+func (c *client) Close() error {
+	return c.conn.Close()
+}
+
+// do sends one command and returns its decoded reply: string for simple and
+// bulk strings, int64 for integers and []any for arrays
+func (c *client) do(args ...any) (any, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if err := c.writeCommand(args); err != nil {
+		return nil, err
+	}
+	return c.readReply()
+}
+
+func (c *client) writeCommand(args []any) error {
+	fmt.Fprintf(c.writer, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case float64:
+			s = strconv.FormatFloat(v, 'f', -1, 64)
+		default:
+			return fmt.Errorf("redis: unsupported argument type %T", arg)
+		}
+		fmt.Fprintf(c.writer, "$%d\r\n%s\r\n", len(s), s)
+	}
+	return c.writer.Flush()
+}
+
+func (c *client) readReply() (any, error) {
+	line, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if len(line) == 0 {
+		return nil, errors.New("redis: empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New("redis: " + line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		items := make([]any, n)
+		for i := range items {
+			item, err := c.readReply()
+			if err != nil && !errors.Is(err, ErrNil) {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unexpected reply %q", line)
+	}
+}
+
+func (c *client) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", fmt.Errorf("redis: malformed line %q", line)
+	}
+	return line[:len(line)-2], nil
+}
+
+// Typed helpers over do
+
+func (c *client) str(args ...any) (string, error) {
+	reply, err := c.do(args...)
+	if err != nil {
+		return "", err
+	}
+	s, ok := reply.(string)
+	if !ok {
+		return "", fmt.Errorf("redis: expected string reply, got %T", reply)
+	}
+	return s, nil
+}
+
+func (c *client) int(args ...any) (int64, error) {
+	reply, err := c.do(args...)
+	if err != nil {
+		return 0, err
+	}
+	n, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("redis: expected integer reply, got %T", reply)
+	}
+	return n, nil
+}
+
+func (c *client) strings(args ...any) ([]string, error) {
+	reply, err := c.do(args...)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok {
+		return nil, fmt.Errorf("redis: expected array reply, got %T", reply)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// hash returns HGETALL as a map
+func (c *client) hash(key string) (map[string]string, error) {
+	fields, err := c.strings("HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	h := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		h[fields[i]] = fields[i+1]
+	}
+	return h, nil
+}
@@ -0,0 +1,191 @@
+package redisshelf
+
+import (
+	"strconv"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+type outcome int
+
+const (
+	outcomeDelivered outcome = iota
+	outcomeWasted
+	outcomeExpired
+)
+
+// recordOutcome updates the run totals and the per-name and per-temperature
+// breakdowns for one order
+func (m *Manager) recordOutcome(o *order.Order, result outcome, at time.Time) {
+	nameKey := m.key("byname", o.Name)
+	tempKey := m.key("bytemp", string(o.Temp))
+	m.call("SADD", m.key("names"), o.Name)
+	m.call("SADD", m.key("temps"), string(o.Temp))
+
+	switch result {
+	case outcomeDelivered:
+		value := o.CalculateValue(at)
+		m.hincr(m.key("totals"), "delivered", 1)
+		for _, key := range []string{nameKey, tempKey} {
+			m.hincr(key, "delivered", 1)
+			m.call("HINCRBYFLOAT", key, "deliveredValue", value)
+		}
+	case outcomeWasted:
+		m.hincr(m.key("totals"), "wasted", 1)
+		m.hincr(nameKey, "wasted", 1)
+		m.hincr(tempKey, "wasted", 1)
+	case outcomeExpired:
+		m.hincr(m.key("totals"), "expired", 1)
+		m.hincr(nameKey, "expired", 1)
+		m.hincr(tempKey, "expired", 1)
+	}
+}
+
+// StatsByName returns the outcome breakdown by item name
+func (m *Manager) StatsByName() map[string]shelf.ItemStats {
+	names, err := m.client.strings("SMEMBERS", m.key("names"))
+	if err != nil {
+		m.setErr(err)
+	}
+
+	stats := make(map[string]shelf.ItemStats, len(names))
+	for _, name := range names {
+		stats[name] = m.itemStats(m.key("byname", name))
+	}
+	return stats
+}
+
+// StatsByTemperature returns the outcome breakdown by temperature
+func (m *Manager) StatsByTemperature() map[order.Temperature]shelf.ItemStats {
+	temps, err := m.client.strings("SMEMBERS", m.key("temps"))
+	if err != nil {
+		m.setErr(err)
+	}
+
+	stats := make(map[order.Temperature]shelf.ItemStats, len(temps))
+	for _, temp := range temps {
+		stats[order.Temperature(temp)] = m.itemStats(m.key("bytemp", temp))
+	}
+	return stats
+}
+
+func (m *Manager) itemStats(key string) shelf.ItemStats {
+	h := m.hash(key)
+	value, _ := strconv.ParseFloat(h["deliveredValue"], 64)
+	return shelf.ItemStats{
+		Delivered:           atoi(h["delivered"]),
+		Wasted:              atoi(h["wasted"]),
+		Expired:             atoi(h["expired"]),
+		TotalDeliveredValue: value,
+	}
+}
+
+// GetStats returns the same map shape as InMemoryShelfManager.GetStats.
+// Lock stats are zero since all coordination happens inside Redis.
+func (m *Manager) GetStats() map[string]interface{} {
+	stats := map[string]interface{}{
+		"managerLockStats": shelf.LockStats{},
+		"byName":           m.StatsByName(),
+		"byTemperature":    m.StatsByTemperature(),
+		"rejections":       m.Rejections(),
+	}
+
+	for _, shelfType := range shelfTypes {
+		h := m.hash(m.key("shelfstats", string(shelfType)))
+		current, err := m.client.int("SCARD", m.key("shelf", string(shelfType)))
+		if err != nil {
+			m.setErr(err)
+		}
+		stats[string(shelfType)+"Shelf"] = map[string]interface{}{
+			"capacity": m.capacities[shelfType],
+			"current":  int(current),
+			"stats": shelf.ShelfStats{
+				OrdersAdded:     atoi(h["added"]),
+				OrdersRemoved:   atoi(h["removed"]),
+				OrdersWasted:    atoi(h["wasted"]),
+				OrdersExpired:   atoi(h["expired"]),
+				OrdersDelivered: atoi(h["delivered"]),
+				PeakUsage:       atoi(h["peak"]),
+			},
+			"lockStats": shelf.LockStats{},
+		}
+	}
+
+	totals := m.hash(m.key("totals"))
+	stats["totalOrders"] = map[string]interface{}{
+		"received":  atoi(totals["received"]),
+		"delivered": atoi(totals["delivered"]),
+		"expired":   atoi(totals["expired"]),
+		"wasted":    atoi(totals["wasted"]),
+	}
+
+	return stats
+}
+
+// ResetStats deletes the shared counters and breakdowns, for every process
+// using the prefix, then counts the orders still shelved as received
+func (m *Manager) ResetStats() {
+	names, err := m.client.strings("SMEMBERS", m.key("names"))
+	if err != nil {
+		m.setErr(err)
+	}
+	temps, err := m.client.strings("SMEMBERS", m.key("temps"))
+	if err != nil {
+		m.setErr(err)
+	}
+
+	keys := []any{m.key("totals"), m.key("rejections"), m.key("names"), m.key("temps")}
+	for _, name := range names {
+		keys = append(keys, m.key("byname", name))
+	}
+	for _, temp := range temps {
+		keys = append(keys, m.key("bytemp", temp))
+	}
+	for _, shelfType := range shelfTypes {
+		keys = append(keys, m.key("shelfstats", string(shelfType)))
+	}
+	m.call(append([]any{"DEL"}, keys...)...)
+
+	for _, shelfType := range shelfTypes {
+		held, err := m.client.int("SCARD", m.key("shelf", string(shelfType)))
+		if err != nil {
+			m.setErr(err)
+			continue
+		}
+		if held > 0 {
+			m.hincr(m.key("totals"), "received", int(held))
+			m.hincr(m.key("shelfstats", string(shelfType)), "added", int(held))
+			m.call("HSET", m.key("shelfstats", string(shelfType)), "peak", held)
+		}
+	}
+}
+
+// recordRejection counts a rejected placement by reason
+func (m *Manager) recordRejection(reason shelf.RejectReason) {
+	m.hincr(m.key("rejections"), string(reason), 1)
+}
+
+// Rejections returns the rejected placement counts by reason
+func (m *Manager) Rejections() map[shelf.RejectReason]int {
+	h := m.hash(m.key("rejections"))
+	rejections := make(map[shelf.RejectReason]int, len(h))
+	for reason, n := range h {
+		rejections[shelf.RejectReason(reason)] = atoi(n)
+	}
+	return rejections
+}
+
+func (m *Manager) hash(key string) map[string]string {
+	h, err := m.client.hash(key)
+	if err != nil {
+		m.setErr(err)
+	}
+	return h
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
@@ -0,0 +1,54 @@
+package shelf
+
+import (
+	"sort"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// AgingEntry describes one shelved order in an aging report
+type AgingEntry struct {
+	Order *order.Order
+	Value float64
+	Age   time.Duration // since first shelved
+
+	// ExpiresIn is the time left until the order's value reaches zero, and
+	// Expires is false if it never will
+	ExpiresIn time.Duration
+	Expires   bool
+}
+
+// AgingReport lists every shelved order with its value at now, least
+// valuable first, then soonest to expire. It helps find orders stuck on the
+// shelves.
+func AgingReport(manager ShelfManager, now time.Time) []AgingEntry {
+	orders := manager.GetAllOrders()
+	report := make([]AgingEntry, 0, len(orders))
+	for _, o := range orders {
+		entry := AgingEntry{
+			Order: o,
+			Value: o.CalculateValue(now),
+			Age:   now.Sub(o.PlacedOnShelfAt),
+		}
+		if expiresAt := o.ExpiresAt(); !expiresAt.IsZero() {
+			entry.ExpiresIn, entry.Expires = expiresAt.Sub(now), true
+		}
+		report = append(report, entry)
+	}
+
+	sort.Slice(report, func(i, j int) bool {
+		a, b := report[i], report[j]
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+		if a.Expires != b.Expires {
+			return a.Expires
+		}
+		if a.ExpiresIn != b.ExpiresIn {
+			return a.ExpiresIn < b.ExpiresIn
+		}
+		return a.Order.ID < b.Order.ID
+	})
+	return report
+}
@@ -0,0 +1,43 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestAgingReport(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	fresh := order.NewOrder("Salad", order.Cold, 300, 1)
+	stale := order.NewOrder("Burger", order.Hot, 100, 1)
+	keeps := order.NewOrder("Ice", order.Frozen, 100, 0)
+	require.NoError(t, sm.PlaceOrder(stale))
+	c.Advance(50 * time.Second)
+	require.NoError(t, sm.PlaceOrder(fresh))
+	require.NoError(t, sm.PlaceOrder(keeps))
+	c.Advance(10 * time.Second)
+
+	report := shelf.AgingReport(sm, c.Now())
+	require.Len(t, report, 3)
+
+	assert.Equal(t, stale, report[0].Order)
+	assert.InDelta(t, 0.4, report[0].Value, 0.001)
+	assert.Equal(t, 60*time.Second, report[0].Age)
+	assert.True(t, report[0].Expires)
+	assert.Equal(t, 40*time.Second, report[0].ExpiresIn)
+
+	assert.Equal(t, fresh, report[1].Order)
+	assert.Equal(t, 290*time.Second, report[1].ExpiresIn)
+
+	assert.Equal(t, keeps, report[2].Order)
+	assert.False(t, report[2].Expires)
+}
@@ -0,0 +1,164 @@
+package shelf
+
+import (
+	"fmt"
+	"slices"
+
+	"dish-dispatcher/internal/order"
+)
+
+// BatchMode says what PlaceOrders does when some orders of a batch cannot
+// be shelved
+type BatchMode string
+
+// Batch modes
+const (
+	// BatchBestEffort shelves what it can and wastes the rest, as placing
+	// the orders one by one would
+	BatchBestEffort BatchMode = "best-effort"
+	// BatchAllOrNothing shelves every order or none: if any cannot be
+	// shelved, the whole batch is turned away and its orders left new, so
+	// a multi-item ticket is never split
+	BatchAllOrNothing BatchMode = "all-or-nothing"
+)
+
+// BatchPlacer is implemented by managers that can shelve several orders
+// under one round of locking. The simulator places batches for managers
+// without it, like the Redis one, one order at a time, and only best-effort.
+type BatchPlacer interface {
+	// PlaceOrders shelves a batch of new orders and returns an error for
+	// each, nil if it was shelved
+	PlaceOrders(orders []*order.Order, mode BatchMode) []error
+}
+
+var _ BatchPlacer = (*InMemoryShelfManager)(nil)
+
+// PlaceOrders shelves a batch of new orders, taking the lock of each shelf
+// they may go on once for the whole batch. Orders are tried in turn, each
+// on its candidate shelves in order, and the errors are returned by index.
+//
+// In BatchBestEffort mode an order that does not fit is then placed on its
+// own, as PlaceOrder would, so the eviction script still gets to make room
+// for it. In BatchAllOrNothing mode the batch fails if any order does not
+// fit: no order is shelved, wasted or counted as received, those at fault
+// get a *PlacementError with their reason and the rest RejectBatch.
+func (sm *InMemoryShelfManager) PlaceOrders(orders []*order.Order, mode BatchMode) []error {
+	if mode != BatchBestEffort && mode != BatchAllOrNothing {
+		errs := make([]error, len(orders))
+		for i := range errs {
+			errs[i] = fmt.Errorf("unknown batch mode %q", mode)
+		}
+		return errs
+	}
+
+	errs := make([]error, len(orders))
+	candidates := make([][]*Shelf, len(orders))
+	seen := make(map[string]bool, len(orders))
+	for i, o := range orders {
+		// An order listed twice is placed the first time
+		if o.State() != order.StateCreated || seen[o.ID] {
+			errs[i] = &PlacementError{OrderID: o.ID, Reason: RejectNotNew}
+			continue
+		}
+		seen[o.ID] = true
+		candidates[i] = sm.candidates(o.Temp)
+		if len(candidates[i]) == 0 {
+			errs[i] = &PlacementError{OrderID: o.ID, Reason: RejectInvalidTemperature}
+		} else if sm.placement != nil {
+			sm.rankShelves(o, candidates[i])
+		}
+	}
+
+	// Shelf locks are taken in layout order, as moves take them, so
+	// batches and moves cannot deadlock
+	locked := make([]*Shelf, 0, len(sm.shelves))
+	for _, s := range sm.shelves {
+		if slices.ContainsFunc(candidates, func(c []*Shelf) bool { return slices.Contains(c, s) }) {
+			locked = append(locked, s)
+		}
+	}
+	for _, s := range locked {
+		s.mutex.Lock()
+	}
+
+	targets := sm.planBatch(orders, candidates, errs)
+	if mode == BatchAllOrNothing && slices.ContainsFunc(errs, func(err error) bool { return err != nil }) {
+		unlockAll(locked...)
+		return sm.rejectBatch(orders, errs)
+	}
+
+	// Indexed before the locks are released, when a sweep may expire them
+	now := sm.clock.Now()
+	shelved := 0
+	for i, o := range orders {
+		if targets[i] == nil {
+			continue
+		}
+		sm.indexOrder(o.ID, targets[i])
+		if err := o.Transition(order.StateShelved, now); err != nil {
+			sm.unindexOrder(o.ID)
+			targets[i] = nil
+			errs[i] = &PlacementError{OrderID: o.ID, Reason: RejectNotNew}
+			continue
+		}
+		targets[i].hold(o, now)
+		shelved++
+	}
+	unlockAll(locked...)
+
+	sm.addCounter(&sm.TotalOrdersReceived, shelved)
+	for i, o := range orders {
+		switch {
+		case targets[i] != nil:
+			sm.scheduleExpiry(o, targets[i])
+		case RejectionReason(errs[i]) == RejectNotNew:
+			sm.recordRejection(RejectNotNew)
+		default:
+			// Placed on its own, wasting it if it still does not fit
+			errs[i] = sm.PlaceOrder(o)
+		}
+	}
+	return errs
+}
+
+// planBatch picks the first candidate shelf with room for each order,
+// counting the room taken by the orders before it, and returns nil for
+// those that fit nowhere, recording why in errs. Callers must hold the
+// locks of every candidate shelf.
+func (sm *InMemoryShelfManager) planBatch(orders []*order.Order, candidates [][]*Shelf, errs []error) []*Shelf {
+	type usage struct {
+		count  int
+		volume float64
+	}
+	planned := make(map[*Shelf]usage)
+	targets := make([]*Shelf, len(orders))
+	for i, o := range orders {
+		if errs[i] != nil {
+			continue
+		}
+		for _, s := range candidates[i] {
+			u := planned[s]
+			if s.fitsAfter(u.count, u.volume, o.Volume()) {
+				targets[i] = s
+				planned[s] = usage{u.count + 1, u.volume + o.Volume()}
+				break
+			}
+		}
+		if targets[i] == nil {
+			errs[i] = &PlacementError{OrderID: o.ID, Reason: fullReason(len(sm.overflow) > 0)}
+		}
+	}
+	return targets
+}
+
+// rejectBatch turns away a whole all-or-nothing batch, blaming the other
+// orders' rejection on the batch
+func (sm *InMemoryShelfManager) rejectBatch(orders []*order.Order, errs []error) []error {
+	for i, o := range orders {
+		if errs[i] == nil {
+			errs[i] = &PlacementError{OrderID: o.ID, Reason: RejectBatch}
+		}
+		sm.recordRejection(RejectionReason(errs[i]))
+	}
+	return errs
+}
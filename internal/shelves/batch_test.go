@@ -0,0 +1,86 @@
+package shelf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_PlaceOrdersBestEffort(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+
+	orders := []*order.Order{
+		order.NewOrder("Burger", order.Hot, 300, 0.5),
+		order.NewOrder("Fries", order.Hot, 300, 0.5),
+		order.NewOrder("Soup", order.Hot, 300, 0.5),
+		order.NewOrder("Salad", order.Cold, 300, 0.5),
+		order.NewOrder("Bread", "ambient", 300, 0.5),
+	}
+	errs := sm.PlaceOrders(orders, shelf.BatchBestEffort)
+	require.Len(t, errs, len(orders))
+
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.Equal(t, shelf.RejectOverflowFull, shelf.RejectionReason(errs[2]))
+	assert.NoError(t, errs[3])
+	assert.Equal(t, shelf.RejectInvalidTemperature, shelf.RejectionReason(errs[4]))
+
+	assert.Equal(t, string(shelf.HotShelf), orders[0].CurrentShelfType)
+	assert.Equal(t, string(shelf.OverflowShelf), orders[1].CurrentShelfType)
+	assert.Equal(t, order.StateWasted, orders[2].State())
+	assert.Len(t, sm.GetAllOrders(), 3)
+
+	totals := sm.GetStats()["totalOrders"].(map[string]interface{})
+	assert.Equal(t, 5, totals["received"])
+	assert.Equal(t, 2, totals["wasted"])
+	_, err := shelf.CheckInvariants(sm)
+	assert.NoError(t, err)
+
+	// Placed orders can be found and delivered as usual
+	assert.True(t, sm.DeliverOrder(orders[1].ID))
+}
+
+func TestShelfManager_PlaceOrdersAllOrNothing(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+
+	ticket := []*order.Order{
+		order.NewOrder("Burger", order.Hot, 300, 0.5),
+		order.NewOrder("Fries", order.Hot, 300, 0.5),
+		order.NewOrder("Soup", order.Hot, 300, 0.5),
+	}
+	errs := sm.PlaceOrders(ticket, shelf.BatchAllOrNothing)
+	assert.Equal(t, shelf.RejectBatch, shelf.RejectionReason(errs[0]))
+	assert.Equal(t, shelf.RejectBatch, shelf.RejectionReason(errs[1]))
+	assert.Equal(t, shelf.RejectOverflowFull, shelf.RejectionReason(errs[2]))
+
+	// Nothing was shelved or wasted, so the ticket can be retried
+	assert.Empty(t, sm.GetAllOrders())
+	for _, o := range ticket {
+		assert.Equal(t, order.StateCreated, o.State())
+	}
+	totals := sm.GetStats()["totalOrders"].(map[string]interface{})
+	assert.Equal(t, 0, totals["received"])
+	assert.Equal(t, 0, totals["wasted"])
+
+	errs = sm.PlaceOrders(ticket[:2], shelf.BatchAllOrNothing)
+	assert.Equal(t, []error{nil, nil}, errs)
+	assert.Len(t, sm.GetAllOrders(), 2)
+}
+
+func TestShelfManager_PlaceOrdersNotNew(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+
+	// The same order twice in one batch is only shelved once
+	errs := sm.PlaceOrders([]*order.Order{o, o}, shelf.BatchBestEffort)
+	assert.NoError(t, errs[0])
+	assert.Equal(t, shelf.RejectNotNew, shelf.RejectionReason(errs[1]))
+	assert.Len(t, sm.GetAllOrders(), 1)
+
+	errs = sm.PlaceOrders([]*order.Order{o}, "maybe")
+	assert.Error(t, errs[0])
+}
@@ -0,0 +1,91 @@
+package shelf
+
+import (
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// ItemStats aggregates the outcomes of one group of orders
+type ItemStats struct {
+	Delivered           int
+	Wasted              int
+	Expired             int
+	TotalDeliveredValue float64
+}
+
+// AverageDeliveredValue returns the mean value of the group's orders at delivery
+func (s ItemStats) AverageDeliveredValue() float64 {
+	if s.Delivered == 0 {
+		return 0
+	}
+	return s.TotalDeliveredValue / float64(s.Delivered)
+}
+
+// Lost returns the number of orders that were wasted or expired
+func (s ItemStats) Lost() int {
+	return s.Wasted + s.Expired
+}
+
+type outcome int
+
+const (
+	outcomeDelivered outcome = iota
+	outcomeWasted
+	outcomeExpired
+)
+
+// recordOutcome updates the run totals and the per-name and per-temperature
+// breakdowns for one order
+func (sm *InMemoryShelfManager) recordOutcome(o *order.Order, result outcome, at time.Time) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	byName := sm.statsByName[o.Name]
+	byTemp := sm.statsByTemp[o.Temp]
+
+	switch result {
+	case outcomeDelivered:
+		value := o.CalculateValue(at)
+		sm.TotalOrdersDelivered++
+		byName.Delivered++
+		byName.TotalDeliveredValue += value
+		byTemp.Delivered++
+		byTemp.TotalDeliveredValue += value
+	case outcomeWasted:
+		sm.TotalOrdersWasted++
+		byName.Wasted++
+		byTemp.Wasted++
+	case outcomeExpired:
+		sm.TotalOrdersExpired++
+		byName.Expired++
+		byTemp.Expired++
+	}
+
+	sm.statsByName[o.Name] = byName
+	sm.statsByTemp[o.Temp] = byTemp
+}
+
+// StatsByName returns a copy of the outcome breakdown by item name
+func (sm *InMemoryShelfManager) StatsByName() map[string]ItemStats {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return copyItemStats(sm.statsByName)
+}
+
+// StatsByTemperature returns a copy of the outcome breakdown by temperature
+func (sm *InMemoryShelfManager) StatsByTemperature() map[order.Temperature]ItemStats {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return copyItemStats(sm.statsByTemp)
+}
+
+func copyItemStats[K comparable](src map[K]ItemStats) map[K]ItemStats {
+	stats := make(map[K]ItemStats, len(src))
+	for k, s := range src {
+		stats[k] = s
+	}
+	return stats
+}
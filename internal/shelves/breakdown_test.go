@@ -0,0 +1,46 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_StatsBreakdown(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 0)
+	now := time.Now()
+
+	burger := &order.Order{ID: "1", Name: "Burger", Temp: order.Hot, ShelfLife: 100, DecayRate: 1, PlacedOnShelfAt: now.Add(-50 * time.Second)}
+	extraBurger := &order.Order{ID: "2", Name: "Burger", Temp: order.Hot, ShelfLife: 100, DecayRate: 1}
+	soup := &order.Order{ID: "3", Name: "Soup", Temp: order.Cold, ShelfLife: 1, DecayRate: 1, PlacedOnShelfAt: now.Add(-5 * time.Second)}
+
+	sm.PlaceOrder(burger)
+	sm.PlaceOrder(extraBurger) // no overflow space, wasted
+	sm.PlaceOrder(soup)
+	assert.True(t, sm.DeliverOrder("1"))
+	assert.Equal(t, 1, sm.RemoveExpiredOrders())
+
+	byName := sm.StatsByName()
+	assert.Equal(t, 1, byName["Burger"].Delivered)
+	assert.Equal(t, 1, byName["Burger"].Wasted)
+	assert.InDelta(t, 0.5, byName["Burger"].AverageDeliveredValue(), 0.01)
+	assert.Equal(t, 1, byName["Soup"].Expired)
+	assert.Equal(t, 1, byName["Soup"].Lost())
+
+	byTemp := sm.StatsByTemperature()
+	assert.Equal(t, 1, byTemp[order.Hot].Delivered)
+	assert.Equal(t, 1, byTemp[order.Hot].Wasted)
+	assert.Equal(t, 1, byTemp[order.Cold].Expired)
+
+	stats := sm.GetStats()
+	assert.Contains(t, stats, "byName")
+	assert.Contains(t, stats, "byTemperature")
+}
+
+func TestItemStats_AverageDeliveredValueEmpty(t *testing.T) {
+	assert.Equal(t, 0.0, shelf.ItemStats{}.AverageDeliveredValue())
+}
@@ -0,0 +1,62 @@
+package shelf
+
+import (
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// ShelfCleanup is implemented by managers whose shelves can each be cleaned
+// up on their own cadence, keeping expired orders for a grace period first
+type ShelfCleanup interface {
+	// SetExpiryGrace keeps a shelf's expired orders for grace before they
+	// are removed, whether by sweeps or at their scheduled expiry. Call it
+	// before placing orders.
+	SetExpiryGrace(shelfType ShelfType, grace time.Duration) error
+	// RemoveExpiredOrdersFrom sweeps one shelf for expired orders
+	RemoveExpiredOrdersFrom(shelfType ShelfType) (int, error)
+}
+
+var _ ShelfCleanup = (*InMemoryShelfManager)(nil)
+
+// SetExpiryGrace keeps a shelf's expired orders for grace before removing
+// them. Call it before placing orders.
+func (sm *InMemoryShelfManager) SetExpiryGrace(shelfType ShelfType, grace time.Duration) error {
+	s := sm.GetShelf(shelfType)
+	if s == nil {
+		return fmt.Errorf("unknown shelf %q", shelfType)
+	}
+	if grace < 0 {
+		return fmt.Errorf("shelf %q: expiry grace must not be negative, got %s", shelfType, grace)
+	}
+	s.grace = grace
+	return nil
+}
+
+// RemoveExpiredOrdersFrom sweeps a single shelf for expired orders
+func (sm *InMemoryShelfManager) RemoveExpiredOrdersFrom(shelfType ShelfType) (int, error) {
+	s := sm.GetShelf(shelfType)
+	if s == nil {
+		return 0, fmt.Errorf("unknown shelf %q", shelfType)
+	}
+
+	expired := s.removeExpired()
+	sm.recordExpired(expired)
+	return len(expired), nil
+}
+
+// scheduleExpiry schedules an order's removal from s once it has expired
+// and the shelf's grace period has passed
+func (sm *InMemoryShelfManager) scheduleExpiry(o *order.Order, s *Shelf) {
+	sm.expiries.schedule(o.ID, s.removalTime(o.ExpiresAt()))
+}
+
+// removalTime is when an order expiring at expiresAt is due to be removed,
+// or the zero time if it never expires
+func (s *Shelf) removalTime(expiresAt time.Time) time.Time {
+	if expiresAt.IsZero() {
+		return expiresAt
+	}
+	return expiresAt.Add(s.grace)
+}
@@ -0,0 +1,65 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_ExpiryGraceScheduled(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+	require.NoError(t, sm.SetExpiryGrace(shelf.HotShelf, 5*time.Second))
+
+	o := order.NewOrder("Burger", order.Hot, 10, 1)
+	require.NoError(t, sm.PlaceOrder(o))
+
+	next, ok := sm.NextExpiry()
+	require.True(t, ok)
+	assert.Equal(t, c.Now().Add(15*time.Second), next)
+
+	// Expired but within the grace period
+	assert.Equal(t, 0, sm.RemoveDueOrders(c.Now().Add(12*time.Second)))
+	assert.Equal(t, 1, sm.RemoveDueOrders(next))
+}
+
+func TestShelfManager_RemoveExpiredOrdersFrom(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+	require.NoError(t, sm.SetExpiryGrace(shelf.ColdShelf, 5*time.Second))
+
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 10, 1)))
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 10, 1)))
+	c.Advance(12 * time.Second)
+
+	// Only the swept shelf is cleaned, and the cold order is still in grace
+	expired, err := sm.RemoveExpiredOrdersFrom(shelf.ColdShelf)
+	require.NoError(t, err)
+	assert.Equal(t, 0, expired)
+	expired, err = sm.RemoveExpiredOrdersFrom(shelf.HotShelf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+
+	c.Advance(3 * time.Second)
+	expired, err = sm.RemoveExpiredOrdersFrom(shelf.ColdShelf)
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	assert.Equal(t, 2, sm.GetStats()["totalOrders"].(map[string]interface{})["expired"])
+}
+
+func TestShelfManager_CleanupErrors(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	assert.Error(t, sm.SetExpiryGrace(shelf.ShelfType("sauna"), time.Second))
+	assert.Error(t, sm.SetExpiryGrace(shelf.HotShelf, -time.Second))
+
+	_, err := sm.RemoveExpiredOrdersFrom(shelf.ShelfType("sauna"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package shelf
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// expiryEntry records when a shelved order is due to expire
+type expiryEntry struct {
+	orderID   string
+	expiresAt time.Time
+}
+
+// expiryHeap is a min-heap of expiry entries ordered by expiresAt
+type expiryHeap []expiryEntry
+
+func (h expiryHeap) Len() int           { return len(h) }
+func (h expiryHeap) Less(i, j int) bool { return h[i].expiresAt.Before(h[j].expiresAt) }
+func (h expiryHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *expiryHeap) Push(x any) {
+	*h = append(*h, x.(expiryEntry))
+}
+
+func (h *expiryHeap) Pop() any {
+	old := *h
+	entry := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return entry
+}
+
+// expiryScheduler tracks upcoming expiries so orders can be removed the
+// moment they expire instead of on the next sweep. Entries for orders that
+// leave the shelves early are not removed eagerly; they are discarded when
+// they come due and the order is no longer found.
+type expiryScheduler struct {
+	mutex   sync.Mutex
+	entries expiryHeap
+	updates chan struct{}
+}
+
+func newExpiryScheduler() *expiryScheduler {
+	return &expiryScheduler{updates: make(chan struct{}, 1)}
+}
+
+// schedule adds an entry and signals waiters if it is the new earliest expiry
+func (es *expiryScheduler) schedule(orderID string, expiresAt time.Time) {
+	if expiresAt.IsZero() {
+		return
+	}
+
+	es.mutex.Lock()
+	heap.Push(&es.entries, expiryEntry{orderID: orderID, expiresAt: expiresAt})
+	earliest := es.entries[0].orderID == orderID
+	es.mutex.Unlock()
+
+	if earliest {
+		select {
+		case es.updates <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// next returns the earliest scheduled expiry
+func (es *expiryScheduler) next() (time.Time, bool) {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	if len(es.entries) == 0 {
+		return time.Time{}, false
+	}
+	return es.entries[0].expiresAt, true
+}
+
+// popDue removes and returns the IDs of every order due at or before now
+func (es *expiryScheduler) popDue(now time.Time) []string {
+	es.mutex.Lock()
+	defer es.mutex.Unlock()
+
+	var due []string
+	for len(es.entries) > 0 && !es.entries[0].expiresAt.After(now) {
+		due = append(due, heap.Pop(&es.entries).(expiryEntry).orderID)
+	}
+	return due
+}
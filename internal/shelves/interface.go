@@ -0,0 +1,142 @@
+package shelf
+
+import (
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// ShelfManager is the storage and dispatch strategy the simulator drives.
+// InMemoryShelfManager is the default implementation; alternatives (remote
+// stores, a single priority heap, capacity by volume) only need to satisfy
+// this interface to run under the unchanged simulator and control API.
+type ShelfManager interface {
+	// PlaceOrder shelves a new order, returning a *PlacementError with the
+	// reason if it was rejected
+	PlaceOrder(o *order.Order) error
+	// DeliverOrder removes a shelved order as delivered at once, with no
+	// courier carrying it
+	DeliverOrder(orderID string) bool
+	// TryDeliver removes a shelved order for delivery if it has not
+	// expired, describing it as it left the shelf, in one step so the
+	// order cannot expire or leave the shelf in between. The order is left
+	// in transit for the caller to move to delivered at handoff. An expired
+	// order is removed as expired instead and, like one no longer shelved,
+	// is not delivered. So is one the manager refuses as below its minimum
+	// delivery value, reported with Refused set.
+	TryDeliver(orderID string) (DeliveryResult, bool)
+
+	// GetAllOrders returns every shelved order
+	GetAllOrders() []*order.Order
+	// Query returns the shelved orders matching filter, evaluated at now
+	Query(filter OrderFilter, now time.Time) []*order.Order
+	// ShelfStates describes each shelf for dashboards and snapshots
+	ShelfStates() []ShelfState
+
+	// RemoveExpiredOrders sweeps every shelf for expired orders
+	RemoveExpiredOrders() int
+	// RemoveDueOrders removes orders whose scheduled expiry is at or before now
+	RemoveDueOrders(now time.Time) int
+	// NextExpiry returns the earliest scheduled expiry
+	NextExpiry() (time.Time, bool)
+	// ExpiryUpdates signals when an earlier expiry has been scheduled
+	ExpiryUpdates() <-chan struct{}
+
+	// StartOutage and EndOutage toggle a shelf losing cooling
+	StartOutage(shelfType ShelfType, factor float64) error
+	EndOutage(shelfType ShelfType) error
+
+	// GetStats returns the stats map printed by the simulator reports
+	GetStats() map[string]interface{}
+	// StatsByName and StatsByTemperature break outcomes down by group
+	StatsByName() map[string]ItemStats
+	StatsByTemperature() map[order.Temperature]ItemStats
+}
+
+// FallbackRouter is implemented by managers that can shelve orders of
+// temperatures no shelf accepts on a fallback shelf instead of wasting them
+type FallbackRouter interface {
+	// SetFallbackShelf picks the fallback shelf, or the first overflow
+	// shelf for an empty type
+	SetFallbackShelf(shelfType ShelfType) error
+	// Accepts reports whether any shelf is routed orders of temp
+	Accepts(temp order.Temperature) bool
+}
+
+// StatsResetter is implemented by managers whose counters can be reset
+// while running, so a long-lived service can start a fresh measurement
+type StatsResetter interface {
+	// ResetStats zeroes the run totals, outcome breakdowns and shelf stats.
+	// Orders still shelved are counted as received, so the counters keep
+	// reconciling with the shelves.
+	ResetStats()
+}
+
+// PluggablePlacement is implemented by managers that can rank candidate
+// shelves with a strategy registered with the plugin package
+type PluggablePlacement interface {
+	// SetPlacementStrategy selects the registered strategy by name
+	SetPlacementStrategy(name string) error
+}
+
+// DeliveryResult describes an order as it was collected from its shelf, so
+// everything recording the delivery sees the same numbers
+type DeliveryResult struct {
+	Order   *order.Order
+	Value   float64       // value at pickup
+	Shelf   ShelfType     // shelf it was collected from
+	At      time.Time     // when it was collected
+	OnShelf time.Duration // since it was first shelved
+
+	// Refused is set when the order was worth less than the minimum
+	// delivery value and was wasted rather than delivered
+	Refused bool
+}
+
+// NewDeliveryResult describes an order worth value collected from
+// shelfType at at
+func NewDeliveryResult(o *order.Order, value float64, shelfType ShelfType, at time.Time) DeliveryResult {
+	return DeliveryResult{Order: o, Value: value, Shelf: shelfType, At: at, OnShelf: at.Sub(o.PlacedOnShelfAt)}
+}
+
+// ShelfState describes one shelf and its current contents
+type ShelfState struct {
+	Type       ShelfType
+	Capacity   int
+	Volume     float64 // zero when limited by count only
+	UsedVolume float64
+	Temps      []order.Temperature // empty on overflow shelves
+	InOutage   bool
+	Orders     []*order.Order
+
+	// DecayModifier multiplies the decay of orders held, 0 or 1 for normal
+	// decay
+	DecayModifier float64
+}
+
+var (
+	_ ShelfManager       = (*InMemoryShelfManager)(nil)
+	_ FallbackRouter     = (*InMemoryShelfManager)(nil)
+	_ StatsResetter      = (*InMemoryShelfManager)(nil)
+	_ Scriptable         = (*InMemoryShelfManager)(nil)
+	_ PluggablePlacement = (*InMemoryShelfManager)(nil)
+)
+
+// ShelfStates describes the shelves in layout order
+func (sm *InMemoryShelfManager) ShelfStates() []ShelfState {
+	states := make([]ShelfState, 0, len(sm.shelves))
+	for _, s := range sm.shelves {
+		states = append(states, ShelfState{
+			Type:       s.Type,
+			Capacity:   s.Capacity,
+			Volume:     s.Volume,
+			UsedVolume: s.UsedVolume(),
+			Temps:      s.Temps,
+			InOutage:   s.InOutage(),
+			Orders:     s.GetAllOrders(),
+
+			DecayModifier: s.decayModifier,
+		})
+	}
+	return states
+}
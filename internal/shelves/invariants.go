@@ -0,0 +1,85 @@
+package shelf
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reconciliation compares the run-wide counters against the shelves.
+// Orders are placed synchronously, so nothing is ever queued between being
+// received and being shelved or wasted.
+type Reconciliation struct {
+	Received  int
+	Delivered int
+	Wasted    int
+	Expired   int
+	Shelved   int
+}
+
+// Drift returns how many received orders are unaccounted for. It is
+// negative if more orders are accounted for than were received.
+func (r Reconciliation) Drift() int {
+	return r.Received - r.Delivered - r.Wasted - r.Expired - r.Shelved
+}
+
+// InvariantError lists every invariant a manager was found violating
+type InvariantError struct {
+	Violations []string
+}
+
+func (e *InvariantError) Error() string {
+	return "shelf invariants violated: " + strings.Join(e.Violations, "; ")
+}
+
+// CheckInvariants reconciles the manager's counters with its shelves and
+// checks that no shelf is over capacity and no order is on two shelves.
+// Counters and shelves are read one after the other, so a check racing
+// in-flight operations can report transient drift.
+func CheckInvariants(m ShelfManager) (Reconciliation, error) {
+	var r Reconciliation
+	var violations []string
+
+	totals, _ := m.GetStats()["totalOrders"].(map[string]interface{})
+	r.Received, _ = totals["received"].(int)
+	r.Delivered, _ = totals["delivered"].(int)
+	r.Wasted, _ = totals["wasted"].(int)
+	r.Expired, _ = totals["expired"].(int)
+
+	seen := make(map[string]ShelfType)
+	for _, state := range m.ShelfStates() {
+		r.Shelved += len(state.Orders)
+		countLimited := state.Volume <= 0 || state.Capacity > 0
+		if countLimited && len(state.Orders) > state.Capacity {
+			violations = append(violations,
+				fmt.Sprintf("%s shelf holds %d orders, capacity %d", state.Type, len(state.Orders), state.Capacity))
+		}
+		if state.Volume > 0 {
+			used := 0.0
+			for _, o := range state.Orders {
+				used += o.Volume()
+			}
+			if used > state.Volume+volumeTolerance {
+				violations = append(violations,
+					fmt.Sprintf("%s shelf holds volume %g, capacity %g", state.Type, used, state.Volume))
+			}
+		}
+		for _, o := range state.Orders {
+			if other, ok := seen[o.ID]; ok {
+				violations = append(violations,
+					fmt.Sprintf("order %s is on both the %s and %s shelves", o.ID, other, state.Type))
+			}
+			seen[o.ID] = state.Type
+		}
+	}
+
+	if drift := r.Drift(); drift != 0 {
+		violations = append(violations, fmt.Sprintf(
+			"received %d orders but %d delivered, %d wasted, %d expired and %d shelved (drift %d)",
+			r.Received, r.Delivered, r.Wasted, r.Expired, r.Shelved, drift))
+	}
+
+	if len(violations) > 0 {
+		return r, &InvariantError{Violations: violations}
+	}
+	return r, nil
+}
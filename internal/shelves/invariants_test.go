@@ -0,0 +1,259 @@
+package shelf_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// Operations decoded from fuzz input, one byte each plus an argument byte
+const (
+	opPlace = iota
+	opDeliver
+	opAdvance
+	opRemoveDue
+	opSweep
+	opStartOutage
+	opEndOutage
+	opCount
+)
+
+var fuzzTemps = []order.Temperature{order.Hot, order.Cold, order.Frozen, "ambient"}
+
+var fuzzShelves = []shelf.ShelfType{shelf.HotShelf, shelf.ColdShelf, shelf.FrozenShelf, shelf.OverflowShelf}
+
+// FuzzShelfManager_Invariants replays random sequences of placements,
+// deliveries, clock advances, expiry passes and outages, checking the
+// manager's invariants after every step
+func FuzzShelfManager_Invariants(f *testing.F) {
+	f.Add([]byte{opPlace, 0, opPlace, 0, opPlace, 0, opDeliver, 1, opAdvance, 200, opRemoveDue, 0})
+	f.Add([]byte{opPlace, 1, opStartOutage, 1, opAdvance, 40, opPlace, 1, opEndOutage, 1, opSweep, 0})
+	f.Add([]byte{opPlace, 3, opPlace, 2, opDeliver, 0, opDeliver, 0, opAdvance, 255, opAdvance, 255, opRemoveDue, 0})
+
+	f.Fuzz(func(t *testing.T, ops []byte) {
+		c := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+		sm := shelf.NewShelfManager(2, 2, 2, 3)
+		sm.SetClock(c)
+
+		var placed []*order.Order
+		wasted := make(map[string]bool)
+		delivered := make(map[string]bool)
+
+		for i := 0; i+1 < len(ops); i += 2 {
+			arg := int(ops[i+1])
+			switch ops[i] % opCount {
+			case opPlace:
+				o := order.NewOrder(fmt.Sprintf("item-%d", arg%3), fuzzTemps[arg%len(fuzzTemps)], float64(arg%50), float64(arg%4)/2)
+				if sm.PlaceOrder(o) == nil {
+					placed = append(placed, o)
+				} else {
+					wasted[o.ID] = true
+				}
+			case opDeliver:
+				if len(placed) == 0 {
+					continue
+				}
+				o := placed[arg%len(placed)]
+				ok := sm.DeliverOrder(o.ID)
+				if ok && delivered[o.ID] {
+					t.Fatalf("order %s delivered twice", o.ID)
+				}
+				if ok {
+					delivered[o.ID] = true
+				}
+			case opAdvance:
+				c.Advance(time.Duration(arg) * time.Second)
+			case opRemoveDue:
+				sm.RemoveDueOrders(c.Now())
+				checkNoneExpired(t, sm, c.Now())
+			case opSweep:
+				sm.RemoveExpiredOrders()
+				checkNoneExpired(t, sm, c.Now())
+			case opStartOutage:
+				sm.StartOutage(fuzzShelves[arg%len(fuzzShelves)], 2+float64(arg%3))
+			case opEndOutage:
+				sm.EndOutage(fuzzShelves[arg%len(fuzzShelves)])
+			}
+
+			checkInvariants(t, sm)
+		}
+
+		// Every order ends in exactly one terminal state, or is still shelved
+		shelved := make(map[string]bool)
+		for _, o := range sm.GetAllOrders() {
+			shelved[o.ID] = true
+		}
+		expired := 0
+		for _, o := range placed {
+			if shelved[o.ID] && delivered[o.ID] {
+				t.Fatalf("order %s is shelved after delivery", o.ID)
+			}
+			if !shelved[o.ID] && !delivered[o.ID] {
+				expired++
+				if !o.IsExpired(o.ExpiredAt()) {
+					t.Fatalf("order %s expired at %v with value %v", o.ID, o.ExpiredAt(), o.CalculateValue(o.ExpiredAt()))
+				}
+			}
+		}
+		totals := sm.GetStats()["totalOrders"].(map[string]interface{})
+		if totals["expired"] != expired || totals["delivered"] != len(delivered) || totals["wasted"] != len(wasted) {
+			t.Fatalf("totals %v, want expired=%d delivered=%d wasted=%d", totals, expired, len(delivered), len(wasted))
+		}
+	})
+}
+
+// checkInvariants asserts the properties every manager state must satisfy
+func checkInvariants(t *testing.T, sm *shelf.InMemoryShelfManager) {
+	t.Helper()
+
+	if _, err := shelf.CheckInvariants(sm); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := sm.GetStats()
+	shelved := 0
+	for _, state := range sm.ShelfStates() {
+		if len(state.Orders) > state.Capacity {
+			t.Fatalf("%s shelf holds %d orders, capacity %d", state.Type, len(state.Orders), state.Capacity)
+		}
+		shelved += len(state.Orders)
+
+		shelfStats := stats[string(state.Type)+"Shelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
+		if left := shelfStats.OrdersAdded - shelfStats.OrdersRemoved - shelfStats.OrdersExpired; left != len(state.Orders) {
+			t.Fatalf("%s shelf stats %+v leave %d orders, shelf holds %d", state.Type, shelfStats, left, len(state.Orders))
+		}
+		if shelfStats.PeakUsage > state.Capacity {
+			t.Fatalf("%s shelf peak %d exceeds capacity %d", state.Type, shelfStats.PeakUsage, state.Capacity)
+		}
+		for _, o := range state.Orders {
+			if o.CurrentShelfType != string(state.Type) {
+				t.Fatalf("order on %s shelf records shelf %q", state.Type, o.CurrentShelfType)
+			}
+		}
+	}
+
+	// Counters reconcile: every received order is shelved or terminal
+	totals := stats["totalOrders"].(map[string]interface{})
+	terminal := totals["delivered"].(int) + totals["expired"].(int) + totals["wasted"].(int)
+	if received := totals["received"].(int); received != shelved+terminal {
+		t.Fatalf("received %d orders, but %d shelved and %d terminal", received, shelved, terminal)
+	}
+
+	// Breakdowns sum to the totals
+	for name, breakdown := range map[string][]shelf.ItemStats{
+		"byName":        values(sm.StatsByName()),
+		"byTemperature": values(sm.StatsByTemperature()),
+	} {
+		var sum shelf.ItemStats
+		for _, s := range breakdown {
+			sum.Delivered += s.Delivered
+			sum.Wasted += s.Wasted
+			sum.Expired += s.Expired
+		}
+		if sum.Delivered != totals["delivered"] || sum.Wasted != totals["wasted"] || sum.Expired != totals["expired"] {
+			t.Fatalf("%s sums to %+v, totals are %v", name, sum, totals)
+		}
+	}
+}
+
+// checkNoneExpired asserts an expiry pass at now left no expired order shelved
+func checkNoneExpired(t *testing.T, sm *shelf.InMemoryShelfManager, now time.Time) {
+	t.Helper()
+
+	for _, o := range sm.GetAllOrders() {
+		if o.IsExpired(now) {
+			t.Fatalf("order %s is still shelved after expiring at %v", o.ID, o.ExpiresAt())
+		}
+	}
+}
+
+func values[K comparable](m map[K]shelf.ItemStats) []shelf.ItemStats {
+	out := make([]shelf.ItemStats, 0, len(m))
+	for _, s := range m {
+		out = append(out, s)
+	}
+	return out
+}
+
+// TestShelfManager_ConcurrentInvariants checks the same invariants after
+// random concurrent interleavings of every mutating operation
+func TestShelfManager_ConcurrentInvariants(t *testing.T) {
+	sm := shelf.NewShelfManager(5, 5, 5, 8)
+
+	var wg sync.WaitGroup
+	ids := make(chan string, 1000)
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				switch (w + i) % 5 {
+				case 0, 1:
+					o := order.NewOrder(fmt.Sprintf("item-%d", i%4), fuzzTemps[i%len(fuzzTemps)], 0.05, 1)
+					if sm.PlaceOrder(o) == nil {
+						select {
+						case ids <- o.ID:
+						default:
+						}
+					}
+				case 2:
+					select {
+					case id := <-ids:
+						sm.DeliverOrder(id)
+					default:
+					}
+				case 3:
+					sm.RemoveDueOrders(time.Now())
+				case 4:
+					if i%20 == 4 {
+						sm.StartOutage(shelf.HotShelf, 2)
+					} else if i%20 == 14 {
+						sm.EndOutage(shelf.HotShelf)
+					} else {
+						sm.RemoveExpiredOrders()
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	checkInvariants(t, sm)
+}
+
+func TestCheckInvariants(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	delivered := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	sm.PlaceOrder(delivered)
+	sm.DeliverOrder(delivered.ID)
+	sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5))
+	sm.PlaceOrder(order.NewOrder("Sorbet", "ambient", 300, 0.5))
+
+	r, err := shelf.CheckInvariants(sm)
+	require.NoError(t, err)
+	assert.Equal(t, shelf.Reconciliation{Received: 3, Delivered: 1, Wasted: 1, Shelved: 1}, r)
+}
+
+func TestCheckInvariants_RemovedOutsideManager(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	sm.PlaceOrder(o)
+	sm.GetShelf(shelf.HotShelf).RemoveOrder(o.ID)
+
+	r, err := shelf.CheckInvariants(sm)
+	assert.Equal(t, 1, r.Drift())
+
+	var invariantErr *shelf.InvariantError
+	require.True(t, errors.As(err, &invariantErr))
+	assert.Len(t, invariantErr.Violations, 1)
+	assert.Contains(t, err.Error(), "drift 1")
+}
@@ -0,0 +1,77 @@
+package shelf
+
+import (
+	"errors"
+	"fmt"
+
+	"dish-dispatcher/internal/order"
+)
+
+// ShelfSpec describes one shelf of a manager's layout
+type ShelfSpec struct {
+	Type     ShelfType
+	Capacity int
+
+	// Volume, if positive, limits the total Size of the orders held. A
+	// shelf with a Volume and no Capacity is limited by volume alone.
+	Volume float64
+
+	// Temps are the temperatures routed to this shelf. A shelf with no
+	// temperatures is an overflow shelf: it accepts any order once every
+	// shelf for the order's temperature is full.
+	Temps []order.Temperature
+
+	// DecayModifier multiplies the decay of orders held on the shelf.
+	// Zero means normal decay.
+	DecayModifier float64
+
+	// Shards splits the shelf's contents into independently locked parts,
+	// so looking orders up does not wait for placements. Zero means
+	// DefaultShardCount.
+	Shards int
+}
+
+// IsOverflow reports whether the shelf accepts orders of any temperature
+func (s ShelfSpec) IsOverflow() bool {
+	return len(s.Temps) == 0
+}
+
+// DefaultLayout returns the classic hot, cold, frozen and overflow shelves
+func DefaultLayout(hotCapacity, coldCapacity, frozenCapacity, overflowCapacity int) []ShelfSpec {
+	return []ShelfSpec{
+		{Type: HotShelf, Capacity: hotCapacity, Temps: []order.Temperature{order.Hot}},
+		{Type: ColdShelf, Capacity: coldCapacity, Temps: []order.Temperature{order.Cold}},
+		{Type: FrozenShelf, Capacity: frozenCapacity, Temps: []order.Temperature{order.Frozen}},
+		{Type: OverflowShelf, Capacity: overflowCapacity},
+	}
+}
+
+// ValidateLayout checks that a layout has at least one shelf, unique names
+// and no negative volumes or decay modifiers
+func ValidateLayout(layout []ShelfSpec) error {
+	if len(layout) == 0 {
+		return errors.New("shelf layout is empty")
+	}
+
+	seen := make(map[ShelfType]bool, len(layout))
+	for _, spec := range layout {
+		if spec.Type == "" {
+			return errors.New("shelf name is empty")
+		}
+		if seen[spec.Type] {
+			return fmt.Errorf("duplicate shelf %q", spec.Type)
+		}
+		seen[spec.Type] = true
+
+		if spec.Volume < 0 {
+			return fmt.Errorf("shelf %q: volume must not be negative, got %v", spec.Type, spec.Volume)
+		}
+		if spec.DecayModifier < 0 {
+			return fmt.Errorf("shelf %q: decay modifier must not be negative, got %v", spec.Type, spec.DecayModifier)
+		}
+		if spec.Shards < 0 {
+			return fmt.Errorf("shelf %q: shards must not be negative, got %d", spec.Type, spec.Shards)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestValidateLayout(t *testing.T) {
+	hot := []order.Temperature{order.Hot}
+	tests := []struct {
+		name   string
+		layout []shelf.ShelfSpec
+		valid  bool
+	}{
+		{"default", shelf.DefaultLayout(1, 1, 1, 1), true},
+		{"empty", nil, false},
+		{"unnamed", []shelf.ShelfSpec{{Capacity: 1, Temps: hot}}, false},
+		{"duplicate", []shelf.ShelfSpec{{Type: "hot", Temps: hot}, {Type: "hot"}}, false},
+		{"negative volume", []shelf.ShelfSpec{{Type: "hot", Temps: hot, Volume: -1}}, false},
+		{"negative modifier", []shelf.ShelfSpec{{Type: "hot", Temps: hot, DecayModifier: -1}}, false},
+		{"negative shards", []shelf.ShelfSpec{{Type: "hot", Temps: hot, Shards: -1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := shelf.ValidateLayout(tt.layout)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+
+	_, err := shelf.NewShelfManagerWithLayout(nil)
+	assert.Error(t, err)
+}
+
+func TestShelfManager_LayoutRouting(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "grill", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+		{Type: "oven", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+		{Type: "room", Capacity: 1, Temps: []order.Temperature{order.Hot, order.Cold}},
+		{Type: "spare", Capacity: 1},
+	})
+	require.NoError(t, err)
+
+	var placed []*order.Order
+	for i := 0; i < 4; i++ {
+		o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+		require.NoError(t, sm.PlaceOrder(o))
+		placed = append(placed, o)
+	}
+
+	// Hot orders fill the hot shelves in layout order, then overflow
+	for i, want := range []string{"grill", "oven", "room", "spare"} {
+		assert.Equal(t, want, placed[i].CurrentShelfType)
+	}
+	assert.False(t, placed[2].PlacedOnShelfAt.IsZero())
+	assert.True(t, placed[2].PlacedOnOverflow.IsZero())
+	assert.False(t, placed[3].PlacedOnOverflow.IsZero())
+
+	// Everything a cold order could use is now full
+	assert.Error(t, sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5)))
+	assert.Equal(t, 1, sm.GetShelf("spare").GetStats().OrdersWasted)
+
+	// No shelf takes frozen orders, so they skip overflow entirely
+	assert.Nil(t, sm.GetShelfForTemperature(order.Frozen))
+	assert.Error(t, sm.PlaceOrder(order.NewOrder("Ice Cream", order.Frozen, 300, 0.5)))
+	assert.Equal(t, 1, sm.GetShelf("spare").GetStats().OrdersWasted)
+
+	states := sm.ShelfStates()
+	require.Len(t, states, 4)
+	assert.Equal(t, shelf.ShelfType("grill"), states[0].Type)
+	assert.Empty(t, states[3].Temps)
+	assert.Contains(t, sm.GetStats(), "roomShelf")
+	assert.Len(t, sm.Shelves(), 4)
+}
+
+func TestShelfManager_LayoutWithoutOverflow(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "hot", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5)))
+	assert.Error(t, sm.PlaceOrder(order.NewOrder("Fries", order.Hot, 300, 0.5)))
+	assert.Equal(t, 1, sm.GetShelf("hot").GetStats().OrdersWasted)
+}
+
+func TestShelfManager_DecayModifier(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "hot", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+		{Type: "warm", Capacity: 1, Temps: []order.Temperature{order.Cold}, DecayModifier: 2},
+	})
+	require.NoError(t, err)
+
+	normal := order.NewOrder("Burger", order.Hot, 100, 1)
+	fast := order.NewOrder("Salad", order.Cold, 100, 1)
+	require.NoError(t, sm.PlaceOrder(normal))
+	require.NoError(t, sm.PlaceOrder(fast))
+
+	assert.Empty(t, normal.DecayWindows)
+	require.Len(t, fast.DecayWindows, 1)
+	assert.InDelta(t, 50, fast.ExpiresAt().Sub(fast.PlacedOnShelfAt).Seconds(), 0.1)
+
+	// An outage compounds the modifier, and ending it restores the modifier
+	require.NoError(t, sm.StartOutage("warm", 3))
+	assert.Equal(t, 6.0, fast.DecayWindows[len(fast.DecayWindows)-1].Factor)
+	require.NoError(t, sm.EndOutage("warm"))
+	last := fast.DecayWindows[len(fast.DecayWindows)-1]
+	assert.Equal(t, 2.0, last.Factor)
+	assert.True(t, last.End.IsZero())
+	assert.True(t, fast.ExpiresAt().Before(fast.PlacedOnShelfAt.Add(51*time.Second)))
+}
+
+func TestShelfManager_VolumeCapacity(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "hot", Volume: 4, Temps: []order.Temperature{order.Hot}},
+		{Type: "overflow", Capacity: 5, Volume: 3},
+	})
+	require.NoError(t, err)
+
+	pizza := order.NewOrder("Large Pizza", order.Hot, 300, 0.5)
+	pizza.Size = 3
+	soda := order.NewOrder("Soda", order.Hot, 300, 0.5)
+	soda.Size = 0.5
+	require.NoError(t, sm.PlaceOrder(pizza))
+	require.NoError(t, sm.PlaceOrder(soda))
+	assert.Equal(t, "hot", soda.CurrentShelfType)
+
+	// A second pizza no longer fits on the hot shelf, so it overflows
+	second := order.NewOrder("Large Pizza", order.Hot, 300, 0.5)
+	second.Size = 3
+	require.NoError(t, sm.PlaceOrder(second))
+	assert.Equal(t, "overflow", second.CurrentShelfType)
+
+	// Unsized orders take one unit: 0.5 is left on hot and none on overflow
+	assert.Error(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5)))
+
+	hot := sm.GetShelf("hot")
+	assert.InDelta(t, 3.5, hot.UsedVolume(), 1e-9)
+	assert.True(t, hot.IsFull())
+
+	// Delivering the pizza frees its space
+	require.True(t, sm.DeliverOrder(pizza.ID))
+	assert.InDelta(t, 0.5, hot.UsedVolume(), 1e-9)
+	assert.NoError(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5)))
+
+	states := sm.ShelfStates()
+	assert.Equal(t, 4.0, states[0].Volume)
+	assert.InDelta(t, 1.5, states[0].UsedVolume, 1e-9)
+
+	_, err = shelf.CheckInvariants(sm)
+	assert.NoError(t, err)
+}
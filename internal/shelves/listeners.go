@@ -0,0 +1,165 @@
+package shelf
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"dish-dispatcher/internal/order"
+)
+
+// ShelfListener holds callbacks for changes to one shelf. Any may be nil.
+// They run after the shelf's lock is released, so they may read the shelf,
+// but on whichever goroutine made the change: listeners watching a busy
+// shelf must be safe for concurrent use and quick to return.
+type ShelfListener struct {
+	// OnAdd is called when an order is placed on or moved to the shelf
+	OnAdd func(o *order.Order)
+	// OnRemove is called when an order leaves the shelf for any reason:
+	// delivered, expired, wasted at pickup or moved elsewhere
+	OnRemove func(o *order.Order)
+	// OnFullnessChange is called after every add or remove with how full
+	// the shelf is, from 0 for empty to 1 for no room left
+	OnFullnessChange func(fullness float64)
+}
+
+// ShelfWatcher is implemented by managers whose shelves can each be
+// watched, so a visualization or policy interested in one shelf need not
+// filter the events of them all
+type ShelfWatcher interface {
+	// WatchShelf registers a listener on a shelf and returns a function
+	// that unregisters it
+	WatchShelf(shelfType ShelfType, l ShelfListener) (unwatch func(), err error)
+}
+
+var _ ShelfWatcher = (*InMemoryShelfManager)(nil)
+
+// WatchShelf registers a listener on a shelf and returns a function that
+// unregisters it
+func (sm *InMemoryShelfManager) WatchShelf(shelfType ShelfType, l ShelfListener) (func(), error) {
+	s := sm.GetShelf(shelfType)
+	if s == nil {
+		return nil, fmt.Errorf("unknown shelf %q", shelfType)
+	}
+	return s.listeners.add(l), nil
+}
+
+// shelfChange is an order added to or removed from a shelf, and the
+// shelf's fullness after it
+type shelfChange struct {
+	order    *order.Order
+	added    bool
+	fullness float64
+}
+
+// shelfListeners are the listeners registered on one shelf. The zero value
+// is ready to use.
+type shelfListeners struct {
+	mutex     sync.Mutex
+	listeners map[int]ShelfListener
+	nextID    int
+	count     atomic.Int32 // so unwatched shelves skip recording changes
+}
+
+// add registers a listener and returns a function that unregisters it
+func (sl *shelfListeners) add(l ShelfListener) func() {
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+
+	if sl.listeners == nil {
+		sl.listeners = make(map[int]ShelfListener)
+	}
+	id := sl.nextID
+	sl.nextID++
+	sl.listeners[id] = l
+	sl.count.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			sl.mutex.Lock()
+			defer sl.mutex.Unlock()
+
+			delete(sl.listeners, id)
+			sl.count.Add(-1)
+		})
+	}
+}
+
+// notify calls the listeners for each change, in order
+func (sl *shelfListeners) notify(changes []shelfChange) {
+	sl.mutex.Lock()
+	listeners := make([]ShelfListener, 0, len(sl.listeners))
+	for _, l := range sl.listeners {
+		listeners = append(listeners, l)
+	}
+	sl.mutex.Unlock()
+
+	for _, c := range changes {
+		for _, l := range listeners {
+			if c.added && l.OnAdd != nil {
+				l.OnAdd(c.order)
+			}
+			if !c.added && l.OnRemove != nil {
+				l.OnRemove(c.order)
+			}
+			if l.OnFullnessChange != nil {
+				l.OnFullnessChange(c.fullness)
+			}
+		}
+	}
+}
+
+// recordChange notes an order added to or removed from the shelf for its
+// listeners. Callers must hold the shelf lock and release it with unlock.
+func (s *Shelf) recordChange(o *order.Order, added bool) {
+	if s.listeners.count.Load() == 0 {
+		return
+	}
+	s.changes = append(s.changes, shelfChange{order: o, added: added, fullness: s.fullness()})
+}
+
+// unlock releases the shelf lock, then tells the listeners of the changes
+// made while it was held
+func (s *Shelf) unlock() {
+	changes := s.changes
+	s.changes = nil
+	s.mutex.Unlock()
+
+	if len(changes) > 0 {
+		s.listeners.notify(changes)
+	}
+}
+
+// unlockAll releases the locks of shelves changed together, then tells
+// their listeners of the changes, shelf by shelf in the order given
+func unlockAll(shelves ...*Shelf) {
+	changes := make([][]shelfChange, len(shelves))
+	for i, s := range shelves {
+		changes[i] = s.changes
+		s.changes = nil
+		s.mutex.Unlock()
+	}
+
+	for i, s := range shelves {
+		if len(changes[i]) > 0 {
+			s.listeners.notify(changes[i])
+		}
+	}
+}
+
+// fullness returns how full the shelf is, by count or by volume, whichever
+// is closer to the limit. Callers must hold the shelf lock.
+func (s *Shelf) fullness() float64 {
+	full := 0.0
+	if s.Capacity > 0 {
+		full = float64(s.orders.len()) / float64(s.Capacity)
+	}
+	if s.Volume > 0 {
+		full = max(full, s.used/s.Volume)
+	}
+	if s.Capacity <= 0 && s.Volume <= 0 {
+		return 1
+	}
+	return min(full, 1)
+}
@@ -0,0 +1,95 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// shelfLog records what a listener was told
+type shelfLog struct {
+	added, removed []string
+	fullness       []float64
+}
+
+func (l *shelfLog) listener() shelf.ShelfListener {
+	return shelf.ShelfListener{
+		OnAdd:            func(o *order.Order) { l.added = append(l.added, o.Name) },
+		OnRemove:         func(o *order.Order) { l.removed = append(l.removed, o.Name) },
+		OnFullnessChange: func(fullness float64) { l.fullness = append(l.fullness, fullness) },
+	}
+}
+
+func TestShelfManager_WatchShelf(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	var hot, cold shelfLog
+	_, err := sm.WatchShelf(shelf.HotShelf, hot.listener())
+	require.NoError(t, err)
+	_, err = sm.WatchShelf(shelf.ColdShelf, cold.listener())
+	require.NoError(t, err)
+
+	burger := order.NewOrder("Burger", order.Hot, 100, 1)
+	fries := order.NewOrder("Fries", order.Hot, 2, 1)
+	require.NoError(t, sm.PlaceOrder(burger))
+	require.NoError(t, sm.PlaceOrder(fries))
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 100, 1)))
+	c.Advance(3 * time.Second)
+
+	assert.Equal(t, 1, sm.RemoveExpiredOrders())
+	_, ok := sm.TryDeliver(burger.ID)
+	assert.True(t, ok)
+
+	assert.Equal(t, []string{"Burger", "Fries"}, hot.added)
+	assert.Equal(t, []string{"Fries", "Burger"}, hot.removed)
+	assert.Equal(t, []float64{0.5, 1, 0.5, 0}, hot.fullness)
+	assert.Equal(t, []string{"Salad"}, cold.added)
+	assert.Empty(t, cold.removed)
+
+	_, err = sm.WatchShelf("pantry", shelf.ShelfListener{})
+	assert.Error(t, err)
+}
+
+func TestShelfManager_WatchShelfMove(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+
+	var hot, overflow shelfLog
+	_, err := sm.WatchShelf(shelf.HotShelf, hot.listener())
+	require.NoError(t, err)
+	_, err = sm.WatchShelf(shelf.OverflowShelf, overflow.listener())
+	require.NoError(t, err)
+
+	o := order.NewOrder("Burger", order.Hot, 100, 1)
+	require.NoError(t, sm.PlaceOrder(o))
+	require.NoError(t, sm.MoveOrder(o.ID, shelf.OverflowShelf))
+
+	assert.Equal(t, []string{"Burger"}, hot.removed)
+	assert.Equal(t, []string{"Burger"}, overflow.added)
+	assert.Equal(t, []float64{1}, overflow.fullness)
+}
+
+func TestShelfManager_Unwatch(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+
+	// Listeners may read the shelf they are told about
+	var sizes []int
+	unwatch, err := sm.WatchShelf(shelf.HotShelf, shelf.ShelfListener{
+		OnAdd: func(*order.Order) { sizes = append(sizes, sm.GetShelf(shelf.HotShelf).Size()) },
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 100, 1)))
+	unwatch()
+	unwatch()
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Fries", order.Hot, 100, 1)))
+
+	assert.Equal(t, []int{1}, sizes)
+}
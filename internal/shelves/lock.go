@@ -0,0 +1,75 @@
+package shelf
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LockStats reports how often a lock was taken and how long callers waited
+type LockStats struct {
+	Acquisitions int64
+	Contended    int64 // acquisitions that had to wait for another holder
+	TotalWait    time.Duration
+	MaxWait      time.Duration
+}
+
+// instrumentedRWMutex is a sync.RWMutex that records wait time. Uncontended
+// acquisitions take the TryLock fast path and are never timed.
+type instrumentedRWMutex struct {
+	mu           sync.RWMutex
+	acquisitions atomic.Int64
+	contended    atomic.Int64
+	totalWait    atomic.Int64
+	maxWait      atomic.Int64
+}
+
+func (m *instrumentedRWMutex) Lock() {
+	if m.mu.TryLock() {
+		m.acquisitions.Add(1)
+		return
+	}
+	start := time.Now()
+	m.mu.Lock()
+	m.recordWait(time.Since(start))
+}
+
+func (m *instrumentedRWMutex) Unlock() {
+	m.mu.Unlock()
+}
+
+func (m *instrumentedRWMutex) RLock() {
+	if m.mu.TryRLock() {
+		m.acquisitions.Add(1)
+		return
+	}
+	start := time.Now()
+	m.mu.RLock()
+	m.recordWait(time.Since(start))
+}
+
+func (m *instrumentedRWMutex) RUnlock() {
+	m.mu.RUnlock()
+}
+
+func (m *instrumentedRWMutex) recordWait(wait time.Duration) {
+	m.acquisitions.Add(1)
+	m.contended.Add(1)
+	m.totalWait.Add(int64(wait))
+	for {
+		max := m.maxWait.Load()
+		if int64(wait) <= max || m.maxWait.CompareAndSwap(max, int64(wait)) {
+			return
+		}
+	}
+}
+
+// Stats returns a snapshot of the lock's contention counters
+func (m *instrumentedRWMutex) Stats() LockStats {
+	return LockStats{
+		Acquisitions: m.acquisitions.Load(),
+		Contended:    m.contended.Load(),
+		TotalWait:    time.Duration(m.totalWait.Load()),
+		MaxWait:      time.Duration(m.maxWait.Load()),
+	}
+}
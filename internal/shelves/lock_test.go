@@ -0,0 +1,43 @@
+package shelf_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelf_LockStats(t *testing.T) {
+	s := shelf.NewShelf(shelf.HotShelf, 100)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.AddOrder(order.NewOrder("Burger", order.Hot, 300, 0.5))
+				s.IsFull()
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats := s.LockStats()
+	assert.GreaterOrEqual(t, stats.Acquisitions, int64(800))
+	assert.LessOrEqual(t, stats.Contended, stats.Acquisitions)
+	assert.LessOrEqual(t, stats.MaxWait, stats.TotalWait)
+}
+
+func TestShelfManager_GetStatsIncludesLockStats(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	sm.PlaceOrder(&order.Order{ID: "1", Temp: order.Hot})
+
+	stats := sm.GetStats()
+	assert.IsType(t, shelf.LockStats{}, stats["managerLockStats"])
+	hot := stats["hotShelf"].(map[string]interface{})
+	assert.Greater(t, hot["lockStats"].(shelf.LockStats).Acquisitions, int64(0))
+}
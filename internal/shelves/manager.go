@@ -1,90 +1,408 @@
 package shelf
 
 import (
-	"sync"
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
+	"dish-dispatcher/internal/clock"
 	"dish-dispatcher/internal/order"
+	"dish-dispatcher/plugin"
 )
 
-type ShelfManager struct {
-	HotShelf      *Shelf
-	ColdShelf     *Shelf
-	FrozenShelf   *Shelf
-	OverflowShelf *Shelf
-	mutex         sync.Mutex
+// InMemoryShelfManager routes orders to the shelves and keeps the run-wide counters.
+//
+// Locking invariants:
+//   - mutex guards only the Total* counters and outcome breakdowns below.
+//   - the orderID -> shelf index is sharded, each shard with its own lock.
+//   - expiries has its own internal lock.
+//   - Neither lock is held while calling into a Shelf. Each shelf serializes
+//     its own state, so the manager takes the shelf lock and its own lock
+//     one after the other, never nested. This rules out lock-order cycles
+//     between the manager and its shelves.
+type InMemoryShelfManager struct {
+	// shelves, byType, routes and overflow describe the layout. They are
+	// fixed at construction, so reading them needs no lock.
+	shelves  []*Shelf
+	byType   map[ShelfType]*Shelf
+	routes   map[order.Temperature][]*Shelf
+	overflow []*Shelf
+
+	// fallback, if set, takes orders of temperatures no shelf accepts. It
+	// is set before any order is placed.
+	fallback *Shelf
+
+	// placement, if set, replaces the layout order of candidate shelves,
+	// and evictionScript wasting orders when they are all full. They are
+	// set before any order is placed.
+	placement      plugin.PlacementStrategy
+	evictionScript *order.Expression
+
+	mutex instrumentedRWMutex
+
+	// index maps the ID of every shelved order to the shelf holding it
+	index *shardedMap[*Shelf]
+
+	expiries *expiryScheduler
+	clock    clock.Clock
 
 	TotalOrdersReceived  int
 	TotalOrdersDelivered int
 	TotalOrdersExpired   int
 	TotalOrdersWasted    int
+	TotalOrdersEvicted   int // discarded by the eviction script, counted as expired too
+	TotalOrdersRefused   int // wasted at pickup below the minimum delivery value, counted as wasted too
+	TotalOrdersMoved     int // moved between shelves by MoveOrder
+
+	// minValue is the least an order may be worth at pickup
+	minValue float64
+
+	statsByName map[string]ItemStats
+	statsByTemp map[order.Temperature]ItemStats
+	rejections  map[RejectReason]int
+}
+
+// NewShelfManager creates the default in-memory ShelfManager with the
+// classic hot, cold, frozen and overflow shelves
+func NewShelfManager(hotCapacity, coldCapacity, frozenCapacity, overflowCapacity int) *InMemoryShelfManager {
+	// The default layout is always valid
+	sm, _ := NewShelfManagerWithLayout(DefaultLayout(hotCapacity, coldCapacity, frozenCapacity, overflowCapacity))
+	return sm
 }
 
-func NewShelfManager(hotCapacity, coldCapacity, frozenCapacity, overflowCapacity int) *ShelfManager {
-	return &ShelfManager{
-		HotShelf:      NewShelf(HotShelf, hotCapacity),
-		ColdShelf:     NewShelf(ColdShelf, coldCapacity),
-		FrozenShelf:   NewShelf(FrozenShelf, frozenCapacity),
-		OverflowShelf: NewShelf(OverflowShelf, overflowCapacity),
+// NewShelfManagerWithLayout creates an in-memory ShelfManager over an
+// arbitrary list of shelves. Orders try the shelves for their temperature in
+// layout order, then the overflow shelves in layout order.
+func NewShelfManagerWithLayout(layout []ShelfSpec) (*InMemoryShelfManager, error) {
+	if err := ValidateLayout(layout); err != nil {
+		return nil, err
+	}
+
+	sm := &InMemoryShelfManager{
+		byType:      make(map[ShelfType]*Shelf, len(layout)),
+		routes:      make(map[order.Temperature][]*Shelf),
+		index:       newShardedMap[*Shelf](DefaultShardCount),
+		expiries:    newExpiryScheduler(),
+		clock:       clock.Real{},
+		statsByName: make(map[string]ItemStats),
+		statsByTemp: make(map[order.Temperature]ItemStats),
+		rejections:  make(map[RejectReason]int),
 	}
+	for _, spec := range layout {
+		s := newShelf(spec)
+		sm.shelves = append(sm.shelves, s)
+		sm.byType[s.Type] = s
+		if spec.IsOverflow() {
+			sm.overflow = append(sm.overflow, s)
+		}
+		for _, temp := range spec.Temps {
+			sm.routes[temp] = append(sm.routes[temp], s)
+		}
+	}
+	return sm, nil
+}
+
+// SetClock replaces the wall clock used for placement, delivery and expiry
+// times, on the manager and all its shelves. Call it before placing orders.
+func (sm *InMemoryShelfManager) SetClock(c clock.Clock) {
+	sm.clock = c
+	for _, s := range sm.shelves {
+		s.clock = c
+	}
+}
+
+// Shelves returns the manager's shelves in layout order
+func (sm *InMemoryShelfManager) Shelves() []*Shelf {
+	return append([]*Shelf(nil), sm.shelves...)
+}
+
+// GetShelf returns the shelf of the given type, or nil if there is none
+func (sm *InMemoryShelfManager) GetShelf(shelfType ShelfType) *Shelf {
+	return sm.byType[shelfType]
 }
 
-func (sm *ShelfManager) GetShelfForTemperature(temp order.Temperature) *Shelf {
-	switch temp {
-	case order.Hot:
-		return sm.HotShelf
-	case order.Cold:
-		return sm.ColdShelf
-	case order.Frozen:
-		return sm.FrozenShelf
-	default:
+// GetShelfForTemperature returns the first shelf for temp, or nil if no
+// shelf in the layout accepts it
+func (sm *InMemoryShelfManager) GetShelfForTemperature(temp order.Temperature) *Shelf {
+	if shelves := sm.routes[temp]; len(shelves) > 0 {
+		return shelves[0]
+	}
+	return nil
+}
+
+// SetFallbackShelf routes orders of temperatures no shelf accepts to the
+// given shelf, then to overflow, instead of wasting them. An empty type
+// picks the first overflow shelf. Call it before placing orders.
+func (sm *InMemoryShelfManager) SetFallbackShelf(shelfType ShelfType) error {
+	if shelfType == "" {
+		if len(sm.overflow) == 0 {
+			return errors.New("no overflow shelf to use as the fallback")
+		}
+		sm.fallback = sm.overflow[0]
 		return nil
+	}
+
+	s, ok := sm.byType[shelfType]
+	if !ok {
+		return fmt.Errorf("unknown fallback shelf %q", shelfType)
+	}
+	sm.fallback = s
+	return nil
+}
+
+// Accepts reports whether any shelf is routed orders of temp, ignoring the
+// fallback shelf
+func (sm *InMemoryShelfManager) Accepts(temp order.Temperature) bool {
+	return len(sm.routes[temp]) > 0
+}
+
+// candidates returns the shelves an order may be placed on, in the order
+// they are tried. Temperatures no shelf accepts have none, not even
+// overflow, unless a fallback shelf is set.
+func (sm *InMemoryShelfManager) candidates(temp order.Temperature) []*Shelf {
+	primary := sm.routes[temp]
+	if len(primary) == 0 {
+		if sm.fallback == nil {
+			return nil
+		}
+		primary = []*Shelf{sm.fallback}
+	}
+
+	shelves := append(make([]*Shelf, 0, len(primary)+len(sm.overflow)), primary...)
+	for _, s := range sm.overflow {
+		if !slices.Contains(primary, s) {
+			shelves = append(shelves, s)
+		}
+	}
+	return shelves
+}
+
+// PlaceOrder shelves a new order on the first candidate shelf with room. It
+// returns a *PlacementError if the order was not shelved.
+func (sm *InMemoryShelfManager) PlaceOrder(o *order.Order) error {
+	// Only new orders can be placed; anything else is already on a shelf
+	// or finished
+	if o.State() != order.StateCreated {
+		sm.recordRejection(RejectNotNew)
+		return &PlacementError{OrderID: o.ID, Reason: RejectNotNew}
+	}
+
+	sm.addCounter(&sm.TotalOrdersReceived, 1)
 
+	shelves := sm.candidates(o.Temp)
+	if len(shelves) == 0 {
+		return sm.wasteOrder(o, RejectInvalidTemperature)
 	}
+	if sm.placement != nil {
+		sm.rankShelves(o, shelves)
+	}
+
+	// Index before shelving: a concurrent sweep may expire the order as soon
+	// as it is on the shelf, and its unindex must not run before our index
+	for _, s := range shelves {
+		sm.indexOrder(o.ID, s)
+		if s.AddOrder(o) {
+			sm.scheduleExpiry(o, s)
+			return nil
+		}
+	}
+	if sm.evictionScript != nil {
+		if s := sm.evictFor(o, shelves); s != nil {
+			sm.indexOrder(o.ID, s)
+			if s.AddOrder(o) {
+				sm.scheduleExpiry(o, s)
+				return nil
+			}
+		}
+	}
+	sm.unindexOrder(o.ID)
+
+	// The waste is counted against the last shelf tried
+	shelves[len(shelves)-1].recordWaste()
+	return sm.wasteOrder(o, fullReason(len(sm.overflow) > 0))
 }
 
-func (sm *ShelfManager) PlaceOrder(order *order.Order) bool {
+// wasteOrder records a new order that could not be shelved and returns the
+// rejection
+func (sm *InMemoryShelfManager) wasteOrder(o *order.Order, reason RejectReason) error {
+	now := sm.clock.Now()
+	o.Transition(order.StateWasted, now)
+	sm.recordOutcome(o, outcomeWasted, now)
+	sm.recordRejection(reason)
+	return &PlacementError{OrderID: o.ID, Reason: reason}
+}
+
+// recordRejection counts a rejected placement by reason
+func (sm *InMemoryShelfManager) recordRejection(reason RejectReason) {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
-	sm.TotalOrdersReceived++
+	sm.rejections[reason]++
+}
+
+// Rejections returns a copy of the rejected placement counts by reason
+func (sm *InMemoryShelfManager) Rejections() map[RejectReason]int {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return copyRejections(sm.rejections)
+}
 
-	primaryShelf := sm.GetShelfForTemperature(order.Temp)
-	if primaryShelf == nil {
-		sm.TotalOrdersWasted++
+func copyRejections(src map[RejectReason]int) map[RejectReason]int {
+	rejections := make(map[RejectReason]int, len(src))
+	for reason, n := range src {
+		rejections[reason] = n
+	}
+	return rejections
+}
+
+func (sm *InMemoryShelfManager) DeliverOrder(orderID string) bool {
+	order, shelf := sm.LocateOrder(orderID)
+	if order == nil || !shelf.MarkOrderDelivered(orderID) {
 		return false
 	}
-	if primaryShelf.AddOrder(order) {
-		return true
+
+	sm.unindexOrder(orderID)
+	sm.recordOutcome(order, outcomeDelivered, order.DeliveredAt())
+	return true
+}
+
+// TryDeliver removes a shelved order as delivered and describes it, or
+// removes it as expired if it has no value left. An order worth less than
+// the minimum delivery value is refused and wasted instead.
+func (sm *InMemoryShelfManager) TryDeliver(orderID string) (DeliveryResult, bool) {
+	_, shelf := sm.LocateOrder(orderID)
+	if shelf == nil {
+		return DeliveryResult{}, false
+	}
+
+	o, value, result := shelf.tryDeliver(orderID, sm.minValue)
+	if o == nil {
+		return DeliveryResult{}, false
 	}
-	if sm.OverflowShelf.AddOrder(order) {
-		order.PlacedOnOverflow = time.Now()
-		return true
+	sm.unindexOrder(orderID)
+	switch result {
+	case outcomeExpired:
+		sm.recordOutcome(o, outcomeExpired, o.ExpiredAt())
+		return DeliveryResult{}, false
+	case outcomeWasted:
+		at := sm.clock.Now()
+		sm.recordOutcome(o, outcomeWasted, at)
+		sm.addCounter(&sm.TotalOrdersRefused, 1)
+		refused := NewDeliveryResult(o, value, shelf.Type, at)
+		refused.Refused = true
+		return refused, false
 	}
-	sm.TotalOrdersWasted++
-	order.WastedAt = time.Now()
-	return false
+	at := o.StateChangedAt()
+	sm.recordOutcome(o, outcomeDelivered, at)
+	return NewDeliveryResult(o, value, shelf.Type, at), true
 }
 
-func (sm *ShelfManager) DeliverOrder(orderID string) bool {
-	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
+// LocateOrder returns a shelved order and the shelf holding it, or nils if
+// the order is not on any shelf
+func (sm *InMemoryShelfManager) LocateOrder(orderID string) (*order.Order, *Shelf) {
+	shelf := sm.lookupShelf(orderID)
+	if shelf == nil {
+		return nil, nil
+	}
 
-	// Try to find and deliver the order from any shelf
-	if sm.deliverFromShelf(sm.HotShelf, orderID) ||
-		sm.deliverFromShelf(sm.ColdShelf, orderID) ||
-		sm.deliverFromShelf(sm.FrozenShelf, orderID) ||
-		sm.deliverFromShelf(sm.OverflowShelf, orderID) {
-		sm.TotalOrdersDelivered++
-		return true
+	order := shelf.GetOrder(orderID)
+	if order == nil {
+		return nil, nil
 	}
+	return order, shelf
+}
 
-	return false
+// NextExpiry returns when the next shelved order is scheduled to expire
+func (sm *InMemoryShelfManager) NextExpiry() (time.Time, bool) {
+	return sm.expiries.next()
 }
 
-func (sm *ShelfManager) deliverFromShelf(shelf *Shelf, orderID string) bool {
-	if order := shelf.GetOrder(orderID); order != nil {
-		return shelf.MarkOrderDelivered(orderID)
+// ExpiryUpdates signals whenever a placement schedules an expiry earlier
+// than any already pending, so waiters can re-read NextExpiry
+func (sm *InMemoryShelfManager) ExpiryUpdates() <-chan struct{} {
+	return sm.expiries.updates
+}
+
+// RemoveDueOrders removes every order whose scheduled expiry is at or before
+// now. It is the scheduled alternative to the RemoveExpiredOrders sweep.
+func (sm *InMemoryShelfManager) RemoveDueOrders(now time.Time) int {
+	expired := make([]string, 0)
+	for _, id := range sm.expiries.popDue(now) {
+		order, shelf := sm.LocateOrder(id)
+		if order == nil {
+			continue
+		}
+
+		// The entry may be stale if the order's decay changed since it was
+		// scheduled, so check against its current expiry
+		removeAt := shelf.removalTime(order.ExpiresAt())
+		if removeAt.IsZero() {
+			continue
+		}
+		if reserved := shelf.reservation(id); reserved.After(removeAt) {
+			removeAt = reserved
+		}
+		if now.Before(removeAt) {
+			sm.expiries.schedule(id, removeAt)
+			continue
+		}
+		if shelf.expireOrder(id, now) {
+			expired = append(expired, id)
+			sm.recordOutcome(order, outcomeExpired, now)
+		} else if reserved := shelf.reservation(id); !reserved.IsZero() {
+			// Reserved since it was checked
+			sm.expiries.schedule(id, reserved)
+		}
 	}
-	return false
+
+	sm.unindexOrder(expired...)
+	return len(expired)
+}
+
+func (sm *InMemoryShelfManager) lookupShelf(orderID string) *Shelf {
+	shelf, _ := sm.index.get(orderID)
+	return shelf
+}
+
+func (sm *InMemoryShelfManager) indexOrder(orderID string, shelf *Shelf) {
+	sm.index.put(orderID, shelf)
+}
+
+func (sm *InMemoryShelfManager) unindexOrder(orderIDs ...string) {
+	for _, id := range orderIDs {
+		sm.index.remove(id)
+	}
+}
+
+// ResetStats zeroes the run totals, breakdowns and shelf stats. Orders still
+// shelved are counted as received. Lock stats are kept.
+func (sm *InMemoryShelfManager) ResetStats() {
+	shelved := 0
+	for _, s := range sm.shelves {
+		shelved += s.resetStats()
+	}
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	sm.TotalOrdersReceived = shelved
+	sm.TotalOrdersDelivered = 0
+	sm.TotalOrdersExpired = 0
+	sm.TotalOrdersWasted = 0
+	sm.TotalOrdersEvicted = 0
+	sm.TotalOrdersRefused = 0
+	sm.TotalOrdersMoved = 0
+	sm.statsByName = make(map[string]ItemStats)
+	sm.statsByTemp = make(map[order.Temperature]ItemStats)
+	sm.rejections = make(map[RejectReason]int)
+}
+
+// addCounter increments one of the Total* counters under the manager lock
+func (sm *InMemoryShelfManager) addCounter(counter *int, delta int) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	*counter += delta
 }
@@ -0,0 +1,105 @@
+package shelf_test
+
+import (
+	"fmt"
+	"testing"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+var benchShelfSizes = []int{10, 100, 1000, 10000}
+
+var benchTemps = []order.Temperature{order.Hot, order.Cold, order.Frozen}
+
+// newFilledManager returns a manager whose temperature shelves each hold size
+// orders, along with the IDs of every order placed
+func newFilledManager(size int) (*shelf.InMemoryShelfManager, []string) {
+	sm := shelf.NewShelfManager(size, size, size, size)
+	ids := make([]string, 0, size*len(benchTemps))
+	for i := 0; i < size; i++ {
+		for _, temp := range benchTemps {
+			id := fmt.Sprintf("%s-%d", temp, i)
+			sm.PlaceOrder(&order.Order{ID: id, Temp: temp, ShelfLife: 300, DecayRate: 0.5})
+			ids = append(ids, id)
+		}
+	}
+	return sm, ids
+}
+
+func BenchmarkShelfManager_PlaceOrder(b *testing.B) {
+	for _, size := range benchShelfSizes {
+		b.Run(fmt.Sprintf("shelf=%d", size), func(b *testing.B) {
+			sm, ids := newFilledManager(size)
+			// Free one slot per temperature so each iteration places then delivers
+			for _, id := range ids[:len(benchTemps)] {
+				sm.DeliverOrder(id)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				id := fmt.Sprintf("bench-%d", i)
+				sm.PlaceOrder(&order.Order{ID: id, Temp: benchTemps[i%len(benchTemps)], ShelfLife: 300})
+				sm.DeliverOrder(id)
+			}
+		})
+	}
+}
+
+func BenchmarkShelfManager_DeliverOrder(b *testing.B) {
+	for _, size := range benchShelfSizes {
+		b.Run(fmt.Sprintf("shelf=%d", size), func(b *testing.B) {
+			sm, _ := newFilledManager(size)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Misses walk every shelf, which is the worst case
+				sm.DeliverOrder("missing")
+			}
+		})
+	}
+}
+
+func BenchmarkShelfManager_RemoveExpiredOrders(b *testing.B) {
+	for _, size := range benchShelfSizes {
+		b.Run(fmt.Sprintf("shelf=%d", size), func(b *testing.B) {
+			sm, _ := newFilledManager(size)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Nothing has expired, so every order is checked and kept
+				sm.RemoveExpiredOrders()
+			}
+		})
+	}
+}
+
+func BenchmarkShelfManager_GetAllOrders(b *testing.B) {
+	for _, size := range benchShelfSizes {
+		b.Run(fmt.Sprintf("shelf=%d", size), func(b *testing.B) {
+			sm, _ := newFilledManager(size)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sm.GetAllOrders()
+			}
+		})
+	}
+}
+
+func BenchmarkShelfManager_PlaceOrders(b *testing.B) {
+	const batch = 10
+	sm := shelf.NewShelfManager(batch, batch, batch, batch)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		orders := make([]*order.Order, batch)
+		for j := range orders {
+			orders[j] = &order.Order{ID: fmt.Sprintf("bench-%d-%d", i, j), Temp: benchTemps[j%len(benchTemps)], ShelfLife: 300}
+		}
+		sm.PlaceOrders(orders, shelf.BatchAllOrNothing)
+		for _, o := range orders {
+			sm.DeliverOrder(o.ID)
+		}
+	}
+}
@@ -1,10 +1,15 @@
 package shelf_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"dish-dispatcher/internal/clock"
 	"dish-dispatcher/internal/order"
 	shelf "dish-dispatcher/internal/shelves"
 )
@@ -17,18 +22,36 @@ func TestShelfManager_PlaceOrder(t *testing.T) {
 	order4 := &order.Order{ID: "4", Temp: order.Hot}
 	order5 := &order.Order{ID: "5", Temp: order.Hot}
 
-	assert.True(t, sm.PlaceOrder(order1))
-	assert.True(t, sm.PlaceOrder(order2))
-	assert.True(t, sm.PlaceOrder(order3))
-	assert.True(t, sm.PlaceOrder(order4)) // Should go to overflow
-	assert.True(t, sm.PlaceOrder(order5)) // Should also go to overflow
+	assert.NoError(t, sm.PlaceOrder(order1))
+	assert.NoError(t, sm.PlaceOrder(order2))
+	assert.NoError(t, sm.PlaceOrder(order3))
+	assert.NoError(t, sm.PlaceOrder(order4)) // Should go to overflow
+	assert.NoError(t, sm.PlaceOrder(order5)) // Should also go to overflow
 
 	// Overflow full, this order should be wasted
 	order6 := &order.Order{ID: "6", Temp: order.Hot}
-	assert.False(t, sm.PlaceOrder(order6))
+	assert.Error(t, sm.PlaceOrder(order6))
 
 	assert.Equal(t, 6, sm.TotalOrdersReceived)
 	assert.Equal(t, 1, sm.TotalOrdersWasted)
+	assert.Equal(t, order.StateShelved, order1.State())
+	assert.Equal(t, order.StateWasted, order6.State())
+}
+
+func TestShelfManager_PlaceOrderTwice(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	o := &order.Order{ID: "1", Temp: order.Hot}
+
+	assert.NoError(t, sm.PlaceOrder(o))
+	assert.Error(t, sm.PlaceOrder(o))
+	assert.Len(t, sm.GetAllOrders(), 1)
+	assert.Equal(t, 1, sm.TotalOrdersReceived)
+	assert.Zero(t, sm.TotalOrdersWasted)
+
+	// Delivered orders cannot come back either
+	assert.True(t, sm.DeliverOrder(o.ID))
+	assert.Error(t, sm.PlaceOrder(o))
+	assert.Equal(t, order.StateDelivered, o.State())
 }
 
 func TestShelfManager_DeliverOrder(t *testing.T) {
@@ -49,24 +72,190 @@ func TestShelfManager_DeliverOrder(t *testing.T) {
 	assert.Equal(t, 3, sm.TotalOrdersDelivered)
 }
 
+func TestShelfManager_TryDeliver(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	fresh := order.NewOrder("Burger", order.Hot, 10, 1)
+	stale := order.NewOrder("Fries", order.Hot, 2, 1)
+	require.NoError(t, sm.PlaceOrder(fresh))
+	require.NoError(t, sm.PlaceOrder(stale))
+	c.Advance(3 * time.Second)
+
+	// The value is read as the order leaves the shelf
+	result, ok := sm.TryDeliver(fresh.ID)
+	assert.True(t, ok)
+	assert.Same(t, fresh, result.Order)
+	assert.InDelta(t, 0.7, result.Value, 1e-9)
+	assert.Equal(t, shelf.HotShelf, result.Shelf)
+	assert.Equal(t, c.Now(), result.At)
+	assert.Equal(t, 3*time.Second, result.OnShelf)
+	assert.Equal(t, order.StateInTransit, fresh.State(), "delivered at handoff, not pickup")
+	_, ok = sm.TryDeliver(fresh.ID)
+	assert.False(t, ok)
+
+	// An expired order the sweep has not reached yet is discarded
+	_, ok = sm.TryDeliver(stale.ID)
+	assert.False(t, ok)
+	assert.Equal(t, order.StateExpired, stale.State())
+	assert.Empty(t, sm.GetAllOrders())
+
+	totals := sm.GetStats()["totalOrders"].(map[string]interface{})
+	assert.Equal(t, 1, totals["delivered"])
+	assert.Equal(t, 1, totals["expired"])
+	_, err := shelf.CheckInvariants(sm)
+	assert.NoError(t, err)
+}
+
 func TestShelfManager_OverflowHandling(t *testing.T) {
 	sm := shelf.NewShelfManager(1, 1, 1, 1)
 	order1 := &order.Order{ID: "1", Temp: order.Hot}
 	order2 := &order.Order{ID: "2", Temp: order.Hot}
 	order3 := &order.Order{ID: "3", Temp: order.Hot}
 
-	assert.True(t, sm.PlaceOrder(order1))  // Goes to hot shelf
-	assert.True(t, sm.PlaceOrder(order2))  // Goes to overflow
-	assert.False(t, sm.PlaceOrder(order3)) // Should be wasted
+	assert.NoError(t, sm.PlaceOrder(order1)) // Goes to hot shelf
+	assert.NoError(t, sm.PlaceOrder(order2)) // Goes to overflow
+	assert.Error(t, sm.PlaceOrder(order3))   // Should be wasted
 
 	assert.Equal(t, 3, sm.TotalOrdersReceived)
 	assert.Equal(t, 1, sm.TotalOrdersWasted)
+	assert.Equal(t, 1, sm.GetShelf(shelf.OverflowShelf).GetStats().OrdersWasted)
+	assert.Zero(t, sm.GetShelf(shelf.OverflowShelf).GetStats().OrdersExpired)
 }
 
 func TestShelfManager_GetShelfForTemperature(t *testing.T) {
 	sm := shelf.NewShelfManager(1, 1, 1, 1)
-	assert.Equal(t, sm.HotShelf, sm.GetShelfForTemperature(order.Hot))
-	assert.Equal(t, sm.ColdShelf, sm.GetShelfForTemperature(order.Cold))
-	assert.Equal(t, sm.FrozenShelf, sm.GetShelfForTemperature(order.Frozen))
+	assert.Equal(t, sm.GetShelf(shelf.HotShelf), sm.GetShelfForTemperature(order.Hot))
+	assert.Equal(t, sm.GetShelf(shelf.ColdShelf), sm.GetShelfForTemperature(order.Cold))
+	assert.Equal(t, sm.GetShelf(shelf.FrozenShelf), sm.GetShelfForTemperature(order.Frozen))
 	assert.Nil(t, sm.GetShelfForTemperature(order.Temperature("invalid")))
 }
+
+// Run with -race: placement, delivery, expiry and stats run concurrently and
+// must neither deadlock nor race.
+func TestShelfManager_ConcurrentAccess(t *testing.T) {
+	sm := shelf.NewShelfManager(10, 10, 10, 10)
+	temps := []order.Temperature{order.Hot, order.Cold, order.Frozen}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("%d-%d", w, i)
+				sm.PlaceOrder(&order.Order{ID: id, Temp: temps[i%len(temps)], ShelfLife: 300})
+				sm.DeliverOrder(id)
+			}
+		}(w)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			sm.GetStats()
+			sm.GetAllOrders()
+			sm.RemoveExpiredOrders()
+		}
+	}()
+	wg.Wait()
+
+	assert.Equal(t, 400, sm.TotalOrdersReceived)
+	assert.Equal(t, 400, sm.TotalOrdersDelivered+sm.TotalOrdersWasted)
+}
+
+func TestShelfManager_LocateOrder(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	order1 := &order.Order{ID: "1", Temp: order.Hot}
+	order2 := &order.Order{ID: "2", Temp: order.Hot}
+
+	sm.PlaceOrder(order1)
+	sm.PlaceOrder(order2)
+
+	o, s := sm.LocateOrder("1")
+	assert.Equal(t, order1, o)
+	assert.Equal(t, sm.GetShelf(shelf.HotShelf), s)
+
+	o, s = sm.LocateOrder("2")
+	assert.Equal(t, order2, o)
+	assert.Equal(t, sm.GetShelf(shelf.OverflowShelf), s)
+
+	assert.True(t, sm.DeliverOrder("2"))
+	o, s = sm.LocateOrder("2")
+	assert.Nil(t, o)
+	assert.Nil(t, s)
+}
+
+func TestShelfManager_RemoveExpiredOrdersUnindexes(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	o := &order.Order{ID: "1", Temp: order.Hot, ShelfLife: 1, DecayRate: 1, PlacedOnShelfAt: time.Now().Add(-5 * time.Second)}
+
+	sm.PlaceOrder(o)
+	assert.Equal(t, 1, sm.RemoveExpiredOrders())
+
+	found, _ := sm.LocateOrder("1")
+	assert.Nil(t, found)
+	assert.False(t, sm.DeliverOrder("1"))
+}
+
+func TestShelfManager_RemoveDueOrders(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	now := time.Now()
+	soon := &order.Order{ID: "soon", Temp: order.Hot, ShelfLife: 10, DecayRate: 1, PlacedOnShelfAt: now}
+	later := &order.Order{ID: "later", Temp: order.Cold, ShelfLife: 100, DecayRate: 1, PlacedOnShelfAt: now}
+	delivered := &order.Order{ID: "delivered", Temp: order.Frozen, ShelfLife: 5, DecayRate: 1, PlacedOnShelfAt: now}
+
+	sm.PlaceOrder(later)
+	sm.PlaceOrder(soon)
+	sm.PlaceOrder(delivered)
+	assert.True(t, sm.DeliverOrder("delivered"))
+
+	next, ok := sm.NextExpiry()
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(5*time.Second), next)
+
+	// The delivered order's entry is discarded without counting as expired
+	assert.Equal(t, 0, sm.RemoveDueOrders(now.Add(9*time.Second)))
+	assert.Equal(t, 1, sm.RemoveDueOrders(now.Add(10*time.Second)))
+	assert.False(t, soon.ExpiredAt().IsZero())
+	assert.Equal(t, 1, sm.TotalOrdersExpired)
+	assert.Equal(t, 1, sm.GetShelf(shelf.HotShelf).GetStats().OrdersExpired)
+	assert.Zero(t, sm.TotalOrdersWasted)
+
+	next, _ = sm.NextExpiry()
+	assert.Equal(t, now.Add(100*time.Second), next)
+}
+
+func TestShelfManager_ExpiryUpdates(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	sm.PlaceOrder(&order.Order{ID: "1", Temp: order.Hot, ShelfLife: 10, DecayRate: 1, PlacedOnShelfAt: time.Now()})
+
+	select {
+	case <-sm.ExpiryUpdates():
+	default:
+		t.Fatal("expected an update for the first scheduled expiry")
+	}
+}
+
+func TestShelfManager_ResetStats(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	delivered := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(delivered))
+	require.True(t, sm.DeliverOrder(delivered.ID))
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Salad", order.Cold, 300, 0.5)))
+	require.Error(t, sm.PlaceOrder(order.NewOrder("Bread", "ambient", 300, 0.5)))
+
+	sm.ResetStats()
+	assert.Equal(t, 1, sm.TotalOrdersReceived)
+	assert.Zero(t, sm.TotalOrdersDelivered)
+	assert.Zero(t, sm.TotalOrdersWasted)
+	assert.Empty(t, sm.StatsByName())
+	assert.Empty(t, sm.GetStats()["rejections"])
+	assert.Equal(t, shelf.ShelfStats{OrdersAdded: 1, PeakUsage: 1}, sm.GetShelf(shelf.ColdShelf).GetStats())
+	assert.Zero(t, sm.GetShelf(shelf.HotShelf).GetStats().OrdersDelivered)
+
+	_, err := shelf.CheckInvariants(sm)
+	assert.NoError(t, err)
+}
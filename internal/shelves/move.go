@@ -0,0 +1,105 @@
+package shelf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// Errors returned by MoveOrder
+var (
+	ErrOrderNotShelved = errors.New("order is not on a shelf")
+	ErrUnknownShelf    = errors.New("unknown shelf")
+	ErrSameShelf       = errors.New("order is already on that shelf")
+	ErrWrongShelf      = errors.New("shelf does not hold orders of that temperature")
+	ErrShelfFull       = errors.New("shelf is full")
+)
+
+// OrderMover is implemented by managers that can move a shelved order to
+// another shelf, so operators and strategies can rebalance shelves mid-run
+type OrderMover interface {
+	// MoveOrder moves a shelved order to the shelf of the given type,
+	// returning one of the Err* errors of this package if it cannot
+	MoveOrder(orderID string, target ShelfType) error
+}
+
+var _ OrderMover = (*InMemoryShelfManager)(nil)
+
+// MoveOrder moves a shelved order to another shelf that could have taken it
+// when it was placed: one routed its temperature, the fallback shelf for
+// temperatures no shelf accepts, or an overflow shelf. The order keeps the
+// value it has lost so far and decays at the target shelf's rate from now
+// on, with time on overflow counting as overflow time even after it leaves.
+func (sm *InMemoryShelfManager) MoveOrder(orderID string, target ShelfType) error {
+	to := sm.GetShelf(target)
+	if to == nil {
+		return fmt.Errorf("%w %q", ErrUnknownShelf, target)
+	}
+	o, from := sm.LocateOrder(orderID)
+	if o == nil {
+		return ErrOrderNotShelved
+	}
+	if from == to {
+		return ErrSameShelf
+	}
+	if !slices.Contains(sm.candidates(o.Temp), to) {
+		return fmt.Errorf("%w: %s cannot take %s orders", ErrWrongShelf, target, o.Temp)
+	}
+
+	// Index before moving, as PlaceOrder does, so a concurrent expiry of the
+	// moved order unindexes it for good
+	sm.indexOrder(orderID, to)
+	if err := sm.transfer(o, from, to); err != nil {
+		sm.indexOrder(orderID, from)
+		return err
+	}
+
+	sm.scheduleExpiry(o, to)
+	sm.addCounter(&sm.TotalOrdersMoved, 1)
+	return nil
+}
+
+// transfer moves an order between two shelves under both their locks, taken
+// in layout order so concurrent moves cannot deadlock
+func (sm *InMemoryShelfManager) transfer(o *order.Order, from, to *Shelf) error {
+	first, second := from, to
+	if slices.Index(sm.shelves, to) < slices.Index(sm.shelves, from) {
+		first, second = to, from
+	}
+	first.mutex.Lock()
+	second.mutex.Lock()
+	// The shelf the order left hears of it before the one it joined
+	defer unlockAll(from, to)
+
+	// It may have been delivered or expired since it was located
+	if held, _ := from.orders.get(o.ID); held != o {
+		return ErrOrderNotShelved
+	}
+	if !to.fits(o.Volume()) {
+		return fmt.Errorf("%w: %s", ErrShelfFull, to.Type)
+	}
+
+	now := sm.clock.Now()
+	if err := o.Transition(order.StateShelved, now); err != nil {
+		return err
+	}
+
+	reserved, isReserved := from.reserved[o.ID]
+	from.take(o)
+	from.stats.OrdersRemoved++
+	o.CloseDecayWindows(now)
+	if from.overflow && !to.overflow {
+		o.LeaveOverflow(now)
+	}
+	to.hold(o, now)
+	if isReserved {
+		if to.reserved == nil {
+			to.reserved = make(map[string]time.Time)
+		}
+		to.reserved[o.ID] = reserved
+	}
+	return nil
+}
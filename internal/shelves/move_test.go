@@ -0,0 +1,82 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_MoveOrder(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 2)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	hot := order.NewOrder("Burger", order.Hot, 300, 1)
+	spill := order.NewOrder("Pizza", order.Hot, 300, 1)
+	require.NoError(t, sm.PlaceOrder(hot))
+	require.NoError(t, sm.PlaceOrder(spill))
+	assert.Equal(t, "overflow", spill.CurrentShelfType)
+
+	// Make room on the hot shelf and bring the spilled order back
+	c.Advance(10 * time.Second)
+	require.NoError(t, sm.MoveOrder(hot.ID, shelf.OverflowShelf))
+	require.NoError(t, sm.MoveOrder(spill.ID, shelf.HotShelf))
+
+	_, s := sm.LocateOrder(spill.ID)
+	assert.Equal(t, shelf.HotShelf, s.Type)
+	assert.Equal(t, "hot", spill.CurrentShelfType)
+	assert.True(t, spill.PlacedOnOverflow.IsZero())
+	assert.Equal(t, order.StateShelved, spill.State())
+	assert.Equal(t, 1, sm.GetShelf(shelf.HotShelf).Size())
+	assert.Equal(t, 1, sm.GetShelf(shelf.OverflowShelf).Size())
+	assert.Equal(t, 2, sm.GetStats()["totalOrders"].(map[string]interface{})["moved"])
+
+	// The moved orders keep decaying from where they were
+	assert.InDelta(t, 290/300.0, spill.CalculateValue(c.Now()), 0.001)
+	assert.InDelta(t, 290/300.0, hot.CalculateValue(c.Now()), 0.001)
+}
+
+func TestShelfManager_MoveOrderErrors(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	hot := order.NewOrder("Burger", order.Hot, 300, 1)
+	spill := order.NewOrder("Pizza", order.Hot, 300, 1)
+	require.NoError(t, sm.PlaceOrder(hot))
+	require.NoError(t, sm.PlaceOrder(spill))
+
+	assert.ErrorIs(t, sm.MoveOrder("missing", shelf.HotShelf), shelf.ErrOrderNotShelved)
+	assert.ErrorIs(t, sm.MoveOrder(hot.ID, shelf.ShelfType("sauna")), shelf.ErrUnknownShelf)
+	assert.ErrorIs(t, sm.MoveOrder(hot.ID, shelf.HotShelf), shelf.ErrSameShelf)
+	assert.ErrorIs(t, sm.MoveOrder(hot.ID, shelf.ColdShelf), shelf.ErrWrongShelf)
+	assert.ErrorIs(t, sm.MoveOrder(spill.ID, shelf.HotShelf), shelf.ErrShelfFull)
+
+	// A failed move leaves the order where it was
+	_, s := sm.LocateOrder(spill.ID)
+	assert.Equal(t, shelf.OverflowShelf, s.Type)
+}
+
+func TestShelfManager_MoveOrderReschedulesExpiry(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	o := order.NewOrder("Fries", order.Hot, 100, 1)
+	minTemp := 50.0
+	o.SafeBand = &order.SafeBand{MinTemp: &minTemp, SpoilageMultiplier: 4}
+	require.NoError(t, sm.PlaceOrder(o))
+	c.Advance(10 * time.Second)
+
+	// Overflow is outside the safe band, so the order expires sooner there
+	require.NoError(t, sm.MoveOrder(o.ID, shelf.OverflowShelf))
+	next, ok := sm.NextExpiry()
+	require.True(t, ok)
+	assert.WithinDuration(t, c.Now().Add(90*time.Second/4), next, time.Millisecond)
+
+	c.Set(next)
+	assert.Equal(t, 1, sm.RemoveDueOrders(c.Now()))
+}
@@ -0,0 +1,80 @@
+package shelf
+
+import (
+	"fmt"
+	"time"
+)
+
+// StartOutage marks the shelf as having lost cooling. Until EndOutage is
+// called, every order on the shelf, and every order added to it, decays
+// factor times faster. Starting an outage on a shelf already in one replaces
+// the factor from now on.
+func (s *Shelf) StartOutage(factor float64, now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.outageFactor = factor
+	for _, order := range s.orders.values() {
+		order.CloseDecayWindows(now)
+		order.OpenDecayWindow(now, s.decayFactor())
+	}
+}
+
+// EndOutage restores normal cooling and closes the outage decay windows of
+// every order still on the shelf
+func (s *Shelf) EndOutage(now time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.outageFactor = 0
+	for _, order := range s.orders.values() {
+		order.CloseDecayWindows(now)
+		if factor := s.decayFactor(); factor != 1 {
+			order.OpenDecayWindow(now, factor)
+		}
+	}
+}
+
+// InOutage reports whether the shelf has currently lost cooling
+func (s *Shelf) InOutage() bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.outageFactor > 0
+}
+
+// StartOutage makes a shelf lose cooling, multiplying the decay rate of its
+// contents by factor until EndOutage is called
+func (sm *InMemoryShelfManager) StartOutage(shelfType ShelfType, factor float64) error {
+	shelf := sm.GetShelf(shelfType)
+	if shelf == nil {
+		return fmt.Errorf("unknown shelf %q", shelfType)
+	}
+	if factor <= 1 {
+		return fmt.Errorf("outage decay factor must be greater than 1, got %v", factor)
+	}
+
+	shelf.StartOutage(factor, sm.clock.Now())
+	sm.rescheduleExpiries(shelf)
+	return nil
+}
+
+// EndOutage restores cooling on a shelf
+func (sm *InMemoryShelfManager) EndOutage(shelfType ShelfType) error {
+	shelf := sm.GetShelf(shelfType)
+	if shelf == nil {
+		return fmt.Errorf("unknown shelf %q", shelfType)
+	}
+
+	shelf.EndOutage(sm.clock.Now())
+	sm.rescheduleExpiries(shelf)
+	return nil
+}
+
+// rescheduleExpiries schedules fresh expiries for a shelf's contents after
+// their decay changed. Superseded entries are discarded when they come due.
+func (sm *InMemoryShelfManager) rescheduleExpiries(shelf *Shelf) {
+	for _, order := range shelf.GetAllOrders() {
+		sm.scheduleExpiry(order, shelf)
+	}
+}
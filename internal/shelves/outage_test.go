@@ -0,0 +1,55 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_OutageAcceleratesDecay(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	placedAt := time.Now().Add(-10 * time.Second)
+	o := &order.Order{ID: "1", Temp: order.Cold, ShelfLife: 100, DecayRate: 1, PlacedOnShelfAt: placedAt}
+	sm.PlaceOrder(o)
+
+	before := o.ExpiresAt()
+	assert.NoError(t, sm.StartOutage(shelf.ColdShelf, 4))
+	assert.True(t, sm.GetShelf(shelf.ColdShelf).InOutage())
+	assert.True(t, o.ExpiresAt().Before(before))
+
+	late := &order.Order{ID: "2", Temp: order.Cold, ShelfLife: 100, DecayRate: 1}
+	sm.PlaceOrder(late)
+	assert.Len(t, late.DecayWindows, 1)
+
+	assert.NoError(t, sm.EndOutage(shelf.ColdShelf))
+	assert.False(t, sm.GetShelf(shelf.ColdShelf).InOutage())
+	assert.False(t, o.DecayWindows[0].End.IsZero())
+}
+
+func TestShelfManager_OutageErrors(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	assert.Error(t, sm.StartOutage(shelf.ShelfType("sauna"), 2))
+	assert.Error(t, sm.StartOutage(shelf.HotShelf, 1))
+	assert.Error(t, sm.EndOutage(shelf.ShelfType("sauna")))
+}
+
+func TestShelfManager_RemoveDueOrdersReschedulesStaleEntries(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	now := time.Now()
+	o := &order.Order{ID: "1", Temp: order.Hot, ShelfLife: 10, DecayRate: 1, PlacedOnShelfAt: now}
+	sm.PlaceOrder(o)
+
+	// Pretend the order picked up a decay window that slows it down relative
+	// to its scheduled expiry: a factor below 1 models restored cooling
+	o.DecayWindows = []order.DecayWindow{{Start: now, End: now.Add(10 * time.Second), Factor: 0.5}}
+
+	assert.Equal(t, 0, sm.RemoveDueOrders(now.Add(10*time.Second)))
+	next, ok := sm.NextExpiry()
+	assert.True(t, ok)
+	assert.True(t, next.After(now.Add(10*time.Second)))
+	assert.Equal(t, 1, sm.RemoveDueOrders(next))
+}
@@ -0,0 +1,126 @@
+package shelf
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+
+	"dish-dispatcher/internal/order"
+	"dish-dispatcher/plugin"
+)
+
+// RejectReason says why an order could not be shelved
+type RejectReason string
+
+// Reasons a placement can be rejected, or an order refused at pickup
+const (
+	// RejectNotNew: the order was already placed or has finished
+	RejectNotNew RejectReason = "not_new"
+	// RejectInvalidTemperature: no shelf accepts the order's temperature
+	RejectInvalidTemperature RejectReason = "invalid_temperature"
+	// RejectPrimaryFull: every shelf for the temperature is full and the
+	// layout has no overflow shelf
+	RejectPrimaryFull RejectReason = "primary_full"
+	// RejectOverflowFull: the temperature's shelves and every overflow
+	// shelf are full
+	RejectOverflowFull RejectReason = "overflow_full"
+	// RejectBackendError: the shelf store failed; Err holds the cause
+	RejectBackendError RejectReason = "backend_error"
+	// RejectLowValue: the order was shelved, but was worth less than the
+	// minimum delivery value when a courier came to collect it. It is a
+	// waste reason only, never a placement rejection: refusals are counted
+	// apart, as TotalOrdersRefused.
+	RejectLowValue RejectReason = "low_value"
+	// RejectBatch: the order was fine, but another order of its
+	// all-or-nothing batch could not be shelved
+	RejectBatch RejectReason = "batch"
+)
+
+// PlacementError is returned by PlaceOrder when an order is not shelved.
+// Apart from RejectNotNew, and the rejections of a failed all-or-nothing
+// batch, a rejected order has been wasted.
+type PlacementError struct {
+	OrderID string
+	Reason  RejectReason
+	Err     error // underlying failure for RejectBackendError
+}
+
+func (e *PlacementError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("order %s not placed: %s: %v", e.OrderID, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("order %s not placed: %s", e.OrderID, e.Reason)
+}
+
+func (e *PlacementError) Unwrap() error {
+	return e.Err
+}
+
+// RejectionReason returns the reason a PlaceOrder error rejected the order,
+// or "" if err is nil
+func RejectionReason(err error) RejectReason {
+	var pe *PlacementError
+	if errors.As(err, &pe) {
+		return pe.Reason
+	}
+	if err != nil {
+		return RejectBackendError
+	}
+	return ""
+}
+
+// fullReason returns why an order was rejected after every candidate
+// shelf turned out to be full
+func fullReason(hasOverflow bool) RejectReason {
+	if hasOverflow {
+		return RejectOverflowFull
+	}
+	return RejectPrimaryFull
+}
+
+// SetPlacementStrategy ranks candidate shelves with a strategy registered
+// with the plugin package. Call it before placing orders.
+func (sm *InMemoryShelfManager) SetPlacementStrategy(name string) error {
+	strategy, err := plugin.NewPlacementStrategy(name)
+	if err != nil {
+		return err
+	}
+	sm.placement = strategy
+	return nil
+}
+
+// rankShelves sorts candidate shelves by the placement strategy's scores,
+// keeping the layout order for equal scores
+func (sm *InMemoryShelfManager) rankShelves(o *order.Order, shelves []*Shelf) {
+	view := plugin.Order{
+		ID:        o.ID,
+		Name:      o.Name,
+		Temp:      string(o.Temp),
+		ShelfLife: o.ShelfLife,
+		DecayRate: o.DecayRate,
+		Volume:    o.Volume(),
+		Value:     o.CalculateValue(sm.clock.Now()),
+		Priority:  o.Priority,
+		Zone:      o.Zone,
+	}
+	primary := sm.routes[o.Temp]
+
+	scores := make(map[*Shelf]float64, len(shelves))
+	for _, s := range shelves {
+		modifier := s.decayModifier
+		if modifier <= 0 {
+			modifier = 1
+		}
+		scores[s] = sm.placement.Score(view, plugin.Shelf{
+			Type:          string(s.Type),
+			Capacity:      s.Capacity,
+			Size:          s.Size(),
+			Overflow:      s.overflow,
+			Primary:       slices.Contains(primary, s),
+			DecayModifier: modifier,
+			InOutage:      s.InOutage(),
+		})
+	}
+	sort.SliceStable(shelves, func(i, j int) bool { return scores[shelves[i]] < scores[shelves[j]] })
+}
@@ -0,0 +1,135 @@
+package shelf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+func TestShelfManager_RejectionReasons(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 0, 0, 1)
+
+	placed := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(placed))
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Fries", order.Hot, 300, 0.5)))
+
+	tests := []struct {
+		name   string
+		order  *order.Order
+		reason shelf.RejectReason
+	}{
+		{"overflow full", order.NewOrder("Soup", order.Hot, 300, 0.5), shelf.RejectOverflowFull},
+		{"invalid temperature", order.NewOrder("Tea", order.Temperature("lukewarm"), 300, 0.5), shelf.RejectInvalidTemperature},
+		{"not new", placed, shelf.RejectNotNew},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sm.PlaceOrder(tt.order)
+			var pe *shelf.PlacementError
+			require.True(t, errors.As(err, &pe))
+			assert.Equal(t, tt.reason, pe.Reason)
+			assert.Equal(t, tt.order.ID, pe.OrderID)
+			assert.Equal(t, tt.reason, shelf.RejectionReason(err))
+		})
+	}
+
+	assert.Equal(t, map[shelf.RejectReason]int{
+		shelf.RejectOverflowFull:       1,
+		shelf.RejectInvalidTemperature: 1,
+		shelf.RejectNotNew:             1,
+	}, sm.Rejections())
+	assert.Equal(t, sm.Rejections(), sm.GetStats()["rejections"])
+
+	// Only orders that were wasted count as waste
+	assert.Equal(t, 2, sm.GetStats()["totalOrders"].(map[string]interface{})["wasted"])
+}
+
+func TestShelfManager_RejectPrimaryFull(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "hot", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5)))
+	err = sm.PlaceOrder(order.NewOrder("Fries", order.Hot, 300, 0.5))
+	assert.Equal(t, shelf.RejectPrimaryFull, shelf.RejectionReason(err))
+	assert.Contains(t, err.Error(), "primary_full")
+}
+
+func TestRejectionReason(t *testing.T) {
+	assert.Equal(t, shelf.RejectReason(""), shelf.RejectionReason(nil))
+	assert.Equal(t, shelf.RejectBackendError, shelf.RejectionReason(errors.New("connection reset")))
+
+	cause := errors.New("connection reset")
+	err := &shelf.PlacementError{OrderID: "1", Reason: shelf.RejectBackendError, Err: cause}
+	assert.ErrorIs(t, err, cause)
+	assert.Contains(t, err.Error(), "connection reset")
+}
+
+func TestShelfManager_FallbackShelf(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "hot", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+		{Type: "counter", Capacity: 1},
+		{Type: "spare", Capacity: 1},
+	})
+	require.NoError(t, err)
+	assert.False(t, sm.Accepts("ambient"))
+	assert.Error(t, sm.SetFallbackShelf("pantry"))
+
+	// The fallback defaults to the first overflow shelf, then tries the rest
+	require.NoError(t, sm.SetFallbackShelf(""))
+	first := order.NewOrder("Bread", "ambient", 300, 0.5)
+	second := order.NewOrder("Crackers", "ambient", 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(first))
+	require.NoError(t, sm.PlaceOrder(second))
+	assert.Equal(t, "counter", first.CurrentShelfType)
+	assert.Equal(t, "spare", second.CurrentShelfType)
+	assert.False(t, sm.Accepts("ambient"))
+
+	err = sm.PlaceOrder(order.NewOrder("Chips", "ambient", 300, 0.5))
+	assert.Equal(t, shelf.RejectOverflowFull, shelf.RejectionReason(err))
+
+	// A designated shelf is tried before overflow
+	sm = shelf.NewShelfManager(1, 1, 1, 1)
+	require.NoError(t, sm.SetFallbackShelf(shelf.ColdShelf))
+	designated := order.NewOrder("Bread", "ambient", 300, 0.5)
+	overflowed := order.NewOrder("Crackers", "ambient", 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(designated))
+	require.NoError(t, sm.PlaceOrder(overflowed))
+	assert.Equal(t, string(shelf.ColdShelf), designated.CurrentShelfType)
+	assert.Equal(t, string(shelf.OverflowShelf), overflowed.CurrentShelfType)
+
+	_, err = shelf.CheckInvariants(sm)
+	assert.NoError(t, err)
+}
+
+// coldestFirst is a plugin placement strategy preferring shelves that slow
+// decay
+type coldestFirst struct{}
+
+func (coldestFirst) Score(_ plugin.Order, s plugin.Shelf) float64 {
+	return s.DecayModifier
+}
+
+func TestShelfManager_PlacementStrategy(t *testing.T) {
+	plugin.RegisterPlacementStrategy("coldest-first", func() plugin.PlacementStrategy { return coldestFirst{} })
+
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "hot", Capacity: 1, Temps: []order.Temperature{order.Hot}},
+		{Type: "overflow", Capacity: 1, DecayModifier: 0.5},
+	})
+	require.NoError(t, err)
+	require.NoError(t, sm.SetPlacementStrategy("coldest-first"))
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(o))
+	assert.Equal(t, "overflow", o.CurrentShelfType)
+
+	assert.Error(t, sm.SetPlacementStrategy("missing"))
+}
@@ -0,0 +1,24 @@
+package shelf
+
+import "fmt"
+
+// QualityGate is implemented by managers that hold deliveries to a minimum
+// value: TryDeliver wastes an order worth less rather than send it out
+type QualityGate interface {
+	// SetMinDeliveryValue refuses orders worth less than min at pickup.
+	// Zero delivers any order that has not expired. Call it before
+	// placing orders.
+	SetMinDeliveryValue(min float64) error
+}
+
+var _ QualityGate = (*InMemoryShelfManager)(nil)
+
+// SetMinDeliveryValue refuses orders worth less than min at pickup, wasting
+// them instead. Call it before placing orders.
+func (sm *InMemoryShelfManager) SetMinDeliveryValue(min float64) error {
+	if min < 0 || min >= 1 {
+		return fmt.Errorf("minimum delivery value must be at least 0 and below 1, got %v", min)
+	}
+	sm.minValue = min
+	return nil
+}
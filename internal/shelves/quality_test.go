@@ -0,0 +1,56 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_SetMinDeliveryValue(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	assert.NoError(t, sm.SetMinDeliveryValue(0))
+	assert.NoError(t, sm.SetMinDeliveryValue(0.2))
+	assert.Error(t, sm.SetMinDeliveryValue(-0.1))
+	assert.Error(t, sm.SetMinDeliveryValue(1))
+}
+
+func TestShelfManager_TryDeliverRefusesLowValue(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+	require.NoError(t, sm.SetMinDeliveryValue(0.5))
+
+	fresh := order.NewOrder("Burger", order.Hot, 100, 1)
+	stale := order.NewOrder("Fries", order.Hot, 10, 1)
+	require.NoError(t, sm.PlaceOrder(fresh))
+	require.NoError(t, sm.PlaceOrder(stale))
+	c.Advance(6 * time.Second)
+
+	result, ok := sm.TryDeliver(fresh.ID)
+	assert.True(t, ok)
+	assert.False(t, result.Refused)
+
+	// Worth 0.4 of its value, below the minimum but not expired
+	result, ok = sm.TryDeliver(stale.ID)
+	assert.False(t, ok)
+	assert.True(t, result.Refused)
+	assert.InDelta(t, 0.4, result.Value, 1e-9)
+	assert.Equal(t, order.StateWasted, stale.State())
+	assert.Empty(t, sm.GetAllOrders())
+
+	stats := sm.GetStats()
+	totals := stats["totalOrders"].(map[string]interface{})
+	assert.Equal(t, 1, totals["delivered"])
+	assert.Equal(t, 1, totals["wasted"])
+	assert.Equal(t, 1, totals["refused"])
+	assert.Empty(t, stats["rejections"], "refusals are not placement rejections")
+	hot := stats["hotShelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
+	assert.Equal(t, 1, hot.OrdersRefused)
+	assert.Equal(t, 0, hot.OrdersWasted)
+}
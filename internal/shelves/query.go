@@ -0,0 +1,67 @@
+package shelf
+
+import (
+	"strings"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// OrderFilter selects shelved orders. Zero-valued fields match everything.
+type OrderFilter struct {
+	Temp       order.Temperature
+	Shelf      ShelfType
+	NamePrefix string // case-insensitive
+	MinValue   *float64
+	MaxValue   *float64
+	MinAge     time.Duration // time since first shelved
+	MaxAge     time.Duration
+}
+
+// Matches reports whether o, held on shelfType, passes the filter at now
+func (f OrderFilter) Matches(o *order.Order, shelfType ShelfType, now time.Time) bool {
+	if f.Temp != "" && o.Temp != f.Temp {
+		return false
+	}
+	if f.Shelf != "" && shelfType != f.Shelf {
+		return false
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(o.Name), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+
+	if f.MinValue != nil || f.MaxValue != nil {
+		value := o.CalculateValue(now)
+		if f.MinValue != nil && value < *f.MinValue {
+			return false
+		}
+		if f.MaxValue != nil && value > *f.MaxValue {
+			return false
+		}
+	}
+
+	age := now.Sub(o.PlacedOnShelfAt)
+	if f.MinAge > 0 && age < f.MinAge {
+		return false
+	}
+	if f.MaxAge > 0 && age > f.MaxAge {
+		return false
+	}
+	return true
+}
+
+// Query returns every shelved order matching the filter, evaluated at now
+func (sm *InMemoryShelfManager) Query(filter OrderFilter, now time.Time) []*order.Order {
+	matched := make([]*order.Order, 0)
+	for _, s := range sm.shelves {
+		if filter.Shelf != "" && s.Type != filter.Shelf {
+			continue
+		}
+		for _, o := range s.GetAllOrders() {
+			if filter.Matches(o, s.Type, now) {
+				matched = append(matched, o)
+			}
+		}
+	}
+	return matched
+}
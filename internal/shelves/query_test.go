@@ -0,0 +1,50 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_Query(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 2, 1, 2)
+	now := time.Now()
+
+	fresh := &order.Order{ID: "1", Name: "Burger", Temp: order.Hot, ShelfLife: 100, DecayRate: 1, PlacedOnShelfAt: now}
+	stale := &order.Order{ID: "2", Name: "Burrito", Temp: order.Hot, ShelfLife: 100, DecayRate: 1, PlacedOnShelfAt: now.Add(-80 * time.Second)}
+	salad := &order.Order{ID: "3", Name: "Salad", Temp: order.Cold, ShelfLife: 100, DecayRate: 1, PlacedOnShelfAt: now.Add(-10 * time.Second)}
+	sm.PlaceOrder(fresh)
+	sm.PlaceOrder(stale) // hot is full, goes to overflow
+	sm.PlaceOrder(salad)
+
+	half := 0.5
+	cases := []struct {
+		name   string
+		filter shelf.OrderFilter
+		want   []string
+	}{
+		{"all", shelf.OrderFilter{}, []string{"1", "2", "3"}},
+		{"temperature", shelf.OrderFilter{Temp: order.Hot}, []string{"1", "2"}},
+		{"shelf", shelf.OrderFilter{Shelf: shelf.OverflowShelf}, []string{"2"}},
+		{"name prefix", shelf.OrderFilter{NamePrefix: "bur"}, []string{"1", "2"}},
+		{"min value", shelf.OrderFilter{MinValue: &half}, []string{"1", "3"}},
+		{"max value", shelf.OrderFilter{MaxValue: &half}, []string{"2"}},
+		{"min age", shelf.OrderFilter{MinAge: 5 * time.Second}, []string{"2", "3"}},
+		{"max age", shelf.OrderFilter{MaxAge: 20 * time.Second}, []string{"1", "3"}},
+		{"combined", shelf.OrderFilter{Temp: order.Hot, MinValue: &half}, []string{"1"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ids := make([]string, 0)
+			for _, o := range sm.Query(tc.filter, now) {
+				ids = append(ids, o.ID)
+			}
+			assert.ElementsMatch(t, tc.want, ids)
+		})
+	}
+}
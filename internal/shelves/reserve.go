@@ -0,0 +1,70 @@
+package shelf
+
+import "time"
+
+// OrderReserver is implemented by managers that can hold a shelved order for
+// the courier on its way to collect it, so neither expiry cleanup nor the
+// eviction script takes it off the shelf before the courier arrives
+type OrderReserver interface {
+	// ReserveOrder keeps a shelved order from expiring or being evicted
+	// until the given time, returning false if it is not shelved
+	ReserveOrder(orderID string, until time.Time) bool
+	// ReleaseOrder ends a reservation early, for a courier that will not
+	// come
+	ReleaseOrder(orderID string)
+}
+
+var _ OrderReserver = (*InMemoryShelfManager)(nil)
+
+// ReserveOrder keeps a shelved order on its shelf until the given time even
+// if it expires meanwhile. The reservation follows the order if it is moved
+// and ends when the order leaves the shelves.
+func (sm *InMemoryShelfManager) ReserveOrder(orderID string, until time.Time) bool {
+	_, s := sm.LocateOrder(orderID)
+	return s != nil && s.reserve(orderID, until)
+}
+
+// ReleaseOrder ends an order's reservation, leaving it to expire as usual
+func (sm *InMemoryShelfManager) ReleaseOrder(orderID string) {
+	if s := sm.lookupShelf(orderID); s != nil {
+		s.release(orderID)
+	}
+}
+
+// reserve holds an order until the given time, returning false if the shelf
+// no longer holds it
+func (s *Shelf) reserve(orderID string, until time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.orders.get(orderID); !ok {
+		return false
+	}
+	if s.reserved == nil {
+		s.reserved = make(map[string]time.Time)
+	}
+	s.reserved[orderID] = until
+	return true
+}
+
+func (s *Shelf) release(orderID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.reserved, orderID)
+}
+
+// reservation returns when an order's reservation ends, or the zero time
+// if it has none
+func (s *Shelf) reservation(orderID string) time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.reserved[orderID]
+}
+
+// isReserved reports whether an order is reserved at now. Callers must
+// hold the shelf lock.
+func (s *Shelf) isReserved(orderID string, now time.Time) bool {
+	return now.Before(s.reserved[orderID])
+}
@@ -0,0 +1,102 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_ReserveOrderScheduled(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	o := order.NewOrder("Burger", order.Hot, 10, 1)
+	require.NoError(t, sm.PlaceOrder(o))
+	until := c.Now().Add(15 * time.Second)
+	require.True(t, sm.ReserveOrder(o.ID, until))
+
+	// Expired, but its courier is still on the way
+	expiry, ok := sm.NextExpiry()
+	require.True(t, ok)
+	assert.Equal(t, 0, sm.RemoveDueOrders(expiry))
+	next, ok := sm.NextExpiry()
+	require.True(t, ok)
+	assert.Equal(t, until, next)
+
+	assert.Equal(t, 1, sm.RemoveDueOrders(until))
+	assert.Equal(t, order.StateExpired, o.State())
+}
+
+func TestShelfManager_ReserveOrderSweep(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	o := order.NewOrder("Burger", order.Hot, 10, 1)
+	require.NoError(t, sm.PlaceOrder(o))
+	require.True(t, sm.ReserveOrder(o.ID, c.Now().Add(15*time.Second)))
+
+	c.Advance(12 * time.Second)
+	assert.Equal(t, 0, sm.RemoveExpiredOrders())
+
+	// A delivered reserved order is saved
+	assert.True(t, sm.DeliverOrder(o.ID))
+	assert.Equal(t, order.StateDelivered, o.State())
+}
+
+func TestShelfManager_ReleaseOrder(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	o := order.NewOrder("Burger", order.Hot, 10, 1)
+	require.NoError(t, sm.PlaceOrder(o))
+	require.True(t, sm.ReserveOrder(o.ID, c.Now().Add(15*time.Second)))
+	sm.ReleaseOrder(o.ID)
+
+	c.Advance(12 * time.Second)
+	assert.Equal(t, 1, sm.RemoveExpiredOrders())
+	assert.False(t, sm.ReserveOrder(o.ID, c.Now().Add(15*time.Second)), "only shelved orders can be reserved")
+}
+
+func TestShelfManager_ReservedOrderNotEvicted(t *testing.T) {
+	c := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	sm := shelf.NewShelfManager(1, 0, 0, 1)
+	sm.SetClock(c)
+	require.NoError(t, sm.SetEvictionScript("value"))
+
+	fast := order.NewOrder("Fries", order.Hot, 300, 0.9)
+	slow := order.NewOrder("Burger", order.Hot, 300, 0.1)
+	require.NoError(t, sm.PlaceOrder(slow))
+	require.NoError(t, sm.PlaceOrder(fast))
+	require.True(t, sm.ReserveOrder(fast.ID, c.Now().Add(120*time.Second)))
+	c.Advance(60 * time.Second)
+
+	// The fast-decaying order is worth least but reserved, so the next
+	// least valuable makes room
+	require.NoError(t, sm.PlaceOrder(order.NewOrder("Soup", order.Hot, 300, 0.5)))
+	assert.Equal(t, order.StateShelved, fast.State())
+	assert.Equal(t, order.StateExpired, slow.State())
+}
+
+func TestShelfManager_ReservationFollowsMove(t *testing.T) {
+	sm := shelf.NewShelfManager(1, 1, 1, 1)
+	c := clock.NewFake(time.Now())
+	sm.SetClock(c)
+
+	o := order.NewOrder("Burger", order.Hot, 10, 1)
+	require.NoError(t, sm.PlaceOrder(o))
+	require.True(t, sm.ReserveOrder(o.ID, c.Now().Add(15*time.Second)))
+	require.NoError(t, sm.MoveOrder(o.ID, shelf.OverflowShelf))
+
+	c.Advance(12 * time.Second)
+	assert.Equal(t, 0, sm.RemoveExpiredOrders())
+	assert.Equal(t, order.StateShelved, o.State())
+}
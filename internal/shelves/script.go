@@ -0,0 +1,147 @@
+package shelf
+
+import (
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	"dish-dispatcher/plugin"
+)
+
+// Scriptable is implemented by managers whose placement and eviction can be
+// driven by scripts, expressions in the language of decay expressions
+type Scriptable interface {
+	// SetPlacementScript scores each shelf an order could go on; shelves
+	// are tried lowest score first
+	SetPlacementScript(script string) error
+	// SetEvictionScript scores the orders on an arriving order's shelves
+	// once they are all full; the lowest scoring order is discarded to make
+	// room instead of wasting the new one
+	SetEvictionScript(script string) error
+}
+
+// PlacementVars are the variables a placement script may use:
+//
+//	capacity, size, free  the shelf's capacity, orders held and room left
+//	overflow              1 on an overflow shelf, otherwise 0
+//	primary               1 if the shelf is routed the order's temperature
+//	decayModifier         the shelf's decay multiplier, 1 for normal decay
+//	outage                1 while the shelf has lost cooling
+//	shelfLife, decayRate  the arriving order's
+//	volume                the arriving order's size
+var PlacementVars = []string{"capacity", "size", "free", "overflow", "primary", "decayModifier", "outage", "shelfLife", "decayRate", "volume"}
+
+// EvictionVars are the variables an eviction script may use, describing a
+// shelved order:
+//
+//	value                 its current value, 0 to 1
+//	remaining             seconds until it expires
+//	age                   seconds since it was first shelved
+//	shelfLife, decayRate  its shelf life and decay rate
+//	overflow              1 if it is on an overflow shelf
+//	volume                its size
+var EvictionVars = []string{"value", "remaining", "age", "shelfLife", "decayRate", "overflow", "volume"}
+
+// SetPlacementScript orders placement candidates by a script. Call it before
+// placing orders.
+func (sm *InMemoryShelfManager) SetPlacementScript(script string) error {
+	expr, err := order.ParseExpression(script, PlacementVars...)
+	if err != nil {
+		return fmt.Errorf("placement script: %w", err)
+	}
+	sm.placement = scriptPlacement{script: expr}
+	return nil
+}
+
+// SetEvictionScript makes room for orders by discarding the lowest scoring
+// shelved order. Evicted orders count as expired. Call it before placing
+// orders.
+func (sm *InMemoryShelfManager) SetEvictionScript(script string) error {
+	expr, err := order.ParseExpression(script, EvictionVars...)
+	if err != nil {
+		return fmt.Errorf("eviction script: %w", err)
+	}
+	sm.evictionScript = expr
+	return nil
+}
+
+// scriptPlacement scores shelves with a placement script
+type scriptPlacement struct {
+	script *order.Expression
+}
+
+func (p scriptPlacement) Score(o plugin.Order, s plugin.Shelf) float64 {
+	return p.script.Eval(map[string]float64{
+		"capacity":      float64(s.Capacity),
+		"size":          float64(s.Size),
+		"free":          float64(s.Capacity - s.Size),
+		"overflow":      flag(s.Overflow),
+		"primary":       flag(s.Primary),
+		"decayModifier": s.DecayModifier,
+		"outage":        flag(s.InOutage),
+		"shelfLife":     o.ShelfLife,
+		"decayRate":     o.DecayRate,
+		"volume":        o.Volume,
+	})
+}
+
+// evictFor discards the lowest scoring order on the given shelves whose
+// removal makes room for o, returning the shelf it was on, or nil if no
+// order could be evicted
+func (sm *InMemoryShelfManager) evictFor(o *order.Order, shelves []*Shelf) *Shelf {
+	now := sm.clock.Now()
+
+	var (
+		victim    *order.Order
+		from      *Shelf
+		bestScore float64
+	)
+	for _, s := range shelves {
+		used := s.UsedVolume()
+		for _, held := range s.GetAllOrders() {
+			// Couriers are on the way for reserved orders
+			if now.Before(s.reservation(held.ID)) {
+				continue
+			}
+			if s.Volume > 0 && used-held.Volume()+o.Volume() > s.Volume+volumeTolerance {
+				continue
+			}
+			score := sm.evictionScript.Eval(evictionVars(held, s, now))
+			if victim == nil || score < bestScore {
+				victim, from, bestScore = held, s, score
+			}
+		}
+	}
+	if victim == nil || !from.expireOrder(victim.ID, now) {
+		return nil
+	}
+
+	sm.unindexOrder(victim.ID)
+	sm.recordOutcome(victim, outcomeExpired, now)
+	sm.addCounter(&sm.TotalOrdersEvicted, 1)
+	return from
+}
+
+func evictionVars(o *order.Order, s *Shelf, now time.Time) map[string]float64 {
+	remaining := 0.0
+	if expiresAt := o.ExpiresAt(); !expiresAt.IsZero() {
+		remaining = expiresAt.Sub(now).Seconds()
+	}
+	return map[string]float64{
+		"value":     o.CalculateValue(now),
+		"remaining": remaining,
+		"age":       now.Sub(o.PlacedOnShelfAt).Seconds(),
+		"shelfLife": o.ShelfLife,
+		"decayRate": o.DecayRate,
+		"overflow":  flag(s.overflow),
+		"volume":    o.Volume(),
+	}
+}
+
+// flag converts a condition to a script value
+func flag(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,58 @@
+package shelf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelfManager_PlacementScript(t *testing.T) {
+	sm := shelf.NewShelfManager(2, 2, 2, 2)
+	require.NoError(t, sm.SetPlacementScript("-overflow"))
+
+	// Overflow is tried first, then the hot shelf once it fills
+	for i := 0; i < 3; i++ {
+		require.NoError(t, sm.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5)))
+	}
+	states := sm.ShelfStates()
+	assert.Len(t, states[0].Orders, 1)
+	assert.Len(t, states[3].Orders, 2)
+
+	assert.Error(t, sm.SetPlacementScript("temperature"))
+}
+
+func TestShelfManager_EvictionScript(t *testing.T) {
+	c := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	sm := shelf.NewShelfManager(1, 0, 0, 1)
+	sm.SetClock(c)
+	require.NoError(t, sm.SetEvictionScript("value"))
+
+	fast := order.NewOrder("Fries", order.Hot, 300, 0.9)
+	slow := order.NewOrder("Burger", order.Hot, 300, 0.1)
+	require.NoError(t, sm.PlaceOrder(slow))
+	require.NoError(t, sm.PlaceOrder(fast))
+	c.Advance(60 * time.Second)
+
+	// The fast-decaying order is worth least, so it makes room
+	arriving := order.NewOrder("Soup", order.Hot, 300, 0.5)
+	require.NoError(t, sm.PlaceOrder(arriving))
+	assert.Equal(t, order.StateExpired, fast.State())
+	assert.Equal(t, order.StateShelved, slow.State())
+	assert.Equal(t, order.StateShelved, arriving.State())
+
+	totals := sm.GetStats()["totalOrders"].(map[string]interface{})
+	assert.Equal(t, 1, totals["evicted"])
+	assert.Equal(t, 1, totals["expired"])
+	assert.Equal(t, 0, totals["wasted"])
+
+	_, err := shelf.CheckInvariants(sm)
+	assert.NoError(t, err)
+
+	assert.Error(t, sm.SetEvictionScript("value +"))
+}
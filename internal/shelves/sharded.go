@@ -0,0 +1,96 @@
+package shelf
+
+import (
+	"hash/maphash"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultShardCount is how many shards a shelf's contents and the manager's
+// order index are split into when the layout does not say
+const DefaultShardCount = 16
+
+// shardedMap is a map by order ID split over independently locked shards.
+// A lookup locks only the shard holding the key, so at thousands of orders
+// per second readers and writers of different orders do not queue behind
+// one mutex. Its length is kept atomically, so reading it locks nothing.
+type shardedMap[V any] struct {
+	seed   maphash.Seed
+	length atomic.Int64
+	shards []mapShard[V]
+}
+
+type mapShard[V any] struct {
+	mutex   sync.RWMutex
+	entries map[string]V
+}
+
+// newShardedMap creates a map of n shards, DefaultShardCount if n is not
+// positive
+func newShardedMap[V any](n int) *shardedMap[V] {
+	if n <= 0 {
+		n = DefaultShardCount
+	}
+	m := &shardedMap[V]{seed: maphash.MakeSeed(), shards: make([]mapShard[V], n)}
+	for i := range m.shards {
+		m.shards[i].entries = make(map[string]V)
+	}
+	return m
+}
+
+func (m *shardedMap[V]) shard(key string) *mapShard[V] {
+	return &m.shards[maphash.String(m.seed, key)%uint64(len(m.shards))]
+}
+
+func (m *shardedMap[V]) get(key string) (V, bool) {
+	sh := m.shard(key)
+	sh.mutex.RLock()
+	defer sh.mutex.RUnlock()
+
+	v, ok := sh.entries[key]
+	return v, ok
+}
+
+func (m *shardedMap[V]) put(key string, v V) {
+	sh := m.shard(key)
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	if _, ok := sh.entries[key]; !ok {
+		m.length.Add(1)
+	}
+	sh.entries[key] = v
+}
+
+// remove deletes the key, reporting whether it was present
+func (m *shardedMap[V]) remove(key string) bool {
+	sh := m.shard(key)
+	sh.mutex.Lock()
+	defer sh.mutex.Unlock()
+
+	if _, ok := sh.entries[key]; !ok {
+		return false
+	}
+	delete(sh.entries, key)
+	m.length.Add(-1)
+	return true
+}
+
+func (m *shardedMap[V]) len() int {
+	return int(m.length.Load())
+}
+
+// values returns every value, locking one shard at a time. Entries added or
+// removed meanwhile may or may not be included.
+func (m *shardedMap[V]) values() []V {
+	values := make([]V, 0, m.len())
+	for i := range m.shards {
+		sh := &m.shards[i]
+		sh.mutex.RLock()
+		for _, v := range sh.entries {
+			values = append(values, v)
+		}
+		sh.mutex.RUnlock()
+	}
+	return values
+}
@@ -0,0 +1,145 @@
+package shelf_test
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestShelf_ConcurrentCapacity(t *testing.T) {
+	sm, err := shelf.NewShelfManagerWithLayout([]shelf.ShelfSpec{
+		{Type: "cold", Capacity: 50, Temps: []order.Temperature{order.Cold}, Shards: 8},
+	})
+	require.NoError(t, err)
+	s := sm.GetShelf("cold")
+
+	var added atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			o := &order.Order{ID: fmt.Sprintf("order-%d", i), Temp: order.Cold}
+			if s.AddOrder(o) {
+				added.Add(1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(50), added.Load())
+	assert.Equal(t, 50, s.Size())
+	assert.Len(t, s.GetAllOrders(), 50)
+	assert.True(t, s.IsFull())
+	assert.Equal(t, 50, s.GetStats().PeakUsage)
+}
+
+func TestShelfManager_ConcurrentPlaceDeliver(t *testing.T) {
+	sm := shelf.NewShelfManager(1000, 1000, 1000, 0)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("order-%d-%d", w, i)
+				assert.NoError(t, sm.PlaceOrder(&order.Order{ID: id, Temp: benchTemps[i%len(benchTemps)], ShelfLife: 300}))
+				o, _ := sm.LocateOrder(id)
+				assert.NotNil(t, o)
+				if i%2 == 0 {
+					assert.True(t, sm.DeliverOrder(id))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.Len(t, sm.GetAllOrders(), 400)
+	o, s := sm.LocateOrder("order-0-1")
+	require.NotNil(t, o)
+	assert.Equal(t, o, s.GetOrder(o.ID))
+	o, _ = sm.LocateOrder("order-0-0")
+	assert.Nil(t, o)
+}
+
+// benchShardCounts compares one lock per shelf with the default sharding
+var benchShardCounts = []int{1, shelf.DefaultShardCount}
+
+// newShardedManager returns a manager with roomy shelves split into the
+// given number of shards
+func newShardedManager(b *testing.B, shards int) *shelf.InMemoryShelfManager {
+	layout := shelf.DefaultLayout(1<<20, 1<<20, 1<<20, 0)
+	for i := range layout {
+		layout[i].Shards = shards
+	}
+	sm, err := shelf.NewShelfManagerWithLayout(layout)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return sm
+}
+
+func BenchmarkShelf_ParallelAddDeliver(b *testing.B) {
+	for _, shards := range benchShardCounts {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			s := newShardedManager(b, shards).GetShelf(shelf.HotShelf)
+
+			var counter atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					id := fmt.Sprintf("order-%d", counter.Add(1))
+					s.AddOrder(&order.Order{ID: id, Temp: order.Hot})
+					s.MarkOrderDelivered(id)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkShelfManager_ParallelPlaceDeliver(b *testing.B) {
+	for _, shards := range benchShardCounts {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			sm := newShardedManager(b, shards)
+
+			var counter atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					n := counter.Add(1)
+					id := fmt.Sprintf("order-%d", n)
+					sm.PlaceOrder(&order.Order{ID: id, Temp: benchTemps[n%int64(len(benchTemps))], ShelfLife: 300})
+					sm.DeliverOrder(id)
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkShelfManager_ParallelLocateOrder(b *testing.B) {
+	const size = 10000
+	for _, shards := range benchShardCounts {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			sm := newShardedManager(b, shards)
+			for i := 0; i < size; i++ {
+				sm.PlaceOrder(&order.Order{ID: fmt.Sprintf("order-%d", i), Temp: benchTemps[i%len(benchTemps)], ShelfLife: 300})
+			}
+
+			var counter atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					sm.LocateOrder(fmt.Sprintf("order-%d", counter.Add(1)%size))
+				}
+			})
+		})
+	}
+}
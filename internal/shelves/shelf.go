@@ -1,9 +1,9 @@
 package shelf
 
 import (
-	"sync"
 	"time"
 
+	"dish-dispatcher/internal/clock"
 	"dish-dispatcher/internal/order"
 )
 
@@ -19,210 +19,422 @@ const (
 type Shelf struct {
 	Type     ShelfType
 	Capacity int
-	mutex    sync.Mutex
+	Volume   float64             // total order size held, 0 to limit by count only
+	Temps    []order.Temperature // routed here first; empty on overflow shelves
 	stats    ShelfStats
-	Orders   map[string]*order.Order
+
+	// mutex serializes changes to the shelf. The orders themselves are
+	// sharded, so lookups and snapshots lock only their shard and never
+	// wait for the shelf; writers hold both.
+	mutex  instrumentedRWMutex
+	orders *shardedMap[*order.Order]
+
+	// used is the total Volume of the orders held
+	used float64
+
+	// overflow shelves accept any temperature and start the overflow phase
+	// of an order's decay
+	overflow bool
+
+	// decayModifier multiplies the decay of every order held here, or is
+	// zero for normal decay
+	decayModifier float64
+
+	// outageFactor is the decay multiplier while the shelf has lost
+	// cooling, or zero when it is working normally
+	outageFactor float64
+
+	// grace is how long expired orders stay on the shelf before they are
+	// removed. It is set before any order is placed.
+	grace time.Duration
+
+	// reserved holds orders a courier is on the way to collect, until the
+	// time given, past any expiry
+	reserved map[string]time.Time
+
+	// listeners watch the shelf, and changes are waiting for them until
+	// the lock is released
+	listeners shelfListeners
+	changes   []shelfChange
+
+	clock clock.Clock
 }
 
 type ShelfStats struct {
 	OrdersAdded     int
 	OrdersRemoved   int
-	OrdersWasted    int
+	OrdersWasted    int // turned away because every shelf they could use was full
+	OrdersRefused   int // wasted at pickup, worth less than the minimum delivery value
+	OrdersExpired   int // decayed to zero while on the shelf
 	OrdersDelivered int
 	PeakUsage       int
 }
 
+// NewShelf creates a shelf of one of the default layout's types
 func NewShelf(shelfType ShelfType, capacity int) *Shelf {
+	for _, spec := range DefaultLayout(capacity, capacity, capacity, capacity) {
+		if spec.Type == shelfType {
+			return newShelf(spec)
+		}
+	}
+	return newShelf(ShelfSpec{Type: shelfType, Capacity: capacity})
+}
+
+func newShelf(spec ShelfSpec) *Shelf {
 	return &Shelf{
-		Type:     shelfType,
-		Capacity: capacity,
-		Orders:   make(map[string]*order.Order),
+		Type:          spec.Type,
+		Capacity:      spec.Capacity,
+		Volume:        spec.Volume,
+		Temps:         spec.Temps,
+		orders:        newShardedMap[*order.Order](spec.Shards),
+		overflow:      spec.IsOverflow(),
+		decayModifier: spec.DecayModifier,
+		clock:         clock.Real{},
 	}
 }
-func (s *Shelf) Size() int {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
 
-	return len(s.Orders)
+// decayFactor is the combined decay multiplier of the shelf's modifier and
+// any outage. Callers must hold the shelf lock.
+func (s *Shelf) decayFactor() float64 {
+	factor := 1.0
+	if s.decayModifier > 0 {
+		factor *= s.decayModifier
+	}
+	if s.outageFactor > 0 {
+		factor *= s.outageFactor
+	}
+	return factor
+}
+func (s *Shelf) Size() int {
+	return s.orders.len()
 }
 
+// IsFull reports whether the shelf has no room for even a one-unit order
 func (s *Shelf) IsFull() bool {
-	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return !s.fits(1)
+}
+
+// volumeTolerance absorbs floating-point error in the running volume total
+const volumeTolerance = 1e-9
 
-	return len(s.Orders) >= s.Capacity
+// fits reports whether an order of the given volume fits in the remaining
+// space. Callers must hold the shelf lock.
+func (s *Shelf) fits(volume float64) bool {
+	return s.fitsAfter(0, 0, volume)
+}
+
+// fitsAfter reports whether an order of the given volume would fit once n
+// more orders, taking up extra volume, were added. Callers must hold the
+// shelf lock.
+func (s *Shelf) fitsAfter(n int, extra, volume float64) bool {
+	if s.Volume <= 0 {
+		return s.orders.len()+n < s.Capacity
+	}
+	if s.Capacity > 0 && s.orders.len()+n >= s.Capacity {
+		return false
+	}
+	return s.used+extra+volume <= s.Volume+volumeTolerance
+}
+
+// UsedVolume returns the total Volume of the orders on the shelf
+func (s *Shelf) UsedVolume() float64 {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.used
+}
+
+// take removes an order from the shelf's contents and frees its space.
+// Callers must hold the shelf lock.
+func (s *Shelf) take(o *order.Order) {
+	s.orders.remove(o.ID)
+	delete(s.reserved, o.ID)
+	s.used -= o.Volume()
+	if s.orders.len() == 0 {
+		s.used = 0
+	}
+	s.recordChange(o, false)
 }
 
 func (s *Shelf) GetStats() ShelfStats {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.stats
+}
+
+// resetStats restarts the shelf's stats, counting the orders it holds as
+// added, and returns how many it holds
+func (s *Shelf) resetStats() int {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	return s.stats
+	held := s.orders.len()
+	s.stats = ShelfStats{OrdersAdded: held, PeakUsage: held}
+	return held
+}
+
+// LockStats reports contention on the shelf's lock
+func (s *Shelf) LockStats() LockStats {
+	return s.mutex.Stats()
 }
 
 func (s *Shelf) MarkOrderDelivered(orderID string) bool {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	defer s.unlock()
 
-	order, exists := s.Orders[orderID]
+	o, exists := s.orders.get(orderID)
 	if !exists {
 		return false
 	}
 
-	delete(s.Orders, orderID)
-	order.DeliveredAt = time.Now()
+	// With no courier to carry it, pickup and drop-off are one instant
+	now := s.clock.Now()
+	if o.Transition(order.StateInTransit, now) != nil || o.Transition(order.StateDelivered, now) != nil {
+		return false
+	}
+
+	s.take(o)
 	s.stats.OrdersDelivered++
 	s.stats.OrdersRemoved++
 
 	return true
 }
 
-func (s *Shelf) RemoveExpiredOrders() int {
+// tryDeliver removes an order for delivery, leaving it in transit until the
+// courier hands it over, and returns it with its value at pickup. An order
+// that has expired is removed as expired instead, and one worth less than
+// minValue as wasted. It returns a nil order if the shelf no longer holds
+// it.
+func (s *Shelf) tryDeliver(orderID string, minValue float64) (*order.Order, float64, outcome) {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	defer s.unlock()
+
+	o, exists := s.orders.get(orderID)
+	if !exists {
+		return nil, 0, outcomeExpired
+	}
 
-	now := time.Now()
-	expiredCount := 0
+	// The courier finds it spoiled, reserved or not, and discards it
+	now := s.clock.Now()
+	if o.IsExpired(now) {
+		if o.Transition(order.StateExpired, now) != nil {
+			return nil, 0, outcomeExpired
+		}
+		s.take(o)
+		s.stats.OrdersExpired++
+		return o, 0, outcomeExpired
+	}
 
-	for id, order := range s.Orders {
-		if order.IsExpired(now) {
-			delete(s.Orders, id)
-			order.WastedAt = now
-			s.stats.OrdersWasted++
-			expiredCount++
+	value := o.CalculateValue(now)
+	if value < minValue {
+		if o.Transition(order.StateWasted, now) != nil {
+			return nil, 0, outcomeWasted
 		}
+		s.take(o)
+		s.stats.OrdersRefused++
+		s.stats.OrdersRemoved++
+		return o, value, outcomeWasted
 	}
+	if o.Transition(order.StateInTransit, now) != nil {
+		return nil, 0, outcomeDelivered
+	}
+	s.take(o)
+	s.stats.OrdersDelivered++
+	s.stats.OrdersRemoved++
+	return o, value, outcomeDelivered
+}
 
-	return expiredCount
+func (s *Shelf) RemoveExpiredOrders() int {
+	return len(s.removeExpired())
 }
 
-func (s *Shelf) GetAllOrders() []*order.Order {
+// removeExpired removes expired orders and returns them
+func (s *Shelf) removeExpired() []*order.Order {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	defer s.unlock()
+
+	now := s.clock.Now()
+	var expired []*order.Order
+
+	// Orders are only removed once their grace period has passed too, and
+	// reserved ones once their courier had time to collect them
+	cutoff := now.Add(-s.grace)
+	for _, o := range s.orders.values() {
+		if o.IsExpired(cutoff) && !s.isReserved(o.ID, now) && o.Transition(order.StateExpired, now) == nil {
+			s.take(o)
+			s.stats.OrdersExpired++
+			expired = append(expired, o)
+		}
+	}
 
-	orders := make([]*order.Order, 0, len(s.Orders))
-	for _, order := range s.Orders {
-		orders = append(orders, order)
+	return expired
+}
+
+// expireOrder removes a single order as expired, returning false if it is
+// no longer on the shelf or is reserved
+func (s *Shelf) expireOrder(orderID string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.unlock()
+
+	o, exists := s.orders.get(orderID)
+	if !exists || s.isReserved(orderID, now) || o.Transition(order.StateExpired, now) != nil {
+		return false
 	}
 
-	return orders
+	s.take(o)
+	s.stats.OrdersExpired++
+
+	return true
 }
 
-func (s *Shelf) GetOrder(orderID string) *order.Order {
+// recordWaste counts an order wasted because this shelf, its last option,
+// was full
+func (s *Shelf) recordWaste() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	return s.Orders[orderID]
+	s.stats.OrdersWasted++
+}
+
+func (s *Shelf) GetAllOrders() []*order.Order {
+	return s.orders.values()
+}
+
+func (s *Shelf) GetOrder(orderID string) *order.Order {
+	o, _ := s.orders.get(orderID)
+	return o
 }
 
 func (s *Shelf) RemoveOrder(orderID string) *order.Order {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	defer s.unlock()
 
-	order, exists := s.Orders[orderID]
+	order, exists := s.orders.get(orderID)
 	if !exists {
 		return nil
 	}
 
-	delete(s.Orders, orderID)
+	s.take(order)
+	order.CloseDecayWindows(s.clock.Now())
 	s.stats.OrdersRemoved++
 
 	return order
 }
 
-func (s *Shelf) AddOrder(order *order.Order) bool {
+func (s *Shelf) AddOrder(o *order.Order) bool {
 	s.mutex.Lock()
-	defer s.mutex.Unlock()
+	defer s.unlock()
+
+	if !s.fits(o.Volume()) {
+		return false
+	}
 
-	if len(s.Orders) >= s.Capacity {
+	now := s.clock.Now()
+	if o.Transition(order.StateShelved, now) != nil {
 		return false
 	}
 
+	s.hold(o, now)
+	return true
+}
+
+// hold puts a shelved order on the shelf, starting its decay here. Callers
+// must hold the shelf lock and have checked that it fits.
+func (s *Shelf) hold(o *order.Order, now time.Time) {
 	// Set placement time if not already set
-	if order.PlacedOnShelfAt.IsZero() {
-		order.PlacedOnShelfAt = time.Now()
+	if o.PlacedOnShelfAt.IsZero() {
+		o.PlacedOnShelfAt = now
 	}
 
 	// Update order current shelf
-	order.CurrentShelfType = string(s.Type)
+	o.CurrentShelfType = string(s.Type)
+
+	// Orders placed on a modified shelf, or during an outage, start
+	// decaying at its rate straight away
+	if factor := s.decayFactor(); factor != 1 {
+		o.OpenDecayWindow(now, factor)
+	}
 
 	// If we're moving to overflow shelf, track time
-	if s.Type == OverflowShelf {
-		// We only care about time on overflow shelf
-		// This doesn't reset if the order moves back to a regular shelf
-		if order.PlacedOnOverflow.IsZero() {
-			order.PlacedOnOverflow = time.Now()
+	if s.overflow {
+		// We only care about time on overflow shelf. Moving between
+		// overflow shelves continues the same stay.
+		if o.PlacedOnOverflow.IsZero() {
+			o.PlacedOnOverflow = now
 		}
 	}
 
-	s.Orders[order.ID] = order
+	s.orders.put(o.ID, o)
+	s.used += o.Volume()
 	s.stats.OrdersAdded++
 
 	// Update peak usage
-	if len(s.Orders) > s.stats.PeakUsage {
-		s.stats.PeakUsage = len(s.Orders)
+	if size := s.orders.len(); size > s.stats.PeakUsage {
+		s.stats.PeakUsage = size
 	}
-
-	return true
+	s.recordChange(o, true)
 }
 
-func (sm *ShelfManager) GetStats() map[string]interface{} {
-	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
-
-	return map[string]interface{}{
-		"hotShelf": map[string]interface{}{
-			"capacity": sm.HotShelf.Capacity,
-			"current":  sm.HotShelf.Size(),
-			"stats":    sm.HotShelf.GetStats(),
-		},
-		"coldShelf": map[string]interface{}{
-			"capacity": sm.ColdShelf.Capacity,
-			"current":  sm.ColdShelf.Size(),
-			"stats":    sm.ColdShelf.GetStats(),
-		},
-		"frozenShelf": map[string]interface{}{
-			"capacity": sm.FrozenShelf.Capacity,
-			"current":  sm.FrozenShelf.Size(),
-			"stats":    sm.FrozenShelf.GetStats(),
-		},
-		"overflowShelf": map[string]interface{}{
-			"capacity": sm.OverflowShelf.Capacity,
-			"current":  sm.OverflowShelf.Size(),
-			"stats":    sm.OverflowShelf.GetStats(),
-		},
-		"totalOrders": map[string]interface{}{
-			"received":  sm.TotalOrdersReceived,
-			"delivered": sm.TotalOrdersDelivered,
-			"expired":   sm.TotalOrdersExpired,
-			"wasted":    sm.TotalOrdersWasted,
-		},
-	}
-}
-
-func (sm *ShelfManager) GetAllOrders() []*order.Order {
-	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
+// GetStats returns the run totals, outcome breakdowns and, under
+// "<type>Shelf", each shelf's capacity, size and counters
+func (sm *InMemoryShelfManager) GetStats() map[string]interface{} {
+	// Shelf stats are read first, without the manager lock held
+	stats := make(map[string]interface{}, len(sm.shelves)+4)
+	for _, s := range sm.shelves {
+		stats[string(s.Type)+"Shelf"] = map[string]interface{}{
+			"capacity":  s.Capacity,
+			"current":   s.Size(),
+			"stats":     s.GetStats(),
+			"lockStats": s.LockStats(),
+		}
+	}
+
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	stats["managerLockStats"] = sm.mutex.Stats()
+	stats["byName"] = copyItemStats(sm.statsByName)
+	stats["byTemperature"] = copyItemStats(sm.statsByTemp)
+	stats["rejections"] = copyRejections(sm.rejections)
+	stats["totalOrders"] = map[string]interface{}{
+		"received":  sm.TotalOrdersReceived,
+		"delivered": sm.TotalOrdersDelivered,
+		"expired":   sm.TotalOrdersExpired,
+		"wasted":    sm.TotalOrdersWasted,
+		"evicted":   sm.TotalOrdersEvicted,
+		"refused":   sm.TotalOrdersRefused,
+		"moved":     sm.TotalOrdersMoved,
+	}
+
+	return stats
+}
 
+func (sm *InMemoryShelfManager) GetAllOrders() []*order.Order {
 	allOrders := make([]*order.Order, 0)
-	allOrders = append(allOrders, sm.HotShelf.GetAllOrders()...)
-	allOrders = append(allOrders, sm.ColdShelf.GetAllOrders()...)
-	allOrders = append(allOrders, sm.FrozenShelf.GetAllOrders()...)
-	allOrders = append(allOrders, sm.OverflowShelf.GetAllOrders()...)
+	for _, s := range sm.shelves {
+		allOrders = append(allOrders, s.GetAllOrders()...)
+	}
 
 	return allOrders
 }
 
-func (sm *ShelfManager) RemoveExpiredOrders() int {
-	sm.mutex.Lock()
-	defer sm.mutex.Unlock()
-
-	expiredCount := 0
-	expiredCount += sm.HotShelf.RemoveExpiredOrders()
-	expiredCount += sm.ColdShelf.RemoveExpiredOrders()
-	expiredCount += sm.FrozenShelf.RemoveExpiredOrders()
-	expiredCount += sm.OverflowShelf.RemoveExpiredOrders()
+func (sm *InMemoryShelfManager) RemoveExpiredOrders() int {
+	expired := make([]*order.Order, 0)
+	for _, s := range sm.shelves {
+		expired = append(expired, s.removeExpired()...)
+	}
+	sm.recordExpired(expired)
+	return len(expired)
+}
 
-	sm.TotalOrdersExpired += expiredCount
-	return expiredCount
+// recordExpired unindexes orders a sweep removed and records their outcome
+func (sm *InMemoryShelfManager) recordExpired(expired []*order.Order) {
+	for _, o := range expired {
+		sm.unindexOrder(o.ID)
+		sm.recordOutcome(o, outcomeExpired, o.ExpiredAt())
+	}
 }
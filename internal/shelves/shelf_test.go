@@ -30,6 +30,21 @@ func TestShelf_IsFull(t *testing.T) {
 	assert.False(t, s.AddOrder(o2))
 }
 
+func TestShelf_IsFullByVolume(t *testing.T) {
+	s := shelf.NewShelf(shelf.ColdShelf, 0)
+	s.Volume = 1.5
+	big := order.NewOrder("Cake", order.Cold, 300, 0.2)
+	big.Size = 1.2
+
+	assert.True(t, s.AddOrder(big))
+	assert.True(t, s.IsFull())
+	assert.False(t, s.AddOrder(order.NewOrder("Juice", order.Cold, 300, 0.2)))
+
+	s.RemoveOrder(big.ID)
+	assert.Zero(t, s.UsedVolume())
+	assert.False(t, s.IsFull())
+}
+
 func TestShelf_RemoveOrder(t *testing.T) {
 	s := shelf.NewShelf(shelf.FrozenShelf, 2)
 	o := order.NewOrder("FrozenPizza", order.Frozen, 300, 0.1)
@@ -48,7 +63,7 @@ func TestShelf_MarkOrderDelivered(t *testing.T) {
 	marked := s.MarkOrderDelivered(o.ID)
 	assert.True(t, marked)
 	assert.Equal(t, 0, s.Size())
-	assert.False(t, o.DeliveredAt.IsZero())
+	assert.False(t, o.DeliveredAt().IsZero())
 }
 
 func TestShelf_RemoveExpiredOrders(t *testing.T) {
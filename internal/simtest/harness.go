@@ -0,0 +1,222 @@
+// Package simtest drives a ShelfManager through a scripted scenario on a fake
+// clock, so dispatch strategies can be tested quickly and deterministically
+// without running the real-time simulator.
+//
+// A scenario is a list of steps at offsets from the start of the run:
+//
+//	h := simtest.NewInMemory(1, 1, 1, 2)
+//	h.Place(0, "pizza", simulator.OrderData{Name: "Pizza", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+//	h.Deliver(10*time.Second, "pizza")
+//	result, err := h.Run(time.Minute)
+//	result.AssertOutcome(t, "pizza", simtest.Delivered)
+package simtest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+)
+
+// Start is the fake clock's time at offset zero of every run
+var Start = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// ManagerFactory builds the manager under test on the harness clock. The
+// manager must take every timestamp from the clock for runs to be
+// deterministic.
+type ManagerFactory func(c clock.Clock) shelf.ShelfManager
+
+// Harness scripts a scenario against one manager
+type Harness struct {
+	Clock   *clock.Fake
+	Manager shelf.ShelfManager
+
+	// DecayModifier scales every order's decay rate, as the simulator's
+	// decayModifier setting does. It defaults to 1.
+	DecayModifier float64
+	// Formula is the decay formula of every order; nil means classic
+	Formula order.DecayFormula
+
+	steps  []step
+	orders map[string]*order.Order
+}
+
+type stepKind int
+
+const (
+	stepPlace stepKind = iota
+	stepDeliver
+	stepStartOutage
+	stepEndOutage
+)
+
+type step struct {
+	at    time.Duration
+	kind  stepKind
+	label string
+	data  simulator.OrderData
+
+	shelf  shelf.ShelfType
+	factor float64
+}
+
+// New creates a harness around the manager built by factory
+func New(factory ManagerFactory) *Harness {
+	c := clock.NewFake(Start)
+	return &Harness{
+		Clock:         c,
+		Manager:       factory(c),
+		DecayModifier: 1,
+		orders:        make(map[string]*order.Order),
+	}
+}
+
+// NewInMemory creates a harness around the default in-memory manager
+func NewInMemory(hotCapacity, coldCapacity, frozenCapacity, overflowCapacity int) *Harness {
+	return New(func(c clock.Clock) shelf.ShelfManager {
+		manager := shelf.NewShelfManager(hotCapacity, coldCapacity, frozenCapacity, overflowCapacity)
+		manager.SetClock(c)
+		return manager
+	})
+}
+
+// Place schedules an order to be placed at the given offset. Later steps
+// refer to it by label.
+func (h *Harness) Place(at time.Duration, label string, data simulator.OrderData) *Harness {
+	h.steps = append(h.steps, step{at: at, kind: stepPlace, label: label, data: data})
+	return h
+}
+
+// Feed schedules a list of orders, one every interval from start, labelled
+// "order-0", "order-1" and so on in list order
+func (h *Harness) Feed(start, interval time.Duration, orders []simulator.OrderData) *Harness {
+	for i, data := range orders {
+		h.Place(start+time.Duration(i)*interval, FeedLabel(i), data)
+	}
+	return h
+}
+
+// FeedLabel returns the label Feed gives the i-th order
+func FeedLabel(i int) string {
+	return fmt.Sprintf("order-%d", i)
+}
+
+// Deliver schedules a courier pickup of a placed order at the given offset
+func (h *Harness) Deliver(at time.Duration, label string) *Harness {
+	h.steps = append(h.steps, step{at: at, kind: stepDeliver, label: label})
+	return h
+}
+
+// Outage schedules a shelf losing cooling at the given offset for duration
+func (h *Harness) Outage(at, duration time.Duration, shelfType shelf.ShelfType, factor float64) *Harness {
+	h.steps = append(h.steps,
+		step{at: at, kind: stepStartOutage, shelf: shelfType, factor: factor},
+		step{at: at + duration, kind: stepEndOutage, shelf: shelfType},
+	)
+	return h
+}
+
+// Run plays the scenario until the given offset and returns the outcome.
+// Steps at the same offset run in the order they were added. Orders expire
+// at their exact expiry times in between steps.
+func (h *Harness) Run(until time.Duration) (*Result, error) {
+	steps := append([]step(nil), h.steps...)
+	sort.SliceStable(steps, func(i, j int) bool { return steps[i].at < steps[j].at })
+
+	result := newResult()
+	for _, st := range steps {
+		if st.at > until {
+			break
+		}
+		h.advance(Start.Add(st.at))
+		if err := h.apply(st, result); err != nil {
+			return nil, err
+		}
+	}
+	h.advance(Start.Add(until))
+
+	h.settle(result)
+	return result, nil
+}
+
+// advance moves the clock to target, expiring orders at each due time on
+// the way
+func (h *Harness) advance(target time.Time) {
+	for {
+		next, ok := h.Manager.NextExpiry()
+		if !ok || next.After(target) {
+			break
+		}
+		if next.After(h.Clock.Now()) {
+			h.Clock.Set(next)
+		}
+		h.Manager.RemoveDueOrders(h.Clock.Now())
+
+		// Guard against managers whose schedule does not move past now
+		if again, ok := h.Manager.NextExpiry(); ok && !again.After(h.Clock.Now()) {
+			break
+		}
+	}
+	if target.After(h.Clock.Now()) {
+		h.Clock.Set(target)
+	}
+}
+
+func (h *Harness) apply(st step, result *Result) error {
+	switch st.kind {
+	case stepPlace:
+		if _, exists := h.orders[st.label]; exists {
+			return fmt.Errorf("order %q placed twice", st.label)
+		}
+		o := st.data.NewOrder(h.DecayModifier, h.Formula)
+		o.CreatedAt = h.Clock.Now()
+		h.orders[st.label] = o
+		if h.Manager.PlaceOrder(o) == nil {
+			result.outcomes[st.label] = Shelved
+		} else {
+			result.outcomes[st.label] = Wasted
+		}
+	case stepDeliver:
+		o, exists := h.orders[st.label]
+		if !exists {
+			return fmt.Errorf("order %q delivered before it was placed", st.label)
+		}
+		if h.Manager.DeliverOrder(o.ID) {
+			result.outcomes[st.label] = Delivered
+			result.values[st.label] = o.CalculateValue(h.Clock.Now())
+		}
+	case stepStartOutage:
+		return h.Manager.StartOutage(st.shelf, st.factor)
+	case stepEndOutage:
+		return h.Manager.EndOutage(st.shelf)
+	}
+	return nil
+}
+
+// settle marks shelved orders that are no longer on a shelf as expired and
+// copies the manager's final stats into the result
+func (h *Harness) settle(result *Result) {
+	onShelf := make(map[string]bool)
+	for _, o := range h.Manager.GetAllOrders() {
+		onShelf[o.ID] = true
+	}
+	for label, o := range h.orders {
+		if result.outcomes[label] == Shelved && !onShelf[o.ID] {
+			result.outcomes[label] = Expired
+		}
+	}
+
+	result.Stats = h.Manager.GetStats()
+	result.ByName = h.Manager.StatsByName()
+	result.ByTemperature = h.Manager.StatsByTemperature()
+	if totals, ok := result.Stats["totalOrders"].(map[string]interface{}); ok {
+		result.Totals.Received, _ = totals["received"].(int)
+		result.Totals.Delivered, _ = totals["delivered"].(int)
+		result.Totals.Expired, _ = totals["expired"].(int)
+		result.Totals.Wasted, _ = totals["wasted"].(int)
+	}
+}
@@ -0,0 +1,88 @@
+package simtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simtest"
+	"dish-dispatcher/internal/simulator"
+)
+
+var burger = simulator.OrderData{Name: "Burger", Temp: "hot", ShelfLife: 100, DecayRate: 0.5}
+
+func TestHarness_Outcomes(t *testing.T) {
+	h := simtest.NewInMemory(1, 1, 1, 1)
+	h.Place(0, "first", burger)
+	h.Place(0, "overflowed", burger)
+	h.Place(0, "wasted", burger)
+	h.Deliver(20*time.Second, "first")
+
+	result, err := h.Run(4 * time.Minute)
+	require.NoError(t, err)
+
+	result.AssertOutcome(t, "first", simtest.Delivered)
+	result.AssertOutcome(t, "overflowed", simtest.Expired)
+	result.AssertOutcome(t, "wasted", simtest.Wasted)
+	result.AssertTotals(t, simtest.Totals{Received: 3, Delivered: 1, Expired: 1, Wasted: 1})
+
+	// Classic decay: (100 - 0.5*20) / 100
+	result.AssertDeliveredValue(t, "first", 0.9, 1e-9)
+	assert.Equal(t, 1, result.ByName["Burger"].Delivered)
+}
+
+func TestHarness_ExpiresAtExactTime(t *testing.T) {
+	h := simtest.NewInMemory(1, 1, 1, 1)
+	h.Place(0, "burger", burger)
+
+	// The burger reaches zero at 100/0.5 = 200s
+	result, err := h.Run(199 * time.Second)
+	require.NoError(t, err)
+	result.AssertOutcome(t, "burger", simtest.Shelved)
+
+	h = simtest.NewInMemory(1, 1, 1, 1)
+	h.Place(0, "burger", burger)
+	result, err = h.Run(200 * time.Second)
+	require.NoError(t, err)
+	result.AssertOutcome(t, "burger", simtest.Expired)
+}
+
+func TestHarness_Deterministic(t *testing.T) {
+	run := func() *simtest.Result {
+		h := simtest.NewInMemory(2, 2, 2, 2)
+		h.Feed(0, time.Second, []simulator.OrderData{burger, burger, burger, burger, burger})
+		h.Outage(2*time.Second, 10*time.Second, shelf.HotShelf, 3)
+		h.Deliver(5*time.Second, simtest.FeedLabel(0))
+		h.Deliver(30*time.Second, simtest.FeedLabel(3))
+
+		result, err := h.Run(time.Minute)
+		require.NoError(t, err)
+		return result
+	}
+
+	first, second := run(), run()
+	assert.Equal(t, first.Totals, second.Totals)
+	for i := 0; i < 5; i++ {
+		a, _ := first.Outcome(simtest.FeedLabel(i))
+		b, _ := second.Outcome(simtest.FeedLabel(i))
+		assert.Equal(t, a, b)
+	}
+	a, _ := first.DeliveredValue(simtest.FeedLabel(0))
+	b, _ := second.DeliveredValue(simtest.FeedLabel(0))
+	assert.Equal(t, a, b)
+}
+
+func TestHarness_Errors(t *testing.T) {
+	h := simtest.NewInMemory(1, 1, 1, 1)
+	h.Deliver(0, "missing")
+	_, err := h.Run(time.Second)
+	assert.Error(t, err)
+
+	h = simtest.NewInMemory(1, 1, 1, 1)
+	h.Place(0, "dup", burger).Place(time.Second, "dup", burger)
+	_, err = h.Run(time.Minute)
+	assert.Error(t, err)
+}
@@ -0,0 +1,111 @@
+package simtest
+
+import (
+	"testing"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// Outcome is what happened to one scripted order
+type Outcome int
+
+const (
+	Shelved   Outcome = iota // still on a shelf when the run ended
+	Delivered                // picked up by a courier
+	Wasted                   // no shelf space when placed
+	Expired                  // decayed to zero on a shelf
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Shelved:
+		return "shelved"
+	case Delivered:
+		return "delivered"
+	case Wasted:
+		return "wasted"
+	case Expired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// Totals are the run-wide order counters
+type Totals struct {
+	Received  int
+	Delivered int
+	Expired   int
+	Wasted    int
+}
+
+// Result is the final state of a scripted run
+type Result struct {
+	Totals        Totals
+	Stats         map[string]interface{} // the manager's GetStats
+	ByName        map[string]shelf.ItemStats
+	ByTemperature map[order.Temperature]shelf.ItemStats
+
+	outcomes map[string]Outcome
+	values   map[string]float64
+}
+
+func newResult() *Result {
+	return &Result{
+		outcomes: make(map[string]Outcome),
+		values:   make(map[string]float64),
+	}
+}
+
+// Outcome returns what happened to the labelled order, and false if no
+// order with that label was placed
+func (r *Result) Outcome(label string) (Outcome, bool) {
+	outcome, ok := r.outcomes[label]
+	return outcome, ok
+}
+
+// DeliveredValue returns the labelled order's value when it was delivered,
+// and false if it was not delivered
+func (r *Result) DeliveredValue(label string) (float64, bool) {
+	value, ok := r.values[label]
+	return value, ok
+}
+
+// AssertTotals fails the test if the run-wide counters differ from want
+func (r *Result) AssertTotals(t testing.TB, want Totals) {
+	t.Helper()
+
+	if r.Totals != want {
+		t.Errorf("totals = %+v, want %+v", r.Totals, want)
+	}
+}
+
+// AssertOutcome fails the test if the labelled order did not end as want
+func (r *Result) AssertOutcome(t testing.TB, label string, want Outcome) {
+	t.Helper()
+
+	got, ok := r.outcomes[label]
+	if !ok {
+		t.Errorf("order %q was never placed", label)
+		return
+	}
+	if got != want {
+		t.Errorf("order %q outcome = %v, want %v", label, got, want)
+	}
+}
+
+// AssertDeliveredValue fails the test unless the labelled order was
+// delivered with a value within tolerance of want
+func (r *Result) AssertDeliveredValue(t testing.TB, label string, want, tolerance float64) {
+	t.Helper()
+
+	got, ok := r.values[label]
+	if !ok {
+		t.Errorf("order %q was not delivered", label)
+		return
+	}
+	if got < want-tolerance || got > want+tolerance {
+		t.Errorf("order %q delivered value = %v, want %v ± %v", label, got, want, tolerance)
+	}
+}
@@ -0,0 +1,193 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+// Errors returned by Submit for orders the admission policy did not accept
+var (
+	ErrDeferred = errors.New("order deferred by the admission policy")
+	ErrRejected = errors.New("order rejected by the admission policy")
+)
+
+// admission consults the configured policy before new orders are placed
+// and holds the orders it defers. The zero value accepts every order.
+type admission struct {
+	policy   plugin.AdmissionPolicy
+	maxDefer time.Duration
+
+	mutex    sync.Mutex // serializes the policy and guards the fields below
+	deferred []deferredOrder
+	stats    admissionStats
+}
+
+// deferredOrder is an order waiting to be admitted
+type deferredOrder struct {
+	data  OrderData
+	since time.Time
+}
+
+// admissionStats counts the policy's decisions
+type admissionStats struct {
+	deferred int // orders deferred at least once
+	admitted int // deferred orders placed in the end
+	rejected int // including deferred orders that waited too long
+}
+
+// newAdmissionPolicy creates the configured admission policy, or nil to
+// accept every order
+func newAdmissionPolicy(cfg config.AdmissionConfig) (plugin.AdmissionPolicy, error) {
+	if cfg.Policy == "" {
+		return nil, nil
+	}
+	if cfg.MaxDefer <= 0 {
+		return nil, fmt.Errorf("admission maxDefer must be positive, got %v", cfg.MaxDefer)
+	}
+	return plugin.NewAdmissionPolicy(cfg.Policy)
+}
+
+// decide asks the policy about an order given the shelves. Decisions other
+// than Defer and Reject accept the order.
+func (a *admission) decide(d OrderData, states []shelf.ShelfState) plugin.Admission {
+	if a.policy == nil {
+		return plugin.Accept
+	}
+	view := plugin.Order{
+		Name:      d.Name,
+		Temp:      d.Temp,
+		ShelfLife: d.ShelfLife,
+		DecayRate: d.DecayRate,
+		Volume:    d.Size,
+		Value:     1,
+		Priority:  d.Priority,
+		Zone:      d.Zone,
+	}
+	if view.Volume <= 0 {
+		view.Volume = 1
+	}
+	shelves := make([]plugin.Shelf, len(states))
+	for i, st := range states {
+		modifier := st.DecayModifier
+		if modifier <= 0 {
+			modifier = 1
+		}
+		shelves[i] = plugin.Shelf{
+			Type:          string(st.Type),
+			Capacity:      st.Capacity,
+			Size:          len(st.Orders),
+			Overflow:      len(st.Temps) == 0,
+			Primary:       slices.Contains(st.Temps, order.Temperature(d.Temp)),
+			DecayModifier: modifier,
+			InOutage:      st.InOutage,
+		}
+	}
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.policy.Admit(view, shelves)
+}
+
+// update changes the stats or the deferred orders under the lock
+func (a *admission) update(change func(a *admission)) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	change(a)
+}
+
+// admit asks the admission policy about a new order, holding it if it is
+// deferred, and returns the decision
+func (s *Simulator) admit(d OrderData) plugin.Admission {
+	switch decision := s.admission.decide(d, s.ShelfManager.ShelfStates()); decision {
+	case plugin.Defer:
+		s.admission.update(func(a *admission) {
+			a.deferred = append(a.deferred, deferredOrder{data: d, since: s.now()})
+			a.stats.deferred++
+		})
+		s.orderLogf("", d.Name, "⏳ Order deferred: %s (%s)\n", d.Name, d.Temp)
+		return plugin.Defer
+	case plugin.Reject:
+		s.admission.update(func(a *admission) { a.stats.rejected++ })
+		s.orderLogf("", d.Name, "🚫 Order rejected: %s (%s)\n", d.Name, d.Temp)
+		return plugin.Reject
+	default:
+		return plugin.Accept
+	}
+}
+
+// admitBatch asks the admission policy about the orders of an all-or-nothing
+// batch and returns which it accepted. None is deferred, since holding some
+// orders of a ticket back would split it: those not accepted are rejected,
+// and the batch with them.
+func (s *Simulator) admitBatch(ds []OrderData) []bool {
+	states := s.ShelfManager.ShelfStates()
+	accepted := make([]bool, len(ds))
+	for i, d := range ds {
+		if accepted[i] = s.admission.decide(d, states) == plugin.Accept; !accepted[i] {
+			s.admission.update(func(a *admission) { a.stats.rejected++ })
+			s.orderLogf("", d.Name, "🚫 Order rejected: %s (%s)\n", d.Name, d.Temp)
+		}
+	}
+	return accepted
+}
+
+// retryDeferred asks the policy again about the deferred orders, oldest
+// first. It places those now accepted and rejects those it rejects or has
+// deferred for longer than maxDefer.
+func (s *Simulator) retryDeferred() {
+	if s.paused.Load() {
+		return
+	}
+	var waiting []deferredOrder
+	s.admission.update(func(a *admission) { waiting, a.deferred = a.deferred, nil })
+	if len(waiting) == 0 {
+		return
+	}
+
+	now := s.now()
+	states := s.ShelfManager.ShelfStates()
+	var still []deferredOrder
+	for _, w := range waiting {
+		switch s.admission.decide(w.data, states) {
+		case plugin.Defer:
+			if now.Sub(w.since) < s.admission.maxDefer {
+				still = append(still, w)
+				continue
+			}
+			fallthrough
+		case plugin.Reject:
+			s.admission.update(func(a *admission) { a.stats.rejected++ })
+			s.orderLogf("", w.data.Name, "🚫 Order rejected after waiting %v: %s (%s)\n", now.Sub(w.since).Round(time.Second), w.data.Name, w.data.Temp)
+		default:
+			s.admission.update(func(a *admission) { a.stats.admitted++ })
+			if o, err := s.placeOrder(w.data); err != nil {
+				s.pool.Put(o)
+			}
+			states = s.ShelfManager.ShelfStates()
+		}
+	}
+	// Orders deferred meanwhile are newer, so they queue behind
+	s.admission.update(func(a *admission) { a.deferred = append(still, a.deferred...) })
+}
+
+// printAdmissionStats prints what the admission policy decided, if one is
+// configured
+func (s *Simulator) printAdmissionStats() {
+	if s.admission.policy == nil {
+		return
+	}
+	var stats admissionStats
+	var waiting int
+	s.admission.update(func(a *admission) { stats, waiting = a.stats, len(a.deferred) })
+	fmt.Printf("  Admission (%s): %d deferred, %d of them placed, %d rejected, %d still waiting\n",
+		s.Config.Admission.Policy, stats.deferred, stats.admitted, stats.rejected, waiting)
+}
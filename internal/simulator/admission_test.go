@@ -0,0 +1,124 @@
+package simulator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/config"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+// admissionFunc adapts a function to plugin.AdmissionPolicy
+type admissionFunc func(o plugin.Order, shelves []plugin.Shelf) plugin.Admission
+
+func (f admissionFunc) Admit(o plugin.Order, shelves []plugin.Shelf) plugin.Admission {
+	return f(o, shelves)
+}
+
+func TestNewAdmissionPolicy(t *testing.T) {
+	plugin.RegisterAdmissionPolicy("accept-all", func() plugin.AdmissionPolicy {
+		return admissionFunc(func(plugin.Order, []plugin.Shelf) plugin.Admission { return plugin.Accept })
+	})
+
+	if policy, err := newAdmissionPolicy(config.DefaultConfig().Admission); err != nil || policy != nil {
+		t.Errorf("Expected no policy by default, got %v, %v", policy, err)
+	}
+	if _, err := newAdmissionPolicy(config.AdmissionConfig{Policy: "accept-all", MaxDefer: 30}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if _, err := newAdmissionPolicy(config.AdmissionConfig{Policy: "accept-all"}); err == nil {
+		t.Errorf("Expected an error for a policy without maxDefer")
+	}
+	if _, err := newAdmissionPolicy(config.AdmissionConfig{Policy: "missing", MaxDefer: 30}); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+}
+
+func TestAdmission_Submit(t *testing.T) {
+	s := setupTestSimulator(t)
+	fake := clock.NewFake(time.Unix(1000, 0))
+	s.clock = fake
+
+	// Frozen orders wait while the frozen shelf is 90% full; hot orders are
+	// refused outright while the hot shelf is full
+	s.admission.maxDefer = 10 * time.Second
+	s.admission.policy = admissionFunc(func(o plugin.Order, shelves []plugin.Shelf) plugin.Admission {
+		for _, sh := range shelves {
+			if !sh.Primary || float64(sh.Size) < 0.9*float64(sh.Capacity) {
+				continue
+			}
+			if o.Temp == "frozen" {
+				return plugin.Defer
+			}
+			return plugin.Reject
+		}
+		return plugin.Accept
+	})
+
+	frozen := OrderData{Name: "Ice Cream", Temp: "frozen", ShelfLife: 300, DecayRate: 0.2}
+	hot := OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	for i := 0; i < 5; i++ {
+		if _, err := s.Submit(frozen); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := s.Submit(hot); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if _, err := s.Submit(frozen); !errors.Is(err, ErrDeferred) {
+		t.Errorf("Expected the frozen order to be deferred, got %v", err)
+	}
+	if _, err := s.Submit(hot); !errors.Is(err, ErrRejected) {
+		t.Errorf("Expected the hot order to be rejected, got %v", err)
+	}
+
+	// Still full: the deferred order keeps waiting
+	fake.Advance(5 * time.Second)
+	s.retryDeferred()
+	if len(s.admission.deferred) != 1 {
+		t.Fatalf("Expected the order to still be waiting, got %d", len(s.admission.deferred))
+	}
+
+	// Once there is room, the deferred order is placed
+	states := s.ShelfManager.ShelfStates()
+	for _, st := range states {
+		if st.Type == shelf.FrozenShelf {
+			s.ShelfManager.DeliverOrder(st.Orders[0].ID)
+		}
+	}
+	s.retryDeferred()
+	if len(s.admission.deferred) != 0 {
+		t.Errorf("Expected the deferred order to be placed, got %d waiting", len(s.admission.deferred))
+	}
+
+	stats := s.admission.stats
+	if stats.deferred != 1 || stats.admitted != 1 || stats.rejected != 1 {
+		t.Errorf("Expected 1 deferred, 1 admitted and 1 rejected, got %+v", stats)
+	}
+}
+
+func TestAdmission_MaxDefer(t *testing.T) {
+	s := setupTestSimulator(t)
+	fake := clock.NewFake(time.Unix(1000, 0))
+	s.clock = fake
+	s.admission.maxDefer = 10 * time.Second
+	s.admission.policy = admissionFunc(func(plugin.Order, []plugin.Shelf) plugin.Admission { return plugin.Defer })
+
+	s.createOrder(OrderData{Name: "Ice Cream", Temp: "frozen", ShelfLife: 300, DecayRate: 0.2})
+	if s.ordersProcessed != 1 || len(s.admission.deferred) != 1 {
+		t.Fatalf("Expected the order to be counted and held, got %d and %d", s.ordersProcessed, len(s.admission.deferred))
+	}
+
+	fake.Advance(10 * time.Second)
+	s.retryDeferred()
+	if len(s.admission.deferred) != 0 || s.admission.stats.rejected != 1 {
+		t.Errorf("Expected the order to be rejected after maxDefer, got %+v", s.admission.stats)
+	}
+	if n := len(s.ShelfManager.GetAllOrders()); n != 0 {
+		t.Errorf("Expected nothing shelved, got %d orders", n)
+	}
+}
@@ -0,0 +1,52 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// agentDispatcher lets external courier agents collect and deliver orders
+type agentDispatcher struct {
+	s *Simulator
+}
+
+func (d agentDispatcher) PickUp(o *order.Order) (float64, bool) {
+	s := d.s
+	result, ok := s.pickUp(o.ID)
+	if !ok {
+		return 0, false
+	}
+	return result.Value, true
+}
+
+func (d agentDispatcher) HandOff(o *order.Order, pickupValue float64) float64 {
+	value := d.s.handOff(o, pickupValue, time.Now())
+	d.s.pool.Put(o)
+	return value
+}
+
+// dispatchAgents sends free external couriers to shelved orders, escalated
+// ones first, then soonest to expire. Orders wait on the shelf while no
+// agent is free. Agents report no positions, so their orders are reserved
+// for the grace only.
+func (s *Simulator) dispatchAgents() {
+	now := time.Now()
+	for _, o := range s.pickupOrders() {
+		if s.Agents.Idle() == 0 {
+			return
+		}
+		if s.Agents.Assign(o, now) {
+			s.reserve(o, 0)
+		}
+	}
+}
+
+// printAgentStats prints what the external couriers did
+func (s *Simulator) printAgentStats() {
+	st := s.Agents.Stats()
+	fmt.Println("\n📡 COURIER AGENTS:")
+	fmt.Printf("  %d connected, %d dispatched, %d picked up, %d missed, %d delivered, %d abandoned\n",
+		st.Connected, st.Dispatched, st.PickedUp, st.Missed, st.Delivered, st.Abandoned)
+}
@@ -0,0 +1,61 @@
+package simulator
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dish-dispatcher/internal/agent"
+	"dish-dispatcher/internal/grpcwire"
+	"dish-dispatcher/internal/order"
+)
+
+func TestDispatchAgents_DeliverThroughAgent(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Agents = agent.NewHub(agentDispatcher{s})
+
+	ts := httptest.NewUnstartedServer(s.Agents)
+	ts.Config.Protocols = grpcwire.Protocols()
+	ts.Start()
+	defer ts.Close()
+
+	ctx := context.Background()
+	client := agent.NewClient(strings.TrimPrefix(ts.URL, "http://"))
+	stream, err := client.Connect(ctx, "courier-1")
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer stream.Close()
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	s.ShelfManager.PlaceOrder(o)
+
+	// The hub registers the agent once the stream is open
+	for s.Agents.Stats().Connected == 0 {
+		s.attemptDeliveries()
+	}
+	s.attemptDeliveries()
+
+	dispatch, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive a dispatch: %v", err)
+	}
+	if dispatch.OrderID != o.ID {
+		t.Fatalf("Expected order %s dispatched, got %s", o.ID, dispatch.OrderID)
+	}
+
+	report := agent.Report{CourierID: "courier-1", OrderID: o.ID}
+	if reply, err := client.ReportPickup(ctx, report); err != nil || !reply.OK {
+		t.Fatalf("Expected the pickup to succeed, got %+v, %v", reply, err)
+	}
+	if len(s.ShelfManager.GetAllOrders()) != 0 {
+		t.Errorf("Expected the order off the shelf after pickup")
+	}
+	if _, err := client.ReportDelivery(ctx, report); err != nil {
+		t.Fatalf("Failed to report delivery: %v", err)
+	}
+	if count, _, _ := s.handoffs.averages(); count != 1 {
+		t.Errorf("Expected 1 handoff, got %d", count)
+	}
+}
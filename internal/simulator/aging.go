@@ -0,0 +1,35 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// PrintAgingReport prints every shelved order, least valuable first, with
+// how long it has left. It is safe to call while the simulation runs.
+func (s *Simulator) PrintAgingReport() {
+	report := shelf.AgingReport(s.ShelfManager, s.now())
+
+	fmt.Printf("\n🕰️ AGING REPORT: %d orders shelved\n", len(report))
+	if len(report) == 0 {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  VALUE\tEXPIRES IN\tAGE\tSHELF\tTEMP\tNAME\tID")
+	for _, entry := range report {
+		expiresIn := "never"
+		if entry.Expires {
+			expiresIn = entry.ExpiresIn.Round(100 * time.Millisecond).String()
+		}
+		o := entry.Order
+		fmt.Fprintf(w, "  %.2f\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.Value, expiresIn, entry.Age.Round(100*time.Millisecond),
+			o.CurrentShelfType, o.Temp, o.Name, o.ID)
+	}
+	w.Flush()
+}
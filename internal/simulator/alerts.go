@@ -0,0 +1,256 @@
+package simulator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// defaultAlertInterval is used when the configured interval is not positive
+const defaultAlertInterval = time.Second
+
+// Alert is the payload POSTed to the alert webhook when a rule fires or
+// resolves
+type Alert struct {
+	Run       string            `json:"run,omitempty"`
+	RunID     string            `json:"runId,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Rule      string            `json:"rule"`
+	Metric    string            `json:"metric"`
+	Shelf     string            `json:"shelf,omitempty"`
+	Value     float64           `json:"value"`
+	Threshold float64           `json:"threshold"`
+	Firing    bool              `json:"firing"`
+	Since     time.Time         `json:"since"` // when the metric first crossed the threshold
+	At        time.Time         `json:"at"`
+}
+
+// alertState tracks one rule between checks
+type alertState struct {
+	rule     config.AlertRule
+	breached time.Time // zero while the metric is below the threshold
+	firing   bool
+}
+
+// observe records the rule's metric at now and returns true if the rule
+// started or stopped firing
+func (a *alertState) observe(value float64, now time.Time) bool {
+	if value < a.rule.Threshold {
+		a.breached = time.Time{}
+		if a.firing {
+			a.firing = false
+			return true
+		}
+		return false
+	}
+
+	if a.breached.IsZero() {
+		a.breached = now
+	}
+	if !a.firing && now.Sub(a.breached) >= time.Duration(a.rule.For)*time.Second {
+		a.firing = true
+		return true
+	}
+	return false
+}
+
+// validateAlertConfig checks every rule watches a known metric on an
+// existing shelf and uses known actions
+func validateAlertConfig(cfg config.AlertConfig, shelves []shelf.ShelfState) error {
+	for i, rule := range cfg.Rules {
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		switch rule.Metric {
+		case config.AlertMetricShelfUsage:
+			if !slices.ContainsFunc(shelves, func(st shelf.ShelfState) bool { return string(st.Type) == rule.Shelf }) {
+				return fmt.Errorf("alert %s: unknown shelf %q", name, rule.Shelf)
+			}
+		case config.AlertMetricWasteRate:
+		default:
+			return fmt.Errorf("alert %s: unknown metric %q", name, rule.Metric)
+		}
+
+		if rule.For < 0 {
+			return fmt.Errorf("alert %s: duration must not be negative, got %d", name, rule.For)
+		}
+
+		for _, action := range rule.Actions {
+			switch action {
+			case config.AlertActionLog, config.AlertActionStop:
+			case config.AlertActionWebhook:
+				if cfg.Webhook == "" {
+					return fmt.Errorf("alert %s: webhook action needs a webhook URL", name)
+				}
+			default:
+				return fmt.Errorf("alert %s: unknown action %q", name, action)
+			}
+		}
+	}
+	return nil
+}
+
+// shelfUsage returns how full a shelf is in percent, by count or volume
+// whichever is higher
+func shelfUsage(st shelf.ShelfState) float64 {
+	usage := 0.0
+	if st.Capacity > 0 {
+		usage = float64(len(st.Orders)) / float64(st.Capacity)
+	}
+	if st.Volume > 0 {
+		usage = max(usage, st.UsedVolume/st.Volume)
+	}
+	return usage * 100
+}
+
+// alertValue returns the current value of a rule's metric
+func alertValue(rule config.AlertRule, totals stopTotals, states []shelf.ShelfState) float64 {
+	switch rule.Metric {
+	case config.AlertMetricShelfUsage:
+		for _, st := range states {
+			if string(st.Type) == rule.Shelf {
+				return shelfUsage(st)
+			}
+		}
+	case config.AlertMetricWasteRate:
+		if totals.received > 0 {
+			return float64(totals.lost) / float64(totals.received) * 100
+		}
+	}
+	return 0
+}
+
+// watchAlerts evaluates the alert rules on an interval and acts on any that
+// fire
+func (s *Simulator) watchAlerts() {
+	defer s.wg.Done()
+
+	cfg := s.Config.Alerts
+	interval := time.Duration(cfg.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultAlertInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	states := make([]*alertState, len(cfg.Rules))
+	for i, rule := range cfg.Rules {
+		states[i] = &alertState{rule: rule}
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if s.checkAlerts(states, client, now) {
+				return
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// checkAlerts observes every rule once and returns true if an alert stopped
+// the simulation
+func (s *Simulator) checkAlerts(states []*alertState, client *http.Client, now time.Time) bool {
+	totals := s.currentTotals()
+	shelves := s.ShelfManager.ShelfStates()
+
+	stopped := false
+	for _, st := range states {
+		value := alertValue(st.rule, totals, shelves)
+		since := st.breached
+		if !st.observe(value, now) {
+			continue
+		}
+
+		run := s.RunContext()
+		alert := Alert{
+			Run:       run.Name,
+			RunID:     run.ID,
+			Tags:      run.Tags,
+			Rule:      st.rule.Name,
+			Metric:    st.rule.Metric,
+			Shelf:     st.rule.Shelf,
+			Value:     value,
+			Threshold: st.rule.Threshold,
+			Firing:    st.firing,
+			Since:     since,
+			At:        now,
+		}
+		if alert.Since.IsZero() {
+			alert.Since = now
+		}
+		if s.raiseAlert(alert, st.rule.Actions, client) {
+			stopped = true
+		}
+	}
+	return stopped
+}
+
+// raiseAlert carries out a rule's actions for a firing or resolved alert and
+// returns true if it stopped the simulation
+func (s *Simulator) raiseAlert(alert Alert, actions []string, client *http.Client) bool {
+	eventType := events.AlertResolved
+	if alert.Firing {
+		eventType = events.AlertFiring
+	}
+	s.Events.Publish(events.Event{Type: eventType, Name: alert.Rule, Shelf: alert.Shelf, Value: alert.Value})
+
+	if len(actions) == 0 {
+		actions = []string{config.AlertActionLog}
+	}
+
+	stopped := false
+	for _, action := range actions {
+		switch action {
+		case config.AlertActionLog:
+			if alert.Firing {
+				fmt.Printf("🚨 Alert %s: %s at %.1f%% (threshold %.1f%%)\n",
+					alert.Rule, alert.Metric, alert.Value, alert.Threshold)
+			} else {
+				fmt.Printf("✅ Alert %s resolved: %s at %.1f%%\n", alert.Rule, alert.Metric, alert.Value)
+			}
+		case config.AlertActionWebhook:
+			if err := postAlert(client, s.Config.Alerts.Webhook, alert); err != nil {
+				fmt.Printf("⚠️ Alert webhook failed: %v\n", err)
+			}
+		case config.AlertActionStop:
+			if alert.Firing {
+				s.setErr(fmt.Errorf("alert %s fired: %s at %.1f%%", alert.Rule, alert.Metric, alert.Value))
+				s.halt()
+				stopped = true
+			}
+		}
+	}
+	return stopped
+}
+
+// postAlert sends an alert to the webhook as JSON
+func postAlert(client *http.Client, url string, alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package simulator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestAlertState_Observe(t *testing.T) {
+	a := &alertState{rule: config.AlertRule{Threshold: 90, For: 30}}
+	start := time.Now()
+
+	if a.observe(95, start) {
+		t.Errorf("Expected the alert to wait out its duration")
+	}
+	if a.observe(80, start.Add(20*time.Second)) {
+		t.Errorf("Expected a dip below the threshold to reset quietly")
+	}
+	if a.observe(90, start.Add(25*time.Second)) || a.observe(92, start.Add(54*time.Second)) {
+		t.Errorf("Expected the duration to restart after the dip")
+	}
+	if !a.observe(91, start.Add(55*time.Second)) || !a.firing {
+		t.Errorf("Expected the alert to fire after 30s at the threshold")
+	}
+	if a.observe(99, start.Add(60*time.Second)) {
+		t.Errorf("Expected a firing alert not to fire again")
+	}
+	if !a.observe(50, start.Add(61*time.Second)) || a.firing {
+		t.Errorf("Expected the alert to resolve")
+	}
+}
+
+func TestValidateAlertConfig(t *testing.T) {
+	states := shelf.NewShelfManager(1, 1, 1, 1).ShelfStates()
+
+	valid := config.AlertConfig{
+		Webhook: "http://example.com/alerts",
+		Rules: []config.AlertRule{
+			{Name: "overflow", Metric: config.AlertMetricShelfUsage, Shelf: "overflow", Threshold: 90, For: 30},
+			{Name: "waste", Metric: config.AlertMetricWasteRate, Threshold: 10,
+				Actions: []string{config.AlertActionLog, config.AlertActionWebhook, config.AlertActionStop}},
+		},
+	}
+	if err := validateAlertConfig(valid, states); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	invalid := []config.AlertConfig{
+		{Rules: []config.AlertRule{{Metric: "latency"}}},
+		{Rules: []config.AlertRule{{Metric: config.AlertMetricShelfUsage, Shelf: "pantry"}}},
+		{Rules: []config.AlertRule{{Metric: config.AlertMetricWasteRate, For: -1}}},
+		{Rules: []config.AlertRule{{Metric: config.AlertMetricWasteRate, Actions: []string{"page"}}}},
+		{Rules: []config.AlertRule{{Metric: config.AlertMetricWasteRate, Actions: []string{config.AlertActionWebhook}}}},
+	}
+	for _, cfg := range invalid {
+		if err := validateAlertConfig(cfg, states); err == nil {
+			t.Errorf("Expected an error for %+v", cfg.Rules[0])
+		}
+	}
+}
+
+func TestShelfUsage(t *testing.T) {
+	st := shelf.ShelfState{Capacity: 4, Orders: make([]*order.Order, 3)}
+	if got := shelfUsage(st); got != 75 {
+		t.Errorf("Expected 75%% usage by count, got %v", got)
+	}
+
+	st.Volume, st.UsedVolume = 2, 1.8
+	if got := shelfUsage(st); got < 89.9 || got > 90.1 {
+		t.Errorf("Expected 90%% usage by volume, got %v", got)
+	}
+}
+
+func TestCheckAlerts_WebhookAndStop(t *testing.T) {
+	received := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var alert Alert
+		if err := json.NewDecoder(r.Body).Decode(&alert); err != nil {
+			t.Errorf("Failed to decode alert: %v", err)
+		}
+		received <- alert
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := setupTestSimulator(t)
+	s.Config.Alerts = config.AlertConfig{Webhook: server.URL}
+	rule := config.AlertRule{
+		Name:      "hot-full",
+		Metric:    config.AlertMetricShelfUsage,
+		Shelf:     "hot",
+		Threshold: 40,
+		Actions:   []string{config.AlertActionWebhook, config.AlertActionStop},
+	}
+	states := []*alertState{{rule: rule}}
+
+	now := time.Now()
+	if s.checkAlerts(states, server.Client(), now) {
+		t.Fatalf("Expected no alert on empty shelves")
+	}
+
+	for i := 0; i < 2; i++ {
+		s.ShelfManager.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5))
+	}
+	if !s.checkAlerts(states, server.Client(), now.Add(time.Second)) {
+		t.Fatalf("Expected the alert to stop the simulation")
+	}
+
+	alert := <-received
+	if alert.Rule != "hot-full" || !alert.Firing || alert.Value != 40 {
+		t.Errorf("Unexpected alert payload: %+v", alert)
+	}
+
+	select {
+	case <-s.stop:
+	default:
+		t.Errorf("Expected the simulation to be halted")
+	}
+	if s.Err() == nil {
+		t.Errorf("Expected the stop alert to be recorded as the simulation error")
+	}
+}
@@ -0,0 +1,100 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"dish-dispatcher/internal/amqp"
+	"dish-dispatcher/internal/config"
+)
+
+// validateAMQPConfig checks orders can be consumed from the configured
+// queue
+func validateAMQPConfig(cfg config.AMQPConfig, service config.ServiceConfig) error {
+	if cfg.URL == "" {
+		return nil
+	}
+	if !service.Enabled {
+		return errors.New("amqp.url needs service mode, where orders arrive from outside the run")
+	}
+	if err := amqp.CheckURL(cfg.URL); err != nil {
+		return err
+	}
+	if cfg.Queue == "" {
+		return errors.New("amqp.url needs amqp.queue")
+	}
+	if cfg.Prefetch < 0 || cfg.Prefetch > 0xffff {
+		return fmt.Errorf("amqp.prefetch must be between 0 and 65535, got %d", cfg.Prefetch)
+	}
+	return nil
+}
+
+// startAMQP connects to the broker and consumes orders from the configured
+// queue until the simulation stops
+func (s *Simulator) startAMQP() error {
+	cfg := s.Config.AMQP
+	consumer, err := amqp.Consume(amqp.Options{
+		URL:         cfg.URL,
+		Queue:       cfg.Queue,
+		Prefetch:    cfg.Prefetch,
+		ConsumerTag: "dish-dispatcher",
+	})
+	if err != nil {
+		return err
+	}
+
+	s.wg.Add(1)
+	go s.consumeAMQP(consumer)
+	return nil
+}
+
+// consumeAMQP places every delivered order. Losing the broker stops the
+// simulation, since orders would silently stop arriving.
+func (s *Simulator) consumeAMQP(consumer *amqp.Consumer) {
+	defer s.wg.Done()
+	defer consumer.Close()
+
+	for {
+		select {
+		case d, ok := <-consumer.Deliveries():
+			if !ok {
+				s.setErr(fmt.Errorf("consuming AMQP queue %s: %w", s.Config.AMQP.Queue, consumer.Err()))
+				s.halt()
+				return
+			}
+			s.submitAMQP(consumer, d)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// submitAMQP places the order in d, acknowledging it once shelved. A
+// rejected order is requeued only if it could still be placed: when this
+// dispatcher is stopping, or with requeueWasted when the shelves were full.
+func (s *Simulator) submitAMQP(consumer *amqp.Consumer, d amqp.Delivery) {
+	var data OrderData
+	err := json.Unmarshal(d.Body, &data)
+	if err != nil {
+		err = &InvalidOrderError{Reason: err.Error()}
+	} else {
+		_, err = s.Submit(data)
+	}
+
+	var invalid *InvalidOrderError
+	switch {
+	case err == nil:
+		err = consumer.Ack(d.Tag)
+	case errors.Is(err, ErrStopped):
+		err = consumer.Nack(d.Tag, true)
+	case errors.As(err, &invalid):
+		s.logf("⚠️ Order from AMQP queue %s rejected: %s\n", s.Config.AMQP.Queue, err)
+		err = consumer.Nack(d.Tag, false)
+	default:
+		err = consumer.Nack(d.Tag, s.Config.AMQP.RequeueWasted)
+	}
+	if err != nil {
+		s.logf("⚠️ Settling AMQP delivery %d failed: %v\n", d.Tag, err)
+	}
+}
@@ -0,0 +1,97 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// configureCleanup applies the per-shelf expiry grace periods to the shelf
+// manager and returns the per-shelf sweep intervals
+func configureCleanup(cfg config.CleanupConfig, manager shelf.ShelfManager) (map[shelf.ShelfType]time.Duration, error) {
+	if cfg.Interval <= 0 {
+		return nil, errors.New("cleanup.interval must be positive")
+	}
+	if len(cfg.Shelves) == 0 {
+		return nil, nil
+	}
+
+	cleanup, ok := manager.(shelf.ShelfCleanup)
+	if !ok {
+		return nil, fmt.Errorf("shelf manager %T does not support per-shelf cleanup", manager)
+	}
+
+	intervals := make(map[shelf.ShelfType]time.Duration)
+	for name, sc := range cfg.Shelves {
+		if sc.Interval < 0 {
+			return nil, fmt.Errorf("cleanup of shelf %q: interval must not be negative", name)
+		}
+		if err := cleanup.SetExpiryGrace(shelf.ShelfType(name), seconds(sc.Grace)); err != nil {
+			return nil, fmt.Errorf("cleanup: %w", err)
+		}
+		if sc.Interval > 0 {
+			intervals[shelf.ShelfType(name)] = seconds(sc.Interval)
+		}
+	}
+	return intervals, nil
+}
+
+// seconds converts a config value in seconds to a duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// sweepExpiredOrders scans the shelves for expired orders on a fixed
+// interval, or sweeps each shelf on its own interval if any are configured
+func (s *Simulator) sweepExpiredOrders() {
+	if len(s.cleanupIntervals) == 0 {
+		s.sweepEvery(s.cleanupInterval, s.ShelfManager.RemoveExpiredOrders)
+		return
+	}
+
+	// configureCleanup only sets intervals on managers that support them
+	cleanup := s.ShelfManager.(shelf.ShelfCleanup)
+
+	var wg sync.WaitGroup
+	for _, state := range s.ShelfManager.ShelfStates() {
+		shelfType := state.Type
+		interval, ok := s.cleanupIntervals[shelfType]
+		if !ok {
+			interval = s.cleanupInterval
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.sweepEvery(interval, func() int {
+				expired, _ := cleanup.RemoveExpiredOrdersFrom(shelfType)
+				return expired
+			})
+		}()
+	}
+	wg.Wait()
+}
+
+// sweepEvery runs remove on every tick of interval until the simulation stops
+func (s *Simulator) sweepEvery(interval time.Duration, remove func() int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			expired := s.cleanupTimed(remove)
+			if expired > 0 {
+				s.logf("🗑️ Removed %d expired orders\n", expired)
+				s.Events.Publish(events.Event{Type: events.OrdersExpired, Count: expired})
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestConfigureCleanup(t *testing.T) {
+	manager := shelf.NewShelfManager(1, 1, 1, 1)
+	cfg := config.CleanupConfig{
+		Interval: 0.5,
+		Shelves: map[string]config.ShelfCleanupConfig{
+			"overflow": {Interval: 0.1},
+			"frozen":   {Interval: 2, Grace: 1},
+		},
+	}
+
+	intervals, err := configureCleanup(cfg, manager)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if intervals[shelf.OverflowShelf] != 100*time.Millisecond || intervals[shelf.FrozenShelf] != 2*time.Second {
+		t.Errorf("Expected the configured intervals, got %v", intervals)
+	}
+
+	// The frozen shelf's grace delays the scheduled removal
+	o := order.NewOrder("Ice Cream", order.Frozen, 10, 1)
+	manager.PlaceOrder(o)
+	if next, _ := manager.NextExpiry(); !next.Equal(o.ExpiresAt().Add(time.Second)) {
+		t.Errorf("Expected removal a second after expiry, got %s", next.Sub(o.ExpiresAt()))
+	}
+
+	for name, bad := range map[string]config.CleanupConfig{
+		"no interval":       {},
+		"unknown shelf":     {Interval: 1, Shelves: map[string]config.ShelfCleanupConfig{"sauna": {Interval: 1}}},
+		"negative interval": {Interval: 1, Shelves: map[string]config.ShelfCleanupConfig{"hot": {Interval: -1}}},
+		"negative grace":    {Interval: 1, Shelves: map[string]config.ShelfCleanupConfig{"hot": {Grace: -1}}},
+	} {
+		if _, err := configureCleanup(bad, shelf.NewShelfManager(1, 1, 1, 1)); err == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+func TestSimulator_SweepPerShelf(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.ExpiryMode = config.ExpiryModeSweep
+	s.cleanupIntervals = map[shelf.ShelfType]time.Duration{shelf.HotShelf: 10 * time.Millisecond}
+
+	s.ShelfManager.PlaceOrder(order.NewOrder("Burger", order.Hot, 0.01, 1))
+	s.ShelfManager.PlaceOrder(order.NewOrder("Salad", order.Cold, 0.01, 1))
+
+	s.wg.Add(1)
+	go s.cleanupExpiredOrders()
+	time.Sleep(100 * time.Millisecond)
+	s.halt()
+	s.wg.Wait()
+
+	// The cold shelf is swept on the 2s default, so only hot was cleaned
+	orders := s.ShelfManager.GetAllOrders()
+	if len(orders) != 1 || orders[0].Name != "Salad" {
+		t.Errorf("Expected only the cold order left, got %v", orders)
+	}
+}
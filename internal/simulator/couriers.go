@@ -0,0 +1,202 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// newFleet builds the configured courier fleet, or returns nil if no
+// couriers are configured
+func newFleet(cfg config.CourierConfig) (*courier.Fleet, error) {
+	if cfg.Handoff < 0 || cfg.TransitDecay < 0 || cfg.ReserveGrace < 0 {
+		return nil, errors.New("courier handoff, transit decay and reserve grace must not be negative")
+	}
+	if cfg.Count > 0 && cfg.AgentAddr != "" {
+		return nil, errors.New("couriers.count and couriers.agentAddr are mutually exclusive")
+	}
+	if cfg.Count <= 0 {
+		return nil, nil
+	}
+	if cfg.Reach < 0 {
+		return nil, errors.New("courier reach must not be negative")
+	}
+
+	strategy, err := courier.NewStrategy(cfg.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	couriers := courier.RandomCouriers(cfg.Count, cfg.Reach, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	return courier.NewFleet(couriers, strategy), nil
+}
+
+// configureMinValue has the shelf manager refuse orders worth less than
+// the minimum delivery value at pickup
+func configureMinValue(cfg config.CourierConfig, manager shelf.ShelfManager) error {
+	if cfg.MinValue == 0 {
+		return nil
+	}
+	gate, ok := manager.(shelf.QualityGate)
+	if !ok {
+		return fmt.Errorf("couriers.minValue needs a shelf manager that refuses orders at pickup, %T does not", manager)
+	}
+	if err := gate.SetMinDeliveryValue(cfg.MinValue); err != nil {
+		return fmt.Errorf("couriers: %w", err)
+	}
+	return nil
+}
+
+// dispatchCouriers assigns free couriers to shelved orders, escalated ones
+// first, then soonest to expire
+func (s *Simulator) dispatchCouriers() {
+	for _, o := range s.pickupOrders() {
+		if s.Couriers.Idle() == 0 {
+			return
+		}
+
+		// During a courier disruption some pickups find no courier; the
+		// order stays shelved and is retried on the next cycle
+		if !s.courierAvailable() {
+			continue
+		}
+
+		if c, travel := s.Couriers.Assign(o); c != nil {
+			s.reserve(o, travel)
+			s.wg.Add(1)
+			go s.runCourier(o, travel)
+		}
+	}
+}
+
+// runCourier travels to the kitchen, collects the order if it is still
+// shelved and carries it to a random customer before becoming free again
+func (s *Simulator) runCourier(o *order.Order, travel time.Duration) {
+	defer s.wg.Done()
+
+	reach := s.Config.Couriers.Reach
+	x, y := s.randomPosition(reach)
+	// Deferred calls run last first, so the order is reused only after its
+	// courier is released
+	handedOff := false
+	defer func() {
+		if handedOff {
+			s.pool.Put(o)
+		}
+	}()
+	defer s.Couriers.Release(o, x, y)
+
+	if !s.wait(travel) {
+		return
+	}
+
+	result, ok := s.pickUp(o.ID)
+	if !ok {
+		s.Couriers.Missed(o)
+		return
+	}
+	s.Couriers.PickedUp(o, result.At)
+
+	// The courier is free again once it reaches the customer and hands the
+	// order over, while the order keeps decaying off-shelf
+	if s.wait(time.Duration(math.Hypot(x, y)*float64(time.Second)) + s.handoffDuration()) {
+		s.handOff(result.Order, result.Value, time.Now())
+		handedOff = true
+	}
+}
+
+// reserve holds an order on its shelf for a courier due to collect it
+// after travel, and for the reservation grace beyond
+func (s *Simulator) reserve(o *order.Order, travel time.Duration) {
+	if reserver, ok := s.ShelfManager.(shelf.OrderReserver); ok {
+		reserver.ReserveOrder(o.ID, time.Now().Add(travel+seconds(s.Config.Couriers.ReserveGrace)))
+	}
+}
+
+// release ends the reservation of an order no courier is coming for
+func (s *Simulator) release(o *order.Order) {
+	if reserver, ok := s.ShelfManager.(shelf.OrderReserver); ok {
+		reserver.ReleaseOrder(o.ID)
+	}
+}
+
+// wait sleeps for d and returns false if the simulation stopped first
+func (s *Simulator) wait(d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-s.stop:
+		return false
+	}
+}
+
+// Seed makes the pickup delays, courier positions and random failures
+// repeat for the same seed; orders.seed does the same for the orders. The
+// discrete engine then replays a run exactly, and the real-time one as
+// closely as its timing allows. Call it before Run.
+func (s *Simulator) Seed(seed uint64) {
+	s.randMutex.Lock()
+	defer s.randMutex.Unlock()
+
+	s.rand = rand.New(rand.NewPCG(seed, seed))
+	if s.Couriers != nil {
+		s.Couriers.Scatter(s.Config.Couriers.Reach, s.rand)
+	}
+}
+
+// pickupDelay returns how long the courier takes to collect an order when
+// there is no fleet, between 2 and 6 seconds
+func (s *Simulator) pickupDelay() time.Duration {
+	s.randMutex.Lock()
+	defer s.randMutex.Unlock()
+
+	return time.Duration(s.random().IntN(5)+2) * time.Second
+}
+
+// randomPosition returns where a courier's customer is, within reach
+func (s *Simulator) randomPosition(reach float64) (x, y float64) {
+	s.randMutex.Lock()
+	defer s.randMutex.Unlock()
+
+	return courier.RandomPosition(reach, s.random())
+}
+
+// random returns the simulator's generator, randomly seeding it on first
+// use. Callers must hold randMutex.
+func (s *Simulator) random() *rand.Rand {
+	if s.rand == nil {
+		s.rand = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return s.rand
+}
+
+// printCourierStats prints the pickup latency under each strategy used
+func (s *Simulator) printCourierStats() {
+	stats := s.Couriers.Stats()
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("\n🛵 COURIERS:")
+	for _, name := range names {
+		st := stats[name]
+		fmt.Printf("  %s: %d assigned, %d picked up, %d missed, avg pickup %.1fs, max %.1fs\n",
+			name, st.Assignments, st.Pickups, st.Missed,
+			st.AvgLatency().Seconds(), st.MaxLatency.Seconds())
+	}
+}
@@ -0,0 +1,157 @@
+package simulator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+// day is the length of a simulated day
+const day = 24 * time.Hour
+
+// demandPoint is a parsed config.DemandPoint
+type demandPoint struct {
+	at   time.Duration // since midnight
+	rate float64
+}
+
+// demandCurve maps elapsed real time to an order rate following a daily
+// schedule of demand points. The schedule wraps around midnight.
+type demandCurve struct {
+	points      []demandPoint // sorted by time of day
+	interpolate bool
+	scale       float64       // simulated seconds per real second
+	start       time.Duration // simulated time of day at elapsed zero
+}
+
+// newDemandCurve builds the configured curve, or returns nil if no demand
+// points are configured and the rate is constant
+func newDemandCurve(cfg config.DemandConfig) (*demandCurve, error) {
+	if len(cfg.Points) == 0 {
+		return nil, nil
+	}
+
+	c := &demandCurve{scale: 1}
+	switch cfg.Shape {
+	case "", config.DemandShapeSteps:
+	case config.DemandShapeCurve:
+		c.interpolate = true
+	default:
+		return nil, fmt.Errorf("unknown demand shape %q", cfg.Shape)
+	}
+
+	if cfg.DayLength < 0 {
+		return nil, fmt.Errorf("demand day length must not be negative, got %d", cfg.DayLength)
+	}
+	if cfg.DayLength > 0 {
+		c.scale = day.Seconds() / float64(cfg.DayLength)
+	}
+
+	if cfg.StartTime != "" {
+		start, err := parseTimeOfDay(cfg.StartTime)
+		if err != nil {
+			return nil, fmt.Errorf("demand start time: %w", err)
+		}
+		c.start = start
+	}
+
+	for _, p := range cfg.Points {
+		at, err := parseTimeOfDay(p.Time)
+		if err != nil {
+			return nil, fmt.Errorf("demand point: %w", err)
+		}
+		if p.OrdersPerSecond < 0 {
+			return nil, fmt.Errorf("demand point %s: rate must not be negative, got %v", p.Time, p.OrdersPerSecond)
+		}
+		c.points = append(c.points, demandPoint{at: at, rate: p.OrdersPerSecond})
+	}
+	sort.Slice(c.points, func(i, j int) bool { return c.points[i].at < c.points[j].at })
+	for i := 1; i < len(c.points); i++ {
+		if c.points[i].at == c.points[i-1].at {
+			return nil, errors.New("demand points must have distinct times")
+		}
+	}
+
+	return c, nil
+}
+
+// parseTimeOfDay parses "HH:MM" into the time since midnight
+func parseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: must be HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// timeOfDay returns the simulated time of day after elapsed real time
+func (c *demandCurve) timeOfDay(elapsed time.Duration) time.Duration {
+	simulated := c.start + time.Duration(float64(elapsed)*c.scale)
+	return simulated % day
+}
+
+// rate returns the orders per second after elapsed real time
+func (c *demandCurve) rate(elapsed time.Duration) float64 {
+	now := c.timeOfDay(elapsed)
+
+	// Find the last point at or before now, wrapping to the previous day's
+	// last point before the first
+	i := sort.Search(len(c.points), func(i int) bool { return c.points[i].at > now }) - 1
+	prev := c.points[(i+len(c.points))%len(c.points)]
+	if !c.interpolate || len(c.points) == 1 {
+		return prev.rate
+	}
+
+	next := c.points[(i+1)%len(c.points)]
+	span := (next.at - prev.at + day) % day
+	since := (now - prev.at + day) % day
+	if span == 0 {
+		return prev.rate
+	}
+	return prev.rate + (next.rate-prev.rate)*float64(since)/float64(span)
+}
+
+// demandTick is how often the order rate is re-read from the demand curve
+const demandTick = 10 * time.Millisecond
+
+// followDemand places orders at the rate the demand curve gives for each
+// moment, carrying fractional orders over between ticks
+func (s *Simulator) followDemand(ctx context.Context) {
+	ticker := time.NewTicker(demandTick)
+	defer ticker.Stop()
+
+	start := time.Now()
+	last := start
+	due := 0.0
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if s.paused.Load() {
+				last = now
+				continue
+			}
+			due += s.demand.rate(now.Sub(start)) * now.Sub(last).Seconds()
+			last = now
+
+			n := int(due)
+			due -= float64(n)
+			if n > 0 && s.placeOrders(ctx, n) {
+				return
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// currentDemand describes the demand curve's position after elapsed time
+func (s *Simulator) currentDemand(elapsed time.Duration) string {
+	tod := s.demand.timeOfDay(elapsed)
+	return fmt.Sprintf("%.2f orders/sec at %02d:%02d",
+		s.demand.rate(elapsed), int(tod.Hours()), int(tod.Minutes())%60)
+}
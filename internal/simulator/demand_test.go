@@ -0,0 +1,111 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+func testDemandPoints() []config.DemandPoint {
+	return []config.DemandPoint{
+		{Time: "18:00", OrdersPerSecond: 4},
+		{Time: "06:00", OrdersPerSecond: 0},
+		{Time: "12:00", OrdersPerSecond: 2},
+	}
+}
+
+func TestDemandCurve_Steps(t *testing.T) {
+	c, err := newDemandCurve(config.DemandConfig{Points: testDemandPoints()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{0, 4}, // before the first point wraps to the previous day's last
+		{6 * time.Hour, 0},
+		{11 * time.Hour, 0},
+		{12 * time.Hour, 2},
+		{20 * time.Hour, 4},
+	}
+	for _, tt := range tests {
+		if got := c.rate(tt.at); got != tt.want {
+			t.Errorf("Expected rate %v at %v, got %v", tt.want, tt.at, got)
+		}
+	}
+}
+
+func TestDemandCurve_Interpolate(t *testing.T) {
+	c, err := newDemandCurve(config.DemandConfig{Shape: config.DemandShapeCurve, Points: testDemandPoints()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		at   time.Duration
+		want float64
+	}{
+		{9 * time.Hour, 1},
+		{15 * time.Hour, 3},
+		{0, 2}, // halfway from 18:00 back round to 06:00
+	}
+	for _, tt := range tests {
+		if got := c.rate(tt.at); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Expected rate %v at %v, got %v", tt.want, tt.at, got)
+		}
+	}
+}
+
+func TestDemandCurve_DayLength(t *testing.T) {
+	c, err := newDemandCurve(config.DemandConfig{
+		Points:    testDemandPoints(),
+		DayLength: 240,
+		StartTime: "11:00",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A 240 second day runs an hour every 10 seconds
+	if got := c.timeOfDay(10 * time.Second); got != 12*time.Hour {
+		t.Errorf("Expected 12:00 after 10s, got %v", got)
+	}
+	if got := c.rate(5 * time.Second); got != 0 {
+		t.Errorf("Expected rate 0 before noon, got %v", got)
+	}
+	if got := c.rate(70 * time.Second); got != 4 {
+		t.Errorf("Expected rate 4 at 18:00, got %v", got)
+	}
+	if got := c.timeOfDay(140 * time.Second); got != 1*time.Hour {
+		t.Errorf("Expected the day to wrap to 01:00, got %v", got)
+	}
+}
+
+func TestDemandCurve_Invalid(t *testing.T) {
+	points := testDemandPoints()
+	tests := []struct {
+		name string
+		cfg  config.DemandConfig
+	}{
+		{"shape", config.DemandConfig{Shape: "sine", Points: points}},
+		{"day length", config.DemandConfig{DayLength: -1, Points: points}},
+		{"start time", config.DemandConfig{StartTime: "noon", Points: points}},
+		{"point time", config.DemandConfig{Points: []config.DemandPoint{{Time: "25:00", OrdersPerSecond: 1}}}},
+		{"negative rate", config.DemandConfig{Points: []config.DemandPoint{{Time: "10:00", OrdersPerSecond: -1}}}},
+		{"duplicate time", config.DemandConfig{Points: []config.DemandPoint{{Time: "10:00"}, {Time: "10:00"}}}},
+	}
+	for _, tt := range tests {
+		if _, err := newDemandCurve(tt.cfg); err == nil {
+			t.Errorf("Expected an error for invalid %s", tt.name)
+		}
+	}
+
+	c, err := newDemandCurve(config.DemandConfig{Shape: "bogus"})
+	if c != nil || err != nil {
+		t.Errorf("Expected no curve and no error without points, got %v, %v", c, err)
+	}
+}
@@ -0,0 +1,443 @@
+package simulator
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/clock"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// DiscreteEngine runs the simulation on a simulated clock, jumping from one
+// event to the next instead of waiting for it. A five minute run finishes
+// in well under a second, so it suits sweeps and regression runs.
+//
+// It models the order source arriving at OrdersPerSecond, a single random
+// pickup after another as the real-time Simulator does without a fleet,
+// the courier fleet, handoff, and orders expiring at their exact expiry
+// times. Demand curves, courier agents, failures, stop conditions, alerts
+// and invariant checks need the wall clock and are ignored with a warning.
+type DiscreteEngine struct {
+	*Simulator
+
+	// Clock is the simulated time, starting when the engine is created
+	Clock *clock.Fake
+
+	queue   discreteQueue
+	seq     int
+	pickups []*order.Order // the shelved orders the courier is working through, soonest to expire first
+	next    *OrderData     // the order arriving next, read ahead from the source
+	ignored []string       // configured features this engine does not model
+
+	pauseMutex sync.Mutex
+	resume     chan struct{} // closed on Resume, nil unless paused
+
+	doneMutex sync.Mutex
+	done      chan struct{} // closed when the event loop returns, nil until Run starts it
+}
+
+// NewDiscreteEngine creates a discrete-event engine on the in-memory shelf
+// manager
+func NewDiscreteEngine(cfg *config.Config, ordersFile string) (*DiscreteEngine, error) {
+	if cfg.Service.Enabled {
+		return nil, errors.New("the discrete engine does not support service mode")
+	}
+	for _, sc := range cfg.Sources {
+		if sc.Type == config.SourceNATS {
+			return nil, errors.New("the discrete engine cannot wait on messages from NATS")
+		}
+	}
+	if cfg.Orders.Watch {
+		return nil, errors.New("the discrete engine cannot watch the orders file, as simulated time does not wait for edits")
+	}
+	if endlessSources(cfg) && cfg.SimulationDuration <= 0 {
+		return nil, errors.New("the discrete engine needs a simulationDuration to stop endless order sources")
+	}
+
+	manager, err := shelf.NewShelfManagerWithLayout(ShelfLayout(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid shelf layout: %w", err)
+	}
+	c := clock.NewFake(time.Now())
+	manager.SetClock(c)
+
+	sim, err := NewSimulatorWithManager(cfg, ordersFile, manager)
+	if err != nil {
+		return nil, err
+	}
+	sim.clock = c
+
+	e := &DiscreteEngine{Simulator: sim, Clock: c, ignored: ignoredByDiscrete(cfg)}
+	// Without a fleet pickups follow a random delay, and the order rate is
+	// constant
+	e.Agents = nil
+	e.demand = nil
+	return e, nil
+}
+
+// Ignored lists the configured features the engine does not model, which
+// make its results differ from a real-time run's
+func (e *DiscreteEngine) Ignored() []string {
+	return e.ignored
+}
+
+// ignoredByDiscrete lists the configured features the discrete engine does
+// not model
+func ignoredByDiscrete(cfg *config.Config) []string {
+	var ignored []string
+	if cfg.Couriers.AgentAddr != "" {
+		ignored = append(ignored, "courier agents")
+	}
+	if len(cfg.Demand.Points) > 0 {
+		ignored = append(ignored, "demand curve")
+	}
+	if len(cfg.Failures.Events) > 0 || len(cfg.Failures.Couriers) > 0 || cfg.Failures.RandomPerMinute > 0 {
+		ignored = append(ignored, "failures")
+	}
+	if stopConditionsEnabled(cfg.Stop) {
+		ignored = append(ignored, "stop conditions")
+	}
+	if cfg.Escalation.Threshold > 0 {
+		ignored = append(ignored, "escalation")
+	}
+	if len(cfg.Alerts.Rules) > 0 {
+		ignored = append(ignored, "alerts")
+	}
+	if cfg.Invariants.Mode != "" && cfg.Invariants.Mode != config.InvariantCheckOff {
+		ignored = append(ignored, "invariant checks")
+	}
+	return ignored
+}
+
+// discreteKind is what happens at a scheduled moment
+type discreteKind int
+
+const (
+	discreteArrival discreteKind = iota // the next order from the source arrives
+	discretePickup                      // the courier turns to the next shelved order
+	discreteDeliver                     // the courier collects an order
+	discreteCollect                     // a fleet courier reaches the kitchen for an order
+	discreteRelease                     // a fleet courier hands an order over and is free again
+	discreteReport                      // current stats are printed
+	discreteSample                      // a sample is taken for the time series
+	discreteEnd                         // the run ends
+)
+
+type discreteEvent struct {
+	at    time.Time
+	seq   int // breaks ties in scheduling order
+	kind  discreteKind
+	order *order.Order
+	value float64 // of a released courier's order at pickup
+	x, y  float64 // where a released courier ends up
+	note  string  // why the run ends
+}
+
+// discreteQueue is a min-heap of events by time
+type discreteQueue []discreteEvent
+
+func (q discreteQueue) Len() int { return len(q) }
+func (q discreteQueue) Less(i, j int) bool {
+	if !q[i].at.Equal(q[j].at) {
+		return q[i].at.Before(q[j].at)
+	}
+	return q[i].seq < q[j].seq
+}
+func (q discreteQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *discreteQueue) Push(x any)   { *q = append(*q, x.(discreteEvent)) }
+func (q *discreteQueue) Pop() any {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
+
+// schedule queues an event after delay in simulated time
+func (e *DiscreteEngine) schedule(delay time.Duration, ev discreteEvent) {
+	ev.at = e.Clock.Now().Add(delay)
+	ev.seq = e.seq
+	e.seq++
+	heap.Push(&e.queue, ev)
+}
+
+// Run plays the simulation to the end as fast as possible
+func (e *DiscreteEngine) Run() {
+	began := time.Now()
+	e.startedAt = e.Clock.Now()
+	e.waste.begin(e.startedAt)
+	fmt.Println("Starting discrete-event simulation...")
+	e.printRun()
+	fmt.Printf("Configuration: %s, Orders/sec=%.1f\n",
+		shelfSummary(e.ShelfManager.ShelfStates(), func(st shelf.ShelfState) int { return st.Capacity }),
+		e.Config.OrdersPerSecond)
+	e.printOrderCount()
+	if len(e.ignored) > 0 {
+		fmt.Printf("⚠️ Ignored by the discrete engine: %s\n", strings.Join(e.ignored, ", "))
+	}
+
+	switch {
+	case e.readAhead():
+		e.schedule(0, discreteEvent{kind: discreteArrival})
+	case e.Config.SimulationDuration <= 0:
+		// Nothing would ever happen
+		e.schedule(0, discreteEvent{kind: discreteEnd, note: "No orders to process!"})
+	}
+	e.schedule(0, discreteEvent{kind: discretePickup})
+	e.schedule(10*time.Second, discreteEvent{kind: discreteReport})
+	if e.sampling() {
+		e.schedule(0, discreteEvent{kind: discreteSample})
+	}
+	if e.Config.SimulationDuration > 0 {
+		fmt.Printf("Maximum simulation time: %d seconds\n", e.Config.SimulationDuration)
+		e.schedule(time.Duration(e.Config.SimulationDuration)*time.Second,
+			discreteEvent{kind: discreteEnd, note: "Maximum simulation time reached!"})
+	}
+
+	stopSinks := e.startSinks()
+	e.Events.Publish(events.Event{Type: events.RunStarted, Time: e.startedAt, Version: buildinfo.Version()})
+	done := make(chan struct{})
+	e.doneMutex.Lock()
+	e.done = done
+	e.doneMutex.Unlock()
+	if e.OnStart != nil {
+		e.OnStart()
+	}
+	e.loop()
+	e.cancelInTransit(e.Clock.Now())
+	close(done)
+	stopSinks()
+	e.closeSeries()
+	e.closeResults()
+	closeSource(e.Source)
+
+	fmt.Printf("Simulation completed! %s simulated in %s\n",
+		e.Clock.Now().Sub(e.startedAt).Round(time.Millisecond), time.Since(began).Round(time.Millisecond))
+	e.printFinalStats()
+	if e.OnStop != nil {
+		e.OnStop()
+	}
+}
+
+// Stop ends the run and waits for the event loop to return. The loop runs
+// on Run's own goroutine rather than under the simulator's WaitGroup, which
+// Stop could otherwise be waiting on while Run adds to it.
+func (e *DiscreteEngine) Stop() {
+	e.halt()
+	e.doneMutex.Lock()
+	done := e.done
+	e.doneMutex.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// readAhead takes the next order from the source, reporting whether there
+// is one. A failing source stops the run.
+func (e *DiscreteEngine) readAhead() bool {
+	d, err := e.nextOrder(context.Background())
+	if err != nil && !errors.Is(err, io.EOF) {
+		e.setErr(fmt.Errorf("reading orders: %w", err))
+		e.halt()
+	}
+	e.next = d
+	return err == nil
+}
+
+// loop handles events in time order until the run ends or is stopped
+func (e *DiscreteEngine) loop() {
+	for e.queue.Len() > 0 {
+		if !e.waitIfPaused() {
+			return
+		}
+		select {
+		case <-e.stop:
+			return
+		default:
+		}
+
+		ev := heap.Pop(&e.queue).(discreteEvent)
+		e.advance(ev.at)
+		if !e.handle(ev) {
+			return
+		}
+	}
+}
+
+// advance moves the clock to target, expiring orders at each due time on
+// the way
+func (e *DiscreteEngine) advance(target time.Time) {
+	for {
+		next, ok := e.ShelfManager.NextExpiry()
+		if !ok || next.After(target) {
+			break
+		}
+		if next.After(e.Clock.Now()) {
+			e.Clock.Set(next)
+		}
+		expired := e.cleanupTimed(func() int { return e.ShelfManager.RemoveDueOrders(e.Clock.Now()) })
+		if expired > 0 {
+			e.logf("🗑️ Removed %d expired orders\n", expired)
+			e.Events.Publish(events.Event{Type: events.OrdersExpired, Time: e.Clock.Now(), Count: expired})
+		}
+
+		// Guard against a schedule that does not move past now
+		if again, ok := e.ShelfManager.NextExpiry(); ok && !again.After(e.Clock.Now()) {
+			break
+		}
+	}
+	if target.After(e.Clock.Now()) {
+		e.Clock.Set(target)
+	}
+}
+
+// handle applies one event, returning false once the run has ended
+func (e *DiscreteEngine) handle(ev discreteEvent) bool {
+	switch ev.kind {
+	case discreteArrival:
+		if e.throttle(1) {
+			e.schedule(time.Duration(float64(time.Second)/e.Config.OrdersPerSecond), discreteEvent{kind: discreteArrival})
+			break
+		}
+		e.createOrder(*e.next)
+		if e.readAhead() {
+			e.schedule(time.Duration(float64(time.Second)/e.Config.OrdersPerSecond), discreteEvent{kind: discreteArrival})
+		} else {
+			// Allow time for deliveries and cleanup, as the real-time run does
+			e.schedule(10*time.Second, discreteEvent{kind: discreteEnd, note: "All orders have been processed!"})
+		}
+	case discretePickup:
+		e.retryDeferred()
+		if e.Couriers != nil {
+			e.dispatch()
+			e.schedule(e.deliveryInterval, discreteEvent{kind: discretePickup})
+			break
+		}
+		if len(e.pickups) == 0 {
+			e.pickups = byExpiry(e.ShelfManager.GetAllOrders())
+		}
+		if len(e.pickups) == 0 {
+			e.schedule(e.deliveryInterval, discreteEvent{kind: discretePickup})
+			break
+		}
+		next := e.pickups[0]
+		e.pickups = e.pickups[1:]
+		delay := e.pickupDelay()
+		e.schedule(delay, discreteEvent{kind: discreteDeliver, order: next})
+	case discreteDeliver:
+		if result, ok := e.pickUp(ev.order.ID); ok {
+			e.handOff(result.Order, result.Value, result.At.Add(e.handoffDuration()))
+			e.pool.Put(ev.order)
+		}
+		e.schedule(0, discreteEvent{kind: discretePickup})
+	case discreteCollect:
+		e.collect(ev.order)
+	case discreteRelease:
+		e.handOff(ev.order, ev.value, e.Clock.Now())
+		e.Couriers.Release(ev.order, ev.x, ev.y)
+		e.pool.Put(ev.order)
+	case discreteReport:
+		e.PrintCurrentStats()
+		e.schedule(10*time.Second, discreteEvent{kind: discreteReport})
+	case discreteSample:
+		e.sampleSeries()
+		e.schedule(seconds(e.Config.TimeSeries.Interval), discreteEvent{kind: discreteSample})
+	case discreteEnd:
+		fmt.Println(ev.note)
+		e.halt()
+		return false
+	}
+	return true
+}
+
+// dispatch sends free fleet couriers to the shelved orders, soonest to
+// expire first, as the real-time Simulator does
+func (e *DiscreteEngine) dispatch() {
+	for _, o := range byExpiry(e.ShelfManager.GetAllOrders()) {
+		if e.Couriers.Idle() == 0 {
+			return
+		}
+		if c, travel := e.Couriers.Assign(o); c != nil {
+			e.schedule(travel, discreteEvent{kind: discreteCollect, order: o})
+		}
+	}
+}
+
+// collect picks up an order for the courier sent to it, which then carries
+// it to a random customer. A courier finding the order gone is free again
+// at once.
+func (e *DiscreteEngine) collect(o *order.Order) {
+	x, y := e.randomPosition(e.Config.Couriers.Reach)
+	result, ok := e.pickUp(o.ID)
+	if !ok {
+		e.Couriers.Missed(o)
+		e.Couriers.Release(o, x, y)
+		return
+	}
+	e.Couriers.PickedUp(o, result.At)
+
+	trip := time.Duration(math.Hypot(x, y)*float64(time.Second)) + e.handoffDuration()
+	e.schedule(trip, discreteEvent{kind: discreteRelease, order: result.Order, value: result.Value, x: x, y: y})
+}
+
+// Pause stops the engine between events until Resume. Simulated time
+// stands still meanwhile.
+func (e *DiscreteEngine) Pause() {
+	e.pauseMutex.Lock()
+	defer e.pauseMutex.Unlock()
+
+	if e.resume == nil {
+		e.resume = make(chan struct{})
+		fmt.Println("⏸️ Simulation paused")
+		e.Events.Publish(events.Event{Type: events.RunPaused, Time: e.Clock.Now()})
+	}
+}
+
+// Resume undoes Pause
+func (e *DiscreteEngine) Resume() {
+	e.pauseMutex.Lock()
+	defer e.pauseMutex.Unlock()
+
+	if e.resume != nil {
+		close(e.resume)
+		e.resume = nil
+		fmt.Println("▶️ Simulation resumed")
+		e.Events.Publish(events.Event{Type: events.RunResumed, Time: e.Clock.Now()})
+	}
+}
+
+// Paused reports whether the engine is paused
+func (e *DiscreteEngine) Paused() bool {
+	e.pauseMutex.Lock()
+	defer e.pauseMutex.Unlock()
+
+	return e.resume != nil
+}
+
+// waitIfPaused blocks while the engine is paused. It returns false if the
+// engine was stopped instead of resumed.
+func (e *DiscreteEngine) waitIfPaused() bool {
+	e.pauseMutex.Lock()
+	resume := e.resume
+	e.pauseMutex.Unlock()
+
+	if resume == nil {
+		return true
+	}
+	select {
+	case <-resume:
+		return true
+	case <-e.stop:
+		return false
+	}
+}
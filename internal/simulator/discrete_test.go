@@ -0,0 +1,313 @@
+package simulator
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/events"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func writeOrders(t *testing.T, orders []OrderData) string {
+	t.Helper()
+
+	raw, err := json.Marshal(orders)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "orders.json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func newTestDiscreteEngine(t *testing.T, n int) *DiscreteEngine {
+	t.Helper()
+
+	orders := make([]OrderData, n)
+	for i := range orders {
+		orders[i] = OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	}
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 2
+	cfg.SimulationDuration = 0
+
+	e, err := NewDiscreteEngine(cfg, writeOrders(t, orders))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	return e
+}
+
+func TestDiscreteEngine_Run(t *testing.T) {
+	e := newTestDiscreteEngine(t, 40)
+
+	began := time.Now()
+	e.Run()
+
+	// 40 orders at 2 a second take 20 simulated seconds, plus 10 to settle
+	if simulated := e.Clock.Now().Sub(e.startedAt); simulated < 29*time.Second || simulated > 31*time.Second {
+		t.Errorf("Expected about 30s of simulated time, got %s", simulated)
+	}
+	if elapsed := time.Since(began); elapsed > 5*time.Second {
+		t.Errorf("Expected the run to finish quickly, took %s", elapsed)
+	}
+
+	totals := e.currentTotals()
+	if totals.received != 40 {
+		t.Errorf("Expected 40 orders received, got %d", totals.received)
+	}
+	if totals.delivered == 0 {
+		t.Errorf("Expected some deliveries")
+	}
+	if totals.delivered+totals.lost+totals.shelved != totals.received {
+		t.Errorf("Expected every order accounted for, got %+v", totals)
+	}
+}
+
+func TestDiscreteEngine_Duration(t *testing.T) {
+	e := newTestDiscreteEngine(t, 100)
+	e.Config.SimulationDuration = 5
+
+	e.Run()
+
+	if simulated := e.Clock.Now().Sub(e.startedAt); simulated != 5*time.Second {
+		t.Errorf("Expected 5s of simulated time, got %s", simulated)
+	}
+	if got := e.currentTotals().received; got != 10 {
+		t.Errorf("Expected 10 orders in 5 seconds, got %d", got)
+	}
+}
+
+func TestDiscreteEngine_PauseResume(t *testing.T) {
+	e := newTestDiscreteEngine(t, 10)
+	updates, unsubscribe := e.Subscribe()
+	defer unsubscribe()
+
+	e.Pause()
+	if !e.Paused() {
+		t.Fatalf("Expected the engine to be paused")
+	}
+	if event := <-updates; event.Type != events.RunPaused {
+		t.Errorf("Expected a paused event, got %s", event.Type)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		e.Run()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("Expected the paused engine to wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := e.Stats()["totalOrders"].(map[string]interface{})["received"].(int); got != 0 {
+		t.Errorf("Expected no orders while paused, got %d", got)
+	}
+
+	e.Resume()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the engine to finish once resumed")
+	}
+	if got := e.currentTotals().received; got != 10 {
+		t.Errorf("Expected 10 orders received, got %d", got)
+	}
+}
+
+func TestDiscreteEngine_StopWhilePaused(t *testing.T) {
+	e := newTestDiscreteEngine(t, 10)
+	e.Pause()
+
+	done := make(chan struct{})
+	go func() {
+		e.Run()
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	e.Stop()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected Stop to end a paused run")
+	}
+}
+
+func TestNewDiscreteEngine_Ignored(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Couriers.Count = 3
+	cfg.Failures.RandomPerMinute = 1
+
+	e, err := NewDiscreteEngine(cfg, writeOrders(t, nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(e.ignored) != 1 {
+		t.Errorf("Expected the failures to be ignored, got %v", e.ignored)
+	}
+	if e.Couriers == nil {
+		t.Errorf("Expected the courier fleet to be modelled")
+	}
+
+	cfg.Service.Enabled = true
+	if _, err := NewDiscreteEngine(cfg, ""); err == nil {
+		t.Errorf("Expected service mode to be rejected")
+	}
+
+	cfg.Service.Enabled = false
+	cfg.Orders.Watch = true
+	if _, err := NewDiscreteEngine(cfg, writeOrders(t, nil)); err == nil {
+		t.Errorf("Expected watching the orders file to be rejected")
+	}
+}
+
+// TestDiscreteEngine_MatchesRealTime guards the accuracy of fast-forwarded
+// runs: with the same orders and seed, the discrete engine must finish
+// with the real-time engine's final stats, within a few points. It runs in
+// real time for two seconds.
+func TestDiscreteEngine_MatchesRealTime(t *testing.T) {
+	orders := make([]OrderData, 200)
+	for i := range orders {
+		orders[i] = OrderData{Name: "Soup", Temp: "hot", ShelfLife: 30, DecayRate: 1}
+	}
+	path := writeOrders(t, orders)
+	newConfig := func() *config.Config {
+		cfg := config.DefaultConfig()
+		cfg.OrdersPerSecond = 40
+		cfg.SimulationDuration = 2
+		cfg.HotShelfCapacity = 4
+		cfg.OverflowCapacity = 4
+		cfg.Couriers.Count = 3
+		cfg.Couriers.Reach = 0.1
+		return cfg
+	}
+	rates := func(s *Simulator) (delivered, lost float64) {
+		totals := s.currentTotals()
+		if totals.received == 0 {
+			t.Fatalf("Expected orders to be received")
+		}
+		return 100 * float64(totals.delivered) / float64(totals.received), 100 * float64(totals.lost) / float64(totals.received)
+	}
+
+	e, err := NewDiscreteEngine(newConfig(), path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e.Seed(1)
+	e.SetVerbose(false)
+	e.Run()
+	fastDelivered, fastLost := rates(e.Simulator)
+
+	s, err := NewSimulator(newConfig(), path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Seed(1)
+	s.SetVerbose(false)
+	s.Run()
+	delivered, lost := rates(s)
+
+	const tolerance = 3 // percentage points
+	if math.Abs(fastDelivered-delivered) > tolerance || math.Abs(fastLost-lost) > tolerance {
+		t.Errorf("Expected the discrete engine to deliver %.1f%% and lose %.1f%% of orders as in real time, got %.1f%% and %.1f%%",
+			delivered, lost, fastDelivered, fastLost)
+	}
+}
+
+func TestDiscreteEngine_Fleet(t *testing.T) {
+	run := func(seed uint64) courier.StrategyStats {
+		e := newTestDiscreteEngine(t, 20)
+		e.Config.Couriers.Count = 5
+		e.Config.Couriers.Reach = 1
+		fleet, err := newFleet(e.Config.Couriers)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		e.Couriers = fleet
+		e.Seed(seed)
+		e.Run()
+
+		stats := e.Couriers.Stats()[courier.StrategyNearestIdle]
+		if stats.Pickups != 20 {
+			t.Errorf("Expected the couriers to pick up all 20 orders, got %+v", stats)
+		}
+		if e.Couriers.Idle() != 5 {
+			t.Errorf("Expected every courier free at the end, got %d", e.Couriers.Idle())
+		}
+		return stats
+	}
+
+	if a, b := run(7), run(7); a != b {
+		t.Errorf("Expected the same seed to repeat the run, got %+v and %+v", a, b)
+	}
+}
+
+func TestDiscreteEngine_SeedRepeats(t *testing.T) {
+	orders := make([]OrderData, 60)
+	for i := range orders {
+		orders[i] = OrderData{Name: "Soup", Temp: "hot", ShelfLife: float64(30 + i%4*30), DecayRate: 1}
+	}
+	path := writeOrders(t, orders)
+	run := func(seed uint64) (map[string]interface{}, []shelf.ShelfStats) {
+		cfg := config.DefaultConfig()
+		cfg.OrdersPerSecond = 2
+		cfg.SimulationDuration = 0
+		cfg.HotShelfCapacity = 4
+		cfg.OverflowCapacity = 6
+		e, err := NewDiscreteEngine(cfg, path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		e.Seed(seed)
+		e.SetVerbose(false)
+		e.Run()
+
+		var stats []shelf.ShelfStats
+		for _, s := range e.ShelfManager.(*shelf.InMemoryShelfManager).Shelves() {
+			stats = append(stats, s.GetStats())
+		}
+		return e.ShelfManager.GetStats()["totalOrders"].(map[string]interface{}), stats
+	}
+
+	totals, stats := run(3)
+	if totals["delivered"] == 0 || totals["expired"] == 0 {
+		t.Fatalf("Expected a mix of deliveries and expiries, got %v", totals)
+	}
+	for range 5 {
+		againTotals, againStats := run(3)
+		if !reflect.DeepEqual(totals, againTotals) || !reflect.DeepEqual(stats, againStats) {
+			t.Fatalf("Expected the same seed to repeat the run, got %v %+v and %v %+v", totals, stats, againTotals, againStats)
+		}
+	}
+}
+
+func TestSimulator_Pause(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.createOrder(OrderData{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+
+	s.Pause()
+	if !s.Paused() {
+		t.Fatalf("Expected the simulator to be paused")
+	}
+	s.attemptDeliveries()
+	if got := len(s.ShelfManager.GetAllOrders()); got != 1 {
+		t.Errorf("Expected no deliveries while paused, got %d orders shelved", got)
+	}
+
+	s.Resume()
+	if s.Paused() {
+		t.Errorf("Expected the simulator to be resumed")
+	}
+}
@@ -0,0 +1,64 @@
+package simulator
+
+import (
+	"fmt"
+
+	"dish-dispatcher/internal/events"
+)
+
+// SimulationEngine runs a simulation. The real-time Simulator and the
+// DiscreteEngine implement it, so callers can drive either.
+type SimulationEngine interface {
+	// Run runs the simulation to completion and prints the final report
+	Run()
+	// Stop ends the simulation early and waits for it to finish
+	Stop()
+	// Pause stops placing and delivering orders until Resume
+	Pause()
+	Resume()
+	// Stats returns the shelf manager's stats map
+	Stats() map[string]interface{}
+	// Subscribe returns a channel of the run's events and a function to
+	// unsubscribe
+	Subscribe() (<-chan events.Event, func())
+	// Err returns the error that stopped the simulation, if any
+	Err() error
+}
+
+var (
+	_ SimulationEngine = (*Simulator)(nil)
+	_ SimulationEngine = (*DiscreteEngine)(nil)
+)
+
+// Pause stops generating orders and dispatching couriers. Time keeps
+// passing, so shelved orders go on decaying and expiring.
+func (s *Simulator) Pause() {
+	if s.paused.CompareAndSwap(false, true) {
+		fmt.Println("⏸️ Simulation paused")
+		s.Events.Publish(events.Event{Type: events.RunPaused})
+	}
+}
+
+// Resume undoes Pause
+func (s *Simulator) Resume() {
+	if s.paused.CompareAndSwap(true, false) {
+		fmt.Println("▶️ Simulation resumed")
+		s.Events.Publish(events.Event{Type: events.RunResumed})
+	}
+}
+
+// Paused reports whether the simulation is paused
+func (s *Simulator) Paused() bool {
+	return s.paused.Load()
+}
+
+// Stats returns the shelf manager's stats map
+func (s *Simulator) Stats() map[string]interface{} {
+	return s.ShelfManager.GetStats()
+}
+
+// Subscribe returns a channel of the run's events and a function to
+// unsubscribe
+func (s *Simulator) Subscribe() (<-chan events.Event, func()) {
+	return s.Events.Subscribe()
+}
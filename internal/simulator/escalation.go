@@ -0,0 +1,317 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// validateEscalationConfig checks the escalation threshold and action, and
+// that the shelf manager can move orders if escalation moves them
+func validateEscalationConfig(cfg config.EscalationConfig, manager shelf.ShelfManager) error {
+	if cfg.Threshold < 0 || cfg.Threshold > 1 {
+		return fmt.Errorf("escalation threshold must be between 0 and 1, got %v", cfg.Threshold)
+	}
+	if cfg.Threshold == 0 {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		return errors.New("escalation interval must be positive")
+	}
+	switch cfg.Action {
+	case config.EscalateBoost:
+	case config.EscalateMove, config.EscalateBoth:
+		if _, ok := manager.(shelf.OrderMover); !ok {
+			return fmt.Errorf("escalation action %q needs a shelf manager that moves orders, %T does not", cfg.Action, manager)
+		}
+	default:
+		return fmt.Errorf("unknown escalation action %q", cfg.Action)
+	}
+	return nil
+}
+
+// EscalationStats counts the orders escalated as they neared expiry and
+// what became of them
+type EscalationStats struct {
+	Escalated int `json:"escalated"` // orders flagged below the threshold
+	Moved     int `json:"moved"`     // of those, moved to a slower decaying shelf
+	Saved     int `json:"saved"`     // escalated orders delivered after all
+	Lost      int `json:"lost"`      // escalated orders that expired or were wasted
+}
+
+// escalation tracks the escalated orders until they finish. The zero value
+// is ready to use.
+type escalation struct {
+	mutex   sync.Mutex
+	flagged map[string]bool // IDs of the escalated orders still unfinished
+	stats   EscalationStats
+}
+
+// flag marks an order escalated and returns false if it already was
+func (e *escalation) flag(id string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.flagged[id] {
+		return false
+	}
+	if e.flagged == nil {
+		e.flagged = make(map[string]bool)
+	}
+	e.flagged[id] = true
+	e.stats.Escalated++
+	return true
+}
+
+func (e *escalation) moved() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.stats.Moved++
+}
+
+// escalated reports whether an order was escalated and is unfinished
+func (e *escalation) escalated(id string) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.flagged[id]
+}
+
+// observe counts how an escalated order finished
+func (e *escalation) observe(o *order.Order, to order.State) {
+	if !to.Terminal() {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !e.flagged[o.ID] {
+		return
+	}
+	delete(e.flagged, o.ID)
+	switch to {
+	case order.StateDelivered:
+		e.stats.Saved++
+	case order.StateExpired, order.StateWasted:
+		e.stats.Lost++
+	}
+}
+
+// reset clears the counts. Orders still flagged stay escalated and are
+// counted as saved or lost when they finish.
+func (e *escalation) reset() {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.stats = EscalationStats{}
+}
+
+func (e *escalation) snapshot() EscalationStats {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.stats
+}
+
+// Escalations returns how many orders were escalated near expiry and how
+// many of them were saved
+func (s *Simulator) Escalations() EscalationStats {
+	return s.escalation.snapshot()
+}
+
+// escalationEnabled reports whether orders are escalated near expiry
+func (s *Simulator) escalationEnabled() bool {
+	return s.Config.Escalation.Threshold > 0
+}
+
+// watchEscalations scans the shelves for orders below the escalation
+// threshold until the simulation stops
+func (s *Simulator) watchEscalations() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(seconds(s.Config.Escalation.Interval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.paused.Load() {
+				s.escalateOrders(s.now())
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// escalateOrders escalates every shelved order that has fallen below the
+// threshold at now and was not escalated before, returning how many
+func (s *Simulator) escalateOrders(now time.Time) int {
+	cfg := s.Config.Escalation
+	move := cfg.Action == config.EscalateMove || cfg.Action == config.EscalateBoth
+
+	escalated := 0
+	for _, o := range s.ShelfManager.GetAllOrders() {
+		value := o.CalculateValue(now)
+		if value >= cfg.Threshold || !s.escalation.flag(o.ID) {
+			continue
+		}
+		escalated++
+		s.orderLogf(o.ID, o.Name, "⏫ Order escalated: %s (Value: %.2f)\n", o.Name, value)
+		if move {
+			s.moveToColdest(o)
+		}
+	}
+	return escalated
+}
+
+// moveToColdest moves an escalated order to the coldest shelf with room
+// that decays it slower than the shelf it is on, if there is one
+func (s *Simulator) moveToColdest(o *order.Order) {
+	mover := s.ShelfManager.(shelf.OrderMover)
+	states := s.ShelfManager.ShelfStates()
+	current := slices.IndexFunc(states, func(st shelf.ShelfState) bool { return st.Type == shelf.ShelfType(o.CurrentShelfType) })
+	if current < 0 {
+		return
+	}
+
+	for _, target := range coldestShelves(states) {
+		if coldness(target, states[current]) >= 0 {
+			return
+		}
+		if target.InOutage {
+			continue
+		}
+		// Shelves that cannot take the order's temperature, or are full,
+		// refuse it; the next coldest may not
+		if err := mover.MoveOrder(o.ID, target.Type); err == nil {
+			s.escalation.moved()
+			s.tracer.settle(o)
+			s.orderLogf(o.ID, o.Name, "🧊 Escalated order moved: %s (%s -> %s)\n", o.Name, states[current].Type, target.Type)
+			return
+		}
+	}
+}
+
+// coldestShelves returns the shelves ordered from the one that decays
+// orders slowest
+func coldestShelves(states []shelf.ShelfState) []shelf.ShelfState {
+	sorted := slices.Clone(states)
+	slices.SortStableFunc(sorted, coldness)
+	return sorted
+}
+
+// tempRanks order temperatures from the coldest
+var tempRanks = map[order.Temperature]int{order.Frozen: 0, order.Cold: 1, order.Hot: 2}
+
+// coldness compares two shelves, negative if a decays orders slower than
+// b. Overflow shelves decay fastest, then shelves by their decay modifier,
+// then by the coldest temperature they hold.
+func coldness(a, b shelf.ShelfState) int {
+	if ao, bo := len(a.Temps) == 0, len(b.Temps) == 0; ao != bo {
+		if ao {
+			return 1
+		}
+		return -1
+	}
+	if am, bm := decayModifier(a), decayModifier(b); am != bm {
+		if am < bm {
+			return -1
+		}
+		return 1
+	}
+	return coldestTemp(a) - coldestTemp(b)
+}
+
+// decayModifier returns the multiplier a shelf applies to decay
+func decayModifier(st shelf.ShelfState) float64 {
+	if st.DecayModifier == 0 {
+		return 1
+	}
+	return st.DecayModifier
+}
+
+// coldestTemp ranks the coldest temperature a shelf holds, with unknown
+// temperatures warmest
+func coldestTemp(st shelf.ShelfState) int {
+	coldest := len(tempRanks)
+	for _, temp := range st.Temps {
+		if rank, ok := tempRanks[temp]; ok {
+			coldest = min(coldest, rank)
+		}
+	}
+	return coldest
+}
+
+// pickupOrders returns the shelved orders in the order couriers should
+// collect them: escalated ones first when escalation boosts them, then
+// soonest to expire
+func (s *Simulator) pickupOrders() []*order.Order {
+	orders := byExpiry(s.ShelfManager.GetAllOrders())
+	cfg := s.Config.Escalation
+	if !s.escalationEnabled() || (cfg.Action != config.EscalateBoost && cfg.Action != config.EscalateBoth) {
+		return orders
+	}
+
+	boosted := make([]*order.Order, 0, len(orders))
+	var rest []*order.Order
+	for _, o := range orders {
+		if s.escalation.escalated(o.ID) {
+			boosted = append(boosted, o)
+		} else {
+			rest = append(rest, o)
+		}
+	}
+	return append(boosted, rest...)
+}
+
+// byExpiry sorts orders soonest to expire first, with orders that never
+// expire last. Ties go to the order created first, then to the lower ID, so
+// a snapshot taken in map order dispatches the same way every seeded run.
+func byExpiry(orders []*order.Order) []*order.Order {
+	type keyed struct {
+		expiresAt time.Time
+		o         *order.Order
+	}
+	keys := make([]keyed, len(orders))
+	for i, o := range orders {
+		keys[i] = keyed{o.ExpiresAt(), o}
+	}
+	slices.SortFunc(keys, func(a, b keyed) int {
+		switch {
+		case a.expiresAt.IsZero() != b.expiresAt.IsZero():
+			// Orders that never expire go last
+			if a.expiresAt.IsZero() {
+				return 1
+			}
+			return -1
+		case !a.expiresAt.Equal(b.expiresAt):
+			return a.expiresAt.Compare(b.expiresAt)
+		case !a.o.CreatedAt.Equal(b.o.CreatedAt):
+			return a.o.CreatedAt.Compare(b.o.CreatedAt)
+		}
+		return strings.Compare(a.o.ID, b.o.ID)
+	})
+	for i, k := range keys {
+		orders[i] = k.o
+	}
+	return orders
+}
+
+// printEscalationStats prints how many escalated orders were saved
+func (s *Simulator) printEscalationStats() {
+	if !s.escalationEnabled() {
+		return
+	}
+	e := s.escalation.snapshot()
+	fmt.Printf("  Escalated near expiry: %d (moved %d), saved %d, lost %d\n",
+		e.Escalated, e.Moved, e.Saved, e.Lost)
+}
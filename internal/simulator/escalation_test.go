@@ -0,0 +1,185 @@
+package simulator
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestValidateEscalationConfig(t *testing.T) {
+	manager := shelf.NewShelfManager(5, 5, 5, 10)
+	if err := validateEscalationConfig(config.DefaultConfig().Escalation, manager); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := validateEscalationConfig(config.EscalationConfig{Threshold: 0.3, Interval: 1, Action: config.EscalateMove}, manager); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, cfg := range []config.EscalationConfig{
+		{Threshold: -0.1, Interval: 1, Action: config.EscalateBoth},
+		{Threshold: 1.5, Interval: 1, Action: config.EscalateBoth},
+		{Threshold: 0.3, Action: config.EscalateBoth},
+		{Threshold: 0.3, Interval: 1, Action: "teleport"},
+	} {
+		if err := validateEscalationConfig(cfg, manager); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+
+	// A manager that cannot move orders can still boost them
+	fixed := struct{ shelf.ShelfManager }{manager}
+	if err := validateEscalationConfig(config.EscalationConfig{Threshold: 0.3, Interval: 1, Action: config.EscalateMove}, fixed); err == nil {
+		t.Errorf("Expected moving orders to need an OrderMover")
+	}
+	if err := validateEscalationConfig(config.EscalationConfig{Threshold: 0.3, Interval: 1, Action: config.EscalateBoost}, fixed); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+// setupEscalation fills the hot shelf and puts one more hot order on
+// overflow, where the challenge formula decays it twice as fast, and
+// returns the orders
+func setupEscalation(t *testing.T, action string) (*Simulator, []*order.Order, *order.Order) {
+	t.Helper()
+	s := setupTestSimulator(t)
+	s.quiet.Store(true)
+	s.Config.Escalation = config.EscalationConfig{Threshold: 0.4, Interval: 1, Action: action}
+
+	newOrder := func() *order.Order {
+		o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+		o.Formula = order.CSSChallengeFormula{}
+		return o
+	}
+	var hot []*order.Order
+	for range 5 {
+		o := newOrder()
+		if err := s.ShelfManager.PlaceOrder(o); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		hot = append(hot, o)
+	}
+	overflowed := newOrder()
+	if err := s.ShelfManager.PlaceOrder(overflowed); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if overflowed.CurrentShelfType != string(shelf.OverflowShelf) {
+		t.Fatalf("Expected the sixth hot order on overflow, got %s", overflowed.CurrentShelfType)
+	}
+	return s, hot, overflowed
+}
+
+func TestEscalateOrders_MovesToColdest(t *testing.T) {
+	s, hot, overflowed := setupEscalation(t, config.EscalateMove)
+
+	// After 100s the hot orders keep half their value and the overflowed
+	// one a third. With the hot shelf full it has nowhere better to go.
+	later := time.Now().Add(100 * time.Second)
+	if n := s.escalateOrders(later); n != 1 {
+		t.Fatalf("Expected 1 order escalated, got %d", n)
+	}
+	if overflowed.CurrentShelfType != string(shelf.OverflowShelf) {
+		t.Errorf("Expected the order to stay on overflow, got %s", overflowed.CurrentShelfType)
+	}
+
+	// Each order is escalated once
+	s.ShelfManager.DeliverOrder(hot[0].ID)
+	if n := s.escalateOrders(later); n != 0 {
+		t.Errorf("Expected no more escalations, got %d", n)
+	}
+
+	s, hot, overflowed = setupEscalation(t, config.EscalateMove)
+	s.ShelfManager.DeliverOrder(hot[0].ID)
+	s.escalateOrders(later)
+	if overflowed.CurrentShelfType != string(shelf.HotShelf) {
+		t.Errorf("Expected the order moved to the hot shelf, got %s", overflowed.CurrentShelfType)
+	}
+	if e := s.Escalations(); e.Escalated != 1 || e.Moved != 1 {
+		t.Errorf("Expected 1 order escalated and moved, got %+v", e)
+	}
+}
+
+func TestPickupOrders_BoostsEscalated(t *testing.T) {
+	s, _, overflowed := setupEscalation(t, config.EscalateBoost)
+
+	if orders := s.pickupOrders(); orders[0] != overflowed {
+		t.Fatalf("Expected the overflowed order, which decays fastest, collected first")
+	}
+	s.escalateOrders(time.Now().Add(100 * time.Second))
+
+	// An order expiring sooner still waits behind the escalated one
+	quick := order.NewOrder("Sushi", order.Cold, 1, 0.5)
+	if err := s.ShelfManager.PlaceOrder(quick); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	orders := s.pickupOrders()
+	if orders[0] != overflowed {
+		t.Errorf("Expected the escalated order collected first, got %s on %s", orders[0].ID, orders[0].CurrentShelfType)
+	}
+	if orders[1] != quick {
+		t.Errorf("Expected the soonest to expire collected next, got %s", orders[1].ID)
+	}
+	if overflowed.CurrentShelfType != string(shelf.OverflowShelf) {
+		t.Errorf("Expected boosting to leave the order on its shelf, got %s", overflowed.CurrentShelfType)
+	}
+}
+
+func TestByExpiry(t *testing.T) {
+	placed := time.Now()
+	shelved := func(id string, shelfLife float64) *order.Order {
+		return &order.Order{ID: id, Temp: order.Hot, ShelfLife: shelfLife, DecayRate: 0.5, PlacedOnShelfAt: placed, CreatedAt: placed}
+	}
+	never := &order.Order{ID: "a-unshelved", Temp: order.Hot, ShelfLife: 10}
+	older := shelved("f", 300)
+	older.CreatedAt = placed.Add(-time.Second)
+	orders := []*order.Order{never, shelved("d", 300), shelved("c", 10), older, shelved("b", 300), shelved("e", 100)}
+
+	var got []string
+	for _, o := range byExpiry(orders) {
+		got = append(got, o.ID)
+	}
+	want := []string{"c", "e", "f", "b", "d", "a-unshelved"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestEscalation_Outcomes(t *testing.T) {
+	var e escalation
+	saved := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	lost := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	other := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	e.flag(saved.ID)
+	e.flag(lost.ID)
+
+	e.observe(saved, order.StateInTransit)
+	e.observe(saved, order.StateDelivered)
+	e.observe(lost, order.StateExpired)
+	e.observe(other, order.StateExpired)
+
+	if stats := e.snapshot(); stats != (EscalationStats{Escalated: 2, Saved: 1, Lost: 1}) {
+		t.Errorf("Expected 1 of 2 escalated orders saved, got %+v", stats)
+	}
+	if e.escalated(saved.ID) || e.escalated(lost.ID) {
+		t.Errorf("Expected finished orders to be forgotten")
+	}
+}
+
+func TestColdestShelves(t *testing.T) {
+	states := []shelf.ShelfState{
+		{Type: shelf.HotShelf, Temps: []order.Temperature{order.Hot}},
+		{Type: shelf.OverflowShelf},
+		{Type: shelf.ColdShelf, Temps: []order.Temperature{order.Cold}},
+		{Type: "chiller", Temps: []order.Temperature{order.Hot}, DecayModifier: 0.5},
+		{Type: shelf.FrozenShelf, Temps: []order.Temperature{order.Frozen}},
+	}
+
+	want := []shelf.ShelfType{"chiller", shelf.FrozenShelf, shelf.ColdShelf, shelf.HotShelf, shelf.OverflowShelf}
+	for i, st := range coldestShelves(states) {
+		if st.Type != want[i] {
+			t.Errorf("Expected %s at %d, got %s", want[i], i, st.Type)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/eventlog"
+	"dish-dispatcher/plugin"
+)
+
+// validateEventLogConfig checks the rotation limits of the event log
+func validateEventLogConfig(cfg config.EventLogConfig) error {
+	if cfg.MaxSize < 0 {
+		return fmt.Errorf("eventLog.maxSize must not be negative, got %d", cfg.MaxSize)
+	}
+	if cfg.MaxAge < 0 {
+		return fmt.Errorf("eventLog.maxAge must not be negative, got %d", cfg.MaxAge)
+	}
+	if cfg.File == "" && (cfg.MaxSize > 0 || cfg.MaxAge > 0 || cfg.Compress) {
+		return errors.New("eventLog rotation needs eventLog.file")
+	}
+	return nil
+}
+
+// eventLogSink writes every event to the event log as a line of JSON
+type eventLogSink struct {
+	w      *eventlog.Writer
+	encode eventEncoder
+	failed bool // a write failed, which is reported only once
+}
+
+// newEventLogSink opens the configured event log
+func newEventLogSink(cfg config.EventLogConfig, encode eventEncoder) (*eventLogSink, error) {
+	w, err := eventlog.Open(eventlog.Options{
+		Path:     cfg.File,
+		MaxSize:  int64(cfg.MaxSize) << 20,
+		MaxAge:   time.Duration(cfg.MaxAge) * time.Second,
+		Compress: cfg.Compress,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogSink{w: w, encode: encode}, nil
+}
+
+func (l *eventLogSink) Handle(e plugin.Event) {
+	line, err := l.encode(e)
+	if err == nil {
+		_, err = l.w.Write(append(line, '\n'))
+	}
+	if err != nil && !l.failed {
+		l.failed = true
+		fmt.Printf("⚠️ Writing the event log failed, later events may be lost: %v\n", err)
+	}
+}
+
+func (l *eventLogSink) Close() error {
+	return l.w.Close()
+}
@@ -0,0 +1,97 @@
+package simulator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/plugin"
+)
+
+func TestValidateEventLogConfig(t *testing.T) {
+	valid := []config.EventLogConfig{
+		{},
+		{File: "events.ndjson"},
+		{File: "events.ndjson", MaxSize: 100, MaxAge: 3600, Compress: true},
+	}
+	for _, cfg := range valid {
+		if err := validateEventLogConfig(cfg); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", cfg, err)
+		}
+	}
+
+	invalid := []config.EventLogConfig{
+		{File: "events.ndjson", MaxSize: -1},
+		{File: "events.ndjson", MaxAge: -1},
+		{MaxSize: 100},
+		{Compress: true},
+	}
+	for _, cfg := range invalid {
+		if err := validateEventLogConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestEventLogSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink, err := newEventLogSink(config.EventLogConfig{File: path}, newEventEncoder(config.DefaultConfig()))
+	if err != nil {
+		t.Fatalf("Failed to open the event log: %v", err)
+	}
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	sink.Handle(plugin.Event{Type: "order_placed", Time: at, OrderID: "o1", Shelf: "hot"})
+	sink.Handle(plugin.Event{Type: "orders_expired", Time: at, Count: 2})
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Failed to close the event log: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", data)
+	}
+	var e events.Event
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("Line %q is not an event: %v", lines[0], err)
+	}
+	if e.Type != events.OrderPlaced || e.OrderID != "o1" || e.Shelf != "hot" || !e.Time.Equal(at) {
+		t.Errorf("Unexpected first event %+v", e)
+	}
+}
+
+func TestEventLog_RunStarted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	cfg := config.DefaultConfig()
+	cfg.SimulationDuration = 0
+	cfg.EventLog.File = path
+	e, err := NewDiscreteEngine(cfg, writeOrders(t, []OrderData{{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e.SetVerbose(false)
+	e.Run()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := strings.Cut(string(data), "\n")
+	var started events.Event
+	if err := json.Unmarshal([]byte(first), &started); err != nil {
+		t.Fatalf("Line %q is not an event: %v", first, err)
+	}
+	if started.Type != events.RunStarted || started.Version != buildinfo.Version() {
+		t.Errorf("Expected the log to open with the binary's version, got %+v", started)
+	}
+}
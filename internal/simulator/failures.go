@@ -0,0 +1,267 @@
+package simulator
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// injectFailures fires the configured scheduled and random shelf failures
+func (s *Simulator) injectFailures() {
+	defer s.wg.Done()
+
+	failures := s.Config.Failures
+	start := time.Now()
+
+	for _, event := range failures.Events {
+		timer := time.AfterFunc(time.Duration(event.At)*time.Second, func() {
+			s.startFailure(event)
+		})
+		defer timer.Stop()
+	}
+
+	for _, disruption := range failures.Couriers {
+		timer := time.AfterFunc(time.Duration(disruption.At)*time.Second, func() {
+			s.startCourierDisruption(disruption)
+		})
+		defer timer.Stop()
+	}
+
+	// Random failures hit the temperature shelves, never overflow
+	var coolable []shelf.ShelfType
+	for _, state := range s.ShelfManager.ShelfStates() {
+		if len(state.Temps) > 0 {
+			coolable = append(coolable, state.Type)
+		}
+	}
+
+	if failures.RandomPerMinute <= 0 || len(coolable) == 0 {
+		<-s.stop
+		return
+	}
+
+	// Roll once a second so RandomPerMinute failures occur per minute on average
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if shelfType, ok := s.rollFailure(coolable, failures.RandomPerMinute/60); ok {
+				s.startFailure(config.FailureEvent{
+					Shelf:       string(shelfType),
+					At:          int(time.Since(start).Seconds()),
+					Duration:    failures.RandomDuration,
+					DecayFactor: failures.RandomDecayFactor,
+				})
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// rollFailure draws whether a random failure starts, with probability p,
+// and which of the shelves it hits. It draws from the simulator's
+// generator so a seeded run repeats its failures.
+func (s *Simulator) rollFailure(shelves []shelf.ShelfType, p float64) (shelf.ShelfType, bool) {
+	s.randMutex.Lock()
+	defer s.randMutex.Unlock()
+
+	if s.random().Float64() >= p {
+		return "", false
+	}
+	return shelves[s.random().IntN(len(shelves))], true
+}
+
+// injectedFailures tracks the outages and courier disruptions in progress
+// and the timers that end them. Overlapping ones each end on their own, and
+// no recovery fires once the simulation has stopped.
+type injectedFailures struct {
+	mutex   sync.Mutex
+	stopped bool
+	timers  map[*time.Timer]struct{}
+
+	// outages holds, per shelf, the outages in progress, oldest first
+	outages map[shelf.ShelfType][]*outage
+	// disruptions holds the courier disruptions in progress, oldest first
+	disruptions []*courierDisruption
+}
+
+// outage is one injected loss of cooling
+type outage struct {
+	factor float64
+}
+
+// courierDisruption is one injected loss of courier capacity
+type courierDisruption struct {
+	loss float64
+}
+
+// after calls f after d unless the failures are stopped first. It reports
+// false, without scheduling f, once they are.
+func (f *injectedFailures) after(d time.Duration, fn func()) bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.stopped {
+		return false
+	}
+	if f.timers == nil {
+		f.timers = make(map[*time.Timer]struct{})
+	}
+	// The timer is registered before its callback can take the lock
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		f.mutex.Lock()
+		_, live := f.timers[timer]
+		delete(f.timers, timer)
+		f.mutex.Unlock()
+		if live {
+			fn()
+		}
+	})
+	f.timers[timer] = struct{}{}
+	return true
+}
+
+// stop cancels every pending recovery
+func (f *injectedFailures) stop() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.stopped = true
+	for timer := range f.timers {
+		timer.Stop()
+	}
+	f.timers = nil
+}
+
+// startFailure makes a shelf lose cooling and schedules its recovery
+func (s *Simulator) startFailure(event config.FailureEvent) {
+	shelfType := shelf.ShelfType(event.Shelf)
+	o := &outage{factor: event.DecayFactor}
+
+	f := &s.failures
+	f.mutex.Lock()
+	if f.stopped {
+		f.mutex.Unlock()
+		return
+	}
+	if err := s.ShelfManager.StartOutage(shelfType, event.DecayFactor); err != nil {
+		f.mutex.Unlock()
+		fmt.Printf("⚠️ Failure injection skipped: %v\n", err)
+		return
+	}
+	if f.outages == nil {
+		f.outages = make(map[shelf.ShelfType][]*outage)
+	}
+	f.outages[shelfType] = append(f.outages[shelfType], o)
+	f.mutex.Unlock()
+
+	fmt.Printf("⚠️ %s shelf lost cooling for %ds (decay x%.1f)\n",
+		event.Shelf, event.Duration, event.DecayFactor)
+	s.Events.Publish(events.Event{Type: events.ShelfOutage, Shelf: event.Shelf})
+
+	f.after(time.Duration(event.Duration)*time.Second, func() {
+		s.endFailure(shelfType, o)
+	})
+}
+
+// endFailure ends one outage on a shelf. Cooling is only restored once no
+// other outage on it is in progress; until then the latest of those sets
+// the decay factor.
+func (s *Simulator) endFailure(shelfType shelf.ShelfType, o *outage) {
+	f := &s.failures
+	f.mutex.Lock()
+	remaining := slices.DeleteFunc(f.outages[shelfType], func(other *outage) bool { return other == o })
+	f.outages[shelfType] = remaining
+	var err error
+	if len(remaining) > 0 {
+		err = s.ShelfManager.StartOutage(shelfType, remaining[len(remaining)-1].factor)
+	} else {
+		delete(f.outages, shelfType)
+		err = s.ShelfManager.EndOutage(shelfType)
+	}
+	f.mutex.Unlock()
+
+	if err == nil && len(remaining) == 0 {
+		fmt.Printf("🔧 %s shelf cooling restored\n", shelfType)
+		s.Events.Publish(events.Event{Type: events.ShelfRestored, Shelf: string(shelfType)})
+	}
+}
+
+// startCourierDisruption limits courier availability and schedules recovery
+func (s *Simulator) startCourierDisruption(disruption config.CourierDisruption) {
+	loss := 1 - min(max(disruption.Capacity, 0), 1)
+	d := &courierDisruption{loss: loss}
+
+	f := &s.failures
+	f.mutex.Lock()
+	if f.stopped {
+		f.mutex.Unlock()
+		return
+	}
+	f.disruptions = append(f.disruptions, d)
+	s.setCourierLoss(loss)
+	f.mutex.Unlock()
+
+	fmt.Printf("🚧 Couriers disrupted for %ds (%.0f%% capacity)\n",
+		disruption.Duration, (1-loss)*100)
+	s.Events.Publish(events.Event{Type: events.CourierOutage})
+
+	f.after(time.Duration(disruption.Duration)*time.Second, func() {
+		s.endCourierDisruption(d)
+	})
+}
+
+// endCourierDisruption ends one disruption. Couriers only return to full
+// capacity once no other disruption is in progress; until then the latest
+// of those sets the loss.
+func (s *Simulator) endCourierDisruption(d *courierDisruption) {
+	f := &s.failures
+	f.mutex.Lock()
+	f.disruptions = slices.DeleteFunc(f.disruptions, func(other *courierDisruption) bool { return other == d })
+	remaining := len(f.disruptions)
+	if remaining > 0 {
+		s.setCourierLoss(f.disruptions[remaining-1].loss)
+	} else {
+		s.setCourierLoss(0)
+	}
+	f.mutex.Unlock()
+
+	if remaining == 0 {
+		fmt.Println("🚚 Couriers back at full capacity")
+		s.Events.Publish(events.Event{Type: events.CourierRestored})
+	}
+}
+
+func (s *Simulator) setCourierLoss(loss float64) {
+	s.courierMutex.Lock()
+	defer s.courierMutex.Unlock()
+
+	s.courierLoss = loss
+}
+
+// courierAvailable reports whether a courier is free for a pickup. It
+// draws from the simulator's generator so a seeded run repeats its
+// disruptions.
+func (s *Simulator) courierAvailable() bool {
+	s.courierMutex.Lock()
+	loss := s.courierLoss
+	s.courierMutex.Unlock()
+
+	if loss == 0 {
+		return true
+	}
+
+	s.randMutex.Lock()
+	defer s.randMutex.Unlock()
+
+	return s.random().Float64() >= loss
+}
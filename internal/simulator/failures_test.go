@@ -0,0 +1,165 @@
+package simulator
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestStartFailure(t *testing.T) {
+	s := setupTestSimulator(t)
+	o := order.NewOrder("Ice Cream", order.Frozen, 200, 0.2)
+	s.ShelfManager.PlaceOrder(o)
+
+	s.startFailure(config.FailureEvent{Shelf: "frozen", Duration: 0, DecayFactor: 3})
+	if factor := openDecayFactor(o); factor != 3 {
+		t.Errorf("Expected the frozen order to decay x3 during the outage, got x%v", factor)
+	}
+
+	// The outage recovers once its duration has passed
+	deadline := time.Now().Add(5 * time.Second)
+	for outageShelves(s)[shelf.FrozenShelf] {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the frozen shelf to recover")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if factor := openDecayFactor(o); factor != 1 {
+		t.Errorf("Expected normal decay after recovery, got x%v", factor)
+	}
+
+	// Unknown shelves are reported and ignored
+	s.startFailure(config.FailureEvent{Shelf: "sauna", Duration: 60, DecayFactor: 2})
+	if len(outageShelves(s)) != 0 || len(s.failures.timers) != 0 {
+		t.Errorf("Expected no outage for an unknown shelf, got %v", outageShelves(s))
+	}
+}
+
+func TestStartFailure_Overlapping(t *testing.T) {
+	s := setupTestSimulator(t)
+	o := order.NewOrder("Ice Cream", order.Frozen, 200, 0.2)
+	s.ShelfManager.PlaceOrder(o)
+
+	s.startFailure(config.FailureEvent{Shelf: "frozen", Duration: 60, DecayFactor: 2})
+	s.startFailure(config.FailureEvent{Shelf: "frozen", Duration: 60, DecayFactor: 3})
+	first, second := s.failures.outages[shelf.FrozenShelf][0], s.failures.outages[shelf.FrozenShelf][1]
+
+	// Ending the first outage leaves the second in progress
+	s.endFailure(shelf.FrozenShelf, first)
+	if !outageShelves(s)[shelf.FrozenShelf] || openDecayFactor(o) != 3 {
+		t.Errorf("Expected the second outage to continue at x3, got x%v", openDecayFactor(o))
+	}
+
+	s.endFailure(shelf.FrozenShelf, second)
+	if outageShelves(s)[shelf.FrozenShelf] {
+		t.Errorf("Expected cooling to be restored once both outages ended")
+	}
+}
+
+func TestStartFailure_StoppedOnHalt(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	s.startFailure(config.FailureEvent{Shelf: "frozen", Duration: 60, DecayFactor: 2})
+	if len(s.failures.timers) != 1 {
+		t.Fatalf("Expected a pending recovery, got %d", len(s.failures.timers))
+	}
+	s.halt()
+	if len(s.failures.timers) != 0 {
+		t.Errorf("Expected halt to cancel the recovery")
+	}
+
+	// No new outage starts once stopped
+	s.startFailure(config.FailureEvent{Shelf: "hot", Duration: 60, DecayFactor: 2})
+	if outageShelves(s)[shelf.HotShelf] {
+		t.Errorf("Expected no outage after the simulation stopped")
+	}
+}
+
+func TestRollFailure_Seeded(t *testing.T) {
+	shelves := []shelf.ShelfType{shelf.HotShelf, shelf.ColdShelf, shelf.FrozenShelf}
+	roll := func() []shelf.ShelfType {
+		s := setupTestSimulator(t)
+		s.Seed(7)
+		var hit []shelf.ShelfType
+		for i := 0; i < 100; i++ {
+			shelfType, _ := s.rollFailure(shelves, 0.3)
+			hit = append(hit, shelfType)
+		}
+		return hit
+	}
+
+	if a, b := roll(), roll(); !slices.Equal(a, b) {
+		t.Errorf("Expected the same seed to roll the same failures")
+	}
+}
+
+// openDecayFactor returns the factor of the order's open decay window, or
+// 1 if none is open
+func openDecayFactor(o *order.Order) float64 {
+	for _, w := range o.DecayWindows {
+		if w.End.IsZero() {
+			return w.Factor
+		}
+	}
+	return 1
+}
+
+// outageShelves returns the shelves currently in an outage
+func outageShelves(s *Simulator) map[shelf.ShelfType]bool {
+	out := make(map[shelf.ShelfType]bool)
+	for _, state := range s.ShelfManager.ShelfStates() {
+		if state.InOutage {
+			out[state.Type] = true
+		}
+	}
+	return out
+}
+
+func TestCourierDisruption(t *testing.T) {
+	s := setupTestSimulator(t)
+	if !s.courierAvailable() {
+		t.Fatalf("Expected couriers to be available by default")
+	}
+
+	s.startCourierDisruption(config.CourierDisruption{Duration: 60, Capacity: 0})
+	for i := 0; i < 100; i++ {
+		if s.courierAvailable() {
+			t.Fatalf("Expected no couriers while paused")
+		}
+	}
+
+	s.setCourierLoss(0)
+	if !s.courierAvailable() {
+		t.Errorf("Expected couriers to recover")
+	}
+}
+
+func TestCourierDisruption_Overlapping(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	s.startCourierDisruption(config.CourierDisruption{Duration: 60, Capacity: 0.5})
+	s.startCourierDisruption(config.CourierDisruption{Duration: 60, Capacity: 0})
+	first, second := s.failures.disruptions[0], s.failures.disruptions[1]
+
+	// Ending the first disruption leaves the second in progress
+	s.endCourierDisruption(first)
+	if s.courierLoss != 1 {
+		t.Errorf("Expected the second disruption to continue, got loss %v", s.courierLoss)
+	}
+
+	s.endCourierDisruption(second)
+	if s.courierLoss != 0 {
+		t.Errorf("Expected full capacity once both disruptions ended, got loss %v", s.courierLoss)
+	}
+
+	// Recoveries still pending are cancelled on halt
+	s.startCourierDisruption(config.CourierDisruption{Duration: 60, Capacity: 0})
+	s.halt()
+	if len(s.failures.timers) != 0 {
+		t.Errorf("Expected halt to cancel the recovery")
+	}
+}
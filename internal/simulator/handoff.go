@@ -0,0 +1,246 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// handoffTotals sums the value of a group of delivered orders at pickup and
+// at handoff
+type handoffTotals struct {
+	count        int
+	pickupValue  float64
+	handoffValue float64
+	values       valueHistogram // of the value at handoff
+}
+
+func (t handoffTotals) add(pickupValue, handoffValue float64) handoffTotals {
+	t.count++
+	t.pickupValue += pickupValue
+	t.handoffValue += handoffValue
+	t.values.add(handoffValue)
+	return t
+}
+
+// averages returns the mean value at pickup and at handoff
+func (t handoffTotals) averages() (float64, float64) {
+	if t.count == 0 {
+		return 0, 0
+	}
+	n := float64(t.count)
+	return t.pickupValue / n, t.handoffValue / n
+}
+
+// handoffStats compares the value of delivered orders at pickup with their
+// value once handed to the customer, overall and by item and temperature.
+// The zero value is ready to use.
+type handoffStats struct {
+	mutex  sync.Mutex
+	total  handoffTotals
+	byName map[string]handoffTotals
+	byTemp map[order.Temperature]handoffTotals
+}
+
+func (h *handoffStats) record(o *order.Order, pickupValue, handoffValue float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.byName == nil {
+		h.byName = make(map[string]handoffTotals)
+		h.byTemp = make(map[order.Temperature]handoffTotals)
+	}
+	h.total = h.total.add(pickupValue, handoffValue)
+	h.byName[o.Name] = h.byName[o.Name].add(pickupValue, handoffValue)
+	h.byTemp[o.Temp] = h.byTemp[o.Temp].add(pickupValue, handoffValue)
+}
+
+// reset discards every recorded handoff
+func (h *handoffStats) reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.total = handoffTotals{}
+	h.byName = nil
+	h.byTemp = nil
+}
+
+// averages returns how many orders were handed off and their mean value at
+// pickup and at handoff
+func (h *handoffStats) averages() (int, float64, float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	pickup, handoff := h.total.averages()
+	return h.total.count, pickup, handoff
+}
+
+// forName returns the totals for one item
+func (h *handoffStats) forName(name string) handoffTotals {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.byName[name]
+}
+
+// forTemp returns the totals for one temperature
+func (h *handoffStats) forTemp(temp order.Temperature) handoffTotals {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return h.byTemp[temp]
+}
+
+// transitSet holds the orders collected from their shelves and not yet
+// handed off. The zero value is ready to use.
+type transitSet struct {
+	mutex  sync.Mutex
+	orders map[string]*order.Order
+}
+
+func (t *transitSet) add(o *order.Order) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.orders == nil {
+		t.orders = make(map[string]*order.Order)
+	}
+	t.orders[o.ID] = o
+}
+
+func (t *transitSet) remove(o *order.Order) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	delete(t.orders, o.ID)
+}
+
+// drain empties the set, returning the orders it held
+func (t *transitSet) drain() []*order.Order {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	orders := make([]*order.Order, 0, len(t.orders))
+	for _, o := range t.orders {
+		orders = append(orders, o)
+	}
+	t.orders = nil
+	return orders
+}
+
+// handoffDuration returns how long a courier spends handing an order over
+// after reaching the customer
+func (s *Simulator) handoffDuration() time.Duration {
+	return time.Duration(s.Config.Couriers.Handoff * float64(time.Second))
+}
+
+// startTransit ends any shelf decay windows at pickup and, if configured,
+// opens a window decaying the order at the in-transit rate until handoff.
+// The order is tracked until then.
+func (s *Simulator) startTransit(o *order.Order, at time.Time) {
+	s.transit.add(o)
+	o.CloseDecayWindows(at)
+	if factor := s.Config.Couriers.TransitDecay; factor > 0 && factor != 1 {
+		o.OpenDecayWindow(at, factor)
+	}
+}
+
+// pickUp collects an order from its shelf, recording its delivery, or its
+// waste if it is refused as worth less than the minimum delivery value
+func (s *Simulator) pickUp(orderID string) (shelf.DeliveryResult, bool) {
+	result, ok := s.deliverTimed(orderID)
+	if ok {
+		s.recordDelivery(result)
+	} else if result.Refused {
+		s.recordRefusal(result)
+	}
+	return result, ok
+}
+
+// recordRefusal records an order wasted at pickup for falling below the
+// minimum delivery value
+func (s *Simulator) recordRefusal(r shelf.DeliveryResult) {
+	o := r.Order
+	s.orderLogf(o.ID, o.Name, "❌ Order wasted (%s): %s (Value: %.2f)\n", shelf.RejectLowValue, o.Name, r.Value)
+	s.Events.Publish(events.Event{
+		Type:    events.OrderWasted,
+		Time:    r.At,
+		OrderID: o.ID,
+		Name:    o.Name,
+		Temp:    string(o.Temp),
+		Shelf:   string(r.Shelf),
+		Value:   r.Value,
+		Reason:  string(shelf.RejectLowValue),
+	})
+}
+
+// recordDelivery records an order collected from its shelf and starts its
+// transit. The log, the event and the metrics all take the result's
+// numbers rather than working them out again later.
+func (s *Simulator) recordDelivery(r shelf.DeliveryResult) {
+	o := r.Order
+	s.startTransit(o, r.At)
+	s.orderLogf(o.ID, o.Name, "🚚 Order delivered: %s (Value: %.2f)\n", o.Name, r.Value)
+	s.Events.Publish(events.Event{
+		Type:    events.OrderDelivered,
+		Time:    r.At,
+		OrderID: o.ID,
+		Name:    o.Name,
+		Temp:    string(o.Temp),
+		Shelf:   string(r.Shelf),
+		Value:   r.Value,
+	})
+	s.metrics.deliver(r)
+}
+
+// handOff delivers an order in transit to the customer at the given time and
+// returns its value then. The order keeps decaying off-shelf between pickup
+// and handoff, and one with no value left by then expires instead.
+func (s *Simulator) handOff(o *order.Order, pickupValue float64, at time.Time) float64 {
+	s.transit.remove(o)
+	if o.IsExpired(at) {
+		o.Transition(order.StateExpired, at)
+		s.orderLogf(o.ID, o.Name, "🗑️ Order expired in transit: %s\n", o.Name)
+		s.Events.Publish(events.Event{Type: events.OrdersExpired, Time: at, Count: 1})
+		return 0
+	}
+
+	value := o.CalculateValue(at)
+	o.Transition(order.StateDelivered, at)
+	s.handoffs.record(o, pickupValue, value)
+	if !o.PromisedAt.IsZero() {
+		s.promises.record(at.Sub(o.PromisedAt))
+	}
+	s.Events.Publish(events.Event{
+		Type:    events.OrderHandedOff,
+		Time:    at,
+		OrderID: o.ID,
+		Name:    o.Name,
+		Temp:    string(o.Temp),
+		Value:   value,
+	})
+	return value
+}
+
+// cancelInTransit cancels the orders still on their way to a customer when
+// the run ends
+func (s *Simulator) cancelInTransit(at time.Time) {
+	for _, o := range s.transit.drain() {
+		o.Transition(order.StateCancelled, at)
+		s.orderLogf(o.ID, o.Name, "🚫 Order cancelled in transit: %s\n", o.Name)
+	}
+}
+
+// printHandoffStats prints how much value orders lost between pickup and
+// handoff
+func (s *Simulator) printHandoffStats() {
+	count, pickup, handoff := s.handoffs.averages()
+	if count == 0 {
+		return
+	}
+	fmt.Printf("  Avg value at pickup: %.2f, at handoff: %.2f (%d handed off)\n", pickup, handoff, count)
+}
@@ -0,0 +1,224 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestHandOff_DecaysOffShelf(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	o := order.NewOrder("Burger", order.Hot, 100, 1)
+	s.ShelfManager.PlaceOrder(o)
+	pickedUp := time.Now()
+	pickupValue := o.CalculateValue(pickedUp)
+	if !s.ShelfManager.DeliverOrder(o.ID) {
+		t.Fatalf("Expected the order to be delivered")
+	}
+
+	s.handOff(o, pickupValue, pickedUp.Add(20*time.Second))
+
+	count, pickup, handoff := s.handoffs.averages()
+	if count != 1 {
+		t.Fatalf("Expected 1 handoff, got %d", count)
+	}
+	if pickup != pickupValue {
+		t.Errorf("Expected pickup value %.3f, got %.3f", pickupValue, pickup)
+	}
+	if handoff >= pickup {
+		t.Errorf("Expected the order to keep decaying until handoff, got %.3f at pickup and %.3f at handoff", pickup, handoff)
+	}
+}
+
+func TestHandOff_ExpiresInTransit(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	o := order.NewOrder("Burger", order.Hot, 10, 1)
+	s.ShelfManager.PlaceOrder(o)
+	result, ok := s.pickUp(o.ID)
+	if !ok {
+		t.Fatalf("Expected the order to be picked up")
+	}
+
+	if value := s.handOff(o, result.Value, result.At.Add(time.Minute)); value != 0 {
+		t.Errorf("Expected a spoiled order worth nothing, got %.3f", value)
+	}
+	if o.State() != order.StateExpired {
+		t.Errorf("Expected the order expired in transit, got %s", o.State())
+	}
+	if count, _, _ := s.handoffs.averages(); count != 0 {
+		t.Errorf("Expected no handoff recorded, got %d", count)
+	}
+}
+
+func TestCancelInTransit(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	carried := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	delivered := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	for _, o := range []*order.Order{carried, delivered} {
+		s.ShelfManager.PlaceOrder(o)
+		if _, ok := s.pickUp(o.ID); !ok {
+			t.Fatalf("Expected the order to be picked up")
+		}
+	}
+	s.handOff(delivered, 1, time.Now())
+
+	s.cancelInTransit(time.Now())
+	if carried.State() != order.StateCancelled {
+		t.Errorf("Expected the order still in transit cancelled, got %s", carried.State())
+	}
+	if delivered.State() != order.StateDelivered {
+		t.Errorf("Expected the handed off order left delivered, got %s", delivered.State())
+	}
+}
+
+func TestRunCourier_WaitsForHandoff(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Couriers = config.CourierConfig{Count: 1, Handoff: 0.2}
+	s.Couriers = courier.NewFleet([]*courier.Courier{{ID: 1}}, courier.NearestIdle{})
+
+	s.ShelfManager.PlaceOrder(order.NewOrder("Burger", order.Hot, 300, 0.5))
+
+	start := time.Now()
+	s.attemptDeliveries()
+	s.wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected the courier to stay busy through the handoff, freed after %v", elapsed)
+	}
+	if count, _, _ := s.handoffs.averages(); count != 1 {
+		t.Errorf("Expected 1 handoff, got %d", count)
+	}
+}
+
+func TestRunCourier_InTransitUntilHandoff(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Couriers = config.CourierConfig{Count: 1, Handoff: 0.2}
+	s.Couriers = courier.NewFleet([]*courier.Courier{{ID: 1}}, courier.NearestIdle{})
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	s.ShelfManager.PlaceOrder(o)
+	s.attemptDeliveries()
+	s.wg.Wait()
+
+	history := o.History()
+	if len(history) != 3 || history[1].To != order.StateInTransit || history[2].To != order.StateDelivered {
+		t.Fatalf("Expected the order shelved, in transit, then delivered, got %+v", history)
+	}
+	pickedUp := history[1].At
+	if carried := o.DeliveredAt().Sub(pickedUp); carried < 200*time.Millisecond {
+		t.Errorf("Expected the order delivered at handoff, %v after pickup", carried)
+	}
+	if _, _, handoff := s.handoffs.averages(); handoff != o.CalculateValue(o.DeliveredAt()) {
+		t.Errorf("Expected the handoff value recorded at delivery, got %.3f", handoff)
+	}
+}
+
+func TestDispatchCouriers_ReservesOrder(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Couriers = config.CourierConfig{Count: 1, ReserveGrace: 1}
+	s.Couriers = courier.NewFleet([]*courier.Courier{{ID: 1, X: 3, Y: 4}}, courier.NearestIdle{})
+	s.quiet.Store(true)
+
+	// The courier is 5s away from an order that expires in 1s
+	o := order.NewOrder("Burger", order.Hot, 1, 0.5)
+	s.ShelfManager.PlaceOrder(o)
+	s.attemptDeliveries()
+
+	if expired := s.ShelfManager.RemoveExpiredOrders(); expired != 0 {
+		t.Errorf("Expected the reserved order kept, %d expired", expired)
+	}
+	if expired := s.ShelfManager.RemoveDueOrders(time.Now().Add(3 * time.Second)); expired != 0 {
+		t.Errorf("Expected the reserved order kept until its courier arrives, %d expired", expired)
+	}
+	if expired := s.ShelfManager.RemoveDueOrders(time.Now().Add(7 * time.Second)); expired != 1 {
+		t.Errorf("Expected the order expired after the reservation grace, %d expired", expired)
+	}
+
+	s.halt()
+	s.wg.Wait()
+}
+
+func TestStartTransit_DecayModifier(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	normal := order.NewOrder("Burger", order.Hot, 100, 1)
+	fast := order.NewOrder("Burger", order.Hot, 100, 1)
+	s.ShelfManager.PlaceOrder(normal)
+	s.ShelfManager.PlaceOrder(fast)
+	fast.PlacedOnShelfAt = normal.PlacedOnShelfAt
+
+	pickedUp := normal.PlacedOnShelfAt.Add(10 * time.Second)
+	s.startTransit(normal, pickedUp)
+	s.Config.Couriers.TransitDecay = 3
+	s.startTransit(fast, pickedUp)
+
+	handoff := pickedUp.Add(10 * time.Second)
+	lossNormal := normal.CalculateValue(pickedUp) - normal.CalculateValue(handoff)
+	lossFast := fast.CalculateValue(pickedUp) - fast.CalculateValue(handoff)
+	if lossNormal <= 0 {
+		t.Fatalf("Expected orders to keep decaying in transit, lost %.3f", lossNormal)
+	}
+	if ratio := lossFast / lossNormal; ratio < 2.9 || ratio > 3.1 {
+		t.Errorf("Expected 3x decay in transit, got %.2fx", ratio)
+	}
+
+	s.handOff(fast, fast.CalculateValue(pickedUp), handoff)
+	if got := s.handoffs.forTemp(order.Hot); got.count != 1 {
+		t.Errorf("Expected 1 hot handoff, got %d", got.count)
+	}
+	if got := s.handoffs.forName("Burger"); got.count != 1 {
+		t.Errorf("Expected 1 Burger handoff, got %d", got.count)
+	}
+}
+
+func TestConfigureMinValue(t *testing.T) {
+	manager := shelf.NewShelfManager(5, 5, 5, 10)
+	if err := configureMinValue(config.CourierConfig{MinValue: 0.2}, manager); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := configureMinValue(config.CourierConfig{MinValue: 1.5}, manager); err == nil {
+		t.Errorf("Expected a minimum value of 1.5 to be rejected")
+	}
+
+	// Without a minimum any manager will do
+	fixed := struct{ shelf.ShelfManager }{manager}
+	if err := configureMinValue(config.CourierConfig{}, fixed); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := configureMinValue(config.CourierConfig{MinValue: 0.2}, fixed); err == nil {
+		t.Errorf("Expected a minimum value to need a QualityGate")
+	}
+}
+
+func TestPickUp_RefusesLowValue(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.quiet.Store(true)
+	s.Events = events.NewBus()
+	sub, unsubscribe := s.Events.Subscribe()
+	defer unsubscribe()
+	if err := configureMinValue(config.CourierConfig{MinValue: 0.99}, s.ShelfManager); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	o := order.NewOrder("Burger", order.Hot, 1, 1)
+	s.ShelfManager.PlaceOrder(o)
+	time.Sleep(20 * time.Millisecond)
+
+	if _, ok := s.pickUp(o.ID); ok {
+		t.Fatalf("Expected the order refused below the minimum value")
+	}
+	if o.State() != order.StateWasted {
+		t.Errorf("Expected the order wasted, got %s", o.State())
+	}
+	if ev := <-sub; ev.Type != events.OrderWasted || ev.Reason != string(shelf.RejectLowValue) {
+		t.Errorf("Expected a low_value waste event, got %+v", ev)
+	}
+}
@@ -0,0 +1,64 @@
+package simulator
+
+import (
+	"fmt"
+	"strings"
+
+	"dish-dispatcher/internal/order"
+)
+
+// valueBuckets is the number of equal-width buckets values are counted in
+const valueBuckets = 10
+
+// histogramWidth is the length of the longest bar in a printed histogram
+const histogramWidth = 40
+
+// valueHistogram counts values in [0, 1] by tenths
+type valueHistogram [valueBuckets]int
+
+func (h *valueHistogram) add(value float64) {
+	// A perfect 1.0 belongs to the top bucket
+	bucket := max(0, min(int(value*valueBuckets), valueBuckets-1))
+	h[bucket]++
+}
+
+// lines draws the histogram as one bar per bucket, scaled to its largest.
+// Any non-empty bucket gets at least one mark.
+func (h *valueHistogram) lines() []string {
+	largest := 0
+	for _, n := range h {
+		largest = max(largest, n)
+	}
+
+	lines := make([]string, 0, valueBuckets)
+	for i, n := range h {
+		bar := 0
+		if largest > 0 {
+			bar = (n*histogramWidth + largest - 1) / largest
+		}
+		lines = append(lines, fmt.Sprintf("%.1f-%.1f |%-*s %d",
+			float64(i)/valueBuckets, float64(i+1)/valueBuckets,
+			histogramWidth, strings.Repeat("#", bar), n))
+	}
+	return lines
+}
+
+// printValueHistograms prints the value of delivered orders at handoff by
+// temperature
+func (s *Simulator) printValueHistograms() {
+	printed := false
+	for _, temp := range []order.Temperature{order.Hot, order.Cold, order.Frozen} {
+		totals := s.handoffs.forTemp(temp)
+		if totals.count == 0 {
+			continue
+		}
+		if !printed {
+			fmt.Println("\n📊 VALUE AT DELIVERY:")
+			printed = true
+		}
+		fmt.Printf("  %s (%d delivered):\n", temp, totals.count)
+		for _, line := range totals.values.lines() {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"dish-dispatcher/internal/order"
+)
+
+func TestValueHistogram(t *testing.T) {
+	var h valueHistogram
+	for _, v := range []float64{0, 0.05, 0.55, 0.95, 0.99, 1, 1.2, -0.1} {
+		h.add(v)
+	}
+
+	want := valueHistogram{3, 0, 0, 0, 0, 1, 0, 0, 0, 4}
+	if h != want {
+		t.Fatalf("Expected buckets %v, got %v", want, h)
+	}
+
+	lines := h.lines()
+	if len(lines) != valueBuckets {
+		t.Fatalf("Expected %d lines, got %d", valueBuckets, len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "0.0-0.1 |"+strings.Repeat("#", 30)+" ") || !strings.HasSuffix(lines[0], " 3") {
+		t.Errorf("Unexpected bottom bucket %q", lines[0])
+	}
+	if !strings.Contains(lines[9], strings.Repeat("#", histogramWidth)) || !strings.HasPrefix(lines[9], "0.9-1.0") {
+		t.Errorf("Expected the largest bucket at full width, got %q", lines[9])
+	}
+	if strings.Contains(lines[1], "#") {
+		t.Errorf("Expected an empty bucket to have no bar, got %q", lines[1])
+	}
+}
+
+func TestHandoffStats_Histogram(t *testing.T) {
+	var h handoffStats
+	h.record(&order.Order{Name: "Burger", Temp: order.Hot}, 0.9, 0.85)
+	h.record(&order.Order{Name: "Soup", Temp: order.Hot}, 0.5, 0.42)
+	h.record(&order.Order{Name: "Salad", Temp: order.Cold}, 0.3, 0.25)
+
+	if got := h.forTemp(order.Hot).values; got[8] != 1 || got[4] != 1 {
+		t.Errorf("Expected hot handoffs in the 0.8 and 0.4 buckets, got %v", got)
+	}
+	if got := h.forTemp(order.Cold).values; got[2] != 1 {
+		t.Errorf("Expected the cold handoff in the 0.2 bucket, got %v", got)
+	}
+}
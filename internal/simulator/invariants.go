@@ -0,0 +1,96 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// defaultInvariantInterval is used when the configured interval is not positive
+const defaultInvariantInterval = 5 * time.Second
+
+// validateInvariantConfig rejects unknown invariant check modes. An empty
+// mode is off.
+func validateInvariantConfig(cfg config.InvariantConfig) error {
+	switch cfg.Mode {
+	case "", config.InvariantCheckOff, config.InvariantCheckLog, config.InvariantCheckFail:
+		return nil
+	}
+	return fmt.Errorf("unknown invariants.mode %q, want %q, %q or %q",
+		cfg.Mode, config.InvariantCheckOff, config.InvariantCheckLog, config.InvariantCheckFail)
+}
+
+// checkInvariants periodically reconciles the manager's counters with its
+// shelves. A check can race orders in flight, so drift is only reported once
+// it shows up on two consecutive checks.
+func (s *Simulator) checkInvariants() {
+	defer s.wg.Done()
+
+	interval := time.Duration(s.Config.Invariants.Interval) * time.Second
+	if interval <= 0 {
+		interval = defaultInvariantInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	suspect := false
+	for {
+		select {
+		case <-ticker.C:
+			_, err := shelf.CheckInvariants(s.ShelfManager)
+			if err == nil {
+				suspect = false
+				continue
+			}
+			if !suspect {
+				suspect = true
+				continue
+			}
+			if s.invariantViolated(err) {
+				return
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// finalInvariantCheck reconciles the counters once every goroutine has
+// stopped, when no order can be in flight and any drift is real
+func (s *Simulator) finalInvariantCheck() {
+	if _, err := shelf.CheckInvariants(s.ShelfManager); err != nil {
+		s.invariantViolated(err)
+	}
+}
+
+// invariantViolated reports a violation and, in fail mode, records it and
+// stops the simulation. It returns true if the simulation was stopped.
+func (s *Simulator) invariantViolated(err error) bool {
+	fmt.Printf("⚠️ %v\n", err)
+	if s.Config.Invariants.Mode != config.InvariantCheckFail {
+		return false
+	}
+
+	s.setErr(err)
+	s.halt()
+	return true
+}
+
+// Err returns the error that stopped the simulation, if any
+func (s *Simulator) Err() error {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+
+	return s.err
+}
+
+func (s *Simulator) setErr(err error) {
+	s.errMutex.Lock()
+	defer s.errMutex.Unlock()
+
+	if s.err == nil {
+		s.err = err
+	}
+}
@@ -0,0 +1,75 @@
+package simulator
+
+import (
+	"context"
+	"testing"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestValidateInvariantConfig(t *testing.T) {
+	for _, mode := range []string{"", config.InvariantCheckOff, config.InvariantCheckLog, config.InvariantCheckFail} {
+		if err := validateInvariantConfig(config.InvariantConfig{Mode: mode}); err != nil {
+			t.Errorf("Unexpected error for mode %q: %v", mode, err)
+		}
+	}
+	for _, mode := range []string{"panic", "Fail", "on"} {
+		if err := validateInvariantConfig(config.InvariantConfig{Mode: mode}); err == nil {
+			t.Errorf("Expected an error for mode %q", mode)
+		}
+	}
+}
+
+// driftedSimulator returns a simulator whose manager lost an order outside
+// DeliverOrder, so its counters no longer reconcile
+func driftedSimulator(t *testing.T, mode string) *Simulator {
+	s := setupTestSimulator(t)
+	s.Config.Invariants.Mode = mode
+
+	manager := s.ShelfManager.(*shelf.InMemoryShelfManager)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	manager.PlaceOrder(o)
+	manager.GetShelf(shelf.HotShelf).RemoveOrder(o.ID)
+	return s
+}
+
+func TestFinalInvariantCheck_Log(t *testing.T) {
+	s := driftedSimulator(t, config.InvariantCheckLog)
+	s.finalInvariantCheck()
+
+	if s.Err() != nil {
+		t.Errorf("Expected log mode to keep running, got %v", s.Err())
+	}
+	select {
+	case <-s.stop:
+		t.Errorf("Expected log mode not to stop the simulation")
+	default:
+	}
+}
+
+func TestFinalInvariantCheck_Fail(t *testing.T) {
+	s := driftedSimulator(t, config.InvariantCheckFail)
+	s.finalInvariantCheck()
+
+	if s.Err() == nil {
+		t.Errorf("Expected fail mode to record the drift")
+	}
+	select {
+	case <-s.stop:
+	default:
+		t.Errorf("Expected fail mode to stop the simulation")
+	}
+}
+
+func TestFinalInvariantCheck_Clean(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Invariants.Mode = config.InvariantCheckFail
+	s.placeOrders(context.Background(), 1)
+	s.finalInvariantCheck()
+
+	if s.Err() != nil {
+		t.Errorf("Expected no drift, got %v", s.Err())
+	}
+}
@@ -0,0 +1,32 @@
+package simulator
+
+import "fmt"
+
+// SetVerbose turns the per-order log lines on or off. They are on unless
+// turned off; summaries, warnings and reports are always printed.
+func (s *Simulator) SetVerbose(verbose bool) {
+	s.quiet.Store(!verbose)
+}
+
+// ToggleVerbose flips the per-order log lines and returns whether they are
+// now on
+func (s *Simulator) ToggleVerbose() bool {
+	for {
+		quiet := s.quiet.Load()
+		if s.quiet.CompareAndSwap(quiet, !quiet) {
+			return quiet
+		}
+	}
+}
+
+// Verbose reports whether per-order log lines are printed
+func (s *Simulator) Verbose() bool {
+	return !s.quiet.Load()
+}
+
+// logf prints a per-order log line unless they are turned off
+func (s *Simulator) logf(format string, args ...any) {
+	if !s.quiet.Load() {
+		fmt.Printf(format, args...)
+	}
+}
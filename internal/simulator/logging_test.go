@@ -0,0 +1,22 @@
+package simulator
+
+import "testing"
+
+func TestSimulator_Verbose(t *testing.T) {
+	s := setupTestSimulator(t)
+	if !s.Verbose() {
+		t.Fatalf("Expected per-order logging on by default")
+	}
+
+	if s.ToggleVerbose() || s.Verbose() {
+		t.Errorf("Expected the toggle to turn logging off")
+	}
+	if !s.ToggleVerbose() || !s.Verbose() {
+		t.Errorf("Expected the toggle to turn logging back on")
+	}
+
+	s.SetVerbose(false)
+	if s.Verbose() {
+		t.Errorf("Expected logging off")
+	}
+}
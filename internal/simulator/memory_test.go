@@ -0,0 +1,60 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+)
+
+func TestCreateOrder_PoolsWastedOrders(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.pool = order.NewPool()
+
+	// The wasted order goes straight back to the pool; nothing else may hold
+	// it, so the shelves stay empty and the stats still count it
+	s.createOrder(OrderData{Name: "Bread", Temp: "ambient", ShelfLife: 300, DecayRate: 0.5})
+	if len(s.ShelfManager.GetAllOrders()) != 0 {
+		t.Fatalf("Expected the ambient order to be wasted")
+	}
+	if got := s.currentTotals().received; got != 1 {
+		t.Errorf("Expected the wasted order to be counted, got %d", got)
+	}
+}
+
+func TestNewSimulator_DiscardCompleted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	if err := os.WriteFile(path, []byte(`[{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Memory.DiscardCompleted = true
+	cfg.Memory.PoolOrders = true
+	s, err := NewSimulator(cfg, path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s.Archive != nil {
+		t.Errorf("Expected no archive when discarding completed orders")
+	}
+	if s.pool == nil {
+		t.Errorf("Expected an order pool")
+	}
+
+	o, err := s.placeOrder(preloadedOrders(s.Source)[0])
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := s.deliverTimed(o.ID); !ok {
+		t.Fatalf("Expected the order to be delivered")
+	}
+	if len(o.History()) != 0 {
+		t.Errorf("Expected no timeline, got %v", o.History())
+	}
+	if got := s.ShelfManager.StatsByName()["Soup"].Delivered; got != 1 {
+		t.Errorf("Expected the delivery in the aggregates, got %d", got)
+	}
+}
@@ -0,0 +1,184 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// maxCourierLabels caps the couriers labelled by ID; the rest share "other"
+const maxCourierLabels = 100
+
+// maxPriorityLabel is the highest priority labelled on its own; higher ones
+// share its label
+const maxPriorityLabel = 5
+
+// orderLabels are the dimensions finished orders are counted by in the
+// Prometheus metrics. Each takes few values, so the series stay bounded.
+type orderLabels struct {
+	outcome  string
+	shelf    string // "none" for orders never shelved
+	temp     string // "other" for temperatures no shelf holds
+	priority string
+	courier  string // "none" unless a courier delivered the order
+}
+
+// orderMetrics counts finished orders by their labels. ResetStats clears
+// them along with starting a new run, so the counters of each run ID only
+// grow, as Prometheus expects.
+type orderMetrics struct {
+	mutex     sync.Mutex
+	counts    map[orderLabels]int
+	temps     map[order.Temperature]bool // labelled by name
+	delivered deliveryTotals
+}
+
+// deliveryTotals sums the delivered orders' value at pickup and time on
+// the shelf, as recorded when they left it
+type deliveryTotals struct {
+	count   int
+	value   float64
+	onShelf time.Duration
+}
+
+func newOrderMetrics(states []shelf.ShelfState) orderMetrics {
+	temps := map[order.Temperature]bool{order.Hot: true, order.Cold: true, order.Frozen: true}
+	for _, st := range states {
+		for _, temp := range st.Temps {
+			temps[temp] = true
+		}
+	}
+	return orderMetrics{temps: temps}
+}
+
+// reset clears the counts and delivery totals
+func (m *orderMetrics) reset() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.counts = nil
+	m.delivered = deliveryTotals{}
+}
+
+func (m *orderMetrics) add(labels orderLabels) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.counts == nil {
+		m.counts = make(map[orderLabels]int)
+	}
+	m.counts[labels]++
+}
+
+// deliver adds a delivered order's value and time on the shelf
+func (m *orderMetrics) deliver(r shelf.DeliveryResult) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.delivered.count++
+	m.delivered.value += r.Value
+	m.delivered.onShelf += r.OnShelf
+}
+
+// labels returns the labels of an order finishing as outcome
+func (m *orderMetrics) labels(o *order.Order, outcome, courier string) orderLabels {
+	l := orderLabels{outcome: outcome, shelf: o.CurrentShelfType, temp: string(o.Temp), courier: courier}
+	if l.shelf == "" {
+		l.shelf = "none"
+	}
+	if !m.temps[o.Temp] {
+		l.temp = "other"
+	}
+	switch {
+	case o.Priority <= 0:
+		l.priority = "0"
+	case o.Priority >= maxPriorityLabel:
+		l.priority = strconv.Itoa(maxPriorityLabel) + "+"
+	default:
+		l.priority = strconv.Itoa(o.Priority)
+	}
+	return l
+}
+
+// observeMetrics is a TransitionHook counting finished orders
+func (s *Simulator) observeMetrics(o *order.Order, from, to order.State, at time.Time) {
+	switch to {
+	case order.StateDelivered:
+		s.metrics.add(s.metrics.labels(o, "delivered", s.courierLabel(o)))
+	case order.StateWasted:
+		s.metrics.add(s.metrics.labels(o, "wasted", "none"))
+	case order.StateExpired:
+		s.metrics.add(s.metrics.labels(o, "expired", "none"))
+	}
+}
+
+// courierLabel returns the label of the courier collecting an order
+func (s *Simulator) courierLabel(o *order.Order) string {
+	if s.Couriers != nil {
+		if id, ok := s.Couriers.AssignedTo(o); ok {
+			if id > maxCourierLabels {
+				return "other"
+			}
+			return strconv.Itoa(id)
+		}
+	}
+	if s.Agents != nil {
+		return "agent"
+	}
+	return "none"
+}
+
+// WritePrometheus writes the finished orders as a Prometheus counter in the
+// text exposition format, labelled by outcome, shelf, temperature, priority
+// and courier, followed by summaries of the delivered orders' value at
+// pickup and time on the shelf. Every series is labelled by the run's ID.
+func (s *Simulator) WritePrometheus(w io.Writer) error {
+	runID := s.RunContext().ID
+
+	s.metrics.mutex.Lock()
+	labels := make([]orderLabels, 0, len(s.metrics.counts))
+	for l := range s.metrics.counts {
+		labels = append(labels, l)
+	}
+	counts := make([]int, len(labels))
+	sort.Slice(labels, func(i, j int) bool { return labelKey(labels[i]) < labelKey(labels[j]) })
+	for i, l := range labels {
+		counts[i] = s.metrics.counts[l]
+	}
+	delivered := s.metrics.delivered
+	s.metrics.mutex.Unlock()
+
+	const metric = "dispatcher_orders_total"
+	if _, err := fmt.Fprintf(w, "# HELP %s Orders finished, by outcome, shelf, temperature, priority and courier.\n# TYPE %s counter\n", metric, metric); err != nil {
+		return err
+	}
+	for i, l := range labels {
+		if _, err := fmt.Fprintf(w, "%s{run_id=%q,outcome=%q,shelf=%q,temp=%q,priority=%q,courier=%q} %d\n",
+			metric, runID, l.outcome, l.shelf, l.temp, l.priority, l.courier, counts[i]); err != nil {
+			return err
+		}
+	}
+	if err := writeSummary(w, "dispatcher_delivered_value", "Value of delivered orders at pickup.", runID, delivered.value, delivered.count); err != nil {
+		return err
+	}
+	return writeSummary(w, "dispatcher_time_on_shelf_seconds", "Time delivered orders spent on the shelves.", runID, delivered.onShelf.Seconds(), delivered.count)
+}
+
+// writeSummary writes a Prometheus summary without quantiles, labelled by
+// the run's ID
+func writeSummary(w io.Writer, metric, help, runID string, sum float64, count int) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s summary\n%s_sum{run_id=%q} %g\n%s_count{run_id=%q} %d\n",
+		metric, help, metric, metric, runID, sum, metric, runID, count)
+	return err
+}
+
+// labelKey orders series so the output is stable
+func labelKey(l orderLabels) string {
+	return l.outcome + "\x00" + l.shelf + "\x00" + l.temp + "\x00" + l.priority + "\x00" + l.courier
+}
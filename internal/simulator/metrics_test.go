@@ -0,0 +1,94 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestWritePrometheus_Labels(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.metrics = newOrderMetrics(s.ShelfManager.ShelfStates())
+	s.run = config.RunContext{ID: "lunch-1"}
+	s.Couriers = courier.NewFleet([]*courier.Courier{{ID: 7}}, courier.NearestIdle{})
+	now := time.Now()
+
+	delivered := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	delivered.CurrentShelfType = string(shelf.HotShelf)
+	delivered.Priority = 2
+	s.Couriers.Assign(delivered)
+	s.observeMetrics(delivered, order.StateInTransit, order.StateDelivered, now)
+
+	urgent := order.NewOrder("Soup", order.Hot, 300, 0.5)
+	urgent.CurrentShelfType = string(shelf.OverflowShelf)
+	urgent.Priority = 12
+	s.observeMetrics(urgent, order.StateShelved, order.StateExpired, now)
+	s.observeMetrics(urgent, order.StateShelved, order.StateExpired, now)
+
+	// Temperatures no shelf holds share a label, however many arrive
+	for _, temp := range []order.Temperature{"tepid", "ambient"} {
+		s.observeMetrics(order.NewOrder("Bread", temp, 300, 0.5), order.StateCreated, order.StateWasted, now)
+	}
+
+	var out strings.Builder
+	if err := s.WritePrometheus(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE dispatcher_orders_total counter\n",
+		`dispatcher_orders_total{run_id="lunch-1",outcome="delivered",shelf="hot",temp="hot",priority="2",courier="7"} 1`,
+		`dispatcher_orders_total{run_id="lunch-1",outcome="expired",shelf="overflow",temp="hot",priority="5+",courier="none"} 2`,
+		`dispatcher_orders_total{run_id="lunch-1",outcome="wasted",shelf="none",temp="other",priority="0",courier="none"} 2`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, out.String())
+		}
+	}
+	if n := strings.Count(out.String(), "dispatcher_orders_total{"); n != 3 {
+		t.Errorf("Expected 3 series, got %d", n)
+	}
+}
+
+func TestRecordDelivery_UsesResult(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.quiet.Store(true)
+	s.metrics = newOrderMetrics(s.ShelfManager.ShelfStates())
+	s.Events = events.NewBus()
+	sub, unsubscribe := s.Events.Subscribe()
+	defer unsubscribe()
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	at := time.Now().Add(-time.Minute)
+	o.PlacedOnShelfAt = at.Add(-30 * time.Second)
+	s.recordDelivery(shelf.NewDeliveryResult(o, 0.8, shelf.HotShelf, at))
+	s.recordDelivery(shelf.NewDeliveryResult(o, 0.4, shelf.OverflowShelf, at))
+
+	// The event carries the numbers read as the order left the shelf,
+	// however much later it is published
+	ev := <-sub
+	if ev.Type != events.OrderDelivered || !ev.Time.Equal(at) || ev.Value != 0.8 || ev.Shelf != string(shelf.HotShelf) {
+		t.Errorf("Expected the delivered event from the result, got %+v", ev)
+	}
+
+	var out strings.Builder
+	if err := s.WritePrometheus(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"# TYPE dispatcher_delivered_value summary\n",
+		`dispatcher_delivered_value_sum{run_id=""} 1.2`,
+		`dispatcher_delivered_value_count{run_id=""} 2` + "\n",
+		`dispatcher_time_on_shelf_seconds_sum{run_id=""} 60` + "\n",
+		`dispatcher_time_on_shelf_seconds_count{run_id=""} 2` + "\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/mqtt"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+// validateMQTTConfig checks the topics events will be published to
+func validateMQTTConfig(cfg config.MQTTConfig, states []shelf.ShelfState) error {
+	if cfg.Addr == "" {
+		return nil
+	}
+	if cfg.TopicPrefix == "" {
+		return errors.New("mqtt.topicPrefix must not be empty")
+	}
+	if err := validateTopic(cfg.TopicPrefix); err != nil {
+		return fmt.Errorf("mqtt.topicPrefix: %w", err)
+	}
+
+	known := make(map[string]bool, len(states))
+	for _, st := range states {
+		known[string(st.Type)] = true
+	}
+	for name, prefix := range cfg.ShelfTopics {
+		if !known[name] {
+			return fmt.Errorf("mqtt.shelfTopics: unknown shelf %q", name)
+		}
+		if err := validateTopic(prefix); err != nil {
+			return fmt.Errorf("mqtt.shelfTopics of shelf %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// validateTopic rejects the wildcards, which only subscriptions may use
+func validateTopic(topic string) error {
+	if strings.ContainsAny(topic, "+#") {
+		return fmt.Errorf("topic %q contains a wildcard", topic)
+	}
+	return nil
+}
+
+// mqttTopic returns the topic an event is published to
+func mqttTopic(cfg config.MQTTConfig, e plugin.Event) string {
+	prefix := cfg.TopicPrefix
+	if e.Shelf != "" {
+		if shelfPrefix, ok := cfg.ShelfTopics[e.Shelf]; ok {
+			prefix = shelfPrefix
+		} else {
+			prefix += "/shelves/" + e.Shelf
+		}
+	}
+	return prefix + "/" + e.Type
+}
+
+// mqttSink publishes every event to an MQTT broker as JSON
+type mqttSink struct {
+	cfg    config.MQTTConfig
+	client *mqtt.Client
+	encode eventEncoder
+	failed bool // a publish failed, which is reported only once
+}
+
+// newMQTTSink connects to the configured broker
+func newMQTTSink(cfg config.MQTTConfig, encode eventEncoder) (*mqttSink, error) {
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = fmt.Sprintf("dish-dispatcher-%d", os.Getpid())
+	}
+	client, err := mqtt.Dial(mqtt.Options{
+		Addr:     cfg.Addr,
+		ClientID: clientID,
+		Username: cfg.Username,
+		Password: cfg.Password,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &mqttSink{cfg: cfg, client: client, encode: encode}, nil
+}
+
+func (m *mqttSink) Handle(e plugin.Event) {
+	payload, err := m.encode(e)
+	if err == nil {
+		err = m.client.Publish(mqttTopic(m.cfg, e), payload, false)
+	}
+	if err != nil && !m.failed {
+		m.failed = true
+		fmt.Printf("⚠️ MQTT publishing failed, later events will be dropped: %v\n", err)
+	}
+}
+
+func (m *mqttSink) Close() error {
+	return m.client.Close()
+}
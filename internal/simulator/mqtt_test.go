@@ -0,0 +1,55 @@
+package simulator
+
+import (
+	"testing"
+
+	"dish-dispatcher/internal/config"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+func TestMQTTTopic(t *testing.T) {
+	cfg := config.MQTTConfig{
+		TopicPrefix: "kitchen",
+		ShelfTopics: map[string]string{"hot": "displays/grill"},
+	}
+
+	tests := []struct {
+		event plugin.Event
+		want  string
+	}{
+		{plugin.Event{Type: "orders_expired"}, "kitchen/orders_expired"},
+		{plugin.Event{Type: "order_placed", Shelf: "cold"}, "kitchen/shelves/cold/order_placed"},
+		{plugin.Event{Type: "order_placed", Shelf: "hot"}, "displays/grill/order_placed"},
+	}
+	for _, tt := range tests {
+		if got := mqttTopic(cfg, tt.event); got != tt.want {
+			t.Errorf("mqttTopic(%+v) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestValidateMQTTConfig(t *testing.T) {
+	states := shelf.NewShelfManager(1, 1, 1, 1).ShelfStates()
+
+	valid := config.MQTTConfig{Addr: "localhost:1883", TopicPrefix: "kitchen", ShelfTopics: map[string]string{"hot": "grill"}}
+	if err := validateMQTTConfig(valid, states); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	invalid := []config.MQTTConfig{
+		{Addr: "localhost:1883"},
+		{Addr: "localhost:1883", TopicPrefix: "kitchen/#"},
+		{Addr: "localhost:1883", TopicPrefix: "kitchen", ShelfTopics: map[string]string{"warm": "grill"}},
+		{Addr: "localhost:1883", TopicPrefix: "kitchen", ShelfTopics: map[string]string{"hot": "grill/+"}},
+	}
+	for _, cfg := range invalid {
+		if err := validateMQTTConfig(cfg, states); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+
+	if err := validateMQTTConfig(config.MQTTConfig{}, states); err != nil {
+		t.Errorf("Expected no checks with MQTT disabled, got %v", err)
+	}
+}
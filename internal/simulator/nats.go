@@ -0,0 +1,141 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/nats"
+	"dish-dispatcher/plugin"
+)
+
+// validateNATSConfig checks orders can be taken from the configured
+// subjects
+func validateNATSConfig(cfg config.NATSConfig, service config.ServiceConfig) error {
+	if cfg.Addr == "" {
+		return nil
+	}
+	if len(cfg.Subjects) == 0 && cfg.CompletionSubject == "" {
+		return errors.New("nats.addr needs nats.subjects or nats.completionSubject")
+	}
+	if len(cfg.Subjects) > 0 && !service.Enabled {
+		return errors.New("nats.subjects needs service mode, where orders arrive from outside the run")
+	}
+	for _, subject := range append(cfg.Subjects, cfg.CompletionSubject) {
+		if strings.ContainsAny(subject, " \t\r\n") {
+			return fmt.Errorf("nats: invalid subject %q", subject)
+		}
+	}
+	return nil
+}
+
+// natsBridge takes orders from NATS and, as an event sink, publishes each
+// order's completion back
+type natsBridge struct {
+	cfg    config.NATSConfig
+	client *nats.Client
+	encode eventEncoder
+	failed bool // a publish failed, which is reported only once
+}
+
+// natsOrderReply answers an order sent as a request
+type natsOrderReply struct {
+	ID     string `json:"id,omitempty"`
+	Shelf  string `json:"shelf,omitempty"`
+	Wasted bool   `json:"wasted,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newNATSBridge connects to the configured server
+func newNATSBridge(cfg config.NATSConfig, encode eventEncoder) (*natsBridge, error) {
+	client, err := nats.Dial(nats.Options{
+		Addr:     cfg.Addr,
+		Name:     "dish-dispatcher",
+		User:     cfg.User,
+		Password: cfg.Password,
+		Token:    cfg.Token,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &natsBridge{cfg: cfg, client: client, encode: encode}, nil
+}
+
+// consume subscribes to the order subjects, submitting every order to s
+func (b *natsBridge) consume(s *Simulator) error {
+	for _, subject := range b.cfg.Subjects {
+		err := b.client.Subscribe(subject, b.cfg.Queue, func(msg nats.Msg) {
+			b.submit(s, msg)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// submit places the order in msg and replies with the outcome if the
+// message was a request
+func (b *natsBridge) submit(s *Simulator, msg nats.Msg) {
+	var reply natsOrderReply
+	var d OrderData
+	if err := json.Unmarshal(msg.Data, &d); err != nil {
+		reply.Error = fmt.Sprintf("invalid order: %v", err)
+	} else {
+		o, err := s.Submit(d)
+		if o != nil {
+			reply.ID, reply.Shelf, reply.Wasted = o.ID, o.CurrentShelfType, err != nil
+		}
+		if err != nil {
+			reply.Error = err.Error()
+		}
+	}
+
+	if msg.Reply == "" {
+		if reply.Error != "" && !reply.Wasted {
+			s.logf("⚠️ Order from NATS subject %s rejected: %s\n", msg.Subject, reply.Error)
+		}
+		return
+	}
+	payload, err := json.Marshal(reply)
+	if err == nil {
+		err = b.client.Publish(msg.Reply, payload)
+	}
+	if err != nil {
+		b.reportFailure(err)
+	}
+}
+
+func (b *natsBridge) Handle(e plugin.Event) {
+	if b.cfg.CompletionSubject == "" {
+		return
+	}
+	switch events.Type(e.Type) {
+	case events.OrderHandedOff, events.OrderWasted, events.OrdersExpired:
+	default:
+		return
+	}
+
+	payload, err := b.encode(e)
+	if err == nil {
+		err = b.client.Publish(b.cfg.CompletionSubject+"."+e.Type, payload)
+	}
+	if err != nil {
+		b.reportFailure(err)
+	}
+}
+
+// reportFailure warns of the first failed publish
+func (b *natsBridge) reportFailure(err error) {
+	if !b.failed {
+		b.failed = true
+		fmt.Printf("⚠️ NATS publishing failed, later messages will be dropped: %v\n", err)
+	}
+}
+
+func (b *natsBridge) Close() error {
+	return b.client.Close()
+}
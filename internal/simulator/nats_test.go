@@ -0,0 +1,39 @@
+package simulator
+
+import (
+	"testing"
+
+	"dish-dispatcher/internal/config"
+)
+
+func TestValidateNATSConfig(t *testing.T) {
+	service := config.ServiceConfig{Enabled: true}
+
+	valid := []struct {
+		cfg     config.NATSConfig
+		service config.ServiceConfig
+	}{
+		{config.NATSConfig{}, config.ServiceConfig{}},
+		{config.NATSConfig{Addr: "localhost:4222", Subjects: []string{"orders.>"}, Queue: "dispatchers"}, service},
+		{config.NATSConfig{Addr: "localhost:4222", CompletionSubject: "orders.done"}, config.ServiceConfig{}},
+	}
+	for _, tt := range valid {
+		if err := validateNATSConfig(tt.cfg, tt.service); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", tt.cfg, err)
+		}
+	}
+
+	invalid := []struct {
+		cfg     config.NATSConfig
+		service config.ServiceConfig
+	}{
+		{config.NATSConfig{Addr: "localhost:4222"}, service},
+		{config.NATSConfig{Addr: "localhost:4222", Subjects: []string{"orders"}}, config.ServiceConfig{}},
+		{config.NATSConfig{Addr: "localhost:4222", Subjects: []string{"new orders"}}, service},
+	}
+	for _, tt := range invalid {
+		if err := validateNATSConfig(tt.cfg, tt.service); err == nil {
+			t.Errorf("Expected %+v to be rejected", tt.cfg)
+		}
+	}
+}
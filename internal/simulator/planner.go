@@ -0,0 +1,371 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+)
+
+// randomPickupDelays are the equally likely pickup delays, in seconds, of
+// orders collected without a courier fleet
+var randomPickupDelays = []float64{2, 3, 4, 5, 6}
+
+// maxPlannedCapacity bounds the capacity search for loads no shelf can hold
+const maxPlannedCapacity = 100000
+
+// ShelfPlan is the planner's estimate for one shelf, or for every overflow
+// shelf pooled together
+type ShelfPlan struct {
+	Shelf       string
+	Overflow    bool
+	Rate        float64 // orders per second offered to the shelf
+	MeanStay    float64 // seconds an order spends on the shelf
+	Current     int     // configured capacity
+	Blocked     float64 // percentage of the shelf's orders turned away at Current
+	Recommended int
+}
+
+// CapacityPlan estimates the shelf capacities keeping the orders wasted for
+// want of room at or below a target rate. Shelves are treated as Erlang loss
+// systems, M/G/c/c, offered the orders routed to them for as long as each
+// waits for its pickup or expires, whichever comes first. They assume
+// orders of each temperature arrive at random, so the estimates err on the
+// safe side for steadier arrivals.
+type CapacityPlan struct {
+	Target      float64 // percent of orders wasted for want of room
+	Rate        float64 // orders per second planned for
+	PickupDelay float64 // mean seconds from shelving to pickup
+	Utilization float64 // fraction of the time pickups are under way
+
+	// Expiring is the percentage of orders expected to expire before pickup
+	// however large the shelves are
+	Expiring float64
+
+	// Wasted is the percentage of orders expected to find no room on any
+	// shelf at the current capacities
+	Wasted float64
+
+	Shelves []ShelfPlan
+	Notes   []string
+}
+
+// PlanCapacity estimates the shelf capacities for the orders, placed at the
+// configured rate and collected as the courier settings describe, to waste
+// at most target percent of them for want of room. The estimate plans for
+// the peak rate of any demand curve.
+func PlanCapacity(cfg *config.Config, orders []OrderData, target float64) (CapacityPlan, error) {
+	if target <= 0 || target >= 100 {
+		return CapacityPlan{}, fmt.Errorf("target waste rate must be a percentage between 0 and 100, got %v", target)
+	}
+	if len(orders) == 0 {
+		return CapacityPlan{}, errors.New("no orders to plan for")
+	}
+	if cfg.Couriers.AgentAddr != "" {
+		return CapacityPlan{}, errors.New("pickups by external courier agents cannot be planned for")
+	}
+	formula, err := decayFormulaFromConfig(cfg)
+	if err != nil {
+		return CapacityPlan{}, err
+	}
+
+	plan := CapacityPlan{Target: target}
+	rates := plan.tempRates(cfg, orders)
+	for _, rate := range rates {
+		plan.Rate += rate
+	}
+	if plan.Rate <= 0 {
+		return CapacityPlan{}, errors.New("no orders arrive at the configured rate")
+	}
+
+	delays, err := plan.pickupDelays(cfg.Couriers)
+	if err != nil {
+		return CapacityPlan{}, err
+	}
+
+	// Each temperature's orders stay until picked up or expired
+	stays := make(map[string]float64)
+	byTemp := make(map[string][]OrderData)
+	for _, d := range orders {
+		byTemp[d.Temp] = append(byTemp[d.Temp], d)
+	}
+	menu := cfg.Orders.Mode == config.OrdersModeMenu
+	for temp, rate := range rates {
+		items := byTemp[temp]
+		if len(items) == 0 {
+			// A rate given for a temperature the file lacks applies to
+			// every order of the file at that temperature
+			items = orders
+		}
+		stay, expiring := meanStay(items, temp, delays, cfg.DecayModifier, formula, menu)
+		stays[temp] = stay
+		plan.Expiring += expiring * rate / plan.Rate * 100
+	}
+
+	plan.planShelves(cfg, rates, stays)
+	return plan, nil
+}
+
+// tempRates returns the orders per second of each temperature at the peak
+// of the demand curve
+func (p *CapacityPlan) tempRates(cfg *config.Config, orders []OrderData) map[string]float64 {
+	base := cfg.OrdersPerSecond
+	if len(cfg.OrdersPerSecondByTemp) > 0 {
+		base = tempRatesTotal(cfg.OrdersPerSecondByTemp)
+	}
+	total := base
+	for _, point := range cfg.Demand.Points {
+		total = math.Max(total, point.OrdersPerSecond)
+	}
+	if total != base {
+		p.Notes = append(p.Notes, fmt.Sprintf("Planned for the peak demand of %.2f orders/sec", total))
+	}
+
+	rates := make(map[string]float64)
+	if len(cfg.OrdersPerSecondByTemp) > 0 {
+		scale := 1.0
+		if sum := tempRatesTotal(cfg.OrdersPerSecondByTemp); sum > 0 {
+			scale = total / sum
+		}
+		for temp, rate := range cfg.OrdersPerSecondByTemp {
+			if rate > 0 {
+				rates[temp] = rate * scale
+			}
+		}
+		return rates
+	}
+
+	// Otherwise temperatures arrive in proportion to the orders file, or to
+	// the menu weights when orders are sampled from it
+	weights := make(map[string]float64)
+	sum := 0.0
+	for _, d := range orders {
+		weight := 1.0
+		if cfg.Orders.Mode == config.OrdersModeMenu && d.Weight > 0 {
+			weight = d.Weight
+		}
+		weights[d.Temp] += weight
+		sum += weight
+	}
+	for temp, weight := range weights {
+		rates[temp] = total * weight / sum
+	}
+	return rates
+}
+
+// pickupDelays returns equally likely delays from shelving to pickup.
+// Orders arrive evenly spaced and wait in a queue for pickup, whose mean
+// wait is the Allen-Cunneen approximation: the M/M/c wait scaled by half
+// the squared coefficient of variation of the pickup time. Without a fleet
+// orders are picked up one at a time, each taking one of
+// randomPickupDelays. With a fleet a courier is busy for its trips to the
+// kitchen and to the customer, each two thirds of reach on average.
+func (p *CapacityPlan) pickupDelays(cfg config.CourierConfig) ([]float64, error) {
+	if cfg.Count <= 0 {
+		service := mean(randomPickupDelays)
+		if p.Rate*service >= 1 {
+			return nil, fmt.Errorf("pickups one at a time keep up with at most %.2f orders/sec, below the %.2f planned for; configure couriers",
+				1/service, p.Rate)
+		}
+		var variance float64
+		for _, d := range randomPickupDelays {
+			variance += (d - service) * (d - service)
+		}
+		variance /= float64(len(randomPickupDelays))
+		wait := p.queueWait(1, service, variance)
+
+		delays := make([]float64, len(randomPickupDelays))
+		for i, d := range randomPickupDelays {
+			delays[i] = wait + d
+		}
+		p.PickupDelay = wait + service
+		return delays, nil
+	}
+
+	// Couriers and customers are spread uniformly over a disc of radius
+	// reach, where distances from the center average 2/3 reach with a
+	// variance of reach²/18
+	trip := 2 * cfg.Reach / 3
+	service := 2*trip + cfg.Handoff
+	if p.Rate*service >= float64(cfg.Count) {
+		return nil, fmt.Errorf("%d couriers keep up with at most %.2f orders/sec, below the %.2f planned for; at least %d are needed",
+			cfg.Count, float64(cfg.Count)/service, p.Rate, int(math.Floor(p.Rate*service))+1)
+	}
+	wait := p.queueWait(cfg.Count, service, cfg.Reach*cfg.Reach/9)
+	p.PickupDelay = wait + trip
+	return []float64{p.PickupDelay}, nil
+}
+
+// queueWait returns the mean wait for one of servers taking service seconds
+// on average, with the given variance, and records their utilization
+func (p *CapacityPlan) queueWait(servers int, service, variance float64) float64 {
+	if service <= 0 {
+		return 0
+	}
+	offered := p.Rate * service
+	n := float64(servers)
+	p.Utilization = offered / n
+
+	b := erlangB(servers, offered)
+	c := b / (1 - p.Utilization*(1-b))
+	return c * service / (n - offered) * variance / (service * service) / 2
+}
+
+// meanStay returns the mean seconds orders of temp spend on their shelf
+// and the fraction expiring before pickup, over the items and the delays
+func meanStay(items []OrderData, temp string, delays []float64, decayModifier float64, formula order.DecayFormula, menu bool) (float64, float64) {
+	placed := time.Unix(0, 0)
+	var stay, expiring, total float64
+	for _, d := range items {
+		weight := 1.0
+		if menu && d.Weight > 0 {
+			weight = d.Weight
+		}
+		d.Temp = temp
+		o := d.newPooledOrder(nil, decayModifier, formula)
+		o.PlacedOnShelfAt = placed
+		life := math.Inf(1)
+		if expiry := o.ExpiresAt(); !expiry.IsZero() {
+			life = expiry.Sub(placed).Seconds()
+		}
+		for _, delay := range delays {
+			stay += weight * math.Min(delay, life)
+			if life < delay {
+				expiring += weight
+			}
+			total += weight
+		}
+	}
+	return stay / total, expiring / total
+}
+
+// planShelves offers each shelf the orders routed to it, splitting a
+// temperature held by several shelves evenly, and the orders turned away
+// from full shelves to the overflow shelves
+func (p *CapacityPlan) planShelves(cfg *config.Config, rates, stays map[string]float64) {
+	target := p.Target / 100
+	overflow := ShelfPlan{Overflow: true}
+	var overflowNames []string
+
+	holders := make(map[string]int)
+	layout := ShelfLayout(cfg)
+	for _, spec := range layout {
+		for _, temp := range spec.Temps {
+			holders[string(temp)]++
+		}
+	}
+	var overflowLoad float64
+	temps := slices.Sorted(maps.Keys(rates))
+	for _, temp := range temps {
+		if rate := rates[temp]; holders[temp] == 0 {
+			overflow.Rate += rate
+			overflowLoad += rate * stays[temp]
+			p.Notes = append(p.Notes, fmt.Sprintf("No shelf holds %s orders; they go to overflow", temp))
+		}
+	}
+
+	for _, spec := range layout {
+		if spec.IsOverflow() {
+			overflow.Current += spec.Capacity
+			overflowNames = append(overflowNames, string(spec.Type))
+			continue
+		}
+		sp := ShelfPlan{Shelf: string(spec.Type), Current: spec.Capacity}
+		var load float64
+		for _, temp := range spec.Temps {
+			rate := rates[string(temp)] / float64(holders[string(temp)])
+			sp.Rate += rate
+			load += rate * stays[string(temp)]
+		}
+		if sp.Rate > 0 {
+			sp.MeanStay = load / sp.Rate
+		}
+		blocked := erlangB(sp.Current, load)
+		sp.Blocked = blocked * 100
+		sp.Recommended = capacityFor(load, target)
+		p.Shelves = append(p.Shelves, sp)
+
+		overflow.Rate += sp.Rate * blocked
+		overflowLoad += sp.Rate * blocked * sp.MeanStay
+	}
+
+	if len(overflowNames) == 0 {
+		p.Wasted = overflow.Rate / p.Rate * 100
+		return
+	}
+	// The overflow needed alongside the current temperature shelves
+	overflow.Shelf = strings.Join(overflowNames, "+")
+	if overflow.Rate > 0 {
+		overflow.MeanStay = overflowLoad / overflow.Rate
+	}
+	blocked := erlangB(overflow.Current, overflowLoad)
+	overflow.Blocked = blocked * 100
+	if allowed := target * p.Rate; overflow.Rate > allowed {
+		overflow.Recommended = capacityFor(overflowLoad, allowed/overflow.Rate)
+	}
+	p.Wasted = overflow.Rate * blocked / p.Rate * 100
+	p.Shelves = append(p.Shelves, overflow)
+}
+
+// erlangB returns the probability that all c servers offered load erlangs
+// are busy
+func erlangB(c int, load float64) float64 {
+	b := 1.0
+	for k := 1; k <= c; k++ {
+		b = load * b / (float64(k) + load*b)
+	}
+	return b
+}
+
+// capacityFor returns the fewest servers turning away at most the fraction
+// target of load erlangs
+func capacityFor(load, target float64) int {
+	if load <= 0 {
+		return 0
+	}
+	b := 1.0
+	for c := 1; c <= maxPlannedCapacity; c++ {
+		b = load * b / (float64(c) + load*b)
+		if b <= target {
+			return c
+		}
+	}
+	return maxPlannedCapacity
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Print writes the plan as a recommendation
+func (p CapacityPlan) Print(w io.Writer) {
+	fmt.Fprintf(w, "\n📐 CAPACITY PLAN (target waste %.1f%%):\n", p.Target)
+	fmt.Fprintf(w, "  Orders: %.2f/sec, mean pickup after %.1fs", p.Rate, p.PickupDelay)
+	fmt.Fprintf(w, ", pickups %.0f%% busy\n", p.Utilization*100)
+	for _, sp := range p.Shelves {
+		advice := fmt.Sprintf("recommend %d", sp.Recommended)
+		if sp.Overflow {
+			advice = fmt.Sprintf("%d needed with the current shelves", sp.Recommended)
+		}
+		fmt.Fprintf(w, "  %s: %.2f orders/sec staying %.1fs, capacity %d turns away %.1f%%, %s\n",
+			sp.Shelf, sp.Rate, sp.MeanStay, sp.Current, sp.Blocked, advice)
+	}
+	fmt.Fprintf(w, "  Expected waste at the current capacities: %.1f%%\n", p.Wasted)
+	if p.Expiring > 0 {
+		fmt.Fprintf(w, "  A further %.1f%% expire before pickup whatever the capacity\n", p.Expiring)
+	}
+	for _, note := range p.Notes {
+		fmt.Fprintf(w, "  %s\n", note)
+	}
+}
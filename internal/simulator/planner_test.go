@@ -0,0 +1,136 @@
+package simulator
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"dish-dispatcher/internal/config"
+)
+
+func TestErlangB(t *testing.T) {
+	for _, tc := range []struct {
+		c    int
+		load float64
+		want float64
+	}{
+		{0, 1, 1},
+		{1, 1, 0.5},
+		{2, 1, 0.2},
+		{3, 2, 4.0 / 19},
+	} {
+		if got := erlangB(tc.c, tc.load); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("erlangB(%d, %v) = %v, want %v", tc.c, tc.load, got, tc.want)
+		}
+	}
+
+	if got := capacityFor(1, 0.2); got != 2 {
+		t.Errorf("Expected 2 servers for 1 erlang at 20%%, got %d", got)
+	}
+	if got := capacityFor(0, 0.01); got != 0 {
+		t.Errorf("Expected no servers without load, got %d", got)
+	}
+}
+
+func planConfig() *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 0.2
+	cfg.HotShelfCapacity = 1
+	cfg.ColdShelfCapacity = 1
+	cfg.FrozenShelfCapacity = 1
+	cfg.OverflowCapacity = 0
+	return cfg
+}
+
+func TestPlanCapacity(t *testing.T) {
+	orders := []OrderData{
+		{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+		{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+		{Name: "Ice Cream", Temp: "frozen", ShelfLife: 300, DecayRate: 0.5},
+		{Name: "Sashimi", Temp: "cold", ShelfLife: 1, DecayRate: 0.5},
+	}
+
+	plan, err := PlanCapacity(planConfig(), orders, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if plan.Rate != 0.2 || plan.Utilization != 0.8 {
+		t.Errorf("Expected 0.2 orders/sec keeping pickups 80%% busy, got %v and %v", plan.Rate, plan.Utilization)
+	}
+	if plan.PickupDelay <= 4 {
+		t.Errorf("Expected orders to queue for pickup, got a delay of %vs", plan.PickupDelay)
+	}
+	if plan.Expiring != 25 {
+		t.Errorf("Expected the cold quarter of orders to expire, got %v%%", plan.Expiring)
+	}
+
+	shelves := make(map[string]ShelfPlan)
+	for _, sp := range plan.Shelves {
+		shelves[sp.Shelf] = sp
+	}
+	hot, frozen, cold, overflow := shelves["hot"], shelves["frozen"], shelves["cold"], shelves["overflow"]
+	if hot.Rate != 2*frozen.Rate || hot.Recommended < frozen.Recommended || hot.Recommended <= hot.Current {
+		t.Errorf("Expected the busier hot shelf to need more room, got %+v and %+v", hot, frozen)
+	}
+	if cold.MeanStay > 1 {
+		t.Errorf("Expected cold orders to stay only until they expire, got %vs", cold.MeanStay)
+	}
+	if !overflow.Overflow || overflow.Blocked != 100 || overflow.Recommended == 0 {
+		t.Errorf("Expected an empty overflow to turn every order away, got %+v", overflow)
+	}
+	if plan.Wasted <= 0 || plan.Wasted >= 100 {
+		t.Errorf("Expected some waste at the current capacities, got %v%%", plan.Wasted)
+	}
+}
+
+func TestPlanCapacity_TempRates(t *testing.T) {
+	cfg := planConfig()
+	cfg.OrdersPerSecondByTemp = map[string]float64{"hot": 0.1, "ambient": 0.05}
+	cfg.Demand.Points = []config.DemandPoint{{Time: "12:00", OrdersPerSecond: 0.1}}
+
+	plan, err := PlanCapacity(cfg, []OrderData{{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}}, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if math.Abs(plan.Rate-0.15) > 1e-9 {
+		t.Errorf("Expected the split rates, got %v", plan.Rate)
+	}
+	notes := strings.Join(plan.Notes, "\n")
+	if !strings.Contains(notes, "No shelf holds ambient") {
+		t.Errorf("Expected a note on the ambient orders, got %q", notes)
+	}
+	for _, sp := range plan.Shelves {
+		// Besides the ambient orders, overflow takes those the hot shelf turns away
+		if sp.Overflow && sp.Rate <= 0.05 {
+			t.Errorf("Expected the ambient orders on overflow, got %v", sp.Rate)
+		}
+	}
+}
+
+func TestPlanCapacity_Overloaded(t *testing.T) {
+	orders := []OrderData{{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}}
+	cfg := planConfig()
+	cfg.OrdersPerSecond = 2
+	if _, err := PlanCapacity(cfg, orders, 5); err == nil || !strings.Contains(err.Error(), "configure couriers") {
+		t.Errorf("Expected pickups one at a time to fall behind, got %v", err)
+	}
+
+	// Each courier is busy for two trips of 2s on average
+	cfg.Couriers.Count = 8
+	cfg.Couriers.Reach = 3
+	if _, err := PlanCapacity(cfg, orders, 5); err == nil || !strings.Contains(err.Error(), "at least 9") {
+		t.Errorf("Expected 8 couriers to fall behind, got %v", err)
+	}
+	cfg.Couriers.Count = 12
+	plan, err := PlanCapacity(cfg, orders, 5)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if plan.PickupDelay < 2 {
+		t.Errorf("Expected at least the trip to the kitchen, got %vs", plan.PickupDelay)
+	}
+
+	if _, err := PlanCapacity(cfg, orders, 0); err == nil {
+		t.Errorf("Expected an error for a zero target")
+	}
+}
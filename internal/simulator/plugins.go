@@ -0,0 +1,155 @@
+package simulator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+// configurePlacementStrategy selects the configured plugin placement
+// strategy on the shelf manager
+func configurePlacementStrategy(cfg *config.Config, manager shelf.ShelfManager) error {
+	if cfg.PlacementStrategy == "" {
+		return nil
+	}
+	if cfg.Scripts.Placement != "" {
+		return errors.New("set placementStrategy or scripts.placement, not both")
+	}
+	pluggable, ok := manager.(shelf.PluggablePlacement)
+	if !ok {
+		return fmt.Errorf("shelf manager %T cannot use placement strategies", manager)
+	}
+	return pluggable.SetPlacementStrategy(cfg.PlacementStrategy)
+}
+
+// newEventSinks creates the named plugin event sinks
+func newEventSinks(names []string) ([]plugin.EventSink, error) {
+	sinks := make([]plugin.EventSink, 0, len(names))
+	for _, name := range names {
+		sink, err := plugin.NewEventSink(name)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	return sinks, nil
+}
+
+// closeSinks closes sinks that will never be started
+func closeSinks(sinks []plugin.EventSink) {
+	for _, sink := range sinks {
+		sink.Close()
+	}
+}
+
+// startSinks feeds every event to the event sinks. The returned function
+// stops them once they have handled the events already published, then
+// closes them.
+func (s *Simulator) startSinks() func() {
+	if len(s.sinks) == 0 {
+		return func() {}
+	}
+
+	var (
+		wg           sync.WaitGroup
+		unsubscribes []func()
+	)
+	for _, sink := range s.sinks {
+		updates, unsubscribe := s.Events.Subscribe()
+		unsubscribes = append(unsubscribes, unsubscribe)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range updates {
+				sink.Handle(pluginEvent(e))
+			}
+		}()
+	}
+
+	return func() {
+		for _, unsubscribe := range unsubscribes {
+			unsubscribe()
+		}
+		wg.Wait()
+		for _, sink := range s.sinks {
+			if err := sink.Close(); err != nil {
+				fmt.Printf("⚠️ Event sink failed to close: %v\n", err)
+			}
+		}
+	}
+}
+
+// pluginEvent converts an event for plugins
+func pluginEvent(e events.Event) plugin.Event {
+	return plugin.Event{
+		Run:     e.Run,
+		RunID:   e.RunID,
+		Type:    string(e.Type),
+		Time:    e.Time,
+		OrderID: e.OrderID,
+		Name:    e.Name,
+		Temp:    e.Temp,
+		Shelf:   e.Shelf,
+		Value:   e.Value,
+		Count:   e.Count,
+		Reason:  e.Reason,
+		Version: e.Version,
+	}
+}
+
+// busEvent converts an event handed to sinks back to its published form,
+// whose JSON encoding matches the API's
+func busEvent(e plugin.Event) events.Event {
+	return events.Event{
+		Run:     e.Run,
+		RunID:   e.RunID,
+		Type:    events.Type(e.Type),
+		Time:    e.Time,
+		OrderID: e.OrderID,
+		Name:    e.Name,
+		Temp:    e.Temp,
+		Shelf:   e.Shelf,
+		Value:   e.Value,
+		Count:   e.Count,
+		Reason:  e.Reason,
+		Version: e.Version,
+	}
+}
+
+// eventEncoder encodes events for the event log, MQTT and NATS
+type eventEncoder func(plugin.Event) ([]byte, error)
+
+// validateEventFormat checks the configured event format
+func validateEventFormat(cfg *config.Config) error {
+	switch cfg.EventFormat {
+	case "", config.EventFormatNative:
+	case config.EventFormatCloudEvents:
+		if cfg.EventSource == "" {
+			return errors.New("eventSource must not be empty with cloudevents")
+		}
+	default:
+		return fmt.Errorf("unknown event format %q, expected %q or %q",
+			cfg.EventFormat, config.EventFormatNative, config.EventFormatCloudEvents)
+	}
+	return nil
+}
+
+// newEventEncoder returns the encoder of the configured event format
+func newEventEncoder(cfg *config.Config) eventEncoder {
+	if cfg.EventFormat == config.EventFormatCloudEvents {
+		source := cfg.EventSource
+		return func(e plugin.Event) ([]byte, error) {
+			return json.Marshal(busEvent(e).CloudEvent(source))
+		}
+	}
+	return func(e plugin.Event) ([]byte, error) {
+		return json.Marshal(busEvent(e))
+	}
+}
@@ -0,0 +1,118 @@
+package simulator
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/plugin"
+)
+
+// countingSink is a plugin event sink counting events by type
+type countingSink struct {
+	mutex  sync.Mutex
+	counts map[string]int
+	closed bool
+}
+
+func (s *countingSink) Handle(e plugin.Event) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.counts[e.Type]++
+}
+
+func (s *countingSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.closed = true
+	return nil
+}
+
+func TestEventSinks(t *testing.T) {
+	sink := &countingSink{counts: make(map[string]int)}
+	plugin.RegisterEventSink("counting", func() (plugin.EventSink, error) { return sink, nil })
+
+	e := newTestDiscreteEngine(t, 5)
+	e.sinks, _ = newEventSinks([]string{"counting"})
+	e.Run()
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if !sink.closed {
+		t.Errorf("Expected the sink to be closed after the run")
+	}
+	if sink.counts["order_placed"] != 5 {
+		t.Errorf("Expected 5 placement events, got %v", sink.counts)
+	}
+}
+
+func TestNewSimulator_UnknownPlugins(t *testing.T) {
+	path := writeOrders(t, nil)
+
+	cfg := config.DefaultConfig()
+	cfg.EventSinks = []string{"missing"}
+	if _, err := NewSimulator(cfg, path); err == nil {
+		t.Errorf("Expected an unknown event sink to be rejected")
+	}
+
+	cfg = config.DefaultConfig()
+	cfg.PlacementStrategy = "missing"
+	if _, err := NewSimulator(cfg, path); err == nil {
+		t.Errorf("Expected an unknown placement strategy to be rejected")
+	}
+}
+
+func TestEventEncoder(t *testing.T) {
+	cfg := config.DefaultConfig()
+	e := plugin.Event{Type: "order_placed", OrderID: "o1"}
+
+	data, err := newEventEncoder(cfg)(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"type":"order_placed","time":"0001-01-01T00:00:00Z","orderId":"o1"}`; string(data) != want {
+		t.Errorf("Expected native encoding %s, got %s", want, data)
+	}
+
+	cfg.EventFormat = config.EventFormatCloudEvents
+	cfg.EventSource = "/kitchens/1"
+	data, err = newEventEncoder(cfg)(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope struct {
+		Type   string `json:"type"`
+		Source string `json:"source"`
+		Data   struct {
+			OrderID string `json:"orderId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Type != "dish-dispatcher.order_placed" || envelope.Source != "/kitchens/1" || envelope.Data.OrderID != "o1" {
+		t.Errorf("Unexpected CloudEvent %s", data)
+	}
+}
+
+func TestValidateEventFormat(t *testing.T) {
+	cfg := config.DefaultConfig()
+	for _, format := range []string{"", config.EventFormatNative, config.EventFormatCloudEvents} {
+		cfg.EventFormat = format
+		if err := validateEventFormat(cfg); err != nil {
+			t.Errorf("Expected format %q to be valid, got %v", format, err)
+		}
+	}
+
+	cfg.EventFormat = "xml"
+	if err := validateEventFormat(cfg); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+	cfg.EventFormat, cfg.EventSource = config.EventFormatCloudEvents, ""
+	if err := validateEventFormat(cfg); err == nil {
+		t.Error("Expected cloudevents without a source to be rejected")
+	}
+}
@@ -0,0 +1,367 @@
+package simulator
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// validatePostMortemConfig checks the breakdowns of the waste post-mortem
+func validatePostMortemConfig(cfg config.PostMortemConfig) error {
+	if cfg.Top < 0 {
+		return fmt.Errorf("postMortem.top must not be negative, got %d", cfg.Top)
+	}
+	if cfg.Top > 0 && cfg.Window <= 0 {
+		return fmt.Errorf("postMortem.window must be positive, got %d", cfg.Window)
+	}
+	return nil
+}
+
+// wasteTracker counts the orders lost by item, temperature, shelf, cause
+// and time window, for the post-mortem printed after the run. A nil
+// wasteTracker counts nothing.
+type wasteTracker struct {
+	window time.Duration
+	routes map[order.Temperature]shelf.ShelfType // shelf each temperature is placed on
+
+	mutex    sync.Mutex
+	start    time.Time
+	lost     int
+	byItem   map[string]int
+	byTemp   map[string]int
+	byShelf  map[string]int
+	byCause  map[string]int
+	byWindow map[int]int // by window since start
+	noSpace  map[shelf.ShelfType]int
+}
+
+// newWasteTracker counts the losses on the given shelves, or returns nil if
+// the post-mortem is disabled
+func newWasteTracker(cfg config.PostMortemConfig, states []shelf.ShelfState) *wasteTracker {
+	if cfg.Top == 0 {
+		return nil
+	}
+	t := &wasteTracker{
+		window: time.Duration(cfg.Window) * time.Second,
+		routes: make(map[order.Temperature]shelf.ShelfType),
+	}
+	for _, state := range states {
+		for _, temp := range state.Temps {
+			if _, ok := t.routes[temp]; !ok {
+				t.routes[temp] = state.Type
+			}
+		}
+	}
+	t.begin(time.Now())
+	return t
+}
+
+// begin discards every count, starting the time windows at the given time
+func (t *wasteTracker) begin(at time.Time) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.start = at
+	t.lost = 0
+	t.byItem = make(map[string]int)
+	t.byTemp = make(map[string]int)
+	t.byShelf = make(map[string]int)
+	t.byCause = make(map[string]int)
+	t.byWindow = make(map[int]int)
+	t.noSpace = make(map[shelf.ShelfType]int)
+}
+
+// observe is a TransitionHook counting the orders lost after placement.
+// Orders wasted on placement are counted by placeOrder, which knows why.
+func (t *wasteTracker) observe(o *order.Order, from, to order.State, at time.Time) {
+	if t == nil || from == order.StateCreated && to == order.StateWasted {
+		return
+	}
+	if to != order.StateExpired && to != order.StateWasted {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.record(o, shelf.ShelfType(o.CurrentShelfType), at, lossCause(from, to))
+}
+
+// placementWasted counts an order wasted on placement, blaming the shelf
+// its temperature is placed on if there was no space
+func (t *wasteTracker) placementWasted(o *order.Order, at time.Time, reason shelf.RejectReason) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	routed := t.routes[o.Temp]
+	if reason == shelf.RejectPrimaryFull || reason == shelf.RejectOverflowFull {
+		t.noSpace[routed]++
+	}
+	t.record(o, routed, at, string(reason))
+}
+
+// record counts a lost order. Callers hold the mutex.
+func (t *wasteTracker) record(o *order.Order, on shelf.ShelfType, at time.Time, cause string) {
+	t.lost++
+	t.byItem[o.Name]++
+	t.byTemp[string(o.Temp)]++
+	if on != "" {
+		t.byShelf[string(on)]++
+	}
+	if cause != "" {
+		t.byCause[cause]++
+	}
+	t.byWindow[max(int(at.Sub(t.start)/t.window), 0)]++
+}
+
+// wasteContributor is one line of a post-mortem breakdown
+type wasteContributor struct {
+	name string
+	lost int
+}
+
+// wasteLever is a setting that might have saved some of the lost orders
+type wasteLever struct {
+	suggestion string
+	saves      int // at most, if the lever removed every loss it addresses
+}
+
+// wastePostMortem is what the waste post-mortem reports
+type wastePostMortem struct {
+	received, lost                 int
+	items, temps, shelves, windows []wasteContributor
+	causes                         []wasteContributor
+	levers                         []wasteLever
+	top                            int
+}
+
+// postMortem breaks the run's losses down and ranks the levers against
+// them. The levers are estimated from the causes of the losses: each saves
+// at most the orders lost to what it addresses.
+func (s *Simulator) postMortem(received int) wastePostMortem {
+	t := s.waste
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	pm := wastePostMortem{
+		received: received,
+		lost:     t.lost,
+		items:    topContributors(t.byItem),
+		temps:    topContributors(t.byTemp),
+		shelves:  topContributors(t.byShelf),
+		causes:   topContributors(t.byCause),
+		top:      s.Config.PostMortem.Top,
+	}
+
+	windows := make([]int, 0, len(t.byWindow))
+	for i := range t.byWindow {
+		windows = append(windows, i)
+	}
+	sort.Ints(windows)
+	for _, i := range windows {
+		pm.windows = append(pm.windows, wasteContributor{name: windowLabel(i, t.window), lost: t.byWindow[i]})
+	}
+	sort.SliceStable(pm.windows, func(i, j int) bool { return pm.windows[i].lost > pm.windows[j].lost })
+
+	pm.levers = s.wasteLevers(t)
+	sort.SliceStable(pm.levers, func(i, j int) bool { return pm.levers[i].saves > pm.levers[j].saves })
+	return pm
+}
+
+// wasteLevers suggests a setting for each cause of loss seen. Callers hold
+// the tracker's mutex.
+func (s *Simulator) wasteLevers(t *wasteTracker) []wasteLever {
+	cfg := s.Config
+	var levers []wasteLever
+	add := func(saves int, format string, args ...any) {
+		if saves > 0 {
+			levers = append(levers, wasteLever{suggestion: fmt.Sprintf(format, args...), saves: saves})
+		}
+	}
+
+	// Shelves that were full when orders arrived
+	states := s.ShelfManager.ShelfStates()
+	var overflow *shelf.ShelfState
+	for i := range states {
+		if len(states[i].Temps) == 0 {
+			overflow = &states[i]
+			break
+		}
+	}
+	for _, state := range states {
+		if saves := t.noSpace[state.Type]; saves > 0 {
+			suggestion := fmt.Sprintf("Raise %s from %d", capacitySetting(cfg, state.Type), state.Capacity)
+			if overflow != nil {
+				suggestion += fmt.Sprintf(", or %s from %d", capacitySetting(cfg, overflow.Type), overflow.Capacity)
+			}
+			add(saves, "%s", suggestion)
+		}
+	}
+
+	// Orders waiting too long for a courier
+	waited := t.byCause["expired_on_shelf"]
+	switch {
+	case cfg.Couriers.AgentAddr != "":
+		add(waited, "Connect more courier agents")
+	case cfg.Couriers.Count > 0:
+		add(waited, "Raise couriers.count from %d", cfg.Couriers.Count)
+	default:
+		add(waited, "Collect orders sooner with a courier fleet: set couriers.count")
+	}
+	if cfg.Couriers.MinValue > 0 {
+		add(t.byCause[string(shelf.RejectLowValue)], "Lower couriers.minValue from %g", cfg.Couriers.MinValue)
+	}
+
+	// Orders decaying on the way
+	var transit []string
+	if cfg.Couriers.Handoff > 0 {
+		transit = append(transit, fmt.Sprintf("couriers.handoff from %gs", cfg.Couriers.Handoff))
+	}
+	if cfg.Couriers.TransitDecay > 1 {
+		transit = append(transit, fmt.Sprintf("couriers.transitDecay from %g", cfg.Couriers.TransitDecay))
+	}
+	if cfg.Couriers.Count > 0 {
+		transit = append(transit, fmt.Sprintf("couriers.reach from %gs", cfg.Couriers.Reach))
+	}
+	if len(transit) > 0 {
+		add(t.byCause["expired_in_transit"], "Lower %s", strings.Join(transit, ", or "))
+	}
+
+	if cfg.UnknownTemps.Policy != config.UnknownTempFallback {
+		add(t.byCause[string(shelf.RejectInvalidTemperature)], "Place unknown temperatures on a fallback shelf: set unknownTemps.policy to %q", config.UnknownTempFallback)
+	}
+
+	// Bursts of losses beyond the usual, which a steadier rate would spread
+	suggestion := fmt.Sprintf("Lower ordersPerSecond from %g", cfg.OrdersPerSecond)
+	if cfg.Throttle.Threshold == 0 {
+		suggestion += ", or hold orders back under load with throttle.threshold"
+	}
+	add(burstLosses(t.byWindow), "%s", suggestion)
+	return levers
+}
+
+// capacitySetting names the setting holding a shelf's capacity
+func capacitySetting(cfg *config.Config, shelfType shelf.ShelfType) string {
+	if len(cfg.Shelves) == 0 {
+		switch shelfType {
+		case shelf.HotShelf:
+			return "hotShelfCapacity"
+		case shelf.ColdShelf:
+			return "coldShelfCapacity"
+		case shelf.FrozenShelf:
+			return "frozenShelfCapacity"
+		case shelf.OverflowShelf:
+			return "overflowCapacity"
+		}
+	}
+	return fmt.Sprintf("shelves[%s].capacity", shelfType)
+}
+
+// burstLosses counts the losses above the median window's, from the start
+// to the last window losing any
+func burstLosses(byWindow map[int]int) int {
+	last := -1
+	for i := range byWindow {
+		last = max(last, i)
+	}
+	if last < 1 {
+		return 0
+	}
+	counts := make([]int, last+1)
+	for i, lost := range byWindow {
+		counts[i] = lost
+	}
+	sorted := slices.Clone(counts)
+	slices.Sort(sorted)
+	median := sorted[len(sorted)/2]
+
+	excess := 0
+	for _, lost := range counts {
+		excess += max(lost-median, 0)
+	}
+	return excess
+}
+
+// topContributors lists the counts, most lost first
+func topContributors(counts map[string]int) []wasteContributor {
+	list := make([]wasteContributor, 0, len(counts))
+	for name, lost := range counts {
+		list = append(list, wasteContributor{name: name, lost: lost})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].lost != list[j].lost {
+			return list[i].lost > list[j].lost
+		}
+		return list[i].name < list[j].name
+	})
+	return list
+}
+
+// windowLabel formats a time window of the run, such as "10s-20s"
+func windowLabel(i int, window time.Duration) string {
+	return fmt.Sprintf("%v-%v", time.Duration(i)*window, time.Duration(i+1)*window)
+}
+
+// print writes the post-mortem as a section of the final report
+func (pm wastePostMortem) print(w io.Writer) {
+	if pm.lost == 0 {
+		fmt.Fprintln(w, "\n🔍 WASTE POST-MORTEM: no orders lost")
+		return
+	}
+	fmt.Fprintf(w, "\n🔍 WASTE POST-MORTEM: %d of %d orders lost (%.1f%%)\n",
+		pm.lost, pm.received, percentOf(pm.lost, pm.received))
+	pm.printContributors(w, "Top items", pm.items)
+	pm.printContributors(w, "Top temperatures", pm.temps)
+	pm.printContributors(w, "Top shelves", pm.shelves)
+	pm.printContributors(w, "Worst windows", pm.windows)
+	pm.printContributors(w, "Causes", pm.causes)
+	if len(pm.levers) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "  Levers, most orders saved first:")
+	for i, lever := range pm.levers[:min(pm.top, len(pm.levers))] {
+		fmt.Fprintf(w, "    %d. %s: up to %d orders (%.1f%% of losses)\n",
+			i+1, lever.suggestion, lever.saves, percentOf(lever.saves, pm.lost))
+	}
+}
+
+// printContributors writes the top of a breakdown on one line
+func (pm wastePostMortem) printContributors(w io.Writer, label string, list []wasteContributor) {
+	if len(list) == 0 {
+		return
+	}
+	parts := make([]string, 0, pm.top)
+	for _, c := range list[:min(pm.top, len(list))] {
+		parts = append(parts, fmt.Sprintf("%s %d (%.1f%%)", c.name, c.lost, percentOf(c.lost, pm.lost)))
+	}
+	fmt.Fprintf(w, "  %s: %s\n", label, strings.Join(parts, ", "))
+}
+
+// percentOf returns n as a percentage of total, 0 if total is
+func percentOf(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) / float64(total) * 100
+}
+
+// printPostMortem prints the waste post-mortem, if enabled
+func (s *Simulator) printPostMortem(received int) {
+	if s.waste == nil {
+		return
+	}
+	s.postMortem(received).print(os.Stdout)
+}
@@ -0,0 +1,138 @@
+package simulator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestValidatePostMortemConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.PostMortemConfig
+		wantErr bool
+	}{
+		{"default", config.PostMortemConfig{Top: 3, Window: 10}, false},
+		{"off", config.PostMortemConfig{}, false},
+		{"negative top", config.PostMortemConfig{Top: -1, Window: 10}, true},
+		{"no window", config.PostMortemConfig{Top: 3}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validatePostMortemConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWasteTracker_Disabled(t *testing.T) {
+	if newWasteTracker(config.PostMortemConfig{}, nil) != nil {
+		t.Errorf("Expected no tracker with the post-mortem off")
+	}
+	var off *wasteTracker
+	off.begin(time.Now())
+	off.observe(order.NewOrder("Burger", order.Hot, 300, 0.5), order.StateShelved, order.StateExpired, time.Now())
+}
+
+func TestBurstLosses(t *testing.T) {
+	tests := []struct {
+		name     string
+		byWindow map[int]int
+		want     int
+	}{
+		{"none", map[int]int{}, 0},
+		{"one window", map[int]int{0: 9}, 0},
+		{"steady", map[int]int{0: 2, 1: 2, 2: 2}, 0},
+		{"burst", map[int]int{0: 1, 1: 7, 2: 1}, 6},
+		{"quiet windows count", map[int]int{3: 4}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := burstLosses(tt.byWindow); got != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSimulator_PostMortem(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.ShelfManager = shelf.NewShelfManager(0, 0, 0, 0)
+	s.Config.Couriers.Count = 2
+	s.Config.PostMortem = config.PostMortemConfig{Top: 2, Window: 10}
+	s.waste = newWasteTracker(s.Config.PostMortem, s.ShelfManager.ShelfStates())
+	s.SetVerbose(false)
+
+	// Wasted for lack of space
+	for _, d := range []OrderData{
+		{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+		{Name: "Ice Cream", Temp: "frozen", ShelfLife: 200, DecayRate: 0.2},
+		{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+	} {
+		s.placeOrder(d)
+	}
+	start := s.waste.start
+	lost := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	lost.CurrentShelfType = string(shelf.HotShelf)
+	s.waste.observe(lost, order.StateShelved, order.StateExpired, start.Add(25*time.Second))
+
+	pm := s.postMortem(4)
+	if pm.lost != 4 {
+		t.Fatalf("Expected 4 orders lost, got %d", pm.lost)
+	}
+	if top := pm.items[0]; top.name != "Burger" || top.lost != 3 {
+		t.Errorf("Expected the burgers to lose most, got %+v", pm.items)
+	}
+	if top := pm.shelves[0]; top.name != "hot" || top.lost != 3 {
+		t.Errorf("Expected the hot shelf to lose most, got %+v", pm.shelves)
+	}
+	if top := pm.windows[0]; top.name != "0s-10s" || top.lost != 3 {
+		t.Errorf("Expected the first window to lose most, got %+v", pm.windows)
+	}
+
+	// Two burgers found the hot shelf full, one expired waiting on it
+	if len(pm.levers) == 0 {
+		t.Fatalf("Expected levers")
+	}
+	if top := pm.levers[0]; top.saves != 2 || !strings.HasPrefix(top.suggestion, "Raise hotShelfCapacity from 0") {
+		t.Errorf("Expected more hot shelf space to save the most, got %+v", pm.levers)
+	}
+	var couriers bool
+	for _, lever := range pm.levers {
+		couriers = couriers || lever.suggestion == "Raise couriers.count from 2" && lever.saves == 1
+	}
+	if !couriers {
+		t.Errorf("Expected more couriers to save the expired burger, got %+v", pm.levers)
+	}
+
+	var out strings.Builder
+	pm.print(&out)
+	for _, want := range []string{"4 of 4 orders lost (100.0%)", "Top items: Burger 3 (75.0%), Ice Cream 1 (25.0%)", "1. Raise hotShelfCapacity"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected the post-mortem to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestSimulator_PostMortemReset(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.ShelfManager = shelf.NewShelfManager(0, 0, 0, 0)
+	s.waste = newWasteTracker(config.PostMortemConfig{Top: 3, Window: 10}, s.ShelfManager.ShelfStates())
+	s.SetVerbose(false)
+	s.placeOrders(context.Background(), 2)
+
+	if err := s.ResetStats(); err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	s.postMortem(0).print(&out)
+	if !strings.Contains(out.String(), "no orders lost") {
+		t.Errorf("Expected a reset to discard the losses, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,114 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// PromiseStats compares when orders reached their customers with when
+// they were promised to on placement
+type PromiseStats struct {
+	HandedOff int     `json:"handedOff"` // orders handed off with a promise
+	OnTime    int     `json:"onTime"`    // of those, handed off by the promised time
+	AvgDelta  float64 `json:"avgDelta"`  // mean seconds late, negative when early
+	MaxLate   float64 `json:"maxLate"`   // seconds the latest order missed its promise by
+}
+
+// OnTimeRate returns the percentage of promises kept
+func (p PromiseStats) OnTimeRate() float64 {
+	if p.HandedOff == 0 {
+		return 0
+	}
+	return float64(p.OnTime) / float64(p.HandedOff) * 100
+}
+
+// promiseStats accumulates PromiseStats. The zero value is ready to use.
+type promiseStats struct {
+	mutex      sync.Mutex
+	stats      PromiseStats
+	totalDelta float64
+}
+
+// record counts an order handed off late by delta, or early if negative
+func (p *promiseStats) record(delta time.Duration) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	seconds := delta.Seconds()
+	p.stats.HandedOff++
+	if seconds <= 0 {
+		p.stats.OnTime++
+	}
+	p.stats.MaxLate = max(p.stats.MaxLate, seconds)
+	p.totalDelta += seconds
+	p.stats.AvgDelta = p.totalDelta / float64(p.stats.HandedOff)
+}
+
+func (p *promiseStats) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.stats, p.totalDelta = PromiseStats{}, 0
+}
+
+func (p *promiseStats) snapshot() PromiseStats {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.stats
+}
+
+// Promises returns how well the orders handed off so far kept the delivery
+// time promised on placement
+func (s *Simulator) Promises() PromiseStats {
+	return s.promises.snapshot()
+}
+
+// promiseDelay estimates how long after placement an order reaches its
+// customer under the courier model. Without a fleet it is the mean pickup
+// delay. With one, the nearest free courier comes to the kitchen, or if all
+// are busy one first finishes a delivery, and then travels the average
+// distance to the customer. External agents report no positions, so their
+// orders are promised nothing.
+func (s *Simulator) promiseDelay() (time.Duration, bool) {
+	handoff := s.handoffDuration()
+	switch {
+	case s.Agents != nil:
+		return 0, false
+	case s.Couriers == nil:
+		return time.Duration(mean(randomPickupDelays)*float64(time.Second)) + handoff, true
+	}
+
+	// Customers are spread over a disc of radius reach, two thirds of it
+	// from the kitchen on average
+	trip := time.Duration(2 * s.Config.Couriers.Reach / 3 * float64(time.Second))
+	pickup, ok := s.Couriers.NearestIdle()
+	if !ok {
+		pickup = 3*trip + handoff
+	}
+	return pickup + trip + handoff, true
+}
+
+// promise sets when a new order should reach its customer
+func (s *Simulator) promise(o *order.Order) {
+	if delay, ok := s.promiseDelay(); ok {
+		o.PromisedAt = o.CreatedAt.Add(delay)
+	}
+}
+
+// printPromiseStats prints how many delivery promises were kept
+func (s *Simulator) printPromiseStats() {
+	p := s.promises.snapshot()
+	if p.HandedOff == 0 {
+		return
+	}
+	early, delta := "late", p.AvgDelta
+	if delta < 0 {
+		early, delta = "early", -delta
+	}
+	fmt.Printf("  Promised delivery kept: %.1f%% of %d (avg %.1fs %s, worst %.1fs late)\n",
+		p.OnTimeRate(), p.HandedOff, delta, early, p.MaxLate)
+}
@@ -0,0 +1,105 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/order"
+)
+
+func TestPromiseDelay_NoFleet(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Couriers.Handoff = 1
+
+	delay, ok := s.promiseDelay()
+	if !ok {
+		t.Fatalf("Expected a promise without a fleet")
+	}
+	want := time.Duration(mean(randomPickupDelays)*float64(time.Second)) + time.Second
+	if delay != want {
+		t.Errorf("Expected a delay of %v, got %v", want, delay)
+	}
+}
+
+func TestPromiseDelay_Fleet(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Couriers = config.CourierConfig{Count: 2, Reach: 6}
+	s.Couriers = courier.NewFleet([]*courier.Courier{{ID: 1, X: 3, Y: 4}, {ID: 2, X: 6}}, courier.NearestIdle{})
+
+	// The nearest courier is 5s from the kitchen and customers 4s from it
+	if delay, ok := s.promiseDelay(); !ok || delay != 9*time.Second {
+		t.Errorf("Expected a delay of 9s, got %v (%v)", delay, ok)
+	}
+
+	s.Couriers.Assign(order.NewOrder("Burger", order.Hot, 100, 1))
+	if delay, _ := s.promiseDelay(); delay != 10*time.Second {
+		t.Errorf("Expected the further courier to set the delay to 10s, got %v", delay)
+	}
+
+	// With everyone busy, a courier first finishes a delivery
+	s.Couriers.Assign(order.NewOrder("Burger", order.Hot, 100, 1))
+	if delay, _ := s.promiseDelay(); delay != 16*time.Second {
+		t.Errorf("Expected a delay of 16s with every courier busy, got %v", delay)
+	}
+}
+
+func TestPromise_SetsPromisedAt(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	o := order.NewOrder("Burger", order.Hot, 100, 1)
+	s.promise(o)
+
+	delay, _ := s.promiseDelay()
+	if !o.PromisedAt.Equal(o.CreatedAt.Add(delay)) {
+		t.Errorf("Expected the order promised at %v, got %v", o.CreatedAt.Add(delay), o.PromisedAt)
+	}
+}
+
+func TestPromiseStats(t *testing.T) {
+	var p promiseStats
+	p.record(-2 * time.Second)
+	p.record(0)
+	p.record(5 * time.Second)
+
+	stats := p.snapshot()
+	if stats.HandedOff != 3 || stats.OnTime != 2 {
+		t.Errorf("Expected 2 of 3 orders on time, got %d of %d", stats.OnTime, stats.HandedOff)
+	}
+	if stats.AvgDelta != 1 {
+		t.Errorf("Expected an average delta of 1s, got %.2f", stats.AvgDelta)
+	}
+	if stats.MaxLate != 5 {
+		t.Errorf("Expected the worst order 5s late, got %.2f", stats.MaxLate)
+	}
+	if rate := stats.OnTimeRate(); rate < 66.6 || rate > 66.7 {
+		t.Errorf("Expected an on-time rate of 66.7%%, got %.2f", rate)
+	}
+
+	p.reset()
+	if stats := p.snapshot(); stats != (PromiseStats{}) {
+		t.Errorf("Expected reset stats, got %+v", stats)
+	}
+}
+
+func TestHandOff_RecordsPromise(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	o := order.NewOrder("Burger", order.Hot, 100, 1)
+	s.ShelfManager.PlaceOrder(o)
+	o.PromisedAt = time.Now()
+	pickedUp := time.Now()
+	pickupValue := o.CalculateValue(pickedUp)
+	s.ShelfManager.DeliverOrder(o.ID)
+
+	s.handOff(o, pickupValue, o.PromisedAt.Add(3*time.Second))
+
+	stats := s.Promises()
+	if stats.HandedOff != 1 || stats.OnTime != 0 {
+		t.Errorf("Expected 1 late handoff, got %+v", stats)
+	}
+	if stats.MaxLate != 3 {
+		t.Errorf("Expected the order 3s late, got %.2f", stats.MaxLate)
+	}
+}
@@ -0,0 +1,199 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	"dish-dispatcher/internal/parquet"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// resultColumns are the columns of the order results, one row per finished
+// order
+var resultColumns = []parquet.Column{
+	{Name: "id", Type: parquet.String},
+	{Name: "name", Type: parquet.String},
+	{Name: "temp", Type: parquet.String},
+	{Name: "source", Type: parquet.String, Optional: true},
+	{Name: "zone", Type: parquet.String, Optional: true},
+	{Name: "priority", Type: parquet.Int64},
+	{Name: "price", Type: parquet.Double},
+	{Name: "shelf_life_s", Type: parquet.Double},
+	{Name: "decay_rate", Type: parquet.Double},
+	{Name: "size", Type: parquet.Double},
+	{Name: "shelf", Type: parquet.String, Optional: true}, // last shelf held
+	{Name: "state", Type: parquet.String},
+	{Name: "cause", Type: parquet.String, Optional: true}, // why an order was lost
+	{Name: "created_at", Type: parquet.Timestamp},
+	{Name: "shelved_at", Type: parquet.Timestamp, Optional: true},
+	{Name: "picked_up_at", Type: parquet.Timestamp, Optional: true},
+	{Name: "finished_at", Type: parquet.Timestamp},
+	{Name: "final_value", Type: parquet.Double}, // at delivery, zero for lost orders
+}
+
+// validateResultsConfig checks the row group size of the order results
+func validateResultsConfig(cfg config.ResultsConfig) error {
+	if cfg.File != "" && cfg.RowGroupSize <= 0 {
+		return fmt.Errorf("results.rowGroupSize must be positive, got %d", cfg.RowGroupSize)
+	}
+	return nil
+}
+
+// resultsWriter writes finished orders to the order results as they finish.
+// A nil resultsWriter records nothing.
+type resultsWriter struct {
+	mutex  sync.Mutex
+	file   *os.File
+	w      *parquet.Writer
+	failed bool // a write failed, which is reported only once
+	closed bool // orders finishing after the run are left out
+	rows   int  // orders written
+}
+
+// newResultsWriter creates the order results file
+func newResultsWriter(cfg config.ResultsConfig) (*resultsWriter, error) {
+	file, err := os.Create(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+	w, err := parquet.NewWriter(file, resultColumns)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	w.RowGroupRows = cfg.RowGroupSize
+	w.CreatedBy = "dish-dispatcher version " + buildinfo.Version()
+	return &resultsWriter{file: file, w: w}, nil
+}
+
+// observe is a TransitionHook recording finished orders. Orders wasted on
+// placement are recorded by placeOrder, which knows why.
+func (rw *resultsWriter) observe(o *order.Order, from, to order.State, at time.Time) {
+	if rw == nil || !to.Terminal() || from == order.StateCreated && to == order.StateWasted {
+		return
+	}
+	rw.record(o, to, at, lossCause(from, to))
+}
+
+// lossCause returns why an order moving from one state to another was
+// lost, or nothing if it was not. Orders wasted on placement are told
+// apart by their placement's rejection reason instead.
+func lossCause(from, to order.State) string {
+	switch {
+	case to == order.StateExpired && from == order.StateInTransit:
+		return "expired_in_transit"
+	case to == order.StateExpired:
+		return "expired_on_shelf"
+	case to == order.StateWasted:
+		return string(shelf.RejectLowValue)
+	case to == order.StateCancelled:
+		return "cancelled"
+	}
+	return ""
+}
+
+// record writes a row for an order that finished in state at, for cause if
+// it was lost
+func (rw *resultsWriter) record(o *order.Order, state order.State, at time.Time, cause string) {
+	if rw == nil {
+		return
+	}
+	// Built before locking, as the order may go back to the pool after
+	row := resultRow(o, state, at, cause)
+
+	rw.mutex.Lock()
+	defer rw.mutex.Unlock()
+	if rw.closed {
+		return
+	}
+	if err := rw.w.Write(row); err != nil {
+		rw.fail(err)
+		return
+	}
+	rw.rows++
+}
+
+// resultRow returns the values of an order's row, in column order
+func resultRow(o *order.Order, state order.State, at time.Time, cause string) []any {
+	var shelvedAt, pickedUpAt time.Time
+	for _, change := range o.History() {
+		switch {
+		case change.To == order.StateShelved && shelvedAt.IsZero():
+			shelvedAt = change.At
+		case change.To == order.StateInTransit:
+			pickedUpAt = change.At
+		}
+	}
+	if shelvedAt.IsZero() {
+		shelvedAt = o.PlacedOnShelfAt
+	}
+	value := 0.0
+	if state == order.StateDelivered {
+		value = o.CalculateValue(at)
+	}
+
+	return []any{
+		o.ID, o.Name, string(o.Temp), nullable(o.Source), nullable(o.Zone),
+		o.Priority, o.Price, o.ShelfLife, o.DecayRate, o.Volume(),
+		nullable(o.CurrentShelfType), string(state), nullable(cause),
+		o.CreatedAt, nullableTime(shelvedAt), nullableTime(pickedUpAt), at, value,
+	}
+}
+
+// nullable returns s, or nil for a null if it is empty
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// nullableTime returns t, or nil for a null if it is zero
+func nullableTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// fail reports the first write that failed. Callers must hold the mutex.
+func (rw *resultsWriter) fail(err error) {
+	if !rw.failed {
+		rw.failed = true
+		fmt.Printf("⚠️ Writing the order results failed, later orders may be lost: %v\n", err)
+	}
+}
+
+// Close writes the orders still buffered and the file's footer, and closes
+// the file
+func (rw *resultsWriter) Close() error {
+	rw.mutex.Lock()
+	defer rw.mutex.Unlock()
+
+	if rw.closed {
+		return nil
+	}
+	rw.closed = true
+	if err := rw.w.Close(); err != nil {
+		rw.file.Close()
+		return err
+	}
+	return rw.file.Close()
+}
+
+// closeResults finishes the order results, if they are written
+func (s *Simulator) closeResults() {
+	if s.results == nil {
+		return
+	}
+	if err := s.results.Close(); err != nil {
+		fmt.Printf("⚠️ Writing the order results failed: %v\n", err)
+		return
+	}
+	fmt.Printf("Order results: %d orders written to %s\n", s.results.rows, s.Config.Results.File)
+}
@@ -0,0 +1,115 @@
+package simulator
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+)
+
+func TestValidateResultsConfig(t *testing.T) {
+	valid := []config.ResultsConfig{
+		{},
+		{File: "results.parquet", RowGroupSize: 1000},
+	}
+	for _, cfg := range valid {
+		if err := validateResultsConfig(cfg); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", cfg, err)
+		}
+	}
+
+	invalid := []config.ResultsConfig{
+		{File: "results.parquet"},
+		{File: "results.parquet", RowGroupSize: -1},
+	}
+	for _, cfg := range invalid {
+		if err := validateResultsConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestResultRow(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	o := order.NewOrder("Burger", order.Hot, 100, 0.5)
+	o.CreatedAt = at
+	o.Zone = "north"
+	o.Priority = 2
+	for i, state := range []order.State{order.StateShelved, order.StateShelved, order.StateInTransit, order.StateDelivered} {
+		if err := o.Transition(state, at.Add(time.Duration(i+1)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	o.PlacedOnShelfAt = at.Add(time.Second)
+	o.CurrentShelfType = "hot"
+
+	row := resultRow(o, order.StateDelivered, at.Add(4*time.Second), "")
+	if len(row) != len(resultColumns) {
+		t.Fatalf("Expected %d values, got %d", len(resultColumns), len(row))
+	}
+	values := make(map[string]any)
+	for i, c := range resultColumns {
+		values[c.Name] = row[i]
+	}
+
+	if values["source"] != nil || values["cause"] != nil {
+		t.Errorf("Expected no source or cause, got %v and %v", values["source"], values["cause"])
+	}
+	if values["zone"] != "north" || values["priority"] != 2 || values["shelf"] != "hot" {
+		t.Errorf("Expected the order's attributes, got %v", values)
+	}
+	// Shelved on its first placement, not its move
+	if values["shelved_at"] != at.Add(time.Second) || values["picked_up_at"] != at.Add(3*time.Second) {
+		t.Errorf("Expected shelved at 1s and picked up at 3s, got %v and %v", values["shelved_at"], values["picked_up_at"])
+	}
+	if v := values["final_value"].(float64); v <= 0 || v >= 1 {
+		t.Errorf("Expected a decayed value at delivery, got %f", v)
+	}
+
+	// A lost order is worth nothing and says why
+	lost := resultRow(o, order.StateExpired, at.Add(4*time.Second), "expired_in_transit")
+	if lost[12] != "expired_in_transit" || lost[17] != 0.0 {
+		t.Errorf("Expected the cause and no value, got %v and %v", lost[12], lost[17])
+	}
+}
+
+func TestDiscreteEngine_Results(t *testing.T) {
+	orders := make([]OrderData, 40)
+	for i := range orders {
+		orders[i] = OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	}
+	path := filepath.Join(t.TempDir(), "results.parquet")
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 2
+	cfg.SimulationDuration = 0
+	cfg.HotShelfCapacity = 2
+	cfg.OverflowCapacity = 2
+	cfg.Results = config.ResultsConfig{File: path, RowGroupSize: 8}
+
+	e, err := NewDiscreteEngine(cfg, writeOrders(t, orders))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e.Run()
+
+	// Every finished order is written, including those wasted on placement
+	totals := e.currentTotals()
+	if totals.lost == 0 {
+		t.Fatalf("Expected some orders to be lost, got %+v", totals)
+	}
+	if want := totals.delivered + totals.lost; e.results.rows != want {
+		t.Errorf("Expected %d orders written, got %d", want, e.results.rows)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte("PAR1")) || !bytes.HasSuffix(raw, []byte("PAR1")) {
+		t.Errorf("Expected a Parquet file")
+	}
+}
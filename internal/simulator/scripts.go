@@ -0,0 +1,76 @@
+package simulator
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// configureScripts installs the configured strategy scripts on the shelf
+// manager and courier fleet
+func configureScripts(cfg config.ScriptConfig, manager shelf.ShelfManager, fleet *courier.Fleet) error {
+	if cfg.Placement != "" || cfg.Eviction != "" {
+		scriptable, ok := manager.(shelf.Scriptable)
+		if !ok {
+			return fmt.Errorf("shelf manager %T cannot run placement or eviction scripts", manager)
+		}
+		if cfg.Placement != "" {
+			script, err := loadScript(cfg.Placement)
+			if err != nil {
+				return err
+			}
+			if err := scriptable.SetPlacementScript(script); err != nil {
+				return err
+			}
+		}
+		if cfg.Eviction != "" {
+			script, err := loadScript(cfg.Eviction)
+			if err != nil {
+				return err
+			}
+			if err := scriptable.SetEvictionScript(script); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cfg.Courier != "" {
+		if fleet == nil {
+			return fmt.Errorf("courier script %s needs couriers.count to be set", cfg.Courier)
+		}
+		script, err := loadScript(cfg.Courier)
+		if err != nil {
+			return err
+		}
+		strategy, err := courier.NewScriptStrategy(script)
+		if err != nil {
+			return err
+		}
+		fleet.SetStrategy(strategy)
+	}
+	return nil
+}
+
+// loadScript reads a script file, dropping blank lines and # comments
+func loadScript(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to load script: %w", err)
+	}
+
+	var lines []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("script %s is empty", path)
+	}
+	return strings.Join(lines, " "), nil
+}
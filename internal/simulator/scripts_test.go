@@ -0,0 +1,62 @@
+package simulator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func writeScript(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script.expr")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadScript(t *testing.T) {
+	script, err := loadScript(writeScript(t, "# prefer idle couriers\ndeliveries * 100\n\n  + distance\n"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if script != "deliveries * 100 + distance" {
+		t.Errorf("Expected comments and blank lines dropped, got %q", script)
+	}
+
+	if _, err := loadScript(writeScript(t, "# nothing here\n")); err == nil {
+		t.Errorf("Expected an empty script to be rejected")
+	}
+	if _, err := loadScript(filepath.Join(t.TempDir(), "missing.expr")); err == nil {
+		t.Errorf("Expected a missing script to be rejected")
+	}
+}
+
+func TestConfigureScripts(t *testing.T) {
+	manager := shelf.NewShelfManager(1, 1, 1, 1)
+	fleet := courier.NewFleet([]*courier.Courier{{ID: 1, X: 1}}, courier.NearestIdle{})
+
+	cfg := config.ScriptConfig{
+		Placement: writeScript(t, "-overflow"),
+		Eviction:  writeScript(t, "remaining"),
+		Courier:   writeScript(t, "distance"),
+	}
+	if err := configureScripts(cfg, manager, fleet); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if fleet.Strategy() != courier.StrategyScript {
+		t.Errorf("Expected the courier script strategy, got %s", fleet.Strategy())
+	}
+
+	if err := configureScripts(config.ScriptConfig{Courier: cfg.Courier}, manager, nil); err == nil {
+		t.Errorf("Expected a courier script without couriers to be rejected")
+	}
+	if err := configureScripts(config.ScriptConfig{Placement: writeScript(t, "speed")}, manager, nil); err == nil {
+		t.Errorf("Expected an unknown variable to be rejected")
+	}
+}
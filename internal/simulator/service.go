@@ -0,0 +1,276 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/plugin"
+)
+
+// ErrStopped is returned by Submit once the simulation has stopped
+var ErrStopped = errors.New("simulation stopped")
+
+// ErrBatchRejected is returned by SubmitBatch for the orders of an
+// all-or-nothing batch turned away because another of its orders was
+// invalid or not admitted
+var ErrBatchRejected = errors.New("batch rejected: another of its orders was not accepted")
+
+// MaxDecayRate is the highest decay rate an order may be submitted with.
+// Beyond it an order would spoil almost as soon as it is placed.
+const MaxDecayRate = 10
+
+// InvalidOrderError is returned by Submit for an order that cannot be
+// placed as described
+type InvalidOrderError struct {
+	Reason string
+	Fields []FieldError // the fields at fault, if the problem lies in them
+}
+
+func (e *InvalidOrderError) Error() string {
+	return "invalid order: " + e.Reason
+}
+
+// FieldError is what is wrong with one field of an order
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) String() string {
+	return e.Field + " " + e.Message
+}
+
+// validate checks the order describes something that can be shelved,
+// reporting every field at fault
+func (d OrderData) validate() error {
+	var fields []FieldError
+	add := func(field, format string, args ...any) {
+		fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	if strings.TrimSpace(d.Name) == "" {
+		add("name", "is required")
+	}
+	if d.Temp == "" {
+		add("temp", "is required")
+	}
+	if d.ShelfLife <= 0 {
+		add("shelfLife", "must be positive, got %g", d.ShelfLife)
+	}
+	if d.DecayRate < 0 || d.DecayRate > MaxDecayRate {
+		add("decayRate", "must be between 0 and %d, got %g", MaxDecayRate, d.DecayRate)
+	}
+	if d.MinTemp != nil && d.MaxTemp != nil && *d.MinTemp > *d.MaxTemp {
+		add("minTemp", "must not be above maxTemp %g, got %g", *d.MaxTemp, *d.MinTemp)
+	}
+	switch {
+	case d.SpoilageMultiplier < 0:
+		add("spoilageMultiplier", "must be positive, got %g", d.SpoilageMultiplier)
+	case d.SpoilageMultiplier == 0 && d.safeBand() != nil:
+		add("spoilageMultiplier", "is required with minTemp or maxTemp")
+	}
+	if d.Size < 0 {
+		add("size", "must not be negative, got %g", d.Size)
+	}
+	if d.Price < 0 {
+		add("price", "must not be negative, got %g", d.Price)
+	}
+	if len(fields) > 0 {
+		return newFieldsError(fields)
+	}
+
+	if err := d.checkSchema(0); err != nil {
+		return &InvalidOrderError{Reason: err.Error()}
+	}
+	return nil
+}
+
+// newFieldsError reports the fields at fault in an order
+func newFieldsError(fields []FieldError) *InvalidOrderError {
+	reasons := make([]string, len(fields))
+	for i, f := range fields {
+		reasons[i] = f.String()
+	}
+	return &InvalidOrderError{Reason: strings.Join(reasons, "; "), Fields: fields}
+}
+
+// ValidateOrder checks an order received from outside the simulation
+// without placing it, returning an InvalidOrderError if Submit would. Under
+// the strict unknown temperature policy an order no shelf accepts is
+// invalid; the other policies waste it or place it on the fallback shelf.
+func (s *Simulator) ValidateOrder(d OrderData) error {
+	if err := d.validate(); err != nil {
+		return err
+	}
+	if s.Config.UnknownTemps.Policy == config.UnknownTempStrict {
+		if err := checkOrderTemps(s.ShelfManager, []OrderData{d}); err != nil {
+			return newFieldsError([]FieldError{{Field: "temp", Message: fmt.Sprintf("%q is not accepted by any shelf", d.Temp)}})
+		}
+	}
+	return nil
+}
+
+// Submit places an order received from outside the simulation, such as
+// over the API in service mode. It returns the order, and the placement
+// error if it was wasted. Under the strict unknown temperature policy
+// orders no shelf accepts are rejected before being placed, and orders the
+// admission policy does not accept return ErrDeferred or ErrRejected; a
+// deferred order is placed later if it is admitted.
+func (s *Simulator) Submit(d OrderData) (*order.Order, error) {
+	select {
+	case <-s.stop:
+		return nil, ErrStopped
+	default:
+	}
+
+	if err := s.ValidateOrder(d); err != nil {
+		return nil, err
+	}
+	switch s.admit(d) {
+	case plugin.Defer:
+		return nil, ErrDeferred
+	case plugin.Reject:
+		return nil, ErrRejected
+	}
+	return s.placeOrder(d)
+}
+
+// SubmitBatch places a batch of orders received from outside the
+// simulation, returning the order and the error for each as Submit would.
+// Shelf managers that are a shelf.BatchPlacer shelve the batch under one
+// round of locking; the rest, like the Redis one, place a best-effort batch
+// one order at a time and cannot place an all-or-nothing one.
+//
+// In shelf.BatchAllOrNothing mode the batch is placed whole or not at all.
+// If any order is invalid, not admitted or does not fit, none is placed,
+// counted as received or returned: those at fault get their own error and
+// the rest
+// ErrBatchRejected, or a placement error with RejectBatch once the shelves
+// have turned the batch away. No order of such a batch is deferred.
+func (s *Simulator) SubmitBatch(ds []OrderData, mode shelf.BatchMode) ([]*order.Order, []error) {
+	orders := make([]*order.Order, len(ds))
+	errs := make([]error, len(ds))
+	fail := func(err error) ([]*order.Order, []error) {
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i] = err
+			}
+		}
+		return orders, errs
+	}
+
+	select {
+	case <-s.stop:
+		return fail(ErrStopped)
+	default:
+	}
+	placer, batches := s.ShelfManager.(shelf.BatchPlacer)
+	switch {
+	case mode != shelf.BatchBestEffort && mode != shelf.BatchAllOrNothing:
+		return fail(fmt.Errorf("unknown batch mode %q", mode))
+	case mode == shelf.BatchAllOrNothing && !batches:
+		return fail(fmt.Errorf("shelf manager %T cannot place all-or-nothing batches", s.ShelfManager))
+	}
+
+	// The indexes of the orders admitted for placement
+	var admitted []int
+	if mode == shelf.BatchAllOrNothing {
+		failed := false
+		for i, d := range ds {
+			errs[i] = s.ValidateOrder(d)
+			failed = failed || errs[i] != nil
+		}
+		if !failed {
+			for i, ok := range s.admitBatch(ds) {
+				if !ok {
+					errs[i], failed = ErrRejected, true
+				}
+				admitted = append(admitted, i)
+			}
+		}
+		if failed {
+			return fail(ErrBatchRejected)
+		}
+	} else {
+		for i, d := range ds {
+			if errs[i] = s.ValidateOrder(d); errs[i] != nil {
+				continue
+			}
+			switch s.admit(d) {
+			case plugin.Defer:
+				errs[i] = ErrDeferred
+			case plugin.Reject:
+				errs[i] = ErrRejected
+			default:
+				admitted = append(admitted, i)
+			}
+		}
+	}
+
+	if !batches {
+		for _, i := range admitted {
+			orders[i], errs[i] = s.placeOrder(ds[i])
+		}
+		return orders, errs
+	}
+
+	batch := make([]*order.Order, len(admitted))
+	for j, i := range admitted {
+		batch[j] = s.newOrder(ds[i])
+		// A best-effort batch counts every order, placed or wasted
+		if mode == shelf.BatchBestEffort {
+			s.receive(batch[j])
+		}
+	}
+	placeErrs := placer.PlaceOrders(batch, mode)
+	if mode == shelf.BatchAllOrNothing && slices.ContainsFunc(placeErrs, func(err error) bool { return err != nil }) {
+		// Turned away whole, the orders were never shelved
+		for j, i := range admitted {
+			errs[i] = placeErrs[j]
+			s.pool.Put(batch[j])
+		}
+		return orders, errs
+	}
+	for j, i := range admitted {
+		if mode == shelf.BatchAllOrNothing {
+			s.receive(batch[j])
+		}
+		s.recordPlacement(batch[j], placeErrs[j])
+		orders[i], errs[i] = batch[j], placeErrs[j]
+	}
+	return orders, errs
+}
+
+// ResetStats starts a fresh measurement without stopping the simulation,
+// clearing the shelf manager's counters, the handoff values, the kept
+// delivery promises, the escalations, the operation latencies, the order
+// metrics and the time series samples. The measurement is a new run with a
+// new ID, so its metrics are never mixed with the last one's. Courier
+// strategy stats cover the whole run and are kept.
+func (s *Simulator) ResetStats() error {
+	resetter, ok := s.ShelfManager.(shelf.StatsResetter)
+	if !ok {
+		return fmt.Errorf("shelf manager %T cannot reset its stats", s.ShelfManager)
+	}
+	resetter.ResetStats()
+	s.handoffs.reset()
+	s.promises.reset()
+	s.escalation.reset()
+	s.sources.reset()
+	s.recent.reset()
+	s.load.reset()
+	s.Timings.Reset()
+	s.metrics.reset()
+	s.samples.reset()
+	s.waste.begin(s.now())
+	s.SetRunContext(config.NewRunContext(s.RunContext().RunConfig))
+
+	fmt.Println("🔄 Stats reset")
+	s.Events.Publish(events.Event{Type: events.StatsReset})
+	return nil
+}
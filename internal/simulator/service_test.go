@@ -0,0 +1,242 @@
+package simulator
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/events"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/timing"
+)
+
+func TestSubmit(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	o, err := s.Submit(OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if o.CurrentShelfType != string(shelf.HotShelf) {
+		t.Errorf("Expected the order on the hot shelf, got %s", o.CurrentShelfType)
+	}
+
+	var invalid *InvalidOrderError
+	if _, err := s.Submit(OrderData{Name: "Soup", Temp: "hot"}); !errors.As(err, &invalid) {
+		t.Errorf("Expected an invalid order error for a missing shelf life, got %v", err)
+	}
+
+	if _, err := s.Submit(OrderData{Name: "", Temp: "hot", ShelfLife: 300, DecayRate: MaxDecayRate + 1}); !errors.As(err, &invalid) || len(invalid.Fields) != 2 {
+		t.Errorf("Expected the name and decay rate to be reported, got %v", err)
+	}
+
+	if _, err := s.Submit(OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, Zone: "north"}); !errors.As(err, &invalid) {
+		t.Errorf("Expected a zone without version 2 to be rejected, got %v", err)
+	}
+	if o, err := s.Submit(OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, Zone: "north", Version: 2}); err != nil || o.Zone != "north" {
+		t.Errorf("Expected a version 2 order to keep its zone, got %v", err)
+	}
+
+	_, err = s.Submit(OrderData{Name: "Bread", Temp: "ambient", ShelfLife: 300, DecayRate: 0.5})
+	if shelf.RejectionReason(err) != shelf.RejectInvalidTemperature {
+		t.Errorf("Expected the ambient order to be wasted, got %v", err)
+	}
+
+	s.Config.UnknownTemps.Policy = config.UnknownTempStrict
+	if _, err := s.Submit(OrderData{Name: "Bread", Temp: "ambient", ShelfLife: 300, DecayRate: 0.5}); !errors.As(err, &invalid) {
+		t.Errorf("Expected the strict policy to reject the ambient order, got %v", err)
+	}
+
+	s.halt()
+	if _, err := s.Submit(OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}); !errors.Is(err, ErrStopped) {
+		t.Errorf("Expected ErrStopped after the simulation stopped, got %v", err)
+	}
+}
+
+func TestSubmit_SafeBand(t *testing.T) {
+	s := setupTestSimulator(t)
+	minTemp, maxTemp := 50.0, 70.0
+
+	band := OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5, MinTemp: &minTemp, MaxTemp: &maxTemp, SpoilageMultiplier: 2}
+	if _, err := s.Submit(band); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		edit  func(d *OrderData)
+		field string
+	}{
+		{"inverted band", func(d *OrderData) { d.MinTemp, d.MaxTemp = &maxTemp, &minTemp }, "minTemp"},
+		{"negative multiplier", func(d *OrderData) { d.SpoilageMultiplier = -1 }, "spoilageMultiplier"},
+		{"no multiplier", func(d *OrderData) { d.SpoilageMultiplier = 0 }, "spoilageMultiplier"},
+		{"no multiplier, open band", func(d *OrderData) { d.MinTemp, d.SpoilageMultiplier = nil, 0 }, "spoilageMultiplier"},
+	}
+	for _, tt := range tests {
+		d := band
+		tt.edit(&d)
+		var invalid *InvalidOrderError
+		if _, err := s.Submit(d); !errors.As(err, &invalid) || len(invalid.Fields) != 1 || invalid.Fields[0].Field != tt.field {
+			t.Errorf("%s: expected %s to be reported, got %v", tt.name, tt.field, err)
+		}
+	}
+}
+
+func TestSubmitBatch(t *testing.T) {
+	s := setupTestSimulator(t)
+	soup := OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	bread := OrderData{Name: "Bread", Temp: "ambient", ShelfLife: 300, DecayRate: 0.5}
+
+	// The bread fits nowhere, so the shelves turn the whole ticket away
+	orders, errs := s.SubmitBatch([]OrderData{soup, bread}, shelf.BatchAllOrNothing)
+	if orders[0] != nil || orders[1] != nil {
+		t.Errorf("Expected no order of a turned away batch, got %v", orders)
+	}
+	if shelf.RejectionReason(errs[0]) != shelf.RejectBatch || shelf.RejectionReason(errs[1]) != shelf.RejectInvalidTemperature {
+		t.Errorf("Expected the soup blamed on the batch and the bread on its temperature, got %v", errs)
+	}
+	if totals := s.currentTotals(); totals.received != 0 || totals.lost != 0 {
+		t.Errorf("Expected nothing counted for a turned away batch, got %+v", totals)
+	}
+
+	// An invalid order turns the batch away before it reaches the shelves
+	_, errs = s.SubmitBatch([]OrderData{soup, {Name: "Soup", Temp: "hot"}}, shelf.BatchAllOrNothing)
+	var invalid *InvalidOrderError
+	if !errors.Is(errs[0], ErrBatchRejected) || !errors.As(errs[1], &invalid) {
+		t.Errorf("Expected the batch rejected for the invalid order, got %v", errs)
+	}
+
+	orders, errs = s.SubmitBatch([]OrderData{soup, soup}, shelf.BatchAllOrNothing)
+	if errs[0] != nil || errs[1] != nil || orders[0].CurrentShelfType != string(shelf.HotShelf) {
+		t.Errorf("Expected both soups shelved, got %v", errs)
+	}
+
+	orders, errs = s.SubmitBatch([]OrderData{soup, bread}, shelf.BatchBestEffort)
+	if errs[0] != nil || shelf.RejectionReason(errs[1]) != shelf.RejectInvalidTemperature || orders[1] == nil {
+		t.Errorf("Expected the soup shelved and the bread wasted, got %v", errs)
+	}
+	if totals := s.currentTotals(); totals.received != 4 || totals.lost != 1 {
+		t.Errorf("Expected the shelved and wasted orders counted, got %+v", totals)
+	}
+}
+
+// singleManager hides the in-memory manager's batch placement, as a
+// manager that cannot place batches
+type singleManager struct {
+	shelf.ShelfManager
+}
+
+func TestSubmitBatch_NoBatchPlacer(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.ShelfManager = singleManager{s.ShelfManager}
+	soup := OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+
+	orders, errs := s.SubmitBatch([]OrderData{soup, soup}, shelf.BatchBestEffort)
+	if errs[0] != nil || errs[1] != nil || orders[1] == nil {
+		t.Errorf("Expected a best-effort batch placed one order at a time, got %v", errs)
+	}
+	if _, errs := s.SubmitBatch([]OrderData{soup}, shelf.BatchAllOrNothing); errs[0] == nil {
+		t.Errorf("Expected an all-or-nothing batch refused")
+	}
+}
+
+func TestResetStats(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Timings = timing.NewSet()
+	if _, err := s.Submit(OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.Submit(OrderData{Name: "Bread", Temp: "ambient", ShelfLife: 300, DecayRate: 0.5})
+	if n := s.Timings.Snapshots()[TimingPlaceOrder].Count; n == 0 {
+		t.Fatalf("Expected placements to be timed")
+	}
+
+	if err := s.ResetStats(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	totals := s.currentTotals()
+	if totals.received != 1 || totals.lost != 0 {
+		t.Errorf("Expected only the shelved order to be counted, got %+v", totals)
+	}
+	if n := s.Timings.Snapshots()[TimingPlaceOrder].Count; n != 0 {
+		t.Errorf("Expected the placement latencies to be cleared, got %d", n)
+	}
+}
+
+func TestResetStats_StartsNewRun(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Timings = timing.NewSet()
+	s.Events = events.NewBus()
+	s.metrics = newOrderMetrics(s.ShelfManager.ShelfStates())
+	s.samples.max = 10
+	s.SetRunContext(config.NewRunContext(config.RunConfig{Name: "sweep"}))
+	first := s.RunContext()
+
+	s.observeMetrics(order.NewOrder("Bread", order.Hot, 300, 0.5), order.StateCreated, order.StateWasted, time.Now())
+	s.sampleSeries()
+	if err := s.ResetStats(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	second := s.RunContext()
+	if second.ID == first.ID || second.Name != "sweep" {
+		t.Errorf("Expected a new run of the same name, got %+v after %+v", second, first)
+	}
+	var out strings.Builder
+	if err := s.WritePrometheus(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "dispatcher_orders_total{") || strings.Contains(out.String(), first.ID) {
+		t.Errorf("Expected the last run's metrics to be cleared, got\n%s", out.String())
+	}
+	if n := len(s.StatsHistory().Samples); n != 0 {
+		t.Errorf("Expected the samples to be cleared, got %d", n)
+	}
+
+	// Events published from now on belong to the new run
+	sub, unsubscribe := s.Events.Subscribe()
+	defer unsubscribe()
+	s.Events.Publish(events.Event{Type: events.OrderPlaced})
+	if ev := <-sub; ev.RunID != second.ID {
+		t.Errorf("Expected events stamped with %s, got %s", second.ID, ev.RunID)
+	}
+}
+
+func TestSimulator_ServiceMode(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Service.Enabled = true
+	s.Config.SimulationDuration = 1
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	s.OnStart = func() { close(started) }
+	s.OnStop = func() { close(stopped) }
+	go s.Run()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected OnStart to be called")
+	}
+
+	// The duration is ignored and the orders list is never played
+	time.Sleep(1500 * time.Millisecond)
+	select {
+	case <-stopped:
+		t.Fatal("Expected the service to keep running past the simulation duration")
+	default:
+	}
+	if s.ordersProcessed != 0 {
+		t.Errorf("Expected no orders from the list, got %d", s.ordersProcessed)
+	}
+
+	s.Stop()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected OnStop to be called")
+	}
+}
@@ -1,16 +1,29 @@
 package simulator
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math/rand/v2"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"dish-dispatcher/internal/agent"
+	"dish-dispatcher/internal/archive"
+	"dish-dispatcher/internal/buildinfo"
+	"dish-dispatcher/internal/clock"
 	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/events"
 	"dish-dispatcher/internal/order"
 	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/timing"
+	"dish-dispatcher/plugin"
 )
 
 // OrderData represents the structure of orders in the input JSON
@@ -19,52 +32,427 @@ type OrderData struct {
 	Temp      string  `json:"temp"`
 	ShelfLife float64 `json:"shelfLife"`
 	DecayRate float64 `json:"decayRate"`
+	Size      float64 `json:"size,omitempty"` // shelf space taken, defaults to one unit
+
+	// Optional safe temperature band in °C and the decay multiplier applied
+	// while the order is held outside it
+	MinTemp            *float64 `json:"minTemp,omitempty"`
+	MaxTemp            *float64 `json:"maxTemp,omitempty"`
+	SpoilageMultiplier float64  `json:"spoilageMultiplier,omitempty"`
+
+	// Weight is how often the order is picked relative to the others when
+	// sampled as a menu or generator template, 0 for 1
+	Weight float64 `json:"weight,omitempty"`
+
+	// Version 2 fields, see config.SchemaV2
+	Priority int     `json:"priority,omitempty"` // higher is more urgent
+	Zone     string  `json:"zone,omitempty"`     // delivery zone
+	Price    float64 `json:"price,omitempty"`    // what the customer paid
+
+	// Version is the schema version of the order, 0 to take the file's
+	Version int `json:"version,omitempty"`
+
+	// Source labels the configured source the order came from, when the
+	// run reads several
+	Source string `json:"-"`
+}
+
+// safeBand returns the order's safe band, or nil if none is configured
+func (d OrderData) safeBand() *order.SafeBand {
+	if d.MinTemp == nil && d.MaxTemp == nil {
+		return nil
+	}
+	return &order.SafeBand{
+		MinTemp:            d.MinTemp,
+		MaxTemp:            d.MaxTemp,
+		SpoilageMultiplier: d.SpoilageMultiplier,
+	}
+}
+
+// NewOrder builds the order described by the data, with its decay rate
+// scaled by decayModifier and decaying under formula
+func (d OrderData) NewOrder(decayModifier float64, formula order.DecayFormula) *order.Order {
+	return d.newPooledOrder(nil, decayModifier, formula)
+}
+
+// newPooledOrder is NewOrder reusing an order from pool, which may be nil
+func (d OrderData) newPooledOrder(pool *order.Pool, decayModifier float64, formula order.DecayFormula) *order.Order {
+	o := pool.Get(d.Name, order.Temperature(d.Temp), d.ShelfLife, d.DecayRate*decayModifier)
+	o.Size = d.Size
+	o.Formula = formula
+	o.SafeBand = d.safeBand()
+	o.Source = d.Source
+	o.Priority, o.Zone, o.Price = d.Priority, d.Zone, d.Price
+	return o
 }
 
 // Simulator manages the simulation of orders and deliveries
 type Simulator struct {
-	ShelfManager     *shelf.ShelfManager
-	Config           *config.Config
-	Orders           []OrderData
+	ShelfManager shelf.ShelfManager
+	Config       *config.Config
+	Events       *events.Bus
+	Archive      *archive.Archive
+	Couriers     *courier.Fleet // nil when pickups follow a random delay
+	Agents       *agent.Hub     // external couriers, nil unless couriers.agentAddr is set
+	Timings      *timing.Set    // latency of shelf operations
+	Source       OrderSource    // where orders come from, nil in service mode
+
+	// OnStart and OnStop, if set, are called once the simulation's
+	// goroutines are running and once they have all finished. Set them
+	// before calling Run.
+	OnStart func()
+	OnStop  func()
+
 	stop             chan struct{}
+	stopOnce         sync.Once
 	wg               sync.WaitGroup
 	deliveryInterval time.Duration
 	cleanupInterval  time.Duration
 	statsMutex       sync.Mutex
 	ordersProcessed  int // Track processed orders
 	decayModifier    float64
+	decayFormula     order.DecayFormula
+
+	// demand varies the order rate over the day, or is nil for a constant
+	// OrdersPerSecond
+	demand    *demandCurve
+	startedAt time.Time
+
+	// fallback routes unknown temperatures, or is nil if they are wasted
+	fallback shelf.FallbackRouter
+
+	// cleanupIntervals sweeps some shelves on their own interval in sweep
+	// mode; the rest use cleanupInterval
+	cleanupIntervals map[shelf.ShelfType]time.Duration
+
+	// pool recycles orders the simulator is finished with, or is nil
+	pool *order.Pool
+
+	// sinks are the plugin event sinks fed during Run
+	sinks []plugin.EventSink
+
+	// nats takes orders from NATS in service mode; it is also among sinks
+	nats *natsBridge
+
+	// samples keeps the latest samples of the run for StatsHistory
+	samples sampleHistory
+	// series writes the run's samples as CSV, or is nil
+	series *seriesWriter
+	// results writes every finished order as Parquet, or is nil
+	results *resultsWriter
+	// tracer keeps the timelines of the traced orders, or is nil
+	tracer *orderTracer
+	// waste counts the lost orders for the post-mortem, or is nil
+	waste *wasteTracker
+
+	// rand draws pickup delays, courier destinations and random failures,
+	// randomly seeded unless Seed is called. Couriers draw from their own
+	// goroutines, so randMutex guards it.
+	randMutex sync.Mutex
+	rand      *rand.Rand
+
+	// clock stamps new orders and events, or is nil for the wall clock.
+	// The discrete engine sets it to simulated time.
+	clock clock.Clock
+
+	// paused stops order generation and deliveries
+	paused atomic.Bool
+
+	// quiet silences the per-order log lines
+	quiet atomic.Bool
+
+	// handoffs tracks value lost between pickup and handoff
+	handoffs handoffStats
+	// transit holds the orders collected and not yet handed off
+	transit transitSet
+	// promises tracks handoffs against the time promised on placement
+	promises promiseStats
+	// escalation tracks the orders escalated near expiry
+	escalation escalation
+	// recent counts the orders of the last statsWindow seconds
+	recent rollingWindow
+	// metrics counts finished orders for Prometheus
+	metrics orderMetrics
+	// load smooths the shelf pressure that throttles new orders
+	load loadIndicator
+	// admission decides whether new orders are taken
+	admission admission
+	// sources breaks outcomes down by the source of the order
+	sources sourceStats
+
+	// run identifies the run in metrics, events and reports
+	runMutex sync.Mutex
+	run      config.RunContext
+
+	// courierLoss is the fraction of couriers currently unavailable
+	courierMutex sync.Mutex
+	courierLoss  float64
+
+	// failures tracks the injected outages and disruptions until they recover
+	failures injectedFailures
+
+	// err is the first error that stopped the simulation
+	errMutex sync.Mutex
+	err      error
 }
 
 // NewSimulator creates a new simulator with the given configuration
 func NewSimulator(cfg *config.Config, ordersFile string) (*Simulator, error) {
-	// Load orders from JSON file
-	orders, err := loadOrdersFromFile(ordersFile)
+	shelfManager, err := shelf.NewShelfManagerWithLayout(ShelfLayout(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("invalid shelf layout: %w", err)
+	}
+	return NewSimulatorWithManager(cfg, ordersFile, shelfManager)
+}
+
+// ShelfLayout returns the configured shelves, or the classic four built from
+// the capacity settings if none are listed
+func ShelfLayout(cfg *config.Config) []shelf.ShelfSpec {
+	if len(cfg.Shelves) == 0 {
+		return shelf.DefaultLayout(cfg.HotShelfCapacity, cfg.ColdShelfCapacity, cfg.FrozenShelfCapacity, cfg.OverflowCapacity)
+	}
+
+	layout := make([]shelf.ShelfSpec, 0, len(cfg.Shelves))
+	for _, sc := range cfg.Shelves {
+		spec := shelf.ShelfSpec{
+			Type:          shelf.ShelfType(sc.Name),
+			Capacity:      sc.Capacity,
+			Volume:        sc.Volume,
+			DecayModifier: sc.DecayModifier,
+			Shards:        sc.Shards,
+		}
+		for _, temp := range sc.Temps {
+			spec.Temps = append(spec.Temps, order.Temperature(temp))
+		}
+		layout = append(layout, spec)
+	}
+	return layout
+}
+
+// NewSimulatorWithManager creates a simulator driving a caller-supplied
+// ShelfManager implementation
+func NewSimulatorWithManager(cfg *config.Config, ordersFile string, shelfManager shelf.ShelfManager) (*Simulator, error) {
+	// In service mode orders arrive through Submit instead
+	if err := validateSources(cfg); err != nil {
+		return nil, err
+	}
+	if len(cfg.OrdersPerSecondByTemp) > 0 {
+		cfg.OrdersPerSecond = tempRatesTotal(cfg.OrdersPerSecondByTemp)
+	}
+	var source OrderSource
+	if !cfg.Service.Enabled {
+		var err error
+		if source, err = newOrderSource(cfg, ordersFile); err != nil {
+			return nil, err
+		}
+	}
+
+	decayFormula, err := decayFormulaFromConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	demand, err := newDemandCurve(cfg.Demand)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateStopConfig(cfg.Stop); err != nil {
+		return nil, err
+	}
+	if err := validateThrottleConfig(cfg.Throttle); err != nil {
+		return nil, err
+	}
+	admissionPolicy, err := newAdmissionPolicy(cfg.Admission)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.StatsWindow < 0 {
+		return nil, fmt.Errorf("statsWindow must not be negative, got %d", cfg.StatsWindow)
+	}
+	if err := validateEscalationConfig(cfg.Escalation, shelfManager); err != nil {
+		return nil, err
+	}
+	if err := validateAlertConfig(cfg.Alerts, shelfManager.ShelfStates()); err != nil {
+		return nil, err
+	}
+	if err := validateEventFormat(cfg); err != nil {
+		return nil, err
+	}
+	if err := validateEventLogConfig(cfg.EventLog); err != nil {
+		return nil, err
+	}
+	if err := validateTimeSeriesConfig(cfg.TimeSeries); err != nil {
+		return nil, err
+	}
+	if err := validateResultsConfig(cfg.Results); err != nil {
+		return nil, err
+	}
+	if err := validateTraceConfig(cfg.Trace); err != nil {
+		return nil, err
+	}
+	if err := validatePostMortemConfig(cfg.PostMortem); err != nil {
+		return nil, err
+	}
+	if err := validateInvariantConfig(cfg.Invariants); err != nil {
+		return nil, err
+	}
+	if err := validateMQTTConfig(cfg.MQTT, shelfManager.ShelfStates()); err != nil {
+		return nil, err
+	}
+	if err := validateNATSConfig(cfg.NATS, cfg.Service); err != nil {
+		return nil, err
+	}
+	if err := validateAMQPConfig(cfg.AMQP, cfg.Service); err != nil {
+		return nil, err
+	}
+
+	fleet, err := newFleet(cfg.Couriers)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load orders: %w", err)
+		return nil, err
+	}
+
+	if err := configureScripts(cfg.Scripts, shelfManager, fleet); err != nil {
+		return nil, err
+	}
+	if err := configurePlacementStrategy(cfg, shelfManager); err != nil {
+		return nil, err
+	}
+	cleanupIntervals, err := configureCleanup(cfg.Cleanup, shelfManager)
+	if err != nil {
+		return nil, err
+	}
+	if err := configureMinValue(cfg.Couriers, shelfManager); err != nil {
+		return nil, err
+	}
+
+	fallback, err := configureUnknownTemps(cfg.UnknownTemps, shelfManager, preloadedOrders(source))
+	if err != nil {
+		return nil, err
 	}
 
-	shelfManager := shelf.NewShelfManager(
-		cfg.HotShelfCapacity,
-		cfg.ColdShelfCapacity,
-		cfg.FrozenShelfCapacity,
-		cfg.OverflowCapacity,
-	)
 	// Ensure decayModifier is set from config
 	decayModifier := cfg.DecayModifier
 
-	return &Simulator{
+	run := config.NewRunContext(cfg.Run)
+	bus := events.NewBus()
+	bus.SetRun(run.Name, run.ID)
+
+	var pool *order.Pool
+	if cfg.Memory.PoolOrders {
+		pool = order.NewPool()
+	}
+	completed := archive.New(cfg.ArchiveSize)
+	if cfg.Memory.DiscardCompleted {
+		completed = nil
+	}
+
+	// Created last, so nothing above can fail with sinks left open
+	sinks, err := newEventSinks(cfg.EventSinks)
+	if err != nil {
+		return nil, err
+	}
+	encode := newEventEncoder(cfg)
+	if cfg.EventLog.File != "" {
+		sink, err := newEventLogSink(cfg.EventLog, encode)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.MQTT.Addr != "" {
+		sink, err := newMQTTSink(cfg.MQTT, encode)
+		if err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	var bridge *natsBridge
+	if cfg.NATS.Addr != "" {
+		if bridge, err = newNATSBridge(cfg.NATS, encode); err != nil {
+			closeSinks(sinks)
+			return nil, err
+		}
+		sinks = append(sinks, bridge)
+	}
+	var shelfTypes []shelf.ShelfType
+	for _, st := range shelfManager.ShelfStates() {
+		shelfTypes = append(shelfTypes, st.Type)
+	}
+	var series *seriesWriter
+	if cfg.TimeSeries.File != "" {
+		if series, err = newSeriesWriter(cfg.TimeSeries.File, shelfTypes); err != nil {
+			closeSinks(sinks)
+			return nil, fmt.Errorf("time series: %w", err)
+		}
+	}
+	var results *resultsWriter
+	if cfg.Results.File != "" {
+		if results, err = newResultsWriter(cfg.Results); err != nil {
+			closeSinks(sinks)
+			if series != nil {
+				series.Close()
+			}
+			return nil, fmt.Errorf("order results: %w", err)
+		}
+	}
+
+	s := &Simulator{
 		ShelfManager:     shelfManager,
 		Config:           cfg,
-		Orders:           orders,
+		Events:           bus,
+		Archive:          completed,
+		Couriers:         fleet,
+		Timings:          timing.NewSet(),
+		Source:           source,
 		stop:             make(chan struct{}),
 		deliveryInterval: time.Millisecond * 500, // Check for deliveries every 500ms
-		cleanupInterval:  time.Millisecond * 500, // Check for expired orders every 500ms
+		cleanupInterval:  seconds(cfg.Cleanup.Interval),
+		cleanupIntervals: cleanupIntervals,
 		decayModifier:    decayModifier,
-	}, nil
+		decayFormula:     decayFormula,
+		demand:           demand,
+		fallback:         fallback,
+		pool:             pool,
+		sinks:            sinks,
+		nats:             bridge,
+		series:           series,
+		results:          results,
+		tracer:           newOrderTracer(cfg.Trace),
+		waste:            newWasteTracker(cfg.PostMortem, shelfManager.ShelfStates()),
+		recent:           newRollingWindow(cfg.StatsWindow),
+		metrics:          newOrderMetrics(shelfManager.ShelfStates()),
+		run:              run,
+		admission:        admission{policy: admissionPolicy, maxDefer: seconds(cfg.Admission.MaxDefer)},
+	}
+	s.samples.shelves = shelfTypes
+	s.samples.max = cfg.TimeSeries.MaxSamples
+	if cfg.Couriers.AgentAddr != "" {
+		s.Agents = agent.NewHub(agentDispatcher{s})
+	}
+	return s, nil
 }
 
-// loadOrdersFromFile reads orders from a JSON file
-func loadOrdersFromFile(filePath string) ([]OrderData, error) {
+// decayFormulaFromConfig returns the expression formula if one is configured,
+// otherwise the named built-in formula
+func decayFormulaFromConfig(cfg *config.Config) (order.DecayFormula, error) {
+	if cfg.DecayExpression != "" {
+		return order.NewExpressionFormula(cfg.DecayExpression)
+	}
+	return order.LookupDecayFormula(cfg.DecayFormula)
+}
+
+// LoadOrdersFromFile reads orders from a JSON file: an array of orders, or
+// a document such as {"version": 2, "orders": [...]}
+func LoadOrdersFromFile(filePath string) ([]OrderData, error) {
+	return loadOrdersFile(filePath, false)
+}
+
+// loadOrdersFile reads orders from a JSON file, rejecting unknown fields if
+// strict
+func loadOrdersFile(filePath string, strict bool) ([]OrderData, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
@@ -72,36 +460,42 @@ func loadOrdersFromFile(filePath string) ([]OrderData, error) {
 	defer file.Close()
 
 	var orders []OrderData
-	if err := json.NewDecoder(file).Decode(&orders); err != nil {
-		return nil, err
+	if err := decodeOrders(file, strict, func(d *OrderData) { orders = append(orders, *d) }); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
 	}
-
 	return orders, nil
 }
 
 func (s *Simulator) generateOrders() {
 	defer s.wg.Done()
 
+	ctx, cancel := s.stopContext()
+	defer cancel()
+
+	if s.demand != nil {
+		s.followDemand(ctx)
+		return
+	}
+
 	// Calculate interval between orders
-	interval := time.Duration(1000.0/s.Config.OrdersPerSecond) * time.Millisecond
+	interval, perTick := orderTicks(s.Config.OrdersPerSecond)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	// Fractions of an order carry over to the next tick, so a rate that is
+	// not a whole number per tick is kept on average
+	due := 0.0
 	for {
 		select {
 		case <-ticker.C:
-			// If we still have orders to process
-			if s.ordersProcessed < len(s.Orders) {
-				s.createOrderFromList()
-
-				// If this was the last order, wait a bit to allow
-				// for deliveries and cleanup before stopping
-				if s.ordersProcessed >= len(s.Orders) {
-					// Give some time for delivery attempts and cleanup
-					time.Sleep(10 * time.Second)
-					fmt.Println("All orders have been processed!")
-					close(s.stop)
-				}
+			if s.paused.Load() {
+				continue
+			}
+			due += perTick
+			n := int(due)
+			due -= float64(n)
+			if n > 0 && s.placeOrders(ctx, n) {
+				return
 			}
 		case <-s.stop:
 			return
@@ -109,21 +503,134 @@ func (s *Simulator) generateOrders() {
 	}
 }
 
+// placeOrders places up to n orders from the source, unless they are
+// throttled. Once the source runs out it allows time for deliveries and
+// cleanup, then stops the simulation and returns true, as it does if the
+// source fails.
+func (s *Simulator) placeOrders(ctx context.Context, n int) bool {
+	if s.throttle(n) {
+		return false
+	}
+	for i := 0; i < n; i++ {
+		d, err := s.nextOrder(ctx)
+		switch {
+		case err == nil:
+			s.createOrder(*d)
+			continue
+		case errors.Is(err, io.EOF):
+		case ctx.Err() != nil:
+			return true
+		default:
+			s.setErr(fmt.Errorf("reading orders: %w", err))
+			s.halt()
+			return true
+		}
+
+		// Give some time for delivery attempts and cleanup
+		select {
+		case <-time.After(10 * time.Second):
+		case <-s.stop:
+			return true
+		}
+		fmt.Println("All orders have been processed!")
+		s.halt()
+		return true
+	}
+	return false
+}
+
+// minOrderInterval is the shortest ticker period used to generate orders.
+// Faster rates place several orders per tick instead, since sub-millisecond
+// tickers cannot keep up.
+const minOrderInterval = time.Millisecond
+
+// orderTicks returns the ticker interval and the orders due per tick for
+// the given rate, which is fractional when the rate needs more than one
+// order per minOrderInterval
+func orderTicks(ordersPerSecond float64) (time.Duration, float64) {
+	interval := time.Duration(float64(time.Second) / ordersPerSecond)
+	if interval >= minOrderInterval {
+		return interval, 1
+	}
+	return minOrderInterval, ordersPerSecond * minOrderInterval.Seconds()
+}
+
 // Run starts the simulation
 func (s *Simulator) Run() {
+	s.startedAt = time.Now()
+	s.waste.begin(s.startedAt)
 	fmt.Println("Starting simulation...")
-	fmt.Printf("Configuration: Hot=%d, Cold=%d, Frozen=%d, Overflow=%d, Orders/sec=%.1f\n",
-		s.Config.HotShelfCapacity,
-		s.Config.ColdShelfCapacity,
-		s.Config.FrozenShelfCapacity,
-		s.Config.OverflowCapacity,
+	s.printRun()
+	fmt.Printf("Configuration: %s, Orders/sec=%.1f\n",
+		shelfSummary(s.ShelfManager.ShelfStates(), func(st shelf.ShelfState) int { return st.Capacity }),
 		s.Config.OrdersPerSecond)
 
-	fmt.Printf("Total orders to process: %d\n", len(s.Orders))
+	if s.Config.DecayExpression != "" {
+		fmt.Printf("Decay formula: %s\n", s.Config.DecayExpression)
+	} else {
+		fmt.Printf("Decay formula: %s\n", s.Config.DecayFormula)
+	}
+	if s.Couriers != nil {
+		fmt.Printf("Couriers: %d, assigned %s\n", s.Config.Couriers.Count, s.Couriers.Strategy())
+	}
+	if s.Agents != nil {
+		fmt.Printf("Courier agents: accepted on %s\n", s.Config.Couriers.AgentAddr)
+	}
+	if scripts := s.Config.Scripts; scripts.Placement != "" || scripts.Eviction != "" {
+		fmt.Printf("Scripts: placement=%q, eviction=%q\n", scripts.Placement, scripts.Eviction)
+	}
+	if s.demand != nil {
+		fmt.Printf("Demand curve: %d points, starting at %s\n", len(s.demand.points), s.currentDemand(0))
+	}
+	if s.Config.PlacementStrategy != "" {
+		fmt.Printf("Placement strategy: %s\n", s.Config.PlacementStrategy)
+	}
+	if len(s.Config.EventSinks) > 0 {
+		fmt.Printf("Event sinks: %s\n", strings.Join(s.Config.EventSinks, ", "))
+	}
+	if s.Config.EventLog.File != "" {
+		fmt.Printf("Event log: %s\n", s.Config.EventLog.File)
+	}
+	if s.Config.TimeSeries.File != "" {
+		fmt.Printf("Time series: %s, every %gs\n", s.Config.TimeSeries.File, s.Config.TimeSeries.Interval)
+	}
+	if s.Config.Results.File != "" {
+		fmt.Printf("Order results: %s\n", s.Config.Results.File)
+	}
+	if s.Config.Trace.Orders != "" {
+		fmt.Printf("Tracing orders: %s\n", s.Config.Trace.Orders)
+	}
+	if s.Config.MQTT.Addr != "" {
+		fmt.Printf("MQTT: publishing events to %s under %s\n", s.Config.MQTT.Addr, s.Config.MQTT.TopicPrefix)
+	}
+	stopSinks := s.startSinks()
+	s.Events.Publish(events.Event{Type: events.RunStarted, Version: buildinfo.Version()})
 
-	// Start order generator
-	s.wg.Add(1)
-	go s.generateOrders()
+	if s.Config.Service.Enabled {
+		fmt.Println("Service mode: accepting orders over the API")
+		if s.nats != nil && len(s.Config.NATS.Subjects) > 0 {
+			if err := s.nats.consume(s); err != nil {
+				s.setErr(fmt.Errorf("subscribing to NATS: %w", err))
+				s.halt()
+			} else {
+				fmt.Printf("NATS: accepting orders on %s\n", strings.Join(s.Config.NATS.Subjects, ", "))
+			}
+		}
+		if s.Config.AMQP.URL != "" {
+			if err := s.startAMQP(); err != nil {
+				s.setErr(fmt.Errorf("consuming AMQP queue %s: %w", s.Config.AMQP.Queue, err))
+				s.halt()
+			} else {
+				fmt.Printf("AMQP: accepting orders from queue %s\n", s.Config.AMQP.Queue)
+			}
+		}
+	} else {
+		s.printOrderCount()
+
+		// Start order generator
+		s.wg.Add(1)
+		go s.generateOrders()
+	}
 
 	// Start delivery processor
 	s.wg.Add(1)
@@ -137,8 +644,48 @@ func (s *Simulator) Run() {
 	s.wg.Add(1)
 	go s.reportStats()
 
-	// If a duration is set, use that as a maximum time
-	if s.Config.SimulationDuration > 0 {
+	// Start equipment failure injection
+	s.wg.Add(1)
+	go s.injectFailures()
+
+	// Start counter reconciliation
+	invariantsEnabled := s.Config.Invariants.Mode != "" && s.Config.Invariants.Mode != config.InvariantCheckOff
+	if invariantsEnabled {
+		s.wg.Add(1)
+		go s.checkInvariants()
+	}
+
+	// Stop early once any configured stop condition is met
+	if stopConditionsEnabled(s.Config.Stop) {
+		s.wg.Add(1)
+		go s.watchStopConditions()
+	}
+
+	// Escalate orders nearing expiry
+	if s.escalationEnabled() {
+		s.wg.Add(1)
+		go s.watchEscalations()
+	}
+
+	// Raise alerts while the run is unattended
+	if len(s.Config.Alerts.Rules) > 0 {
+		s.wg.Add(1)
+		go s.watchAlerts()
+	}
+
+	// Sample the run for the time series
+	if s.sampling() {
+		s.wg.Add(1)
+		go s.recordTimeSeries()
+	}
+
+	if s.OnStart != nil {
+		s.OnStart()
+	}
+
+	// If a duration is set, use that as a maximum time. A service runs
+	// until stopped.
+	if s.Config.SimulationDuration > 0 && !s.Config.Service.Enabled {
 		fmt.Printf("Maximum simulation time: %d seconds\n", s.Config.SimulationDuration)
 
 		// Create a timer for the maximum duration
@@ -148,7 +695,7 @@ func (s *Simulator) Run() {
 		select {
 		case <-durationTimer.C:
 			fmt.Println("Maximum simulation time reached!")
-			close(s.stop)
+			s.halt()
 		case <-s.stop:
 			// The simulation ended on its own
 		}
@@ -158,50 +705,120 @@ func (s *Simulator) Run() {
 	}
 
 	s.wg.Wait()
+	s.cancelInTransit(time.Now())
+	stopSinks()
+	s.closeSeries()
+	s.closeResults()
+	closeSource(s.Source)
 	fmt.Println("Simulation completed!")
+	if invariantsEnabled {
+		s.finalInvariantCheck()
+	}
 	s.printFinalStats()
+	if s.OnStop != nil {
+		s.OnStop()
+	}
 }
 
 // Stop stops the simulation
 func (s *Simulator) Stop() {
-	close(s.stop)
+	s.halt()
 	s.wg.Wait()
 }
 
-// createOrderFromList creates an order from the loaded list
-func (s *Simulator) createOrderFromList() {
-	orderData := s.Orders[s.ordersProcessed]
-	modifiedDecayRate := orderData.DecayRate * s.decayModifier
-	temp := order.Temperature(orderData.Temp)
-	newOrder := order.NewOrder(orderData.Name, temp, orderData.ShelfLife, modifiedDecayRate)
+// RunContext returns the run the simulator's metrics, events and reports
+// are labelled with
+func (s *Simulator) RunContext() config.RunContext {
+	s.runMutex.Lock()
+	defer s.runMutex.Unlock()
 
-	success := s.ShelfManager.PlaceOrder(newOrder)
-	if success {
-		fmt.Printf("📦 Order placed: %s (%s) - Shelf life: %.1fs, Decay rate: %.3f\n",
-			newOrder.Name, newOrder.Temp, newOrder.ShelfLife, newOrder.DecayRate)
-	} else {
-		fmt.Printf("❌ Order wasted (no shelf space): %s (%s)\n", newOrder.Name, newOrder.Temp)
+	return s.run
+}
+
+// SetRunContext labels the simulator's metrics, events and reports with
+// run from now on. A sweep running several configurations in one process
+// sets it to tell their iterations apart.
+func (s *Simulator) SetRunContext(run config.RunContext) {
+	s.runMutex.Lock()
+	s.run = run
+	s.runMutex.Unlock()
+
+	s.Events.SetRun(run.Name, run.ID)
+}
+
+// now returns the current time on the simulator's clock
+func (s *Simulator) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
 	}
+	return s.clock.Now()
+}
+
+// halt signals every simulation goroutine to stop. It is safe to call more
+// than once, since the run can end on its own while Stop is being called.
+func (s *Simulator) halt() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		s.failures.stop()
+	})
+}
+
+// createOrder places an order taken from the source
+func (s *Simulator) createOrder(d OrderData) {
 	s.ordersProcessed++
+	if s.admit(d) != plugin.Accept {
+		return
+	}
+	// A wasted order is finished with, so it can be reused at once
+	if o, err := s.placeOrder(d); err != nil {
+		s.pool.Put(o)
+	}
 }
 
-// processLoadedOrders places all orders from the loaded list
-func (s *Simulator) processLoadedOrders() {
-	defer s.wg.Done()
+// placeOrder creates the described order and shelves it, returning the
+// order and the placement error if it was wasted
+func (s *Simulator) placeOrder(d OrderData) (*order.Order, error) {
+	newOrder := s.newOrder(d)
+	s.receive(newOrder)
+	err := s.placeTimed(newOrder)
+	s.recordPlacement(newOrder, err)
+	return newOrder, err
+}
 
-	for _, orderData := range s.Orders {
-		temp := order.Temperature(orderData.Temp)
-		newOrder := order.NewOrder(orderData.Name, temp, orderData.ShelfLife, orderData.DecayRate)
+// newOrder creates the described order, promised for delivery
+func (s *Simulator) newOrder(d OrderData) *order.Order {
+	newOrder := d.newPooledOrder(s.pool, s.decayModifier, s.decayFormula)
+	newOrder.CreatedAt = s.now()
+	newOrder.DiscardHistory = s.Config.Memory.DiscardCompleted
+	newOrder.OnTransition = s.ObserveTransition
+	s.promise(newOrder)
+	return newOrder
+}
 
-		success := s.ShelfManager.PlaceOrder(newOrder)
-		if success {
-			fmt.Printf("📦 Order placed: %s (%s) - Shelf life: %.1fs, Decay rate: %.3f\n",
-				newOrder.Name, newOrder.Temp, newOrder.ShelfLife, newOrder.DecayRate)
-		} else {
-			fmt.Printf("❌ Order wasted (no shelf space): %s (%s)\n", newOrder.Name, newOrder.Temp)
-		}
+// receive counts a new order about to be placed
+func (s *Simulator) receive(o *order.Order) {
+	s.sources.receive(o)
+	s.recent.receive(o.CreatedAt)
+	s.warnUnknownTemp(o)
+}
+
+// recordPlacement reports a new order shelved, or wasted with err
+func (s *Simulator) recordPlacement(newOrder *order.Order, err error) {
+	if err == nil {
+		s.tracer.settle(newOrder)
+		s.orderLogf(newOrder.ID, newOrder.Name, "📦 Order placed: %s (%s) - Shelf life: %.1fs, Decay rate: %.3f\n",
+			newOrder.Name, newOrder.Temp, newOrder.ShelfLife, newOrder.DecayRate)
+		s.publishOrderEvent(events.OrderPlaced, newOrder)
+		return
 	}
-	close(s.stop) // Signal to stop after processing all orders
+	reason := shelf.RejectionReason(err)
+	s.orderLogf(newOrder.ID, newOrder.Name, "❌ Order wasted (%s): %s (%s)\n", reason, newOrder.Name, newOrder.Temp)
+	s.results.record(newOrder, order.StateWasted, newOrder.StateChangedAt(), string(reason))
+	s.tracer.record(newOrder, order.StateCreated, order.StateWasted, newOrder.StateChangedAt(), string(reason))
+	s.waste.placementWasted(newOrder, newOrder.StateChangedAt(), reason)
+	event := s.orderEvent(events.OrderWasted, newOrder)
+	event.Reason = string(reason)
+	s.Events.Publish(event)
 }
 
 // processDeliveries simulates order deliveries
@@ -214,6 +831,7 @@ func (s *Simulator) processDeliveries() {
 	for {
 		select {
 		case <-ticker.C:
+			s.retryDeferred()
 			s.attemptDeliveries()
 		case <-s.stop:
 			return
@@ -223,8 +841,20 @@ func (s *Simulator) processDeliveries() {
 
 // attemptDeliveries attempts to deliver orders based on a probability
 func (s *Simulator) attemptDeliveries() {
+	if s.paused.Load() {
+		return
+	}
+	if s.Agents != nil {
+		s.dispatchAgents()
+		return
+	}
+	if s.Couriers != nil {
+		s.dispatchCouriers()
+		return
+	}
+
 	// Get all orders
-	allOrders := s.ShelfManager.GetAllOrders()
+	allOrders := s.pickupOrders()
 	if len(allOrders) == 0 {
 		return
 	}
@@ -233,34 +863,90 @@ func (s *Simulator) attemptDeliveries() {
 	for _, order := range allOrders {
 		//if rand.Float64() < 0.30 {
 		// Introduce a random delay between 2 to 6 seconds before delivering the order
-		randomDelay := time.Duration(rand.IntN(5)+2) * time.Second
+		randomDelay := s.pickupDelay()
+		s.reserve(order, randomDelay)
 		time.Sleep(randomDelay)
+		if s.paused.Load() {
+			s.release(order)
+			return
+		}
 
-		if s.ShelfManager.DeliverOrder(order.ID) {
-			fmt.Printf("🚚 Order delivered: %s (Value: %.2f)\n",
-				order.Name, order.CalculateValue(time.Now()))
+		// During a courier disruption some pickups find no courier; the
+		// order stays shelved and is retried on the next cycle
+		if !s.courierAvailable() {
+			s.release(order)
+			continue
+		}
+
+		if result, ok := s.pickUp(order.ID); ok {
+			s.handOff(result.Order, result.Value, result.At.Add(s.handoffDuration()))
+			s.pool.Put(order)
 		}
 		//}
 	}
 }
 
+// publishOrderEvent publishes an event about a single order
+func (s *Simulator) publishOrderEvent(eventType events.Type, o *order.Order) {
+	s.Events.Publish(s.orderEvent(eventType, o))
+}
+
+// orderEvent describes a single order as an event
+func (s *Simulator) orderEvent(eventType events.Type, o *order.Order) events.Event {
+	now := s.now()
+	return events.Event{
+		Type:    eventType,
+		Time:    now,
+		OrderID: o.ID,
+		Name:    o.Name,
+		Temp:    string(o.Temp),
+		Shelf:   o.CurrentShelfType,
+		Value:   o.CalculateValue(now),
+	}
+}
+
 // cleanupExpiredOrders removes expired orders from shelves
 func (s *Simulator) cleanupExpiredOrders() {
 	defer s.wg.Done()
 
-	ticker := time.NewTicker(s.cleanupInterval)
-	defer ticker.Stop()
+	if s.Config.ExpiryMode == config.ExpiryModeSweep {
+		s.sweepExpiredOrders()
+		return
+	}
+	s.scheduleExpiredOrders()
+}
+
+// scheduleExpiredOrders sleeps until the next scheduled expiry and removes
+// orders exactly when they expire
+func (s *Simulator) scheduleExpiredOrders() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
 
 	for {
 		select {
-		case <-ticker.C:
-			expired := s.ShelfManager.RemoveExpiredOrders()
+		case <-timer.C:
+			expired := s.cleanupTimed(func() int { return s.ShelfManager.RemoveDueOrders(time.Now()) })
 			if expired > 0 {
-				fmt.Printf("🗑️ Removed %d expired orders\n", expired)
+				s.logf("🗑️ Removed %d expired orders\n", expired)
+				s.Events.Publish(events.Event{Type: events.OrdersExpired, Count: expired})
 			}
+		case <-s.ShelfManager.ExpiryUpdates():
+			// An earlier expiry was scheduled; re-arm the timer below
 		case <-s.stop:
 			return
 		}
+
+		wait := time.Hour
+		if next, ok := s.ShelfManager.NextExpiry(); ok {
+			wait = time.Until(next)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
 	}
 }
 
@@ -274,21 +960,18 @@ func (s *Simulator) reportStats() {
 	for {
 		select {
 		case <-ticker.C:
-			s.printCurrentStats()
+			s.PrintCurrentStats()
 		case <-s.stop:
 			return
 		}
 	}
 }
 
-// printCurrentStats prints the current statistics of the simulation
-func (s *Simulator) printCurrentStats() {
+// PrintCurrentStats prints the current statistics of the simulation. It is
+// safe to call while the simulation runs.
+func (s *Simulator) PrintCurrentStats() {
 	stats := s.ShelfManager.GetStats()
-
-	hotCurrent := stats["hotShelf"].(map[string]interface{})["current"].(int)
-	coldCurrent := stats["coldShelf"].(map[string]interface{})["current"].(int)
-	frozenCurrent := stats["frozenShelf"].(map[string]interface{})["current"].(int)
-	overflowCurrent := stats["overflowShelf"].(map[string]interface{})["current"].(int)
+	states := s.ShelfManager.ShelfStates()
 
 	totalReceived := stats["totalOrders"].(map[string]interface{})["received"].(int)
 	totalDelivered := stats["totalOrders"].(map[string]interface{})["delivered"].(int)
@@ -297,10 +980,13 @@ func (s *Simulator) printCurrentStats() {
 
 	fmt.Println("\n📊 CURRENT SIMULATION STATS 📊")
 	fmt.Println("------------------------------")
-	fmt.Printf("Shelves: Hot=%d, Cold=%d, Frozen=%d, Overflow=%d\n",
-		hotCurrent, coldCurrent, frozenCurrent, overflowCurrent)
+	fmt.Printf("Shelves: %s\n",
+		shelfSummary(states, func(st shelf.ShelfState) int { return len(st.Orders) }))
 	fmt.Printf("Orders: Received=%d, Delivered=%d, Wasted=%d, Expired=%d\n",
 		totalReceived, totalDelivered, totalWasted, totalExpired)
+	if s.demand != nil {
+		fmt.Printf("Demand: %s\n", s.currentDemand(time.Since(s.startedAt)))
+	}
 
 	// Calculate percentages for better visibility
 	deliveryRate := 0.0
@@ -315,6 +1001,8 @@ func (s *Simulator) printCurrentStats() {
 
 	fmt.Printf("Delivery rate: %.1f%%, Waste rate: %.1f%%\n",
 		deliveryRate, wasteRate)
+	s.printLoad()
+	s.printRecentStats()
 	fmt.Println("------------------------------")
 }
 
@@ -322,11 +1010,7 @@ func (s *Simulator) printCurrentStats() {
 func (s *Simulator) printFinalStats() {
 	stats := s.ShelfManager.GetStats()
 
-	// Get shelf stats
-	hotStats := stats["hotShelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
-	coldStats := stats["coldShelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
-	frozenStats := stats["frozenShelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
-	overflowStats := stats["overflowShelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
+	states := s.ShelfManager.ShelfStates()
 
 	// Get total numbers
 	totalReceived := stats["totalOrders"].(map[string]interface{})["received"].(int)
@@ -336,39 +1020,158 @@ func (s *Simulator) printFinalStats() {
 
 	fmt.Println("\n🎯 FINAL SIMULATION RESULTS 🎯")
 	fmt.Println("===============================")
+	s.printRun()
 
 	fmt.Println("📦 ORDERS:")
 	fmt.Printf("  Total received: %d\n", totalReceived)
+	if s.Config.Throttle.Threshold > 0 {
+		_, throttled := s.load.current()
+		fmt.Printf("  Total throttled: %d (held back above %.0f%% shelf pressure)\n", throttled, s.Config.Throttle.Threshold)
+	}
+	s.printAdmissionStats()
 	fmt.Printf("  Total delivered: %d (%.1f%%)\n",
 		totalDelivered, float64(totalDelivered)/float64(totalReceived)*100)
+	s.printHandoffStats()
+	s.printPromiseStats()
+	s.printEscalationStats()
 	fmt.Printf("  Total wasted: %d (%.1f%%)\n",
 		totalWasted, float64(totalWasted)/float64(totalReceived)*100)
+	if rejections, ok := stats["rejections"].(map[shelf.RejectReason]int); ok {
+		printRejections(rejections)
+	}
 	fmt.Printf("  Total expired: %d (%.1f%%)\n",
 		totalExpired, float64(totalExpired)/float64(totalReceived)*100)
+	if evicted, _ := stats["totalOrders"].(map[string]interface{})["evicted"].(int); evicted > 0 {
+		fmt.Printf("    evicted by script: %d\n", evicted)
+	}
+	if refused, _ := stats["totalOrders"].(map[string]interface{})["refused"].(int); refused > 0 {
+		fmt.Printf("  Refused at pickup (below minValue): %d\n", refused)
+	}
 
-	fmt.Println("\n🔥 HOT SHELF:")
-	fmt.Printf("  Orders added: %d\n", hotStats.OrdersAdded)
-	fmt.Printf("  Orders delivered: %d\n", hotStats.OrdersDelivered)
-	fmt.Printf("  Orders wasted: %d\n", hotStats.OrdersWasted)
-	fmt.Printf("  Peak usage: %d\n", hotStats.PeakUsage)
-
-	fmt.Println("\n❄️ COLD SHELF:")
-	fmt.Printf("  Orders added: %d\n", coldStats.OrdersAdded)
-	fmt.Printf("  Orders delivered: %d\n", coldStats.OrdersDelivered)
-	fmt.Printf("  Orders wasted: %d\n", coldStats.OrdersWasted)
-	fmt.Printf("  Peak usage: %d\n", coldStats.PeakUsage)
-
-	fmt.Println("\n🧊 FROZEN SHELF:")
-	fmt.Printf("  Orders added: %d\n", frozenStats.OrdersAdded)
-	fmt.Printf("  Orders delivered: %d\n", frozenStats.OrdersDelivered)
-	fmt.Printf("  Orders wasted: %d\n", frozenStats.OrdersWasted)
-	fmt.Printf("  Peak usage: %d\n", frozenStats.PeakUsage)
-
-	fmt.Println("\n♻️ OVERFLOW SHELF:")
-	fmt.Printf("  Orders added: %d\n", overflowStats.OrdersAdded)
-	fmt.Printf("  Orders delivered: %d\n", overflowStats.OrdersDelivered)
-	fmt.Printf("  Orders wasted: %d\n", overflowStats.OrdersWasted)
-	fmt.Printf("  Peak usage: %d\n", overflowStats.PeakUsage)
+	for _, state := range states {
+		shelfStats := stats[string(state.Type)+"Shelf"].(map[string]interface{})["stats"].(shelf.ShelfStats)
+		fmt.Printf("\n%s:\n", shelfHeading(state))
+		fmt.Printf("  Orders added: %d\n", shelfStats.OrdersAdded)
+		fmt.Printf("  Orders delivered: %d\n", shelfStats.OrdersDelivered)
+		fmt.Printf("  Orders expired: %d\n", shelfStats.OrdersExpired)
+		if shelfStats.OrdersWasted > 0 || len(state.Temps) == 0 {
+			fmt.Printf("  Orders wasted (no space): %d\n", shelfStats.OrdersWasted)
+		}
+		if shelfStats.OrdersRefused > 0 {
+			fmt.Printf("  Orders refused at pickup: %d\n", shelfStats.OrdersRefused)
+		}
+		fmt.Printf("  Peak usage: %d\n", shelfStats.PeakUsage)
+	}
+
+	fmt.Println("\n🌡️ BY TEMPERATURE:")
+	byTemp := s.ShelfManager.StatsByTemperature()
+	for _, temp := range []order.Temperature{order.Hot, order.Cold, order.Frozen} {
+		printItemStats(string(temp), byTemp[temp], s.handoffs.forTemp(temp))
+	}
+	s.printValueHistograms()
+
+	fmt.Println("\n🍽️ BY ITEM (most lost first):")
+	byName := s.ShelfManager.StatsByName()
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		li, lj := byName[names[i]].Lost(), byName[names[j]].Lost()
+		if li != lj {
+			return li > lj
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		printItemStats(name, byName[name], s.handoffs.forName(name))
+	}
+	s.printSourceStats()
+
+	if s.Couriers != nil {
+		s.printCourierStats()
+	}
+	if s.Agents != nil {
+		s.printAgentStats()
+	}
+	s.printPostMortem(totalReceived)
+
+	s.printTimings()
+	if s.pool != nil {
+		fmt.Printf("\n♻️ ORDER POOL: %d orders reused\n", s.pool.Reused())
+	}
+
+	fmt.Println("\n🔒 LOCK CONTENTION:")
+	printLockStats("Manager", stats["managerLockStats"].(shelf.LockStats))
+	for _, state := range states {
+		name := string(state.Type) + "Shelf"
+		printLockStats(name, stats[name].(map[string]interface{})["lockStats"].(shelf.LockStats))
+	}
 
 	fmt.Println("===============================")
 }
+
+// shelfHeadings label the default shelves in the final report
+var shelfHeadings = map[shelf.ShelfType]string{
+	shelf.HotShelf:      "🔥 HOT SHELF",
+	shelf.ColdShelf:     "❄️ COLD SHELF",
+	shelf.FrozenShelf:   "🧊 FROZEN SHELF",
+	shelf.OverflowShelf: "♻️ OVERFLOW SHELF",
+}
+
+// shelfHeading returns the final report heading for a shelf
+func shelfHeading(state shelf.ShelfState) string {
+	if heading, ok := shelfHeadings[state.Type]; ok {
+		return heading
+	}
+	return "🗄️ " + strings.ToUpper(string(state.Type)) + " SHELF"
+}
+
+// shelfSummary formats one figure per shelf, such as "hot=3, overflow=0"
+func shelfSummary(states []shelf.ShelfState, figure func(shelf.ShelfState) int) string {
+	parts := make([]string, 0, len(states))
+	for _, state := range states {
+		parts = append(parts, fmt.Sprintf("%s=%d", state.Type, figure(state)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// printRun prints the run's name, tags and description, if any
+func (s *Simulator) printRun() {
+	fmt.Printf("Version: %s\n", buildinfo.Version())
+	if label := s.Config.Run.Label(); label != "" {
+		fmt.Printf("Run: %s\n", label)
+	}
+	if s.Config.Run.Description != "" {
+		fmt.Printf("Description: %s\n", s.Config.Run.Description)
+	}
+}
+
+// printItemStats prints one line of an outcome breakdown
+func printItemStats(label string, is shelf.ItemStats, handoffs handoffTotals) {
+	handedOff := ""
+	if handoffs.count > 0 {
+		_, value := handoffs.averages()
+		handedOff = fmt.Sprintf(", %.2f at handoff", value)
+	}
+	fmt.Printf("  %s: delivered %d (avg value %.2f%s), wasted %d, expired %d\n",
+		label, is.Delivered, is.AverageDeliveredValue(), handedOff, is.Wasted, is.Expired)
+}
+
+// printRejections prints the rejected placements by reason
+func printRejections(rejections map[shelf.RejectReason]int) {
+	reasons := make([]string, 0, len(rejections))
+	for reason := range rejections {
+		reasons = append(reasons, string(reason))
+	}
+	sort.Strings(reasons)
+	for _, reason := range reasons {
+		fmt.Printf("    %s: %d\n", reason, rejections[shelf.RejectReason(reason)])
+	}
+}
+
+// printLockStats prints one line of lock contention figures
+func printLockStats(name string, ls shelf.LockStats) {
+	fmt.Printf("  %s: %d acquisitions, %d contended, total wait %v, max wait %v\n",
+		name, ls.Acquisitions, ls.Contended, ls.TotalWait, ls.MaxWait)
+}
@@ -1,12 +1,18 @@
 package simulator
 
 import (
+	"context"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/courier"
+	"dish-dispatcher/internal/order"
 	shelf "dish-dispatcher/internal/shelves"
 )
 
@@ -28,7 +34,7 @@ func setupTestSimulator(t *testing.T) *Simulator {
 	s := &Simulator{
 		ShelfManager:     shelf.NewShelfManager(cfg.HotShelfCapacity, cfg.ColdShelfCapacity, cfg.FrozenShelfCapacity, cfg.OverflowCapacity),
 		Config:           cfg,
-		Orders:           orders,
+		Source:           NewSliceSource(orders),
 		stop:             make(chan struct{}),
 		deliveryInterval: 500 * time.Millisecond,
 		cleanupInterval:  2 * time.Second,
@@ -39,7 +45,7 @@ func setupTestSimulator(t *testing.T) *Simulator {
 
 func TestOrderPlacement(t *testing.T) {
 	s := setupTestSimulator(t)
-	s.createOrderFromList()
+	s.placeOrders(context.Background(), 1)
 
 	if s.ordersProcessed != 1 {
 		t.Errorf("Expected 1 order to be processed, got %d", s.ordersProcessed)
@@ -53,7 +59,7 @@ func TestOrderPlacement(t *testing.T) {
 
 // func TestOrderDelivery(t *testing.T) {
 // 	s := setupTestSimulator(t)
-// 	s.createOrderFromList()
+// 	s.placeOrders(context.Background(), 1)
 
 // 	s.wg.Add(1)
 // 	go func() {
@@ -84,7 +90,7 @@ func TestLoadOrdersFromFile(t *testing.T) {
 	}
 	file.Close()
 
-	orders, err := loadOrdersFromFile(file.Name()) // Ensure correct function reference
+	orders, err := LoadOrdersFromFile(file.Name()) // Ensure correct function reference
 	if err != nil {
 		t.Fatalf("Failed to load orders from file: %v", err)
 	}
@@ -94,6 +100,64 @@ func TestLoadOrdersFromFile(t *testing.T) {
 	}
 }
 
+func TestLoadOrdersFromFile_Versions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	load := func(data string) ([]OrderData, error) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return LoadOrdersFromFile(path)
+	}
+
+	orders, err := load(`{"version": 2, "orders": [{"name": "Pizza", "temp": "hot", "shelfLife": 600, "priority": 3, "zone": "north", "price": 12.5}]}`)
+	if err != nil {
+		t.Fatalf("Failed to load a version 2 file: %v", err)
+	}
+	if len(orders) != 1 || orders[0].Priority != 3 || orders[0].Zone != "north" || orders[0].Price != 12.5 || orders[0].Version != 2 {
+		t.Errorf("Unexpected orders %+v", orders)
+	}
+
+	if orders, err = load(`{"version": 1, "orders": [{"name": "Pizza", "temp": "hot", "shelfLife": 600}]}`); err != nil || len(orders) != 1 {
+		t.Errorf("Failed to load a version 1 document: %+v, %v", orders, err)
+	}
+
+	// A version 2 order may appear in an unversioned file if it says so
+	if _, err = load(`[{"name": "Pizza", "temp": "hot", "shelfLife": 600, "priority": 3, "version": 2}]`); err != nil {
+		t.Errorf("Failed to load a versioned order: %v", err)
+	}
+
+	for data, want := range map[string]string{
+		`[{"name": "Pizza", "temp": "hot", "shelfLife": 600, "zone": "north"}]`:                    "order 1: uses version 2 fields (zone)",
+		`{"version": 1, "orders": [{"name": "Pizza", "priority": 1, "price": 3}]}`:                 "order 1: uses version 2 fields (priority, price)",
+		`{"version": 3, "orders": []}`:                                                             "unsupported version 3, expected 1 to 2",
+		`[{"name": "Pizza"}, {"name": "Soup", "version": 7}]`:                                      "order 2: unsupported version 7",
+		`{"name": "Pizza", "temp": "hot"}` + "\n" + `{"name": "Soup", "temp": "hot", "zone": "x"}`: "order 2: uses version 2 fields (zone)",
+	} {
+		if _, err := load(data); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %s to fail with %q, got %v", data, want, err)
+		}
+	}
+}
+
+func TestLoadOrdersFile_Strict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	for data, want := range map[string]string{
+		"[\n  {\"name\": \"Pizza\", \"temp\": \"hot\"},\n  {\"name\": \"Soup\", \"shelflife\": 1, \"tmep\": \"hot\"}\n]": `order 2: line 3, column 36: unknown field "tmep"`,
+		"\n\n{\"name\": \"Pizza\"}\n  {\"name\": \"Soup\", \"colour\": \"red\"}":                                         `order 2: line 4, column 20: unknown field "colour"`,
+		"{\"version\": 2,\n \"orders\": [{\"name\": \"Pizza\"},\n  {\"name\": \"Soup\", \"zones\": \"north\"}]}":         `line 3, column 20: unknown field "orders[1].zones"`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadOrdersFromFile(path); err != nil {
+			t.Errorf("Expected %q to load leniently, got %v", data, err)
+		}
+		if _, err := loadOrdersFile(path, true); err == nil || err.Error() != path+": "+want {
+			t.Errorf("Expected %q to fail with %q, got %v", data, want, err)
+		}
+	}
+}
+
 func TestSimulator_Run(t *testing.T) {
 	s := setupTestSimulator(t)
 	s.Config.SimulationDuration = 2 // Set short duration for testing
@@ -113,6 +177,9 @@ func TestSimulator_Run(t *testing.T) {
 
 func TestSimulator_Stop(t *testing.T) {
 	s := setupTestSimulator(t)
+	// Place orders well inside the sleep below; at one per second the first
+	// order races the call to Stop
+	s.Config.OrdersPerSecond = 10
 
 	// Use a WaitGroup to track when the simulator is done processing
 	var wg sync.WaitGroup
@@ -138,3 +205,96 @@ func TestSimulator_Stop(t *testing.T) {
 		t.Errorf("Expected orders to be processed before stopping, but none were")
 	}
 }
+
+func TestOrderTicks(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		interval time.Duration
+		perTick  float64
+	}{
+		{2, 500 * time.Millisecond, 1},
+		{1000, time.Millisecond, 1},
+		{1400, time.Millisecond, 1.4},
+		{2500, time.Millisecond, 2.5},
+		{5000, time.Millisecond, 5},
+		{100000, time.Millisecond, 100},
+	}
+
+	for _, tt := range tests {
+		interval, perTick := orderTicks(tt.rate)
+		if interval != tt.interval || math.Abs(perTick-tt.perTick) > 1e-9 {
+			t.Errorf("orderTicks(%v) = %v, %v; want %v, %v", tt.rate, interval, perTick, tt.interval, tt.perTick)
+		}
+
+		// A second of ticks, carrying fractions over as the order loop
+		// does, places the full rate
+		placed, due := 0, 0.0
+		for range time.Second / interval {
+			due += perTick
+			n := int(due)
+			due -= float64(n)
+			placed += n
+		}
+		if want := int(tt.rate); placed < want-1 || placed > want {
+			t.Errorf("orderTicks(%v) placed %d orders in a second, want %d", tt.rate, placed, want)
+		}
+	}
+}
+
+func TestShelfLayout(t *testing.T) {
+	cfg := &config.Config{HotShelfCapacity: 3, ColdShelfCapacity: 4, FrozenShelfCapacity: 5, OverflowCapacity: 6}
+
+	layout := ShelfLayout(cfg)
+	if len(layout) != 4 || layout[0].Type != shelf.HotShelf || layout[3].Capacity != 6 {
+		t.Errorf("Expected the default layout from capacities, got %+v", layout)
+	}
+
+	cfg.Shelves = []config.ShelfConfig{
+		{Name: "grill", Capacity: 2, Temps: []string{"hot"}},
+		{Name: "room", Capacity: 8, Temps: []string{"hot", "cold"}, DecayModifier: 1.5},
+		{Name: "spare", Volume: 4.5},
+	}
+	layout = ShelfLayout(cfg)
+	if len(layout) != 3 {
+		t.Fatalf("Expected 3 shelves, got %d", len(layout))
+	}
+	if room := layout[1]; room.Type != "room" || len(room.Temps) != 2 || room.DecayModifier != 1.5 {
+		t.Errorf("Unexpected room shelf %+v", room)
+	}
+	if !layout[2].IsOverflow() || layout[2].Volume != 4.5 {
+		t.Errorf("Expected a volume-limited overflow shelf, got %+v", layout[2])
+	}
+
+	if o := (OrderData{Name: "Pizza", Temp: "hot", Size: 2.5}).NewOrder(1, nil); o.Volume() != 2.5 {
+		t.Errorf("Expected order size 2.5, got %v", o.Volume())
+	}
+}
+
+func TestDispatchCouriers(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Couriers = config.CourierConfig{Count: 1}
+	s.Couriers = courier.NewFleet([]*courier.Courier{{ID: 1}}, courier.NearestIdle{})
+
+	first := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	second := order.NewOrder("Salad", order.Cold, 300, 0.5)
+	s.ShelfManager.PlaceOrder(first)
+	s.ShelfManager.PlaceOrder(second)
+
+	// A single courier takes one order per trip
+	s.attemptDeliveries()
+	s.wg.Wait()
+	if got := len(s.ShelfManager.GetAllOrders()); got != 1 {
+		t.Fatalf("Expected one order left after the first trip, got %d", got)
+	}
+
+	s.attemptDeliveries()
+	s.wg.Wait()
+	if got := len(s.ShelfManager.GetAllOrders()); got != 0 {
+		t.Errorf("Expected every order delivered, got %d left", got)
+	}
+
+	stats := s.Couriers.Stats()[courier.StrategyNearestIdle]
+	if stats.Assignments != 2 || stats.Pickups != 2 {
+		t.Errorf("Expected 2 assignments and pickups, got %+v", stats)
+	}
+}
@@ -0,0 +1,1271 @@
+package simulator
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/nats"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/strictjson"
+)
+
+// OrderSource supplies the orders of a run. Next blocks until an order is
+// available, and returns io.EOF once the source has no more, or ctx's error
+// if ctx is done first. Sources are read from one goroutine at a time.
+type OrderSource interface {
+	Next(ctx context.Context) (*OrderData, error)
+}
+
+// SliceSource supplies a list of orders held in memory, such as a file read
+// up front
+type SliceSource struct {
+	orders []OrderData
+	next   int
+}
+
+// NewSliceSource supplies orders in order
+func NewSliceSource(orders []OrderData) *SliceSource {
+	return &SliceSource{orders: orders}
+}
+
+func (s *SliceSource) Next(ctx context.Context) (*OrderData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.next >= len(s.orders) {
+		return nil, io.EOF
+	}
+	d := s.orders[s.next]
+	s.next++
+	return &d, nil
+}
+
+// Orders returns the whole list, including orders already supplied
+func (s *SliceSource) Orders() []OrderData {
+	return s.orders
+}
+
+// Len returns the number of orders in the list
+func (s *SliceSource) Len() int {
+	return len(s.orders)
+}
+
+// NewJSONFileSource reads orders from a JSON file as LoadOrdersFromFile
+// does, rejecting unknown fields if strict
+func NewJSONFileSource(path string, strict bool) (*SliceSource, error) {
+	orders, err := loadOrdersFile(path, strict)
+	if err != nil {
+		return nil, err
+	}
+	return NewSliceSource(orders), nil
+}
+
+// FileWatchSource supplies the orders of a JSON orders file, then checks it
+// every interval and supplies orders appended to it since. It never runs
+// out, so hand-written orders can be fed to a live run by editing the file.
+type FileWatchSource struct {
+	path     string
+	strict   bool
+	interval time.Duration
+
+	pending []OrderData // read from the file but not yet supplied
+	seen    int         // orders of the file supplied or pending
+	modTime time.Time
+	size    int64
+	broken  bool // the file failed to parse, which is reported once
+}
+
+// NewFileWatchSource reads the orders file at path and watches it for more
+func NewFileWatchSource(path string, strict bool, interval time.Duration) (*FileWatchSource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := loadOrdersFile(path, strict)
+	if err != nil {
+		return nil, err
+	}
+	return &FileWatchSource{
+		path:     path,
+		strict:   strict,
+		interval: interval,
+		pending:  orders,
+		seen:     len(orders),
+		modTime:  info.ModTime(),
+		size:     info.Size(),
+	}, nil
+}
+
+func (w *FileWatchSource) Next(ctx context.Context) (*OrderData, error) {
+	for len(w.pending) == 0 {
+		timer := time.NewTimer(w.interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+		w.check()
+	}
+	d := w.pending[0]
+	w.pending = w.pending[1:]
+	return &d, nil
+}
+
+// Read returns the number of orders read from the file so far
+func (w *FileWatchSource) Read() int {
+	return w.seen
+}
+
+// check rereads the file if it changed, queueing the orders past those
+// already seen. Files are often truncated before being rewritten, so one
+// caught shorter is taken as incomplete rather than cut.
+func (w *FileWatchSource) check() {
+	info, err := os.Stat(w.path)
+	if err != nil || (info.ModTime().Equal(w.modTime) && info.Size() == w.size) {
+		return
+	}
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	orders, err := loadOrdersFile(w.path, w.strict)
+	if err != nil {
+		// Likely saved halfway through an edit; the next save may fix it
+		if !w.broken {
+			w.broken = true
+			fmt.Printf("⚠️ Ignoring the orders file until it is fixed: %v\n", err)
+		}
+		return
+	}
+	w.broken = false
+	if len(orders) > w.seen {
+		w.pending = append(w.pending, orders[w.seen:]...)
+		w.seen = len(orders)
+	}
+}
+
+// csvColumns are the columns a CSV order file may have, in any order.
+// Name, temp and shelfLife are required; the rest default to zero.
+var csvColumns = []string{"name", "temp", "shelfLife", "decayRate", "size", "minTemp", "maxTemp", "spoilageMultiplier"}
+
+// NewCSVFileSource reads orders from a CSV file whose header row names the
+// columns, as the JSON fields are named
+func NewCSVFileSource(path string) (*SliceSource, error) {
+	orders, err := LoadOrdersFromCSV(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewSliceSource(orders), nil
+}
+
+// LoadOrdersFromCSV reads orders from a CSV file with a header row
+func LoadOrdersFromCSV(path string) ([]OrderData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := csv.NewReader(file)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: reading the header: %w", path, err)
+	}
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		known := false
+		for _, column := range csvColumns {
+			if strings.EqualFold(strings.TrimSpace(name), column) {
+				index[column], known = i, true
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("%s: unknown column %q, expected some of %s", path, name, strings.Join(csvColumns, ", "))
+		}
+	}
+	for _, required := range csvColumns[:3] {
+		if _, ok := index[required]; !ok {
+			return nil, fmt.Errorf("%s: missing column %q", path, required)
+		}
+	}
+
+	var orders []OrderData
+	for line := 2; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return orders, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		d, err := csvOrder(record, index)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+		}
+		orders = append(orders, d)
+	}
+}
+
+// csvOrder parses one CSV record, leaving empty optional fields unset
+func csvOrder(record []string, index map[string]int) (OrderData, error) {
+	field := func(column string) string {
+		if i, ok := index[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(column string) (*float64, error) {
+		s := field(column)
+		if s == "" {
+			return nil, nil
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", column, err)
+		}
+		return &v, nil
+	}
+
+	d := OrderData{Name: field("name"), Temp: field("temp")}
+	var err error
+	for _, f := range []struct {
+		column string
+		into   *float64
+	}{
+		{"shelfLife", &d.ShelfLife},
+		{"decayRate", &d.DecayRate},
+		{"size", &d.Size},
+		{"spoilageMultiplier", &d.SpoilageMultiplier},
+	} {
+		var v *float64
+		if v, err = number(f.column); err != nil {
+			return OrderData{}, err
+		}
+		if v != nil {
+			*f.into = *v
+		}
+	}
+	if d.MinTemp, err = number("minTemp"); err != nil {
+		return OrderData{}, err
+	}
+	if d.MaxTemp, err = number("maxTemp"); err != nil {
+		return OrderData{}, err
+	}
+	return d, nil
+}
+
+// defaultTemplates are generated when a generator source names no
+// templates file
+var defaultTemplates = []OrderData{
+	{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+	{Name: "Salad", Temp: "cold", ShelfLife: 240, DecayRate: 0.4},
+	{Name: "Ice Cream", Temp: "frozen", ShelfLife: 200, DecayRate: 0.2},
+}
+
+// GeneratorSource supplies orders picked at random from templates, in
+// proportion to their weights
+type GeneratorSource struct {
+	templates menu
+	byTemp    []tempGroup // set by SplitByTemp
+	remaining int         // orders left to generate, or -1 for no limit
+	rand      *rand.Rand
+}
+
+// tempGroup is the templates of one temperature, picked while a random
+// share of the total rate falls below until
+type tempGroup struct {
+	templates menu
+	until     float64
+}
+
+// menu is a list of templates picked at random by weight
+type menu struct {
+	items      []OrderData
+	cumulative []float64 // running total of the weights, nil if they are all equal
+}
+
+func newMenu(items []OrderData) menu {
+	m := menu{items: items}
+	for _, item := range items {
+		if item.Weight > 0 && item.Weight != 1 {
+			m.cumulative = make([]float64, len(items))
+			break
+		}
+	}
+	total := 0.0
+	for i := range m.cumulative {
+		weight := items[i].Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		total += weight
+		m.cumulative[i] = total
+	}
+	return m
+}
+
+func (m menu) pick(r *rand.Rand) OrderData {
+	if m.cumulative == nil {
+		return m.items[r.IntN(len(m.items))]
+	}
+	share := r.Float64() * m.cumulative[len(m.cumulative)-1]
+	return m.items[sort.Search(len(m.cumulative), func(i int) bool { return share < m.cumulative[i] })]
+}
+
+// NewGeneratorSource generates count orders, or endlessly if count is 0,
+// picking templates with a generator seeded by seed, or at random if seed
+// is 0
+func NewGeneratorSource(templates []OrderData, count int, seed uint64) *GeneratorSource {
+	if len(templates) == 0 {
+		templates = defaultTemplates
+	}
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	remaining := count
+	if count <= 0 {
+		remaining = -1
+	}
+	return &GeneratorSource{templates: newMenu(templates), remaining: remaining, rand: rand.New(rand.NewPCG(seed, seed))}
+}
+
+func (g *GeneratorSource) Next(ctx context.Context) (*OrderData, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if g.remaining == 0 {
+		return nil, io.EOF
+	}
+	if g.remaining > 0 {
+		g.remaining--
+	}
+	templates := g.templates
+	if len(g.byTemp) > 0 {
+		share := g.rand.Float64() * g.byTemp[len(g.byTemp)-1].until
+		i := sort.Search(len(g.byTemp), func(i int) bool { return share < g.byTemp[i].until })
+		templates = g.byTemp[i].templates
+	}
+	d := templates.pick(g.rand)
+	return &d, nil
+}
+
+// Templates returns the number of templates orders are picked from
+func (g *GeneratorSource) Templates() int {
+	return len(g.templates.items)
+}
+
+// SplitByTemp picks each order's temperature in proportion to its rate,
+// then a template of that temperature. Temperatures with no rate are not
+// generated.
+func (g *GeneratorSource) SplitByTemp(rates map[string]float64) error {
+	temps := make([]string, 0, len(rates))
+	for temp := range rates {
+		temps = append(temps, temp)
+	}
+	sort.Strings(temps) // so a seed always picks the same orders
+
+	var groups []tempGroup
+	total := 0.0
+	for _, temp := range temps {
+		if rates[temp] <= 0 {
+			continue
+		}
+		var templates []OrderData
+		for _, t := range g.templates.items {
+			if t.Temp == temp {
+				templates = append(templates, t)
+			}
+		}
+		if len(templates) == 0 {
+			return fmt.Errorf("no template has temperature %q", temp)
+		}
+		total += rates[temp]
+		groups = append(groups, tempGroup{templates: newMenu(templates), until: total})
+	}
+	if len(groups) == 0 {
+		return errors.New("no temperature has a positive rate")
+	}
+	g.byTemp = groups
+	return nil
+}
+
+// newGenerator creates a generator over templates following the configured
+// rate split, rejecting negative weights
+func newGenerator(cfg *config.Config, templates []OrderData, count int, seed uint64) (*GeneratorSource, error) {
+	for _, t := range templates {
+		if t.Weight < 0 {
+			return nil, fmt.Errorf("%s: weight must not be negative, got %g", t.Name, t.Weight)
+		}
+	}
+	g := NewGeneratorSource(templates, count, seed)
+	if len(cfg.OrdersPerSecondByTemp) > 0 {
+		if err := g.SplitByTemp(cfg.OrdersPerSecondByTemp); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// streamSource supplies orders produced by a goroutine, so Next can give up
+// when ctx is done even while the producer is blocked reading. The producer
+// starts on the first call to Next.
+type streamSource struct {
+	produce func(emit func(*OrderData)) error
+	once    sync.Once
+	orders  chan *OrderData
+	done    chan struct{} // closed once the producer has finished
+	err     error         // why the producer finished, read after done
+}
+
+func newStreamSource(produce func(emit func(*OrderData)) error) *streamSource {
+	return &streamSource{produce: produce, orders: make(chan *OrderData), done: make(chan struct{})}
+}
+
+func (s *streamSource) Next(ctx context.Context) (*OrderData, error) {
+	s.once.Do(func() {
+		go func() {
+			defer close(s.done)
+			s.err = s.produce(func(d *OrderData) { s.orders <- d })
+		}()
+	})
+	select {
+	case d := <-s.orders:
+		return d, nil
+	case <-s.done:
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// NewReaderSource streams orders from r as they arrive, such as from
+// stdin. It accepts a JSON array of orders, or orders one after another as
+// in NDJSON.
+func NewReaderSource(r io.Reader) OrderSource {
+	return newReaderSource(r, false)
+}
+
+func newReaderSource(r io.Reader, strict bool) OrderSource {
+	return newStreamSource(func(emit func(*OrderData)) error {
+		return decodeOrders(r, strict, emit)
+	})
+}
+
+// ordersDocument is a versioned orders file. Unversioned files are a bare
+// array of orders.
+type ordersDocument struct {
+	Version int         `json:"version"`
+	Orders  []OrderData `json:"orders"`
+}
+
+// decodeOrders decodes a JSON array of orders, a sequence of them or a
+// versioned document, emitting each order once it is complete and checked
+// against its schema version. Arrays and sequences are streamed; documents
+// are read whole. In strict mode unknown fields are errors.
+func decodeOrders(r io.Reader, strict bool, emit func(*OrderData)) error {
+	var lines *strictjson.Reader
+	if strict {
+		lines = strictjson.NewReader(r)
+		r = lines
+	}
+	br := bufio.NewReader(r)
+	var skipped int64 // leading whitespace, which the decoder never sees
+	array := false
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !strings.ContainsRune(" \t\r\n", rune(b)) {
+			array = b == '['
+			br.UnreadByte()
+			break
+		}
+		skipped++
+	}
+
+	dec := json.NewDecoder(br)
+	// next reads the next value whole, returning its offset in r
+	next := func() (json.RawMessage, int64, error) {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, 0, err
+		}
+		return raw, skipped + dec.InputOffset() - int64(len(raw)), nil
+	}
+	// unmarshal decodes a value read by next, checking it for unknown
+	// fields first in strict mode
+	unmarshal := func(raw json.RawMessage, start int64, v any) error {
+		if strict {
+			err := strictjson.Check(raw, v)
+			var unknown *strictjson.UnknownFieldError
+			if errors.As(err, &unknown) {
+				unknown.Offset += start
+				unknown.Line, unknown.Column = lines.Position(unknown.Offset)
+			}
+			if err != nil {
+				return err
+			}
+			lines.Advance(start + int64(len(raw)))
+		}
+		return json.Unmarshal(raw, v)
+	}
+	order := func(n int, raw json.RawMessage, start int64) error {
+		var d OrderData
+		if err := unmarshal(raw, start, &d); err != nil {
+			return fmt.Errorf("order %d: %w", n, err)
+		}
+		if err := d.checkSchema(0); err != nil {
+			return fmt.Errorf("order %d: %w", n, err)
+		}
+		emit(&d)
+		return nil
+	}
+
+	n := 1
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	} else {
+		// The first object is either a document or the first order
+		raw, start, err := next()
+		if err != nil {
+			return fmt.Errorf("order 1: %w", err)
+		}
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &keys); err != nil {
+			return fmt.Errorf("order 1: %w", err)
+		}
+		if _, ok := keys["orders"]; ok {
+			return decodeDocument(raw, func(v any) error { return unmarshal(raw, start, v) }, emit)
+		}
+		if err := order(n, raw, start); err != nil {
+			return err
+		}
+		n++
+	}
+	for ; ; n++ {
+		if array && !dec.More() {
+			_, err := dec.Token() // the closing bracket
+			return err
+		}
+		raw, start, err := next()
+		if err != nil {
+			if !array && errors.Is(err, io.EOF) {
+				return nil
+			}
+			return fmt.Errorf("order %d: %w", n, err)
+		}
+		if err := order(n, raw, start); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeDocument emits the orders of a versioned document, decoding it
+// with unmarshal once its version is known to be readable
+func decodeDocument(data []byte, unmarshal func(any) error, emit func(*OrderData)) error {
+	var version struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &version); err != nil {
+		return err
+	}
+	if err := config.CheckSchemaVersion(version.Version); err != nil {
+		return err
+	}
+
+	var doc ordersDocument
+	if err := unmarshal(&doc); err != nil {
+		return err
+	}
+	for i := range doc.Orders {
+		d := &doc.Orders[i]
+		if err := d.checkSchema(doc.Version); err != nil {
+			return fmt.Errorf("order %d: %w", i+1, err)
+		}
+		// Orders keep the file's version, so they can be sent on as they are
+		if d.Version == 0 {
+			d.Version = doc.Version
+		}
+		emit(d)
+	}
+	return nil
+}
+
+// checkSchema checks the order uses only fields of its schema version,
+// which is the file's if the order names none
+func (d *OrderData) checkSchema(fileVersion int) error {
+	version := d.Version
+	if version == 0 {
+		version = fileVersion
+	}
+	if err := config.CheckSchemaVersion(version); err != nil {
+		return err
+	}
+	if version >= config.SchemaV2 {
+		return nil
+	}
+
+	var fields []string
+	if d.Priority != 0 {
+		fields = append(fields, "priority")
+	}
+	if d.Zone != "" {
+		fields = append(fields, "zone")
+	}
+	if d.Price != 0 {
+		fields = append(fields, "price")
+	}
+	if len(fields) > 0 {
+		return fmt.Errorf("uses version %d fields (%s); set \"version\": %d on the file or order", config.SchemaV2, strings.Join(fields, ", "), config.SchemaV2)
+	}
+	return nil
+}
+
+// NewHTTPSource streams orders from the response to a GET of url, in
+// either format NewReaderSource accepts
+func NewHTTPSource(url string) OrderSource {
+	return newHTTPSource(url, false)
+}
+
+func newHTTPSource(url string, strict bool) OrderSource {
+	return newStreamSource(func(emit func(*OrderData)) error {
+		resp, err := http.Get(url)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("GET %s: %s", url, resp.Status)
+		}
+		if err := decodeOrders(resp.Body, strict, emit); err != nil {
+			return fmt.Errorf("GET %s: %w", url, err)
+		}
+		return nil
+	})
+}
+
+// NATSSource supplies the orders published to a NATS subject, each message
+// a JSON order. It connects on the first call to Next and never runs out.
+type NATSSource struct {
+	opts    nats.Options
+	subject string
+	queue   string
+
+	client *nats.Client
+	orders chan *OrderData
+}
+
+// NewNATSSource subscribes to subject, sharing its messages with other
+// subscribers in queue if that is not empty. Messages that are not orders
+// are dropped with a warning.
+func NewNATSSource(opts nats.Options, subject, queue string) *NATSSource {
+	return &NATSSource{opts: opts, subject: subject, queue: queue, orders: make(chan *OrderData, 256)}
+}
+
+func (s *NATSSource) Next(ctx context.Context) (*OrderData, error) {
+	if s.client == nil {
+		if err := s.subscribe(); err != nil {
+			return nil, err
+		}
+	}
+	select {
+	case d := <-s.orders:
+		return d, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *NATSSource) subscribe() error {
+	client, err := nats.Dial(s.opts)
+	if err != nil {
+		return err
+	}
+	err = client.Subscribe(s.subject, s.queue, func(msg nats.Msg) {
+		var d OrderData
+		if err := json.Unmarshal(msg.Data, &d); err != nil {
+			fmt.Printf("⚠️ Dropped a message on NATS subject %s that is not an order: %v\n", msg.Subject, err)
+			return
+		}
+		// Blocking here holds up the connection, so the server buffers
+		// further orders until the run catches up
+		s.orders <- &d
+	})
+	if err != nil {
+		client.Close()
+		return err
+	}
+	s.client = client
+	return nil
+}
+
+// Close disconnects, if Next has connected
+func (s *NATSSource) Close() error {
+	if s.client == nil {
+		return nil
+	}
+	return s.client.Close()
+}
+
+// mergedSource interleaves its sources in the order their orders arrive
+type mergedSource struct {
+	sources []OrderSource
+	once    sync.Once
+	orders  chan *OrderData
+	errs    chan error
+	open    int // sources that have not finished
+}
+
+// MergeSources combines sources. Lists held in memory are concatenated, so
+// runs from files stay reproducible; other sources are read concurrently
+// and their orders interleaved as they arrive. The first source to fail
+// fails the whole.
+func MergeSources(sources ...OrderSource) OrderSource {
+	var lists []OrderData
+	var streams []OrderSource
+	for _, src := range sources {
+		if list, ok := src.(*SliceSource); ok {
+			lists = append(lists, list.orders[list.next:]...)
+		} else {
+			streams = append(streams, src)
+		}
+	}
+	if len(streams) == 0 {
+		return NewSliceSource(lists)
+	}
+	if len(lists) > 0 {
+		streams = append([]OrderSource{NewSliceSource(lists)}, streams...)
+	}
+	if len(streams) == 1 {
+		return streams[0]
+	}
+	return &mergedSource{sources: streams, open: len(streams)}
+}
+
+// start reads every source on its own goroutine until ctx is done
+func (m *mergedSource) start(ctx context.Context) {
+	m.orders = make(chan *OrderData)
+	m.errs = make(chan error, len(m.sources))
+	for _, src := range m.sources {
+		go func() {
+			for {
+				d, err := src.Next(ctx)
+				if err != nil {
+					m.errs <- err
+					return
+				}
+				select {
+				case m.orders <- d:
+				case <-ctx.Done():
+					m.errs <- ctx.Err()
+					return
+				}
+			}
+		}()
+	}
+}
+
+// Next reads from the sources with the context of the first call, which
+// should last the whole run
+func (m *mergedSource) Next(ctx context.Context) (*OrderData, error) {
+	m.once.Do(func() { m.start(ctx) })
+	for m.open > 0 {
+		select {
+		case d := <-m.orders:
+			return d, nil
+		case err := <-m.errs:
+			m.open--
+			if !errors.Is(err, io.EOF) {
+				m.open = 0
+				return nil, err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return nil, io.EOF
+}
+
+// closeSource closes src, and any sources merged into it, that hold a
+// connection
+func closeSource(src OrderSource) {
+	switch src := src.(type) {
+	case io.Closer:
+		src.Close()
+	case *mergedSource:
+		for _, s := range src.sources {
+			closeSource(s)
+		}
+	case *labelledSource:
+		closeSource(src.OrderSource)
+	}
+}
+
+// newOrderSource creates the configured order sources, defaulting to the
+// JSON orders file
+func newOrderSource(cfg *config.Config, ordersFile string) (OrderSource, error) {
+	if len(cfg.Sources) == 0 && cfg.Orders.Mode == config.OrdersModeMenu {
+		templates, err := loadOrdersFile(ordersFile, cfg.Strict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load orders: %w", err)
+		}
+		if len(templates) == 0 {
+			return nil, fmt.Errorf("failed to load orders: %s has no orders to sample", ordersFile)
+		}
+		return newGenerator(cfg, templates, 0, cfg.Orders.Seed)
+	}
+	if len(cfg.Sources) == 0 && cfg.Orders.Watch {
+		src, err := NewFileWatchSource(ordersFile, cfg.Strict, seconds(cfg.Orders.WatchInterval))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load orders: %w", err)
+		}
+		return src, nil
+	}
+	if len(cfg.Sources) == 0 {
+		orders, err := loadOrdersFile(ordersFile, cfg.Strict)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load orders: %w", err)
+		}
+		return NewSliceSource(arrangeOrders(orders, cfg.Orders)), nil
+	}
+
+	// Only files are read here; streams start when the run first asks for
+	// an order
+	sources := make([]OrderSource, 0, len(cfg.Sources))
+	for i, sc := range cfg.Sources {
+		src, err := newConfiguredSource(cfg, sc)
+		if err != nil {
+			return nil, fmt.Errorf("sources[%d] (%s): %w", i, sc.Type, err)
+		}
+		if len(cfg.Sources) > 1 {
+			src = labelSource(src, sc.Label())
+		}
+		sources = append(sources, src)
+	}
+	return MergeSources(sources...), nil
+}
+
+// InputFiles returns the files a run with cfg reads orders from. Streams
+// such as stdin, HTTP and NATS are not files and are left out.
+func InputFiles(cfg *config.Config, ordersFile string) []string {
+	if cfg.Service.Enabled {
+		return nil
+	}
+	if len(cfg.Sources) == 0 {
+		return []string{ordersFile}
+	}
+	var files []string
+	for _, sc := range cfg.Sources {
+		switch sc.Type {
+		case config.SourceJSON, config.SourceCSV, config.SourceGenerator:
+			if sc.Path != "" {
+				files = append(files, sc.Path)
+			}
+		}
+	}
+	return files
+}
+
+// labelSource attributes every order of src to the named source. Lists are
+// labelled in place so they can still be concatenated.
+func labelSource(src OrderSource, name string) OrderSource {
+	if list, ok := src.(*SliceSource); ok {
+		for i := range list.orders {
+			list.orders[i].Source = name
+		}
+		return list
+	}
+	return &labelledSource{OrderSource: src, name: name}
+}
+
+// labelledSource attributes the orders of a stream to a named source
+type labelledSource struct {
+	OrderSource
+	name string
+}
+
+func (l *labelledSource) Next(ctx context.Context) (*OrderData, error) {
+	d, err := l.OrderSource.Next(ctx)
+	if d != nil {
+		d.Source = l.name
+	}
+	return d, err
+}
+
+func newConfiguredSource(cfg *config.Config, sc config.SourceConfig) (OrderSource, error) {
+	switch sc.Type {
+	case config.SourceJSON:
+		return NewJSONFileSource(sc.Path, cfg.Strict)
+	case config.SourceCSV:
+		return NewCSVFileSource(sc.Path)
+	case config.SourceGenerator:
+		var templates []OrderData
+		if sc.Path != "" {
+			var err error
+			if templates, err = loadOrdersFile(sc.Path, cfg.Strict); err != nil {
+				return nil, err
+			}
+		}
+		return newGenerator(cfg, templates, sc.Count, sc.Seed)
+	case config.SourceStdin:
+		return newReaderSource(os.Stdin, cfg.Strict), nil
+	case config.SourceHTTP:
+		return newHTTPSource(sc.URL, cfg.Strict), nil
+	case config.SourceNATS:
+		opts := nats.Options{
+			Addr:     cfg.NATS.Addr,
+			Name:     "dish-dispatcher",
+			User:     cfg.NATS.User,
+			Password: cfg.NATS.Password,
+			Token:    cfg.NATS.Token,
+		}
+		return NewNATSSource(opts, sc.Subject, cfg.NATS.Queue), nil
+	default:
+		return nil, fmt.Errorf("unknown order source type %q", sc.Type)
+	}
+}
+
+// arrangeOrders repeats, shuffles and cuts the orders of a file as
+// configured
+func arrangeOrders(orders []OrderData, cfg config.OrdersConfig) []OrderData {
+	if cfg.Repeat > 1 {
+		repeated := make([]OrderData, 0, len(orders)*cfg.Repeat)
+		for range cfg.Repeat {
+			repeated = append(repeated, orders...)
+		}
+		orders = repeated
+	}
+	if cfg.Shuffle {
+		seed := cfg.Seed
+		if seed == 0 {
+			seed = rand.Uint64()
+		}
+		r := rand.New(rand.NewPCG(seed, seed))
+		r.Shuffle(len(orders), func(i, j int) { orders[i], orders[j] = orders[j], orders[i] })
+	}
+	if cfg.Limit > 0 && cfg.Limit < len(orders) {
+		orders = orders[:cfg.Limit]
+	}
+	return orders
+}
+
+// validateSources checks the configured order sources
+func validateSources(cfg *config.Config) error {
+	if len(cfg.Sources) > 0 && cfg.Service.Enabled {
+		return errors.New("sources are not read in service mode, where orders arrive over the API")
+	}
+	switch cfg.Orders.Mode {
+	case "", config.OrdersModeSequence:
+	case config.OrdersModeMenu:
+		if len(cfg.Sources) > 0 {
+			return errors.New("orders.mode menu samples the orders file, which sources replace; use a generator source instead")
+		}
+		if cfg.Orders.Rearranged() {
+			return errors.New("orders.repeat, shuffle and limit only apply to orders.mode sequence")
+		}
+	default:
+		return fmt.Errorf("unknown orders.mode %q, expected %s or %s", cfg.Orders.Mode, config.OrdersModeSequence, config.OrdersModeMenu)
+	}
+	switch {
+	case cfg.Orders.Repeat < 0:
+		return fmt.Errorf("orders.repeat must not be negative, got %d", cfg.Orders.Repeat)
+	case cfg.Orders.Limit < 0:
+		return fmt.Errorf("orders.limit must not be negative, got %d", cfg.Orders.Limit)
+	case cfg.Orders.Rearranged() && len(cfg.Sources) > 0:
+		return errors.New("orders.repeat, shuffle and limit apply to the orders file, which sources replace")
+	}
+	if cfg.Orders.Watch {
+		switch {
+		case len(cfg.Sources) > 0 || cfg.Service.Enabled:
+			return errors.New("orders.watch watches the orders file, which is not read with sources or in service mode")
+		case cfg.Orders.Mode == config.OrdersModeMenu:
+			return errors.New("orders.watch only applies to orders.mode sequence")
+		case cfg.Orders.Rearranged():
+			return errors.New("orders.watch places the orders file as written, without repeat, shuffle or limit")
+		case cfg.Orders.WatchInterval <= 0:
+			return fmt.Errorf("orders.watchInterval must be positive, got %v", cfg.Orders.WatchInterval)
+		}
+	}
+	stdin := 0
+	labels := make(map[string]bool)
+	for i, sc := range cfg.Sources {
+		var err error
+		switch sc.Type {
+		case config.SourceJSON, config.SourceCSV:
+			if sc.Path == "" {
+				err = errors.New("needs a path")
+			}
+		case config.SourceGenerator:
+			if sc.Count < 0 {
+				err = fmt.Errorf("count must not be negative, got %d", sc.Count)
+			}
+		case config.SourceStdin:
+			if stdin++; stdin > 1 {
+				err = errors.New("stdin can only be read once")
+			}
+		case config.SourceHTTP:
+			if !strings.HasPrefix(sc.URL, "http://") && !strings.HasPrefix(sc.URL, "https://") {
+				err = fmt.Errorf("needs an http or https url, got %q", sc.URL)
+			}
+		case config.SourceNATS:
+			switch {
+			case cfg.NATS.Addr == "":
+				err = errors.New("needs nats.addr")
+			case sc.Subject == "" || strings.ContainsAny(sc.Subject, " \t\r\n"):
+				err = fmt.Errorf("invalid subject %q", sc.Subject)
+			}
+		default:
+			err = fmt.Errorf("unknown type %q", sc.Type)
+		}
+		if err == nil && labels[sc.Label()] {
+			err = fmt.Errorf("%q names another source too; set name to tell them apart", sc.Label())
+		}
+		labels[sc.Label()] = true
+		if err != nil {
+			return fmt.Errorf("sources[%d]: %w", i, err)
+		}
+	}
+	return validateTempRates(cfg)
+}
+
+// validateTempRates checks the rate split by temperature, which only
+// generators can follow
+func validateTempRates(cfg *config.Config) error {
+	if len(cfg.OrdersPerSecondByTemp) == 0 {
+		return nil
+	}
+	if len(cfg.Sources) == 0 && cfg.Orders.Mode != config.OrdersModeMenu {
+		return errors.New("ordersPerSecondByTemp needs generator sources or orders.mode menu")
+	}
+	for i, sc := range cfg.Sources {
+		if sc.Type != config.SourceGenerator {
+			return fmt.Errorf("ordersPerSecondByTemp needs every source to be a generator, sources[%d] is %s", i, sc.Type)
+		}
+	}
+	if tempRatesTotal(cfg.OrdersPerSecondByTemp) <= 0 {
+		return errors.New("ordersPerSecondByTemp needs a positive rate")
+	}
+	for temp, rate := range cfg.OrdersPerSecondByTemp {
+		if rate < 0 {
+			return fmt.Errorf("ordersPerSecondByTemp: %s must not be negative, got %g", temp, rate)
+		}
+	}
+	return nil
+}
+
+// tempRatesTotal returns the run's rate when split by temperature
+func tempRatesTotal(rates map[string]float64) float64 {
+	total := 0.0
+	for _, rate := range rates {
+		if rate > 0 {
+			total += rate
+		}
+	}
+	return total
+}
+
+// endlessSources reports whether the orders never run out, so the run only
+// ends when its time is up
+func endlessSources(cfg *config.Config) bool {
+	if len(cfg.Sources) == 0 && (cfg.Orders.Mode == config.OrdersModeMenu || cfg.Orders.Watch) {
+		return true
+	}
+	for _, sc := range cfg.Sources {
+		if sc.Type == config.SourceNATS || (sc.Type == config.SourceGenerator && sc.Count == 0) {
+			return true
+		}
+	}
+	return false
+}
+
+// preloadedOrders returns every order of a source read up front, or nil if
+// the source streams them
+func preloadedOrders(src OrderSource) []OrderData {
+	if list, ok := src.(*SliceSource); ok {
+		return list.Orders()
+	}
+	return nil
+}
+
+// nextOrder takes the next order from the source. Under the strict unknown
+// temperature policy, streamed orders no shelf accepts are skipped with a
+// warning; orders read up front were checked before the run.
+func (s *Simulator) nextOrder(ctx context.Context) (*OrderData, error) {
+	for {
+		d, err := s.Source.Next(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if s.Config.UnknownTemps.Policy == config.UnknownTempStrict {
+			if err := checkOrderTemps(s.ShelfManager, []OrderData{*d}); err != nil {
+				s.logf("⚠️ Skipped order: %v\n", err)
+				continue
+			}
+		}
+		return d, nil
+	}
+}
+
+// stopContext returns a context canceled once the simulation stops, for
+// reading the source
+func (s *Simulator) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// printOrderCount reports how many orders the run will place, if the source
+// knows up front
+func (s *Simulator) printOrderCount() {
+	if list, ok := s.Source.(*SliceSource); ok {
+		fmt.Printf("Total orders to process: %d\n", list.Len())
+	} else if watch, ok := s.Source.(*FileWatchSource); ok {
+		fmt.Printf("Orders: %d, then any appended to %s until the run ends\n", watch.Read(), watch.path)
+	} else if menu, ok := s.Source.(*GeneratorSource); ok {
+		fmt.Printf("Orders: sampled from %d menu items until the run ends\n", menu.Templates())
+	} else {
+		fmt.Println("Orders: streamed from the configured sources")
+	}
+}
+
+// sourceTotals counts the orders taken from one source and their outcomes
+type sourceTotals struct {
+	received int
+	outcomes shelf.ItemStats
+}
+
+// sourceStats breaks outcomes down by the source that supplied each order,
+// for runs merging several. Orders with no source are not counted. The zero
+// value is ready to use.
+type sourceStats struct {
+	mutex    sync.Mutex
+	bySource map[string]sourceTotals
+}
+
+// receive counts an order taken from its source
+func (ss *sourceStats) receive(o *order.Order) {
+	if o.Source == "" {
+		return
+	}
+	ss.update(o.Source, func(t *sourceTotals) { t.received++ })
+}
+
+// observe is an order.TransitionHook counting the outcome of each order
+func (ss *sourceStats) observe(o *order.Order, from, to order.State, at time.Time) {
+	if o.Source == "" {
+		return
+	}
+	switch to {
+	case order.StateDelivered:
+		value := o.CalculateValue(at)
+		ss.update(o.Source, func(t *sourceTotals) {
+			t.outcomes.Delivered++
+			t.outcomes.TotalDeliveredValue += value
+		})
+	case order.StateWasted:
+		ss.update(o.Source, func(t *sourceTotals) { t.outcomes.Wasted++ })
+	case order.StateExpired:
+		ss.update(o.Source, func(t *sourceTotals) { t.outcomes.Expired++ })
+	}
+}
+
+func (ss *sourceStats) update(source string, change func(*sourceTotals)) {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	if ss.bySource == nil {
+		ss.bySource = make(map[string]sourceTotals)
+	}
+	totals := ss.bySource[source]
+	change(&totals)
+	ss.bySource[source] = totals
+}
+
+// snapshot returns a copy of the totals by source
+func (ss *sourceStats) snapshot() map[string]sourceTotals {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	totals := make(map[string]sourceTotals, len(ss.bySource))
+	for source, t := range ss.bySource {
+		totals[source] = t
+	}
+	return totals
+}
+
+// reset discards every count
+func (ss *sourceStats) reset() {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.bySource = nil
+}
+
+// ObserveTransition is the order.TransitionHook attached to every order the
+// simulator places. It archives finished orders, attributes outcomes to
+// the order's source, counts them in the recent rates and metrics and
+// writes them to the order results.
+// Shelf backends that rebuild orders, such as Redis, should attach it to
+// the orders they load.
+func (s *Simulator) ObserveTransition(o *order.Order, from, to order.State, at time.Time) {
+	s.Archive.Observe(o, from, to, at)
+	s.sources.observe(o, from, to, at)
+	s.recent.observe(o, from, to, at)
+	s.observeMetrics(o, from, to, at)
+	s.escalation.observe(o, to)
+	s.results.observe(o, from, to, at)
+	s.tracer.observe(o, from, to, at)
+	s.waste.observe(o, from, to, at)
+}
+
+// printSourceStats prints the outcome breakdown by source, if the run
+// merged several
+func (s *Simulator) printSourceStats() {
+	bySource := s.sources.snapshot()
+	if len(bySource) == 0 {
+		return
+	}
+	names := make([]string, 0, len(bySource))
+	for name := range bySource {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Println("\n📥 BY SOURCE:")
+	for _, name := range names {
+		t := bySource[name]
+		fmt.Printf("  %s: received %d, delivered %d (avg value %.2f), wasted %d, expired %d\n",
+			name, t.received, t.outcomes.Delivered, t.outcomes.AverageDeliveredValue(), t.outcomes.Wasted, t.outcomes.Expired)
+	}
+}
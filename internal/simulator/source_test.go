@@ -0,0 +1,575 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+// drain reads every order from src
+func drain(t *testing.T, src OrderSource) []string {
+	t.Helper()
+
+	var names []string
+	for {
+		d, err := src.Next(context.Background())
+		if errors.Is(err, io.EOF) {
+			return names
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error after %v: %v", names, err)
+		}
+		names = append(names, d.Name)
+	}
+}
+
+// drainN reads n orders from src
+func drainN(t *testing.T, src OrderSource, n int) []string {
+	t.Helper()
+
+	names := make([]string, n)
+	for i := range names {
+		d, err := src.Next(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error after %d orders: %v", i, err)
+		}
+		names[i] = d.Name
+	}
+	return names
+}
+
+func TestLoadOrdersFromCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.csv")
+	data := "name,temp,shelfLife,decayRate,maxTemp\nBurger,hot,300,0.5,\n\"Ice Cream, Vanilla\",frozen,200,0.2,-10\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := LoadOrdersFromCSV(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("Expected 2 orders, got %+v", orders)
+	}
+	if got := orders[0]; got.Name != "Burger" || got.Temp != "hot" || got.ShelfLife != 300 || got.DecayRate != 0.5 || got.MaxTemp != nil {
+		t.Errorf("Unexpected first order %+v", got)
+	}
+	if got := orders[1]; got.Name != "Ice Cream, Vanilla" || got.MaxTemp == nil || *got.MaxTemp != -10 {
+		t.Errorf("Unexpected second order %+v", got)
+	}
+
+	for _, bad := range []string{
+		"name,temp\nBurger,hot\n",                          // no shelfLife
+		"name,temp,shelfLife,colour\nBurger,hot,300,red\n", // unknown column
+		"name,temp,shelfLife\nBurger,hot,long\n",           // not a number
+	} {
+		if err := os.WriteFile(path, []byte(bad), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadOrdersFromCSV(path); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestReaderSource(t *testing.T) {
+	tests := map[string]string{
+		"array":  ` [{"name":"Burger"}, {"name":"Salad"}] `,
+		"ndjson": "{\"name\":\"Burger\"}\n{\"name\":\"Salad\"}\n",
+	}
+	for format, input := range tests {
+		got := drain(t, NewReaderSource(strings.NewReader(input)))
+		if want := []string{"Burger", "Salad"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", format, want, got)
+		}
+	}
+
+	src := NewReaderSource(strings.NewReader(`{"name":"Burger"} {"name":`))
+	if _, err := src.Next(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := src.Next(context.Background()); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Expected a truncated order to fail, got %v", err)
+	}
+}
+
+func TestReaderSource_Canceled(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := NewReaderSource(r).Next(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a blocked read to give up, got %v", err)
+	}
+}
+
+func TestHTTPSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/orders" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `[{"name":"Burger"},{"name":"Salad"}]`)
+	}))
+	defer server.Close()
+
+	if got := drain(t, NewHTTPSource(server.URL+"/orders")); !reflect.DeepEqual(got, []string{"Burger", "Salad"}) {
+		t.Errorf("Unexpected orders %v", got)
+	}
+	if _, err := NewHTTPSource(server.URL + "/missing").Next(context.Background()); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("Expected a 404 to fail, got %v", err)
+	}
+}
+
+func TestGeneratorSource(t *testing.T) {
+	templates := []OrderData{{Name: "Burger"}, {Name: "Salad"}, {Name: "Soup"}}
+	first := drain(t, NewGeneratorSource(templates, 20, 7))
+	if len(first) != 20 {
+		t.Fatalf("Expected 20 orders, got %d", len(first))
+	}
+	if again := drain(t, NewGeneratorSource(templates, 20, 7)); !reflect.DeepEqual(first, again) {
+		t.Errorf("Expected the same seed to generate the same orders")
+	}
+
+	endless := NewGeneratorSource(nil, 0, 1)
+	for i := 0; i < 1000; i++ {
+		if _, err := endless.Next(context.Background()); err != nil {
+			t.Fatalf("Expected an endless generator, got %v after %d orders", err, i)
+		}
+	}
+}
+
+func TestGeneratorSource_Weights(t *testing.T) {
+	templates := []OrderData{{Name: "Burger", Weight: 3}, {Name: "Salad"}, {Name: "Soup", Weight: 0}}
+	counts := make(map[string]int)
+	for _, name := range drain(t, NewGeneratorSource(templates, 10000, 3)) {
+		counts[name]++
+	}
+	// An unset weight counts as 1
+	if share := float64(counts["Burger"]) / 10000; share < 0.57 || share > 0.63 {
+		t.Errorf("Expected about 60%% burgers, got %.1f%%", share*100)
+	}
+	if counts["Salad"] == 0 || counts["Soup"] == 0 {
+		t.Errorf("Expected every item to be picked, got %v", counts)
+	}
+
+	if _, err := newGenerator(config.DefaultConfig(), []OrderData{{Name: "Burger", Weight: -1}}, 0, 1); err == nil {
+		t.Errorf("Expected a negative weight to be rejected")
+	}
+}
+
+func TestNewOrderSource_Menu(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "menu.json")
+	if err := os.WriteFile(path, []byte(`[{"name":"Burger","temp":"hot","shelfLife":300,"weight":2},{"name":"Salad","temp":"cold","shelfLife":300}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Orders = config.OrdersConfig{Mode: config.OrdersModeMenu, Seed: 9}
+	if err := validateSources(cfg); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !endlessSources(cfg) {
+		t.Errorf("Expected a menu to be endless")
+	}
+	src, err := newOrderSource(cfg, path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// Well past the two items in the file
+	for _, name := range drainN(t, src, 100) {
+		if name != "Burger" && name != "Salad" {
+			t.Fatalf("Unexpected order %q", name)
+		}
+	}
+
+	cfg.OrdersPerSecondByTemp = map[string]float64{"cold": 1}
+	if err := validateSources(cfg); err != nil {
+		t.Errorf("Expected a menu to follow the rates by temperature, got %v", err)
+	}
+	if src, err = newOrderSource(cfg, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := drainN(t, src, 10); !reflect.DeepEqual(got, strings.Fields(strings.Repeat("Salad ", 10))) {
+		t.Errorf("Expected only cold orders, got %v", got)
+	}
+
+	cfg.SimulationDuration = 0
+	if _, err := NewDiscreteEngine(cfg, path); err == nil {
+		t.Errorf("Expected the discrete engine to need a duration to stop a menu")
+	}
+
+	for _, invalid := range []func(*config.Config){
+		func(c *config.Config) { c.Orders.Mode = "shuffle" },
+		func(c *config.Config) { c.Sources = []config.SourceConfig{{Type: config.SourceGenerator}} },
+	} {
+		c := *cfg
+		invalid(&c)
+		if err := validateSources(&c); err == nil {
+			t.Errorf("Expected %+v with %d sources to be rejected", c.Orders, len(c.Sources))
+		}
+	}
+}
+
+func TestGeneratorSource_SplitByTemp(t *testing.T) {
+	g := NewGeneratorSource(nil, 10000, 5)
+	if err := g.SplitByTemp(map[string]float64{"hot": 1.5, "cold": 0.5, "frozen": 0}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	counts := make(map[string]int)
+	for {
+		d, err := g.Next(context.Background())
+		if err != nil {
+			break
+		}
+		counts[d.Temp]++
+	}
+	if counts["frozen"] != 0 {
+		t.Errorf("Expected no frozen orders at a zero rate, got %d", counts["frozen"])
+	}
+	if share := float64(counts["hot"]) / 10000; share < 0.72 || share > 0.78 {
+		t.Errorf("Expected about 75%% hot orders, got %.1f%%", share*100)
+	}
+
+	if err := NewGeneratorSource(nil, 0, 1).SplitByTemp(map[string]float64{"ambient": 1}); err == nil {
+		t.Errorf("Expected a temperature with no templates to be rejected")
+	}
+}
+
+func TestValidateTempRates(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecondByTemp = map[string]float64{"hot": 1.5, "cold": 0.5, "frozen": 0.3}
+	if err := validateSources(cfg); err == nil {
+		t.Errorf("Expected the default orders file to be rejected")
+	}
+
+	cfg.Sources = []config.SourceConfig{{Type: config.SourceGenerator, Count: 10}}
+	if err := validateSources(cfg); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if got := tempRatesTotal(cfg.OrdersPerSecondByTemp); got != 2.3 {
+		t.Errorf("Expected a total rate of 2.3, got %v", got)
+	}
+
+	for _, invalid := range []func(*config.Config){
+		func(c *config.Config) { c.Sources = append(c.Sources, config.SourceConfig{Type: config.SourceStdin}) },
+		func(c *config.Config) { c.OrdersPerSecondByTemp = map[string]float64{"hot": -1, "cold": 2} },
+		func(c *config.Config) { c.OrdersPerSecondByTemp = map[string]float64{"hot": 0} },
+	} {
+		c := *cfg
+		invalid(&c)
+		if err := validateSources(&c); err == nil {
+			t.Errorf("Expected %+v to be rejected", c.OrdersPerSecondByTemp)
+		}
+	}
+}
+
+func TestMergeSources(t *testing.T) {
+	a := NewSliceSource([]OrderData{{Name: "A1"}, {Name: "A2"}})
+	b := NewSliceSource([]OrderData{{Name: "B1"}})
+
+	// Lists stay in order, so file runs remain reproducible
+	merged := MergeSources(a, b)
+	if _, ok := merged.(*SliceSource); !ok {
+		t.Errorf("Expected lists to merge into a list, got %T", merged)
+	}
+	if got := drain(t, merged); !reflect.DeepEqual(got, []string{"A1", "A2", "B1"}) {
+		t.Errorf("Unexpected orders %v", got)
+	}
+
+	// Streams are interleaved, so only the set of orders is known
+	stream := NewReaderSource(strings.NewReader(`{"name":"S1"} {"name":"S2"}`))
+	got := drain(t, MergeSources(NewSliceSource([]OrderData{{Name: "A1"}}), stream))
+	sort.Strings(got)
+	if want := []string{"A1", "S1", "S2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	merged = MergeSources(NewSliceSource([]OrderData{{Name: "A1"}}), NewReaderSource(strings.NewReader(`not json`)))
+	var err error
+	for err == nil {
+		_, err = merged.Next(context.Background())
+	}
+	if errors.Is(err, io.EOF) {
+		t.Errorf("Expected the failing source to fail the merge")
+	}
+}
+
+func TestNewOrderSource_LabelsMergedSources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "orders.json")
+	if err := os.WriteFile(path, []byte(`[{"name":"Burger","temp":"hot","shelfLife":300}]`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Sources = []config.SourceConfig{{Type: config.SourceJSON, Path: path}}
+	src, err := newOrderSource(cfg, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d, _ := src.Next(context.Background()); d.Source != "" {
+		t.Errorf("Expected a lone source to be unlabelled, got %q", d.Source)
+	}
+
+	cfg.Sources = []config.SourceConfig{
+		{Type: config.SourceJSON, Path: path},
+		{Name: "baseline", Type: config.SourceGenerator, Count: 2, Seed: 1},
+	}
+	if src, err = newOrderSource(cfg, ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	counts := make(map[string]int)
+	for {
+		d, err := src.Next(context.Background())
+		if err != nil {
+			break
+		}
+		counts[d.Source]++
+	}
+	if want := map[string]int{"json": 1, "baseline": 2}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected %v, got %v", want, counts)
+	}
+}
+
+func TestSourceStats(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Source = MergeSources(
+		labelSource(NewSliceSource([]OrderData{
+			{Name: "Burger", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+			{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5},
+		}), "baseline"),
+		labelSource(NewSliceSource([]OrderData{{Name: "Bread", Temp: "ambient", ShelfLife: 300}}), "adhoc"),
+	)
+	s.placeOrders(context.Background(), 3)
+
+	burger := s.ShelfManager.GetAllOrders()[0]
+	if !s.ShelfManager.DeliverOrder(burger.ID) {
+		t.Fatalf("Expected %s to be delivered", burger.Name)
+	}
+
+	got := s.sources.snapshot()
+	if b := got["baseline"]; b.received != 2 || b.outcomes.Delivered != 1 || b.outcomes.TotalDeliveredValue <= 0 {
+		t.Errorf("Unexpected baseline totals %+v", b)
+	}
+	if a := got["adhoc"]; a.received != 1 || a.outcomes.Wasted != 1 {
+		t.Errorf("Expected the ambient order to be wasted, got %+v", a)
+	}
+
+	s.sources.reset()
+	if got := s.sources.snapshot(); len(got) != 0 {
+		t.Errorf("Expected no totals after a reset, got %v", got)
+	}
+}
+
+func TestNextOrder_StrictSkipsUnknownTemps(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.UnknownTemps.Policy = config.UnknownTempStrict
+	s.Source = NewReaderSource(strings.NewReader(`{"name":"Bread","temp":"ambient"} {"name":"Burger","temp":"hot"}`))
+
+	d, err := s.nextOrder(context.Background())
+	if err != nil || d.Name != "Burger" {
+		t.Errorf("Expected the ambient order to be skipped, got %+v, %v", d, err)
+	}
+}
+
+func TestArrangeOrders(t *testing.T) {
+	file := func() []OrderData {
+		return []OrderData{{Name: "A"}, {Name: "B"}, {Name: "C"}}
+	}
+	names := func(orders []OrderData) []string {
+		return drain(t, NewSliceSource(orders))
+	}
+
+	if got := names(arrangeOrders(file(), config.OrdersConfig{})); !reflect.DeepEqual(got, []string{"A", "B", "C"}) {
+		t.Errorf("Expected the file as it is, got %v", got)
+	}
+	if got := names(arrangeOrders(file(), config.OrdersConfig{Repeat: 2, Limit: 4})); !reflect.DeepEqual(got, []string{"A", "B", "C", "A"}) {
+		t.Errorf("Expected the first 4 of the repeated file, got %v", got)
+	}
+	if got := names(arrangeOrders(file(), config.OrdersConfig{Limit: 10})); len(got) != 3 {
+		t.Errorf("Expected a limit past the end to keep every order, got %v", got)
+	}
+
+	shuffled := names(arrangeOrders(file(), config.OrdersConfig{Repeat: 10, Shuffle: true, Seed: 4}))
+	if again := names(arrangeOrders(file(), config.OrdersConfig{Repeat: 10, Shuffle: true, Seed: 4})); !reflect.DeepEqual(shuffled, again) {
+		t.Errorf("Expected the same seed to shuffle the same way")
+	}
+	if unshuffled := names(arrangeOrders(file(), config.OrdersConfig{Repeat: 10})); reflect.DeepEqual(shuffled, unshuffled) {
+		t.Errorf("Expected the orders to be shuffled")
+	}
+	sort.Strings(shuffled)
+	if shuffled[0] != "A" || shuffled[9] != "A" || shuffled[10] != "B" {
+		t.Errorf("Expected every order 10 times, got %v", shuffled)
+	}
+
+	cfg := config.DefaultConfig()
+	for _, invalid := range []config.OrdersConfig{
+		{Mode: config.OrdersModeSequence, Repeat: -1},
+		{Mode: config.OrdersModeSequence, Limit: -1},
+		{Mode: config.OrdersModeMenu, Shuffle: true},
+	} {
+		cfg.Orders = invalid
+		if err := validateSources(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", invalid)
+		}
+	}
+	cfg.Orders = config.OrdersConfig{Mode: config.OrdersModeSequence, Limit: 5}
+	cfg.Sources = []config.SourceConfig{{Type: config.SourceGenerator, Count: 10}}
+	if err := validateSources(cfg); err == nil {
+		t.Errorf("Expected a limit on the orders file to be rejected with sources")
+	}
+}
+
+func TestFileWatchSource(t *testing.T) {
+	soup := OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	salad := OrderData{Name: "Salad", Temp: "cold", ShelfLife: 300, DecayRate: 0.5}
+	path := writeOrders(t, []OrderData{soup})
+	src, err := NewFileWatchSource(path, false, 5*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next := func() (*OrderData, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return src.Next(ctx)
+	}
+
+	if d, err := next(); err != nil || d.Name != "Soup" {
+		t.Fatalf("Expected the file's order, got %+v, %v", d, err)
+	}
+
+	// A half-written file is skipped until it parses again
+	if err := os.WriteFile(path, []byte(`[{"name": "Soup"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		raw, _ := json.Marshal([]OrderData{soup, salad})
+		os.WriteFile(path, raw, 0o644)
+	}()
+	if d, err := next(); err != nil || d.Name != "Salad" {
+		t.Fatalf("Expected the appended order, got %+v, %v", d, err)
+	}
+	if src.Read() != 2 {
+		t.Errorf("Expected 2 orders read, got %d", src.Read())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := src.Next(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected to wait for more orders until canceled, got %v", err)
+	}
+}
+
+func TestValidateSources_Watch(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Orders.Watch = true
+	if err := validateSources(cfg); err != nil {
+		t.Errorf("Expected watching the orders file to be valid, got %v", err)
+	}
+	if !endlessSources(cfg) {
+		t.Errorf("Expected a watched file never to run out")
+	}
+
+	for _, change := range []func(*config.Config){
+		func(cfg *config.Config) { cfg.Sources = []config.SourceConfig{{Type: config.SourceStdin}} },
+		func(cfg *config.Config) { cfg.Orders.Mode = config.OrdersModeMenu },
+		func(cfg *config.Config) { cfg.Orders.Shuffle = true },
+		func(cfg *config.Config) { cfg.Orders.WatchInterval = 0 },
+	} {
+		cfg := config.DefaultConfig()
+		cfg.Orders.Watch = true
+		change(cfg)
+		if err := validateSources(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg.Orders)
+		}
+	}
+}
+
+func TestValidateSources(t *testing.T) {
+	valid := []config.SourceConfig{
+		{Type: config.SourceJSON, Path: "orders.json"},
+		{Type: config.SourceCSV, Path: "orders.csv"},
+		{Type: config.SourceGenerator, Count: 100},
+		{Name: "generator-2", Type: config.SourceGenerator, Count: 5},
+		{Type: config.SourceStdin},
+		{Type: config.SourceHTTP, URL: "https://kitchen.example/orders"},
+	}
+	cfg := config.DefaultConfig()
+	cfg.Sources = valid
+	if err := validateSources(cfg); err != nil {
+		t.Errorf("Expected the sources to be valid, got %v", err)
+	}
+	if endlessSources(cfg) {
+		t.Errorf("Expected the sources to be finite")
+	}
+
+	cfg.NATS.Addr = "localhost:4222"
+	cfg.Sources = []config.SourceConfig{{Type: config.SourceNATS, Subject: "orders.new"}}
+	if err := validateSources(cfg); err != nil {
+		t.Errorf("Expected the NATS source to be valid, got %v", err)
+	}
+	if !endlessSources(cfg) {
+		t.Errorf("Expected the NATS source to be endless")
+	}
+
+	invalid := [][]config.SourceConfig{
+		{{Type: "kafka"}},
+		{{Type: config.SourceJSON}},
+		{{Type: config.SourceGenerator, Count: -1}},
+		{{Type: config.SourceStdin}, {Type: config.SourceStdin}},
+		{{Type: config.SourceGenerator}, {Type: config.SourceGenerator, Seed: 1}},
+		{{Name: "a", Type: config.SourceGenerator}, {Name: "a", Type: config.SourceStdin}},
+		{{Type: config.SourceHTTP, URL: "ftp://kitchen.example/orders"}},
+		{{Type: config.SourceNATS, Subject: "new orders"}},
+	}
+	for _, sources := range invalid {
+		cfg.Sources = sources
+		if err := validateSources(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", sources)
+		}
+	}
+
+	cfg.Sources = valid[:1]
+	cfg.Service.Enabled = true
+	if err := validateSources(cfg); err == nil {
+		t.Errorf("Expected sources to be rejected in service mode")
+	}
+}
+
+func TestInputFiles(t *testing.T) {
+	cfg := config.DefaultConfig()
+	if got := InputFiles(cfg, "orders.json"); !reflect.DeepEqual(got, []string{"orders.json"}) {
+		t.Errorf("Expected the orders file, got %v", got)
+	}
+
+	cfg.Sources = []config.SourceConfig{
+		{Type: config.SourceCSV, Path: "orders.csv"},
+		{Type: config.SourceGenerator, Count: 10},
+		{Type: config.SourceGenerator, Path: "menu.json"},
+		{Type: config.SourceStdin},
+	}
+	if got := InputFiles(cfg, "orders.json"); !reflect.DeepEqual(got, []string{"orders.csv", "menu.json"}) {
+		t.Errorf("Expected only the source files, got %v", got)
+	}
+
+	cfg.Sources = nil
+	cfg.Service.Enabled = true
+	if got := InputFiles(cfg, "orders.json"); len(got) != 0 {
+		t.Errorf("Expected no files in service mode, got %v", got)
+	}
+}
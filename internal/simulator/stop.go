@@ -0,0 +1,115 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+// stopCheckInterval is how often the stop conditions are evaluated
+const stopCheckInterval = 250 * time.Millisecond
+
+// validateStopConfig rejects negative stop conditions and unknown idle
+// starts
+func validateStopConfig(cfg config.StopConfig) error {
+	if cfg.MaxOrders < 0 || cfg.MaxDeliveries < 0 || cfg.MinOrders < 0 || cfg.IdleFor < 0 {
+		return errors.New("stop conditions must not be negative")
+	}
+	switch cfg.IdleFrom {
+	case "", config.StopIdleFromFirstOrder, config.StopIdleFromStart:
+	default:
+		return fmt.Errorf("unknown stop idleFrom %q, want %q or %q", cfg.IdleFrom, config.StopIdleFromFirstOrder, config.StopIdleFromStart)
+	}
+	if cfg.MaxWasteRate < 0 || cfg.MaxWasteRate > 100 {
+		return fmt.Errorf("stop waste rate must be between 0 and 100, got %v", cfg.MaxWasteRate)
+	}
+	return nil
+}
+
+// stopConditionsEnabled reports whether any stop condition is configured
+func stopConditionsEnabled(cfg config.StopConfig) bool {
+	return cfg.MaxOrders > 0 || cfg.MaxDeliveries > 0 || cfg.MaxWasteRate > 0 || cfg.IdleFor > 0
+}
+
+// stopTotals is the part of the manager's stats the stop conditions use
+type stopTotals struct {
+	received, delivered, lost int
+	shelved                   int
+}
+
+// stopReason returns why the simulation should stop given the totals and
+// how long the shelves have been empty with no new order, or "" to keep
+// running
+func stopReason(cfg config.StopConfig, t stopTotals, idle time.Duration) string {
+	if cfg.MaxOrders > 0 && t.received >= cfg.MaxOrders {
+		return fmt.Sprintf("%d orders placed", t.received)
+	}
+	if cfg.MaxDeliveries > 0 && t.delivered >= cfg.MaxDeliveries {
+		return fmt.Sprintf("%d orders delivered", t.delivered)
+	}
+	if cfg.MaxWasteRate > 0 && t.received > 0 && t.received >= cfg.MinOrders {
+		rate := float64(t.lost) / float64(t.received) * 100
+		if rate > cfg.MaxWasteRate {
+			return fmt.Sprintf("waste rate %.1f%% exceeds %.1f%%", rate, cfg.MaxWasteRate)
+		}
+	}
+	if cfg.IdleFor > 0 && idle >= time.Duration(cfg.IdleFor)*time.Second {
+		return fmt.Sprintf("no orders and empty shelves for %v", idle.Truncate(time.Second))
+	}
+	return ""
+}
+
+// currentTotals reads the stop condition totals from the shelf manager
+func (s *Simulator) currentTotals() stopTotals {
+	totals := s.ShelfManager.GetStats()["totalOrders"].(map[string]interface{})
+	t := stopTotals{
+		received:  totals["received"].(int),
+		delivered: totals["delivered"].(int),
+		lost:      totals["wasted"].(int) + totals["expired"].(int),
+	}
+	for _, state := range s.ShelfManager.ShelfStates() {
+		t.shelved += len(state.Orders)
+	}
+	return t
+}
+
+// watchStopConditions stops the simulation once any configured stop
+// condition is met
+func (s *Simulator) watchStopConditions() {
+	defer s.wg.Done()
+
+	cfg := s.Config.Stop
+	ticker := time.NewTicker(stopCheckInterval)
+	defer ticker.Stop()
+
+	// Each new order restarts the idle clock, which only starts with the
+	// first order unless it is set to run from the start
+	var idleSince time.Time
+	if cfg.IdleFrom == config.StopIdleFromStart {
+		idleSince = time.Now()
+	}
+	lastReceived := 0
+	for {
+		select {
+		case now := <-ticker.C:
+			t := s.currentTotals()
+			if t.received != lastReceived || t.shelved > 0 {
+				idleSince, lastReceived = now, t.received
+			}
+			var idle time.Duration
+			if t.shelved == 0 && !idleSince.IsZero() {
+				idle = now.Sub(idleSince)
+			}
+
+			if reason := stopReason(cfg, t, idle); reason != "" {
+				fmt.Printf("Stop condition reached: %s\n", reason)
+				s.halt()
+				return
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
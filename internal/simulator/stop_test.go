@@ -0,0 +1,127 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+)
+
+func TestStopReason(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.StopConfig
+		totals stopTotals
+		idle   time.Duration
+		stop   bool
+	}{
+		{"disabled", config.StopConfig{}, stopTotals{received: 100, lost: 100}, time.Hour, false},
+		{"orders below", config.StopConfig{MaxOrders: 10}, stopTotals{received: 9}, 0, false},
+		{"orders reached", config.StopConfig{MaxOrders: 10}, stopTotals{received: 10}, 0, true},
+		{"deliveries reached", config.StopConfig{MaxDeliveries: 5}, stopTotals{received: 8, delivered: 5}, 0, true},
+		{"waste below", config.StopConfig{MaxWasteRate: 50}, stopTotals{received: 10, lost: 5}, 0, false},
+		{"waste exceeded", config.StopConfig{MaxWasteRate: 50}, stopTotals{received: 10, lost: 6}, 0, true},
+		{"waste too early", config.StopConfig{MaxWasteRate: 50, MinOrders: 20}, stopTotals{received: 10, lost: 10}, 0, false},
+		{"idle below", config.StopConfig{IdleFor: 5}, stopTotals{received: 1}, 4 * time.Second, false},
+		{"idle reached", config.StopConfig{IdleFor: 5}, stopTotals{received: 1}, 5 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		reason := stopReason(tt.cfg, tt.totals, tt.idle)
+		if tt.stop && reason == "" {
+			t.Errorf("%s: expected the simulation to stop", tt.name)
+		}
+		if !tt.stop && reason != "" {
+			t.Errorf("%s: expected the simulation to keep running, got %q", tt.name, reason)
+		}
+	}
+}
+
+func TestValidateStopConfig(t *testing.T) {
+	if err := validateStopConfig(config.StopConfig{MaxOrders: 1, MaxWasteRate: 100, IdleFrom: config.StopIdleFromStart}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, cfg := range []config.StopConfig{
+		{MaxOrders: -1},
+		{IdleFor: -1},
+		{IdleFor: 5, IdleFrom: "lastOrder"},
+		{MaxWasteRate: 101},
+	} {
+		if err := validateStopConfig(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
+
+func TestWatchStopConditions_MaxDeliveries(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Stop = config.StopConfig{MaxDeliveries: 1}
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	s.ShelfManager.PlaceOrder(o)
+	s.ShelfManager.DeliverOrder(o.ID)
+
+	s.wg.Add(1)
+	go s.watchStopConditions()
+
+	select {
+	case <-s.stop:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the simulation to stop after one delivery")
+	}
+	s.wg.Wait()
+}
+
+func TestWatchStopConditions_Idle(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Stop = config.StopConfig{IdleFor: 1}
+
+	s.wg.Add(1)
+	go s.watchStopConditions()
+
+	// Empty shelves do not count as idle before the first order
+	select {
+	case <-s.stop:
+		t.Fatal("Expected the simulation to wait for the first order")
+	case <-time.After(1500 * time.Millisecond):
+	}
+
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	s.ShelfManager.PlaceOrder(o)
+	s.ShelfManager.DeliverOrder(o.ID)
+
+	select {
+	case <-s.stop:
+	case <-time.After(3 * time.Second):
+		t.Fatal("Expected the simulation to stop once the shelves were idle")
+	}
+	s.wg.Wait()
+}
+
+func TestWatchStopConditions_IdleFromStart(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Config.Stop = config.StopConfig{IdleFor: 1, IdleFrom: config.StopIdleFromStart}
+
+	// A new order keeps the run going even though it leaves the shelves
+	// empty again
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	time.AfterFunc(700*time.Millisecond, func() {
+		s.ShelfManager.PlaceOrder(o)
+		s.ShelfManager.DeliverOrder(o.ID)
+	})
+	start := time.Now()
+
+	s.wg.Add(1)
+	go s.watchStopConditions()
+
+	select {
+	case <-s.stop:
+		if waited := time.Since(start); waited < 1500*time.Millisecond {
+			t.Errorf("Expected the order to restart the timeout, stopped after %v", waited)
+		}
+	case <-time.After(4 * time.Second):
+		t.Fatal("Expected the simulation to stop with no orders arriving")
+	}
+	s.wg.Wait()
+}
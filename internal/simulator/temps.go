@@ -0,0 +1,57 @@
+package simulator
+
+import (
+	"fmt"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// configureUnknownTemps applies the unknown temperature policy. It returns
+// the manager's FallbackRouter under the fallback policy, so placements can
+// warn about rerouted orders, and nil otherwise.
+func configureUnknownTemps(cfg config.UnknownTempConfig, manager shelf.ShelfManager, orders []OrderData) (shelf.FallbackRouter, error) {
+	switch cfg.Policy {
+	case "", config.UnknownTempWaste:
+		return nil, nil
+	case config.UnknownTempFallback:
+		router, ok := manager.(shelf.FallbackRouter)
+		if !ok {
+			return nil, fmt.Errorf("shelf manager %T cannot route unknown temperatures", manager)
+		}
+		if err := router.SetFallbackShelf(shelf.ShelfType(cfg.Shelf)); err != nil {
+			return nil, err
+		}
+		return router, nil
+	case config.UnknownTempStrict:
+		return nil, checkOrderTemps(manager, orders)
+	default:
+		return nil, fmt.Errorf("unknown temperature policy %q", cfg.Policy)
+	}
+}
+
+// checkOrderTemps rejects orders whose temperature no shelf accepts
+func checkOrderTemps(manager shelf.ShelfManager, orders []OrderData) error {
+	accepted := make(map[order.Temperature]bool)
+	for _, state := range manager.ShelfStates() {
+		for _, temp := range state.Temps {
+			accepted[temp] = true
+		}
+	}
+
+	for i, d := range orders {
+		if !accepted[order.Temperature(d.Temp)] {
+			return fmt.Errorf("order %d (%s): no shelf accepts temperature %q", i+1, d.Name, d.Temp)
+		}
+	}
+	return nil
+}
+
+// warnUnknownTemp logs an order rerouted to the fallback shelf
+func (s *Simulator) warnUnknownTemp(o *order.Order) {
+	if s.fallback == nil || s.fallback.Accepts(o.Temp) {
+		return
+	}
+	s.orderLogf(o.ID, o.Name, "⚠️ Unknown temperature %q for %s, using the fallback shelf\n", o.Temp, o.Name)
+}
@@ -0,0 +1,58 @@
+package simulator
+
+import (
+	"strings"
+	"testing"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestConfigureUnknownTemps(t *testing.T) {
+	orders := []OrderData{
+		{Name: "Burger", Temp: "hot"},
+		{Name: "Bread", Temp: "ambient"},
+	}
+
+	router, err := configureUnknownTemps(config.UnknownTempConfig{Policy: config.UnknownTempWaste}, shelf.NewShelfManager(1, 1, 1, 1), orders)
+	if router != nil || err != nil {
+		t.Errorf("Expected the waste policy to need no setup, got %v, %v", router, err)
+	}
+
+	_, err = configureUnknownTemps(config.UnknownTempConfig{Policy: config.UnknownTempStrict}, shelf.NewShelfManager(1, 1, 1, 1), orders)
+	if err == nil || !strings.Contains(err.Error(), "order 2 (Bread)") {
+		t.Errorf("Expected the strict policy to reject the ambient order, got %v", err)
+	}
+	if _, err := configureUnknownTemps(config.UnknownTempConfig{Policy: config.UnknownTempStrict}, shelf.NewShelfManager(1, 1, 1, 1), orders[:1]); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if _, err := configureUnknownTemps(config.UnknownTempConfig{Policy: "ignore"}, shelf.NewShelfManager(1, 1, 1, 1), orders); err == nil {
+		t.Errorf("Expected an error for an unknown policy")
+	}
+	if _, err := configureUnknownTemps(config.UnknownTempConfig{Policy: config.UnknownTempFallback, Shelf: "pantry"}, shelf.NewShelfManager(1, 1, 1, 1), orders); err == nil {
+		t.Errorf("Expected an error for an unknown fallback shelf")
+	}
+}
+
+func TestCreateOrder_Fallback(t *testing.T) {
+	s := setupTestSimulator(t)
+	bread := OrderData{Name: "Bread", Temp: "ambient", ShelfLife: 300, DecayRate: 0.5}
+
+	router, err := configureUnknownTemps(config.UnknownTempConfig{Policy: config.UnknownTempFallback}, s.ShelfManager, []OrderData{bread})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	s.fallback = router
+
+	s.createOrder(bread)
+
+	orders := s.ShelfManager.GetAllOrders()
+	if len(orders) != 1 || orders[0].Temp != order.Temperature("ambient") {
+		t.Fatalf("Expected the ambient order to be shelved, got %v", orders)
+	}
+	if orders[0].CurrentShelfType != string(shelf.OverflowShelf) {
+		t.Errorf("Expected the order on the overflow shelf, got %s", orders[0].CurrentShelfType)
+	}
+}
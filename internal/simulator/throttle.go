@@ -0,0 +1,152 @@
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// loadSampleInterval is the least time between samples of the shelf
+// pressure, which copies every shelf's orders
+const loadSampleInterval = 100 * time.Millisecond
+
+// throttleHysteresis is how many percentage points below the threshold the
+// pressure must fall before throttled orders resume, so they do not pause
+// and resume on every sample near the threshold
+const throttleHysteresis = 5.0
+
+// validateThrottleConfig checks the pressure threshold and smoothing
+func validateThrottleConfig(cfg config.ThrottleConfig) error {
+	if cfg.Threshold < 0 || cfg.Threshold > 100 {
+		return fmt.Errorf("throttle threshold must be between 0 and 100, got %v", cfg.Threshold)
+	}
+	if cfg.Smoothing <= 0 {
+		return errors.New("throttle smoothing must be positive")
+	}
+	return nil
+}
+
+// loadIndicator is an exponentially weighted average of the shelf
+// pressure, and the orders held back while it was too high. The zero value
+// is ready to use.
+type loadIndicator struct {
+	mutex      sync.Mutex
+	pressure   float64   // smoothed, 0 to 1
+	at         time.Time // of the last sample, zero before the first
+	throttling bool
+	throttled  int
+}
+
+// sample folds the current pressure into the average, weighting it by the
+// time since the last sample so the average does not depend on how often
+// it is sampled
+func (l *loadIndicator) sample(pressure float64, now time.Time, smoothing time.Duration) float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch {
+	case l.at.IsZero():
+		l.pressure = pressure
+	case now.Sub(l.at) < loadSampleInterval:
+		return l.pressure
+	default:
+		alpha := 1 - math.Exp(-now.Sub(l.at).Seconds()/smoothing.Seconds())
+		l.pressure += alpha * (pressure - l.pressure)
+	}
+	l.at = now
+	return l.pressure
+}
+
+// hold records whether n orders were held back, reporting whether that
+// started or ended throttling
+func (l *loadIndicator) hold(throttle bool, n int) (changed bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if throttle {
+		l.throttled += n
+	}
+	changed = throttle != l.throttling
+	l.throttling = throttle
+	return changed
+}
+
+// throttlingNow reports whether orders are being held back
+func (l *loadIndicator) throttlingNow() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.throttling
+}
+
+// current returns the smoothed pressure and the orders held back
+func (l *loadIndicator) current() (float64, int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.pressure, l.throttled
+}
+
+// reset discards the orders held back; the pressure carries on
+func (l *loadIndicator) reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.throttled = 0
+}
+
+// shelfPressure returns the share of the shelves' capacity in use
+func shelfPressure(states []shelf.ShelfState) float64 {
+	used, capacity := 0, 0
+	for _, st := range states {
+		used += len(st.Orders)
+		capacity += st.Capacity
+	}
+	if capacity == 0 {
+		return 0
+	}
+	return float64(used) / float64(capacity)
+}
+
+// throttle samples the shelf pressure and reports whether the next n
+// orders should be held back, counting them if so
+func (s *Simulator) throttle(n int) bool {
+	cfg := s.Config.Throttle
+	smoothing := time.Duration(cfg.Smoothing * float64(time.Second))
+	if smoothing <= 0 {
+		smoothing = time.Second
+	}
+	pressure := s.load.sample(shelfPressure(s.ShelfManager.ShelfStates()), s.now(), smoothing)
+	if cfg.Threshold <= 0 {
+		return false
+	}
+
+	throttle := pressure*100 >= cfg.Threshold
+	if s.load.throttlingNow() && pressure*100 >= cfg.Threshold-throttleHysteresis {
+		throttle = true
+	}
+	if s.load.hold(throttle, n) {
+		if throttle {
+			fmt.Printf("⏸️ Pausing new orders at %.1f%% shelf pressure\n", pressure*100)
+		} else {
+			fmt.Printf("▶️ Resuming new orders at %.1f%% shelf pressure\n", pressure*100)
+		}
+	}
+	return throttle
+}
+
+// printLoad prints the smoothed shelf pressure and the orders held back
+func (s *Simulator) printLoad() {
+	pressure, throttled := s.load.current()
+	if s.Config.Throttle.Threshold > 0 {
+		fmt.Printf("Load: %.1f%% shelf pressure, %d orders throttled above %.0f%%\n",
+			pressure*100, throttled, s.Config.Throttle.Threshold)
+	} else {
+		fmt.Printf("Load: %.1f%% shelf pressure\n", pressure*100)
+	}
+}
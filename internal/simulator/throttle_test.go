@@ -0,0 +1,93 @@
+package simulator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestLoadIndicator_Sample(t *testing.T) {
+	var l loadIndicator
+	start := time.Unix(1000, 0)
+	if got := l.sample(0.2, start, 5*time.Second); got != 0.2 {
+		t.Errorf("Expected the first sample to be taken as is, got %v", got)
+	}
+	if got := l.sample(1, start.Add(loadSampleInterval/2), 5*time.Second); got != 0.2 {
+		t.Errorf("Expected a sample too soon after the last to be ignored, got %v", got)
+	}
+
+	// After one smoothing period the average has moved 1 - 1/e of the way
+	got := l.sample(1, start.Add(5*time.Second), 5*time.Second)
+	if want := 0.2 + 0.8*(1-math.Exp(-1)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if !l.hold(true, 3) || l.hold(true, 2) || !l.hold(false, 4) {
+		t.Errorf("Expected only the first hold and the release to change throttling")
+	}
+	if _, throttled := l.current(); throttled != 5 {
+		t.Errorf("Expected 5 orders held back, got %d", throttled)
+	}
+	l.reset()
+	if pressure, throttled := l.current(); throttled != 0 || pressure != got {
+		t.Errorf("Expected a reset to keep only the pressure, got %v and %d", pressure, throttled)
+	}
+}
+
+func TestShelfPressure(t *testing.T) {
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+	states := []shelf.ShelfState{
+		{Type: shelf.HotShelf, Capacity: 3, Orders: []*order.Order{o, o, o}},
+		{Type: shelf.OverflowShelf, Capacity: 5, Orders: []*order.Order{o}},
+	}
+	if got := shelfPressure(states); got != 0.5 {
+		t.Errorf("Expected half the capacity in use, got %v", got)
+	}
+	if got := shelfPressure(nil); got != 0 {
+		t.Errorf("Expected no pressure without shelves, got %v", got)
+	}
+}
+
+func TestValidateThrottleConfig(t *testing.T) {
+	if err := validateThrottleConfig(config.DefaultConfig().Throttle); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	for _, cfg := range []config.ThrottleConfig{
+		{Threshold: -1, Smoothing: 5},
+		{Threshold: 101, Smoothing: 5},
+		{Threshold: 80},
+	} {
+		if err := validateThrottleConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestDiscreteEngine_Throttle(t *testing.T) {
+	orders := make([]OrderData, 100)
+	for i := range orders {
+		orders[i] = OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	}
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 10
+	cfg.SimulationDuration = 0
+	cfg.Throttle = config.ThrottleConfig{Threshold: 10, Smoothing: 1}
+
+	e, err := NewDiscreteEngine(cfg, writeOrders(t, orders))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e.Run()
+
+	if _, throttled := e.load.current(); throttled == 0 {
+		t.Errorf("Expected orders to be throttled")
+	}
+	// Throttled orders are only delayed, so every one is still placed
+	if totals := e.currentTotals(); totals.received != 100 {
+		t.Errorf("Expected all 100 orders to be placed, got %d", totals.received)
+	}
+}
@@ -0,0 +1,311 @@
+package simulator
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// validateTimeSeriesConfig checks the sampling interval of the time series
+func validateTimeSeriesConfig(cfg config.TimeSeriesConfig) error {
+	if cfg.File != "" && cfg.Interval <= 0 {
+		return fmt.Errorf("timeSeries.interval must be positive, got %g", cfg.Interval)
+	}
+	if cfg.MaxSamples < 0 {
+		return fmt.Errorf("timeSeries.maxSamples must not be negative, got %d", cfg.MaxSamples)
+	}
+	return nil
+}
+
+// seriesSample is the shelves and order totals at one moment of the run
+type seriesSample struct {
+	at      time.Time
+	elapsed time.Duration
+	counts  []int // orders on each shelf, in layout order
+
+	received, delivered, wasted, expired int
+}
+
+// takeSample samples the shelves and totals at, elapsed into the run
+func takeSample(manager shelf.ShelfManager, shelves []shelf.ShelfType, at time.Time, elapsed time.Duration) seriesSample {
+	counts := make(map[shelf.ShelfType]int)
+	for _, st := range manager.ShelfStates() {
+		counts[st.Type] = len(st.Orders)
+	}
+	totals := manager.GetStats()["totalOrders"].(map[string]interface{})
+
+	sample := seriesSample{
+		at:        at,
+		elapsed:   elapsed,
+		counts:    make([]int, len(shelves)),
+		received:  totals["received"].(int),
+		delivered: totals["delivered"].(int),
+		wasted:    totals["wasted"].(int),
+		expired:   totals["expired"].(int),
+	}
+	for i, t := range shelves {
+		sample.counts[i] = counts[t]
+	}
+	return sample
+}
+
+// sampleHistory keeps the latest samples of the run for /stats/history
+type sampleHistory struct {
+	mutex   sync.Mutex
+	shelves []shelf.ShelfType // in column order
+	samples []seriesSample
+	max     int // samples kept, 0 for none
+}
+
+func (h *sampleHistory) add(sample seriesSample) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.max <= 0 {
+		return
+	}
+	h.samples = append(h.samples, sample)
+	if len(h.samples) > h.max {
+		h.samples = h.samples[len(h.samples)-h.max:]
+	}
+}
+
+// reset drops the samples kept
+func (h *sampleHistory) reset() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.samples = nil
+}
+
+// snapshot returns a copy of the samples kept, oldest first
+func (h *sampleHistory) snapshot() []seriesSample {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	return append([]seriesSample(nil), h.samples...)
+}
+
+// seriesWriter writes samples of the run as rows of a wide CSV: when each
+// was taken, the orders on each shelf and the running order totals
+type seriesWriter struct {
+	file   *os.File
+	w      *csv.Writer
+	failed bool // a write failed, which is reported only once
+}
+
+// newSeriesWriter creates the time series file and writes its header, a
+// count column for each of the shelves
+func newSeriesWriter(path string, shelves []shelf.ShelfType) (*seriesWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	sw := &seriesWriter{file: file, w: csv.NewWriter(file)}
+
+	header := []string{"timestamp", "elapsed_s"}
+	for _, t := range shelves {
+		header = append(header, seriesColumn(string(t))+"_count")
+	}
+	header = append(header, "received_cum", "delivered_cum", "wasted_cum", "expired_cum")
+	if err := sw.w.Write(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return sw, nil
+}
+
+// seriesColumn turns a shelf name into a column name that is a valid
+// identifier in pandas and R: lower case, with anything but letters and
+// digits replaced by underscores
+func seriesColumn(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r - 'A' + 'a'
+		}
+		return '_'
+	}, name)
+}
+
+// write writes a sample as a row
+func (sw *seriesWriter) write(sample seriesSample) {
+	row := []string{sample.at.UTC().Format(time.RFC3339Nano), strconv.FormatFloat(sample.elapsed.Seconds(), 'f', 3, 64)}
+	for _, n := range sample.counts {
+		row = append(row, strconv.Itoa(n))
+	}
+	for _, total := range []int{sample.received, sample.delivered, sample.wasted, sample.expired} {
+		row = append(row, strconv.Itoa(total))
+	}
+	if err := sw.w.Write(row); err != nil {
+		sw.fail(err)
+	}
+}
+
+// fail reports the first write that failed
+func (sw *seriesWriter) fail(err error) {
+	if !sw.failed {
+		sw.failed = true
+		fmt.Printf("⚠️ Writing the time series failed, later samples may be lost: %v\n", err)
+	}
+}
+
+// Close flushes the rows written and closes the file
+func (sw *seriesWriter) Close() error {
+	sw.w.Flush()
+	if err := sw.w.Error(); err != nil {
+		sw.file.Close()
+		return err
+	}
+	return sw.file.Close()
+}
+
+// sampling reports whether the run is sampled for the time series
+func (s *Simulator) sampling() bool {
+	return s.Config.TimeSeries.Interval > 0
+}
+
+// sampleSeries samples the run, keeping the sample for /stats/history and
+// writing it to the time series file if there is one
+func (s *Simulator) sampleSeries() {
+	now := s.now()
+	sample := takeSample(s.ShelfManager, s.samples.shelves, now, now.Sub(s.startedAt))
+	s.samples.add(sample)
+	if s.series != nil {
+		s.series.write(sample)
+	}
+}
+
+// closeSeries takes the last sample of the run and closes the time series
+func (s *Simulator) closeSeries() {
+	if !s.sampling() {
+		return
+	}
+	s.sampleSeries()
+	if s.series == nil {
+		return
+	}
+	if err := s.series.Close(); err != nil {
+		s.series.fail(err)
+	}
+	s.series = nil
+}
+
+// recordTimeSeries samples the run on the configured interval until it
+// stops
+func (s *Simulator) recordTimeSeries() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(seconds(s.Config.TimeSeries.Interval))
+	defer ticker.Stop()
+
+	s.sampleSeries()
+	for {
+		select {
+		case <-ticker.C:
+			s.sampleSeries()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// StatsHistory is the run's time series and outcome breakdowns, as served
+// at /stats/history. Field names are stable, so notebooks polling a run
+// can load each list straight into a data frame.
+type StatsHistory struct {
+	RunID           string           `json:"runId"`
+	IntervalSeconds float64          `json:"intervalSeconds"`
+	Shelves         []string         `json:"shelves"` // the keys of each sample's shelves, in layout order
+	Samples         []StatsSample    `json:"samples"` // oldest first
+	ByItem          []OutcomeSummary `json:"byItem"`
+	ByTemperature   []OutcomeSummary `json:"byTemperature"`
+}
+
+// StatsSample is the shelves and running order totals at one moment
+type StatsSample struct {
+	Timestamp      time.Time      `json:"timestamp"`
+	ElapsedSeconds float64        `json:"elapsedSeconds"`
+	Shelves        map[string]int `json:"shelves"` // orders on each shelf
+	Received       int            `json:"received"`
+	Delivered      int            `json:"delivered"`
+	Wasted         int            `json:"wasted"`
+	Expired        int            `json:"expired"`
+}
+
+// OutcomeSummary is how the orders of one item or temperature ended
+type OutcomeSummary struct {
+	Name              string  `json:"name"`
+	Delivered         int     `json:"delivered"`
+	Wasted            int     `json:"wasted"`
+	Expired           int     `json:"expired"`
+	AvgDeliveredValue float64 `json:"avgDeliveredValue"`
+}
+
+// StatsHistory returns the samples kept of the run and the outcomes of its
+// orders by item and temperature. It is safe to call while the simulation
+// runs.
+func (s *Simulator) StatsHistory() StatsHistory {
+	h := StatsHistory{
+		RunID:           s.RunContext().ID,
+		IntervalSeconds: s.Config.TimeSeries.Interval,
+		Shelves:         make([]string, len(s.samples.shelves)),
+		Samples:         make([]StatsSample, 0),
+		ByItem:          outcomeSummaries(s.ShelfManager.StatsByName()),
+		ByTemperature:   make([]OutcomeSummary, 0),
+	}
+	for i, t := range s.samples.shelves {
+		h.Shelves[i] = string(t)
+	}
+	for _, sample := range s.samples.snapshot() {
+		shelves := make(map[string]int, len(sample.counts))
+		for i, n := range sample.counts {
+			shelves[h.Shelves[i]] = n
+		}
+		h.Samples = append(h.Samples, StatsSample{
+			Timestamp:      sample.at,
+			ElapsedSeconds: sample.elapsed.Seconds(),
+			Shelves:        shelves,
+			Received:       sample.received,
+			Delivered:      sample.delivered,
+			Wasted:         sample.wasted,
+			Expired:        sample.expired,
+		})
+	}
+	byTemp := s.ShelfManager.StatsByTemperature()
+	for _, temp := range []order.Temperature{order.Hot, order.Cold, order.Frozen} {
+		h.ByTemperature = append(h.ByTemperature, outcomeSummary(string(temp), byTemp[temp]))
+	}
+	return h
+}
+
+// outcomeSummaries returns the outcomes of each item, by name
+func outcomeSummaries(byName map[string]shelf.ItemStats) []OutcomeSummary {
+	summaries := make([]OutcomeSummary, 0, len(byName))
+	for name, stats := range byName {
+		summaries = append(summaries, outcomeSummary(name, stats))
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries
+}
+
+func outcomeSummary(name string, stats shelf.ItemStats) OutcomeSummary {
+	return OutcomeSummary{
+		Name:              name,
+		Delivered:         stats.Delivered,
+		Wasted:            stats.Wasted,
+		Expired:           stats.Expired,
+		AvgDeliveredValue: stats.AverageDeliveredValue(),
+	}
+}
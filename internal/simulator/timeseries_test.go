@@ -0,0 +1,152 @@
+package simulator
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+func TestValidateTimeSeriesConfig(t *testing.T) {
+	valid := []config.TimeSeriesConfig{
+		{},
+		{Interval: -1},
+		{File: "series.csv", Interval: 0.5},
+	}
+	for _, cfg := range valid {
+		if err := validateTimeSeriesConfig(cfg); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", cfg, err)
+		}
+	}
+
+	invalid := []config.TimeSeriesConfig{
+		{File: "series.csv"},
+		{File: "series.csv", Interval: -1},
+	}
+	for _, cfg := range invalid {
+		if err := validateTimeSeriesConfig(cfg); err == nil {
+			t.Errorf("Expected %+v to be rejected", cfg)
+		}
+	}
+}
+
+func TestSeriesColumn(t *testing.T) {
+	tests := map[string]string{
+		"hot":        "hot",
+		"Walk-in 2":  "walk_in_2",
+		"ice.cream":  "ice_cream",
+		"OVERFLOW_B": "overflow_b",
+		"café":       "caf_",
+	}
+	for name, want := range tests {
+		if got := seriesColumn(name); got != want {
+			t.Errorf("seriesColumn(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDiscreteEngine_TimeSeries(t *testing.T) {
+	orders := make([]OrderData, 20)
+	for i := range orders {
+		orders[i] = OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5}
+	}
+	path := filepath.Join(t.TempDir(), "series.csv")
+	cfg := config.DefaultConfig()
+	cfg.OrdersPerSecond = 2
+	cfg.SimulationDuration = 0
+	cfg.TimeSeries = config.TimeSeriesConfig{File: path, Interval: 2}
+
+	e, err := NewDiscreteEngine(cfg, writeOrders(t, orders))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	e.Run()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	rows, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read the time series: %v", err)
+	}
+
+	want := "timestamp,elapsed_s,hot_count,cold_count,frozen_count,overflow_count," +
+		"received_cum,delivered_cum,wasted_cum,expired_cum"
+	if header := strings.Join(rows[0], ","); header != want {
+		t.Fatalf("Expected header %s, got %s", want, header)
+	}
+
+	// 10 seconds of orders and 10 to settle, sampled every 2 seconds from
+	// the start until the run ends at 20s, then once more for the end
+	samples := rows[1:]
+	if len(samples) != 11 {
+		t.Fatalf("Expected 11 samples, got %d", len(samples))
+	}
+	if _, err := time.Parse(time.RFC3339Nano, samples[0][0]); err != nil {
+		t.Errorf("Expected an RFC 3339 timestamp, got %q", samples[0][0])
+	}
+	for i, row := range samples[:10] {
+		if want := strconv.Itoa(2*i) + ".000"; row[1] != want {
+			t.Errorf("Expected sample %d at %ss, got %s", i, want, row[1])
+		}
+	}
+
+	last := samples[len(samples)-1]
+	totals := e.currentTotals()
+	if last[6] != strconv.Itoa(totals.received) || last[7] != strconv.Itoa(totals.delivered) {
+		t.Errorf("Expected the last sample to match the final totals %+v, got %v", totals, last)
+	}
+	if last[6] != "20" {
+		t.Errorf("Expected 20 orders received, got %s", last[6])
+	}
+}
+
+func TestDiscreteEngine_StatsHistory(t *testing.T) {
+	e := newTestDiscreteEngine(t, 20)
+	e.Config.TimeSeries.Interval = 2
+	e.samples.max = 4
+	e.Run()
+
+	h := e.StatsHistory()
+	if len(h.Shelves) != 4 || h.Shelves[0] != "hot" {
+		t.Errorf("Expected the shelves in layout order, got %v", h.Shelves)
+	}
+
+	// Only the latest samples are kept: 14s, 16s, 18s and the last at 20s
+	if len(h.Samples) != 4 {
+		t.Fatalf("Expected 4 samples, got %d", len(h.Samples))
+	}
+	if h.Samples[0].ElapsedSeconds != 14 {
+		t.Errorf("Expected the oldest kept sample at 14s, got %gs", h.Samples[0].ElapsedSeconds)
+	}
+	last := h.Samples[len(h.Samples)-1]
+	if last.Received != 20 || last.Delivered != e.currentTotals().delivered {
+		t.Errorf("Expected the last sample to match the final totals, got %+v", last)
+	}
+	if _, ok := last.Shelves["overflow"]; !ok {
+		t.Errorf("Expected a count for every shelf, got %v", last.Shelves)
+	}
+
+	if len(h.ByItem) != 1 || h.ByItem[0].Name != "Soup" || h.ByItem[0].Delivered != last.Delivered {
+		t.Errorf("Expected the outcomes of Soup, got %+v", h.ByItem)
+	}
+	if len(h.ByTemperature) != 3 || h.ByTemperature[0].Name != "hot" {
+		t.Errorf("Expected the outcomes by temperature, got %+v", h.ByTemperature)
+	}
+}
+
+func TestStatsHistory_NotSampled(t *testing.T) {
+	s := setupTestSimulator(t)
+
+	h := s.StatsHistory()
+	if h.Samples == nil || len(h.Samples) != 0 {
+		t.Errorf("Expected an empty list of samples, got %v", h.Samples)
+	}
+}
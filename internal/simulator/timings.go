@@ -0,0 +1,53 @@
+package simulator
+
+import (
+	"fmt"
+	"time"
+
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+// Timed operations, as named in Timings and the /metrics endpoint
+const (
+	TimingPlaceOrder   = "place_order"
+	TimingDeliverOrder = "deliver_order"
+	TimingCleanup      = "cleanup" // one expiry sweep or scheduled removal
+)
+
+// placeTimed places an order on the shelves, timing the call
+func (s *Simulator) placeTimed(o *order.Order) error {
+	defer s.Timings.Histogram(TimingPlaceOrder).Since(time.Now())
+	return s.ShelfManager.PlaceOrder(o)
+}
+
+// deliverTimed delivers an order from the shelves unless it has expired,
+// timing the call, and describes it as it left the shelf
+func (s *Simulator) deliverTimed(orderID string) (shelf.DeliveryResult, bool) {
+	defer s.Timings.Histogram(TimingDeliverOrder).Since(time.Now())
+	return s.ShelfManager.TryDeliver(orderID)
+}
+
+// cleanupTimed runs one expiry pass, timing it
+func (s *Simulator) cleanupTimed(remove func() int) int {
+	defer s.Timings.Histogram(TimingCleanup).Since(time.Now())
+	return remove()
+}
+
+// printTimings prints the latency summary of each timed operation
+func (s *Simulator) printTimings() {
+	if s.Timings == nil {
+		return
+	}
+
+	fmt.Println("\n⏱️ OPERATION TIMINGS:")
+	snapshots := s.Timings.Snapshots()
+	for _, name := range []string{TimingPlaceOrder, TimingDeliverOrder, TimingCleanup} {
+		snap, ok := snapshots[name]
+		if !ok {
+			continue
+		}
+		fmt.Printf("  %s: %d calls, mean %v, p50 %v, p99 %v, max %v\n",
+			name, snap.Count, snap.Mean(), snap.Quantile(0.5), snap.Quantile(0.99), snap.Max)
+	}
+}
@@ -0,0 +1,28 @@
+package simulator
+
+import (
+	"testing"
+
+	"dish-dispatcher/internal/timing"
+)
+
+func TestTimedOperations(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.Timings = timing.NewSet()
+
+	o, err := s.placeOrder(OrderData{Name: "Soup", Temp: "hot", ShelfLife: 300, DecayRate: 0.5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := s.deliverTimed(o.ID); !ok {
+		t.Fatalf("Expected the order to be delivered")
+	}
+	s.cleanupTimed(s.ShelfManager.RemoveExpiredOrders)
+
+	snapshots := s.Timings.Snapshots()
+	for _, name := range []string{TimingPlaceOrder, TimingDeliverOrder, TimingCleanup} {
+		if snapshots[name].Count != 1 {
+			t.Errorf("Expected one %s timing, got %d", name, snapshots[name].Count)
+		}
+	}
+}
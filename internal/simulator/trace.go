@@ -0,0 +1,179 @@
+package simulator
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+)
+
+// validateTraceConfig checks the pattern of the traced orders
+func validateTraceConfig(cfg config.TraceConfig) error {
+	if _, err := path.Match(cfg.Orders, ""); err != nil {
+		return fmt.Errorf("trace.orders %q: %w", cfg.Orders, err)
+	}
+	if cfg.Orders != "" && cfg.MaxOrders <= 0 {
+		return fmt.Errorf("trace.maxOrders must be positive, got %d", cfg.MaxOrders)
+	}
+	return nil
+}
+
+// OrderTimeline is what happened to a traced order, as served at
+// /orders/{id}/history
+type OrderTimeline struct {
+	ID      string            `json:"id"`
+	Name    string            `json:"name"`
+	Temp    order.Temperature `json:"temp"`
+	Source  string            `json:"source,omitempty"`
+	Entries []TraceEntry      `json:"entries"` // oldest first
+}
+
+// TraceEntry is one step of a traced order's lifecycle
+type TraceEntry struct {
+	At     time.Time   `json:"at"`
+	From   order.State `json:"from"`
+	To     order.State `json:"to"`
+	Shelf  string      `json:"shelf,omitempty"`  // put on or taken from, if any
+	Value  float64     `json:"value"`            // at the time of the step
+	Reason string      `json:"reason,omitempty"` // why the order was lost, if known
+}
+
+// orderTracer keeps the timelines of the orders matching a pattern. A nil
+// orderTracer traces nothing.
+type orderTracer struct {
+	pattern string
+	max     int
+
+	mutex     sync.Mutex
+	timelines map[string]*OrderTimeline
+	ids       []string // traced, oldest first
+}
+
+// newOrderTracer traces the orders matching cfg.Orders, or returns nil if
+// there is no pattern
+func newOrderTracer(cfg config.TraceConfig) *orderTracer {
+	if cfg.Orders == "" {
+		return nil
+	}
+	return &orderTracer{pattern: cfg.Orders, max: cfg.MaxOrders, timelines: make(map[string]*OrderTimeline)}
+}
+
+// matches reports whether the order with this ID or name is traced
+func (t *orderTracer) matches(id, name string) bool {
+	if t == nil {
+		return false
+	}
+	byID, _ := path.Match(t.pattern, id)
+	byName, _ := path.Match(t.pattern, name)
+	return byID || byName
+}
+
+// observe is a TransitionHook recording the steps of traced orders. Orders
+// wasted on placement are recorded by placeOrder, which knows why.
+func (t *orderTracer) observe(o *order.Order, from, to order.State, at time.Time) {
+	if from == order.StateCreated && to == order.StateWasted {
+		return
+	}
+	t.record(o, from, to, at, lossCause(from, to))
+}
+
+// record adds a step to a traced order's timeline and prints it
+func (t *orderTracer) record(o *order.Order, from, to order.State, at time.Time, reason string) {
+	if !t.matches(o.ID, o.Name) {
+		return
+	}
+	entry := TraceEntry{At: at, From: from, To: to, Value: o.CalculateValue(at), Reason: reason}
+	if to != order.StateShelved {
+		entry.Shelf = o.CurrentShelfType
+	}
+	if to.Terminal() && to != order.StateDelivered {
+		entry.Value = 0
+	}
+	fmt.Printf("🔎 Order %s (%s): %s -> %s%s\n", o.ID, o.Name, from, to, traceDetail(entry))
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if from == order.StateShelved {
+		t.settleLocked(o)
+	}
+	timeline, ok := t.timelines[o.ID]
+	if !ok {
+		timeline = &OrderTimeline{ID: o.ID, Name: o.Name, Temp: o.Temp, Source: o.Source}
+		t.timelines[o.ID] = timeline
+		t.ids = append(t.ids, o.ID)
+		if len(t.ids) > t.max {
+			delete(t.timelines, t.ids[0])
+			t.ids = t.ids[1:]
+		}
+	}
+	timeline.Entries = append(timeline.Entries, entry)
+}
+
+// settle fills in the shelf of a traced order's latest step if the step
+// shelved it. The shelves only set the order's shelf after the transition,
+// so callers settle the order once it is placed or moved; a step from the
+// shelf settles the previous one otherwise.
+func (t *orderTracer) settle(o *order.Order) {
+	if !t.matches(o.ID, o.Name) {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.settleLocked(o)
+}
+
+// settleLocked is settle for callers holding the mutex
+func (t *orderTracer) settleLocked(o *order.Order) {
+	timeline, ok := t.timelines[o.ID]
+	if !ok || len(timeline.Entries) == 0 {
+		return
+	}
+	last := &timeline.Entries[len(timeline.Entries)-1]
+	if last.To == order.StateShelved {
+		last.Shelf = o.CurrentShelfType
+	}
+}
+
+// traceDetail describes where a step left the order
+func traceDetail(e TraceEntry) string {
+	var detail string
+	if e.Shelf != "" {
+		detail += " from " + e.Shelf
+	}
+	if e.Reason != "" {
+		detail += " (" + e.Reason + ")"
+	}
+	return detail + fmt.Sprintf(", value %.2f", e.Value)
+}
+
+// OrderHistory returns the timeline of a traced order, and false if the
+// order is not traced or its timeline was dropped. It is safe to call while
+// the simulation runs.
+func (s *Simulator) OrderHistory(id string) (OrderTimeline, bool) {
+	t := s.tracer
+	if t == nil {
+		return OrderTimeline{}, false
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	timeline, ok := t.timelines[id]
+	if !ok {
+		return OrderTimeline{}, false
+	}
+	copied := *timeline
+	copied.Entries = append([]TraceEntry(nil), timeline.Entries...)
+	return copied, true
+}
+
+// orderLogf prints a log line about one order unless per-order lines are
+// turned off and the order is not traced
+func (s *Simulator) orderLogf(id, name, format string, args ...any) {
+	if !s.quiet.Load() || s.tracer.matches(id, name) {
+		fmt.Printf(format, args...)
+	}
+}
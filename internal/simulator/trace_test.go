@@ -0,0 +1,174 @@
+package simulator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+)
+
+func TestValidateTraceConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.TraceConfig
+		wantErr bool
+	}{
+		{"off", config.TraceConfig{}, false},
+		{"name", config.TraceConfig{Orders: "Burger", MaxOrders: 10}, false},
+		{"glob", config.TraceConfig{Orders: "Ice*", MaxOrders: 10}, false},
+		{"bad pattern", config.TraceConfig{Orders: "[Ice", MaxOrders: 10}, true},
+		{"no timelines kept", config.TraceConfig{Orders: "Burger"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateTraceConfig(tt.cfg); (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOrderTracer_Matches(t *testing.T) {
+	var off *orderTracer
+	if off.matches("abc", "Burger") {
+		t.Errorf("Expected a nil tracer to trace nothing")
+	}
+
+	tr := newOrderTracer(config.TraceConfig{Orders: "Ice*", MaxOrders: 10})
+	if !tr.matches("abc", "Ice Cream") {
+		t.Errorf("Expected the order to match by name")
+	}
+	if !tr.matches("Ice-1", "Burger") {
+		t.Errorf("Expected the order to match by ID")
+	}
+	if tr.matches("abc", "Burger") {
+		t.Errorf("Expected the order not to match")
+	}
+}
+
+func TestOrderTracer_KeepsLatest(t *testing.T) {
+	tr := newOrderTracer(config.TraceConfig{Orders: "*", MaxOrders: 2})
+	at := time.Now()
+	var ids []string
+	for range 3 {
+		o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+		tr.record(o, order.StateCreated, order.StateShelved, at, "")
+		ids = append(ids, o.ID)
+	}
+
+	if _, ok := tr.timelines[ids[0]]; ok {
+		t.Errorf("Expected the oldest timeline to be dropped")
+	}
+	if len(tr.timelines) != 2 || len(tr.ids) != 2 {
+		t.Errorf("Expected 2 timelines kept, got %d", len(tr.timelines))
+	}
+}
+
+func TestOrderTracer_Settle(t *testing.T) {
+	tr := newOrderTracer(config.TraceConfig{Orders: "*", MaxOrders: 10})
+	at := time.Now()
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+
+	// The shelves transition the order before putting it on the shelf
+	tr.record(o, order.StateCreated, order.StateShelved, at, "")
+	o.CurrentShelfType = "overflow"
+	tr.record(o, order.StateShelved, order.StateShelved, at, "")
+	o.CurrentShelfType = "hot"
+
+	entries := tr.timelines[o.ID].Entries
+	if entries[0].Shelf != "overflow" {
+		t.Errorf("Expected the move to settle the placement on overflow, got %q", entries[0].Shelf)
+	}
+	if entries[1].Shelf != "" {
+		t.Errorf("Expected the move's shelf unknown until settled, got %q", entries[1].Shelf)
+	}
+	tr.settle(o)
+	if entries[1].Shelf != "hot" {
+		t.Errorf("Expected the move settled on hot, got %q", entries[1].Shelf)
+	}
+}
+
+func TestSimulator_OrderHistory(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.tracer = newOrderTracer(config.TraceConfig{Orders: "Burger", MaxOrders: 10})
+	s.SetVerbose(false)
+	s.placeOrders(context.Background(), 2)
+
+	if len(s.tracer.ids) != 1 {
+		t.Fatalf("Expected only the burger to be traced, got %d orders", len(s.tracer.ids))
+	}
+	id := s.tracer.ids[0]
+	result, ok := s.pickUp(id)
+	if !ok {
+		t.Fatalf("Expected the burger to be picked up")
+	}
+	if timeline, _ := s.OrderHistory(id); len(timeline.Entries) != 2 || timeline.Entries[1].To != order.StateInTransit {
+		t.Fatalf("Expected the burger in transit until handoff, got %+v", timeline.Entries)
+	}
+	handedOff := result.At.Add(time.Second)
+	s.handOff(result.Order, result.Value, handedOff)
+
+	timeline, ok := s.OrderHistory(id)
+	if !ok {
+		t.Fatalf("Expected the burger's timeline")
+	}
+	if timeline.Name != "Burger" || timeline.Temp != order.Hot {
+		t.Errorf("Expected a hot burger, got %+v", timeline)
+	}
+	if len(timeline.Entries) != 3 {
+		t.Fatalf("Expected 3 steps, got %+v", timeline.Entries)
+	}
+	placed, picked, delivered := timeline.Entries[0], timeline.Entries[1], timeline.Entries[2]
+	if placed.From != order.StateCreated || placed.To != order.StateShelved || placed.Shelf != "hot" {
+		t.Errorf("Expected the burger shelved on hot, got %+v", placed)
+	}
+	if picked.From != order.StateShelved || picked.To != order.StateInTransit || picked.Shelf != "hot" || picked.Value <= 0 {
+		t.Errorf("Expected the burger picked up from hot with some value, got %+v", picked)
+	}
+	if delivered.To != order.StateDelivered || !delivered.At.Equal(handedOff) || delivered.Value <= 0 {
+		t.Errorf("Expected the burger delivered with some value, got %+v", delivered)
+	}
+
+	// The timeline served is a copy
+	timeline.Entries[0].Shelf = "changed"
+	if again, _ := s.OrderHistory(id); again.Entries[0].Shelf != "hot" {
+		t.Errorf("Expected the kept timeline unchanged, got %+v", again.Entries[0])
+	}
+
+	if _, ok := s.OrderHistory("missing"); ok {
+		t.Errorf("Expected no timeline for an untraced order")
+	}
+}
+
+func TestSimulator_OrderHistoryWasted(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.ShelfManager = shelf.NewShelfManager(0, 0, 0, 0)
+	s.tracer = newOrderTracer(config.TraceConfig{Orders: "*", MaxOrders: 10})
+	s.SetVerbose(false)
+	s.placeOrders(context.Background(), 1)
+
+	if len(s.tracer.ids) != 1 {
+		t.Fatalf("Expected the wasted order to be traced")
+	}
+	timeline, _ := s.OrderHistory(s.tracer.ids[0])
+	if len(timeline.Entries) != 1 {
+		t.Fatalf("Expected 1 step, got %+v", timeline.Entries)
+	}
+	wasted := timeline.Entries[0]
+	if wasted.From != order.StateCreated || wasted.To != order.StateWasted || wasted.Reason == "" || wasted.Value != 0 {
+		t.Errorf("Expected the order wasted on placement with a reason and no value, got %+v", wasted)
+	}
+}
+
+func TestSimulator_OrderHistoryOff(t *testing.T) {
+	s := setupTestSimulator(t)
+	s.SetVerbose(false)
+	s.placeOrders(context.Background(), 1)
+
+	if _, ok := s.OrderHistory("anything"); ok {
+		t.Errorf("Expected no timelines without a pattern")
+	}
+}
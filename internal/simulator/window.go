@@ -0,0 +1,122 @@
+package simulator
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+// windowTotals counts the orders received and finished over a span of time
+type windowTotals struct {
+	received, delivered, wasted, expired int
+}
+
+// rates returns the delivered and the wasted or expired orders as
+// percentages of the orders finished
+func (t windowTotals) rates() (float64, float64) {
+	finished := t.delivered + t.wasted + t.expired
+	if finished == 0 {
+		return 0, 0
+	}
+	n := float64(finished)
+	return float64(t.delivered) / n * 100, float64(t.wasted+t.expired) / n * 100
+}
+
+// windowBucket is the totals of one second
+type windowBucket struct {
+	second int64 // unix time
+	totals windowTotals
+}
+
+// rollingWindow counts orders in one-second buckets over the last few
+// seconds, so recent rates can be told apart from the cumulative ones. The
+// zero value counts nothing.
+type rollingWindow struct {
+	mutex   sync.Mutex
+	buckets []windowBucket // a ring indexed by the second
+}
+
+func newRollingWindow(seconds int) rollingWindow {
+	if seconds <= 0 {
+		return rollingWindow{}
+	}
+	return rollingWindow{buckets: make([]windowBucket, seconds)}
+}
+
+// span returns how long the window covers, 0 if disabled
+func (w *rollingWindow) span() time.Duration {
+	return time.Duration(len(w.buckets)) * time.Second
+}
+
+func (w *rollingWindow) add(at time.Time, change func(*windowTotals)) {
+	if len(w.buckets) == 0 {
+		return
+	}
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	second := at.Unix()
+	b := &w.buckets[second%int64(len(w.buckets))]
+	if b.second != second {
+		*b = windowBucket{second: second}
+	}
+	change(&b.totals)
+}
+
+func (w *rollingWindow) receive(at time.Time) {
+	w.add(at, func(t *windowTotals) { t.received++ })
+}
+
+// observe is a TransitionHook counting finished orders
+func (w *rollingWindow) observe(o *order.Order, from, to order.State, at time.Time) {
+	switch to {
+	case order.StateDelivered:
+		w.add(at, func(t *windowTotals) { t.delivered++ })
+	case order.StateWasted:
+		w.add(at, func(t *windowTotals) { t.wasted++ })
+	case order.StateExpired:
+		w.add(at, func(t *windowTotals) { t.expired++ })
+	}
+}
+
+// totals sums the window ending at now
+func (w *rollingWindow) totals(now time.Time) windowTotals {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	var sum windowTotals
+	since := now.Unix() - int64(len(w.buckets))
+	for _, b := range w.buckets {
+		if b.second > since && b.second <= now.Unix() {
+			sum.received += b.totals.received
+			sum.delivered += b.totals.delivered
+			sum.wasted += b.totals.wasted
+			sum.expired += b.totals.expired
+		}
+	}
+	return sum
+}
+
+// reset discards every count
+func (w *rollingWindow) reset() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	clear(w.buckets)
+}
+
+// printRecentStats prints the rates of the last window, if enabled
+func (s *Simulator) printRecentStats() {
+	span := s.recent.span()
+	if span == 0 {
+		return
+	}
+	t := s.recent.totals(s.now())
+	deliveryRate, wasteRate := t.rates()
+	fmt.Printf("Last %v: Received=%d, Delivered=%d, Wasted=%d, Expired=%d\n",
+		span, t.received, t.delivered, t.wasted, t.expired)
+	fmt.Printf("  Delivery rate: %.1f%%, Waste rate: %.1f%% of the orders finished\n",
+		deliveryRate, wasteRate)
+}
@@ -0,0 +1,56 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"dish-dispatcher/internal/order"
+)
+
+func TestRollingWindow(t *testing.T) {
+	w := newRollingWindow(60)
+	start := time.Unix(1000, 0)
+	o := order.NewOrder("Burger", order.Hot, 300, 0.5)
+
+	// Old enough to have left the window
+	w.receive(start)
+	w.observe(o, order.StateShelved, order.StateWasted, start)
+
+	now := start.Add(90 * time.Second)
+	for i := 0; i < 4; i++ {
+		w.receive(now.Add(-time.Duration(i) * 10 * time.Second))
+	}
+	w.observe(o, order.StateShelved, order.StateDelivered, now.Add(-30*time.Second))
+	w.observe(o, order.StateShelved, order.StateDelivered, now)
+	w.observe(o, order.StateInTransit, order.StateDelivered, now)
+	w.observe(o, order.StateShelved, order.StateExpired, now.Add(-59*time.Second))
+
+	got := w.totals(now)
+	if want := (windowTotals{received: 4, delivered: 3, expired: 1}); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+	if delivery, waste := got.rates(); delivery != 75 || waste != 25 {
+		t.Errorf("Expected 75%% delivered and 25%% lost, got %.1f%% and %.1f%%", delivery, waste)
+	}
+
+	// Counts from later seconds are not yet in a window ending earlier
+	if got := w.totals(now.Add(-20 * time.Second)); got.delivered != 1 || got.received != 2 {
+		t.Errorf("Unexpected totals %+v", got)
+	}
+
+	w.reset()
+	if got := w.totals(now); got != (windowTotals{}) {
+		t.Errorf("Expected a reset window to be empty, got %+v", got)
+	}
+}
+
+func TestRollingWindow_Disabled(t *testing.T) {
+	var w rollingWindow
+	w.receive(time.Now())
+	if w.span() != 0 || w.totals(time.Now()) != (windowTotals{}) {
+		t.Errorf("Expected the zero window to count nothing")
+	}
+	if delivery, waste := (windowTotals{received: 3}).rates(); delivery != 0 || waste != 0 {
+		t.Errorf("Expected no rates without finished orders")
+	}
+}
@@ -0,0 +1,258 @@
+// Package strictjson finds fields in JSON that a Go type would silently
+// ignore, such as a misspelt "ordersPerSec", and reports where they are.
+package strictjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnknownFieldError reports a field that does not exist in the type decoded
+// into
+type UnknownFieldError struct {
+	Field  string // the path of the field, such as "couriers.strategyy" or "shelves[1].nmae"
+	Offset int64  // of the field name, in bytes from the start of the input
+	Line   int    // of the field name, counting from 1
+	Column int    // of the field name in bytes, counting from 1
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("line %d, column %d: unknown field %q", e.Line, e.Column, e.Field)
+}
+
+// Check returns an *UnknownFieldError for the first field in data that
+// decoding into v would ignore, or nil if there is none. Values of the
+// wrong type are left for decoding to report, and syntax errors are
+// returned as they are.
+func Check(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := walk(dec, reflect.TypeOf(v), ""); err != nil {
+		if unknown, ok := err.(*UnknownFieldError); ok {
+			unknown.Line, unknown.Column = Position(data, unknown.Offset)
+		}
+		return err
+	}
+	return nil
+}
+
+// Unmarshal decodes data into v, as json.Unmarshal does, but fails on the
+// first unknown field
+func Unmarshal(data []byte, v any) error {
+	if err := Check(data, v); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(v)
+}
+
+// Position returns the line and column of a byte offset in data, both
+// counting from 1
+func Position(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	column = len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+// walk checks the next value in dec against t
+func walk(dec *json.Decoder, t reflect.Type, path string) error {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	// Types that decode themselves, and any, take whatever they are given
+	if t == nil || t.Kind() == reflect.Interface || reflect.PointerTo(t).Implements(unmarshalerType) {
+		return skip(dec, delim)
+	}
+
+	switch {
+	case delim == '{' && t.Kind() == reflect.Struct:
+		fields := fieldsOf(t)
+		for dec.More() {
+			key, offset, err := readKey(dec)
+			if err != nil {
+				return err
+			}
+			field, ok := lookup(fields, key)
+			if !ok {
+				return &UnknownFieldError{Field: path + key, Offset: offset}
+			}
+			if err := walk(dec, field, path+key+"."); err != nil {
+				return err
+			}
+		}
+	case delim == '{' && t.Kind() == reflect.Map:
+		for dec.More() {
+			key, _, err := readKey(dec)
+			if err != nil {
+				return err
+			}
+			if err := walk(dec, t.Elem(), path+key+"."); err != nil {
+				return err
+			}
+		}
+	case delim == '[' && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+		prefix := strings.TrimSuffix(path, ".")
+		for i := 0; dec.More(); i++ {
+			if err := walk(dec, t.Elem(), prefix+"["+strconv.Itoa(i)+"]."); err != nil {
+				return err
+			}
+		}
+	default:
+		return skip(dec, delim)
+	}
+	_, err = dec.Token() // the closing delimiter
+	return err
+}
+
+// readKey reads an object key, returning it and the offset of its opening
+// quote
+func readKey(dec *json.Decoder) (string, int64, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", 0, err
+	}
+	key, _ := tok.(string)
+	quoted, _ := json.Marshal(key)
+	return key, dec.InputOffset() - int64(len(quoted)), nil
+}
+
+// skip consumes the rest of a value whose opening delimiter has been read
+func skip(dec *json.Decoder, delim json.Delim) error {
+	if delim == '}' || delim == ']' {
+		return nil
+	}
+	for depth := 1; depth > 0; {
+		tok, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+	return nil
+}
+
+// fieldsOf returns the JSON names of a struct's fields and their types,
+// including those of embedded structs, as encoding/json decodes them
+func fieldsOf(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for name, ft := range fieldsOf(embedded) {
+					if _, ok := fields[name]; !ok {
+						fields[name] = ft
+					}
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookup finds a field by name, falling back to the case-insensitive match
+// encoding/json accepts
+func lookup(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// Reader tracks the lines of a stream as it is read, so positions in a
+// stream decoded a value at a time can still be reported by line. It holds
+// only what has been read past the last call to Advance.
+type Reader struct {
+	r      io.Reader
+	buf    []byte
+	base   int64 // offset of buf[0]
+	line   int   // lines before base, less one
+	column int   // bytes since the last newline before base
+}
+
+// NewReader tracks the lines read from r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// Advance discards what was read before offset, counting its lines.
+// Offsets must not go backwards.
+func (r *Reader) Advance(offset int64) {
+	n := offset - r.base
+	if n <= 0 {
+		return
+	}
+	if n > int64(len(r.buf)) {
+		n = int64(len(r.buf))
+	}
+	consumed := r.buf[:n]
+	if i := bytes.LastIndexByte(consumed, '\n'); i >= 0 {
+		r.line += bytes.Count(consumed, []byte("\n"))
+		r.column = len(consumed) - i - 1
+	} else {
+		r.column += len(consumed)
+	}
+	r.buf = r.buf[n:]
+	r.base += n
+}
+
+// Position returns the line and column of an offset not yet discarded by
+// Advance, both counting from 1
+func (r *Reader) Position(offset int64) (line, column int) {
+	r.Advance(offset)
+	return r.line + 1, r.column + 1
+}
@@ -0,0 +1,120 @@
+package strictjson_test
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/strictjson"
+)
+
+type shelf struct {
+	Name     string `json:"name"`
+	Capacity int    `json:"capacity"`
+}
+
+type Common struct {
+	Label string `json:"label"`
+}
+
+type config struct {
+	Common
+	OrdersPerSecond float64          `json:"ordersPerSecond"`
+	Shelves         []shelf          `json:"shelves"`
+	Limits          map[string]shelf `json:"limits"`
+	Extra           json.RawMessage  `json:"extra"`
+	Anything        any              `json:"anything"`
+	Started         time.Time        `json:"started"`
+	Ignored         string           `json:"-"`
+	Untagged        int
+	Pointer         *shelf             `json:"pointer"`
+	Nested          map[string][]shelf `json:"nested"`
+	unexported      int
+}
+
+func TestCheck_Valid(t *testing.T) {
+	data := `{
+		"label": "run",
+		"ORDERSPERSECOND": 2,
+		"shelves": [{"name": "hot", "capacity": 3}],
+		"limits": {"hot": {"capacity": 1}},
+		"extra": {"anything": [1, {"goes": true}]},
+		"anything": {"at": "all"},
+		"started": "2024-01-01T00:00:00Z",
+		"Untagged": 1,
+		"pointer": null,
+		"nested": {"a": [{"name": "x"}]}
+	}`
+	assert.NoError(t, strictjson.Check([]byte(data), &config{}))
+}
+
+func TestCheck_UnknownField(t *testing.T) {
+	tests := map[string]struct {
+		data   string
+		field  string
+		line   int
+		column int
+	}{
+		"top level":    {"{\n  \"ordersPerSec\": 2\n}", "ordersPerSec", 2, 3},
+		"in a list":    {`{"shelves": [{"name": "hot"}, {"nmae": "cold"}]}`, "shelves[1].nmae", 1, 32},
+		"in a map":     {`{"limits": {"hot": {"cap": 1}}}`, "limits.hot.cap", 1, 21},
+		"behind a ptr": {`{"pointer": {"size": 1}}`, "pointer.size", 1, 14},
+		"deep":         {`{"nested": {"a": [{}, {}, {"x": 1}]}}`, "nested.a[2].x", 1, 28},
+		"tagged out":   {`{"Ignored": "x"}`, "Ignored", 1, 2},
+		"unexported":   {`{"unexported": 1}`, "unexported", 1, 2},
+	}
+	for name, test := range tests {
+		err := strictjson.Check([]byte(test.data), &config{})
+		var unknown *strictjson.UnknownFieldError
+		if assert.ErrorAs(t, err, &unknown, name) {
+			assert.Equal(t, test.field, unknown.Field, name)
+			assert.Equal(t, test.line, unknown.Line, name)
+			assert.Equal(t, test.column, unknown.Column, name)
+		}
+	}
+}
+
+func TestCheck_LeavesTypeErrorsToDecoding(t *testing.T) {
+	assert.NoError(t, strictjson.Check([]byte(`{"shelves": {"name": "hot"}, "label": [1]}`), &config{}))
+	assert.Error(t, strictjson.Check([]byte(`{"label": `), &config{}))
+}
+
+func TestUnmarshal(t *testing.T) {
+	var cfg config
+	require.NoError(t, strictjson.Unmarshal([]byte(`{"label": "run", "shelves": [{"name": "hot"}]}`), &cfg))
+	assert.Equal(t, "run", cfg.Label)
+	assert.Equal(t, []shelf{{Name: "hot"}}, cfg.Shelves)
+
+	err := strictjson.Unmarshal([]byte(`{"label": "run", "colour": "red"}`), &cfg)
+	assert.EqualError(t, err, `line 1, column 18: unknown field "colour"`)
+}
+
+func TestReader_Position(t *testing.T) {
+	input := "{\"a\": 1}\n{\"b\": 2}\n\n  {\"c\": 3}\n"
+	r := strictjson.NewReader(strings.NewReader(input))
+	dec := json.NewDecoder(r)
+
+	var starts []int64
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		end := dec.InputOffset()
+		starts = append(starts, end-int64(len(raw)))
+		r.Advance(end - int64(len(raw)))
+	}
+	require.Len(t, starts, 3)
+
+	// Only positions from the last value on are still known
+	line, column := r.Position(starts[2] + 1)
+	assert.Equal(t, 4, line)
+	assert.Equal(t, 4, column)
+}
@@ -0,0 +1,101 @@
+package tenant
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/order"
+	shelf "dish-dispatcher/internal/shelves"
+	"dish-dispatcher/internal/simulator"
+)
+
+// quota counts a tenant's orders in fixed one-minute windows
+type quota struct {
+	perMinute int
+	now       func() time.Time
+
+	mutex sync.Mutex
+	start time.Time // of the current window
+	used  int
+}
+
+// take counts an order, reporting false if the window's quota is used up
+func (q *quota) take() bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	now := q.now()
+	if now.Sub(q.start) >= time.Minute {
+		q.start, q.used = now, 0
+	}
+	if q.used >= q.perMinute {
+		return false
+	}
+	q.used++
+	return true
+}
+
+// quotaService rejects a tenant's orders beyond its quota, as an admission
+// policy would, so the API answers them with 429
+type quotaService struct {
+	api.Service
+	name  string
+	quota *quota
+}
+
+// withQuota limits svc to perMinute orders a minute, or returns it as is
+// for no limit
+func withQuota(svc api.Service, name string, perMinute int) api.Service {
+	if perMinute <= 0 {
+		return svc
+	}
+	return &quotaService{Service: svc, name: name, quota: &quota{perMinute: perMinute, now: time.Now}}
+}
+
+func (s *quotaService) Submit(d simulator.OrderData) (*order.Order, error) {
+	if !s.quota.take() {
+		return nil, s.overQuota()
+	}
+	return s.Service.Submit(d)
+}
+
+// SubmitBatch takes the quota for each order of the batch, rejecting those
+// beyond it. An all-or-nothing batch with any order beyond the quota is
+// turned away whole, though its orders within it still count.
+func (s *quotaService) SubmitBatch(ds []simulator.OrderData, mode shelf.BatchMode) ([]*order.Order, []error) {
+	orders := make([]*order.Order, len(ds))
+	errs := make([]error, len(ds))
+	var within []simulator.OrderData
+	var indexes []int
+	for i, d := range ds {
+		if !s.quota.take() {
+			errs[i] = s.overQuota()
+			continue
+		}
+		within = append(within, d)
+		indexes = append(indexes, i)
+	}
+
+	if len(within) == 0 {
+		return orders, errs
+	}
+	if mode == shelf.BatchAllOrNothing && len(within) < len(ds) {
+		for _, i := range indexes {
+			errs[i] = simulator.ErrBatchRejected
+		}
+		return orders, errs
+	}
+	placed, placeErrs := s.Service.SubmitBatch(within, mode)
+	for j, i := range indexes {
+		orders[i], errs[i] = placed[j], placeErrs[j]
+	}
+	return orders, errs
+}
+
+// overQuota rejects an order beyond the quota
+func (s *quotaService) overQuota() error {
+	return fmt.Errorf("%w: tenant %s is over its quota of %d orders a minute",
+		simulator.ErrRejected, s.name, s.quota.perMinute)
+}
@@ -0,0 +1,79 @@
+package tenant
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Headers naming a request's tenant. Tenants with an API key are named by
+// it, as a bearer token or in APIKeyHeader; the others by TenantHeader. When
+// the control API requires tokens, they take the bearer token, so keys go
+// in APIKeyHeader.
+const (
+	APIKeyHeader = "X-API-Key"
+	TenantHeader = "X-Tenant"
+)
+
+// probes, and the API document, are served by the main simulation whoever
+// asks, so load balancers and monitoring need no tenant
+var probes = map[string]bool{"/healthz": true, "/readyz": true, "/version": true, "/openapi.json": true}
+
+// Handler routes API requests to the API of the tenant they name. Requests
+// naming no tenant go to fallback, the main simulation's API, unless
+// requireTenant refuses them.
+func (g *Group) Handler(fallback http.Handler, requireTenant bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if probes[r.URL.Path] {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+
+		t, status, message := g.identify(r)
+		switch {
+		case status != 0:
+			writeError(w, status, message)
+		case t != nil:
+			t.Server.Handler().ServeHTTP(w, r)
+		case requireTenant:
+			writeError(w, http.StatusUnauthorized, "name a tenant with an API key or the "+TenantHeader+" header")
+		default:
+			fallback.ServeHTTP(w, r)
+		}
+	})
+}
+
+// identify returns the tenant a request names, nil if it names none, or an
+// error status and message if it names one it may not use
+func (g *Group) identify(r *http.Request) (*Tenant, int, string) {
+	key := r.Header.Get(APIKeyHeader)
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		key = strings.TrimSpace(bearer)
+	}
+	if key != "" {
+		t, ok := g.byKey[key]
+		if !ok {
+			return nil, http.StatusUnauthorized, "unknown API key"
+		}
+		return t, 0, ""
+	}
+
+	name := r.Header.Get(TenantHeader)
+	if name == "" {
+		return nil, 0, ""
+	}
+	t, ok := g.byName[name]
+	switch {
+	case !ok:
+		return nil, http.StatusNotFound, "unknown tenant " + name
+	case t.key != "":
+		return nil, http.StatusUnauthorized, "tenant " + name + " requires its API key"
+	}
+	return t, 0, ""
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
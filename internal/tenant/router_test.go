@@ -0,0 +1,116 @@
+package tenant_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/tenant"
+)
+
+const soup = `{"name":"Soup","temp":"hot","shelfLife":300,"decayRate":0.5}`
+
+func newRouter(t *testing.T, requireTenant bool) *httptest.Server {
+	cfg := tenantsConfig(
+		config.TenantConfig{Name: "checkout"},
+		config.TenantConfig{Name: "search", APIKey: "secret", OrdersPerMinute: 1},
+	)
+	g, err := tenant.New(cfg, newSimulator)
+	require.NoError(t, err)
+	g.Start()
+	t.Cleanup(g.Stop)
+
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	srv := httptest.NewServer(g.Handler(fallback, requireTenant))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func do(t *testing.T, method, url, body string, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestHandler_Routes(t *testing.T) {
+	srv := newRouter(t, false)
+	checkout := http.Header{tenant.TenantHeader: {"checkout"}}
+	search := http.Header{"Authorization": {"Bearer secret"}}
+
+	// Each tenant has a shelf of its own
+	assert.Equal(t, http.StatusCreated, do(t, http.MethodPost, srv.URL+"/orders", soup, checkout).StatusCode)
+	assert.Equal(t, http.StatusConflict, do(t, http.MethodPost, srv.URL+"/orders", soup, checkout).StatusCode)
+	assert.Equal(t, http.StatusCreated, do(t, http.MethodPost, srv.URL+"/orders", soup, search).StatusCode)
+
+	var orders []json.RawMessage
+	resp := do(t, http.MethodGet, srv.URL+"/orders", "", checkout)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&orders))
+	assert.Len(t, orders, 1)
+
+	// Untenanted requests and probes go to the main simulation
+	assert.Equal(t, http.StatusTeapot, do(t, http.MethodGet, srv.URL+"/api/stats", "", nil).StatusCode)
+	assert.Equal(t, http.StatusTeapot, do(t, http.MethodGet, srv.URL+"/healthz", "", checkout).StatusCode)
+}
+
+func TestHandler_Identify(t *testing.T) {
+	srv := newRouter(t, true)
+
+	for _, tc := range []struct {
+		header http.Header
+		status int
+	}{
+		{nil, http.StatusUnauthorized},
+		{http.Header{tenant.APIKeyHeader: {"secret"}}, http.StatusOK},
+		{http.Header{tenant.APIKeyHeader: {"wrong"}}, http.StatusUnauthorized},
+		{http.Header{tenant.TenantHeader: {"search"}}, http.StatusUnauthorized},
+		{http.Header{tenant.TenantHeader: {"billing"}}, http.StatusNotFound},
+	} {
+		resp := do(t, http.MethodGet, srv.URL+"/api/stats", "", tc.header)
+		assert.Equal(t, tc.status, resp.StatusCode, "headers %v", tc.header)
+	}
+	assert.Equal(t, http.StatusTeapot, do(t, http.MethodGet, srv.URL+"/readyz", "", nil).StatusCode)
+}
+
+func TestHandler_Quota(t *testing.T) {
+	srv := newRouter(t, false)
+	search := http.Header{tenant.APIKeyHeader: {"secret"}}
+
+	assert.Equal(t, http.StatusCreated, do(t, http.MethodPost, srv.URL+"/orders", soup, search).StatusCode)
+	resp := do(t, http.MethodPost, srv.URL+"/orders", soup, search)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+	var result struct{ Reason, Error string }
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, "rejected", result.Reason)
+	assert.Contains(t, result.Error, "quota of 1 orders a minute")
+}
+
+func TestHandler_QuotaBatch(t *testing.T) {
+	srv := newRouter(t, false)
+	search := http.Header{tenant.APIKeyHeader: {"secret"}}
+
+	resp := do(t, http.MethodPost, srv.URL+"/orders", "["+soup+","+soup+"]", search)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var results []struct{ Reason, Error string }
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&results))
+	require.Len(t, results, 2)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "rejected", results[1].Reason)
+	assert.Contains(t, results[1].Error, "quota of 1 orders a minute")
+}
@@ -0,0 +1,156 @@
+// Package tenant lets several teams share one service-mode deployment. Each
+// tenant gets a simulation of its own, with separate shelves, couriers,
+// quota and stats, served through the control API under its name.
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+
+	"dish-dispatcher/internal/api"
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/simulator"
+)
+
+// Tenant is one tenant's simulation and the API serving it
+type Tenant struct {
+	Name   string
+	Config *config.Config
+	Sim    *simulator.Simulator
+	Server *api.Server
+
+	key     string
+	started chan struct{} // closed once the simulation takes orders
+}
+
+// Group runs the simulations of a deployment's tenants
+type Group struct {
+	tenants []*Tenant
+	byName  map[string]*Tenant
+	byKey   map[string]*Tenant
+	wg      sync.WaitGroup
+}
+
+// NewSimulator creates one tenant's simulation from its config
+type NewSimulator func(cfg *config.Config) (*simulator.Simulator, error)
+
+// New creates a simulation for each tenant of cfg with newSim
+func New(cfg *config.Config, newSim NewSimulator) (*Group, error) {
+	if err := validate(cfg); err != nil {
+		return nil, err
+	}
+
+	g := &Group{byName: make(map[string]*Tenant), byKey: make(map[string]*Tenant)}
+	for _, tc := range cfg.Service.Tenants {
+		tcfg := Config(cfg, tc)
+		sim, err := newSim(tcfg)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tc.Name, err)
+		}
+
+		server := api.NewServer(sim.ShelfManager, sim.Events, sim.Archive)
+		server.SetRun(sim)
+		server.SetTimings(sim.Timings)
+		server.SetMetrics(sim)
+		server.SetService(withQuota(sim, tc.Name, tc.OrdersPerMinute))
+		started := make(chan struct{})
+		sim.OnStart = func() {
+			server.SetReady(true)
+			close(started)
+		}
+		sim.OnStop = func() { server.SetReady(false) }
+
+		t := &Tenant{Name: tc.Name, Config: tcfg, Sim: sim, Server: server, key: tc.APIKey, started: started}
+		g.tenants = append(g.tenants, t)
+		g.byName[t.Name] = t
+		if t.key != "" {
+			g.byKey[t.key] = t
+		}
+	}
+	return g, nil
+}
+
+// validate checks the configured tenants
+func validate(cfg *config.Config) error {
+	if len(cfg.Service.Tenants) > 0 && !cfg.Service.Enabled {
+		return errors.New("service.tenants only apply in service mode")
+	}
+	names := make(map[string]bool)
+	keys := make(map[string]bool)
+	for i, tc := range cfg.Service.Tenants {
+		var err error
+		switch {
+		case tc.Name == "":
+			err = errors.New("needs a name")
+		case names[tc.Name]:
+			err = fmt.Errorf("name %q is already taken", tc.Name)
+		case tc.APIKey != "" && keys[tc.APIKey]:
+			err = errors.New("apiKey is already taken")
+		case tc.OrdersPerMinute < 0:
+			err = fmt.Errorf("ordersPerMinute must not be negative, got %d", tc.OrdersPerMinute)
+		}
+		if err != nil {
+			return fmt.Errorf("service.tenants[%d]: %w", i, err)
+		}
+		names[tc.Name] = true
+		keys[tc.APIKey] = true
+	}
+	return nil
+}
+
+// Config returns the config of one tenant's simulation: cfg with the tenant
+// tagged on its runs and its Redis keys, and without the broker, event log
+// and courier agent connections, which stay with the main simulation
+func Config(cfg *config.Config, tc config.TenantConfig) *config.Config {
+	tcfg := *cfg
+	tcfg.Run.Tags = maps.Clone(cfg.Run.Tags)
+	if tcfg.Run.Tags == nil {
+		tcfg.Run.Tags = make(map[string]string)
+	}
+	tcfg.Run.Tags["tenant"] = tc.Name
+	tcfg.Service.Tenants = nil
+	tcfg.Redis.Prefix = cfg.Redis.Prefix + ":" + tc.Name
+	tcfg.Couriers.AgentAddr = ""
+	tcfg.EventLog = config.EventLogConfig{}
+	tcfg.MQTT = config.MQTTConfig{}
+	tcfg.NATS = config.NATSConfig{}
+	tcfg.AMQP = config.AMQPConfig{}
+	return &tcfg
+}
+
+// Tenants returns the tenants in configured order
+func (g *Group) Tenants() []*Tenant {
+	return g.tenants
+}
+
+// SetVerbose turns the per-order log lines of every tenant on or off
+func (g *Group) SetVerbose(verbose bool) {
+	for _, t := range g.tenants {
+		t.Sim.SetVerbose(verbose)
+	}
+}
+
+// Start runs every tenant's simulation in the background, returning once
+// they all take orders
+func (g *Group) Start() {
+	for _, t := range g.tenants {
+		g.wg.Add(1)
+		go func() {
+			defer g.wg.Done()
+			t.Sim.Run()
+		}()
+	}
+	for _, t := range g.tenants {
+		<-t.started
+	}
+}
+
+// Stop ends every tenant's simulation and waits for them to finish
+func (g *Group) Stop() {
+	for _, t := range g.tenants {
+		t.Sim.Stop()
+	}
+	g.wg.Wait()
+}
@@ -0,0 +1,72 @@
+package tenant_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/simulator"
+	"dish-dispatcher/internal/tenant"
+)
+
+func newSimulator(cfg *config.Config) (*simulator.Simulator, error) {
+	return simulator.NewSimulator(cfg, "")
+}
+
+func tenantsConfig(tenants ...config.TenantConfig) *config.Config {
+	cfg := config.DefaultConfig()
+	cfg.HotShelfCapacity = 1
+	cfg.OverflowCapacity = 0
+	cfg.Service.Enabled = true
+	cfg.Service.Tenants = tenants
+	return cfg
+}
+
+func TestNew(t *testing.T) {
+	cfg := tenantsConfig(config.TenantConfig{Name: "checkout"}, config.TenantConfig{Name: "search", APIKey: "secret"})
+	g, err := tenant.New(cfg, newSimulator)
+	require.NoError(t, err)
+
+	tenants := g.Tenants()
+	require.Len(t, tenants, 2)
+	assert.Equal(t, "checkout", tenants[0].Name)
+	assert.NotSame(t, tenants[0].Sim.ShelfManager, tenants[1].Sim.ShelfManager)
+
+	g.Start()
+	g.Stop()
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, tenants := range [][]config.TenantConfig{
+		{{Name: ""}},
+		{{Name: "checkout"}, {Name: "checkout"}},
+		{{Name: "checkout", APIKey: "secret"}, {Name: "search", APIKey: "secret"}},
+		{{Name: "checkout", OrdersPerMinute: -1}},
+	} {
+		_, err := tenant.New(tenantsConfig(tenants...), newSimulator)
+		assert.Error(t, err, "tenants %+v", tenants)
+	}
+
+	cfg := tenantsConfig(config.TenantConfig{Name: "checkout"})
+	cfg.Service.Enabled = false
+	_, err := tenant.New(cfg, newSimulator)
+	assert.Error(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	cfg := tenantsConfig(config.TenantConfig{Name: "checkout"})
+	cfg.Run.Tags = map[string]string{"team": "payments"}
+	cfg.EventLog.File = "events.ndjson"
+	cfg.Couriers.AgentAddr = ":9090"
+
+	tcfg := tenant.Config(cfg, cfg.Service.Tenants[0])
+	assert.Equal(t, map[string]string{"team": "payments", "tenant": "checkout"}, tcfg.Run.Tags)
+	assert.Equal(t, map[string]string{"team": "payments"}, cfg.Run.Tags)
+	assert.Equal(t, "dish-dispatcher:checkout", tcfg.Redis.Prefix)
+	assert.Empty(t, tcfg.EventLog.File)
+	assert.Empty(t, tcfg.Couriers.AgentAddr)
+	assert.Empty(t, tcfg.Service.Tenants)
+	assert.True(t, tcfg.Service.Enabled)
+}
@@ -0,0 +1,202 @@
+// Package timing records latency histograms of internal operations, cheap
+// enough to leave on in high-rate runs.
+package timing
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// bucketCount is the number of bounded histogram buckets
+const bucketCount = 21
+
+// Buckets are the histogram upper bounds, doubling from 1µs to about 1s.
+// Slower observations fall in a final unbounded bucket.
+var Buckets = func() []time.Duration {
+	buckets := make([]time.Duration, bucketCount)
+	for i := range buckets {
+		buckets[i] = time.Microsecond << i
+	}
+	return buckets
+}()
+
+// Histogram counts durations into Buckets. It is safe for concurrent use
+// and never blocks.
+type Histogram struct {
+	counts [bucketCount + 1]atomic.Int64 // one per bucket, then the unbounded bucket
+	count  atomic.Int64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+// Observe records one duration
+func (h *Histogram) Observe(d time.Duration) {
+	i := sort.Search(len(Buckets), func(i int) bool { return d <= Buckets[i] })
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	h.sum.Add(int64(d))
+	for {
+		max := h.max.Load()
+		if int64(d) <= max || h.max.CompareAndSwap(max, int64(d)) {
+			return
+		}
+	}
+}
+
+// Since records the time elapsed since start
+func (h *Histogram) Since(start time.Time) {
+	h.Observe(time.Since(start))
+}
+
+// Snapshot returns the histogram's current counts
+func (h *Histogram) Snapshot() Snapshot {
+	s := Snapshot{
+		Count:  h.count.Load(),
+		Sum:    time.Duration(h.sum.Load()),
+		Max:    time.Duration(h.max.Load()),
+		Counts: make([]int64, len(h.counts)),
+	}
+	for i := range h.counts {
+		s.Counts[i] = h.counts[i].Load()
+	}
+	return s
+}
+
+// Snapshot is a point-in-time copy of a Histogram. Counts are per bucket,
+// not cumulative, with the unbounded bucket last.
+type Snapshot struct {
+	Count  int64
+	Sum    time.Duration
+	Max    time.Duration
+	Counts []int64
+}
+
+// Mean returns the mean observed duration
+func (s Snapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Quantile estimates the q quantile, 0 to 1, as the upper bound of the
+// bucket it falls in, capped at the largest observation
+func (s Snapshot) Quantile(q float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := int64(q*float64(s.Count) + 0.5)
+	var seen int64
+	for i, n := range s.Counts {
+		seen += n
+		if seen >= rank && i < len(Buckets) {
+			return min(Buckets[i], s.Max)
+		}
+	}
+	return s.Max
+}
+
+// Set is a named group of histograms
+type Set struct {
+	mutex sync.RWMutex
+	hists map[string]*Histogram
+}
+
+// NewSet creates an empty set
+func NewSet() *Set {
+	return &Set{hists: make(map[string]*Histogram)}
+}
+
+// Histogram returns the named histogram, creating it on first use. A nil
+// set returns a histogram that is not kept, so callers need no checks.
+func (s *Set) Histogram(name string) *Histogram {
+	if s == nil {
+		return &Histogram{}
+	}
+
+	s.mutex.RLock()
+	h, ok := s.hists[name]
+	s.mutex.RUnlock()
+	if ok {
+		return h
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if h, ok := s.hists[name]; ok {
+		return h
+	}
+	h = &Histogram{}
+	s.hists[name] = h
+	return h
+}
+
+// Reset drops every histogram, so each counts afresh from its next
+// observation
+func (s *Set) Reset() {
+	if s == nil {
+		return
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.hists = make(map[string]*Histogram)
+}
+
+// Snapshots returns a snapshot of every histogram by name
+func (s *Set) Snapshots() map[string]Snapshot {
+	if s == nil {
+		return nil
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	snapshots := make(map[string]Snapshot, len(s.hists))
+	for name, h := range s.hists {
+		snapshots[name] = h.Snapshot()
+	}
+	return snapshots
+}
+
+// WritePrometheus writes the set as one Prometheus histogram metric in the
+// text exposition format, labelling each histogram by its name and every
+// series by constLabels, given as name and value pairs
+func (s *Set) WritePrometheus(w io.Writer, metric, help, label string, constLabels ...string) error {
+	var extra string
+	for i := 0; i+1 < len(constLabels); i += 2 {
+		extra += fmt.Sprintf(",%s=%q", constLabels[i], constLabels[i+1])
+	}
+
+	snapshots := s.Snapshots()
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", metric, help, metric); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		snap := snapshots[name]
+		var cumulative int64
+		for i, bound := range Buckets {
+			cumulative += snap.Counts[i]
+			le := strconv.FormatFloat(bound.Seconds(), 'g', -1, 64)
+			fmt.Fprintf(w, "%s_bucket{%s=%q%s,le=%q} %d\n", metric, label, name, extra, le, cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s=%q%s,le=\"+Inf\"} %d\n", metric, label, name, extra, snap.Count)
+		fmt.Fprintf(w, "%s_sum{%s=%q%s} %g\n", metric, label, name, extra, snap.Sum.Seconds())
+		if _, err := fmt.Fprintf(w, "%s_count{%s=%q%s} %d\n", metric, label, name, extra, snap.Count); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package timing_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/timing"
+)
+
+func TestHistogram_Observe(t *testing.T) {
+	var h timing.Histogram
+	for i := 0; i < 98; i++ {
+		h.Observe(3 * time.Microsecond)
+	}
+	h.Observe(100 * time.Microsecond)
+	h.Observe(5 * time.Second)
+
+	snap := h.Snapshot()
+	assert.Equal(t, int64(100), snap.Count)
+	assert.Equal(t, 5*time.Second, snap.Max)
+	assert.Equal(t, int64(98), snap.Counts[2]) // 3µs falls in the 4µs bucket
+	assert.Equal(t, int64(1), snap.Counts[len(snap.Counts)-1])
+	assert.Equal(t, 4*time.Microsecond, snap.Quantile(0.5))
+	assert.Equal(t, 128*time.Microsecond, snap.Quantile(0.99))
+	assert.Equal(t, 5*time.Second, snap.Quantile(1))
+	assert.Equal(t, (98*3*time.Microsecond+100*time.Microsecond+5*time.Second)/100, snap.Mean())
+}
+
+func TestHistogram_Empty(t *testing.T) {
+	var h timing.Histogram
+	snap := h.Snapshot()
+	assert.Zero(t, snap.Mean())
+	assert.Zero(t, snap.Quantile(0.99))
+}
+
+func TestSet_Concurrent(t *testing.T) {
+	set := timing.NewSet()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				set.Histogram("place").Observe(time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(800), set.Snapshots()["place"].Count)
+}
+
+func TestSet_Nil(t *testing.T) {
+	var set *timing.Set
+	set.Histogram("place").Observe(time.Microsecond)
+	assert.Empty(t, set.Snapshots())
+}
+
+func TestSet_Reset(t *testing.T) {
+	set := timing.NewSet()
+	set.Histogram("place").Observe(time.Millisecond)
+	set.Reset()
+	assert.Empty(t, set.Snapshots())
+
+	set.Histogram("place").Observe(time.Microsecond)
+	assert.Equal(t, int64(1), set.Snapshots()["place"].Count)
+	assert.Equal(t, time.Microsecond, set.Snapshots()["place"].Max)
+
+	var unset *timing.Set
+	unset.Reset()
+}
+
+func TestSet_WritePrometheus(t *testing.T) {
+	set := timing.NewSet()
+	set.Histogram("place").Observe(3 * time.Microsecond)
+	set.Histogram("place").Observe(2 * time.Second)
+
+	var buf bytes.Buffer
+	require.NoError(t, set.WritePrometheus(&buf, "op_seconds", "Op latency.", "op"))
+	out := buf.String()
+	assert.Contains(t, out, "# TYPE op_seconds histogram\n")
+	assert.Contains(t, out, `op_seconds_bucket{op="place",le="2e-06"} 0`)
+	assert.Contains(t, out, `op_seconds_bucket{op="place",le="4e-06"} 1`)
+	assert.Contains(t, out, `op_seconds_bucket{op="place",le="+Inf"} 2`)
+	assert.Contains(t, out, `op_seconds_count{op="place"} 2`)
+
+	buf.Reset()
+	require.NoError(t, set.WritePrometheus(&buf, "op_seconds", "Op latency.", "op", "run_id", "baseline-1"))
+	out = buf.String()
+	assert.Contains(t, out, `op_seconds_bucket{op="place",run_id="baseline-1",le="+Inf"} 2`)
+	assert.Contains(t, out, `op_seconds_sum{op="place",run_id="baseline-1"} 2.000003`)
+}
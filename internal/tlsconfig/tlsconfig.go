@@ -0,0 +1,214 @@
+// Package tlsconfig builds the TLS configs of the dispatcher's listeners
+// and clients from the tls section of the config file, reloading the
+// certificate, and a listener's client CAs, whenever their files change.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"dish-dispatcher/internal/config"
+)
+
+// Server returns the config of a TLS listener, or nil if cfg configures no
+// certificate and listeners serve plain connections
+func Server(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" && cfg.KeyFile == "" {
+		if cfg.ClientCAFile != "" {
+			return nil, errors.New("tls.clientCAFile needs tls.certFile and tls.keyFile")
+		}
+		return nil, nil
+	}
+	certs, err := newCertLoader(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tc := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return certs.get(), nil
+		},
+	}
+	if cfg.ClientCAFile != "" {
+		cas, err := newPoolLoader(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.clientCAFile: %w", err)
+		}
+		tc.ClientAuth = tls.RequireAndVerifyClientCert
+		// Each handshake verifies against the CAs as they are now, so a
+		// rotated CA bundle takes effect without a restart
+		base := tc.Clone()
+		tc.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			hc := base.Clone()
+			hc.ClientCAs = cas.get()
+			return hc, nil
+		}
+	}
+	return tc, nil
+}
+
+// Client returns the config of outgoing TLS connections, trusting the CAs
+// of cfg.CAFile and presenting its certificate if it has one. It returns
+// nil if cfg sets neither, leaving the defaults.
+func Client(cfg config.TLSConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" {
+		return nil, nil
+	}
+	tc := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		pool, err := loadPool(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls.caFile: %w", err)
+		}
+		tc.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certs, err := newCertLoader(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tc.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.get(), nil
+		}
+	}
+	return tc, nil
+}
+
+// loadPool reads the PEM certificates of file into a pool
+func loadPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", file)
+	}
+	return pool, nil
+}
+
+// poolLoader holds a CA pool, reloading it on the first handshake after its
+// file changes
+type poolLoader struct {
+	file string
+
+	mutex     sync.Mutex
+	pool      *x509.CertPool
+	mod       time.Time
+	failedMod time.Time // of the file that last failed to load, so it is reported once
+}
+
+// newPoolLoader loads the pool, failing if it cannot
+func newPoolLoader(file string) (*poolLoader, error) {
+	l := &poolLoader{file: file}
+	if err := l.load(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// get returns the current pool. A pool that fails to reload leaves the
+// previous one in use.
+func (l *poolLoader) get() *x509.CertPool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	info, err := os.Stat(l.file)
+	if err != nil || info.ModTime().Equal(l.mod) {
+		return l.pool
+	}
+	if err := l.load(); err != nil && !info.ModTime().Equal(l.failedMod) {
+		l.failedMod = info.ModTime()
+		fmt.Printf("TLS client CA reload failed, keeping the previous ones: %v\n", err)
+	}
+	return l.pool
+}
+
+// load reads the pool. The caller holds the mutex, or has the loader to
+// itself.
+func (l *poolLoader) load() error {
+	info, err := os.Stat(l.file)
+	if err != nil {
+		return err
+	}
+	pool, err := loadPool(l.file)
+	if err != nil {
+		return err
+	}
+	l.pool, l.mod = pool, info.ModTime()
+	return nil
+}
+
+// certLoader holds a certificate, reloading it on the first handshake after
+// its files change
+type certLoader struct {
+	certFile, keyFile string
+
+	mutex           sync.Mutex
+	cert            *tls.Certificate
+	certMod, keyMod time.Time
+	failedMod       time.Time // of the cert file that last failed to load, so it is reported once
+}
+
+// newCertLoader loads the certificate, failing if it cannot
+func newCertLoader(certFile, keyFile string) (*certLoader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls.certFile and tls.keyFile must be set together")
+	}
+	l := &certLoader{certFile: certFile, keyFile: keyFile}
+	if err := l.load(); err != nil {
+		return nil, fmt.Errorf("tls certificate: %w", err)
+	}
+	return l, nil
+}
+
+// get returns the current certificate. A certificate that fails to reload,
+// perhaps because only one of its files has been replaced yet, leaves the
+// previous one in use.
+func (l *certLoader) get() *tls.Certificate {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	certMod, keyMod, err := l.modTimes()
+	if err != nil || (certMod.Equal(l.certMod) && keyMod.Equal(l.keyMod)) {
+		return l.cert
+	}
+	if err := l.load(); err != nil && !certMod.Equal(l.failedMod) {
+		l.failedMod = certMod
+		fmt.Printf("TLS certificate reload failed, keeping the previous one: %v\n", err)
+	}
+	return l.cert
+}
+
+// load reads the certificate and key. The caller holds the mutex, or has
+// the loader to itself.
+func (l *certLoader) load() error {
+	certMod, keyMod, err := l.modTimes()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return err
+	}
+	l.cert, l.certMod, l.keyMod = &cert, certMod, keyMod
+	return nil
+}
+
+func (l *certLoader) modTimes() (time.Time, time.Time, error) {
+	cert, err := os.Stat(l.certFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	key, err := os.Stat(l.keyFile)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return cert.ModTime(), key.ModTime(), nil
+}
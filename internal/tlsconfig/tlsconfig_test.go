@@ -0,0 +1,233 @@
+package tlsconfig_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/internal/config"
+	"dish-dispatcher/internal/tlsconfig"
+)
+
+// issue writes a certificate for name and its key to dir, signed by ca or
+// self-signed as a CA if ca is nil, and returns their paths
+func issue(t *testing.T, dir, name string, ca *tls.Certificate) (string, string, *tls.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, signer := tmpl, any(key)
+	if ca == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+	} else {
+		parent, signer = ca.Leaf, ca.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	return certFile, keyFile, &cert
+}
+
+// serve accepts TLS connections with cfg until the test ends, completing
+// each handshake
+func serve(t *testing.T, cfg *tls.Config) string {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// accepted reports why the server refused the connection, if it did. With
+// TLS 1.3 the server rejects a client certificate after the client's side
+// of the handshake completes, so the refusal surfaces on the first read.
+func accepted(addr string, cfg *tls.Config) error {
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// dial returns the common name of the certificate the server presents
+func dial(addr string, cfg *tls.Config) (string, error) {
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].Subject.CommonName, nil
+}
+
+func TestServer_Disabled(t *testing.T) {
+	cfg, err := tlsconfig.Server(config.TLSConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, cfg)
+
+	cfg, err = tlsconfig.Client(config.TLSConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestServer_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, _ := issue(t, dir, "server", nil)
+
+	for name, cfg := range map[string]config.TLSConfig{
+		"cert without key":    {CertFile: certFile},
+		"key without cert":    {KeyFile: keyFile},
+		"client CA alone":     {ClientCAFile: certFile},
+		"missing cert":        {CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile},
+		"key is not the cert": {CertFile: keyFile, KeyFile: keyFile},
+		"client CA not PEM":   {CertFile: certFile, KeyFile: keyFile, ClientCAFile: keyFile},
+	} {
+		_, err := tlsconfig.Server(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _, ca := issue(t, dir, "ca", nil)
+	certFile, keyFile, _ := issue(t, dir, "server", ca)
+	clientCert, clientKey, _ := issue(t, dir, "client", ca)
+
+	server, err := tlsconfig.Server(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	require.NoError(t, err)
+	addr := serve(t, server)
+
+	client, err := tlsconfig.Client(config.TLSConfig{CAFile: caFile, CertFile: clientCert, KeyFile: clientKey})
+	require.NoError(t, err)
+	name, err := dial(addr, client)
+	require.NoError(t, err)
+	assert.Equal(t, "server", name)
+
+	// Without a client certificate the server ends the handshake
+	anonymous, err := tlsconfig.Client(config.TLSConfig{CAFile: caFile})
+	require.NoError(t, err)
+	assert.Error(t, accepted(addr, anonymous))
+}
+
+func TestServer_ReloadClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _, ca := issue(t, dir, "ca", nil)
+	otherCAFile, _, otherCA := issue(t, dir, "other-ca", nil)
+	certFile, keyFile, _ := issue(t, dir, "server", ca)
+	clientCert, clientKey, _ := issue(t, dir, "client", otherCA)
+
+	clientCAFile := filepath.Join(dir, "client-ca.crt")
+	data, err := os.ReadFile(caFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(clientCAFile, data, 0o600))
+
+	server, err := tlsconfig.Server(config.TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: clientCAFile})
+	require.NoError(t, err)
+	addr := serve(t, server)
+	client, err := tlsconfig.Client(config.TLSConfig{CAFile: caFile, CertFile: clientCert, KeyFile: clientKey})
+	require.NoError(t, err)
+
+	// The client's CA is not trusted yet
+	assert.Error(t, accepted(addr, client))
+
+	// Rotate the client CAs in place
+	data, err = os.ReadFile(otherCAFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(clientCAFile, data, 0o600))
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(clientCAFile, later, later))
+	assert.NoError(t, accepted(addr, client))
+
+	// A broken CA file leaves the previous CAs in use
+	require.NoError(t, os.WriteFile(clientCAFile, []byte("not a certificate"), 0o600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(clientCAFile, later, later))
+	assert.NoError(t, accepted(addr, client))
+}
+
+func TestServer_Reload(t *testing.T) {
+	dir := t.TempDir()
+	caFile, _, ca := issue(t, dir, "ca", nil)
+	certFile, keyFile, _ := issue(t, dir, "first", ca)
+
+	server, err := tlsconfig.Server(config.TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	addr := serve(t, server)
+	client, err := tlsconfig.Client(config.TLSConfig{CAFile: caFile})
+	require.NoError(t, err)
+
+	name, err := dial(addr, client)
+	require.NoError(t, err)
+	assert.Equal(t, "first", name)
+
+	// Rotate the certificate in place
+	newCert, newKey, _ := issue(t, dir, "second", ca)
+	later := time.Now().Add(time.Minute)
+	for src, dst := range map[string]string{newCert: certFile, newKey: keyFile} {
+		data, err := os.ReadFile(src)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(dst, data, 0o600))
+		require.NoError(t, os.Chtimes(dst, later, later))
+	}
+	name, err = dial(addr, client)
+	require.NoError(t, err)
+	assert.Equal(t, "second", name)
+
+	// A broken certificate leaves the previous one in use
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+	later = later.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	name, err = dial(addr, client)
+	require.NoError(t, err)
+	assert.Equal(t, "second", name)
+}
@@ -0,0 +1,236 @@
+// Package plugin lets other Go modules add placement strategies, courier
+// dispatch strategies, admission policies and event sinks to the
+// dispatcher. A module registers them by name from an init function, is
+// linked in with a blank import in cmd/server/plugins.go, and the names
+// become selectable in config:
+//
+//	placementStrategy  a registered PlacementStrategy
+//	couriers.strategy  a built-in or registered DispatchStrategy
+//	admission.policy   a registered AdmissionPolicy
+//	eventSinks         registered EventSinks, all fed every event
+//
+// The dispatcher's own types are internal, so plugins see the plain views
+// defined here.
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Order describes an order to a plugin
+type Order struct {
+	ID        string
+	Name      string
+	Temp      string
+	ShelfLife float64 // seconds
+	DecayRate float64
+	Volume    float64 // shelf space taken
+	Value     float64 // current value, 0 to 1
+	Priority  int     // higher is more urgent
+	Zone      string  // delivery zone
+}
+
+// Shelf describes a shelf an order could be placed on
+type Shelf struct {
+	Type          string
+	Capacity      int
+	Size          int     // orders held
+	Overflow      bool    // accepts any temperature
+	Primary       bool    // routed the order's temperature
+	DecayModifier float64 // decay multiplier of orders held, 1 for normal decay
+	InOutage      bool    // lost cooling
+}
+
+// Courier describes an idle courier
+type Courier struct {
+	ID         int
+	X, Y       float64
+	Distance   time.Duration // travel time to the kitchen
+	Deliveries int           // orders picked up so far
+}
+
+// Event is a notable occurrence during a run, as published on the
+// dispatcher's event bus
+type Event struct {
+	Run     string
+	RunID   string
+	Type    string
+	Time    time.Time
+	OrderID string
+	Name    string
+	Temp    string
+	Shelf   string
+	Value   float64
+	Count   int
+	Reason  string
+	Version string
+}
+
+// PlacementStrategy ranks the shelves an order may go on. Shelves are tried
+// lowest score first; equal scores keep the layout order.
+type PlacementStrategy interface {
+	Score(o Order, s Shelf) float64
+}
+
+// DispatchStrategy chooses which idle courier collects an order. It returns
+// an index into idle, which is never empty. Calls are serialized.
+type DispatchStrategy interface {
+	Choose(o Order, idle []Courier) int
+}
+
+// Admission is an AdmissionPolicy's decision on a new order
+type Admission int
+
+const (
+	Accept Admission = iota // place the order now
+	Defer                   // hold the order and ask again shortly
+	Reject                  // turn the order away
+)
+
+// AdmissionPolicy decides whether a new order is taken, given the shelves
+// as they are, before it is placed. Shelves are in layout order, with
+// Primary set on those routed the order's temperature. Calls are
+// serialized.
+//
+// A policy that stops taking frozen orders once the frozen shelf is 90%
+// full rejects an order with Temp "frozen" while that shelf's Size is at
+// least 0.9 of its Capacity.
+type AdmissionPolicy interface {
+	Admit(o Order, shelves []Shelf) Admission
+}
+
+// EventSink receives every event of a run in order, from one goroutine.
+// Like every event subscriber, a sink that falls far behind misses events.
+type EventSink interface {
+	Handle(e Event)
+	// Close is called once the run ends
+	Close() error
+}
+
+// Factories create a fresh strategy or sink for each run
+type (
+	PlacementFactory func() PlacementStrategy
+	DispatchFactory  func() DispatchStrategy
+	AdmissionFactory func() AdmissionPolicy
+	EventSinkFactory func() (EventSink, error)
+)
+
+var registry = struct {
+	sync.RWMutex
+	placement map[string]PlacementFactory
+	dispatch  map[string]DispatchFactory
+	admission map[string]AdmissionFactory
+	sinks     map[string]EventSinkFactory
+}{
+	placement: make(map[string]PlacementFactory),
+	dispatch:  make(map[string]DispatchFactory),
+	admission: make(map[string]AdmissionFactory),
+	sinks:     make(map[string]EventSinkFactory),
+}
+
+// RegisterPlacementStrategy makes a placement strategy selectable by name.
+// It panics if the name is empty or already registered.
+func RegisterPlacementStrategy(name string, factory PlacementFactory) {
+	register(registry.placement, "placement strategy", name, factory)
+}
+
+// RegisterDispatchStrategy makes a courier dispatch strategy selectable by
+// name. Built-in strategy names take precedence. It panics if the name is
+// empty or already registered.
+func RegisterDispatchStrategy(name string, factory DispatchFactory) {
+	register(registry.dispatch, "dispatch strategy", name, factory)
+}
+
+// RegisterAdmissionPolicy makes an admission policy selectable by name. It
+// panics if the name is empty or already registered.
+func RegisterAdmissionPolicy(name string, factory AdmissionFactory) {
+	register(registry.admission, "admission policy", name, factory)
+}
+
+// RegisterEventSink makes an event sink selectable by name. It panics if
+// the name is empty or already registered.
+func RegisterEventSink(name string, factory EventSinkFactory) {
+	register(registry.sinks, "event sink", name, factory)
+}
+
+func register[F any](m map[string]F, kind, name string, factory F) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if name == "" {
+		panic(fmt.Sprintf("plugin: %s registered without a name", kind))
+	}
+	if _, exists := m[name]; exists {
+		panic(fmt.Sprintf("plugin: %s %q registered twice", kind, name))
+	}
+	m[name] = factory
+}
+
+// NewPlacementStrategy creates the named placement strategy
+func NewPlacementStrategy(name string) (PlacementStrategy, error) {
+	factory, err := lookup(registry.placement, "placement strategy", name)
+	if err != nil {
+		return nil, err
+	}
+	return factory(), nil
+}
+
+// NewDispatchStrategy creates the named dispatch strategy
+func NewDispatchStrategy(name string) (DispatchStrategy, error) {
+	factory, err := lookup(registry.dispatch, "dispatch strategy", name)
+	if err != nil {
+		return nil, err
+	}
+	return factory(), nil
+}
+
+// NewAdmissionPolicy creates the named admission policy
+func NewAdmissionPolicy(name string) (AdmissionPolicy, error) {
+	factory, err := lookup(registry.admission, "admission policy", name)
+	if err != nil {
+		return nil, err
+	}
+	return factory(), nil
+}
+
+// NewEventSink creates the named event sink
+func NewEventSink(name string) (EventSink, error) {
+	factory, err := lookup(registry.sinks, "event sink", name)
+	if err != nil {
+		return nil, err
+	}
+	return factory()
+}
+
+func lookup[F any](m map[string]F, kind, name string) (F, error) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	factory, ok := m[name]
+	if !ok {
+		var zero F
+		return zero, fmt.Errorf("unknown %s %q", kind, name)
+	}
+	return factory, nil
+}
+
+// Registered lists the registered names of each kind, sorted
+func Registered() (placement, dispatch, admission, sinks []string) {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	return sortedKeys(registry.placement), sortedKeys(registry.dispatch),
+		sortedKeys(registry.admission), sortedKeys(registry.sinks)
+}
+
+func sortedKeys[F any](m map[string]F) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,92 @@
+package plugin_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"dish-dispatcher/plugin"
+)
+
+type lastCourier struct{}
+
+func (lastCourier) Choose(_ plugin.Order, idle []plugin.Courier) int { return len(idle) - 1 }
+
+type preferOverflow struct{}
+
+func (preferOverflow) Score(_ plugin.Order, s plugin.Shelf) float64 {
+	if s.Overflow {
+		return 0
+	}
+	return 1
+}
+
+type frozenLimit struct{}
+
+func (frozenLimit) Admit(o plugin.Order, shelves []plugin.Shelf) plugin.Admission {
+	for _, s := range shelves {
+		if o.Temp == "frozen" && s.Primary && float64(s.Size) >= 0.9*float64(s.Capacity) {
+			return plugin.Reject
+		}
+	}
+	return plugin.Accept
+}
+
+type discardSink struct{ closed bool }
+
+func (*discardSink) Handle(plugin.Event) {}
+func (s *discardSink) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	plugin.RegisterPlacementStrategy("test-prefer-overflow", func() plugin.PlacementStrategy { return preferOverflow{} })
+	plugin.RegisterDispatchStrategy("test-last", func() plugin.DispatchStrategy { return lastCourier{} })
+	plugin.RegisterAdmissionPolicy("test-frozen-limit", func() plugin.AdmissionPolicy { return frozenLimit{} })
+	plugin.RegisterEventSink("test-discard", func() (plugin.EventSink, error) { return &discardSink{}, nil })
+
+	placement, err := plugin.NewPlacementStrategy("test-prefer-overflow")
+	require.NoError(t, err)
+	assert.Zero(t, placement.Score(plugin.Order{}, plugin.Shelf{Overflow: true}))
+
+	dispatch, err := plugin.NewDispatchStrategy("test-last")
+	require.NoError(t, err)
+	assert.Equal(t, 1, dispatch.Choose(plugin.Order{}, make([]plugin.Courier, 2)))
+
+	admission, err := plugin.NewAdmissionPolicy("test-frozen-limit")
+	require.NoError(t, err)
+	frozen := []plugin.Shelf{{Type: "frozen", Capacity: 10, Size: 9, Primary: true}}
+	assert.Equal(t, plugin.Reject, admission.Admit(plugin.Order{Temp: "frozen"}, frozen))
+	assert.Equal(t, plugin.Accept, admission.Admit(plugin.Order{Temp: "hot"}, frozen))
+
+	sink, err := plugin.NewEventSink("test-discard")
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	placements, dispatches, admissions, sinks := plugin.Registered()
+	assert.Contains(t, placements, "test-prefer-overflow")
+	assert.Contains(t, dispatches, "test-last")
+	assert.Contains(t, admissions, "test-frozen-limit")
+	assert.Contains(t, sinks, "test-discard")
+
+	_, err = plugin.NewPlacementStrategy("missing")
+	assert.Error(t, err)
+	_, err = plugin.NewDispatchStrategy("missing")
+	assert.Error(t, err)
+	_, err = plugin.NewAdmissionPolicy("missing")
+	assert.Error(t, err)
+	_, err = plugin.NewEventSink("missing")
+	assert.Error(t, err)
+}
+
+func TestRegistry_Invalid(t *testing.T) {
+	plugin.RegisterEventSink("test-twice", func() (plugin.EventSink, error) { return &discardSink{}, nil })
+	assert.Panics(t, func() {
+		plugin.RegisterEventSink("test-twice", func() (plugin.EventSink, error) { return &discardSink{}, nil })
+	})
+	assert.Panics(t, func() {
+		plugin.RegisterDispatchStrategy("", func() plugin.DispatchStrategy { return lastCourier{} })
+	})
+}